@@ -9,7 +9,6 @@ import (
 )
 
 func handleBackupCommand(ctx context.Context, args []string) {
-	_ = ctx // May be used for cancellation in future
 	if len(args) < 1 {
 		fmt.Println("Usage: romman backup <destination>")
 		fmt.Println("  Creates a timestamped backup of the database")
@@ -25,36 +24,39 @@ func handleBackupCommand(ctx context.Context, args []string) {
 		os.Exit(1)
 	}
 
-	// Open source database
+	// Check the database exists rather than creating an empty one to back up
 	srcPath := getDBPath()
 	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
 		PrintError("Error: database not found at %s\n", srcPath)
 		os.Exit(1)
 	}
 
+	database, err := openDB(ctx)
+	if err != nil {
+		PrintError("Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
 	// Create backup filename with timestamp
 	timestamp := time.Now().Format("20060102-150405")
 	backupName := fmt.Sprintf("romman-%s.db", timestamp)
 	destPath := filepath.Join(destDir, backupName)
 
-	// Copy the file
-	// #nosec G304
-	src, err := os.ReadFile(srcPath)
-	if err != nil {
-		PrintError("Error: failed to read database: %v\n", err)
+	if err := database.Backup(ctx, destPath); err != nil {
+		PrintError("Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// #nosec G306
-	if err := os.WriteFile(destPath, src, 0o644); err != nil {
-		PrintError("Error: failed to write backup: %v\n", err)
+	info, err := os.Stat(destPath)
+	if err != nil {
+		PrintError("Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	result := map[string]interface{}{
 		"source":      srcPath,
 		"destination": destPath,
-		"size":        len(src),
+		"size":        info.Size(),
 		"timestamp":   timestamp,
 	}
 
@@ -62,6 +64,6 @@ func handleBackupCommand(ctx context.Context, args []string) {
 		PrintResult(result)
 	} else {
 		PrintInfo("Backup created: %s\n", destPath)
-		PrintInfo("  Size: %d bytes\n", len(src))
+		PrintInfo("  Size: %d bytes\n", info.Size())
 	}
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleBIOSCommand(ctx context.Context, args []string) {
+	switch args[0] {
+	case "scan":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman bios scan <dir>")
+			os.Exit(1)
+		}
+		scanBIOS(ctx, args[1])
+	case "status":
+		system := ""
+		if len(args) > 1 {
+			system = args[1]
+		}
+		showBIOSStatus(ctx, system)
+	default:
+		fmt.Printf("Unknown bios command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// newBIOSManager returns a BIOS manager using the built-in and systems.yaml requirements.
+func newBIOSManager(conn *sql.DB) *library.BIOSManager {
+	return library.NewBIOSManager(conn, dat.LoadSystemMappings().BIOS)
+}
+
+func scanBIOS(ctx context.Context, dir string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := newBIOSManager(database.Conn()).Scan(ctx, dir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error scanning BIOS directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+
+	fmt.Printf("Scanned BIOS directory: %s\n", result.Dir)
+	fmt.Printf("  Files:   %d\n", result.FilesScanned)
+	fmt.Printf("  Matched: %d\n", result.Matched)
+	fmt.Printf("  Unknown: %d\n", result.Unknown)
+	fmt.Println("\nRun 'romman bios status' to see which systems are ready.")
+}
+
+func showBIOSStatus(ctx context.Context, system string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	requirements := dat.LoadSystemMappings().BIOS
+	var systems []string
+	if system != "" {
+		systems = []string{system}
+	} else {
+		for name := range requirements {
+			systems = append(systems, name)
+		}
+		sort.Strings(systems)
+	}
+
+	bios := library.NewBIOSManager(database.Conn(), requirements)
+	var all []library.BIOSStatus
+	for _, name := range systems {
+		statuses, err := bios.Status(ctx, name)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error getting BIOS status: %v\n", err)
+			os.Exit(1)
+		}
+		all = append(all, statuses...)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(all)
+		return
+	}
+
+	if len(all) == 0 {
+		fmt.Printf("No BIOS files are required for %s\n", system)
+		return
+	}
+
+	for _, name := range systems {
+		var statuses []library.BIOSStatus
+		for _, s := range all {
+			if s.System == name {
+				statuses = append(statuses, s)
+			}
+		}
+		ready := "ready"
+		if !library.BIOSReady(statuses) {
+			ready = "missing BIOS"
+		}
+		fmt.Printf("%s: %s\n", name, ready)
+		for _, s := range statuses {
+			mark := "✗"
+			if s.Present {
+				mark = "✓"
+			}
+			optional := ""
+			if s.Optional {
+				optional = " (optional)"
+			}
+			fmt.Printf("  %s %s%s", mark, s.Name, optional)
+			if s.Present {
+				fmt.Printf("  %s", s.Path)
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/ryanm101/romman-lib/library"
 )
@@ -18,24 +22,74 @@ func handleCleanupCommand(ctx context.Context, args []string) {
 	switch args[0] {
 	case "plan":
 		if len(args) < 3 {
-			fmt.Println("Usage: romman cleanup plan <library> <quarantine-dir>")
+			fmt.Println("Usage: romman cleanup plan <library> <quarantine-dir> [--hardlink] [--summary]")
 			os.Exit(1)
 		}
-		generateCleanupPlan(ctx, args[1], args[2])
+		var opts cleanupPlanOptions
+		for _, arg := range args[3:] {
+			switch arg {
+			case "--hardlink":
+				opts.hardlink = true
+			case "--summary":
+				opts.summary = true
+			}
+		}
+		generateCleanupPlan(ctx, args[1], args[2], opts)
 	case "exec":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman cleanup exec <plan-file> [--dry-run]")
+			fmt.Println("Usage: romman cleanup exec <plan-file> [--dry-run] [--resume] [--stop-on-error]")
+			os.Exit(1)
+		}
+		var opts library.ExecuteOptions
+		for _, arg := range args[2:] {
+			switch arg {
+			case "--dry-run":
+				opts.DryRun = true
+			case "--resume":
+				opts.Resume = true
+			case "--stop-on-error":
+				opts.StopOnError = true
+			}
+		}
+		executeCleanupPlan(ctx, args[1], opts)
+	case "undo":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman cleanup undo <manifest> [--dry-run]")
 			os.Exit(1)
 		}
-		dryRun := len(args) > 2 && args[2] == "--dry-run"
-		executeCleanupPlan(ctx, args[1], dryRun)
+		dryRun := false
+		for _, arg := range args[2:] {
+			if arg == "--dry-run" {
+				dryRun = true
+			}
+		}
+		undoCleanup(args[1], dryRun)
 	default:
 		fmt.Printf("Unknown cleanup command: %s\n", args[0])
 		os.Exit(1)
 	}
 }
 
-func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string) {
+// cleanupPlanOptions selects how generateCleanupPlan deals with duplicates.
+type cleanupPlanOptions struct {
+	hardlink    bool // Hardlink exact duplicates instead of quarantining them
+	interactive bool // Ask which copy of each duplicate group to keep
+	summary     bool // Report the plan grouped by reason rather than file by file
+}
+
+// cleanupPlanSummary is the --json --summary output of a cleanup plan.
+type cleanupPlanSummary struct {
+	PlanFile      string               `json:"plan_file"`
+	LibraryName   string               `json:"library_name"`
+	QuarantineDir string               `json:"quarantine_dir"`
+	Summary       library.PlanSummary  `json:"summary"`
+	Rollup        []library.PlanRollup `json:"rollup"`
+}
+
+func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string, opts cleanupPlanOptions) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -45,7 +99,13 @@ func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string)
 
 	manager := library.NewManager(database.Conn())
 	finder := library.NewDuplicateFinder(database.Conn())
+	finder.Scoring = copyScoring()
 	planner := library.NewCleanupPlanner(finder, manager)
+	planner.Hardlink = opts.hardlink
+	finish := showDuplicateProgress(finder)
+	if opts.interactive {
+		planner.ChooseKeeper = keeperPrompt(ctx, finish)
+	}
 
 	absQuarantine, err := filepath.Abs(quarantineDir)
 	if err != nil {
@@ -53,8 +113,13 @@ func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string)
 		os.Exit(1)
 	}
 
-	plan, err := planner.GeneratePlan(context.Background(), libraryName, absQuarantine)
+	plan, err := planner.GeneratePlan(ctx, libraryName, absQuarantine)
+	finish()
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			_, _ = fmt.Fprintln(os.Stderr, "Cleanup planning interrupted; no plan was written.")
+			os.Exit(130)
+		}
 		_, _ = fmt.Fprintf(os.Stderr, "Error generating plan: %v\n", err)
 		os.Exit(1)
 	}
@@ -66,6 +131,16 @@ func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string)
 	}
 
 	if outputCfg.JSON {
+		if opts.summary {
+			PrintResult(cleanupPlanSummary{
+				PlanFile:      planFile,
+				LibraryName:   plan.LibraryName,
+				QuarantineDir: plan.QuarantineDir,
+				Summary:       plan.Summary,
+				Rollup:        library.RollupPlan(plan),
+			})
+			return
+		}
 		PrintResult(plan)
 		return
 	}
@@ -78,11 +153,45 @@ func generateCleanupPlan(ctx context.Context, libraryName, quarantineDir string)
 	fmt.Printf("  Keep (ignore): %d\n", plan.Summary.IgnoreCount)
 	fmt.Printf("  Move to quarantine: %d\n", plan.Summary.MoveCount)
 	fmt.Printf("  Space to reclaim: %.2f MB\n", float64(plan.Summary.SpaceReclaimed)/1024/1024)
+	if opts.hardlink {
+		fmt.Printf("  Replace with hardlinks: %d\n", plan.Summary.HardlinkCount)
+		fmt.Printf("  Space saved by hardlinks: %.2f MB\n", float64(plan.Summary.HardlinkSaved)/1024/1024)
+	}
+	if opts.summary {
+		printPlanRollup(library.RollupPlan(plan))
+	}
 	fmt.Println()
 	fmt.Printf("To execute: romman cleanup exec %s [--dry-run]\n", planFile)
 }
 
-func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
+// printPlanRollup prints a plan's actions grouped by reason, e.g.
+// "142 duplicate of preferred (exact) -> quarantine, 4.2 GiB".
+func printPlanRollup(rollups []library.PlanRollup) {
+	if len(rollups) == 0 {
+		return
+	}
+	outcomes := map[library.ActionType]string{
+		library.ActionMove:     "quarantine",
+		library.ActionHardlink: "hardlink",
+		library.ActionDelete:   "delete",
+		library.ActionIgnore:   "keep",
+	}
+
+	fmt.Printf("\nBy reason:\n")
+	for _, r := range rollups {
+		reason := r.Reason
+		if r.DupType != "" && !strings.Contains(reason, r.DupType) {
+			reason += " (" + r.DupType + ")"
+		}
+		line := fmt.Sprintf("  %6d %s -> %s", r.Count, reason, outcomes[r.Action])
+		if r.Action != library.ActionIgnore {
+			line += ", " + library.FormatBytes(r.Bytes)
+		}
+		fmt.Println(line)
+	}
+}
+
+func executeCleanupPlan(ctx context.Context, planFile string, opts library.ExecuteOptions) {
 	_ = ctx // May be used for operations in future
 	plan, err := library.LoadPlan(planFile)
 	if err != nil {
@@ -90,6 +199,7 @@ func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
 		os.Exit(1)
 	}
 
+	dryRun := opts.DryRun
 	mode := "LIVE"
 	if dryRun {
 		mode = "DRY-RUN"
@@ -100,7 +210,11 @@ func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
 	fmt.Printf("Actions: %d\n\n", plan.Summary.TotalActions)
 
 	if !dryRun && !outputCfg.JSON && !outputCfg.Quiet {
-		fmt.Print("This will move files to quarantine. Continue? [y/N] ")
+		if plan.Summary.HardlinkCount > 0 {
+			fmt.Print("This will move files to quarantine and replace duplicates with hardlinks. Continue? [y/N] ")
+		} else {
+			fmt.Print("This will move files to quarantine. Continue? [y/N] ")
+		}
 		var response string
 		_, _ = fmt.Scanln(&response)
 		if response != "y" && response != "Y" {
@@ -109,7 +223,9 @@ func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
 		}
 	}
 
-	result, err := library.ExecutePlan(plan, dryRun)
+	opts.PlanPath = planFile
+	opts.ManifestPath = undoManifestPath(planFile)
+	result, err := library.ExecutePlanWithOptions(plan, opts)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error executing plan: %v\n", err)
 		os.Exit(1)
@@ -123,6 +239,12 @@ func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
 	fmt.Printf("\nResults:\n")
 	fmt.Printf("  Succeeded: %d\n", result.Succeeded)
 	fmt.Printf("  Failed: %d\n", result.Failed)
+	if result.Skipped > 0 {
+		fmt.Printf("  Skipped (already done): %d\n", result.Skipped)
+	}
+	if result.Pending > 0 {
+		fmt.Printf("  Not attempted: %d\n", result.Pending)
+	}
 
 	if len(result.Errors) > 0 {
 		fmt.Println("\nErrors:")
@@ -133,5 +255,79 @@ func executeCleanupPlan(ctx context.Context, planFile string, dryRun bool) {
 
 	if dryRun {
 		fmt.Println("\n(Dry run - no files were modified)")
+		return
+	}
+	if result.Failed > 0 || result.Pending > 0 {
+		fmt.Printf("\nProgress was saved to the plan. Retry the rest with: romman cleanup exec %s --resume\n", planFile)
+	}
+	if plan.Summary.MoveCount > 0 {
+		fmt.Printf("\nUndo the quarantine moves with: romman cleanup undo %s\n", opts.ManifestPath)
+	}
+}
+
+// undoManifestPath is where cleanup exec writes a plan's undo manifest.
+func undoManifestPath(planFile string) string {
+	return strings.TrimSuffix(planFile, filepath.Ext(planFile)) + ".undo.json"
+}
+
+func undoCleanup(manifestFile string, dryRun bool) {
+	manifest, err := library.LoadUndoManifest(manifestFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error loading undo manifest: %v\n", err)
+		os.Exit(1)
+	}
+
+	mode := "LIVE"
+	if dryRun {
+		mode = "DRY-RUN"
+	}
+	fmt.Printf("Undoing cleanup (%s): %s\n\n", mode, manifestFile)
+	fmt.Printf("Library: %s\n", manifest.LibraryName)
+	fmt.Printf("Plan created: %s\n", manifest.PlanCreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Executed: %s\n", manifest.ExecutedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Files to restore: %d\n\n", len(manifest.Moves))
+
+	if !dryRun && !outputCfg.JSON && !outputCfg.Quiet {
+		fmt.Print("This will move files from quarantine back to their original paths. Continue? [y/N] ")
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+
+	result := library.UndoCleanup(manifest, dryRun)
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+
+	fmt.Printf("\nResults:\n")
+	fmt.Printf("  Restored: %d\n", result.Restored)
+	if result.AlreadyRestored > 0 {
+		fmt.Printf("  Already restored: %d\n", result.AlreadyRestored)
+	}
+	fmt.Printf("  Conflicts: %d\n", len(result.Conflicts))
+	fmt.Printf("  Failed: %d\n", len(result.Errors))
+
+	if len(result.Conflicts) > 0 {
+		fmt.Println("\nLeft in quarantine, the original path is taken:")
+		for _, m := range result.Conflicts {
+			fmt.Printf("  %s -> %s\n", m.QuarantinePath, m.OriginalPath)
+		}
+	}
+	if len(result.Errors) > 0 {
+		fmt.Println("\nErrors:")
+		for _, e := range result.Errors {
+			fmt.Printf("  %s: %s\n", e.Move.QuarantinePath, e.Error)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("\n(Dry run - no files were modified)")
+	} else if result.Restored > 0 {
+		fmt.Printf("\nRescan the library to pick up the restored files: romman library scan %s\n", manifest.LibraryName)
 	}
 }
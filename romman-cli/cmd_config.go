@@ -55,8 +55,14 @@ func initConfig() {
 	}
 
 	example := `# ROM Manager Configuration
+db_path: romman.db
+
+# Connection pool (0 = auto). Defaults suit the CLI; for the long-running
+# web server try max_open_conns: 8, max_idle_conns: 8, conn_max_lifetime: 30m
 db:
-  path: romman.db
+  max_open_conns: 0
+  max_idle_conns: 0
+  conn_max_lifetime: 0s
 
 # Directory containing DAT files
 dat_dir: dat
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/library"
 )
 
 func handleDatCommand(ctx context.Context, args []string) {
@@ -18,12 +21,38 @@ func handleDatCommand(ctx context.Context, args []string) {
 	switch args[0] {
 	case "import":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman dat import <file>")
+			fmt.Println("Usage: romman dat import <file|url>")
 			os.Exit(1)
 		}
 		importDat(ctx, args[1])
+	case "update":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman dat update <file|url> [--prune]")
+			os.Exit(1)
+		}
+		prune := len(args) >= 3 && args[2] == "--prune"
+		updateDat(ctx, args[1], prune)
 	case "scan":
 		scanDatDir(ctx)
+	case "link":
+		system := ""
+		if len(args) >= 2 {
+			system = args[1]
+		}
+		linkDatClones(ctx, system)
+	case "sources":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman dat sources <system>")
+			os.Exit(1)
+		}
+		listDatSources(ctx, args[1])
+	case "remove-source":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman dat remove-source <system> <type|priority> [--yes]")
+			os.Exit(1)
+		}
+		yes := len(args) >= 4 && args[3] == "--yes"
+		removeDatSource(ctx, args[1], args[2], yes)
 	default:
 		fmt.Printf("Unknown dat command: %s\n", args[0])
 		os.Exit(1)
@@ -43,23 +72,31 @@ func importDat(ctx context.Context, inputPath string) {
 	paths := []string{inputPath}
 	results := make([]*dat.ImportResult, 0, len(paths))
 	for _, path := range paths {
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "Error resolving path %s: %v\n", path, err)
-			continue
-		}
-
 		if !outputCfg.Quiet && !outputCfg.JSON {
 			fmt.Printf("Importing %s...\n", filepath.Base(path))
 		}
-		result, err := importer.Import(ctx, absPath)
+
+		var result *dat.ImportResult
+		if dat.IsURL(path) {
+			result, err = importer.ImportURL(ctx, path, cfg.DatTimeout)
+		} else {
+			var absPath string
+			absPath, err = filepath.Abs(path)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Error resolving path %s: %v\n", path, err)
+				continue
+			}
+			result, err = importer.Import(ctx, absPath)
+		}
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
 			continue
 		}
 		results = append(results, result)
 
-		if !outputCfg.Quiet && !outputCfg.JSON {
+		if result.Skipped && !outputCfg.Quiet && !outputCfg.JSON {
+			fmt.Printf("  System: %s (unchanged since last import, skipped)\n", result.SystemName)
+		} else if !outputCfg.Quiet && !outputCfg.JSON {
 			status := "updated"
 			if result.IsNewSystem {
 				status = "created"
@@ -67,12 +104,113 @@ func importDat(ctx context.Context, inputPath string) {
 			fmt.Printf("  System: %s (%s)\n", result.SystemName, status)
 			fmt.Printf("  Games imported: %d, ROMs: %d, Skipped: %d\n",
 				result.GamesImported, result.RomsImported, result.GamesSkipped)
+			if result.HashConflicts > 0 {
+				fmt.Printf("  Hash conflicts: %d (see: romman systems conflicts %s)\n",
+					result.HashConflicts, result.SystemName)
+			}
+		}
+	}
+
+	if outputCfg.JSON {
+		PrintResult(results)
+	}
+}
+
+// updateDat re-imports a changed DAT and reports the releases it added,
+// updated and dropped. With prune the dropped releases are deleted and the
+// libraries that had files matched to them are rematched.
+func updateDat(ctx context.Context, source string, prune bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	if !dat.IsURL(source) {
+		if source, err = filepath.Abs(source); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error resolving path: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	result, err := dat.NewImporter(database.Conn()).Update(ctx, source, dat.UpdateOptions{
+		Prune:   prune,
+		Timeout: cfg.DatTimeout,
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error updating DAT: %v\n", err)
+		os.Exit(1)
+	}
+
+	chatty := !outputCfg.Quiet && !outputCfg.JSON
+	if chatty {
+		if result.Skipped {
+			fmt.Printf("System: %s (unchanged since last import, skipped)\n", result.SystemName)
+		} else {
+			fmt.Printf("System: %s\n", result.SystemName)
+			fmt.Printf("Releases added: %d, updated: %d, removed: %d\n",
+				result.GamesImported, result.GamesUpdated, result.ReleasesRemoved)
+			if !prune && result.StaleReleases > 0 {
+				fmt.Printf("%d releases are no longer in the DAT; re-run with --prune to remove them\n", result.StaleReleases)
+			}
 		}
 	}
 
+	for _, name := range result.AffectedLibraries {
+		if chatty {
+			fmt.Printf("Rematching library: %s\n", name)
+		}
+		if err := rematchLibrary(ctx, database.Conn(), name); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error rematching %s: %v (run: romman library scan %s --full)\n", name, err, name)
+		}
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+	}
+}
+
+// rematchLibrary scans a library matching every file again, so files
+// matched to releases a DAT update removed can match their replacements.
+func rematchLibrary(ctx context.Context, conn *sql.DB, name string) error {
+	// A rematch only re-reads the library, so no sample is rehashed
+	scanCfg := scanConfigFromCfg()
+	scanCfg.SampleVerifyPercent = 0
+	scanner := library.NewScannerWithConfig(conn, scanCfg)
+	_, err := scanner.Scan(ctx, name)
+	return err
+}
+
+// linkDatClones links clones to their parents for one system, or for every
+// system when system is empty, without needing a library.
+func linkDatClones(ctx context.Context, system string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	results, err := dat.LinkSystemClones(database.Conn(), system)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error linking clones: %v\n", err)
+		os.Exit(1)
+	}
+
 	if outputCfg.JSON {
 		PrintResult(results)
+		return
 	}
+	if outputCfg.Quiet {
+		return
+	}
+	var total int64
+	for _, r := range results {
+		fmt.Printf("  %-20s %d clones linked\n", r.System, r.Linked)
+		total += r.Linked
+	}
+	fmt.Printf("Linked %d clones across %d systems\n", total, len(results))
 }
 
 func scanDatDir(ctx context.Context) {
@@ -105,8 +243,9 @@ func scanDatDir(ctx context.Context) {
 		if entry.IsDir() {
 			continue
 		}
-		ext := filepath.Ext(entry.Name())
-		if ext != ".dat" && ext != ".xml" {
+		switch filepath.Ext(entry.Name()) {
+		case ".dat", ".xml", ".gz", ".zip":
+		default:
 			continue
 		}
 
@@ -137,3 +276,89 @@ func scanDatDir(ctx context.Context) {
 		fmt.Printf("\nImported %d DAT files\n", len(results))
 	}
 }
+
+// listDatSources lists a system's DAT sources in priority order.
+func listDatSources(ctx context.Context, system string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	sources, err := dat.ListDATSources(ctx, database.Conn(), system)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(sources)
+		return
+	}
+
+	if len(sources) == 0 {
+		fmt.Printf("No DAT sources for %s.\n", system)
+		return
+	}
+
+	fmt.Printf("DAT sources for %s:\n", system)
+	for _, s := range sources {
+		fmt.Printf("  %d. %-9s %s", s.Priority, s.SourceType, s.DATName)
+		if s.DATVersion != "" {
+			fmt.Printf(" (%s)", s.DATVersion)
+		}
+		fmt.Printf("\n     %d releases, %d only in this source\n", s.Releases, s.Exclusive)
+	}
+}
+
+// removeDatSource removes one of a system's DAT sources, chosen by type or
+// priority, with the releases only it lists. Without yes it only reports
+// what would be removed and exits non-zero.
+func removeDatSource(ctx context.Context, system, which string, yes bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	sources, err := dat.ListDATSources(ctx, database.Conn(), system)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var source *dat.SourceSummary
+	priority, byPriority := strconv.Atoi(which)
+	for i := range sources {
+		if (byPriority == nil && sources[i].Priority == priority) || string(sources[i].SourceType) == which {
+			source = &sources[i]
+			break
+		}
+	}
+	if source == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "No %s DAT source for %s (see 'romman dat sources %s')\n", which, system, system)
+		os.Exit(1)
+	}
+
+	if !yes {
+		fmt.Printf("Removing the %s source of %s would delete %d of its %d releases; the rest are listed by other sources and kept.\n",
+			source.SourceType, system, source.Exclusive, source.Releases)
+		fmt.Println("Re-run with --yes to remove it.")
+		os.Exit(1)
+	}
+
+	result, err := dat.RemoveDATSource(ctx, database.Conn(), source.ID)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error removing DAT source: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+	fmt.Printf("Removed the %s source of %s: %d releases deleted, %d kept\n",
+		source.SourceType, system, result.ReleasesRemoved, result.ReleasesKept)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ryanm101/romman-lib/db"
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleDBCommand(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: romman db <command>")
+		fmt.Println("Commands: vacuum, analyze, stats, backup, restore")
+		os.Exit(1)
+	}
+
+	// Restore never opens the current database, which may be the corrupt
+	// one being replaced
+	if args[0] == "restore" {
+		if len(args) < 2 {
+			fmt.Println("Usage: romman db restore <path>")
+			os.Exit(1)
+		}
+		restoreDB(ctx, args[1])
+		return
+	}
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	switch args[0] {
+	case "vacuum":
+		vacuumDB(ctx, database)
+	case "analyze":
+		if err := database.Analyze(ctx); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if outputCfg.JSON {
+			PrintResult(map[string]string{"status": "ok"})
+			return
+		}
+		PrintInfo("Query planner statistics updated\n")
+	case "stats":
+		showDBStats(ctx, database)
+	case "backup":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman db backup <path>")
+			os.Exit(1)
+		}
+		backupDB(ctx, database, args[1])
+	default:
+		fmt.Printf("Unknown db command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// vacuumDB rebuilds the database and reports the space reclaimed.
+func vacuumDB(ctx context.Context, database *db.DB) {
+	before, err := database.Stats(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	PrintProgress("Vacuuming %s (%s)...\n", before.Path, library.FormatBytes(before.FileSize+before.WALSize))
+
+	if err := database.Vacuum(ctx); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	after, err := database.Stats(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sizeBefore, sizeAfter := before.FileSize+before.WALSize, after.FileSize+after.WALSize
+	if outputCfg.JSON {
+		PrintResult(map[string]int64{
+			"sizeBefore": sizeBefore,
+			"sizeAfter":  sizeAfter,
+			"reclaimed":  sizeBefore - sizeAfter,
+		})
+		return
+	}
+	PrintInfo("Database size: %s -> %s (reclaimed %s)\n",
+		library.FormatBytes(sizeBefore), library.FormatBytes(sizeAfter), library.FormatBytes(max(sizeBefore-sizeAfter, 0)))
+}
+
+// showDBStats prints the database file size and per-table row counts.
+func showDBStats(ctx context.Context, database *db.DB) {
+	st, err := database.Stats(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(st)
+		return
+	}
+
+	fmt.Printf("Database: %s\n", st.Path)
+	fmt.Printf("File size: %s (WAL %s)\n", library.FormatBytes(st.FileSize), library.FormatBytes(st.WALSize))
+	fmt.Printf("Pages: %d of %d bytes, %d free (reclaimable with db vacuum: %s)\n",
+		st.PageCount, st.PageSize, st.FreePages, library.FormatBytes(st.FreePages*st.PageSize))
+	fmt.Println()
+
+	rows := make([][]string, 0, len(st.Tables))
+	for _, tc := range st.Tables {
+		rows = append(rows, []string{tc.Name, fmt.Sprintf("%d", tc.Rows)})
+	}
+	PrintTable([]string{"Table", "Rows"}, rows)
+}
+
+// backupDB snapshots the database to path, or to a timestamped file inside
+// path if it is a directory.
+func backupDB(ctx context.Context, database *db.DB, path string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, fmt.Sprintf("romman-%s.db", time.Now().Format("20060102-150405")))
+	}
+
+	if err := database.Backup(ctx, path); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"path": path,
+			"size": info.Size(),
+		})
+		return
+	}
+	PrintInfo("Backup written to %s (%s)\n", path, library.FormatBytes(info.Size()))
+}
+
+// restoreDB replaces the database with the backup at path.
+func restoreDB(ctx context.Context, path string) {
+	PrintProgress("Restoring %s from %s...\n", getDBPath(), path)
+	if err := db.RestoreFile(ctx, getDBPath(), path); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Opening the restored database migrates an older schema
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening restored database: %v\n", err)
+		os.Exit(1)
+	}
+	_ = database.Close()
+
+	if outputCfg.JSON {
+		PrintResult(map[string]string{"status": "ok", "restored": path})
+		return
+	}
+	PrintInfo("Database restored from %s\n", path)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func dedupeLibrary(ctx context.Context, args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: romman library dedupe <name> <quarantine-dir> [--interactive] [--hardlink] [--summary]")
+		os.Exit(1)
+	}
+
+	var opts cleanupPlanOptions
+	for _, arg := range args[2:] {
+		switch arg {
+		case "--interactive":
+			opts.interactive = true
+		case "--hardlink":
+			opts.hardlink = true
+		case "--summary":
+			opts.summary = true
+		}
+	}
+	if opts.interactive && (outputCfg.JSON || outputCfg.Quiet) {
+		PrintError("Error: --interactive cannot be combined with --json or --quiet\n")
+		os.Exit(1)
+	}
+	generateCleanupPlan(ctx, args[0], args[1], opts)
+}
+
+// keeperPrompt returns a CleanupPlanner.ChooseKeeper that lists each
+// duplicate group's copies and asks which to keep. Enter keeps the copy the
+// scoring preferred, marked with *; "a" does so for every remaining group.
+// finishProgress clears the duplicate search's progress bar before the first
+// question.
+func keeperPrompt(ctx context.Context, finishProgress func()) func(library.Duplicate, int, int) (int, error) {
+	var lines chan string
+	automatic := false
+
+	return func(dup library.Duplicate, n, total int) (int, error) {
+		if automatic {
+			return -1, nil
+		}
+		if lines == nil {
+			finishProgress()
+			lines = make(chan string)
+			go func() {
+				scanner := bufio.NewScanner(os.Stdin)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+				close(lines)
+			}()
+		}
+
+		fmt.Printf("\n[%d/%d] %s duplicate", n, total, dup.Type)
+		if dup.Title != "" {
+			fmt.Printf(" - %s", dup.Title)
+		}
+		if len(dup.Hash) >= 8 {
+			fmt.Printf(" (SHA1: %s...)", dup.Hash[:8])
+		}
+		fmt.Println()
+		for i, file := range dup.Files {
+			marker := " "
+			if file.IsPreferred {
+				marker = "*"
+			}
+			path := file.Path
+			if file.ArchivePath != "" {
+				path += ":" + file.ArchivePath
+			}
+			var notes []string
+			for _, note := range []string{file.MatchType, file.Flags, file.Tags} {
+				if note != "" {
+					notes = append(notes, note)
+				}
+			}
+			extra := ""
+			if len(notes) > 0 {
+				extra = fmt.Sprintf(" [%s]", strings.Join(notes, "; "))
+			}
+			fmt.Printf("  %d) %s %s%s\n", i+1, marker, path, extra)
+		}
+
+		for {
+			fmt.Printf("Keep which copy? [1-%d, Enter = *, a = * for all remaining] ", len(dup.Files))
+			var answer string
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					// Input ended: keep the automatic choices from here on
+					fmt.Println()
+					automatic = true
+					return -1, nil
+				}
+				answer = strings.TrimSpace(line)
+			case <-ctx.Done():
+				fmt.Println()
+				return -1, ctx.Err()
+			}
+
+			switch strings.ToLower(answer) {
+			case "":
+				return -1, nil
+			case "a":
+				automatic = true
+				return -1, nil
+			}
+			if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(dup.Files) {
+				return i - 1, nil
+			}
+			fmt.Printf("Enter a number from 1 to %d.\n", len(dup.Files))
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+
+	"github.com/ryanm101/romman-lib/library"
 )
 
 func handleDoctorCommand(ctx context.Context, args []string) {
@@ -95,6 +97,56 @@ func handleDoctorCommand(ctx context.Context, args []string) {
 	// Ignore error for this check (might return nothing)
 	checks = append(checks, systemCheck)
 
+	// Check 5: BIOS for systems that have libraries, from the shared BIOS scan
+	biosCheck := map[string]interface{}{
+		"name":   "bios",
+		"status": "pass",
+	}
+	var missingBIOS []string
+	bios := newBIOSManager(database.Conn())
+	systemRows, err := database.Conn().Query(`
+		SELECT DISTINCT s.name FROM systems s JOIN libraries l ON l.system_id = s.id ORDER BY s.name
+	`)
+	if err == nil {
+		var systems []string
+		for systemRows.Next() {
+			var name string
+			if err := systemRows.Scan(&name); err == nil {
+				systems = append(systems, name)
+			}
+		}
+		_ = systemRows.Close()
+
+		for _, name := range systems {
+			ready, required, err := bios.Ready(ctx, name)
+			if err == nil && required && !ready {
+				missingBIOS = append(missingBIOS, name)
+			}
+		}
+	}
+	if len(missingBIOS) > 0 {
+		biosCheck["status"] = "warn"
+		biosCheck["missing"] = missingBIOS
+		issues = append(issues, fmt.Sprintf("Systems missing BIOS files: %v (run: romman bios scan <dir>)", missingBIOS))
+	}
+	checks = append(checks, biosCheck)
+
+	// Check 6: libraries whose system has no DAT to match against
+	stubCheck := map[string]interface{}{
+		"name":   "stub_systems",
+		"status": "pass",
+	}
+	if stubs, err := library.GetStubSystems(ctx, database.Conn()); err == nil && len(stubs) > 0 {
+		var names []string
+		for _, st := range stubs {
+			names = append(names, st.Name)
+		}
+		stubCheck["status"] = "warn"
+		stubCheck["systems"] = names
+		issues = append(issues, fmt.Sprintf("Systems with libraries but no DAT: %v (run: romman systems stubs)", names))
+	}
+	checks = append(checks, stubCheck)
+
 	result := map[string]interface{}{
 		"checks": checks,
 		"issues": len(issues),
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/ryanm101/romman-lib/library"
+	"github.com/schollz/progressbar/v3"
 )
 
 func handleDuplicatesCommand(ctx context.Context, args []string) {
@@ -29,6 +33,9 @@ func handleDuplicatesCommand(ctx context.Context, args []string) {
 }
 
 func listDuplicates(ctx context.Context, libName string) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -37,15 +44,22 @@ func listDuplicates(ctx context.Context, libName string) {
 	defer func() { _ = database.Close() }()
 
 	manager := library.NewManager(database.Conn())
-	lib, err := manager.Get(context.Background(), libName)
+	lib, err := manager.Get(ctx, libName)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	finder := library.NewDuplicateFinder(database.Conn())
-	duplicates, err := finder.FindAllDuplicates(context.Background(), lib.ID)
+	finder.Scoring = copyScoring()
+	finish := showDuplicateProgress(finder)
+	duplicates, err := finder.FindAllDuplicates(ctx, lib.ID)
+	finish()
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			_, _ = fmt.Fprintln(os.Stderr, "Duplicate search interrupted.")
+			os.Exit(130)
+		}
 		_, _ = fmt.Fprintf(os.Stderr, "Error finding duplicates: %v\n", err)
 		os.Exit(1)
 	}
@@ -81,3 +95,54 @@ func listDuplicates(ctx context.Context, libName string) {
 		fmt.Println()
 	}
 }
+
+// copyScoring returns the duplicate scoring weights from config, falling
+// back to the built-in defaults for unset values.
+func copyScoring() library.CopyScoring {
+	scoring := library.DefaultCopyScoring()
+	if cfg == nil {
+		return scoring
+	}
+	d := cfg.Duplicates
+	for matchType, points := range d.MatchScores {
+		scoring.MatchScores[matchType] = points
+	}
+	if d.FlagPenalty != nil {
+		scoring.FlagPenalty = *d.FlagPenalty
+	}
+	scoring.PreferArchive = d.PreferArchive
+	scoring.PreferredPathPrefix = d.PreferredPathPrefix
+	if d.PathPrefixBonus != nil {
+		scoring.PathPrefixBonus = *d.PathPrefixBonus
+	}
+	if d.PathDepthWeight != nil {
+		scoring.PathDepthWeight = *d.PathDepthWeight
+	}
+	return scoring
+}
+
+// showDuplicateProgress shows a progress bar per duplicate pass. The returned
+// function clears the bar once the search is done.
+func showDuplicateProgress(finder *library.DuplicateFinder) func() {
+	if outputCfg.Quiet || outputCfg.JSON {
+		return func() {}
+	}
+
+	var bar *progressbar.ProgressBar
+	var phase library.DuplicateType
+	finder.OnProgress = func(p library.DuplicateProgress) {
+		if bar == nil || p.Phase != phase {
+			if bar != nil {
+				_ = bar.Finish()
+			}
+			phase = p.Phase
+			bar = progressbar.Default(int64(p.GroupsTotal), fmt.Sprintf("Checking %s duplicates", p.Phase))
+		}
+		_ = bar.Set(p.GroupsProcessed)
+	}
+	return func() {
+		if bar != nil {
+			_ = bar.Finish()
+		}
+	}
+}
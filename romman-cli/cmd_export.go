@@ -4,15 +4,39 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/ryanm101/romman-lib/library"
 )
 
+// printExportUsage lists every export target.
+func printExportUsage() {
+	fmt.Println("Usage: romman export <library> <report> <format> [file] [--verified-only] [--fallback] [--playable-only] [--exclude-flagged]")
+	fmt.Println("       romman export <library> retroarch <output.lpl> [--preferred] [--regions=USA,Europe] [--exclude-flagged]")
+	fmt.Println("       romman export <library> gamelist [output.xml] [--matched-only] [--path-prefix=<prefix>] [--image-dir=<dir>]")
+	fmt.Println("       romman export <library> launchbox [output.xml] [--matched-only] [--path-prefix=<prefix>]")
+	fmt.Println("       romman export <library> pegasus [metadata.pegasus.txt] [--path-prefix=<prefix>]")
+	fmt.Println("       romman export <library> esde <collection> [dir] [--preferred] [--rom-path=<dir>]")
+	fmt.Println("       romman export <library> fixdat <output.dat>")
+	fmt.Println("       romman export <library> have|missing dat [file] [--exclude-flagged]")
+	fmt.Println("Reports: matched, missing, preferred, unmatched, 1g1r, stats, duplicates, mismatch, flagged")
+	fmt.Println("Formats: csv, tsv, json, txt")
+	fmt.Println("--exclude-flagged leaves out bad-dump, overdump, cracked, fixed, hack, pirate, trainer and translated matches.")
+	fmt.Println("Without a file, gamelist, launchbox, pegasus, esde, report and DAT exports are written to stdout.")
+}
+
+// exitUnknownExportFlag rejects a flag no export accepts, so that a typo is
+// not taken for the output file name.
+func exitUnknownExportFlag(arg string) {
+	_, _ = fmt.Fprintf(os.Stderr, "Unknown flag: %s\n", arg)
+	printExportUsage()
+	os.Exit(1)
+}
+
 func handleExportCommand(ctx context.Context, args []string) {
-	if len(args) < 3 {
-		fmt.Println("Usage: romman export <library> <report> <format> [file]")
-		fmt.Println("       romman export <library> retroarch <output.lpl>")
-		fmt.Println("       romman export <library> gamelist <output.xml> [--matched-only]")
+	if len(args) < 2 {
+		printExportUsage()
 		os.Exit(1)
 	}
 
@@ -21,70 +45,171 @@ func handleExportCommand(ctx context.Context, args []string) {
 
 	if reportOrFormat == "retroarch" {
 		if len(args) < 3 {
-			fmt.Println("Usage: romman export <library> retroarch <output.lpl>")
+			fmt.Println("Usage: romman export <library> retroarch <output.lpl> [--preferred] [--regions=USA,Europe] [--exclude-flagged]")
 			os.Exit(1)
 		}
 		outputPath := args[2]
-		exportRetroArch(ctx, libName, outputPath)
+		var opts library.PlaylistOptions
+		for _, flag := range args[3:] {
+			switch {
+			case flag == "--preferred":
+				opts.PreferredOnly = true
+			case flag == "--exclude-flagged":
+				opts.ExcludeFlagged = true
+			case strings.HasPrefix(flag, "--regions="):
+				for _, region := range strings.Split(strings.TrimPrefix(flag, "--regions="), ",") {
+					if region = strings.TrimSpace(region); region != "" {
+						opts.Regions = append(opts.Regions, region)
+					}
+				}
+			}
+		}
+		exportRetroArch(ctx, libName, outputPath, opts)
 		return
 	}
 
 	if reportOrFormat == "gamelist" {
+		outputPath := ""
+		opts := library.GamelistOptions{PathPrefix: "./"}
+		for _, arg := range args[2:] {
+			switch {
+			case arg == "--matched-only":
+				opts.MatchedOnly = true
+			case strings.HasPrefix(arg, "--path-prefix="):
+				opts.PathPrefix = strings.TrimPrefix(arg, "--path-prefix=")
+			case strings.HasPrefix(arg, "--image-dir="):
+				opts.ImageDir = strings.TrimPrefix(arg, "--image-dir=")
+			case strings.HasPrefix(arg, "--"):
+				exitUnknownExportFlag(arg)
+			case outputPath == "":
+				outputPath = arg
+			}
+		}
+		exportGamelist(ctx, libName, outputPath, opts)
+		return
+	}
+
+	if reportOrFormat == "launchbox" {
+		outputPath := ""
+		opts := library.LaunchBoxOptions{PathPrefix: ".\\"}
+		for _, arg := range args[2:] {
+			switch {
+			case arg == "--matched-only":
+				opts.MatchedOnly = true
+			case strings.HasPrefix(arg, "--path-prefix="):
+				opts.PathPrefix = strings.TrimPrefix(arg, "--path-prefix=")
+			case strings.HasPrefix(arg, "--"):
+				exitUnknownExportFlag(arg)
+			case outputPath == "":
+				outputPath = arg
+			}
+		}
+		exportLaunchBox(ctx, libName, outputPath, opts)
+		return
+	}
+
+	if reportOrFormat == "pegasus" {
+		outputPath := ""
+		opts := library.PegasusOptions{PathPrefix: "./"}
+		for _, arg := range args[2:] {
+			switch {
+			case strings.HasPrefix(arg, "--path-prefix="):
+				opts.PathPrefix = strings.TrimPrefix(arg, "--path-prefix=")
+			case strings.HasPrefix(arg, "--"):
+				exitUnknownExportFlag(arg)
+			case outputPath == "":
+				outputPath = arg
+			}
+		}
+		exportPegasus(ctx, libName, outputPath, opts)
+		return
+	}
+
+	if reportOrFormat == "esde" {
 		if len(args) < 3 {
-			fmt.Println("Usage: romman export <library> gamelist <output.xml> [--matched-only]")
+			fmt.Println("Usage: romman export <library> esde <collection> [dir] [--preferred] [--rom-path=<dir>]")
 			os.Exit(1)
 		}
-		outputPath := args[2]
-		matchedOnly := len(args) > 3 && args[3] == "--matched-only"
-		exportGamelist(ctx, libName, outputPath, matchedOnly)
+		outputDir := ""
+		var opts library.ESDEOptions
+		for _, arg := range args[3:] {
+			switch {
+			case arg == "--preferred":
+				opts.PreferredOnly = true
+			case strings.HasPrefix(arg, "--rom-path="):
+				opts.ROMPath = strings.TrimPrefix(arg, "--rom-path=")
+			case strings.HasPrefix(arg, "--"):
+				exitUnknownExportFlag(arg)
+			case outputDir == "":
+				outputDir = arg
+			}
+		}
+		exportESDECollection(ctx, libName, args[2], outputDir, opts)
 		return
 	}
 
-	if reportOrFormat == "launchbox" {
+	if reportOrFormat == "fixdat" {
 		if len(args) < 3 {
-			fmt.Println("Usage: romman export <library> launchbox <output.xml> [--matched-only]")
+			fmt.Println("Usage: romman export <library> fixdat <output.dat>")
 			os.Exit(1)
 		}
-		outputPath := args[2]
-		matchedOnly := len(args) > 3 && args[3] == "--matched-only"
-		exportLaunchBox(ctx, libName, outputPath, matchedOnly)
+		exportFixDAT(ctx, libName, args[2])
 		return
 	}
 
 	// Generic report export
 	if len(args) < 3 {
-		fmt.Println("Usage: romman export <library> <report> <format> [file]")
+		printExportUsage()
 		os.Exit(1)
 	}
 
 	report := args[1]
 	format := args[2]
 	output := ""
-	if len(args) >= 4 {
-		output = args[3]
+	var opts library.ExportOptions
+	for _, arg := range args[3:] {
+		switch {
+		case arg == "--verified-only":
+			opts.VerifiedOnly = true
+		case arg == "--playable-only":
+			opts.PlayableOnly = true
+		case arg == "--exclude-flagged":
+			opts.ExcludeFlagged = true
+		case arg == "--fallback":
+			opts.Fallback = true
+			opts.Preferences = preferenceConfig()
+		case strings.HasPrefix(arg, "--"):
+			exitUnknownExportFlag(arg)
+		case output == "":
+			output = arg
+		}
+	}
+	if format == "dat" {
+		exportDATReport(ctx, libName, report, output, opts)
+		return
 	}
-	exportReport(ctx, libName, report, format, output)
+	exportReport(ctx, libName, report, format, output, opts)
 }
 
-func exportReport(ctx context.Context, libName, report, format, output string) {
+func exportReport(ctx context.Context, libName, report, format, output string, opts library.ExportOptions) {
 	reportType := library.ReportType(report)
 	exportFormat := library.ExportFormat(format)
 
 	switch reportType {
-	case library.ReportMatched, library.ReportMissing, library.ReportPreferred, library.ReportUnmatched, library.Report1G1R, library.ReportStats, library.ReportDuplicates, library.ReportMismatch:
+	case library.ReportMatched, library.ReportMissing, library.ReportPreferred, library.ReportUnmatched, library.Report1G1R, library.ReportStats, library.ReportDuplicates, library.ReportMismatch, library.ReportFlagged:
 		// valid
 	default:
 		_, _ = fmt.Fprintf(os.Stderr, "Unknown report type: %s\n", report)
-		fmt.Println("Valid reports: matched, missing, preferred, unmatched, 1g1r, stats, duplicates, mismatch")
+		fmt.Println("Valid reports: matched, missing, preferred, unmatched, 1g1r, stats, duplicates, mismatch, flagged")
 		os.Exit(1)
 	}
 
 	switch exportFormat {
-	case library.FormatCSV, library.FormatJSON, library.FormatTXT:
+	case library.FormatCSV, library.FormatTSV, library.FormatJSON, library.FormatTXT:
 		// valid
 	default:
 		_, _ = fmt.Fprintf(os.Stderr, "Unknown format: %s\n", format)
-		fmt.Println("Valid formats: csv, json, txt")
+		fmt.Println("Valid formats: csv, tsv, json, txt")
 		os.Exit(1)
 	}
 
@@ -98,7 +223,7 @@ func exportReport(ctx context.Context, libName, report, format, output string) {
 	manager := library.NewManager(database.Conn())
 	exporter := library.NewExporter(database.Conn(), manager)
 
-	data, err := exporter.Export(context.Background(), libName, reportType, exportFormat)
+	data, err := exporter.ExportWithOptions(context.Background(), libName, reportType, exportFormat, opts)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error exporting: %v\n", err)
 		os.Exit(1)
@@ -139,7 +264,7 @@ func exportReport(ctx context.Context, libName, report, format, output string) {
 	}
 }
 
-func exportRetroArch(ctx context.Context, libraryName, outputPath string) {
+func exportRetroArch(ctx context.Context, libraryName, outputPath string, opts library.PlaylistOptions) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -148,7 +273,7 @@ func exportRetroArch(ctx context.Context, libraryName, outputPath string) {
 	defer func() { _ = database.Close() }()
 
 	exporter := library.NewRetroArchExporter(database.Conn())
-	if err := exporter.ExportPlaylist(context.Background(), libraryName, outputPath); err != nil {
+	if err := exporter.ExportPlaylist(context.Background(), libraryName, outputPath, opts); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error exporting RetroArch playlist: %v\n", err)
 		os.Exit(1)
 	}
@@ -165,7 +290,9 @@ func exportRetroArch(ctx context.Context, libraryName, outputPath string) {
 	}
 }
 
-func exportGamelist(ctx context.Context, libraryName, outputPath string, matchedOnly bool) {
+// exportGamelist writes an EmulationStation gamelist.xml to outputPath, or to
+// stdout when outputPath is empty.
+func exportGamelist(ctx context.Context, libraryName, outputPath string, opts library.GamelistOptions) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -176,17 +303,17 @@ func exportGamelist(ctx context.Context, libraryName, outputPath string, matched
 	manager := library.NewManager(database.Conn())
 	exporter := library.NewExporter(database.Conn(), manager)
 
-	opts := library.GamelistOptions{
-		MatchedOnly: matchedOnly,
-		PathPrefix:  "./",
-	}
-
 	data, err := exporter.ExportGamelist(context.Background(), libraryName, opts)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error exporting gamelist: %v\n", err)
 		os.Exit(1)
 	}
 
+	if outputPath == "" {
+		fmt.Println(string(data))
+		return
+	}
+
 	// #nosec G306
 	if err := os.WriteFile(outputPath, data, 0644); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
@@ -198,7 +325,7 @@ func exportGamelist(ctx context.Context, libraryName, outputPath string, matched
 			"library":     libraryName,
 			"format":      "gamelist",
 			"output":      outputPath,
-			"matchedOnly": matchedOnly,
+			"matchedOnly": opts.MatchedOnly,
 			"status":      "success",
 		})
 	} else {
@@ -206,7 +333,7 @@ func exportGamelist(ctx context.Context, libraryName, outputPath string, matched
 	}
 }
 
-func exportLaunchBox(ctx context.Context, libraryName, outputPath string, matchedOnly bool) {
+func exportFixDAT(ctx context.Context, libraryName, outputPath string) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -217,17 +344,123 @@ func exportLaunchBox(ctx context.Context, libraryName, outputPath string, matche
 	manager := library.NewManager(database.Conn())
 	exporter := library.NewExporter(database.Conn(), manager)
 
-	opts := library.LaunchBoxOptions{
-		MatchedOnly: matchedOnly,
-		PathPrefix:  ".\\",
+	fixdat, err := exporter.ExportFixDAT(ctx, libraryName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error exporting fixdat: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := fixdat.Marshal()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error encoding fixdat: %v\n", err)
+		os.Exit(1)
 	}
 
+	// #nosec G306
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"library": libraryName,
+			"format":  "fixdat",
+			"output":  outputPath,
+			"games":   len(fixdat.Games),
+			"roms":    fixdat.ROMCount(),
+			"status":  "success",
+		})
+	} else {
+		fmt.Printf("Exported fixdat with %d missing ROMs in %d games to %s\n", fixdat.ROMCount(), len(fixdat.Games), outputPath)
+	}
+}
+
+// exportDATReport writes a Logiqx DAT report to outputPath, or to stdout
+// when outputPath is empty.
+func exportDATReport(ctx context.Context, libraryName, report, outputPath string, opts library.ExportOptions) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	manager := library.NewManager(database.Conn())
+	exporter := library.NewExporter(database.Conn(), manager)
+
+	var logiqx *library.LogiqxDAT
+	switch report {
+	case "have":
+		logiqx, err = exporter.ExportDAT(ctx, libraryName, opts)
+	case "missing":
+		logiqx, err = exporter.ExportMissingDAT(ctx, libraryName)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "Unknown DAT report: %s\n", report)
+		fmt.Println("Valid DAT reports: have, missing")
+		os.Exit(1)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error exporting %s DAT: %v\n", report, err)
+		os.Exit(1)
+	}
+
+	data, err := logiqx.Marshal()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error encoding DAT: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	// #nosec G306
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"library": libraryName,
+			"report":  report,
+			"format":  "dat",
+			"output":  outputPath,
+			"games":   len(logiqx.Games),
+			"roms":    logiqx.ROMCount(),
+			"status":  "success",
+		})
+	} else {
+		fmt.Printf("Exported %s DAT with %d ROMs in %d games to %s\n", report, logiqx.ROMCount(), len(logiqx.Games), outputPath)
+	}
+}
+
+// exportLaunchBox writes a LaunchBox platform XML to outputPath, or to stdout
+// when outputPath is empty.
+func exportLaunchBox(ctx context.Context, libraryName, outputPath string, opts library.LaunchBoxOptions) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	manager := library.NewManager(database.Conn())
+	exporter := library.NewExporter(database.Conn(), manager)
+
 	data, err := exporter.ExportLaunchBox(context.Background(), libraryName, opts)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error exporting LaunchBox: %v\n", err)
 		os.Exit(1)
 	}
 
+	if outputPath == "" {
+		fmt.Println(string(data))
+		return
+	}
+
 	// #nosec G306
 	if err := os.WriteFile(outputPath, data, 0644); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
@@ -239,10 +472,102 @@ func exportLaunchBox(ctx context.Context, libraryName, outputPath string, matche
 			"library":     libraryName,
 			"format":      "launchbox",
 			"output":      outputPath,
-			"matchedOnly": matchedOnly,
+			"matchedOnly": opts.MatchedOnly,
 			"status":      "success",
 		})
 	} else {
 		fmt.Printf("Exported LaunchBox platform XML to %s\n", outputPath)
 	}
 }
+
+// exportPegasus writes a Pegasus metadata file to outputPath, or to stdout
+// when outputPath is empty.
+func exportPegasus(ctx context.Context, libraryName, outputPath string, opts library.PegasusOptions) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	manager := library.NewManager(database.Conn())
+	exporter := library.NewExporter(database.Conn(), manager)
+
+	data, err := exporter.ExportPegasus(ctx, libraryName, opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error exporting Pegasus metadata: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	// #nosec G306
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"library": libraryName,
+			"format":  "pegasus",
+			"output":  outputPath,
+			"status":  "success",
+		})
+	} else {
+		fmt.Printf("Exported Pegasus metadata to %s\n", outputPath)
+	}
+}
+
+// exportESDECollection writes an ES-DE custom collection named collection to
+// custom-<collection>.cfg in outputDir, or to stdout when outputDir is empty.
+func exportESDECollection(ctx context.Context, libraryName, collection, outputDir string, opts library.ESDEOptions) {
+	fileName, err := library.ESDECollectionFile(collection)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	manager := library.NewManager(database.Conn())
+	exporter := library.NewExporter(database.Conn(), manager)
+
+	data, err := exporter.ExportESDECollection(ctx, libraryName, opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error exporting ES-DE collection: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputDir == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	outputPath := filepath.Join(outputDir, fileName)
+	// #nosec G306
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"library":       libraryName,
+			"format":        "esde",
+			"output":        outputPath,
+			"preferredOnly": opts.PreferredOnly,
+			"status":        "success",
+		})
+	} else {
+		fmt.Printf("Exported ES-DE custom collection to %s\n", outputPath)
+	}
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/ryanm101/romman-lib/dat"
 	"github.com/ryanm101/romman-lib/library"
+	"github.com/ryanm101/romman-lib/logging"
 	"github.com/ryanm101/romman-lib/tracing"
 	"github.com/schollz/progressbar/v3"
 	"go.opentelemetry.io/otel/baggage"
@@ -24,29 +30,96 @@ func handleLibraryCommand(ctx context.Context, args []string) {
 	switch args[0] {
 	case "add":
 		if len(args) < 4 {
-			fmt.Println("Usage: romman library add <name> <path> <system>")
+			fmt.Println("Usage: romman library add <name> <path> <system> [--multi-system]")
 			os.Exit(1)
 		}
-		addLibrary(ctx, args[1], args[2], args[3])
+		multiSystem := len(args) >= 5 && args[4] == "--multi-system"
+		addLibrary(ctx, args[1], args[2], args[3], multiSystem)
+	case "multi-system":
+		if len(args) < 3 || (args[2] != "on" && args[2] != "off") {
+			fmt.Println("Usage: romman library multi-system <name> <on|off>")
+			os.Exit(1)
+		}
+		setMultiSystem(ctx, args[1], args[2] == "on")
 	case "list":
 		listLibraries(ctx)
+	case "remove":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman library remove <name> [--yes]")
+			os.Exit(1)
+		}
+		yes := len(args) >= 3 && (args[2] == "--yes" || args[2] == "-y")
+		removeLibrary(ctx, args[1], yes)
+	case "move":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman library move <name> <new-path>")
+			os.Exit(1)
+		}
+		moveLibrary(ctx, args[1], args[2])
+	case "diff":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman library diff <a> <b> [--preferred]")
+			os.Exit(1)
+		}
+		var opts library.DiffOptions
+		for _, arg := range args[3:] {
+			if arg == "--preferred" {
+				opts.PreferredOnly = true
+			}
+		}
+		diffLibraries(ctx, args[1], args[2], opts)
 	case "scan":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman library scan <name>")
+			fmt.Println("Usage: romman library scan <name> [--full] [--force-rehash] [--resume] [--no-archives] [--no-progress] [--fail-on-error]")
 			os.Exit(1)
 		}
-		scanLibrary(ctx, args[1])
+		scanLibrary(ctx, args[1], args[2:])
+	case "prune":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman library prune <name>")
+			os.Exit(1)
+		}
+		pruneLibrary(ctx, args[1])
+	case "watch":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman library watch <name> [--once]")
+			os.Exit(1)
+		}
+		watchLibrary(ctx, args[1], args[2:])
 	case "status":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman library status <name>")
+			fmt.Println("Usage: romman library status <name> [--verified-only] [--playable-only] [--release <title>]")
 			os.Exit(1)
 		}
-		showLibraryStatus(ctx, args[1])
+		var opts library.StatusOptions
+		release := ""
+		for i := 2; i < len(args); i++ {
+			switch {
+			case args[i] == "--verified-only":
+				opts.VerifiedOnly = true
+			case args[i] == "--playable-only":
+				opts.PlayableOnly = true
+			case args[i] == "--release" && i+1 < len(args):
+				i++
+				release = args[i]
+			case strings.HasPrefix(args[i], "--release="):
+				release = strings.TrimPrefix(args[i], "--release=")
+			}
+		}
+		if release != "" {
+			showReleaseStatus(ctx, args[1], release, opts.VerifiedOnly)
+			return
+		}
+		showLibraryStatus(ctx, args[1], opts)
 	case "unmatched":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman library unmatched <name>")
+			fmt.Println("Usage: romman library unmatched <name> [--suggest]")
 			os.Exit(1)
 		}
+		if len(args) >= 3 && args[2] == "--suggest" {
+			suggestUnmatchedMatches(ctx, args[1])
+			return
+		}
 		showUnmatchedFiles(ctx, args[1])
 	case "discover":
 		if len(args) < 2 {
@@ -73,12 +146,23 @@ func handleLibraryCommand(ctx context.Context, args []string) {
 		}
 		dryRun := len(args) >= 3 && args[2] == "--dry-run"
 		renameFiles(ctx, args[1], dryRun)
+	case "dedupe":
+		dedupeLibrary(ctx, args[1:])
 	case "verify":
 		if len(args) < 2 {
-			fmt.Println("Usage: romman library verify <name>")
+			fmt.Println("Usage: romman library verify <name> [--repair] [--dry-run]")
 			os.Exit(1)
 		}
-		checkLibrary(ctx, args[1])
+		repair, dryRun := false, false
+		for _, arg := range args[2:] {
+			switch arg {
+			case "--repair":
+				repair = true
+			case "--dry-run":
+				dryRun = true
+			}
+		}
+		checkLibrary(ctx, args[1], repair, dryRun)
 	case "scrape":
 		if len(args) < 2 {
 			fmt.Println("Usage: romman library scrape <name> [--force]")
@@ -97,17 +181,31 @@ func handleLibraryCommand(ctx context.Context, args []string) {
 		linkLibrary(ctx, args[1])
 	case "organize":
 		if len(args) < 3 {
-			fmt.Println("Usage: romman library organize <name> <output-dir> [--dry-run] [--preferred] [--rename] [--structure=system]")
+			fmt.Println("Usage: romman library organize <name> <output-dir> [--dry-run] [--copy|--hardlink] [--preferred] [--rename] [--multi-disc] [--structure=system]")
 			os.Exit(1)
 		}
 		organizeLibrary(ctx, args[1], args[2], args[3:])
+	case "replace":
+		if len(args) < 4 {
+			fmt.Println("Usage: romman library replace <name> <newfile> <quarantine-dir> [--dry-run] [--keep-name] [--output=<dir>] [--structure=system]")
+			os.Exit(1)
+		}
+		replaceFile(ctx, args[1], args[2], args[3], args[4:])
+	case "tag":
+		handleTagCommand(ctx, args[1:])
+	case "import-hashes":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman library import-hashes <name> <hashfile> [--format=sfv|csv|lines]")
+			os.Exit(1)
+		}
+		importHashes(ctx, args[1], args[2], args[3:])
 	default:
 		fmt.Printf("Unknown library command: %s\n", args[0])
 		os.Exit(1)
 	}
 }
 
-func addLibrary(ctx context.Context, name, rootPath, system string) {
+func addLibrary(ctx context.Context, name, rootPath, system string, multiSystem bool) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -138,9 +236,138 @@ func addLibrary(ctx context.Context, name, rootPath, system string) {
 		os.Exit(1)
 	}
 
+	if multiSystem {
+		if err := manager.SetMultiSystem(ctx, lib.Name, true); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error enabling multi-system: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	fmt.Printf("Library added: %s\n", lib.Name)
 	fmt.Printf("  Path: %s\n", lib.RootPath)
 	fmt.Printf("  System: %s\n", lib.SystemName)
+	if multiSystem {
+		fmt.Println("  Multi-system: systems are detected from top-level subdirectories")
+	}
+}
+
+func setMultiSystem(ctx context.Context, name string, enabled bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	if err := library.NewManager(database.Conn()).SetMultiSystem(ctx, name, enabled); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error updating library: %v\n", err)
+		os.Exit(1)
+	}
+
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+	fmt.Printf("Multi-system %s for %s (rescan to rematch files)\n", state, name)
+}
+
+// removeLibrary deletes a library with its scanned files and matches, after
+// asking for confirmation unless yes is set. The files on disk are untouched.
+func removeLibrary(ctx context.Context, name string, yes bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	manager := library.NewManager(database.Conn())
+	lib, err := manager.Get(ctx, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !yes {
+		var files int
+		_ = database.Conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM scanned_files WHERE library_id = ?", lib.ID).Scan(&files)
+		fmt.Printf("Remove library %s (%s) and its %d scanned files? Files on disk are kept. [y/N] ", lib.Name, lib.RootPath, files)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			fmt.Println("Aborted")
+			return
+		}
+	}
+
+	if err := manager.Delete(ctx, name); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error removing library: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed library %s\n", name)
+}
+
+func moveLibrary(ctx context.Context, name, newPath string) {
+	absPath, err := filepath.Abs(newPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error resolving path: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := library.NewManager(database.Conn()).Move(ctx, name, absPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error moving library: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+	fmt.Printf("Moved library %s from %s to %s\n", name, result.OldRoot, result.NewRoot)
+	fmt.Printf("  Paths updated: %d\n", result.FilesMoved)
+	if result.FilesSampled > 0 {
+		fmt.Printf("  Files checked: %d of %d sampled found\n", result.FilesFound, result.FilesSampled)
+	}
+}
+
+func diffLibraries(ctx context.Context, a, b string, opts library.DiffOptions) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	diff, err := library.NewManager(database.Conn()).Diff(ctx, a, b, opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error comparing libraries: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(diff)
+		return
+	}
+
+	printOnly := func(have, lack string, releases []library.DiffRelease) {
+		fmt.Printf("In %s but not %s (%d):\n", have, lack, len(releases))
+		for _, r := range releases {
+			fmt.Printf("  %s\n", r.Name)
+		}
+		fmt.Println()
+	}
+	printOnly(diff.LibraryA, diff.LibraryB, diff.OnlyInA)
+	printOnly(diff.LibraryB, diff.LibraryA, diff.OnlyInB)
+	fmt.Printf("In both: %d\n", diff.InBoth)
 }
 
 func listLibraries(ctx context.Context) {
@@ -174,12 +401,17 @@ func listLibraries(ctx context.Context) {
 		if lib.LastScanAt != nil {
 			lastScan = lib.LastScanAt.Format("2006-01-02 15:04")
 		}
-		rowsData = append(rowsData, []string{lib.Name, lib.SystemName, lib.RootPath, lastScan})
+		system := lib.SystemName
+		if lib.MultiSystem {
+			system += " (multi)"
+		}
+		rowsData = append(rowsData, []string{lib.Name, system, lib.RootPath, lastScan})
 		jsonData = append(jsonData, map[string]interface{}{
-			"name":       lib.Name,
-			"system":     lib.SystemName,
-			"path":       lib.RootPath,
-			"lastScanAt": lastScan,
+			"name":        lib.Name,
+			"system":      lib.SystemName,
+			"multiSystem": lib.MultiSystem,
+			"path":        lib.RootPath,
+			"lastScanAt":  lastScan,
 		})
 	}
 
@@ -190,12 +422,50 @@ func listLibraries(ctx context.Context) {
 	}
 }
 
-func scanLibrary(ctx context.Context, name string) {
+// scanJSON is the --json output of library scan.
+type scanJSON struct {
+	Library string `json:"library"`
+	*library.ScanResult
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+func scanLibrary(ctx context.Context, name string, flags []string) {
+	// Only files hashed by this scan are re-matched unless --full asks for
+	// every file to be matched again, e.g. after a DAT update
+	changedOnly := true
+	var noProgress, failOnError, resume, forceRehash bool
+	noArchives := !cfg.GetExpandArchives()
+	for _, flag := range flags {
+		switch flag {
+		case "--full":
+			changedOnly = false
+		case "--force-rehash":
+			forceRehash = true
+		case "--resume":
+			resume = true
+		case "--no-archives":
+			noArchives = true
+		case "--changed":
+			// Incremental matching is the default; kept for old scripts
+			changedOnly = true
+		case "--no-progress":
+			noProgress = true
+		case "--fail-on-error":
+			failOnError = true
+		}
+	}
+	// Machine-readable and quiet runs print nothing but the result
+	chatty := !outputCfg.Quiet && !outputCfg.JSON
+
 	// Add library name to baggage
 	m, _ := baggage.NewMember("library.name", name)
 	b, _ := baggage.New(m)
 	ctx = baggage.ContextWithBaggage(ctx, b)
 
+	// Stop cleanly on Ctrl-C so the batch in flight is committed and matched
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -203,15 +473,23 @@ func scanLibrary(ctx context.Context, name string) {
 	}
 	defer func() { _ = database.Close() }()
 
-	scanCfg := library.ScanConfig{
-		Workers:   cfg.Scan.Workers,
-		BatchSize: cfg.Scan.BatchSize,
-		Parallel:  cfg.Scan.Parallel,
+	scanCfg := scanConfigFromCfg()
+	scanCfg.ChangedOnly = changedOnly
+	scanCfg.Resume = resume
+	scanCfg.ForceRehash = forceRehash
+	scanCfg.NoArchives = noArchives
+	if chatty {
+		fmt.Printf("Scanning library: %s\n", name)
+		if state, err := library.NewScanner(database.Conn()).GetScanState(ctx, name); err == nil && state != nil && state.Interrupted() {
+			fmt.Printf("Resuming interrupted scan (%d files already committed)\n", state.FilesCommitted)
+			if resume && state.LastDir != "" {
+				fmt.Printf("Skipping directories committed up to %s\n", state.LastDir)
+			}
+		}
 	}
-	fmt.Printf("Scanning library: %s\n", name)
 
 	var bar *progressbar.ProgressBar
-	if !outputCfg.Quiet && !outputCfg.JSON {
+	if chatty && !noProgress {
 		bar = progressbar.Default(-1, "Scanning")
 	}
 
@@ -230,25 +508,118 @@ func scanLibrary(ctx context.Context, name string) {
 		_ = bar.Finish()
 	}
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			_, _ = fmt.Fprintln(os.Stderr, "Scan interrupted; progress was saved and the next scan will resume.")
+			os.Exit(130)
+		}
 		_, _ = fmt.Fprintf(os.Stderr, "Error scanning library: %v\n", err)
 		os.Exit(1)
 	}
 
 	if outputCfg.JSON {
-		PrintResult(result)
-		return
+		if result.Errors == nil {
+			result.Errors = []library.ScanError{}
+		}
+		PrintResult(scanJSON{Library: name, ScanResult: result, DurationSeconds: result.Duration.Seconds()})
+	} else {
+		printScanResult(result)
 	}
 
+	// Exit non-zero after reporting, so CI sees both the result and the failure
+	if failOnError && len(result.Errors) > 0 {
+		_ = database.Close()
+		os.Exit(1)
+	}
+}
+
+// printScanResult prints the summary of a single library scan.
+func printScanResult(result *library.ScanResult) {
 	fmt.Println()
 	fmt.Printf("Files scanned: %d\n", result.FilesScanned)
 	fmt.Printf("Files hashed: %d\n", result.FilesHashed)
 	fmt.Printf("Files cached: %d\n", result.FilesSkipped)
+	if result.FilesVerified > 0 {
+		fmt.Printf("Files verified: %d\n", result.FilesVerified)
+	}
+	if result.FilesPruned > 0 {
+		fmt.Printf("Files pruned (ignored extension): %d\n", result.FilesPruned)
+	}
+	if result.DirsResumed > 0 {
+		fmt.Printf("Directories skipped (resumed): %d\n", result.DirsResumed)
+	}
 	fmt.Println()
 	fmt.Printf("Matches found: %d\n", result.MatchesFound)
 	fmt.Printf("Unmatched files: %d\n", result.UnmatchedFiles)
+	fmt.Printf("Duration: %s\n", result.Duration.Round(time.Millisecond))
+	printScanErrors(result.Errors)
+}
+
+// printScanErrors lists per-file problems found during a scan.
+func printScanErrors(scanErrors []library.ScanError) {
+	if len(scanErrors) == 0 {
+		return
+	}
+	fmt.Printf("\nErrors (%d):\n", len(scanErrors))
+	for _, e := range scanErrors {
+		path := e.Path
+		if e.ArchivePath != "" {
+			path += ":" + e.ArchivePath
+		}
+		fmt.Printf("  [%s] %s: %s\n", e.Kind, path, e.Message)
+	}
+}
+
+func importHashes(ctx context.Context, name, hashFile string, flags []string) {
+	format := library.DetectHashListFormat(hashFile)
+	for _, flag := range flags {
+		switch {
+		case strings.HasPrefix(flag, "--format="):
+			format = library.HashListFormat(strings.TrimPrefix(flag, "--format="))
+		}
+	}
+
+	f, err := os.Open(hashFile) // #nosec G304
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening hash list: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = f.Close() }()
+
+	entries, skipped, err := library.ParseHashList(f, format)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error reading hash list: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	scanner := library.NewScanner(database.Conn())
+	result, err := scanner.ImportHashes(ctx, name, entries)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error importing hashes: %v\n", err)
+		os.Exit(1)
+	}
+	result.Skipped = skipped
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+
+	fmt.Printf("Imported %d virtual files into %s\n", result.Imported, name)
+	if result.Skipped > 0 {
+		fmt.Printf("  Skipped lines: %d (no SHA1 or CRC32)\n", result.Skipped)
+	}
+	fmt.Printf("  Matched:   %d\n", result.MatchesFound)
+	fmt.Printf("  Unmatched: %d\n", result.UnmatchedFiles)
 }
 
-func showLibraryStatus(ctx context.Context, name string) {
+func showLibraryStatus(ctx context.Context, name string, opts library.StatusOptions) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -276,17 +647,19 @@ func showLibraryStatus(ctx context.Context, name string) {
 		res["lastScan"] = summary.LastScan.Format("2006-01-02 15:04:05")
 	}
 
-	statuses, err := scanner.GetLibraryStatus(ctx, name)
+	statuses, err := scanner.GetLibraryStatusWithOptions(ctx, name, opts)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error getting library status: %v\n", err)
 		os.Exit(1)
 	}
 
-	var present, missing, partial int
+	var present, missing, partial, unverified int
 	for _, s := range statuses {
 		switch s.Status {
 		case "present":
 			present++
+		case "unverified":
+			unverified++
 		case "missing":
 			missing++
 		case "partial":
@@ -294,12 +667,37 @@ func showLibraryStatus(ctx context.Context, name string) {
 		}
 	}
 
-	res["releases"] = map[string]int{
+	releases := map[string]int{
 		"total":   len(statuses),
 		"present": present,
 		"partial": partial,
 		"missing": missing,
 	}
+	if opts.VerifiedOnly {
+		releases["unverified"] = unverified
+	}
+	res["releases"] = releases
+
+	var breakdown []library.SystemStatus
+	if summary.Library.MultiSystem {
+		breakdown, err = scanner.GetSystemBreakdown(ctx, name, opts)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error getting system breakdown: %v\n", err)
+			os.Exit(1)
+		}
+		res["multiSystem"] = true
+		res["systems"] = breakdown
+	}
+
+	biosStatuses, err := newBIOSManager(database.Conn()).Status(ctx, summary.Library.SystemName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting BIOS status: %v\n", err)
+		os.Exit(1)
+	}
+	biosReady := library.BIOSReady(biosStatuses)
+	if len(biosStatuses) > 0 {
+		res["biosReady"] = biosReady
+	}
 
 	if outputCfg.JSON {
 		PrintResult(res)
@@ -320,10 +718,104 @@ func showLibraryStatus(ctx context.Context, name string) {
 	fmt.Printf("Unmatched: %d\n", summary.UnmatchedFiles)
 
 	fmt.Println()
-	fmt.Printf("Releases: %d total\n", len(statuses))
+	if opts.PlayableOnly {
+		fmt.Printf("Releases: %d total (playable only)\n", len(statuses))
+	} else {
+		fmt.Printf("Releases: %d total\n", len(statuses))
+	}
 	fmt.Printf("  Present: %d\n", present)
+	if opts.VerifiedOnly {
+		fmt.Printf("  Present (unverified): %d\n", unverified)
+	}
 	fmt.Printf("  Partial: %d\n", partial)
 	fmt.Printf("  Missing: %d\n", missing)
+
+	if len(breakdown) > 0 {
+		fmt.Println()
+		fmt.Println("Systems:")
+		rowsData := make([][]string, 0, len(breakdown))
+		for _, b := range breakdown {
+			rowsData = append(rowsData, []string{
+				b.System,
+				fmt.Sprintf("%d/%d", b.MatchedFiles, b.TotalFiles),
+				fmt.Sprintf("%d/%d", b.Present, b.Releases),
+				fmt.Sprintf("%d", b.Partial),
+				fmt.Sprintf("%d", b.Missing),
+			})
+		}
+		PrintTable([]string{"SYSTEM", "MATCHED FILES", "PRESENT", "PARTIAL", "MISSING"}, rowsData)
+	}
+
+	if len(biosStatuses) > 0 {
+		fmt.Println()
+		if biosReady {
+			fmt.Println("BIOS: ready")
+		} else {
+			fmt.Printf("BIOS: missing (run: romman bios status %s)\n", summary.Library.SystemName)
+		}
+	}
+}
+
+// showReleaseStatus shows one release's status in a library and, unless it
+// is complete, the ROM entries no file matches.
+func showReleaseStatus(ctx context.Context, name, releaseName string, verifiedOnly bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	scanner := library.NewScanner(database.Conn())
+	statuses, err := scanner.GetLibraryStatusWithOptions(ctx, name, library.StatusOptions{VerifiedOnly: verifiedOnly})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting library status: %v\n", err)
+		os.Exit(1)
+	}
+
+	var status *library.ReleaseStatus
+	for _, s := range statuses {
+		if s.ReleaseName == releaseName {
+			status = s
+			break
+		}
+	}
+	if status == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Release not found in library %s: %s\n", name, releaseName)
+		os.Exit(1)
+	}
+
+	missing, err := scanner.GetMissingROMEntries(ctx, name, status.ReleaseID)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting missing ROMs: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{
+			"release":     status.ReleaseName,
+			"system":      status.SystemName,
+			"status":      status.Status,
+			"totalRoms":   status.TotalROMs,
+			"matchedRoms": status.MatchedROMs,
+			"missingRoms": missing,
+		})
+		return
+	}
+
+	fmt.Printf("Release: %s\n", status.ReleaseName)
+	fmt.Printf("Status: %s (%d/%d ROMs)\n", status.Status, status.MatchedROMs, status.TotalROMs)
+	if len(missing) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Missing ROMs:")
+	rowsData := make([][]string, 0, len(missing))
+	for _, m := range missing {
+		rowsData = append(rowsData, []string{m.Name, fmt.Sprintf("%d", m.Size), m.CRC32, m.SHA1})
+	}
+	PrintTable([]string{"NAME", "SIZE", "CRC32", "SHA1"}, rowsData)
 }
 
 func showUnmatchedFiles(ctx context.Context, name string) {
@@ -340,20 +832,67 @@ func showUnmatchedFiles(ctx context.Context, name string) {
 		_, _ = fmt.Fprintf(os.Stderr, "Error getting unmatched files: %v\n", err)
 		os.Exit(1)
 	}
-
-	if len(files) == 0 {
-		fmt.Println("No unmatched files.")
-		return
+	skipped, err := scanner.GetSkippedFiles(ctx, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting skipped files: %v\n", err)
+		os.Exit(1)
 	}
 
 	if outputCfg.JSON {
 		PrintResult(files)
+		return
+	}
+
+	if len(files) == 0 {
+		fmt.Println("No unmatched files.")
 	} else {
 		fmt.Printf("Unmatched files (%d):\n", len(files))
 		for _, f := range files {
 			fmt.Printf("  %s\n", f)
 		}
 	}
+
+	if len(skipped) > 0 {
+		fmt.Printf("\nSkipped by the last scan (%d):\n", len(skipped))
+		for _, f := range skipped {
+			fmt.Printf("  %s: %s\n", f.Path, f.Reason)
+		}
+	}
+}
+
+func suggestUnmatchedMatches(ctx context.Context, name string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	suggestions, err := library.NewScanner(database.Conn()).SuggestMatches(ctx, name, 0)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error suggesting matches: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(suggestions)
+		return
+	}
+
+	if len(suggestions) == 0 {
+		fmt.Println("No unmatched files.")
+		return
+	}
+	fmt.Printf("Unmatched files (%d):\n", len(suggestions))
+	for _, s := range suggestions {
+		fmt.Printf("\n  %s (%d bytes)\n", s.Path, s.Size)
+		if len(s.Candidates) == 0 {
+			fmt.Println("    no releases for this system")
+		}
+		for _, c := range s.Candidates {
+			fmt.Printf("    %-8s %s (distance %d, size %+d)\n", c.Reason, c.Release, c.Distance, c.SizeDelta)
+		}
+	}
 }
 
 func discoverLibraries(ctx context.Context, rootDir string, autoAdd, force bool) {
@@ -437,6 +976,7 @@ func discoverLibraries(ctx context.Context, rootDir string, autoAdd, force bool)
 		for _, s := range skippedDirs {
 			fmt.Printf("  %-20s - %s\n", s.name, s.reason)
 		}
+		fmt.Printf("\nTo map unknown directories, see: romman systems suggest %s\n", rootDir)
 	}
 
 	if !autoAdd {
@@ -495,6 +1035,162 @@ func discoverLibraries(ctx context.Context, rootDir string, autoAdd, force bool)
 	fmt.Println()
 }
 
+func pruneLibrary(ctx context.Context, name string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	scanner := library.NewScannerWithConfig(database.Conn(), library.ScanConfig{
+		IgnoreExtensions: cfg.Scan.IgnoreExtensions,
+		LibraryFilters:   libraryFilters(),
+	})
+	pruned, err := scanner.Prune(ctx, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error pruning library: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(map[string]interface{}{"library": name, "pruned": pruned})
+		return
+	}
+	fmt.Printf("Pruned %d entries with ignored extensions from %s\n", pruned, name)
+}
+
+// watchDebounce is how long a library must be quiet before a rescan, so a
+// large copy triggers one scan rather than one per file.
+const watchDebounce = 3 * time.Second
+
+func watchLibrary(ctx context.Context, name string, flags []string) {
+	once := false
+	for _, flag := range flags {
+		if flag == "--once" {
+			once = true
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	lib, err := library.NewManager(database.Conn()).Get(ctx, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting library: %v\n", err)
+		os.Exit(1)
+	}
+
+	scanCfg := scanConfigFromCfg()
+	fullScanner := library.NewScannerWithConfig(database.Conn(), scanCfg)
+	// Rescans only re-match the files that changed
+	scanCfg.ChangedOnly = true
+	changedScanner := library.NewScannerWithConfig(database.Conn(), scanCfg)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error starting watcher: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	// Watch before the first scan so changes made during it are not missed
+	if err := addWatchTree(watcher, lib.RootPath); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error watching %s: %v\n", lib.RootPath, err)
+		os.Exit(1)
+	}
+
+	rescan := func(scanner *library.Scanner, reason string) {
+		logging.Info("scanning library", "library", name, "reason", reason)
+		result, err := scanner.Scan(ctx, name)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logging.Error("scan failed", "library", name, "error", err)
+			}
+			return
+		}
+		logging.Info("scan complete",
+			"library", name,
+			"files", result.FilesScanned,
+			"hashed", result.FilesHashed,
+			"matches", result.MatchesFound,
+			"unmatched", result.UnmatchedFiles,
+			"errors", len(result.Errors),
+			"duration", result.Duration.Round(time.Millisecond))
+	}
+
+	rescan(fullScanner, "initial")
+	if once {
+		return
+	}
+
+	logging.Info("watching library", "library", name, "path", lib.RootPath, "debounce", watchDebounce)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	changed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			logging.Info("stopped watching library", "library", name)
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				// New directories, including whole trees moved in, need
+				// their own watches
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchTree(watcher, event.Name); err != nil {
+						logging.Warn("failed to watch directory", "path", event.Name, "error", err)
+					}
+					changed++
+					debounce.Reset(watchDebounce)
+					continue
+				}
+			}
+			if fullScanner.IsIgnored(lib, event.Name) {
+				continue
+			}
+			logging.Debug("library changed", "path", event.Name, "op", event.Op.String())
+			changed++
+			debounce.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Warn("watch error", "library", name, "error", err)
+		case <-debounce.C:
+			rescan(changedScanner, fmt.Sprintf("%d changes", changed))
+			changed = 0
+		}
+	}
+}
+
+// addWatchTree watches root and every directory below it.
+func addWatchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}
+
 func scanAllLibraries(ctx context.Context) {
 	database, err := openDB(ctx)
 	if err != nil {
@@ -527,18 +1223,14 @@ func scanAllLibraries(ctx context.Context) {
 			bar = progressbar.Default(-1, "Scanning")
 		}
 
-		scanCfg := library.ScanConfig{
-			Workers:   cfg.Scan.Workers,
-			BatchSize: cfg.Scan.BatchSize,
-			Parallel:  cfg.Scan.Parallel,
-			OnProgress: func(p library.ScanProgress) {
-				if bar != nil {
-					if p.TotalFiles > 0 && bar.GetMax() == -1 {
-						bar.ChangeMax64(p.TotalFiles)
-					}
-					_ = bar.Set64(p.FilesScanned)
+		scanCfg := scanConfigFromCfg()
+		scanCfg.OnProgress = func(p library.ScanProgress) {
+			if bar != nil {
+				if p.TotalFiles > 0 && bar.GetMax() == -1 {
+					bar.ChangeMax64(p.TotalFiles)
 				}
-			},
+				_ = bar.Set64(p.FilesScanned)
+			}
 		}
 
 		scanner := library.NewScannerWithConfig(database.Conn(), scanCfg)
@@ -553,6 +1245,7 @@ func scanAllLibraries(ctx context.Context) {
 		}
 		fmt.Printf("  Files: %d, Matches: %d, Unmatched: %d\n",
 			result.FilesScanned, result.MatchesFound, result.UnmatchedFiles)
+		printScanErrors(result.Errors)
 	}
 
 	fmt.Println("\nDone.")
@@ -580,7 +1273,11 @@ func renameFiles(ctx context.Context, name string, dryRun bool) {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	printRenameResult(result)
+}
 
+// printRenameResult lists the renames made, or planned in a dry run, and totals them.
+func printRenameResult(result *library.RenameResult) {
 	for _, action := range result.Actions {
 		switch action.Status {
 		case "pending":
@@ -594,7 +1291,7 @@ func renameFiles(ctx context.Context, name string, dryRun bool) {
 		}
 	}
 
-	if dryRun {
+	if result.DryRun {
 		pending := len(result.Actions) - result.Skipped
 		fmt.Printf("\nWould rename: %d files\n", pending)
 		fmt.Printf("Skipped: %d (already correct or target exists)\n", result.Skipped)
@@ -604,7 +1301,7 @@ func renameFiles(ctx context.Context, name string, dryRun bool) {
 	}
 }
 
-func checkLibrary(ctx context.Context, name string) {
+func checkLibrary(ctx context.Context, name string, repair, dryRun bool) {
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -617,7 +1314,12 @@ func checkLibrary(ctx context.Context, name string) {
 
 	fmt.Printf("Verifying library: %s\n\n", name)
 
-	result, err := checker.Check(ctx, name)
+	var result *library.IntegrityResult
+	if repair {
+		result, err = checker.Repair(ctx, name, dryRun)
+	} else {
+		result, err = checker.Check(ctx, name)
+	}
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -631,10 +1333,25 @@ func checkLibrary(ctx context.Context, name string) {
 	fmt.Printf("Files checked: %d\n", result.FilesChecked)
 	fmt.Printf("OK: %d, Changed: %d, Missing: %d, Incomplete: %d\n",
 		result.OK, result.Changed, result.Missing, result.Incomplete)
+	if result.CRCMismatch > 0 {
+		fmt.Printf("Zip CRC mismatches: %d (corrupt or repacked archives; re-download or rebuild them)\n", result.CRCMismatch)
+	}
+	if result.CHDCorrupt > 0 || result.CHDMismatch > 0 {
+		fmt.Printf("CHDs corrupt: %d, not matching their DAT: %d\n", result.CHDCorrupt, result.CHDMismatch)
+	}
 
 	if len(result.Issues) == 0 {
 		fmt.Println("\n✓ All files verified OK")
 	}
+
+	if result.Renames != nil {
+		mode := "LIVE"
+		if dryRun {
+			mode = "DRY-RUN"
+		}
+		fmt.Printf("\nRepairing names of verified files [%s]...\n\n", mode)
+		printRenameResult(result.Renames)
+	}
 }
 
 func scrapeLibrary(ctx context.Context, name string, force bool) {
@@ -798,11 +1515,22 @@ func organizeLibrary(ctx context.Context, libraryName, outputDir string, flags [
 			opts.PreferredOnly = true
 		case flag == "--rename":
 			opts.RenameToDAT = true
+		case flag == "--multi-disc":
+			opts.MultiDisc = true
+		case flag == "--copy":
+			opts.Copy = true
+		case flag == "--hardlink":
+			opts.Hardlink = true
 		case strings.HasPrefix(flag, "--structure="):
 			opts.Structure = strings.TrimPrefix(flag, "--structure=")
 		}
 	}
 
+	if opts.Copy && opts.Hardlink {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: --copy and --hardlink cannot be used together")
+		os.Exit(1)
+	}
+
 	manager := library.NewManager(database.Conn())
 	organizer := library.NewOrganizer(database.Conn(), manager)
 
@@ -819,6 +1547,15 @@ func organizeLibrary(ctx context.Context, libraryName, outputDir string, flags [
 	if opts.PreferredOnly {
 		fmt.Println("  Preferred releases only: yes")
 	}
+	if opts.MultiDisc {
+		fmt.Println("  Multi-disc playlists: yes")
+	}
+	if opts.Copy {
+		fmt.Println("  Copying (library left as it is): yes")
+	}
+	if opts.Hardlink {
+		fmt.Println("  Hardlinking (library left as it is): yes")
+	}
 	fmt.Println()
 
 	// Generate plan
@@ -828,7 +1565,7 @@ func organizeLibrary(ctx context.Context, libraryName, outputDir string, flags [
 		os.Exit(1)
 	}
 
-	if len(result.Actions) == 0 {
+	if len(result.Actions) == 0 && len(result.DiscSets) == 0 {
 		fmt.Println("Nothing to organize.")
 		return
 	}
@@ -839,7 +1576,14 @@ func organizeLibrary(ctx context.Context, libraryName, outputDir string, flags [
 		fmt.Printf("    -> %s\n", action.DestPath)
 	}
 
+	for _, set := range result.DiscSets {
+		fmt.Printf("  %s (%d discs)\n", set.Path, len(set.Discs))
+	}
+
 	fmt.Printf("\n%d files to organize\n", len(result.Actions))
+	if len(result.DiscSets) > 0 {
+		fmt.Printf("%d multi-disc playlists to write\n", len(result.DiscSets))
+	}
 
 	if !dryRun {
 		// Execute the plan
@@ -847,9 +1591,140 @@ func organizeLibrary(ctx context.Context, libraryName, outputDir string, flags [
 			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("\nMoved: %d, Errors: %d\n", result.Moved, result.Errors)
+		switch {
+		case opts.Hardlink:
+			fmt.Printf("\nLinked: %d, Errors: %d\n", result.Linked, result.Errors)
+		case opts.Copy:
+			fmt.Printf("\nCopied: %d, Errors: %d\n", result.Copied, result.Errors)
+		default:
+			fmt.Printf("\nMoved: %d, Errors: %d\n", result.Moved, result.Errors)
+		}
 		for _, msg := range result.ErrorMsgs {
 			fmt.Printf("  Error: %s\n", msg)
 		}
 	}
 }
+
+func replaceFile(ctx context.Context, libraryName, newFile, quarantineDir string, flags []string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	opts := library.ReplaceOptions{RenameToDAT: true}
+	for _, flag := range flags {
+		switch {
+		case flag == "--dry-run":
+			opts.DryRun = true
+		case flag == "--keep-name":
+			opts.RenameToDAT = false
+		case strings.HasPrefix(flag, "--output="):
+			opts.OutputDir = strings.TrimPrefix(flag, "--output=")
+		case strings.HasPrefix(flag, "--structure="):
+			opts.Structure = strings.TrimPrefix(flag, "--structure=")
+		}
+	}
+
+	for _, p := range []*string{&newFile, &quarantineDir, &opts.OutputDir} {
+		if *p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error resolving path: %v\n", err)
+			os.Exit(1)
+		}
+		*p = abs
+	}
+	opts.QuarantineBase = quarantineDir
+
+	result, err := library.NewScanner(database.Conn()).Replace(ctx, libraryName, newFile, opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error replacing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+
+	mode := "Replaced"
+	if result.DryRun {
+		mode = "Would replace"
+	}
+	fmt.Printf("%s %s with a verified copy\n", mode, result.Release)
+	fmt.Printf("  New file: %s\n", result.NewFile)
+	fmt.Printf("    -> %s\n", result.DestPath)
+	for _, old := range result.Replaced {
+		name := old.Path
+		if old.ArchivePath != "" {
+			name += "#" + old.ArchivePath
+		}
+		fmt.Printf("  Quarantine: %s (%s", name, old.MatchType)
+		if old.Flags != "" {
+			fmt.Printf(", %s", old.Flags)
+		}
+		fmt.Println(")")
+		fmt.Printf("    -> %s\n", old.QuarantinePath)
+	}
+}
+
+// scanConfigFromCfg builds the scanner configuration from the config file.
+// Callers set their per-command options, such as ChangedOnly or a progress
+// callback, on top.
+func scanConfigFromCfg() library.ScanConfig {
+	return library.ScanConfig{
+		Workers:             cfg.Scan.Workers,
+		BatchSize:           cfg.Scan.BatchSize,
+		Parallel:            cfg.Scan.Parallel,
+		SampleVerifyPercent: cfg.Scan.SampleVerifyPercent,
+		SplitROMs:           splitROMRules(),
+		OneFileSystem:       cfg.Scan.OneFileSystem,
+		IgnoreExtensions:    cfg.Scan.IgnoreExtensions,
+		LibraryFilters:      libraryFilters(),
+		SystemExtensions:    systemExtensions(),
+		SHA256:              cfg.Scan.SHA256,
+		Fuzzy:               cfg.Scan.Fuzzy,
+		FuzzyDistance:       cfg.Scan.FuzzyDistance,
+		ParallelHashMinSize: int64(cfg.Scan.ParallelHashMinMB) << 20,
+		CacheKey:            cfg.Scan.CacheKey,
+		NoArchives:          !cfg.GetExpandArchives(),
+	}
+}
+
+// systemExtensions returns the per-system ROM extensions set in config.
+func systemExtensions() map[string][]string {
+	exts := make(map[string][]string)
+	for system, sysCfg := range cfg.Systems {
+		if sysCfg.Extensions != nil {
+			exts[system] = sysCfg.Extensions
+		}
+	}
+	return exts
+}
+
+// libraryFilters returns the per-library ignore settings from config.
+func libraryFilters() map[string]library.LibraryFilter {
+	filters := make(map[string]library.LibraryFilter)
+	for name, libCfg := range cfg.Libraries {
+		filters[name] = library.LibraryFilter{
+			IgnorePatterns:    libCfg.IgnorePatterns,
+			IncludeExtensions: libCfg.IncludeExtensions,
+		}
+	}
+	return filters
+}
+
+// splitROMRules converts the per-system split ROM config into scanner rules.
+func splitROMRules() map[string][]library.SplitROMRule {
+	rules := make(map[string][]library.SplitROMRule)
+	for system, sysCfg := range cfg.Systems {
+		for _, r := range sysCfg.SplitROMs {
+			rules[system] = append(rules[system], library.SplitROMRule{Parts: r.Parts})
+		}
+	}
+	return rules
+}
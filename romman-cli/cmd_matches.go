@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleMatchesCommand(ctx context.Context, args []string) {
+	switch args[0] {
+	case "reassign":
+		if len(args) < 4 {
+			fmt.Println("Usage: romman matches reassign <library> <file> <release>")
+			os.Exit(1)
+		}
+		reassignMatch(ctx, args[1], args[2], args[3])
+	default:
+		fmt.Printf("Unknown matches command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func reassignMatch(ctx context.Context, libName, file, release string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := library.NewScanner(database.Conn()).ReassignMatch(ctx, libName, file, release)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error reassigning match: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+
+	fmt.Printf("Matched %s to %s (%s)\n", result.Path, result.Release, result.ROM)
+	if len(result.Previous) > 0 {
+		fmt.Printf("  Replaced: %s\n", strings.Join(result.Previous, ", "))
+	}
+}
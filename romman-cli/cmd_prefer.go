@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ryanm101/romman-lib/library"
 )
@@ -27,6 +29,12 @@ func handlePreferCommand(ctx context.Context, args []string) {
 			os.Exit(1)
 		}
 		listPreferences(ctx, args[1])
+	case "explain":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman prefer explain <system> <title|release>")
+			os.Exit(1)
+		}
+		explainPreference(ctx, args[1], args[2])
 	default:
 		fmt.Printf("Unknown prefer command: %s\n", args[0])
 		os.Exit(1)
@@ -50,8 +58,7 @@ func rebuildPreferences(ctx context.Context, systemName string) {
 
 	fmt.Printf("Rebuilding preferred releases for: %s\n", systemName)
 
-	config := library.DefaultPreferenceConfig()
-	selector := library.NewPreferenceSelector(database.Conn(), config)
+	selector := library.NewPreferenceSelector(database.Conn(), preferenceConfig())
 
 	if err := selector.SelectPreferred(context.Background(), systemID); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error rebuilding preferred: %v\n", err)
@@ -97,8 +104,7 @@ func listPreferences(ctx context.Context, systemName string) {
 		os.Exit(1)
 	}
 
-	config := library.DefaultPreferenceConfig()
-	selector := library.NewPreferenceSelector(database.Conn(), config)
+	selector := library.NewPreferenceSelector(database.Conn(), preferenceConfig())
 
 	preferred, err := selector.GetPreferredReleases(systemID)
 	if err != nil {
@@ -122,3 +128,107 @@ func listPreferences(ctx context.Context, systemName string) {
 		fmt.Printf("  %s\n", r.Name)
 	}
 }
+
+// explainPreference explains the preference group of a release name, or of
+// a base title such as "Game" when no release has that exact name.
+func explainPreference(ctx context.Context, systemName, name string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	var systemID int64
+	err = database.Conn().QueryRow("SELECT id FROM systems WHERE name = ?", systemName).Scan(&systemID)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "System not found: %s\n", systemName)
+		os.Exit(1)
+	}
+
+	selector := library.NewPreferenceSelector(database.Conn(), preferenceConfig())
+
+	explanation, err := selector.Explain(ctx, systemID, name)
+	if errors.Is(err, library.ErrNotFound) {
+		explanation, err = selector.ExplainGroup(ctx, systemID, name)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error explaining preference: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(explanation)
+		return
+	}
+
+	fmt.Printf("Preference group: %s (%d candidates)\n\n", explanation.BaseTitle, len(explanation.Candidates))
+	fmt.Printf("  %-6s %8s %9s %8s %6s %5s  %s\n", "", "Language", "Stability", "Revision", "Region", "Total", "Release")
+	for _, c := range explanation.Candidates {
+		marker := ""
+		if c.IsPreferred {
+			marker = "WINNER"
+		}
+		fmt.Printf("  %-6s %8d %9d %8d %6d %5d  %s\n", marker,
+			c.Breakdown.Language, c.Breakdown.Stability, c.Breakdown.Revision, c.Breakdown.Region,
+			c.Score, c.Name)
+	}
+
+	fmt.Println("\nParsed tags:")
+	for _, c := range explanation.Candidates {
+		fmt.Printf("  %s: regions %s, languages %s, revision %d, %s\n", c.Name,
+			joinOrNone(c.Regions), joinOrNone(c.Languages), c.Revision, c.Stability)
+	}
+
+	fmt.Println("\nStored selection:")
+	for _, c := range explanation.Candidates {
+		switch {
+		case c.StoredPreferred:
+			fmt.Printf("  %s: preferred\n", c.Name)
+		case c.StoredIgnoreReason != "":
+			fmt.Printf("  %s: ignored (%s)\n", c.Name, c.StoredIgnoreReason)
+		default:
+			fmt.Printf("  %s: not selected yet\n", c.Name)
+		}
+	}
+
+	for _, c := range explanation.Candidates {
+		if c.IsPreferred && !c.StoredPreferred {
+			fmt.Printf("\nStored selection is out of date; run: romman prefer rebuild %s\n", systemName)
+			break
+		}
+	}
+}
+
+// joinOrNone joins values with commas, or returns "none" for an empty list.
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ",")
+}
+
+// preferenceConfig returns the region order and score weights from config,
+// falling back to the built-in defaults for unset values.
+func preferenceConfig() library.PreferenceConfig {
+	prefs := library.DefaultPreferenceConfig()
+	if cfg == nil {
+		return prefs
+	}
+	prefs.RegionOrder = cfg.GetRegionOrder()
+	p := cfg.Preferences
+	if p.LanguageWeight != nil {
+		prefs.LanguageWeight = *p.LanguageWeight
+	}
+	if p.StabilityWeight != nil {
+		prefs.StabilityWeight = *p.StabilityWeight
+	}
+	if p.RevisionWeight != nil {
+		prefs.RevisionWeight = *p.RevisionWeight
+	}
+	if p.RegionWeight != nil {
+		prefs.RegionWeight = *p.RegionWeight
+	}
+	prefs.GroupByClones = p.GroupByClones
+	return prefs
+}
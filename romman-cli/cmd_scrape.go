@@ -54,6 +54,33 @@ func handleScrapeCommand(ctx context.Context, args []string) {
 }
 
 func setupMetadataService(db *db.DB) (*metadata.Service, error) {
+	homeDir, _ := os.UserHomeDir()
+	mediaRoot := filepath.Join(homeDir, ".romman", "media")
+
+	switch cfg.Metadata.Provider {
+	case "", "igdb":
+	case "screenscraper":
+		ss := cfg.Metadata.ScreenScraper
+		provider, err := metadata.NewScreenScraperProvider(metadata.ScreenScraperConfig{
+			DevID:       ss.DevID,
+			DevPassword: ss.DevPassword,
+			Username:    ss.Username,
+			Password:    ss.Password,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to init ScreenScraper provider: %w", err)
+		}
+		return metadata.NewService(db, provider, mediaRoot), nil
+	case "thumbnails":
+		provider, err := metadata.NewThumbnailsProvider(cfg.Metadata.ThumbnailsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init thumbnails provider: %w", err)
+		}
+		return metadata.NewService(db, provider, mediaRoot), nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider %q (use igdb, screenscraper or thumbnails)", cfg.Metadata.Provider)
+	}
+
 	clientID := os.Getenv("IGDB_CLIENT_ID")
 	clientSecret := os.Getenv("IGDB_CLIENT_SECRET")
 	if clientID == "" || clientSecret == "" {
@@ -64,8 +91,5 @@ func setupMetadataService(db *db.DB) (*metadata.Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to init IGDB provider: %w", err)
 	}
-
-	homeDir, _ := os.UserHomeDir()
-	mediaRoot := filepath.Join(homeDir, ".romman", "media")
 	return metadata.NewService(db, provider, mediaRoot), nil
 }
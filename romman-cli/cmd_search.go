@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleSearchCommand(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: romman search <query>")
+		os.Exit(1)
+	}
+	query := strings.Join(args, " ")
+
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	hits, err := library.Search(ctx, database.Conn(), query)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error searching: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(hits)
+		return
+	}
+
+	if len(hits) == 0 {
+		fmt.Printf("No releases match %q.\n", query)
+		return
+	}
+
+	rowsData := make([][]string, 0, len(hits))
+	for _, hit := range hits {
+		if !hit.Matched {
+			rowsData = append(rowsData, []string{hit.System, hit.Release, "-", "(missing)"})
+			continue
+		}
+		for _, loc := range hit.Locations {
+			path := loc.Path
+			if loc.ArchivePath != "" {
+				path += ":" + loc.ArchivePath
+			}
+			rowsData = append(rowsData, []string{hit.System, hit.Release, loc.Library, path})
+		}
+	}
+	PrintTable([]string{"SYSTEM", "RELEASE", "LIBRARY", "PATH"}, rowsData)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleStatsCommand(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		showCollectionStats(ctx)
+		return
+	}
+
+	switch args[0] {
+	case "space":
+		showSpaceUsage(ctx)
+	default:
+		fmt.Printf("Unknown stats command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// showCollectionStats prints totals across every system and library.
+func showCollectionStats(ctx context.Context) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	stats, err := library.GetCollectionStats(ctx, database.Conn())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting stats: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(stats)
+		return
+	}
+
+	fmt.Printf("Systems:        %d\n", stats.Systems)
+	fmt.Printf("Libraries:      %d\n", stats.Libraries)
+	fmt.Printf("Releases:       %d\n", stats.Releases)
+	fmt.Printf("ROM entries:    %d\n", stats.ROMEntries)
+	fmt.Printf("Scanned files:  %d\n", stats.ScannedFiles)
+	fmt.Printf("Matched files:  %d\n", stats.MatchedFiles)
+	fmt.Printf("Completion:     %.1f%% (%d/%d releases)\n", stats.Completion, stats.MatchedReleases, stats.Releases)
+	fmt.Printf("Matched size:   %s\n", library.FormatBytes(stats.MatchedBytes))
+}
+
+func showSpaceUsage(ctx context.Context) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	report, err := library.GetSpaceUsage(ctx, database.Conn())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error getting space usage: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(report)
+		return
+	}
+
+	if len(report.Libraries) == 0 {
+		fmt.Println("No libraries found.")
+		return
+	}
+
+	fmt.Printf("  %-24s %12s %12s %8s\n", "", "Matched", "Total", "Files")
+	for _, sys := range report.Systems {
+		fmt.Printf("  %-24s %12s %12s %8d\n", sys.System,
+			library.FormatBytes(sys.MatchedBytes), library.FormatBytes(sys.TotalBytes), sys.TotalFiles)
+		for _, lib := range report.Libraries {
+			if lib.System != sys.System {
+				continue
+			}
+			fmt.Printf("    %-22s %12s %12s %8d\n", lib.Library,
+				library.FormatBytes(lib.MatchedBytes), library.FormatBytes(lib.TotalBytes), lib.TotalFiles)
+		}
+	}
+	fmt.Printf("\n  %-24s %12s %12s\n", "Total",
+		library.FormatBytes(report.MatchedBytes), library.FormatBytes(report.TotalBytes))
+}
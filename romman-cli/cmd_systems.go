@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/library"
 )
 
 func handleSystemsCommand(ctx context.Context, args []string) {
@@ -16,7 +19,7 @@ func handleSystemsCommand(ctx context.Context, args []string) {
 
 	switch args[0] {
 	case "list":
-		listSystems(ctx)
+		listSystems(ctx, args[1:])
 	case "info":
 		if len(args) < 2 {
 			fmt.Println("Usage: romman systems info <name>")
@@ -25,13 +28,58 @@ func handleSystemsCommand(ctx context.Context, args []string) {
 		showSystemInfo(ctx, args[1])
 	case "status":
 		showSystemsStatus(ctx)
+	case "conflicts":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman systems conflicts <name>")
+			os.Exit(1)
+		}
+		listHashConflicts(ctx, args[1])
+	case "stubs":
+		listStubSystems(ctx)
+	case "remove":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman systems remove <name> [--yes]")
+			os.Exit(1)
+		}
+		yes := len(args) >= 3 && args[2] == "--yes"
+		removeSystem(ctx, args[1], yes)
+	case "rename":
+		if len(args) < 3 {
+			fmt.Println("Usage: romman systems rename <old> <new>")
+			os.Exit(1)
+		}
+		renameSystem(ctx, args[1], args[2])
+	case "suggest":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman systems suggest <parent-dir>")
+			os.Exit(1)
+		}
+		suggestSystems(args[1])
 	default:
 		fmt.Printf("Unknown systems command: %s\n", args[0])
 		os.Exit(1)
 	}
 }
 
-func listSystems(ctx context.Context) {
+func listSystems(ctx context.Context, flags []string) {
+	sortBy := "name"
+	incompleteOnly := false
+	for _, flag := range flags {
+		switch {
+		case flag == "--incomplete":
+			incompleteOnly = true
+		case strings.HasPrefix(flag, "--sort="):
+			sortBy = strings.TrimPrefix(flag, "--sort=")
+		}
+	}
+
+	switch sortBy {
+	case "name", "releases", "completion":
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "Invalid sort: %s (use name, releases, or completion)\n", sortBy)
+		os.Exit(1)
+	}
+
 	database, err := openDB(ctx)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -39,43 +87,97 @@ func listSystems(ctx context.Context) {
 	}
 	defer func() { _ = database.Close() }()
 
-	rows, err := database.Conn().Query(`
-		SELECT s.name, s.dat_name, COUNT(r.id) as release_count
-		FROM systems s
-		LEFT JOIN releases r ON r.system_id = s.id
-		GROUP BY s.id
-		ORDER BY s.name
-	`)
+	systems, err := library.GetSystemCompletion(ctx, database.Conn())
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error querying systems: %v\n", err)
 		os.Exit(1)
 	}
-	defer func() { _ = rows.Close() }()
+
+	if incompleteOnly {
+		filtered := systems[:0]
+		for _, sys := range systems {
+			if !sys.IsComplete() {
+				filtered = append(filtered, sys)
+			}
+		}
+		systems = filtered
+	}
+
+	// Results arrive sorted by name; stable sort keeps name as the tie-breaker
+	switch sortBy {
+	case "releases":
+		sort.SliceStable(systems, func(i, j int) bool {
+			return systems[i].TotalReleases > systems[j].TotalReleases
+		})
+	case "completion":
+		sort.SliceStable(systems, func(i, j int) bool {
+			return systems[i].Completion > systems[j].Completion
+		})
+	}
 
 	var rowsData [][]string
 	var jsonData []map[string]interface{}
 
-	for rows.Next() {
-		var name, datName string
-		var releaseCount int
-		if err := rows.Scan(&name, &datName, &releaseCount); err != nil {
-			continue
-		}
-		rowsData = append(rowsData, []string{name, datName, fmt.Sprintf("%d", releaseCount)})
+	for _, sys := range systems {
+		rowsData = append(rowsData, []string{
+			sys.Name,
+			sys.DATName,
+			fmt.Sprintf("%d", sys.TotalReleases),
+			fmt.Sprintf("%d", sys.MatchedReleases),
+			fmt.Sprintf("%.1f%%", sys.Completion),
+		})
 		jsonData = append(jsonData, map[string]interface{}{
-			"name":     name,
-			"datName":  datName,
-			"releases": releaseCount,
+			"name":       sys.Name,
+			"datName":    sys.DATName,
+			"releases":   sys.TotalReleases,
+			"matched":    sys.MatchedReleases,
+			"completion": sys.Completion,
 		})
 	}
 
 	if outputCfg.JSON {
 		PrintResult(jsonData)
 	} else {
-		PrintTable([]string{"SYSTEM", "DAT NAME", "RELEASES"}, rowsData)
+		PrintTable([]string{"SYSTEM", "DAT NAME", "RELEASES", "MATCHED", "COMPLETE"}, rowsData)
 	}
 }
 
+func listStubSystems(ctx context.Context) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	stubs, err := library.GetStubSystems(ctx, database.Conn())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error querying systems: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(stubs)
+		return
+	}
+	if len(stubs) == 0 {
+		fmt.Println("Every system with a library has a DAT.")
+		return
+	}
+
+	var rowsData [][]string
+	for _, st := range stubs {
+		reason := "no DAT source"
+		if st.HasDAT {
+			reason = "no releases"
+		}
+		rowsData = append(rowsData, []string{st.Name, strings.Join(st.Libraries, ", "), reason})
+	}
+	PrintTable([]string{"SYSTEM", "LIBRARIES", "PROBLEM"}, rowsData)
+	fmt.Println()
+	fmt.Println("Files in these libraries cannot match until a DAT is imported (romman dat import <file>).")
+}
+
 func showSystemInfo(ctx context.Context, name string) {
 	database, err := openDB(ctx)
 	if err != nil {
@@ -112,6 +214,29 @@ func showSystemInfo(ctx context.Context, name string) {
 		WHERE r.system_id = ?
 	`, system.id).Scan(&romCount)
 
+	// Releases imported before sources were tracked belong to none
+	sources, err := dat.ListDATSources(ctx, database.Conn(), name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error listing DAT sources: %v\n", err)
+		os.Exit(1)
+	}
+	var unsourced int
+	_ = database.Conn().QueryRow(`
+		SELECT COUNT(*) FROM releases r
+		WHERE r.system_id = ? AND NOT EXISTS (SELECT 1 FROM release_sources rs WHERE rs.release_id = r.id)
+	`, system.id).Scan(&unsourced)
+	sourceInfo := make([]map[string]interface{}, 0, len(sources))
+	for _, src := range sources {
+		sourceInfo = append(sourceInfo, map[string]interface{}{
+			"type":       src.SourceType,
+			"priority":   src.Priority,
+			"datName":    src.DATName,
+			"datVersion": src.DATVersion,
+			"releases":   src.Releases,
+			"exclusive":  src.Exclusive,
+		})
+	}
+
 	res := map[string]interface{}{
 		"name":        name,
 		"displayName": dat.GetSystemDisplayName(name),
@@ -122,6 +247,8 @@ func showSystemInfo(ctx context.Context, name string) {
 		"releases":    releaseCount,
 		"roms":        romCount,
 		"added":       system.createdAt,
+		"sources":     sourceInfo,
+		"unsourced":   unsourced,
 	}
 
 	if outputCfg.JSON {
@@ -138,6 +265,136 @@ func showSystemInfo(ctx context.Context, name string) {
 		fmt.Printf("Releases: %d\n", releaseCount)
 		fmt.Printf("ROM Entries: %d\n", romCount)
 		fmt.Printf("Added: %s\n", system.createdAt)
+		if len(sources) > 0 {
+			fmt.Println()
+			fmt.Println("DAT Sources:")
+			for _, src := range sources {
+				fmt.Printf("  %d. %s: %d releases (%d only from it)", src.Priority, src.SourceType, src.Releases, src.Exclusive)
+				if src.DATVersion != "" {
+					fmt.Printf(", %s", src.DATVersion)
+				}
+				fmt.Println()
+			}
+		}
+		if unsourced > 0 {
+			fmt.Printf("Releases with no recorded source: %d\n", unsourced)
+		}
+	}
+}
+
+// removeSystem deletes a system with its DATs, releases and libraries. Without
+// yes it only reports what would be deleted and exits non-zero.
+func removeSystem(ctx context.Context, name string, yes bool) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	if !yes {
+		deps, err := dat.CountSystemDependents(ctx, database.Conn(), name)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removing %s would delete:\n", name)
+		printSystemDependents(deps)
+		fmt.Println("Files on disk are kept. Re-run with --yes to remove it.")
+		os.Exit(1)
+	}
+
+	deps, err := dat.RemoveSystem(ctx, database.Conn(), name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error removing system: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(deps)
+		return
+	}
+	fmt.Printf("Removed system %s:\n", name)
+	printSystemDependents(deps)
+}
+
+func renameSystem(ctx context.Context, oldName, newName string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := dat.RenameSystem(ctx, database.Conn(), oldName, newName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error renaming system: %v\n", err)
+		os.Exit(1)
+	}
+	if !result.Known {
+		PrintError("Warning: %s is not a known system id, so DATs and directories won't be detected as it\n", result.NewName)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(result)
+		return
+	}
+	fmt.Printf("Renamed system %s to %s\n", result.OldName, result.NewName)
+	if len(result.Libraries) > 0 {
+		fmt.Printf("Libraries now under %s: %s\n", result.NewName, strings.Join(result.Libraries, ", "))
+	}
+}
+
+func printSystemDependents(deps *dat.SystemDependents) {
+	fmt.Printf("  Releases:      %d (%d ROMs)\n", deps.Releases, deps.ROMEntries)
+	fmt.Printf("  DAT sources:   %d\n", deps.DATSources)
+	fmt.Printf("  Libraries:     %d (%d scanned files)\n", deps.Libraries, deps.ScannedFiles)
+	fmt.Printf("  Matches:       %d\n", deps.Matches)
+}
+
+// listHashConflicts lists SHA1s claimed by more than one release of a
+// system, so users can see where their DAT sources disagree.
+func listHashConflicts(ctx context.Context, name string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	var systemID int64
+	err = database.Conn().QueryRow("SELECT id FROM systems WHERE name = ?", name).Scan(&systemID)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "System not found: %s\n", name)
+		os.Exit(1)
+	}
+
+	conflicts, err := dat.NewImporter(database.Conn()).FindHashConflicts(ctx, systemID)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error finding hash conflicts: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(conflicts)
+		return
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Printf("No hash conflicts in %s.\n", name)
+		return
+	}
+
+	fmt.Printf("Hash conflicts in %s (%d):\n", name, len(conflicts))
+	for _, c := range conflicts {
+		fmt.Printf("\n  SHA1 %s\n", c.SHA1)
+		for _, e := range c.Entries {
+			source := e.Source
+			if source == "" {
+				source = "unknown source"
+			}
+			fmt.Printf("    %s [%s]\n", e.ReleaseName, source)
+		}
 	}
 }
 
@@ -190,3 +447,69 @@ func showSystemsStatus(ctx context.Context) {
 		PrintTable([]string{"SYSTEM", "RELEASES", "PREFERRED", "LIBRARIES"}, rowsData)
 	}
 }
+
+// suggestSystems lists subdirectories that discover can't map to a system,
+// with the closest known system, and prints directory_mappings entries to
+// paste into systems.yaml.
+func suggestSystems(parentDir string) {
+	entries, err := os.ReadDir(parentDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	mappings := dat.LoadSystemMappings().DirectoryMappings
+	suggestions := make([]library.SystemSuggestion, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if _, ok := dat.DetectSystemFromDirName(entry.Name()); ok {
+			continue
+		}
+		suggestions = append(suggestions, library.SuggestSystem(entry.Name(), mappings))
+	}
+
+	if outputCfg.JSON {
+		PrintResult(suggestions)
+		return
+	}
+
+	if len(suggestions) == 0 {
+		fmt.Println("All subdirectories map to a known system.")
+		return
+	}
+
+	fmt.Printf("Unmapped directories in %s:\n\n", parentDir)
+	for _, s := range suggestions {
+		if s.System == "" {
+			fmt.Printf("  %-24s -> (no close match)\n", s.Directory)
+			continue
+		}
+		fmt.Printf("  %-24s -> %-8s (like %q, %.0f%%)\n", s.Directory, s.System, s.ClosestKey, s.Score*100)
+	}
+
+	fmt.Println("\nAdd to systems.yaml, checking each system:")
+	fmt.Println()
+	fmt.Println("directory_mappings:")
+	for _, s := range suggestions {
+		if s.System == "" {
+			fmt.Printf("  %s: \"\"  # TODO: set the system for %q\n", yamlKey(s.MappingKey), s.Directory)
+			continue
+		}
+		fmt.Printf("  %s: %s\n", yamlKey(s.MappingKey), s.System)
+	}
+}
+
+// yamlKey quotes a mapping key that YAML would read as a number or boolean.
+// Keys are already normalized to lowercase letters and digits.
+func yamlKey(key string) string {
+	switch key {
+	case "", "y", "n", "yes", "no", "on", "off", "true", "false", "null":
+		return fmt.Sprintf("%q", key)
+	}
+	if strings.TrimLeft(key, "0123456789") == "" {
+		return fmt.Sprintf("%q", key)
+	}
+	return key
+}
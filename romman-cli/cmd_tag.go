@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func handleTagCommand(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: romman library tag <add|remove|list> ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add", "remove":
+		if len(args) < 4 {
+			fmt.Printf("Usage: romman library tag %s <library> <path> <tag> [--archive=<entry>]\n", args[0])
+			fmt.Println("Tags: keep, delete, replace, or any single-word label")
+			os.Exit(1)
+		}
+		archivePath := ""
+		for _, flag := range args[4:] {
+			if strings.HasPrefix(flag, "--archive=") {
+				archivePath = strings.TrimPrefix(flag, "--archive=")
+			}
+		}
+		updateTag(ctx, args[0] == "add", args[1], args[2], archivePath, args[3])
+	case "list":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman library tag list <library> [--tag=<tag>]")
+			os.Exit(1)
+		}
+		tag := ""
+		for _, flag := range args[2:] {
+			if strings.HasPrefix(flag, "--tag=") {
+				tag = strings.TrimPrefix(flag, "--tag=")
+			}
+		}
+		listTags(ctx, args[1], tag)
+	default:
+		fmt.Printf("Unknown tag command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func updateTag(ctx context.Context, add bool, libName, path, archivePath, tag string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	tags := library.NewTagManager(database.Conn())
+	if add {
+		err = tags.Add(ctx, libName, path, archivePath, tag)
+	} else {
+		err = tags.Remove(ctx, libName, path, archivePath, tag)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if add {
+		PrintInfo("Tagged %s as %s\n", path, tag)
+	} else {
+		PrintInfo("Removed tag %s from %s\n", tag, path)
+	}
+}
+
+func listTags(ctx context.Context, libName, tag string) {
+	database, err := openDB(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = database.Close() }()
+
+	tags, err := library.NewTagManager(database.Conn()).List(ctx, libName, tag)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error listing tags: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputCfg.JSON {
+		PrintResult(tags)
+		return
+	}
+
+	if len(tags) == 0 {
+		fmt.Println("No tagged files.")
+		return
+	}
+
+	fmt.Printf("Tagged files (%d):\n", len(tags))
+	for _, t := range tags {
+		path := t.Path
+		if t.ArchivePath != "" {
+			path += ":" + t.ArchivePath
+		}
+		fmt.Printf("  %-8s %s\n", t.Tag, path)
+	}
+}
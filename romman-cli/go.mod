@@ -3,6 +3,7 @@ module github.com/ryanm101/romman-cli
 go 1.24.0
 
 require (
+	github.com/fsnotify/fsnotify v1.8.0 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/schollz/progressbar/v3 v3.19.0 // indirect
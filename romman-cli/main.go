@@ -69,14 +69,14 @@ func main() {
 	case "systems":
 		if len(args) < 2 {
 			fmt.Println("Usage: romman systems <command>")
-			fmt.Println("Commands: list, info, status")
+			fmt.Println("Commands: list, info, status, stubs, suggest")
 			os.Exit(1)
 		}
 		handleSystemsCommand(ctx, args[1:])
 	case "library":
 		if len(args) < 2 {
 			fmt.Println("Usage: romman library <command>")
-			fmt.Println("Commands: add, list, scan, status, unmatched, discover")
+			fmt.Println("Commands: add, list, scan, status, unmatched, discover, tag")
 			os.Exit(1)
 		}
 		handleLibraryCommand(ctx, args[1:])
@@ -97,19 +97,30 @@ func main() {
 	case "prefer":
 		if len(args) < 2 {
 			fmt.Println("Usage: romman prefer <command>")
-			fmt.Println("Commands: rebuild, list")
+			fmt.Println("Commands: rebuild, list, explain")
 			os.Exit(1)
 		}
 		handlePreferCommand(ctx, args[1:])
-	case "export":
-		if len(args) < 3 {
-			fmt.Println("Usage: romman export <library> <report> <format> [file]")
-			fmt.Println("       romman export <library> retroarch <output.lpl>")
-			fmt.Println("Reports: matched, missing, preferred, unmatched, 1g1r")
-			fmt.Println("Formats: csv, json, retroarch")
+	case "bios":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman bios <command>")
+			fmt.Println("Commands: scan, status")
+			os.Exit(1)
+		}
+		handleBIOSCommand(ctx, args[1:])
+	case "matches":
+		if len(args) < 2 {
+			fmt.Println("Usage: romman matches <command>")
+			fmt.Println("Commands: reassign")
 			os.Exit(1)
 		}
+		handleMatchesCommand(ctx, args[1:])
+	case "stats":
+		handleStatsCommand(ctx, args[1:])
+	case "export":
 		handleExportCommand(ctx, args[1:])
+	case "search":
+		handleSearchCommand(ctx, args[1:])
 
 	case "help", "-h", "--help":
 		printUsage()
@@ -121,6 +132,8 @@ func main() {
 			os.Exit(1)
 		}
 		handleBackupCommand(ctx, args[1:])
+	case "db":
+		handleDBCommand(ctx, args[1:])
 	case "config":
 		handleConfigCommand(ctx, args[1:])
 	case "scrape":
@@ -142,31 +155,93 @@ func printUsage() {
 	fmt.Println("  --quiet, -q                         Suppress non-error output")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  dat import <file>                   Import a DAT file")
+	fmt.Println("  dat import <file|url>               Import a DAT file, or download one")
+	fmt.Println("  dat update <file|url> [--prune]     Re-import a changed DAT; --prune removes dropped releases")
 	fmt.Println("  dat scan                            Auto-import DATs from dat_dir")
-	fmt.Println("  systems list                        List all systems")
+	fmt.Println("  dat link [system]                   Link clones to parents for a system, or all")
+	fmt.Println("  dat sources <system>                List a system's DAT sources in priority order")
+	fmt.Println("  dat remove-source <system> <type|priority> [--yes]")
+	fmt.Println("                                      Remove a DAT source and the releases only it lists")
+	fmt.Println("  systems list [--sort=name|releases|completion] [--incomplete]")
+	fmt.Println("                                      List systems with completion %")
 	fmt.Println("  systems info <name>                 Show system details")
 	fmt.Println("  systems status                      Show all systems summary")
-	fmt.Println("  library add <name> <path> <system>  Add a library")
+	fmt.Println("  systems stubs                       List systems with libraries but no DAT")
+	fmt.Println("  systems conflicts <name>            List SHA1s claimed by more than one release")
+	fmt.Println("  systems suggest <parent-dir>        Suggest systems.yaml mappings for unknown directories")
+	fmt.Println("  systems remove <name> [--yes]       Delete a system with its DATs, releases and libraries")
+	fmt.Println("  systems rename <old> <new>          Rename a system, e.g. one detected as the wrong id")
+	fmt.Println("  library add <name> <path> <system> [--multi-system]")
+	fmt.Println("                                      Add a library (--multi-system: detect system per subdirectory)")
+	fmt.Println("  library multi-system <name> <on|off>")
+	fmt.Println("                                      Toggle per-subdirectory system detection")
 	fmt.Println("  library list                        List all libraries")
+	fmt.Println("  library remove <name> [--yes]       Remove a library and its scan data (files on disk are kept)")
+	fmt.Println("  library move <name> <new-path>      Point a library at its relocated ROMs, keeping the hash cache")
+	fmt.Println("  library diff <a> <b> [--preferred]  List releases one library has that the other lacks")
 	fmt.Println("  library discover <dir> [--add]      Auto-detect libraries from subdirs")
-	fmt.Println("  library scan <name>                 Scan a library for ROMs")
+	fmt.Println("  library scan <name> [--full] [--force-rehash] [--resume] [--no-archives] [--no-progress] [--fail-on-error]")
+	fmt.Println("                                      Scan a library for ROMs (--full: re-match all files, e.g. after a DAT update)")
 	fmt.Println("  library scan-all                    Scan all libraries")
-	fmt.Println("  library status <name>               Show release status")
-	fmt.Println("  library unmatched <name>            Show unmatched files")
+	fmt.Println("  library prune <name>                Drop scanned entries whose extension is now ignored")
+	fmt.Println("  library watch <name> [--once]       Rescan a library when its files change")
+	fmt.Println("  library status <name> [--verified-only] [--playable-only] [--release <title>]")
+	fmt.Println("                                      Show release status (--verified-only: require sha1/md5;")
+	fmt.Println("                                      --playable-only: skip MAME BIOS/device/mechanical sets;")
+	fmt.Println("                                      --release: list the release's missing ROMs)")
+	fmt.Println("  library unmatched <name> [--suggest]")
+	fmt.Println("                                      Show unmatched files (--suggest: likely")
+	fmt.Println("                                      releases by name and size)")
 	fmt.Println("  library rename <name> [--dry-run]   Rename files to DAT names")
-	fmt.Println("  library verify <name>               Check file integrity")
+	fmt.Println("  library dedupe <name> <quarantine> [--interactive] [--hardlink] [--summary]")
+	fmt.Println("                                      Generate a cleanup plan, choosing which duplicate to keep")
+	fmt.Println("  library verify <name> [--repair] [--dry-run]")
+	fmt.Println("                                      Check file integrity (--repair: rename verified files to DAT names)")
+	fmt.Println("  library replace <name> <file> <quarantine> [--dry-run] [--keep-name]")
+	fmt.Println("                                      Swap in a verified file and quarantine the flagged copy")
+	fmt.Println("  library tag add|remove <lib> <path> <tag>")
+	fmt.Println("                                      Tag a file (keep, delete, replace)")
+	fmt.Println("  library tag list <lib> [--tag=<t>]  List tagged files")
+	fmt.Println("  library import-hashes <lib> <file>  Import a hash list (sfv, csv, hash path) as virtual files")
+	fmt.Println("  matches reassign <lib> <file> <release>")
+	fmt.Println("                                      Replace a file's matches with a manual match kept by rescans")
 	fmt.Println("  duplicates list <library>           List duplicate files")
-	fmt.Println("  cleanup plan <lib> <quarantine>     Generate cleanup plan")
-	fmt.Println("  cleanup exec <plan> [--dry-run]     Execute cleanup plan")
+	fmt.Println("  cleanup plan <lib> <quarantine> [--hardlink] [--summary]")
+	fmt.Println("                                      Generate cleanup plan (--hardlink: link exact duplicates instead,")
+	fmt.Println("                                      --summary: group actions by reason)")
+	fmt.Println("  cleanup exec <plan> [--dry-run] [--resume] [--stop-on-error]")
+	fmt.Println("                                      Execute cleanup plan (--resume: retry failed/pending actions)")
+	fmt.Println("  cleanup undo <manifest> [--dry-run]  Move files a cleanup quarantined back")
 	fmt.Println("  prefer rebuild <system>             Rebuild preferred releases")
 	fmt.Println("  prefer list <system>                List preferred releases")
-	fmt.Println("  export <lib> <report> <fmt> [file]  Export report (csv/json)")
+	fmt.Println("  prefer explain <system> <title|release>")
+	fmt.Println("                                      Show why a release was or wasn't preferred")
+	fmt.Println("  export <lib> <report> <fmt> [file]  Export report (csv/tsv/json/txt; 1g1r accepts --verified-only, --fallback, --playable-only)")
+	fmt.Println("                                      --exclude-flagged: skip bad, overdumped and modified matches")
+	fmt.Println("  export <lib> retroarch <file.lpl>   Export a RetroArch playlist")
+	fmt.Println("  export <lib> gamelist [file]        Export an EmulationStation gamelist.xml")
+	fmt.Println("  export <lib> launchbox [file]       Export a LaunchBox platform XML")
+	fmt.Println("  export <lib> pegasus [file]         Export a Pegasus metadata.pegasus.txt")
+	fmt.Println("  export <lib> esde <collection> [dir] [--preferred] [--rom-path=<dir>]")
+	fmt.Println("                                      Export an ES-DE custom collection (--preferred: 1G1R set)")
+	fmt.Println("  export <lib> fixdat <file.dat>      Export a Logiqx DAT of missing ROMs")
+	fmt.Println("  export <lib> have dat [file]        Export a Logiqx DAT of the ROMs you have")
+	fmt.Println("  export <lib> missing dat [file]     Export a Logiqx DAT of wholly missing releases")
+	fmt.Println("  bios scan <dir>                     Match a shared BIOS directory for all systems")
+	fmt.Println("  bios status [system]                Show which BIOS files are present")
+	fmt.Println("  stats                               Show collection-wide totals and completion")
+	fmt.Println("  stats space                         Show disk used by matched and all files per system/library")
+	fmt.Println("  search <query>                      Find releases by name across all systems and libraries")
 	fmt.Println("  doctor                              Run database health checks")
 	fmt.Println("  backup <dest>                       Backup database to destination")
+	fmt.Println("  db vacuum                           Rebuild the database to reclaim space from deleted rows")
+	fmt.Println("  db analyze                          Refresh query planner statistics")
+	fmt.Println("  db stats                            Show database file size and table row counts")
+	fmt.Println("  db backup <path>                    Snapshot the database to a file or directory")
+	fmt.Println("  db restore <path>                   Replace the database with a backup")
 	fmt.Println("  config show                         Show active configuration")
 	fmt.Println("  config init                         Initialize example config")
-	fmt.Println("  scrape <release_id>                 Scrape metadata from IGDB")
+	fmt.Println("  scrape <release_id>                 Scrape metadata and media")
 	fmt.Println("  help                                Show this help")
 	fmt.Println()
 	fmt.Println("Environment:")
@@ -177,6 +252,18 @@ func getDBPath() string {
 	return cfg.GetDBPath()
 }
 
+// poolConfig returns the database pool settings from config.
+func poolConfig() db.PoolConfig {
+	if cfg == nil {
+		return db.DefaultPoolConfig()
+	}
+	return db.PoolConfig{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+	}
+}
+
 func openDB(ctx context.Context) (*db.DB, error) {
-	return db.Open(ctx, getDBPath())
+	return db.OpenWithConfig(ctx, getDBPath(), poolConfig())
 }
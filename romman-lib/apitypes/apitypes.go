@@ -0,0 +1,248 @@
+// Package apitypes defines the JSON request and response types of the
+// romman-web HTTP API, so Go clients can decode responses without
+// reverse-engineering their shape. Field order is the order keys appear in
+// the encoded JSON.
+package apitypes
+
+import "time"
+
+// StatsResponse is returned by GET /api/stats.
+type StatsResponse struct {
+	TotalSystems   int `json:"totalSystems"`
+	TotalLibraries int `json:"totalLibraries"`
+	TotalReleases  int `json:"totalReleases"`
+}
+
+// SpaceUsage holds the disk usage of scanned files for a library or system.
+type SpaceUsage struct {
+	System       string `json:"system"`
+	Library      string `json:"library,omitempty"` // Empty for system totals
+	TotalFiles   int    `json:"totalFiles"`
+	TotalBytes   int64  `json:"totalBytes"`
+	MatchedFiles int    `json:"matchedFiles"`
+	MatchedBytes int64  `json:"matchedBytes"`
+}
+
+// SpaceResponse is returned by GET /api/stats/space.
+type SpaceResponse struct {
+	Libraries    []SpaceUsage `json:"libraries"`
+	Systems      []SpaceUsage `json:"systems"`
+	TotalBytes   int64        `json:"totalBytes"`
+	MatchedBytes int64        `json:"matchedBytes"`
+}
+
+// SystemSummary is one entry of the /api/systems response.
+type SystemSummary struct {
+	Name      string `json:"name"`
+	Releases  int    `json:"releases"`
+	Preferred int    `json:"preferred"`
+	BIOSReady *bool  `json:"biosReady,omitempty"` // Nil when the system needs no BIOS
+}
+
+// SystemsResponse is returned by GET /api/systems.
+type SystemsResponse struct {
+	Systems []SystemSummary `json:"systems"`
+}
+
+// StubSystem is one entry of the /api/systems/stubs response: a system with
+// libraries but no DAT source or no releases, so its files cannot match.
+type StubSystem struct {
+	Name      string   `json:"name"`
+	Libraries []string `json:"libraries"`
+	Releases  int      `json:"releases"`
+	HasDAT    bool     `json:"hasDat"`
+}
+
+// StubSystemsResponse is returned by GET /api/systems/stubs.
+type StubSystemsResponse struct {
+	Systems []StubSystem `json:"systems"`
+}
+
+// LibrarySummary is one entry of the /api/libraries response.
+type LibrarySummary struct {
+	Name     string `json:"name"`
+	System   string `json:"system"`
+	Matched  int    `json:"matched"`
+	Total    int    `json:"total"`
+	MatchPct int    `json:"matchPct"`
+}
+
+// LibrariesResponse is returned by GET /api/libraries.
+type LibrariesResponse struct {
+	Libraries []LibrarySummary `json:"libraries"`
+}
+
+// StatusResponse is returned by endpoints that only report success, such
+// as POST /api/scan, and by GET /health.
+type StatusResponse struct {
+	Status string `json:"status"`
+	DB     string `json:"db,omitempty"`    // "true" or "false", set by /health only
+	Error  string `json:"error,omitempty"` // Set by a streamed scan's "error" event
+
+	// FileErrors counts the files a scan could not read and skipped, set by
+	// POST /api/scan and a streamed scan's "done" event
+	FileErrors int `json:"fileErrors,omitempty"`
+}
+
+// ScanProgress is the data of each "progress" event streamed by POST
+// /api/scan/stream, or POST /api/scan when the client accepts
+// text/event-stream.
+type ScanProgress struct {
+	TotalFiles   int64  `json:"totalFiles"` // 0 until the files have been counted
+	FilesScanned int64  `json:"filesScanned"`
+	FilesHashed  int64  `json:"filesHashed"`
+	FilesSkipped int64  `json:"filesSkipped"`
+	CurrentPath  string `json:"currentPath,omitempty"`
+	MatchesFound int64  `json:"matchesFound"` // Files matched so far; final in the last event before "done"
+}
+
+// ScanAllResponse is returned by POST /api/scan-all.
+type ScanAllResponse struct {
+	Status  string `json:"status"`
+	Scanned int    `json:"scanned"`
+	Total   int    `json:"total"`
+}
+
+// CountsResponse is returned by GET /api/counts.
+type CountsResponse struct {
+	Matched   int `json:"matched"`
+	Missing   int `json:"missing"`
+	Flagged   int `json:"flagged"`
+	Unmatched int `json:"unmatched"`
+	Preferred int `json:"preferred"`
+}
+
+// DetailItem is one row of the /api/details response.
+type DetailItem struct {
+	Name        string `json:"name"`
+	Path        string `json:"path,omitempty"`
+	MatchType   string `json:"matchType,omitempty"`
+	Flags       string `json:"flags,omitempty"`
+	Status      string `json:"status"` // matched, missing, flagged or unmatched
+	Description string `json:"description,omitempty"`
+	Boxart      string `json:"boxart,omitempty"` // URL under /api/media/
+}
+
+// DetailsResponse is returned by GET /api/details.
+type DetailsResponse struct {
+	Items    []DetailItem `json:"items"`
+	Total    int          `json:"total"`              // Items matching the filter and search, across all pages
+	Page     int          `json:"page,omitempty"`     // Set when paging was requested
+	PageSize int          `json:"pageSize,omitempty"` // Set when paging was requested
+}
+
+// SearchLocation is a library file matching a search hit.
+type SearchLocation struct {
+	Library     string `json:"library"`
+	Path        string `json:"path"`
+	ArchivePath string `json:"archivePath,omitempty"`
+}
+
+// SearchHit is a release matching a search, across all systems.
+type SearchHit struct {
+	ReleaseID int64            `json:"releaseId"`
+	Release   string           `json:"release"`
+	System    string           `json:"system"`
+	Matched   bool             `json:"matched"` // Some library has a file matching it
+	Locations []SearchLocation `json:"locations,omitempty"`
+}
+
+// SearchResponse is returned by GET /api/search.
+type SearchResponse struct {
+	Items    []SearchHit `json:"items"`
+	Total    int         `json:"total"` // Hits across all pages
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+}
+
+// PackGame is a game available for packing.
+type PackGame struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	FilePath string `json:"filePath"`
+	Size     int64  `json:"size"`
+}
+
+// PackSystem groups the packable games of one system.
+type PackSystem struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Games []PackGame `json:"games"`
+}
+
+// PackGamesResponse is returned by GET /api/packs/games.
+type PackGamesResponse struct {
+	Systems []PackSystem `json:"systems"`
+}
+
+// PackGenerateRequest is the request body of POST /api/packs/generate.
+type PackGenerateRequest struct {
+	GameIDs     []int64 `json:"gameIds"`
+	Format      string  `json:"format"`
+	Name        string  `json:"name"`
+	OnCollision string  `json:"onCollision,omitempty"` // "rename" (default) or "error"
+}
+
+// Job types accepted by POST /api/jobs.
+const (
+	JobRename   = "rename"
+	JobOrganize = "organize"
+	JobCleanup  = "cleanup"
+)
+
+// Job states reported by GET /api/jobs/{id}.
+const (
+	JobQueued    = "queued" // Waiting for another job on the same library
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// JobOptions holds the options of a job. Each job type reads only the
+// fields that apply to it.
+type JobOptions struct {
+	DryRun        bool   `json:"dryRun,omitempty"`
+	OutputDir     string `json:"outputDir,omitempty"`     // organize
+	Structure     string `json:"structure,omitempty"`     // organize: flat (default), system, system-region, system-genre or system-region-genre
+	Rename        bool   `json:"rename,omitempty"`        // organize: rename to DAT names
+	PreferredOnly bool   `json:"preferredOnly,omitempty"` // organize
+	MultiDisc     bool   `json:"multiDisc,omitempty"`     // organize
+	Copy          bool   `json:"copy,omitempty"`          // organize: copy instead of move
+	QuarantineDir string `json:"quarantineDir,omitempty"` // cleanup
+	Hardlink      bool   `json:"hardlink,omitempty"`      // organize: hardlink instead of move; cleanup: hardlink exact duplicates instead
+}
+
+// JobRequest is the request body of POST /api/jobs.
+type JobRequest struct {
+	Type    string     `json:"type"`
+	Library string     `json:"library"`
+	Options JobOptions `json:"options"`
+}
+
+// JobProgress reports how far a job has got. Total is known once the job
+// has planned its actions; Done is filled in when they have run.
+type JobProgress struct {
+	Phase string `json:"phase"` // waiting, planning, executing or finished
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// Job is returned by POST /api/jobs, GET /api/jobs and GET /api/jobs/{id}.
+type Job struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Library    string      `json:"library"`
+	Status     string      `json:"status"`
+	Progress   JobProgress `json:"progress"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"` // The rename, organize or cleanup result
+	CreatedAt  time.Time   `json:"createdAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// JobsResponse is returned by GET /api/jobs.
+type JobsResponse struct {
+	Jobs []Job `json:"jobs"`
+}
@@ -0,0 +1,84 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+func TestSystemsResponseJSON(t *testing.T) {
+	ready := true
+	data, err := json.Marshal(SystemsResponse{Systems: []SystemSummary{
+		{Name: "gba", Releases: 3, Preferred: 1, BIOSReady: &ready},
+		{Name: "nes", Releases: 2},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"systems":[{"name":"gba","releases":3,"preferred":1,"biosReady":true},{"name":"nes","releases":2,"preferred":0}]}`,
+		string(data))
+}
+
+func TestSpaceUsageMatchesLibrary(t *testing.T) {
+	// The web handler converts library.SpaceUsage directly, so both must
+	// encode identically
+	u := library.SpaceUsage{System: "nes", Library: "a", TotalFiles: 2, TotalBytes: 10, MatchedFiles: 1, MatchedBytes: 5}
+	want, err := json.Marshal(u)
+	require.NoError(t, err)
+	got, err := json.Marshal(SpaceUsage(u))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestStubSystemMatchesLibrary(t *testing.T) {
+	st := library.StubSystem{Name: "snes", Libraries: []string{"snes-a"}, HasDAT: false}
+	want, err := json.Marshal(st)
+	require.NoError(t, err)
+	got, err := json.Marshal(StubSystem(st))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestScanProgressMatchesLibrary(t *testing.T) {
+	p := library.ScanProgress{TotalFiles: 10, FilesScanned: 4, FilesHashed: 3, FilesSkipped: 1, CurrentPath: "/roms/a.nes"}
+	want, err := json.Marshal(p)
+	require.NoError(t, err)
+	got, err := json.Marshal(ScanProgress(p))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestJobJSON(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := json.Marshal(Job{
+		ID:        "job-1",
+		Type:      JobOrganize,
+		Library:   "nes",
+		Status:    JobQueued,
+		Progress:  JobProgress{Phase: "waiting"},
+		CreatedAt: created,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t,
+		`{"id":"job-1","type":"organize","library":"nes","status":"queued","progress":{"phase":"waiting","done":0,"total":0},"createdAt":"2024-01-02T03:04:05Z"}`,
+		string(data))
+
+	var req JobRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"cleanup","library":"nes","options":{"quarantineDir":"/q","dryRun":true}}`), &req))
+	assert.Equal(t, JobRequest{Type: JobCleanup, Library: "nes", Options: JobOptions{DryRun: true, QuarantineDir: "/q"}}, req)
+}
+
+func TestSearchHitMatchesLibrary(t *testing.T) {
+	hit := library.SearchHit{ReleaseID: 7, Release: "Chrono Trigger (USA)", System: "snes", Matched: true,
+		Locations: []library.SearchLocation{{Library: "snes-a", Path: "/roms/ct.zip", ArchivePath: "ct.sfc"}}}
+	want, err := json.Marshal(hit)
+	require.NoError(t, err)
+	got, err := json.Marshal(SearchHit{ReleaseID: 7, Release: "Chrono Trigger (USA)", System: "snes", Matched: true,
+		Locations: []SearchLocation{SearchLocation(hit.Locations[0])}})
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
@@ -3,18 +3,57 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds application configuration.
 type Config struct {
-	DBPath        string        `yaml:"db_path"`
-	DatDir        string        `yaml:"dat_dir"`
-	RegionOrder   []string      `yaml:"region_order"`
-	QuarantineDir string        `yaml:"quarantine_dir"`
-	Scan          ScanConfig    `yaml:"scan"`
-	Logging       LoggingConfig `yaml:"logging"`
+	DBPath        string            `yaml:"db_path"`
+	DatDir        string            `yaml:"dat_dir"`
+	DatTimeout    time.Duration     `yaml:"dat_timeout"` // Timeout of `dat import <url>` downloads (0 = 5m)
+	RegionOrder   []string          `yaml:"region_order"`
+	QuarantineDir string            `yaml:"quarantine_dir"`
+	Scan          ScanConfig        `yaml:"scan"`
+	DB            DBConfig          `yaml:"db"`
+	Logging       LoggingConfig     `yaml:"logging"`
+	Duplicates    DuplicatesConfig  `yaml:"duplicates"`
+	Preferences   PreferencesConfig `yaml:"preferences"`
+	Metadata      MetadataConfig    `yaml:"metadata"`
+
+	// Per-system settings, keyed by system name
+	Systems map[string]SystemConfig `yaml:"systems"`
+
+	// Per-library settings, keyed by library name
+	Libraries map[string]LibraryConfig `yaml:"libraries"`
+}
+
+// LibraryConfig holds settings that apply to a single library.
+type LibraryConfig struct {
+	// Glob patterns of files to skip, e.g. ["*.sav", "saves/*.bin"]; patterns
+	// with a "/" match the path relative to the library root
+	IgnorePatterns []string `yaml:"ignore_patterns"`
+
+	// Extensions to scan even though they are ignored by default or by
+	// scan.ignore_extensions, e.g. [".txt"] for manuals
+	IncludeExtensions []string `yaml:"include_extensions"`
+}
+
+// SystemConfig holds settings that apply to a single system.
+type SystemConfig struct {
+	SplitROMs []SplitROMConfig `yaml:"split_roms"` // ROMs stored as sibling part files
+
+	// ROM extensions used by the system, replacing those learned from its DAT.
+	// Decides whether extension-less, .bin and .rom files are scanned; "" means no extension.
+	Extensions []string `yaml:"extensions"`
+}
+
+// SplitROMConfig describes a ROM split into sibling files that are
+// concatenated before matching, e.g. parts ["-lo", "-hi"] joins
+// "game-lo.bin" and "game-hi.bin".
+type SplitROMConfig struct {
+	Parts []string `yaml:"parts"` // Filename suffixes before the extension, in order
 }
 
 // ScanConfig holds scan-related configuration.
@@ -22,6 +61,79 @@ type ScanConfig struct {
 	Workers   int  `yaml:"workers"`    // Number of parallel workers (0 = auto)
 	BatchSize int  `yaml:"batch_size"` // Files per transaction batch
 	Parallel  bool `yaml:"parallel"`   // Enable parallel scanning
+
+	// Percentage of cached files rehashed each scan to detect bitrot (0 = off)
+	SampleVerifyPercent float64 `yaml:"sample_verify_percent"`
+
+	// Skip directories on other mounted filesystems, like find -xdev (no effect on Windows)
+	OneFileSystem bool `yaml:"one_file_system"`
+
+	// Extra file extensions to skip, e.g. [".bak", ".ips"]; already-scanned entries are pruned
+	IgnoreExtensions []string `yaml:"ignore_extensions"`
+
+	// Also hash files with SHA256 and match on it first, for Redump disc DATs (default off)
+	SHA256 bool `yaml:"sha256"`
+
+	// Match files nothing else matched to the closest release name within fuzzy_distance edits (default off)
+	Fuzzy         bool `yaml:"fuzzy"`
+	FuzzyDistance int  `yaml:"fuzzy_distance"` // Largest edit distance for fuzzy matches (0 = default 2)
+
+	// Files from this many MiB up compute each hash in its own goroutine (0 = default 64, -1 = never)
+	ParallelHashMinMB int `yaml:"parallel_hash_min_mb"`
+
+	// What marks a file unchanged since it was hashed: "mtime+size" (default) or "size"
+	CacheKey string `yaml:"cache_key"`
+
+	// Hash the entries of .zip and .7z files (default true); false hashes each archive whole
+	ExpandArchives *bool `yaml:"expand_archives"`
+}
+
+// DuplicatesConfig tunes which copy of a duplicate is kept by cleanup.
+// Unset values keep the built-in weights.
+type DuplicatesConfig struct {
+	MatchScores         map[string]int `yaml:"match_scores"`          // Points per match type, e.g. sha1: 100
+	FlagPenalty         *int           `yaml:"flag_penalty"`          // Points subtracted for bad-dump etc. (default 10)
+	PreferArchive       *bool          `yaml:"prefer_archive"`        // true = keep zipped copies, false = keep loose files
+	PreferredPathPrefix string         `yaml:"preferred_path_prefix"` // Copies under this directory are kept first
+	PathPrefixBonus     *int           `yaml:"path_prefix_bonus"`     // Points for the preferred prefix (default 50)
+	PathDepthWeight     *int           `yaml:"path_depth_weight"`     // Points lost per 10 path characters (default 1, 0 = off)
+}
+
+// PreferencesConfig weighs the parts of a release's score when picking the
+// preferred release of a title. Unset values keep the built-in weights.
+type PreferencesConfig struct {
+	LanguageWeight  *int `yaml:"language_weight"`  // Points for an English release (default 1000)
+	StabilityWeight *int `yaml:"stability_weight"` // Points for a stable release; betas etc. get a fraction (default 500)
+	RevisionWeight  *int `yaml:"revision_weight"`  // Points per revision step (default 10)
+	RegionWeight    *int `yaml:"region_weight"`    // Points per place in region_order (default 50)
+
+	// Group releases by the DAT's parent/clone links rather than by base
+	// title; releases without links still group by title
+	GroupByClones bool `yaml:"group_by_clones"`
+}
+
+// MetadataConfig selects the metadata scraper used by scrape commands.
+type MetadataConfig struct {
+	Provider      string              `yaml:"provider"` // "igdb" (default), "screenscraper" or "thumbnails"
+	ScreenScraper ScreenScraperConfig `yaml:"screenscraper"`
+	ThumbnailsDir string              `yaml:"thumbnails_dir"` // Local RetroArch thumbnails pack for "thumbnails"
+}
+
+// ScreenScraperConfig holds ScreenScraper.fr API credentials.
+type ScreenScraperConfig struct {
+	DevID       string `yaml:"dev_id"`       // Developer credentials, required
+	DevPassword string `yaml:"dev_password"` // Developer credentials, required
+	Username    string `yaml:"username"`     // Member account, optional; raises rate limits
+	Password    string `yaml:"password"`
+}
+
+// DBConfig holds database connection pool configuration.
+// SQLite in WAL mode serves one writer alongside many readers, so the pool
+// should allow the writer plus the expected number of concurrent readers.
+type DBConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`    // Maximum open connections (0 = NumCPU+1)
+	MaxIdleConns    int           `yaml:"max_idle_conns"`    // Maximum idle connections (0 = max_open_conns)
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"` // Maximum connection age (0 = unlimited)
 }
 
 // LoggingConfig holds logging configuration.
@@ -109,6 +221,18 @@ func (c *Config) applyEnvOverrides() {
 	if datDir := os.Getenv("ROMMAN_DAT_DIR"); datDir != "" {
 		c.DatDir = datDir
 	}
+	if devID := os.Getenv("SCREENSCRAPER_DEV_ID"); devID != "" {
+		c.Metadata.ScreenScraper.DevID = devID
+	}
+	if devPassword := os.Getenv("SCREENSCRAPER_DEV_PASSWORD"); devPassword != "" {
+		c.Metadata.ScreenScraper.DevPassword = devPassword
+	}
+	if user := os.Getenv("SCREENSCRAPER_USER"); user != "" {
+		c.Metadata.ScreenScraper.Username = user
+	}
+	if password := os.Getenv("SCREENSCRAPER_PASSWORD"); password != "" {
+		c.Metadata.ScreenScraper.Password = password
+	}
 }
 
 // GetDBPath returns the database path, applying defaults.
@@ -131,3 +255,9 @@ func (c *Config) GetRegionOrder() []string {
 	}
 	return []string{"Europe", "World", "USA", "Japan"}
 }
+
+// GetExpandArchives reports whether scans hash the entries of archives
+// rather than the archives themselves, which they do unless disabled.
+func (c *Config) GetExpandArchives() bool {
+	return c.Scan.ExpandArchives == nil || *c.Scan.ExpandArchives
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,6 +18,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 0, cfg.Scan.Workers)
 	assert.Equal(t, 100, cfg.Scan.BatchSize)
 	assert.True(t, cfg.Scan.Parallel)
+	assert.Equal(t, DBConfig{}, cfg.DB)
 	assert.Equal(t, "text", cfg.Logging.Format)
 	assert.Equal(t, "info", cfg.Logging.Level)
 }
@@ -58,6 +60,15 @@ func TestConfig_GetRegionOrder(t *testing.T) {
 	}
 }
 
+func TestConfig_GetExpandArchives(t *testing.T) {
+	cfg := &Config{}
+	assert.True(t, cfg.GetExpandArchives())
+
+	expand := false
+	cfg.Scan.ExpandArchives = &expand
+	assert.False(t, cfg.GetExpandArchives())
+}
+
 func TestConfig_LoadFromFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
@@ -73,9 +84,36 @@ scan:
   workers: 4
   batch_size: 50
   parallel: false
+  sample_verify_percent: 2.5
+  one_file_system: true
+  sha256: true
+  fuzzy: true
+  fuzzy_distance: 3
+  parallel_hash_min_mb: 256
+  cache_key: size
+  ignore_extensions: [".bak", "ips"]
+db:
+  max_open_conns: 8
+  max_idle_conns: 8
+  conn_max_lifetime: 30m
 logging:
   format: json
   level: debug
+duplicates:
+  match_scores:
+    crc32: 95
+  flag_penalty: 0
+  prefer_archive: false
+  preferred_path_prefix: /roms/keep
+preferences:
+  region_weight: 2000
+  language_weight: 0
+  group_by_clones: true
+systems:
+  atari2600:
+    split_roms:
+      - parts: ["-lo", "-hi"]
+    extensions: [".a26", ".bin"]
 `
 	err := os.WriteFile(configPath, []byte(configContent), 0644) // #nosec G306
 	require.NoError(t, err)
@@ -91,8 +129,34 @@ logging:
 	assert.Equal(t, 4, cfg.Scan.Workers)
 	assert.Equal(t, 50, cfg.Scan.BatchSize)
 	assert.False(t, cfg.Scan.Parallel)
+	assert.Equal(t, 2.5, cfg.Scan.SampleVerifyPercent)
+	assert.True(t, cfg.Scan.OneFileSystem)
+	assert.True(t, cfg.Scan.SHA256)
+	assert.True(t, cfg.Scan.Fuzzy)
+	assert.Equal(t, 3, cfg.Scan.FuzzyDistance)
+	assert.Equal(t, 256, cfg.Scan.ParallelHashMinMB)
+	assert.Equal(t, "size", cfg.Scan.CacheKey)
+	assert.Equal(t, []string{".bak", "ips"}, cfg.Scan.IgnoreExtensions)
+	assert.Equal(t, 8, cfg.DB.MaxOpenConns)
+	assert.Equal(t, 8, cfg.DB.MaxIdleConns)
+	assert.Equal(t, 30*time.Minute, cfg.DB.ConnMaxLifetime)
 	assert.Equal(t, "json", cfg.Logging.Format)
 	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, map[string]int{"crc32": 95}, cfg.Duplicates.MatchScores)
+	require.NotNil(t, cfg.Duplicates.FlagPenalty)
+	assert.Equal(t, 0, *cfg.Duplicates.FlagPenalty)
+	require.NotNil(t, cfg.Duplicates.PreferArchive)
+	assert.False(t, *cfg.Duplicates.PreferArchive)
+	assert.Equal(t, "/roms/keep", cfg.Duplicates.PreferredPathPrefix)
+	assert.Nil(t, cfg.Duplicates.PathDepthWeight)
+	require.NotNil(t, cfg.Preferences.RegionWeight)
+	assert.Equal(t, 2000, *cfg.Preferences.RegionWeight)
+	require.NotNil(t, cfg.Preferences.LanguageWeight)
+	assert.Equal(t, 0, *cfg.Preferences.LanguageWeight)
+	assert.Nil(t, cfg.Preferences.RevisionWeight)
+	assert.True(t, cfg.Preferences.GroupByClones)
+	assert.Equal(t, []string{"-lo", "-hi"}, cfg.Systems["atari2600"].SplitROMs[0].Parts)
+	assert.Equal(t, []string{".a26", ".bin"}, cfg.Systems["atari2600"].Extensions)
 }
 
 func TestConfig_LoadFromFile_NotFound(t *testing.T) {
@@ -132,6 +196,20 @@ func TestConfig_ApplyEnvOverrides(t *testing.T) {
 	assert.Equal(t, "/env/dat", cfg.DatDir)
 }
 
+func TestConfig_ScreenScraperEnvOverrides(t *testing.T) {
+	t.Setenv("SCREENSCRAPER_DEV_ID", "dev")
+	t.Setenv("SCREENSCRAPER_DEV_PASSWORD", "devpass")
+	t.Setenv("SCREENSCRAPER_USER", "user")
+	t.Setenv("SCREENSCRAPER_PASSWORD", "pass")
+
+	cfg := DefaultConfig()
+	cfg.Metadata.ScreenScraper.DevID = "from-file"
+	cfg.applyEnvOverrides()
+
+	assert.Equal(t, ScreenScraperConfig{DevID: "dev", DevPassword: "devpass", Username: "user", Password: "pass"},
+		cfg.Metadata.ScreenScraper)
+}
+
 func TestLoad_WithEnvConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
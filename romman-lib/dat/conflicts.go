@@ -0,0 +1,60 @@
+package dat
+
+import (
+	"context"
+	"fmt"
+)
+
+// HashConflict is a SHA1 that more than one release of a system claims,
+// usually because two DAT sources name the same dump differently.
+type HashConflict struct {
+	SHA1    string          `json:"sha1"`
+	Entries []ConflictEntry `json:"entries"`
+}
+
+// ConflictEntry is one release's ROM entry in a hash conflict.
+type ConflictEntry struct {
+	ReleaseID   int64  `json:"releaseId"`
+	ReleaseName string `json:"releaseName"`
+	ROMName     string `json:"romName"`
+	Source      string `json:"source,omitempty"` // Source type of the DAT that last defined the release
+}
+
+// FindHashConflicts returns the SHA1s shared by different releases of a
+// system, ordered by hash, with their entries ordered by release name.
+func (imp *Importer) FindHashConflicts(ctx context.Context, systemID int64) ([]HashConflict, error) {
+	rows, err := imp.db.QueryContext(ctx, `
+		SELECT sha1, release_id, release_name, rom_name, source_type
+		FROM hash_conflicts
+		WHERE system_id = ?
+		ORDER BY sha1, release_name, rom_entry_id
+	`, systemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hash conflicts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var conflicts []HashConflict
+	for rows.Next() {
+		var sha1 string
+		var e ConflictEntry
+		if err := rows.Scan(&sha1, &e.ReleaseID, &e.ReleaseName, &e.ROMName, &e.Source); err != nil {
+			return nil, err
+		}
+		if len(conflicts) == 0 || conflicts[len(conflicts)-1].SHA1 != sha1 {
+			conflicts = append(conflicts, HashConflict{SHA1: sha1})
+		}
+		last := &conflicts[len(conflicts)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	return conflicts, rows.Err()
+}
+
+// countHashConflicts returns the number of SHA1s shared by different releases of a system.
+func (imp *Importer) countHashConflicts(ctx context.Context, systemID int64) (int, error) {
+	var n int
+	err := imp.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT sha1) FROM hash_conflicts WHERE system_id = ?
+	`, systemID).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,62 @@
+package dat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestImporter_FindHashConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeDAT := func(name, header, games string) string {
+		path := filepath.Join(tmpDir, name)
+		content := `<?xml version="1.0"?>
+<datafile>
+	<header><name>` + header + `</name></header>` + games + `
+</datafile>`
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644)) // #nosec G306
+		return path
+	}
+
+	noIntro := writeDAT("gba-nointro.dat", "Nintendo - Game Boy Advance (No-Intro)", `
+	<game name="Shared Game (USA)">
+		<rom name="Shared Game (USA).gba" size="4" crc="11111111" sha1="AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"/>
+	</game>
+	<game name="Unique Game (USA)">
+		<rom name="Unique Game (USA).gba" size="4" crc="22222222" sha1="bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"/>
+	</game>`)
+	tosec := writeDAT("gba-tosec.dat", "Nintendo - Game Boy Advance (TOSEC)", `
+	<game name="Shared Game (2001)(Publisher)(US)">
+		<rom name="Shared Game (2001)(Publisher)(US).gba" size="4" crc="11111111" sha1="aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"/>
+	</game>`)
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	importer := NewImporter(database.Conn())
+
+	result, err := importer.Import(context.Background(), noIntro)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.HashConflicts)
+
+	result, err = importer.Import(context.Background(), tosec)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.HashConflicts)
+
+	conflicts, err := importer.FindHashConflicts(context.Background(), result.SystemID)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", conflicts[0].SHA1)
+	require.Len(t, conflicts[0].Entries, 2)
+	assert.Equal(t, "Shared Game (2001)(Publisher)(US)", conflicts[0].Entries[0].ReleaseName)
+	assert.Equal(t, string(SourceTOSEC), conflicts[0].Entries[0].Source)
+	assert.Equal(t, "Shared Game (USA)", conflicts[0].Entries[1].ReleaseName)
+	assert.Equal(t, "Shared Game (USA).gba", conflicts[0].Entries[1].ROMName)
+	assert.Equal(t, string(SourceNoIntro), conflicts[0].Entries[1].Source)
+}
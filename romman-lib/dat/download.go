@@ -0,0 +1,106 @@
+package dat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// DefaultDownloadTimeout bounds a DAT download when no timeout is given.
+// Large MAME DATs run to hundreds of megabytes.
+const DefaultDownloadTimeout = 5 * time.Minute
+
+// IsURL reports whether a DAT argument is an http or https URL rather than
+// a file path.
+func IsURL(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// ImportURL downloads a DAT, gzipped or zipped ones included, and imports
+// it like Import. The URL is recorded as the source's DAT path, and an
+// unchanged download is skipped by its hash as a re-imported file would be.
+// A timeout of 0 means DefaultDownloadTimeout.
+func (imp *Importer) ImportURL(ctx context.Context, rawURL string, timeout time.Duration) (*ImportResult, error) {
+	return imp.importURL(ctx, rawURL, UpdateOptions{Timeout: timeout})
+}
+
+// importURL downloads the DAT at rawURL and imports it with opts.
+func (imp *Importer) importURL(ctx context.Context, rawURL string, opts UpdateOptions) (*ImportResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "dat.ImportURL",
+		tracing.WithAttributes(attribute.String("dat.url", rawURL)),
+	)
+	defer span.End()
+
+	tmpDir, err := os.MkdirTemp("", "romman-dat-")
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	datPath, err := downloadDAT(ctx, rawURL, tmpDir, opts.Timeout)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return imp.importDAT(ctx, datPath, rawURL, opts)
+}
+
+// downloadDAT saves the DAT at rawURL into dir under the URL's file name,
+// which system detection falls back to, and returns its path.
+func downloadDAT(ctx context.Context, rawURL, dir string, timeout time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid DAT URL %q", rawURL)
+	}
+	if timeout <= 0 {
+		timeout = DefaultDownloadTimeout
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = "download.dat"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create DAT request: %w", err)
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req) // #nosec G107 - the URL is the user's DAT source
+	if err != nil {
+		return "", fmt.Errorf("failed to download DAT: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download DAT: %s returned %s", rawURL, resp.Status)
+	}
+
+	datPath := filepath.Join(dir, name)
+	f, err := os.Create(datPath) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("failed to create DAT file: %w", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to download DAT: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write DAT file: %w", err)
+	}
+	return datPath, nil
+}
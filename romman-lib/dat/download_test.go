@@ -0,0 +1,75 @@
+package dat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestImporter_ImportURL(t *testing.T) {
+	datContent := `<?xml version="1.0"?>
+<datafile>
+	<header><name>Unlabelled DAT</name></header>
+	<game name="Test Game (USA)">
+		<rom name="Test Game (USA).gba" size="4194304" crc="12345678" sha1="abcdef1234567890abcdef1234567890abcdef12"/>
+	</game>
+</datafile>`
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write([]byte(datContent))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dats/gba.dat.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(gz.Bytes())
+	}))
+	defer server.Close()
+
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	importer := NewImporter(database.Conn())
+
+	// The header names no system, so it is detected from the URL's file name
+	datURL := server.URL + "/dats/gba.dat.gz"
+	result, err := importer.ImportURL(context.Background(), datURL, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "gba", result.SystemName)
+	assert.Equal(t, 1, result.GamesImported)
+	assert.False(t, result.Skipped)
+
+	var storedPath string
+	require.NoError(t, database.Conn().QueryRow("SELECT dat_file_path FROM dat_sources").Scan(&storedPath))
+	assert.Equal(t, datURL, storedPath)
+
+	// An unchanged download is not imported again
+	result, err = importer.ImportURL(context.Background(), datURL, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+
+	_, err = importer.ImportURL(context.Background(), server.URL+"/missing.dat", 0)
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	_, err = importer.ImportURL(context.Background(), "ftp://example.com/nes.dat", 0)
+	assert.ErrorContains(t, err, "invalid DAT URL")
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("https://example.com/nes.dat"))
+	assert.True(t, IsURL("HTTP://example.com/nes.dat"))
+	assert.False(t, IsURL("dats/nes.dat"))
+	assert.False(t, IsURL("/tmp/http.dat"))
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/ryanm101/romman-lib/tracing"
 	"go.opentelemetry.io/otel/attribute"
@@ -28,17 +29,34 @@ type ImportResult struct {
 	GamesImported   int
 	RomsImported    int
 	GamesSkipped    int // Already existed
+	GamesUpdated    int // Already existed and were refreshed; included in GamesSkipped
 	IsNewSystem     bool
 	IsNewSource     bool
 	ParentsResolved int  // Number of parent_id references resolved
+	HashConflicts   int  // SHA1s the system's releases now share; see FindHashConflicts
 	Skipped         bool // DAT was unchanged
+
+	// StaleReleases counts releases the DAT no longer lists that no other
+	// source lists either. An Update with Prune deletes them.
+	StaleReleases   int
+	ReleasesRemoved int
+
+	// AffectedLibraries names the libraries with files matched to removed
+	// releases, which need a rescan to rematch them.
+	AffectedLibraries []string
 }
 
 // Import imports a DAT file into the database.
 // The import is idempotent - re-importing the same DAT will update existing entries.
 func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult, error) {
+	return imp.importDAT(ctx, datPath, datPath, UpdateOptions{})
+}
+
+// importDAT imports the DAT file at datPath, recording sourcePath as where
+// it came from.
+func (imp *Importer) importDAT(ctx context.Context, datPath, sourcePath string, opts UpdateOptions) (*ImportResult, error) {
 	ctx, span := tracing.StartSpan(ctx, "dat.Import",
-		tracing.WithAttributes(attribute.String("dat.path", datPath)),
+		tracing.WithAttributes(attribute.String("dat.path", sourcePath)),
 	)
 	defer span.End()
 
@@ -56,8 +74,11 @@ func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult,
 		systemName = normalizeSystemName(dat.Header.Name)
 	}
 
-	// Detect source type
+	// Detect source type; software lists are always MAME
 	sourceType := DetectSourceType(dat.Header.Name)
+	if dat.SoftwareList {
+		sourceType = SourceMAME
+	}
 
 	// Hash the DAT file for update detection
 	datHash, err := HashFile(datPath)
@@ -74,6 +95,30 @@ func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult,
 		attribute.String("dat.source_type", string(sourceType)),
 	)
 
+	// Skip a DAT unchanged since its source's last import, unless pruning
+	// releases a previous import left behind
+	var existingSystemID int64
+	err = imp.db.QueryRowContext(ctx, "SELECT id FROM systems WHERE name = ?", systemName).Scan(&existingSystemID)
+	if err != nil && err != sql.ErrNoRows {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to query system: %w", err)
+	}
+	if err == nil && !opts.Prune {
+		unchanged, err := IsDATUnchanged(imp.db, existingSystemID, sourceType, datHash)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, fmt.Errorf("failed to check DAT hash: %w", err)
+		}
+		if unchanged {
+			return &ImportResult{
+				SystemID:   existingSystemID,
+				SystemName: systemName,
+				SourceType: sourceType,
+				Skipped:    true,
+			}, nil
+		}
+	}
+
 	// Start transaction
 	tx, err := imp.db.Begin()
 	if err != nil {
@@ -90,26 +135,12 @@ func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult,
 	}
 
 	// Get or create DAT source entry
-	datSource, isNewSource, err := GetOrCreateDATSource(tx, systemID, sourceType, dat, datPath, datHash)
+	datSource, isNewSource, err := GetOrCreateDATSource(tx, systemID, sourceType, dat, sourcePath, datHash)
 	if err != nil {
 		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("failed to get/create dat_source: %w", err)
 	}
 
-	// Check if DAT is unchanged (only if source existed)
-	if !isNewSource && datSource.DATFileHash == datHash {
-		// DAT unchanged, skip import
-		_ = tx.Commit()
-		return &ImportResult{
-			SystemID:    systemID,
-			SystemName:  systemName,
-			SourceType:  sourceType,
-			IsNewSystem: false,
-			IsNewSource: false,
-			Skipped:     true,
-		}, nil
-	}
-
 	result := &ImportResult{
 		SystemID:    systemID,
 		SystemName:  systemName,
@@ -137,6 +168,15 @@ func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult,
 			result.RomsImported += len(game.Roms)
 		} else {
 			result.GamesSkipped++
+			result.GamesUpdated++
+		}
+	}
+
+	// Find, and when pruning delete, releases the DAT dropped
+	if !isNewSource {
+		if err := imp.pruneStaleReleases(ctx, tx, datSource.ID, dat, opts.Prune, result); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
 		}
 	}
 
@@ -153,12 +193,21 @@ func (imp *Importer) Import(ctx context.Context, datPath string) (*ImportResult,
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Sources that disagree on names leave one dump under several releases
+	result.HashConflicts, err = imp.countHashConflicts(ctx, systemID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to count hash conflicts: %w", err)
+	}
+
 	// Record success with result attributes
 	tracing.AddSpanAttributes(span,
 		attribute.Int("result.games_imported", result.GamesImported),
 		attribute.Int("result.games_skipped", result.GamesSkipped),
 		attribute.Int("result.roms_imported", result.RomsImported),
 		attribute.Int("result.parents_resolved", result.ParentsResolved),
+		attribute.Int("result.releases_removed", result.ReleasesRemoved),
+		attribute.Int("result.hash_conflicts", result.HashConflicts),
 	)
 	tracing.SetSpanOK(span)
 
@@ -178,9 +227,11 @@ func (imp *Importer) getOrCreateSystem(ctx context.Context, tx *sql.Tx, name str
 		// System exists, update DAT metadata
 		_, err = tx.Exec(`
 			UPDATE systems 
-			SET dat_name = ?, dat_description = ?, dat_version = ?, dat_date = ?
+			SET dat_name = ?, dat_description = ?, dat_version = ?, dat_date = ?,
+				dat_header_rule = ?, dat_force_merging = ?
 			WHERE id = ?`,
-			dat.Header.Name, dat.Header.Description, dat.Header.Version, dat.Header.Date, id)
+			dat.Header.Name, dat.Header.Description, dat.Header.Version, dat.Header.Date,
+			dat.Header.ClrMamePro.Header, dat.Header.ClrMamePro.ForceMerging, id)
 		if err != nil {
 			return 0, false, fmt.Errorf("failed to update system: %w", err)
 		}
@@ -193,9 +244,11 @@ func (imp *Importer) getOrCreateSystem(ctx context.Context, tx *sql.Tx, name str
 
 	// Create new system
 	result, err := tx.Exec(`
-		INSERT INTO systems (name, dat_name, dat_description, dat_version, dat_date)
-		VALUES (?, ?, ?, ?, ?)`,
-		name, dat.Header.Name, dat.Header.Description, dat.Header.Version, dat.Header.Date)
+		INSERT INTO systems (name, dat_name, dat_description, dat_version, dat_date,
+			dat_header_rule, dat_force_merging)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		name, dat.Header.Name, dat.Header.Description, dat.Header.Version, dat.Header.Date,
+		dat.Header.ClrMamePro.Header, dat.Header.ClrMamePro.ForceMerging)
 	if err != nil {
 		return 0, false, fmt.Errorf("failed to insert system: %w", err)
 	}
@@ -226,10 +279,15 @@ func (imp *Importer) importGame(ctx context.Context, tx *sql.Tx, systemID, datSo
 
 	if err == nil {
 		// Release exists, update metadata (idempotent but refresh)
-		if _, err := tx.Exec(`UPDATE releases SET description = ?, clone_of = ?, dat_source_id = ?, year = ?, manufacturer = ? WHERE id = ?`,
-			game.Description, game.CloneOf, datSourceID, game.Year, game.Manufacturer, existingID); err != nil {
+		if _, err := tx.Exec(`UPDATE releases SET description = ?, clone_of = ?, dat_source_id = ?, year = ?, manufacturer = ?,
+			is_bios = ?, is_device = ?, is_mechanical = ? WHERE id = ?`,
+			game.Description, game.CloneOf, datSourceID, game.Year, game.Manufacturer,
+			game.IsBIOS == "yes", game.IsDevice == "yes", game.IsMech == "yes", existingID); err != nil {
 			return false, fmt.Errorf("failed to update release: %w", err)
 		}
+		if err := addReleaseSource(tx, existingID, datSourceID); err != nil {
+			return false, err
+		}
 		return false, nil
 	}
 	if err != sql.ErrNoRows {
@@ -238,8 +296,10 @@ func (imp *Importer) importGame(ctx context.Context, tx *sql.Tx, systemID, datSo
 
 	// Insert the release with dat_source_id and MAME metadata
 	result, err := tx.Exec(
-		`INSERT INTO releases (system_id, name, description, clone_of, dat_source_id, year, manufacturer) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO releases (system_id, name, description, clone_of, dat_source_id, year, manufacturer, is_bios, is_device, is_mechanical)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		systemID, game.Name, game.Description, game.CloneOf, datSourceID, game.Year, game.Manufacturer,
+		game.IsBIOS == "yes", game.IsDevice == "yes", game.IsMech == "yes",
 	)
 	if err != nil {
 		return false, fmt.Errorf("failed to insert release: %w", err)
@@ -249,12 +309,15 @@ func (imp *Importer) importGame(ctx context.Context, tx *sql.Tx, systemID, datSo
 	if err != nil {
 		return false, fmt.Errorf("failed to get release ID: %w", err)
 	}
+	if err := addReleaseSource(tx, releaseID, datSourceID); err != nil {
+		return false, err
+	}
 
 	// Insert ROM entries using prepared statement for better performance
 	if len(game.Roms) > 0 {
 		stmt, err := tx.Prepare(`
-			INSERT INTO rom_entries (release_id, name, sha1, crc32, md5, size)
-			VALUES (?, ?, ?, ?, ?, ?)
+			INSERT INTO rom_entries (release_id, name, sha1, crc32, md5, sha256, size, part)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return false, fmt.Errorf("failed to prepare ROM statement: %w", err)
@@ -262,7 +325,10 @@ func (imp *Importer) importGame(ctx context.Context, tx *sql.Tx, systemID, datSo
 		defer func() { _ = stmt.Close() }()
 
 		for _, rom := range game.Roms {
-			_, err := stmt.Exec(releaseID, rom.Name, rom.SHA1, rom.CRC32, rom.MD5, rom.Size)
+			// Hashes are stored lowercase so lookups can use plain equality on the indexes
+			_, err := stmt.Exec(releaseID, rom.Name,
+				strings.ToLower(rom.SHA1), strings.ToLower(rom.CRC32), strings.ToLower(rom.MD5), strings.ToLower(rom.SHA256),
+				rom.Size, rom.Part)
 			if err != nil {
 				return false, fmt.Errorf("failed to insert ROM %q: %w", rom.Name, err)
 			}
@@ -272,6 +338,15 @@ func (imp *Importer) importGame(ctx context.Context, tx *sql.Tx, systemID, datSo
 	return true, nil
 }
 
+// addReleaseSource records that a DAT source lists a release.
+func addReleaseSource(tx *sql.Tx, releaseID, datSourceID int64) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO release_sources (release_id, dat_source_id) VALUES (?, ?)`,
+		releaseID, datSourceID); err != nil {
+		return fmt.Errorf("failed to record release source: %w", err)
+	}
+	return nil
+}
+
 // normalizeSystemName creates a simple identifier from a DAT header name
 func normalizeSystemName(name string) string {
 	// Use the base of the path if it looks like a path
@@ -284,6 +359,14 @@ func normalizeSystemName(name string) string {
 // resolveParentIDs updates parent_id by resolving clone_of text references to actual release IDs.
 // This needs to be called after all games in a system are imported to ensure parents exist.
 func (imp *Importer) resolveParentIDs(tx *sql.Tx, systemID int64) (int, error) {
+	// Releases a newer DAT made parents keep no link to their old parent
+	if _, err := tx.Exec(`
+		UPDATE releases SET parent_id = NULL
+		WHERE system_id = ? AND COALESCE(clone_of, '') = '' AND parent_id IS NOT NULL
+	`, systemID); err != nil {
+		return 0, fmt.Errorf("failed to clear parent IDs: %w", err)
+	}
+
 	result, err := tx.Exec(`
 		UPDATE releases 
 		SET parent_id = (
@@ -1,6 +1,8 @@
 package dat
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"os"
@@ -73,6 +75,92 @@ func TestImporter_Import(t *testing.T) {
 	assert.Equal(t, 2, romCount)
 }
 
+func TestImporter_ImportGzipped(t *testing.T) {
+	datContent := `<?xml version="1.0"?>
+<datafile>
+	<header>
+		<name>Nintendo - Game Boy Advance</name>
+		<version>2024-01-01</version>
+	</header>
+	<game name="Test Game (USA)">
+		<rom name="Test Game (USA).gba" size="4194304" crc="12345678" sha1="abcdef1234567890abcdef1234567890abcdef12"/>
+	</game>
+	<game name="Another Game (Europe)">
+		<rom name="Another Game (Europe).gba" size="8388608" crc="87654321" sha1="fedcba0987654321fedcba0987654321fedcba09"/>
+	</game>
+</datafile>`
+
+	tmpDir := t.TempDir()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write([]byte(datContent))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	datPath := filepath.Join(tmpDir, "gba.dat.gz")
+	require.NoError(t, os.WriteFile(datPath, buf.Bytes(), 0644)) // #nosec G306
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	result, err := NewImporter(database.Conn()).Import(context.Background(), datPath)
+	require.NoError(t, err)
+	assert.Equal(t, "gba", result.SystemName)
+	assert.Equal(t, 2, result.GamesImported)
+
+	var names []string
+	rows, err := database.Conn().Query(`
+		SELECT r.name FROM releases r JOIN systems s ON s.id = r.system_id
+		WHERE s.name = 'gba' ORDER BY r.name
+	`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"Another Game (Europe)", "Test Game (USA)"}, names)
+}
+
+func TestImporter_StoresClrMameProHints(t *testing.T) {
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "nes.dat")
+	err := os.WriteFile(datPath, []byte(`<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Nintendo Entertainment System (Headered)</name>
+		<description>Nintendo - Nintendo Entertainment System (Headered)</description>
+		<version>20240301-123456</version>
+		<author>No-Intro</author>
+		<homepage>No-Intro</homepage>
+		<url>https://www.no-intro.org</url>
+		<clrmamepro header="No-Intro_NES.xml" forcemerging="split"/>
+	</header>
+	<game name="Test Game (USA)">
+		<description>Test Game (USA)</description>
+		<rom name="Test Game (USA).nes" size="40976" crc="12345678" sha1="abcdef1234567890abcdef1234567890abcdef12"/>
+	</game>
+</datafile>`), 0644) // #nosec G306
+	require.NoError(t, err)
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	result, err := NewImporter(database.Conn()).Import(context.Background(), datPath)
+	require.NoError(t, err)
+
+	var headerRule, forceMerging string
+	err = database.Conn().QueryRow(`SELECT dat_header_rule, dat_force_merging FROM systems WHERE name = ?`,
+		result.SystemName).Scan(&headerRule, &forceMerging)
+	require.NoError(t, err)
+	assert.Equal(t, "No-Intro_NES.xml", headerRule)
+	assert.Equal(t, "split", forceMerging)
+}
+
 func TestImporter_Idempotent(t *testing.T) {
 	datContent := `<?xml version="1.0"?>
 <datafile>
@@ -172,3 +260,121 @@ func TestImporter_Clones(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "Parent Game", parentName, "Clone's parent_id should point to Parent Game")
 }
+
+func TestImporter_ClonePromotedToParent(t *testing.T) {
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "clones.dat")
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	importer := NewImporter(database.Conn())
+
+	// A later DAT version makes the clone a parent of its own
+	for _, cloneOf := range []string{`cloneof="Parent Game"`, ""} {
+		content := `<datafile><header><name>Clones</name></header>
+	<game name="Parent Game"><description>Parent Game</description></game>
+	<game name="Clone Game" ` + cloneOf + `><description>Clone Game</description></game>
+</datafile>`
+		require.NoError(t, os.WriteFile(datPath, []byte(content), 0644)) // #nosec G306
+		_, err = importer.Import(context.Background(), datPath)
+		require.NoError(t, err)
+
+		var storedCloneOf sql.NullString
+		var parentID sql.NullInt64
+		err = database.Conn().QueryRow("SELECT clone_of, parent_id FROM releases WHERE name = 'Clone Game'").
+			Scan(&storedCloneOf, &parentID)
+		require.NoError(t, err)
+		if cloneOf != "" {
+			assert.Equal(t, "Parent Game", storedCloneOf.String)
+			assert.True(t, parentID.Valid)
+		} else {
+			assert.Empty(t, storedCloneOf.String)
+			assert.False(t, parentID.Valid, "parent_id is cleared with clone_of")
+		}
+	}
+}
+
+func TestImporter_RecordsReleaseSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	importer := NewImporter(conn)
+	_, err = importer.Import(ctx, writeSourceDAT(t, tmpDir, "No-Intro", "Alpha (USA)", "Beta (USA)"))
+	require.NoError(t, err)
+	_, err = importer.Import(ctx, writeSourceDAT(t, tmpDir, "TOSEC", "Beta (USA)"))
+	require.NoError(t, err)
+
+	sourceOf := func(release string) (last string, all []string) {
+		t.Helper()
+		require.NoError(t, conn.QueryRow(`
+			SELECT ds.source_type FROM releases r JOIN dat_sources ds ON ds.id = r.dat_source_id
+			WHERE r.name = ?`, release).Scan(&last))
+		rows, err := conn.Query(`
+			SELECT ds.source_type FROM release_sources rs
+			JOIN releases r ON r.id = rs.release_id
+			JOIN dat_sources ds ON ds.id = rs.dat_source_id
+			WHERE r.name = ? ORDER BY ds.priority`, release)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var s string
+			require.NoError(t, rows.Scan(&s))
+			all = append(all, s)
+		}
+		return last, all
+	}
+
+	last, all := sourceOf("Alpha (USA)")
+	assert.Equal(t, "no-intro", last)
+	assert.Equal(t, []string{"no-intro"}, all)
+
+	// dat_source_id follows the latest import; release_sources keeps both
+	last, all = sourceOf("Beta (USA)")
+	assert.Equal(t, "tosec", last)
+	assert.Equal(t, []string{"no-intro", "tosec"}, all)
+}
+
+func TestImporter_RecordsMAMEFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "mame.xml")
+	err := os.WriteFile(datPath, []byte(`<?xml version="1.0"?>
+<mame build="0.262">
+	<machine name="neogeo" isbios="yes">
+		<description>Neo-Geo</description>
+		<rom name="sp-s2.sp1" size="131072" crc="9036d879" sha1="4f5ed7105b7128794654ce82b51723e16e389543"/>
+	</machine>
+	<machine name="pacman">
+		<description>Pac-Man</description>
+		<rom name="pacman.6e" size="4096" crc="c1e6ab10" sha1="e87e059c5be45753f7e9f33dff851f16d6751181"/>
+	</machine>
+	<machine name="bowlrama" ismechanical="yes">
+		<description>Bowl-O-Rama</description>
+		<rom name="bowl.u1" size="4096" crc="7b8e8c2f" sha1="1111111111111111111111111111111111111111"/>
+	</machine>
+</mame>`), 0644) // #nosec G306
+	require.NoError(t, err)
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	result, err := NewImporter(database.Conn()).Import(context.Background(), datPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.GamesImported)
+	assert.Equal(t, 1, result.GamesSkipped, "BIOS sets are not imported as releases")
+
+	var mechanical []string
+	rows, err := database.Conn().Query(`SELECT name FROM releases WHERE is_mechanical = 1`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		mechanical = append(mechanical, name)
+	}
+	assert.Equal(t, []string{"bowlrama"}, mechanical)
+}
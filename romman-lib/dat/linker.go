@@ -41,3 +41,56 @@ func LinkClones(db *sql.DB, systemID int64) (int64, error) {
 
 	return rows, nil
 }
+
+// SystemLinks is the number of clones linked in one system.
+type SystemLinks struct {
+	System string `json:"system"`
+	Linked int64  `json:"linked"`
+}
+
+// LinkSystemClones runs LinkClones for the named system, or for every
+// system when name is empty, so clones can be linked straight after a DAT
+// import without a library. Systems are returned in name order.
+func LinkSystemClones(db *sql.DB, name string) ([]SystemLinks, error) {
+	query := "SELECT id, name FROM systems ORDER BY name"
+	var args []any
+	if name != "" {
+		query = "SELECT id, name FROM systems WHERE name = ?"
+		args = append(args, name)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query systems: %w", err)
+	}
+	type system struct {
+		id   int64
+		name string
+	}
+	var systems []system
+	for rows.Next() {
+		var s system
+		if err := rows.Scan(&s.id, &s.name); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan system: %w", err)
+		}
+		systems = append(systems, s)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query systems: %w", err)
+	}
+	if name != "" && len(systems) == 0 {
+		return nil, fmt.Errorf("system not found: %s", name)
+	}
+
+	results := make([]SystemLinks, 0, len(systems))
+	for _, s := range systems {
+		linked, err := LinkClones(db, s.id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		results = append(results, SystemLinks{System: s.name, Linked: linked})
+	}
+	return results, nil
+}
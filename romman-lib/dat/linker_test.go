@@ -0,0 +1,52 @@
+package dat
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestLinkSystemClones(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	for _, stmt := range []string{
+		`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes')`,
+		`INSERT INTO releases (system_id, name, clone_of) VALUES
+			(1, 'Rockman (Japan)', ''),
+			(1, 'Mega Man (USA)', 'Rockman (Japan)'),
+			(1, 'Mega Man (Europe)', 'Rockman (Japan)'),
+			(1, 'Orphan (USA)', 'Missing Parent'),
+			(2, 'Rockman X (Japan)', ''),
+			(2, 'Mega Man X (USA)', 'Rockman X (Japan)')`,
+	} {
+		_, err := conn.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	results, err := LinkSystemClones(conn, "snes")
+	require.NoError(t, err)
+	assert.Equal(t, []SystemLinks{{System: "snes", Linked: 1}}, results)
+
+	// Linked clones are not counted again
+	results, err = LinkSystemClones(conn, "")
+	require.NoError(t, err)
+	assert.Equal(t, []SystemLinks{{System: "nes", Linked: 2}, {System: "snes", Linked: 0}}, results)
+
+	var parent string
+	require.NoError(t, conn.QueryRow(`
+		SELECT p.name FROM releases r JOIN releases p ON p.id = r.parent_id
+		WHERE r.name = 'Mega Man (Europe)'`).Scan(&parent))
+	assert.Equal(t, "Rockman (Japan)", parent)
+
+	_, err = LinkSystemClones(conn, "gba")
+	assert.ErrorContains(t, err, "system not found")
+}
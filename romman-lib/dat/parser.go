@@ -1,6 +1,9 @@
 package dat
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -16,6 +19,22 @@ type Header struct {
 	Author      string `xml:"author"`
 	Homepage    string `xml:"homepage"`
 	URL         string `xml:"url"`
+
+	// ClrMamePro holds the DAT's hints for ROM managers.
+	ClrMamePro ClrMamePro `xml:"clrmamepro"`
+}
+
+// ClrMamePro is the <clrmamepro> element of a DAT header.
+type ClrMamePro struct {
+	// Header names the header-skip rule file for dumps carrying a copier or
+	// emulator header, e.g. "No-Intro_NES.xml".
+	Header string `xml:"header,attr"`
+
+	// ForceMerging is the merge mode the DAT expects: "none", "split",
+	// "merged" or "full". Empty when unspecified.
+	ForceMerging string `xml:"forcemerging,attr"`
+	ForceNodump  string `xml:"forcenodump,attr"`
+	ForcePacking string `xml:"forcepacking,attr"`
 }
 
 // Rom represents a single ROM file within a game.
@@ -25,6 +44,13 @@ type Rom struct {
 	CRC32 string `xml:"crc,attr"`
 	MD5   string `xml:"md5,attr"`
 	SHA1  string `xml:"sha1,attr"`
+
+	// SHA256 is only present in some DATs, notably Redump's disc DATs.
+	SHA256 string `xml:"sha256,attr"`
+
+	// Part is the software list part the ROM or disk belongs to, e.g. "cart"
+	// or "flop1". Empty for Logiqx DATs.
+	Part string `xml:"-"`
 }
 
 // Game represents a game/machine entry in the DAT file.
@@ -53,17 +79,113 @@ type Game struct {
 type DATFile struct {
 	Header Header
 	Games  []Game
+
+	// SoftwareList is set for MAME software lists, whose <software> entries
+	// are read into Games.
+	SoftwareList bool
 }
 
-// ParseFile parses a Logiqx XML DAT file from the given path.
+// ParseFile parses a Logiqx XML DAT file from the given path. DATs
+// compressed with gzip, or zipped alone as No-Intro and Redump distribute
+// them, are decompressed transparently.
 func ParseFile(path string) (*DATFile, error) {
+	r, err := openDAT(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	return Parse(r)
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// datReader reads a possibly decompressed DAT, closing every layer when done.
+type datReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *datReader) Close() error {
+	var firstErr error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if err := r.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// openDAT opens a DAT file, detecting gzip and zip compression by their
+// magic bytes rather than the extension.
+func openDAT(path string) (io.ReadCloser, error) {
 	f, err := os.Open(path) // #nosec G304
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DAT file: %w", err)
 	}
-	defer func() { _ = f.Close() }()
 
-	return Parse(f)
+	magic := make([]byte, len(zipMagic))
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read DAT file: %w", err)
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to open gzipped DAT file: %w", err)
+		}
+		return &datReader{Reader: gz, closers: []io.Closer{f, gz}}, nil
+
+	case bytes.Equal(magic, zipMagic):
+		rc, err := openZippedDAT(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &datReader{Reader: rc, closers: []io.Closer{f, rc}}, nil
+	}
+
+	return f, nil
+}
+
+// openZippedDAT opens the only file in a zipped DAT.
+func openZippedDAT(f *os.File) (io.ReadCloser, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat zipped DAT file: %w", err)
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zipped DAT file: %w", err)
+	}
+
+	var entry *zip.File
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		if entry != nil {
+			return nil, fmt.Errorf("zipped DAT file holds more than one file: %s and %s", entry.Name, zf.Name)
+		}
+		entry = zf
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("zipped DAT file is empty")
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zipped DAT file: %w", entry.Name, err)
+	}
+	return rc, nil
 }
 
 // Parse parses a Logiqx XML DAT file from the given reader.
@@ -98,6 +220,25 @@ func Parse(r io.Reader) (*DATFile, error) {
 					return nil, fmt.Errorf("failed to decode game: %w", err)
 				}
 				dat.Games = append(dat.Games, game)
+
+			case "softwarelist":
+				// Software lists have no <header>; the root carries the list name
+				dat.SoftwareList = true
+				for _, attr := range elem.Attr {
+					switch attr.Name.Local {
+					case "name":
+						dat.Header.Name = attr.Value
+					case "description":
+						dat.Header.Description = attr.Value
+					}
+				}
+
+			case "software":
+				var sw software
+				if err := decoder.DecodeElement(&sw, &elem); err != nil {
+					return nil, fmt.Errorf("failed to decode software: %w", err)
+				}
+				dat.Games = append(dat.Games, sw.game())
 			}
 		}
 	}
@@ -1,6 +1,11 @@
 package dat
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -41,6 +46,34 @@ func TestParse_ValidDAT(t *testing.T) {
 	assert.Equal(t, "abcdef", dat.Games[0].Roms[0].SHA1)
 }
 
+func TestParse_ClrMameProHeader(t *testing.T) {
+	datXML := `<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Nintendo Entertainment System (Headered)</name>
+		<description>Nintendo - Nintendo Entertainment System (Headered)</description>
+		<version>20240301-123456</version>
+		<author>No-Intro</author>
+		<homepage>No-Intro</homepage>
+		<url>https://www.no-intro.org</url>
+		<clrmamepro header="No-Intro_NES.xml" forcemerging="split"/>
+	</header>
+	<game name="Test Game (USA)">
+		<description>Test Game (USA)</description>
+		<rom name="Test Game (USA).nes" size="40976" crc="12345678" sha1="abcdef1234567890abcdef1234567890abcdef12"/>
+	</game>
+</datafile>`
+
+	dat, err := Parse(strings.NewReader(datXML))
+	require.NoError(t, err)
+
+	assert.Equal(t, "No-Intro_NES.xml", dat.Header.ClrMamePro.Header)
+	assert.Equal(t, "split", dat.Header.ClrMamePro.ForceMerging)
+	assert.Equal(t, "No-Intro", dat.Header.Author)
+	assert.Len(t, dat.Games, 1)
+}
+
 func TestParse_MachineElement(t *testing.T) {
 	// MAME uses <machine> instead of <game>
 	datXML := `<?xml version="1.0"?>
@@ -141,7 +174,7 @@ func TestParse_AllHashTypes(t *testing.T) {
 <datafile>
 	<header><name>Hashes</name></header>
 	<game name="Hash Test">
-		<rom name="test.rom" size="1024" crc="AABBCCDD" md5="0123456789abcdef0123456789abcdef" sha1="0123456789abcdef0123456789abcdef01234567"/>
+		<rom name="test.rom" size="1024" crc="AABBCCDD" md5="0123456789abcdef0123456789abcdef" sha1="0123456789abcdef0123456789abcdef01234567" sha256="0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"/>
 	</game>
 </datafile>`
 
@@ -152,6 +185,57 @@ func TestParse_AllHashTypes(t *testing.T) {
 	assert.Equal(t, "AABBCCDD", rom.CRC32)
 	assert.Equal(t, "0123456789abcdef0123456789abcdef", rom.MD5)
 	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", rom.SHA1)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", rom.SHA256)
+}
+
+func TestParseFile_Compressed(t *testing.T) {
+	const content = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo - Game Boy</name></header>
+	<game name="Test Game (USA)">
+		<rom name="Test Game (USA).gb" size="32768" crc="12345678"/>
+	</game>
+</datafile>`
+	tmpDir := t.TempDir()
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	gzPath := filepath.Join(tmpDir, "gb.dat.gz")
+	require.NoError(t, os.WriteFile(gzPath, gz.Bytes(), 0644)) // #nosec G306
+
+	writeZip := func(name string, files ...string) string {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, file := range files {
+			w, err := zw.Create(file)
+			require.NoError(t, err)
+			if !strings.HasSuffix(file, "/") {
+				_, err = w.Write([]byte(content))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, zw.Close())
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644)) // #nosec G306
+		return path
+	}
+
+	// Detection is by content, not extension
+	for _, path := range []string{gzPath, writeZip("gb.zip", "gb.dat"), writeZip("gb.dat", "gb.xml")} {
+		dat, err := ParseFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, "Nintendo - Game Boy", dat.Header.Name)
+		require.Len(t, dat.Games, 1)
+		assert.Equal(t, "Test Game (USA)", dat.Games[0].Name)
+	}
+
+	_, err = ParseFile(writeZip("two.zip", "a.dat", "b.dat"))
+	assert.ErrorContains(t, err, "more than one file")
+	_, err = ParseFile(writeZip("dir.zip", "dats/"))
+	assert.ErrorContains(t, err, "empty")
 }
 
 func TestParseFile_NotFound(t *testing.T) {
@@ -0,0 +1,92 @@
+package dat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SystemDependents counts the rows that go with a system when it is removed.
+type SystemDependents struct {
+	SystemID     int64
+	Releases     int
+	ROMEntries   int
+	DATSources   int
+	Libraries    int
+	ScannedFiles int // In the system's libraries
+	Matches      int // Against the system's ROMs, from any library
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// CountSystemDependents reports what removing a system would delete.
+func CountSystemDependents(ctx context.Context, db *sql.DB, name string) (*SystemDependents, error) {
+	return countSystemDependents(ctx, db, name)
+}
+
+// RemoveSystem deletes a system along with its releases, ROM entries, DAT
+// sources and libraries, which go through ON DELETE CASCADE, and returns
+// what was deleted.
+func RemoveSystem(ctx context.Context, db *sql.DB, name string) (*SystemDependents, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deps, err := countSystemDependents(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM systems WHERE id = ?", deps.SystemID); err != nil {
+		return nil, fmt.Errorf("failed to delete system: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return deps, nil
+}
+
+func countSystemDependents(ctx context.Context, q querier, name string) (*SystemDependents, error) {
+	var deps SystemDependents
+	err := q.QueryRowContext(ctx, "SELECT id FROM systems WHERE name = ?", name).Scan(&deps.SystemID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("system not found: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system: %w", err)
+	}
+
+	counts := []struct {
+		dest  *int
+		query string
+	}{
+		{&deps.Releases, "SELECT COUNT(*) FROM releases WHERE system_id = ?"},
+		{&deps.ROMEntries, `
+			SELECT COUNT(*) FROM rom_entries re
+			JOIN releases r ON r.id = re.release_id
+			WHERE r.system_id = ?`},
+		{&deps.DATSources, "SELECT COUNT(*) FROM dat_sources WHERE system_id = ?"},
+		{&deps.Libraries, "SELECT COUNT(*) FROM libraries WHERE system_id = ?"},
+		{&deps.ScannedFiles, `
+			SELECT COUNT(*) FROM scanned_files sf
+			JOIN libraries l ON l.id = sf.library_id
+			WHERE l.system_id = ?`},
+		{&deps.Matches, `
+			SELECT COUNT(*) FROM matches m
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			JOIN releases r ON r.id = re.release_id
+			WHERE r.system_id = ?`},
+	}
+	for _, c := range counts {
+		if err := q.QueryRowContext(ctx, c.query, deps.SystemID).Scan(c.dest); err != nil {
+			return nil, fmt.Errorf("failed to count system dependents: %w", err)
+		}
+	}
+	return &deps, nil
+}
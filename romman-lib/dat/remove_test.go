@@ -0,0 +1,88 @@
+package dat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestRemoveSystem(t *testing.T) {
+	datContent := `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo - Game Boy Advance</name></header>
+	<game name="Test Game (USA)">
+		<rom name="Test Game (USA).gba" size="4" crc="12345678" sha1="abcdef1234567890abcdef1234567890abcdef12"/>
+	</game>
+	<game name="Another Game (Europe)">
+		<rom name="Another Game (Europe).gba" size="8" crc="87654321" sha1="fedcba0987654321fedcba0987654321fedcba09"/>
+	</game>
+</datafile>`
+
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "gba.dat")
+	require.NoError(t, os.WriteFile(datPath, []byte(datContent), 0644)) // #nosec G306
+
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	_, err = NewImporter(conn).Import(ctx, datPath)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO systems (name) VALUES ('nes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO libraries (id, name, root_path, system_id)
+		SELECT 1, 'gba-lib', '/roms/gba', id FROM systems WHERE name = 'gba'`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO scanned_files (id, library_id, path, size, mtime) VALUES (1, 1, '/roms/gba/a.gba', 4, 0)`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type)
+		SELECT 1, id, 'sha1' FROM rom_entries WHERE sha1 = 'abcdef1234567890abcdef1234567890abcdef12'`)
+	require.NoError(t, err)
+
+	deps, err := CountSystemDependents(ctx, conn, "gba")
+	require.NoError(t, err)
+	expected := SystemDependents{
+		SystemID:     deps.SystemID,
+		Releases:     2,
+		ROMEntries:   2,
+		DATSources:   1,
+		Libraries:    1,
+		ScannedFiles: 1,
+		Matches:      1,
+	}
+	assert.Equal(t, expected, *deps)
+
+	removed, err := RemoveSystem(ctx, conn, "gba")
+	require.NoError(t, err)
+	assert.Equal(t, expected, *removed)
+
+	// Everything under the system cascades; other systems are kept
+	for _, table := range []string{"releases", "rom_entries", "dat_sources", "libraries", "scanned_files", "matches"} {
+		var n int
+		require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n)) // #nosec G202
+		assert.Zero(t, n, table)
+	}
+	var names []string
+	rows, err := conn.Query("SELECT name FROM systems")
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"nes"}, names)
+
+	_, err = RemoveSystem(ctx, conn, "gba")
+	assert.ErrorContains(t, err, "system not found")
+}
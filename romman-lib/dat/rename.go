@@ -0,0 +1,78 @@
+package dat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// RenameSystemResult describes a renamed system.
+type RenameSystemResult struct {
+	SystemID  int64
+	OldName   string
+	NewName   string
+	Known     bool     // NewName is a system id romman knows, see IsKnownSystem
+	Libraries []string // Libraries of the system, which now show the new name
+}
+
+// RenameSystem changes a system's name, e.g. after a DAT was detected as the
+// wrong system. Releases, DAT sources and libraries refer to the system by
+// id, so they follow it. The new name must not belong to another system.
+func RenameSystem(ctx context.Context, db *sql.DB, oldName, newName string) (*RenameSystemResult, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, fmt.Errorf("new system name is empty")
+	}
+	if newName == oldName {
+		return nil, fmt.Errorf("system is already named %s", newName)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result := &RenameSystemResult{OldName: oldName, NewName: newName, Known: IsKnownSystem(newName)}
+	err = tx.QueryRowContext(ctx, "SELECT id FROM systems WHERE name = ?", oldName).Scan(&result.SystemID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("system not found: %s", oldName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system: %w", err)
+	}
+
+	var taken int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM systems WHERE name = ?", newName).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check system name: %w", err)
+	}
+	if taken > 0 {
+		return nil, fmt.Errorf("system already exists: %s", newName)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE systems SET name = ? WHERE id = ?", newName, result.SystemID); err != nil {
+		return nil, fmt.Errorf("failed to rename system: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM libraries WHERE system_id = ? ORDER BY name", result.SystemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list libraries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list libraries: %w", err)
+		}
+		result.Libraries = append(result.Libraries, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list libraries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return result, nil
+}
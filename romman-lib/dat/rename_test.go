@@ -0,0 +1,61 @@
+package dat
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestRenameSystem(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'md'), (2, 'nes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (system_id, name) VALUES (1, 'Sonic CD (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO libraries (name, root_path, system_id) VALUES ('megacd', '/roms/megacd', 1), ('cd-extra', '/roms/extra', 1)`)
+	require.NoError(t, err)
+
+	_, err = RenameSystem(ctx, conn, "md", "nes")
+	assert.ErrorContains(t, err, "system already exists")
+	_, err = RenameSystem(ctx, conn, "gba", "gbc")
+	assert.ErrorContains(t, err, "system not found")
+	_, err = RenameSystem(ctx, conn, "md", " ")
+	assert.Error(t, err)
+
+	result, err := RenameSystem(ctx, conn, "md", "segacd")
+	require.NoError(t, err)
+	assert.Equal(t, &RenameSystemResult{
+		SystemID:  1,
+		OldName:   "md",
+		NewName:   "segacd",
+		Known:     true,
+		Libraries: []string{"cd-extra", "megacd"},
+	}, result)
+
+	// Everything attached to the system follows it
+	var systemName string
+	require.NoError(t, conn.QueryRow(`
+		SELECT s.name FROM releases r JOIN systems s ON s.id = r.system_id
+		WHERE r.name = 'Sonic CD (USA)'`).Scan(&systemName))
+	assert.Equal(t, "segacd", systemName)
+
+	result, err = RenameSystem(ctx, conn, "segacd", "my-homebrew")
+	require.NoError(t, err)
+	assert.False(t, result.Known)
+}
+
+func TestIsKnownSystem(t *testing.T) {
+	assert.True(t, IsKnownSystem("segacd"))
+	assert.True(t, IsKnownSystem("nes"))
+	assert.False(t, IsKnownSystem("not-a-system"))
+}
@@ -0,0 +1,88 @@
+package dat
+
+import "strconv"
+
+// software is a <software> entry of a MAME software list. ROMs are nested
+// in parts and data areas, and CHDs in disk areas.
+type software struct {
+	Name        string         `xml:"name,attr"`
+	CloneOf     string         `xml:"cloneof,attr"`
+	Description string         `xml:"description"`
+	Year        string         `xml:"year"`
+	Publisher   string         `xml:"publisher"`
+	Parts       []softwarePart `xml:"part"`
+}
+
+type softwarePart struct {
+	Name      string             `xml:"name,attr"`
+	DataAreas []softwareDataArea `xml:"dataarea"`
+	DiskAreas []softwareDiskArea `xml:"diskarea"`
+}
+
+type softwareDataArea struct {
+	Name string        `xml:"name,attr"`
+	Roms []softwareRom `xml:"rom"`
+}
+
+// softwareRom is a <rom> of a software list data area. Its size is often
+// written in hex, e.g. "0x200000", so it is read as a string.
+type softwareRom struct {
+	Name  string `xml:"name,attr"`
+	Size  string `xml:"size,attr"`
+	CRC32 string `xml:"crc,attr"`
+	MD5   string `xml:"md5,attr"`
+	SHA1  string `xml:"sha1,attr"`
+}
+
+type softwareDiskArea struct {
+	Name  string         `xml:"name,attr"`
+	Disks []softwareDisk `xml:"disk"`
+}
+
+type softwareDisk struct {
+	Name string `xml:"name,attr"`
+	SHA1 string `xml:"sha1,attr"`
+}
+
+// game flattens a software entry into a Game with one ROM per <rom> and
+// <disk> across all parts. Disks are named <name>.chd, the file MAME loads,
+// and carry the CHD's SHA1.
+func (sw software) game() Game {
+	g := Game{
+		Name:         sw.Name,
+		CloneOf:      sw.CloneOf,
+		Description:  sw.Description,
+		Year:         sw.Year,
+		Manufacturer: sw.Publisher,
+	}
+
+	for _, part := range sw.Parts {
+		for _, area := range part.DataAreas {
+			for _, rom := range area.Roms {
+				// Unnamed entries continue or fill the previous ROM's load region
+				if rom.Name == "" {
+					continue
+				}
+				size, _ := strconv.ParseInt(rom.Size, 0, 64)
+				g.Roms = append(g.Roms, Rom{
+					Name:  rom.Name,
+					Size:  size,
+					CRC32: rom.CRC32,
+					MD5:   rom.MD5,
+					SHA1:  rom.SHA1,
+					Part:  part.Name,
+				})
+			}
+		}
+		for _, area := range part.DiskAreas {
+			for _, disk := range area.Disks {
+				if disk.Name == "" {
+					continue
+				}
+				g.Roms = append(g.Roms, Rom{Name: disk.Name + ".chd", SHA1: disk.SHA1, Part: part.Name})
+			}
+		}
+	}
+
+	return g
+}
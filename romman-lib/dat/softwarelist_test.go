@@ -0,0 +1,154 @@
+package dat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+const softwareListXML = `<?xml version="1.0"?>
+<!DOCTYPE softwarelist SYSTEM "softwarelist.dtd">
+<softwarelist name="amiga_flop" description="Commodore Amiga 3.5&quot; floppy disks">
+	<software name="lemmings">
+		<description>Lemmings</description>
+		<year>1991</year>
+		<publisher>Psygnosis</publisher>
+		<part name="flop1" interface="floppy_3_5">
+			<dataarea name="flop" size="901120">
+				<rom name="lemmings disk 1.adf" size="901120" crc="0a1b2c3d" sha1="1111111111111111111111111111111111111111"/>
+			</dataarea>
+		</part>
+		<part name="flop2" interface="floppy_3_5">
+			<dataarea name="flop" size="901120">
+				<rom name="lemmings disk 2.adf" size="901120" crc="4e5f6a7b" sha1="2222222222222222222222222222222222222222"/>
+			</dataarea>
+		</part>
+	</software>
+	<software name="lemmingsa" cloneof="lemmings">
+		<description>Lemmings (alt)</description>
+		<year>1991</year>
+		<publisher>Psygnosis</publisher>
+		<part name="cart" interface="cart">
+			<dataarea name="rom" size="1048576">
+				<rom name="lem.lo" size="524288" crc="11111111" sha1="3333333333333333333333333333333333333333" offset="0"/>
+				<rom size="524288" offset="0x80000" loadflag="continue"/>
+				<rom name="lem.hi" size="524288" crc="22222222" sha1="4444444444444444444444444444444444444444" offset="0x100000"/>
+			</dataarea>
+		</part>
+		<part name="cdrom" interface="cdrom">
+			<diskarea name="cdrom">
+				<disk name="lemmings cd" sha1="5555555555555555555555555555555555555555"/>
+			</diskarea>
+		</part>
+	</software>
+</softwarelist>`
+
+func TestParse_SoftwareList(t *testing.T) {
+	dat, err := Parse(strings.NewReader(softwareListXML))
+	require.NoError(t, err)
+
+	assert.True(t, dat.SoftwareList)
+	assert.Equal(t, "amiga_flop", dat.Header.Name)
+	assert.Equal(t, `Commodore Amiga 3.5" floppy disks`, dat.Header.Description)
+	require.Len(t, dat.Games, 2)
+
+	game := dat.Games[0]
+	assert.Equal(t, "lemmings", game.Name)
+	assert.Equal(t, "Lemmings", game.Description)
+	assert.Equal(t, "Psygnosis", game.Manufacturer)
+	require.Len(t, game.Roms, 2)
+	assert.Equal(t, "lemmings disk 1.adf", game.Roms[0].Name)
+	assert.Equal(t, "flop1", game.Roms[0].Part)
+	assert.Equal(t, "flop2", game.Roms[1].Part)
+
+	// Continuation entries are dropped and disks become .chd entries
+	clone := dat.Games[1]
+	assert.Equal(t, "lemmings", clone.CloneOf)
+	require.Len(t, clone.Roms, 3)
+	assert.Equal(t, Rom{Name: "lem.lo", Size: 524288, CRC32: "11111111", SHA1: "3333333333333333333333333333333333333333", Part: "cart"}, clone.Roms[0])
+	assert.Equal(t, "lem.hi", clone.Roms[1].Name)
+	assert.Equal(t, Rom{Name: "lemmings cd.chd", SHA1: "5555555555555555555555555555555555555555", Part: "cdrom"}, clone.Roms[2])
+}
+
+func TestParse_SoftwareListMultipleDataAreas(t *testing.T) {
+	// A trimmed Neo Geo entry: one part spread over several data areas with
+	// hex sizes, an empty nvram area, and a disk area alongside them
+	xmlData := `<?xml version="1.0"?>
+<softwarelist name="neogeo" description="SNK Neo-Geo cartridges">
+	<software name="mslug">
+		<description>Metal Slug - Super Vehicle-001</description>
+		<year>1996</year>
+		<publisher>Nazca</publisher>
+		<part name="cart" interface="neo_cart">
+			<dataarea name="maincpu" width="16" endianness="big" size="0x200000">
+				<rom name="201-p1.p1" size="0x200000" crc="08d8daa5" sha1="b0ce2ea53ea3a6d6d0b6b0c4b8a4b0b6e3f1f2a1" offset="0x000000" loadflag="load16_word_swap"/>
+			</dataarea>
+			<dataarea name="fixed" size="0x040000">
+				<rom name="201-s1.s1" size="0x20000" crc="2f55958d" sha1="550b53628daec9f1e1e11a398854092d90f9505a" offset="0x000000"/>
+			</dataarea>
+			<dataarea name="sprites" size="0x1000000">
+				<rom name="201-c1.c1" size="0x400000" crc="72813676" sha1="7b045d1a48980cb1a140699011cb1a3d4acdc4d1" offset="0x000000" loadflag="load16_byte"/>
+				<rom name="201-c2.c2" size="0x400000" crc="96f62574" sha1="cb7254b885989223bba597b8ff0972dfa5957816" offset="0x000001" loadflag="load16_byte"/>
+			</dataarea>
+			<dataarea name="nvram" size="0x2000"/>
+			<diskarea name="cdrom">
+				<disk name="mslug cd" sha1="6666666666666666666666666666666666666666"/>
+			</diskarea>
+		</part>
+	</software>
+</softwarelist>`
+
+	dat, err := Parse(strings.NewReader(xmlData))
+	require.NoError(t, err)
+	require.Len(t, dat.Games, 1)
+
+	var names []string
+	for _, rom := range dat.Games[0].Roms {
+		assert.Equal(t, "cart", rom.Part)
+		names = append(names, rom.Name)
+	}
+	assert.Equal(t, []string{"201-p1.p1", "201-s1.s1", "201-c1.c1", "201-c2.c2", "mslug cd.chd"}, names)
+	assert.Equal(t, int64(0x400000), dat.Games[0].Roms[2].Size)
+	assert.Equal(t, "6666666666666666666666666666666666666666", dat.Games[0].Roms[4].SHA1)
+}
+
+func TestImporter_SoftwareList(t *testing.T) {
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "amiga_flop.xml")
+	require.NoError(t, os.WriteFile(datPath, []byte(softwareListXML), 0644)) // #nosec G306
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	result, err := NewImporter(database.Conn()).Import(context.Background(), datPath)
+	require.NoError(t, err)
+	assert.Equal(t, SourceMAME, result.SourceType)
+	assert.Equal(t, 2, result.GamesImported)
+	assert.Equal(t, 5, result.RomsImported)
+	assert.Equal(t, 1, result.ParentsResolved)
+
+	rows, err := database.Conn().Query(`
+		SELECT re.name, re.part FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		WHERE r.name = 'lemmingsa'
+		ORDER BY re.id
+	`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	var got []string
+	for rows.Next() {
+		var name, part string
+		require.NoError(t, rows.Scan(&name, &part))
+		got = append(got, part+"/"+name)
+	}
+	assert.Equal(t, []string{"cart/lem.lo", "cart/lem.hi", "cdrom/lemmings cd.chd"}, got)
+}
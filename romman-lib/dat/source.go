@@ -1,6 +1,7 @@
 package dat
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
@@ -153,3 +154,152 @@ func IsDATUnchanged(db *sql.DB, systemID int64, sourceType SourceType, currentHa
 
 	return storedHash == currentHash, nil
 }
+
+// SourceSummary is a DAT source with counts of the releases it lists.
+type SourceSummary struct {
+	DATSource
+	ImportedAt string
+	Releases   int // Releases the source lists
+	Exclusive  int // Releases no other source lists, removed along with it
+}
+
+// ListDATSources returns a system's DAT sources in priority order.
+func ListDATSources(ctx context.Context, db *sql.DB, systemName string) ([]SourceSummary, error) {
+	var systemID int64
+	err := db.QueryRowContext(ctx, "SELECT id FROM systems WHERE name = ?", systemName).Scan(&systemID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("system not found: %s", systemName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT ds.id, ds.system_id, ds.source_type, COALESCE(ds.dat_name, ''), COALESCE(ds.dat_version, ''),
+			COALESCE(ds.dat_date, ''), COALESCE(ds.dat_file_path, ''), COALESCE(ds.dat_file_hash, ''),
+			ds.priority, COALESCE(ds.imported_at, ''),
+			(SELECT COUNT(*) FROM release_sources rs WHERE rs.dat_source_id = ds.id),
+			(SELECT COUNT(*) FROM release_sources rs WHERE rs.dat_source_id = ds.id AND NOT EXISTS (
+				SELECT 1 FROM release_sources other
+				WHERE other.release_id = rs.release_id AND other.dat_source_id != ds.id
+			))
+		FROM dat_sources ds
+		WHERE ds.system_id = ?
+		ORDER BY ds.priority, ds.id
+	`, systemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dat_sources: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sources []SourceSummary
+	for rows.Next() {
+		var s SourceSummary
+		if err := rows.Scan(&s.ID, &s.SystemID, &s.SourceType, &s.DATName, &s.DATVersion,
+			&s.DATDate, &s.DATFilePath, &s.DATFileHash, &s.Priority, &s.ImportedAt,
+			&s.Releases, &s.Exclusive); err != nil {
+			return nil, fmt.Errorf("failed to scan dat_source: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+// RemoveSourceResult reports what RemoveDATSource changed.
+type RemoveSourceResult struct {
+	ReleasesRemoved int // Listed by no other source
+	ReleasesKept    int // Also listed by another source
+}
+
+// RemoveDATSource deletes a DAT source and the releases only it lists.
+// Releases another source also lists are kept and attributed to the
+// highest-priority remaining one, and the system's remaining sources are
+// renumbered from priority 0.
+func RemoveDATSource(ctx context.Context, db *sql.DB, sourceID int64) (*RemoveSourceResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var systemID int64
+	err = tx.QueryRowContext(ctx, "SELECT system_id FROM dat_sources WHERE id = ?", sourceID).Scan(&systemID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("DAT source not found: %d", sourceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dat_source: %w", err)
+	}
+
+	var result RemoveSourceResult
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM releases
+		WHERE id IN (SELECT release_id FROM release_sources WHERE dat_source_id = ?)
+		AND NOT EXISTS (
+			SELECT 1 FROM release_sources other
+			WHERE other.release_id = releases.id AND other.dat_source_id != ?
+		)
+	`, sourceID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete releases: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+	result.ReleasesRemoved = int(removed)
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE releases SET dat_source_id = (
+			SELECT rs.dat_source_id FROM release_sources rs
+			JOIN dat_sources ds ON ds.id = rs.dat_source_id
+			WHERE rs.release_id = releases.id AND rs.dat_source_id != ?
+			ORDER BY ds.priority, ds.id LIMIT 1
+		)
+		WHERE id IN (SELECT release_id FROM release_sources WHERE dat_source_id = ?)
+	`, sourceID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update releases: %w", err)
+	}
+	kept, _ := res.RowsAffected()
+	result.ReleasesKept = int(kept)
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM dat_sources WHERE id = ?", sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete dat_source: %w", err)
+	}
+
+	if err := renumberSourcePriorities(ctx, tx, systemID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &result, nil
+}
+
+// renumberSourcePriorities closes the gaps in a system's source priorities,
+// keeping their order.
+func renumberSourcePriorities(ctx context.Context, tx *sql.Tx, systemID int64) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM dat_sources WHERE system_id = ? ORDER BY priority, id", systemID)
+	if err != nil {
+		return fmt.Errorf("failed to query dat_sources: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan dat_source: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query dat_sources: %w", err)
+	}
+
+	for priority, id := range ids {
+		if _, err := tx.ExecContext(ctx, "UPDATE dat_sources SET priority = ? WHERE id = ?", priority, id); err != nil {
+			return fmt.Errorf("failed to renumber dat_source: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,16 @@
 package dat
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
 )
 
 func TestDetectSourceType(t *testing.T) {
@@ -113,3 +117,78 @@ func TestDATSource_Struct(t *testing.T) {
 	assert.Equal(t, "Test DAT", ds.DATName)
 	assert.Equal(t, 0, ds.Priority)
 }
+
+// writeSourceDAT writes a DAT for the GBA from the given source listing the
+// given games, one ROM each.
+func writeSourceDAT(t *testing.T, dir, source string, games ...string) string {
+	t.Helper()
+	content := fmt.Sprintf("<datafile><header><name>Nintendo - Game Boy Advance (%s)</name></header>", source)
+	for i, game := range games {
+		content += fmt.Sprintf(`<game name=%q><rom name=%q size="4" crc="%08x"/></game>`, game, game+".gba", i)
+	}
+	content += "</datafile>"
+	path := filepath.Join(dir, source+".dat")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644)) // #nosec G306
+	return path
+}
+
+func TestRemoveDATSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	importer := NewImporter(conn)
+	for _, path := range []string{
+		writeSourceDAT(t, tmpDir, "No-Intro", "Alpha (USA)", "Beta (USA)"),
+		writeSourceDAT(t, tmpDir, "TOSEC", "Beta (USA)", "Gamma (1991)"),
+		writeSourceDAT(t, tmpDir, "Other", "Delta (USA)"),
+	} {
+		_, err := importer.Import(ctx, path)
+		require.NoError(t, err)
+	}
+
+	sources, err := ListDATSources(ctx, conn, "gba")
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	assert.Equal(t, SourceTOSEC, sources[1].SourceType)
+	assert.Equal(t, 1, sources[1].Priority)
+	assert.Equal(t, 2, sources[1].Releases)
+	assert.Equal(t, 1, sources[1].Exclusive)
+
+	// Beta was last imported from TOSEC but No-Intro lists it too
+	result, err := RemoveDATSource(ctx, conn, sources[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, RemoveSourceResult{ReleasesRemoved: 1, ReleasesKept: 1}, *result)
+
+	rows, err := conn.Query(`
+		SELECT r.name, ds.source_type FROM releases r
+		JOIN dat_sources ds ON ds.id = r.dat_source_id
+		ORDER BY r.name`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	releases := map[string]string{}
+	for rows.Next() {
+		var name, source string
+		require.NoError(t, rows.Scan(&name, &source))
+		releases[name] = source
+	}
+	assert.Equal(t, map[string]string{
+		"Alpha (USA)": "no-intro",
+		"Beta (USA)":  "no-intro",
+		"Delta (USA)": "other",
+	}, releases)
+
+	sources, err = ListDATSources(ctx, conn, "gba")
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, SourceNoIntro, sources[0].SourceType)
+	assert.Equal(t, 0, sources[0].Priority)
+	assert.Equal(t, SourceOther, sources[1].SourceType)
+	assert.Equal(t, 1, sources[1].Priority)
+
+	_, err = RemoveDATSource(ctx, conn, 999)
+	assert.ErrorContains(t, err, "not found")
+}
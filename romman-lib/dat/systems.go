@@ -270,14 +270,16 @@ func detectFromName(name string) string {
 		return sys
 	}
 
-	// Prefix match for variations
+	// Prefix match for variations. The longest prefix wins so that
+	// "game boy advance" is not taken for "game boy" by map order.
+	var best, bestSys string
 	for pattern, sys := range SystemMapping {
-		if strings.HasPrefix(lower, pattern) {
-			return sys
+		if strings.HasPrefix(lower, pattern) && len(pattern) > len(best) {
+			best, bestSys = pattern, sys
 		}
 	}
 
-	return ""
+	return bestSys
 }
 
 func detectFromFilename(filename string) string {
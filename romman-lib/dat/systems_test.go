@@ -14,6 +14,8 @@ func TestDetectSystem_HeaderName(t *testing.T) {
 		{"Nintendo - Nintendo Entertainment System", "nes"},
 		{"Nintendo - Super Nintendo Entertainment System", "snes"},
 		{"Nintendo - Game Boy Advance", "gba"},
+		{"Nintendo - Game Boy Advance (TOSEC)", "gba"},
+		{"Nintendo - Game Boy Color (No-Intro)", "gbc"},
 		{"Sega - Mega Drive - Genesis", "md"},
 		{"Sony - PlayStation", "psx"},
 		{"MAME", "mame"},
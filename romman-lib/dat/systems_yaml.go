@@ -20,6 +20,16 @@ type SystemMappingsConfig struct {
 	DATMappings map[string]string `yaml:"dat_mappings"`
 	// DisplayNames maps system IDs to human-readable names
 	DisplayNames map[string]string `yaml:"display_names"`
+	// BIOS lists the BIOS files each system ID needs
+	BIOS map[string][]BIOSRequirement `yaml:"bios"`
+}
+
+// BIOSRequirement describes a BIOS file a system needs, identified by hash.
+type BIOSRequirement struct {
+	Name     string `yaml:"name"` // Expected filename, e.g. "scph5501.bin"
+	MD5      string `yaml:"md5"`  // At least one of MD5 and SHA1 is set
+	SHA1     string `yaml:"sha1"`
+	Optional bool   `yaml:"optional"` // Improves compatibility but not needed to play
 }
 
 var (
@@ -47,12 +57,30 @@ func LoadSystemMappings() *SystemMappingsConfig {
 	return cachedMappings
 }
 
+// IsKnownSystem reports whether id is a system id that DAT detection,
+// directory detection or the display names can produce.
+func IsKnownSystem(id string) bool {
+	cfg := LoadSystemMappings()
+	if _, ok := cfg.DisplayNames[id]; ok {
+		return true
+	}
+	for _, mapping := range []map[string]string{SystemMapping, DirectoryNameMapping, cfg.DATMappings, cfg.DirectoryMappings} {
+		for _, sys := range mapping {
+			if sys == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // loadEmbeddedDefaults loads the built-in defaults from the embedded YAML.
 func loadEmbeddedDefaults() *SystemMappingsConfig {
 	cfg := &SystemMappingsConfig{
 		DirectoryMappings: make(map[string]string),
 		DATMappings:       make(map[string]string),
 		DisplayNames:      make(map[string]string),
+		BIOS:              make(map[string][]BIOSRequirement),
 	}
 
 	data, err := defaultsFS.ReadFile("system_defaults.yaml")
@@ -81,6 +109,10 @@ func mergeMappings(dest, source *SystemMappingsConfig) {
 	for k, v := range source.DisplayNames {
 		dest.DisplayNames[k] = v
 	}
+	// A system's BIOS list is replaced as a whole so users can drop entries
+	for k, v := range source.BIOS {
+		dest.BIOS[k] = v
+	}
 }
 
 func getSystemMappingPaths() []string {
@@ -36,6 +36,10 @@ dat_mappings:
   "custom dat name": customsystem
 display_names:
   customsystem: "My Custom System"
+bios:
+  psx:
+    - name: custom.bin
+      sha1: 0123456789abcdef0123456789abcdef01234567
 `
 	// #nosec G306
 	err := os.WriteFile(yamlPath, []byte(yamlContent), 0644)
@@ -51,6 +55,8 @@ display_names:
 	assert.Equal(t, "nes", cfg.DirectoryMappings["mynes"])
 	assert.Equal(t, "customsystem", cfg.DATMappings["custom dat name"])
 	assert.Equal(t, "My Custom System", cfg.DisplayNames["customsystem"])
+	assert.Equal(t, []BIOSRequirement{{Name: "custom.bin", SHA1: "0123456789abcdef0123456789abcdef01234567"}}, cfg.BIOS["psx"])
+	assert.Len(t, cfg.BIOS["segacd"], 3, "systems not overridden keep their defaults")
 }
 
 func TestDetectSystemFromDirName_UserMappingOverride(t *testing.T) {
@@ -176,4 +182,9 @@ func TestGetDefaultMappings_HasBuiltInDefaults(t *testing.T) {
 	// Verify display names are present
 	assert.Equal(t, "Nintendo Entertainment System", defaults.DisplayNames["nes"])
 	assert.Equal(t, "Sony PlayStation", defaults.DisplayNames["psx"])
+
+	// Verify BIOS requirements are present
+	require.Len(t, defaults.BIOS["psx"], 3)
+	assert.Equal(t, "scph5501.bin", defaults.BIOS["psx"][1].Name)
+	assert.True(t, defaults.BIOS["gba"][0].Optional)
 }
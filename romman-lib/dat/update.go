@@ -0,0 +1,136 @@
+package dat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UpdateOptions configures Update.
+type UpdateOptions struct {
+	// Prune deletes the releases the DAT no longer lists, rather than only
+	// counting them. It also re-imports an unchanged DAT, so releases left
+	// by an earlier import without it are pruned.
+	Prune bool
+
+	// Timeout bounds downloads of URL sources (0 = DefaultDownloadTimeout).
+	Timeout time.Duration
+}
+
+// Update re-imports a DAT file or URL like Import, skipping it if unchanged,
+// and reports the releases a newer version dropped: games removed or
+// renamed since the previous import of its source. Releases another source
+// still lists are never deleted; with Prune they are only unlinked from
+// this source.
+func (imp *Importer) Update(ctx context.Context, source string, opts UpdateOptions) (*ImportResult, error) {
+	if IsURL(source) {
+		return imp.importURL(ctx, source, opts)
+	}
+	return imp.importDAT(ctx, source, source, opts)
+}
+
+// pruneStaleReleases counts the releases a source lists that its new DAT
+// does not, deleting them if prune is set, and records the counts on result.
+func (imp *Importer) pruneStaleReleases(ctx context.Context, tx *sql.Tx, sourceID int64, dat *DATFile, prune bool, result *ImportResult) error {
+	listed := make(map[string]bool, len(dat.Games))
+	for _, game := range dat.Games {
+		listed[game.Name] = true
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.id, r.name, EXISTS (
+			SELECT 1 FROM release_sources other
+			WHERE other.release_id = r.id AND other.dat_source_id != ?
+		)
+		FROM releases r
+		JOIN release_sources rs ON rs.release_id = r.id
+		WHERE rs.dat_source_id = ?
+	`, sourceID, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to query source releases: %w", err)
+	}
+	var exclusive, shared []int64
+	for rows.Next() {
+		var id int64
+		var name string
+		var otherSource bool
+		if err := rows.Scan(&id, &name, &otherSource); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan release: %w", err)
+		}
+		switch {
+		case listed[name]:
+		case otherSource:
+			shared = append(shared, id)
+		default:
+			exclusive = append(exclusive, id)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query source releases: %w", err)
+	}
+
+	result.StaleReleases = len(exclusive)
+	if !prune {
+		return nil
+	}
+
+	for _, id := range shared {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM release_sources WHERE release_id = ? AND dat_source_id = ?", id, sourceID); err != nil {
+			return fmt.Errorf("failed to unlink release: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE releases SET dat_source_id = (
+				SELECT rs.dat_source_id FROM release_sources rs
+				JOIN dat_sources ds ON ds.id = rs.dat_source_id
+				WHERE rs.release_id = releases.id
+				ORDER BY ds.priority, ds.id LIMIT 1
+			)
+			WHERE id = ?
+		`, id); err != nil {
+			return fmt.Errorf("failed to update release: %w", err)
+		}
+	}
+
+	affected := make(map[string]bool)
+	for _, id := range exclusive {
+		libRows, err := tx.QueryContext(ctx, `
+			SELECT DISTINCT l.name
+			FROM matches m
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			JOIN libraries l ON l.id = sf.library_id
+			WHERE re.release_id = ?
+		`, id)
+		if err != nil {
+			return fmt.Errorf("failed to query matched libraries: %w", err)
+		}
+		for libRows.Next() {
+			var name string
+			if err := libRows.Scan(&name); err != nil {
+				_ = libRows.Close()
+				return fmt.Errorf("failed to scan library: %w", err)
+			}
+			affected[name] = true
+		}
+		_ = libRows.Close()
+		if err := libRows.Err(); err != nil {
+			return fmt.Errorf("failed to query matched libraries: %w", err)
+		}
+
+		// ROM entries and their matches go through ON DELETE CASCADE
+		if _, err := tx.ExecContext(ctx, "DELETE FROM releases WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete release: %w", err)
+		}
+		result.ReleasesRemoved++
+	}
+
+	for name := range affected {
+		result.AffectedLibraries = append(result.AffectedLibraries, name)
+	}
+	sort.Strings(result.AffectedLibraries)
+	return nil
+}
@@ -0,0 +1,145 @@
+package dat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestImporter_Update(t *testing.T) {
+	const v1 = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo - Game Boy Advance</name><version>1</version></header>
+	<game name="Alpha (USA)"><rom name="Alpha (USA).gba" size="4" crc="00000001" sha1="0000000000000000000000000000000000000001"/></game>
+	<game name="Bravo (USA)"><rom name="Bravo (USA).gba" size="4" crc="00000002" sha1="0000000000000000000000000000000000000002"/></game>
+	<game name="Charlie (USA)"><rom name="Charlie (USA).gba" size="4" crc="00000003" sha1="0000000000000000000000000000000000000003"/></game>
+</datafile>`
+	// Version 2 drops Bravo, renames Charlie and adds Delta
+	const v2 = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo - Game Boy Advance</name><version>2</version></header>
+	<game name="Alpha (USA)"><rom name="Alpha (USA).gba" size="4" crc="00000001" sha1="0000000000000000000000000000000000000001"/></game>
+	<game name="Charlie (USA) (Rev 1)"><rom name="Charlie (USA) (Rev 1).gba" size="4" crc="00000033" sha1="0000000000000000000000000000000000000033"/></game>
+	<game name="Delta (USA)"><rom name="Delta (USA).gba" size="4" crc="00000004" sha1="0000000000000000000000000000000000000004"/></game>
+</datafile>`
+	// A second source still lists Charlie under its old name
+	const tosec = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo Game Boy Advance - Games (TOSEC)</name></header>
+	<game name="Charlie (USA)"><rom name="Charlie (USA).gba" size="4" crc="00000003" sha1="0000000000000000000000000000000000000003"/></game>
+</datafile>`
+
+	tmpDir := t.TempDir()
+	writeDAT := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644)) // #nosec G306
+		return path
+	}
+
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+	importer := NewImporter(conn)
+
+	_, err = importer.Import(ctx, writeDAT("gba.dat", v1))
+	require.NoError(t, err)
+	result, err := importer.Import(ctx, writeDAT("gba-tosec.dat", tosec))
+	require.NoError(t, err)
+	require.Equal(t, SourceTOSEC, result.SourceType)
+
+	_, err = conn.Exec(`
+		INSERT INTO libraries (id, name, root_path, system_id)
+		SELECT 1, 'gba-lib', '/roms/gba', id FROM systems WHERE name = 'gba'`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO scanned_files (id, library_id, path, size, mtime) VALUES (1, 1, '/roms/gba/b.gba', 4, 0)`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type)
+		SELECT 1, id, 'sha1' FROM rom_entries WHERE sha1 = '0000000000000000000000000000000000000002'`)
+	require.NoError(t, err)
+
+	releaseNames := func() []string {
+		rows, err := conn.Query("SELECT name FROM releases ORDER BY name")
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	// Without pruning the changed DAT is imported and stale releases counted
+	datPath := writeDAT("gba.dat", v2)
+	result, err = importer.Update(ctx, datPath, UpdateOptions{})
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 2, result.GamesImported)
+	assert.Equal(t, 1, result.GamesUpdated)
+	assert.Equal(t, 1, result.StaleReleases, "Charlie is still listed by TOSEC")
+	assert.Zero(t, result.ReleasesRemoved)
+	assert.Len(t, releaseNames(), 5)
+
+	result, err = importer.Update(ctx, datPath, UpdateOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+
+	// Pruning re-imports the unchanged DAT to remove what it left behind
+	result, err = importer.Update(ctx, datPath, UpdateOptions{Prune: true})
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 1, result.StaleReleases)
+	assert.Equal(t, 1, result.ReleasesRemoved)
+	assert.Equal(t, []string{"gba-lib"}, result.AffectedLibraries)
+	assert.Equal(t, []string{"Alpha (USA)", "Charlie (USA)", "Charlie (USA) (Rev 1)", "Delta (USA)"}, releaseNames())
+
+	var matches int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM matches").Scan(&matches))
+	assert.Zero(t, matches)
+
+	// Charlie now belongs to the TOSEC source alone
+	var sourceType string
+	require.NoError(t, conn.QueryRow(`
+		SELECT ds.source_type FROM releases r
+		JOIN dat_sources ds ON ds.id = r.dat_source_id
+		WHERE r.name = 'Charlie (USA)'`).Scan(&sourceType))
+	assert.Equal(t, string(SourceTOSEC), sourceType)
+	var links int
+	require.NoError(t, conn.QueryRow(`
+		SELECT COUNT(*) FROM release_sources rs
+		JOIN releases r ON r.id = rs.release_id
+		WHERE r.name = 'Charlie (USA)'`).Scan(&links))
+	assert.Equal(t, 1, links)
+}
+
+func TestImporter_ImportChangedDAT(t *testing.T) {
+	tmpDir := t.TempDir()
+	datPath := filepath.Join(tmpDir, "nes.dat")
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	importer := NewImporter(database.Conn())
+
+	games := ""
+	for _, name := range []string{"Game A", "Game B"} {
+		games += `<game name="` + name + `"><rom name="` + name + `.nes" size="1" crc="AAAAAAAA"/></game>`
+		content := `<datafile><header><name>Nintendo - NES</name></header>` + games + `</datafile>`
+		require.NoError(t, os.WriteFile(datPath, []byte(content), 0644)) // #nosec G306
+
+		result, err := importer.Import(ctx, datPath)
+		require.NoError(t, err)
+		assert.False(t, result.Skipped, "a changed DAT is imported")
+		assert.Equal(t, 1, result.GamesImported)
+	}
+}
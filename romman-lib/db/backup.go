@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Backup writes a consistent snapshot of the database to dest with VACUUM
+// INTO, which includes changes still in the WAL and is safe while other
+// connections read and write. dest must not exist yet.
+func (db *DB) Backup(ctx context.Context, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dest)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check backup destination: %w", err)
+	}
+	if _, err := db.conn.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the database with the backup at src through RestoreFile
+// and reopens it, which migrates a backup with an older schema. Other
+// handles on the database file, such as a running web server, must be
+// closed first.
+func (db *DB) Restore(ctx context.Context, src string) error {
+	if err := db.conn.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if err := RestoreFile(ctx, db.path, src); err != nil {
+		return errors.Join(err, db.reopen(ctx))
+	}
+	return db.reopen(ctx)
+}
+
+// RestoreFile replaces the database file at path with the backup at src
+// without opening the current file, so that a database that is corrupt or
+// fails to migrate can still be restored. The backup must pass an integrity
+// check and must not have a schema newer than SchemaVersion; an older schema
+// is migrated by the next Open. Nothing may have the database open.
+func RestoreFile(ctx context.Context, path, src string) error {
+	srcAbs, err := filepath.Abs(src)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", src, err)
+	}
+	dbAbs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if srcAbs == dbAbs {
+		return fmt.Errorf("cannot restore %s onto itself", src)
+	}
+	if info, err := os.Stat(src); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	} else if !info.Mode().IsRegular() {
+		return fmt.Errorf("backup %s is not a file", src)
+	}
+
+	backup, err := sql.Open("sqlite", src)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() { _ = backup.Close() }()
+
+	version, err := backupSchemaVersion(ctx, backup)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("backup has schema version %d, newer than this version of romman supports (%d)", version, SchemaVersion)
+	}
+
+	// Copy through SQLite rather than the file system so that changes in the
+	// backup's own WAL are included
+	staged := path + ".restore"
+	_ = os.Remove(staged)
+	if _, err := backup.ExecContext(ctx, "VACUUM INTO ?", staged); err != nil {
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	_ = backup.Close()
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = os.Remove(staged)
+			return fmt.Errorf("failed to remove %s: %w", path+suffix, err)
+		}
+	}
+	if err := os.Rename(staged, path); err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	return nil
+}
+
+// backupSchemaVersion checks that conn holds an intact romman database and
+// returns its schema version.
+func backupSchemaVersion(ctx context.Context, conn *sql.DB) (int, error) {
+	var result string
+	if err := conn.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("backup failed integrity check: %s", result)
+	}
+
+	var version int
+	err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("backup is not a romman database: %w", err)
+	}
+	return version, nil
+}
+
+// reopen opens a new connection to the database file in place of the closed one.
+func (db *DB) reopen(ctx context.Context) error {
+	reopened, err := OpenWithConfig(ctx, db.path, db.pool)
+	if err != nil {
+		return err
+	}
+	db.conn = reopened.conn
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"runtime"
+	"time"
 
 	"github.com/XSAM/otelsql"
 	"go.opentelemetry.io/otel/attribute"
@@ -15,15 +17,48 @@ import (
 type DB struct {
 	conn *sql.DB
 	path string
+	pool PoolConfig
 }
 
-// Open opens or creates a SQLite database at the given path.
+// PoolConfig controls the database/sql connection pool.
+//
+// SQLite in WAL mode allows one writer alongside any number of readers; extra
+// writers wait on busy_timeout rather than failing. Size MaxOpenConns for the
+// writer plus the expected concurrent readers: the defaults (NumCPU+1) suit the
+// CLI, where scan workers read the hash cache while one collector writes. A
+// long-running web server should keep MaxIdleConns equal to MaxOpenConns so
+// connections aren't reopened per request, and may set ConnMaxLifetime to
+// recycle connections periodically.
+type PoolConfig struct {
+	MaxOpenConns    int           // Maximum open connections (0 = NumCPU+1)
+	MaxIdleConns    int           // Maximum idle connections (0 = MaxOpenConns)
+	ConnMaxLifetime time.Duration // Maximum connection age (0 = unlimited)
+}
+
+// DefaultPoolConfig returns pool settings suitable for one writer plus a reader per CPU.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns: runtime.NumCPU() + 1,
+	}
+}
+
+// Open opens or creates a SQLite database at the given path with default pool settings.
 // The connection is instrumented with OpenTelemetry for automatic query tracing.
 func Open(ctx context.Context, path string) (*DB, error) {
+	return OpenWithConfig(ctx, path, DefaultPoolConfig())
+}
+
+// OpenWithConfig opens or creates a SQLite database using the given pool settings.
+func OpenWithConfig(ctx context.Context, path string, pool PoolConfig) (*DB, error) {
 	dbName := filepath.Base(path)
 
+	// busy_timeout and foreign_keys are per-connection settings, so they are passed
+	// in the DSN to apply to every pooled connection rather than just the first.
+	// The busy timeout waits up to 30 seconds for locks instead of failing immediately.
+	dsn := path + "?_pragma=busy_timeout(30000)&_pragma=foreign_keys(1)"
+
 	// Use otelsql to wrap the database connection with tracing
-	conn, err := otelsql.Open("sqlite", path,
+	conn, err := otelsql.Open("sqlite", dsn,
 		otelsql.WithAttributes(
 			attribute.String("db.system", "sqlite"),
 			attribute.String("db.name", dbName),
@@ -43,25 +78,15 @@ func Open(ctx context.Context, path string) (*DB, error) {
 		attribute.String("db.name", dbName),
 	))
 
-	// Enable WAL mode for better concurrent access
+	applyPoolConfig(conn, pool)
+
+	// Enable WAL mode for better concurrent access (persisted in the database file)
 	if _, err := conn.ExecContext(ctx, "PRAGMA journal_mode=WAL"); err != nil {
 		_ = conn.Close()
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Set busy timeout to 30 seconds to wait for locks instead of failing immediately
-	if _, err := conn.ExecContext(ctx, "PRAGMA busy_timeout=30000"); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
-	}
-
-	// Enable foreign keys
-	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
-	db := &DB{conn: conn, path: path}
+	db := &DB{conn: conn, path: path, pool: pool}
 	if err := db.migrate(ctx); err != nil {
 		_ = conn.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
@@ -70,6 +95,20 @@ func Open(ctx context.Context, path string) (*DB, error) {
 	return db, nil
 }
 
+// applyPoolConfig sets pool limits, filling in defaults for zero values.
+func applyPoolConfig(conn *sql.DB, pool PoolConfig) {
+	if pool.MaxOpenConns <= 0 {
+		pool.MaxOpenConns = DefaultPoolConfig().MaxOpenConns
+	}
+	if pool.MaxIdleConns <= 0 || pool.MaxIdleConns > pool.MaxOpenConns {
+		pool.MaxIdleConns = pool.MaxOpenConns
+	}
+
+	conn.SetMaxOpenConns(pool.MaxOpenConns)
+	conn.SetMaxIdleConns(pool.MaxIdleConns)
+	conn.SetConnMaxLifetime(pool.ConnMaxLifetime)
+}
+
 // Close closes the database connection.
 func (db *DB) Close() error {
 	return db.conn.Close()
@@ -80,6 +119,10 @@ func (db *DB) Conn() *sql.DB {
 	return db.conn
 }
 
+// SchemaVersion is the schema version migrate brings a database up to. Bump it
+// with every new migration.
+const SchemaVersion = 28
+
 // migrate runs database migrations up to the current schema version.
 func (db *DB) migrate(ctx context.Context) error {
 	// Create schema version table if not exists
@@ -144,6 +187,101 @@ func (db *DB) migrate(ctx context.Context) error {
 			return err
 		}
 	}
+	if version < 10 {
+		if err := db.migrateV10(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 11 {
+		if err := db.migrateV11(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 12 {
+		if err := db.migrateV12(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 13 {
+		if err := db.migrateV13(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 14 {
+		if err := db.migrateV14(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 15 {
+		if err := db.migrateV15(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 16 {
+		if err := db.migrateV16(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 17 {
+		if err := db.migrateV17(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 18 {
+		if err := db.migrateV18(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 19 {
+		if err := db.migrateV19(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 20 {
+		if err := db.migrateV20(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 21 {
+		if err := db.migrateV21(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 22 {
+		if err := db.migrateV22(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 23 {
+		if err := db.migrateV23(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 24 {
+		if err := db.migrateV24(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 25 {
+		if err := db.migrateV25(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 26 {
+		if err := db.migrateV26(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 27 {
+		if err := db.migrateV27(ctx); err != nil {
+			return err
+		}
+	}
+	if version < 28 {
+		if err := db.migrateV28(ctx); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -276,7 +414,7 @@ func (db *DB) migrateV2(ctx context.Context) error {
 			id INTEGER PRIMARY KEY,
 			scanned_file_id INTEGER NOT NULL,
 			rom_entry_id INTEGER NOT NULL,
-			match_type TEXT NOT NULL,  -- 'sha1' or 'crc32'
+			match_type TEXT NOT NULL,  -- 'sha1', 'md5', 'crc32' or a name match
 			FOREIGN KEY(scanned_file_id) REFERENCES scanned_files(id) ON DELETE CASCADE,
 			FOREIGN KEY(rom_entry_id) REFERENCES rom_entries(id) ON DELETE CASCADE,
 			UNIQUE(scanned_file_id, rom_entry_id)
@@ -418,3 +556,385 @@ func (db *DB) migrateV9(ctx context.Context) error {
 
 	return nil
 }
+
+// migrateV10 normalizes stored hashes to lowercase so equality lookups can use indexes.
+func (db *DB) migrateV10(ctx context.Context) error {
+	schema := `
+		UPDATE rom_entries SET
+			sha1 = LOWER(sha1),
+			crc32 = LOWER(crc32),
+			md5 = LOWER(md5);
+
+		UPDATE scanned_files SET
+			sha1 = LOWER(sha1),
+			crc32 = LOWER(crc32);
+
+		INSERT INTO schema_version (version) VALUES (10);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v10 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV11 adds the scan_state table for checkpointed scans.
+func (db *DB) migrateV11(ctx context.Context) error {
+	schema := `
+		-- Per-library scan progress so interrupted scans can be detected and resumed
+		CREATE TABLE IF NOT EXISTS scan_state (
+			library_id INTEGER PRIMARY KEY REFERENCES libraries(id) ON DELETE CASCADE,
+			status TEXT NOT NULL DEFAULT 'running', -- 'running' or 'complete'
+			files_committed INTEGER NOT NULL DEFAULT 0,
+			started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO schema_version (version) VALUES (11);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v11 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV12 adds the file_tags table for user tags that persist across rescans.
+func (db *DB) migrateV12(ctx context.Context) error {
+	schema := `
+		-- User tags on files, keyed by location rather than scanned_files.id so
+		-- they survive rescans and match rebuilds. archive_path is '' for plain files.
+		CREATE TABLE IF NOT EXISTS file_tags (
+			id INTEGER PRIMARY KEY,
+			library_id INTEGER NOT NULL REFERENCES libraries(id) ON DELETE CASCADE,
+			path TEXT NOT NULL,
+			archive_path TEXT NOT NULL DEFAULT '',
+			tag TEXT NOT NULL, -- 'keep', 'delete', 'replace' or a free-form label
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(library_id, path, archive_path, tag)
+		);
+		CREATE INDEX IF NOT EXISTS idx_file_tags_tag ON file_tags(library_id, tag);
+
+		INSERT INTO schema_version (version) VALUES (12);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v12 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV13 marks scanned files imported from hash lists as virtual.
+func (db *DB) migrateV13(ctx context.Context) error {
+	schema := `
+		-- Entries imported from another tool's hash list rather than hashed from disk.
+		-- Virtual files count towards completion but are never verified, moved or deleted.
+		ALTER TABLE scanned_files ADD COLUMN virtual INTEGER NOT NULL DEFAULT 0;
+
+		INSERT INTO schema_version (version) VALUES (13);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v13 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV14 adds the bios_files table for the shared BIOS directory.
+func (db *DB) migrateV14(ctx context.Context) error {
+	schema := `
+		-- Files in the shared BIOS directory, one row per BIOS requirement
+		-- they satisfy. Files matching no requirement have system = ''.
+		CREATE TABLE IF NOT EXISTS bios_files (
+			id INTEGER PRIMARY KEY,
+			path TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			sha1 TEXT NOT NULL,
+			md5 TEXT NOT NULL,
+			system TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '', -- Requirement name, e.g. 'scph5501.bin'
+			scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(path, system, name)
+		);
+		CREATE INDEX IF NOT EXISTS idx_bios_files_system ON bios_files(system, name);
+
+		INSERT INTO schema_version (version) VALUES (14);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v14 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV15 adds the CRC32 declared in a zip entry's header, kept alongside
+// the computed crc32 so corrupt or repacked zips can be detected.
+func (db *DB) migrateV15(ctx context.Context) error {
+	schema := `
+		ALTER TABLE scanned_files ADD COLUMN zip_crc32 TEXT NOT NULL DEFAULT '';
+
+		INSERT INTO schema_version (version) VALUES (15);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v15 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV16 adds multi-system libraries, which resolve each file's system
+// from its top-level subdirectory instead of using the library's system.
+func (db *DB) migrateV16(ctx context.Context) error {
+	schema := `
+		ALTER TABLE libraries ADD COLUMN multi_system INTEGER NOT NULL DEFAULT 0;
+
+		INSERT INTO schema_version (version) VALUES (16);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v16 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV17 adds the software list part a ROM entry belongs to.
+func (db *DB) migrateV17(ctx context.Context) error {
+	schema := `
+		ALTER TABLE rom_entries ADD COLUMN part TEXT NOT NULL DEFAULT '';
+
+		INSERT INTO schema_version (version) VALUES (17);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v17 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV18 records files the last scan left out, with the reason, so
+// unmatched reports can explain them.
+func (db *DB) migrateV18(ctx context.Context) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS skipped_files (
+			id INTEGER PRIMARY KEY,
+			library_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			FOREIGN KEY(library_id) REFERENCES libraries(id) ON DELETE CASCADE,
+			UNIQUE(library_id, path)
+		);
+
+		INSERT INTO schema_version (version) VALUES (18);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v18 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV19 stores the MD5 of scanned files, the third hash the scanner
+// matches on.
+func (db *DB) migrateV19(ctx context.Context) error {
+	schema := `
+		ALTER TABLE scanned_files ADD COLUMN md5 TEXT NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS idx_scanned_files_md5 ON scanned_files(md5);
+		CREATE INDEX IF NOT EXISTS idx_rom_entries_md5 ON rom_entries(md5);
+
+		INSERT INTO schema_version (version) VALUES (19);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v19 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV20 stores the hashes of headered ROMs (iNES, Lynx, ...) minus
+// their header, which is what No-Intro DATs hash.
+func (db *DB) migrateV20(ctx context.Context) error {
+	schema := `
+		ALTER TABLE scanned_files ADD COLUMN headerless_sha1 TEXT NOT NULL DEFAULT '';
+		ALTER TABLE scanned_files ADD COLUMN headerless_crc32 TEXT NOT NULL DEFAULT '';
+
+		INSERT INTO schema_version (version) VALUES (20);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v20 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV21 removes duplicate scanned_files rows left for plain files that
+// were rehashed before upserts handled a NULL archive_path.
+func (db *DB) migrateV21(ctx context.Context) error {
+	schema := `
+		-- Keep only the newest row of each plain file; older duplicates hold
+		-- the hashes from before the file was last rehashed
+		DELETE FROM scanned_files
+		WHERE archive_path IS NULL AND EXISTS (
+			SELECT 1 FROM scanned_files newer
+			WHERE newer.library_id = scanned_files.library_id
+				AND newer.path = scanned_files.path
+				AND newer.archive_path IS NULL
+				AND newer.id > scanned_files.id
+		);
+		DELETE FROM matches WHERE scanned_file_id NOT IN (SELECT id FROM scanned_files);
+
+		INSERT INTO schema_version (version) VALUES (21);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v21 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV22 records the header-skip rule and merge mode from the DAT's
+// <clrmamepro> element.
+func (db *DB) migrateV22(ctx context.Context) error {
+	schema := `
+		ALTER TABLE systems ADD COLUMN dat_header_rule TEXT NOT NULL DEFAULT '';
+		ALTER TABLE systems ADD COLUMN dat_force_merging TEXT NOT NULL DEFAULT '';
+
+		INSERT INTO schema_version (version) VALUES (22);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v22 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV23 adds the hash_conflicts view: ROM entries whose SHA1 also
+// belongs to another release of the same system, as when two DAT sources
+// name the same dump differently.
+func (db *DB) migrateV23(ctx context.Context) error {
+	schema := `
+		CREATE VIEW IF NOT EXISTS hash_conflicts AS
+		SELECT r.system_id, re.sha1, re.id AS rom_entry_id, re.name AS rom_name,
+			r.id AS release_id, r.name AS release_name, COALESCE(ds.source_type, '') AS source_type
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		LEFT JOIN dat_sources ds ON ds.id = r.dat_source_id
+		WHERE re.sha1 IS NOT NULL AND re.sha1 != '' AND EXISTS (
+			SELECT 1 FROM rom_entries other
+			JOIN releases other_r ON other_r.id = other.release_id
+			WHERE other.sha1 = re.sha1 AND other_r.system_id = r.system_id AND other_r.id != r.id
+		);
+
+		INSERT INTO schema_version (version) VALUES (23);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v23 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV24 adds SHA256 hashes to ROM entries and scanned files. Redump
+// disc DATs carry SHA256, and scanned files only record it when scan.sha256
+// is enabled.
+func (db *DB) migrateV24(ctx context.Context) error {
+	schema := `
+		ALTER TABLE rom_entries ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
+		ALTER TABLE scanned_files ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS idx_rom_entries_sha256 ON rom_entries(sha256) WHERE sha256 != '';
+		CREATE INDEX IF NOT EXISTS idx_scanned_files_sha256 ON scanned_files(sha256) WHERE sha256 != '';
+
+		INSERT INTO schema_version (version) VALUES (24);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v24 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV25 records the last directory a running scan has fully walked and
+// committed, so an interrupted scan can resume past it.
+func (db *DB) migrateV25(ctx context.Context) error {
+	schema := `
+		ALTER TABLE scan_state ADD COLUMN last_dir TEXT NOT NULL DEFAULT '';
+
+		INSERT INTO schema_version (version) VALUES (25);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v25 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV26 records every DAT source that lists a release. releases.dat_source_id
+// only keeps the last source imported, so a single source's releases can be
+// removed without dropping those another source also lists.
+func (db *DB) migrateV26(ctx context.Context) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS release_sources (
+			release_id INTEGER NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+			dat_source_id INTEGER NOT NULL REFERENCES dat_sources(id) ON DELETE CASCADE,
+			PRIMARY KEY(release_id, dat_source_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_release_sources_dat_source_id ON release_sources(dat_source_id);
+
+		INSERT OR IGNORE INTO release_sources (release_id, dat_source_id)
+		SELECT id, dat_source_id FROM releases WHERE dat_source_id IS NOT NULL;
+
+		INSERT INTO schema_version (version) VALUES (26);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v26 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV27 stores the scraped genre, which organize can sort files by.
+func (db *DB) migrateV27(ctx context.Context) error {
+	schema := `
+		ALTER TABLE game_metadata ADD COLUMN genre TEXT;
+
+		INSERT INTO schema_version (version) VALUES (27);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v27 migration: %w", err)
+	}
+
+	return nil
+}
+
+// migrateV28 marks matches assigned by hand, which re-matching leaves alone.
+func (db *DB) migrateV28(ctx context.Context) error {
+	schema := `
+		ALTER TABLE matches ADD COLUMN is_manual INTEGER NOT NULL DEFAULT 0;
+
+		INSERT INTO schema_version (version) VALUES (28);
+	`
+
+	if _, err := db.conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to execute v28 migration: %w", err)
+	}
+
+	return nil
+}
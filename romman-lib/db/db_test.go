@@ -2,9 +2,13 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,7 +38,7 @@ func TestSchemaVersion(t *testing.T) {
 	var version int
 	err = db.Conn().QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version)
 	require.NoError(t, err)
-	assert.Equal(t, 9, version, "schema version should be 9")
+	assert.Equal(t, 28, version, "schema version should be 28")
 }
 
 func TestTablesExist(t *testing.T) {
@@ -48,7 +52,7 @@ func TestTablesExist(t *testing.T) {
 	tables := []string{
 		"systems", "releases", "rom_entries", "schema_version",
 		"libraries", "scanned_files", "matches",
-		"game_metadata", "game_media",
+		"game_metadata", "game_media", "scan_state", "file_tags", "bios_files",
 	}
 	for _, table := range tables {
 		var name string
@@ -80,7 +84,7 @@ func TestMigrationIdempotent(t *testing.T) {
 	var version int
 	err = db.Conn().QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version)
 	require.NoError(t, err)
-	assert.Equal(t, 9, version, "schema version should still be 9 after multiple opens")
+	assert.Equal(t, 28, version, "schema version should still be 28 after multiple opens")
 }
 
 func TestV6Columns(t *testing.T) {
@@ -191,6 +195,38 @@ func TestV9Indexes(t *testing.T) {
 	}
 }
 
+func TestV10LowercaseHashes(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := Open(context.Background(), dbPath)
+	require.NoError(t, err)
+
+	_, err = db.Conn().Exec(`INSERT INTO systems (name) VALUES ('nes')`)
+	require.NoError(t, err)
+	_, err = db.Conn().Exec(`INSERT INTO releases (system_id, name) VALUES (1, 'Game')`)
+	require.NoError(t, err)
+	_, err = db.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, md5, size)
+		VALUES (1, 'game.nes', 'ABCDEF0123', 'DEADBEEF', 'A1B2C3', 1024)
+	`)
+	require.NoError(t, err)
+
+	// Re-run the migration against existing mixed-case rows
+	_, err = db.Conn().Exec(`DELETE FROM schema_version WHERE version = 10`)
+	require.NoError(t, err)
+	require.NoError(t, db.migrateV10(context.Background()))
+
+	var sha1, crc32, md5 string
+	err = db.Conn().QueryRow(`SELECT sha1, crc32, md5 FROM rom_entries WHERE release_id = 1`).Scan(&sha1, &crc32, &md5)
+	require.NoError(t, err)
+
+	assert.Equal(t, "abcdef0123", sha1)
+	assert.Equal(t, "deadbeef", crc32)
+	assert.Equal(t, "a1b2c3", md5)
+	_ = db.Close()
+}
+
 func TestClose(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -221,3 +257,202 @@ func TestDBConn(t *testing.T) {
 	err = conn.Ping()
 	assert.NoError(t, err)
 }
+
+func TestOpenWithConfig_PragmasOnEveryConnection(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db, err := OpenWithConfig(context.Background(), filepath.Join(tmpDir, "test.db"), PoolConfig{MaxOpenConns: 3})
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	assert.Equal(t, 3, db.Conn().Stats().MaxOpenConnections)
+
+	// Hold several connections at once so each is a distinct pooled connection
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		c, err := db.Conn().Conn(ctx)
+		require.NoError(t, err)
+		conns = append(conns, c)
+	}
+	defer func() {
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+
+	for _, c := range conns {
+		var fk, timeout int
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fk))
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&timeout))
+		assert.Equal(t, 1, fk)
+		assert.Equal(t, 30000, timeout)
+	}
+}
+
+func TestConcurrentReadsDoNotStarveWriter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db, err := OpenWithConfig(context.Background(), filepath.Join(tmpDir, "test.db"), PoolConfig{MaxOpenConns: 5})
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	conn := db.Conn()
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Readers hammer the database until the writer finishes
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				var n int
+				_ = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM releases`).Scan(&n)
+			}
+		}()
+	}
+
+	const writes = 200
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < writes; i++ {
+			if _, err := conn.Exec(`INSERT INTO releases (system_id, name) VALUES (1, ?)`, fmt.Sprintf("Game %d", i)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(20 * time.Second):
+		t.Fatal("writer starved by concurrent readers")
+	}
+	cancel()
+	wg.Wait()
+
+	var count int
+	require.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM releases`).Scan(&count))
+	assert.Equal(t, writes, count)
+}
+
+func TestMaintenance_VacuumAnalyzeStats(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(context.Background(), dbPath)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	_, err = db.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	for i := 0; i < 2000; i++ {
+		_, err = db.Conn().Exec(`INSERT INTO releases (system_id, name, description) VALUES (1, ?, ?)`,
+			fmt.Sprintf("Game %d", i), fmt.Sprintf("%0200d", i))
+		require.NoError(t, err)
+	}
+	_, err = db.Conn().Exec(`DELETE FROM releases WHERE id > 10`)
+	require.NoError(t, err)
+
+	before, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dbPath, before.Path)
+	assert.Greater(t, before.FreePages, int64(0))
+	counts := make(map[string]int64)
+	for _, tc := range before.Tables {
+		counts[tc.Name] = tc.Rows
+	}
+	assert.Equal(t, int64(10), counts["releases"])
+	assert.Equal(t, int64(1), counts["systems"])
+
+	require.NoError(t, db.Analyze(ctx))
+	require.NoError(t, db.Vacuum(ctx))
+
+	after, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), after.FreePages)
+	assert.Less(t, after.PageCount, before.PageCount)
+	assert.Equal(t, int64(0), after.WALSize)
+	assert.Equal(t, after.PageCount*after.PageSize, after.FileSize)
+}
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(context.Background(), filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	countSystems := func() int {
+		var n int
+		require.NoError(t, db.Conn().QueryRow(`SELECT COUNT(*) FROM systems`).Scan(&n))
+		return n
+	}
+	_, err = db.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+
+	backupPath := filepath.Join(dir, "backup.db")
+	require.NoError(t, db.Backup(ctx, backupPath))
+	assert.Error(t, db.Backup(ctx, backupPath), "existing destination should be refused")
+
+	_, err = db.Conn().Exec(`INSERT INTO systems (id, name) VALUES (2, 'snes')`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, countSystems())
+
+	require.NoError(t, db.Restore(ctx, backupPath))
+	assert.Equal(t, 1, countSystems(), "restore should bring back the backed up rows")
+	_, err = os.Stat(filepath.Join(dir, "test.db.restore"))
+	assert.True(t, os.IsNotExist(err), "staged copy should be renamed into place")
+
+	// A backup written by a newer romman is refused and leaves the database alone
+	newer := filepath.Join(dir, "newer.db")
+	require.NoError(t, db.Backup(ctx, newer))
+	other, err := Open(ctx, newer)
+	require.NoError(t, err)
+	_, err = other.Conn().Exec(`INSERT INTO schema_version (version) VALUES (?)`, SchemaVersion+1)
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+	err = db.Restore(ctx, newer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer")
+	assert.Equal(t, 1, countSystems())
+
+	notDB := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notDB, []byte("not a database"), 0o600))
+	assert.Error(t, db.Restore(ctx, notDB))
+	assert.Error(t, db.Restore(ctx, filepath.Join(dir, "missing.db")))
+	assert.Error(t, db.Restore(ctx, filepath.Join(dir, "test.db")))
+	assert.Equal(t, 1, countSystems())
+}
+
+func TestRestoreFile_CorruptDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "backup.db")
+	db, err := Open(ctx, filepath.Join(dir, "old.db"))
+	require.NoError(t, err)
+	_, err = db.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Backup(ctx, backupPath))
+	require.NoError(t, db.Close())
+
+	// A database that cannot be opened is still replaced
+	dbPath := filepath.Join(dir, "test.db")
+	require.NoError(t, os.WriteFile(dbPath, []byte("corrupt"), 0o600))
+	_, err = Open(ctx, dbPath)
+	require.Error(t, err)
+
+	require.NoError(t, RestoreFile(ctx, dbPath, backupPath))
+	restored, err := Open(ctx, dbPath)
+	require.NoError(t, err)
+	defer func() { _ = restored.Close() }()
+	var n int
+	require.NoError(t, restored.Conn().QueryRow(`SELECT COUNT(*) FROM systems`).Scan(&n))
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// TableCount is the row count of one table.
+type TableCount struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// Stats describes the database file and the size of its tables.
+type Stats struct {
+	Path      string       `json:"path"`
+	FileSize  int64        `json:"fileSize"` // Main file only, excluding the WAL
+	WALSize   int64        `json:"walSize"`
+	PageSize  int64        `json:"pageSize"`
+	PageCount int64        `json:"pageCount"`
+	FreePages int64        `json:"freePages"` // Unused pages VACUUM would reclaim
+	Tables    []TableCount `json:"tables"`
+}
+
+// Vacuum rebuilds the database file, reclaiming the space of deleted rows,
+// and truncates the WAL so the file shrinks on disk. It needs free disk
+// space for a full copy of the database and blocks other writers meanwhile.
+func (db *DB) Vacuum(ctx context.Context) error {
+	if _, err := db.conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if _, err := db.conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
+
+// Analyze refreshes the statistics the query planner uses to pick indexes.
+func (db *DB) Analyze(ctx context.Context) error {
+	if _, err := db.conn.ExecContext(ctx, "ANALYZE"); err != nil {
+		return fmt.Errorf("failed to analyze: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the database's file size, page usage and per-table row counts.
+func (db *DB) Stats(ctx context.Context) (*Stats, error) {
+	st := &Stats{Path: db.path}
+	if info, err := os.Stat(db.path); err == nil {
+		st.FileSize = info.Size()
+	}
+	if info, err := os.Stat(db.path + "-wal"); err == nil {
+		st.WALSize = info.Size()
+	}
+
+	for pragma, dest := range map[string]*int64{
+		"page_size":      &st.PageSize,
+		"page_count":     &st.PageCount,
+		"freelist_count": &st.FreePages,
+	} {
+		if err := db.conn.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(dest); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", pragma, err)
+		}
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	_ = rows.Close()
+
+	for _, name := range names {
+		tc := TableCount{Name: name}
+		// Names come from sqlite_master, not user input
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, name) // #nosec G201
+		if err := db.conn.QueryRowContext(ctx, query).Scan(&tc.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", name, err)
+		}
+		st.Tables = append(st.Tables, tc)
+	}
+
+	return st, nil
+}
@@ -14,24 +14,26 @@ type GameMetadata struct {
 	ReleaseDate string
 	Developer   string
 	Publisher   string
+	Genre       string
 	Rating      float64
 }
 
 // SetGameMetadata saves metadata for a release.
 func (db *DB) SetGameMetadata(ctx context.Context, md GameMetadata) error {
 	query := `
-		INSERT INTO game_metadata (release_id, provider_id, description, release_date, developer, publisher, rating, scraped_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		INSERT INTO game_metadata (release_id, provider_id, description, release_date, developer, publisher, genre, rating, scraped_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(release_id) DO UPDATE SET
 			provider_id = excluded.provider_id,
 			description = excluded.description,
 			release_date = excluded.release_date,
 			developer = excluded.developer,
 			publisher = excluded.publisher,
+			genre = excluded.genre,
 			rating = excluded.rating,
 			scraped_at = CURRENT_TIMESTAMP
 	`
-	_, err := db.conn.ExecContext(ctx, query, md.ReleaseID, md.ProviderID, md.Description, md.ReleaseDate, md.Developer, md.Publisher, md.Rating)
+	_, err := db.conn.ExecContext(ctx, query, md.ReleaseID, md.ProviderID, md.Description, md.ReleaseDate, md.Developer, md.Publisher, md.Genre, md.Rating)
 	if err != nil {
 		return fmt.Errorf("failed to save game metadata: %w", err)
 	}
@@ -41,13 +43,13 @@ func (db *DB) SetGameMetadata(ctx context.Context, md GameMetadata) error {
 // GetGameMetadata retrieves metadata for a release.
 func (db *DB) GetGameMetadata(ctx context.Context, releaseID int64) (*GameMetadata, error) {
 	query := `
-		SELECT release_id, provider_id, description, release_date, developer, publisher, rating
+		SELECT release_id, provider_id, description, release_date, developer, publisher, COALESCE(genre, ''), rating
 		FROM game_metadata WHERE release_id = ?
 	`
 	row := db.conn.QueryRowContext(ctx, query, releaseID)
 
 	var md GameMetadata
-	if err := row.Scan(&md.ReleaseID, &md.ProviderID, &md.Description, &md.ReleaseDate, &md.Developer, &md.Publisher, &md.Rating); err != nil {
+	if err := row.Scan(&md.ReleaseID, &md.ProviderID, &md.Description, &md.ReleaseDate, &md.Developer, &md.Publisher, &md.Genre, &md.Rating); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
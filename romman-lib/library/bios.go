@@ -0,0 +1,206 @@
+package library
+
+import (
+	"context"
+	"crypto/md5"  // #nosec G501
+	"crypto/sha1" // #nosec G505
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxBIOSSize skips files too large to be a BIOS, such as stray disc images.
+const maxBIOSSize = 64 << 20
+
+// BIOSScanResult contains the results of scanning a BIOS directory.
+type BIOSScanResult struct {
+	Dir          string `json:"dir"`
+	FilesScanned int    `json:"filesScanned"`
+	Matched      int    `json:"matched"` // Files satisfying at least one requirement
+	Unknown      int    `json:"unknown"`
+}
+
+// BIOSStatus reports whether a required BIOS file has been found.
+type BIOSStatus struct {
+	System   string `json:"system"`
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+	Present  bool   `json:"present"`
+	Path     string `json:"path,omitempty"`
+}
+
+// BIOSManager tracks a shared BIOS directory, such as RetroArch's system/
+// folder, once for every system that needs it.
+type BIOSManager struct {
+	db           *sql.DB
+	requirements map[string][]dat.BIOSRequirement
+}
+
+// NewBIOSManager creates a BIOS manager for the given per-system requirements.
+func NewBIOSManager(db *sql.DB, requirements map[string][]dat.BIOSRequirement) *BIOSManager {
+	return &BIOSManager{db: db, requirements: requirements}
+}
+
+// Scan hashes every file under dir and matches it against the BIOS
+// requirements of all systems, replacing the results of any previous scan.
+func (b *BIOSManager) Scan(ctx context.Context, dir string) (*BIOSScanResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.BIOSScan",
+		tracing.WithAttributes(attribute.String("bios.dir", dir)),
+	)
+	defer span.End()
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrInvalidArg, dir)
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bios_files`); err != nil {
+		return nil, WrapDBError(err, "clear bios files")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO bios_files (path, size, sha1, md5, system, name)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	result := &BIOSScanResult{Dir: absDir}
+	err = filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() || info.Size() > maxBIOSSize {
+			return nil
+		}
+
+		sha1Hash, md5Hash, err := hashBIOSFile(path)
+		if err != nil {
+			slog.Warn("failed to hash BIOS file", "path", path, "error", err)
+			return nil
+		}
+		result.FilesScanned++
+
+		matched := false
+		for system, reqs := range b.requirements {
+			for _, req := range reqs {
+				if !biosMatches(req, sha1Hash, md5Hash) {
+					continue
+				}
+				matched = true
+				if _, err := stmt.ExecContext(ctx, path, info.Size(), sha1Hash, md5Hash, system, req.Name); err != nil {
+					return WrapDBError(err, "store bios file")
+				}
+			}
+		}
+
+		if matched {
+			result.Matched++
+			return nil
+		}
+		result.Unknown++
+		_, err = stmt.ExecContext(ctx, path, info.Size(), sha1Hash, md5Hash, "", "")
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	tracing.AddSpanAttributes(span,
+		attribute.Int("result.files", result.FilesScanned),
+		attribute.Int("result.matched", result.Matched),
+	)
+	return result, nil
+}
+
+// biosMatches reports whether a file's hashes satisfy a requirement.
+func biosMatches(req dat.BIOSRequirement, sha1Hash, md5Hash string) bool {
+	if req.SHA1 != "" && strings.EqualFold(req.SHA1, sha1Hash) {
+		return true
+	}
+	return req.MD5 != "" && strings.EqualFold(req.MD5, md5Hash)
+}
+
+// hashBIOSFile computes the SHA1 and MD5 of a file.
+func hashBIOSFile(path string) (string, string, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	sha1Hasher := sha1.New() // #nosec G401
+	md5Hasher := md5.New()   // #nosec G401
+	if _, err := io.Copy(io.MultiWriter(sha1Hasher, md5Hasher), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(sha1Hasher.Sum(nil)), hex.EncodeToString(md5Hasher.Sum(nil)), nil
+}
+
+// Status returns the state of each BIOS file a system needs. Systems
+// without BIOS requirements return an empty list.
+func (b *BIOSManager) Status(ctx context.Context, system string) ([]BIOSStatus, error) {
+	var statuses []BIOSStatus
+	for _, req := range b.requirements[system] {
+		status := BIOSStatus{System: system, Name: req.Name, Optional: req.Optional}
+		err := b.db.QueryRowContext(ctx, `
+			SELECT path FROM bios_files WHERE system = ? AND name = ? ORDER BY path LIMIT 1
+		`, system, req.Name).Scan(&status.Path)
+		switch {
+		case err == nil:
+			status.Present = true
+		case err != sql.ErrNoRows:
+			return nil, WrapDBError(err, "get bios status")
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Ready reports whether every non-optional BIOS of a system has been found,
+// and whether the system needs any BIOS at all.
+func (b *BIOSManager) Ready(ctx context.Context, system string) (ready, required bool, err error) {
+	statuses, err := b.Status(ctx, system)
+	if err != nil {
+		return false, false, err
+	}
+	return BIOSReady(statuses), len(statuses) > 0, nil
+}
+
+// BIOSReady reports whether all non-optional BIOS in statuses are present.
+func BIOSReady(statuses []BIOSStatus) bool {
+	for _, s := range statuses {
+		if !s.Present && !s.Optional {
+			return false
+		}
+	}
+	return true
+}
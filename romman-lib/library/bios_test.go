@@ -0,0 +1,89 @@
+package library
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) // #nosec G401
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBIOSManager_ScanAndStatus(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	biosDir := filepath.Join(tmpDir, "system")
+	require.NoError(t, os.MkdirAll(filepath.Join(biosDir, "psx"), 0755))                                    // #nosec G301
+	require.NoError(t, os.WriteFile(filepath.Join(biosDir, "psx", "renamed.bin"), []byte("us bios"), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(biosDir, "shared.bin"), []byte("shared bios"), 0644))     // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(biosDir, "readme.txt"), []byte("not a bios"), 0644))      // #nosec G306
+
+	requirements := map[string][]dat.BIOSRequirement{
+		"psx": {
+			{Name: "scph5501.bin", MD5: md5Hex("us bios")},
+			{Name: "scph5502.bin", MD5: md5Hex("eu bios")},
+		},
+		"segacd": {
+			{Name: "shared.bin", MD5: md5Hex("shared bios")},
+		},
+		"pcecd": {
+			{Name: "shared.bin", MD5: md5Hex("shared bios")},
+			{Name: "extra.bin", MD5: md5Hex("extra"), Optional: true},
+		},
+	}
+	bios := NewBIOSManager(database.Conn(), requirements)
+
+	result, err := bios.Scan(ctx, biosDir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.FilesScanned)
+	assert.Equal(t, 2, result.Matched)
+	assert.Equal(t, 1, result.Unknown)
+
+	// Files are matched by hash, not name
+	statuses, err := bios.Status(ctx, "psx")
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Present)
+	assert.Equal(t, filepath.Join(biosDir, "psx", "renamed.bin"), statuses[0].Path)
+	assert.False(t, statuses[1].Present)
+
+	ready, required, err := bios.Ready(ctx, "psx")
+	require.NoError(t, err)
+	assert.True(t, required)
+	assert.False(t, ready)
+
+	// One file is shared by every system that needs it
+	ready, _, err = bios.Ready(ctx, "segacd")
+	require.NoError(t, err)
+	assert.True(t, ready)
+	ready, _, err = bios.Ready(ctx, "pcecd")
+	require.NoError(t, err)
+	assert.True(t, ready, "missing optional BIOS does not block readiness")
+
+	_, required, err = bios.Ready(ctx, "nes")
+	require.NoError(t, err)
+	assert.False(t, required)
+
+	// Rescanning replaces previous results
+	require.NoError(t, os.Remove(filepath.Join(biosDir, "shared.bin")))
+	_, err = bios.Scan(ctx, biosDir)
+	require.NoError(t, err)
+	ready, _, err = bios.Ready(ctx, "segacd")
+	require.NoError(t, err)
+	assert.False(t, ready)
+}
@@ -1,11 +1,14 @@
 package library
 
 import (
+	"bytes"
+	"crypto/sha1" // #nosec G505
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 )
 
 // CHD header constants
@@ -14,6 +17,10 @@ const (
 	chdV4Header = 108
 	chdV5Header = 124
 	sha1Size    = 20
+
+	chdMetaEntrySize  = 16
+	chdMetaChecksum   = 0x01 // Metadata flag: included in the header's overall SHA1
+	chdMaxMetaEntries = 1024
 )
 
 // CHDInfo contains metadata extracted from a CHD file header.
@@ -25,6 +32,9 @@ type CHDInfo struct {
 	SHA1         string // SHA1 of compressed data
 	DataSHA1     string // SHA1 of decompressed data (most important for matching)
 	ParentSHA1   string // SHA1 of parent CHD (if delta file)
+	Compressed   bool   // Hunks are compressed
+	MapOffset    uint64 // Offset of the hunk map
+	MetaOffset   uint64 // Offset of the first metadata entry, 0 if none
 }
 
 // ParseCHD reads a CHD file header and extracts hash information.
@@ -87,9 +97,13 @@ func parseCHDv4(f *os.File, info *CHDInfo, headerLen uint32) (*CHDInfo, error) {
 		return nil, fmt.Errorf("failed to read v4 header: %w", err)
 	}
 
+	info.Compressed = binary.BigEndian.Uint32(header[20:24]) != 0
 	info.TotalHunks = binary.BigEndian.Uint32(header[24:28])
 	info.LogicalBytes = binary.BigEndian.Uint64(header[28:36])
+	info.MetaOffset = binary.BigEndian.Uint64(header[36:44])
 	info.HunkBytes = binary.BigEndian.Uint32(header[44:48])
+	// The v4 map follows the header
+	info.MapOffset = uint64(headerLen)
 
 	// Extract SHA1 hashes
 	info.SHA1 = hex.EncodeToString(header[48:68])
@@ -123,8 +137,14 @@ func parseCHDv5(f *os.File, info *CHDInfo, headerLen uint32) (*CHDInfo, error) {
 		return nil, fmt.Errorf("failed to read v5 header: %w", err)
 	}
 
+	info.Compressed = binary.BigEndian.Uint32(header[16:20]) != 0
 	info.LogicalBytes = binary.BigEndian.Uint64(header[32:40])
+	info.MapOffset = binary.BigEndian.Uint64(header[40:48])
+	info.MetaOffset = binary.BigEndian.Uint64(header[48:56])
 	info.HunkBytes = binary.BigEndian.Uint32(header[56:60])
+	if info.HunkBytes > 0 {
+		info.TotalHunks = uint32((info.LogicalBytes + uint64(info.HunkBytes) - 1) / uint64(info.HunkBytes))
+	}
 
 	// Extract SHA1 hashes
 	info.SHA1 = hex.EncodeToString(header[64:84])
@@ -134,6 +154,113 @@ func parseCHDv5(f *os.File, info *CHDInfo, headerLen uint32) (*CHDInfo, error) {
 	return info, nil
 }
 
+// VerifyCHD parses a CHD header like ParseCHD, then checks the file against
+// it: the hunk map and metadata the header points to must lie within the
+// file, and the header's overall SHA1 must match the one computed from its
+// data SHA1 and checksummed metadata, as chdman computes it. A truncated
+// file, or one chdman never finished writing, fails these checks.
+func VerifyCHD(path string) (*CHDInfo, error) {
+	info, err := ParseCHD(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CHD: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(stat.Size()) // #nosec G115
+
+	mapEnd, err := chdMapEnd(f, info, size)
+	if err != nil {
+		return nil, err
+	}
+	if mapEnd > size {
+		return nil, fmt.Errorf("truncated: hunk map ends at byte %d of a %d byte file", mapEnd, size)
+	}
+
+	overall, err := chdOverallSHA1(f, info, size)
+	if err != nil {
+		return nil, err
+	}
+	if overall != info.SHA1 {
+		return nil, fmt.Errorf("header SHA1 %s does not match its data and metadata (%s)", info.SHA1, overall)
+	}
+	return info, nil
+}
+
+// chdMapEnd returns the offset just past a CHD's hunk map.
+func chdMapEnd(f *os.File, info *CHDInfo, size uint64) (uint64, error) {
+	switch {
+	case info.Version == 4:
+		return info.MapOffset + uint64(info.TotalHunks)*16, nil
+	case !info.Compressed:
+		return info.MapOffset + uint64(info.TotalHunks)*4, nil
+	}
+
+	// A compressed v5 map starts with a 16 byte header holding its length
+	if info.MapOffset+16 > size {
+		return info.MapOffset + 16, nil
+	}
+	header := make([]byte, 16)
+	if _, err := f.ReadAt(header, int64(info.MapOffset)); err != nil { // #nosec G115
+		return 0, fmt.Errorf("failed to read hunk map: %w", err)
+	}
+	return info.MapOffset + 16 + uint64(binary.BigEndian.Uint32(header[0:4])), nil
+}
+
+// chdOverallSHA1 computes a CHD's overall SHA1: the SHA1 of its data SHA1
+// followed by the tag and SHA1 of each checksummed metadata entry, sorted.
+func chdOverallSHA1(f *os.File, info *CHDInfo, size uint64) (string, error) {
+	rawSHA1, err := hex.DecodeString(info.DataSHA1)
+	if err != nil {
+		return "", err
+	}
+
+	var hashes [][]byte
+	offset := info.MetaOffset
+	for n := 0; offset != 0; n++ {
+		if n == chdMaxMetaEntries {
+			return "", fmt.Errorf("metadata chain has more than %d entries", chdMaxMetaEntries)
+		}
+		if offset+chdMetaEntrySize > size {
+			return "", fmt.Errorf("truncated: metadata entry at byte %d of a %d byte file", offset, size)
+		}
+		entry := make([]byte, chdMetaEntrySize)
+		if _, err := f.ReadAt(entry, int64(offset)); err != nil { // #nosec G115
+			return "", fmt.Errorf("failed to read metadata: %w", err)
+		}
+		flags := entry[4]
+		length := uint64(binary.BigEndian.Uint32(entry[4:8]) & 0x00ffffff)
+		if offset+chdMetaEntrySize+length > size {
+			return "", fmt.Errorf("truncated: metadata entry at byte %d runs past the end of a %d byte file", offset, size)
+		}
+
+		if flags&chdMetaChecksum != 0 {
+			data := make([]byte, length)
+			if _, err := f.ReadAt(data, int64(offset+chdMetaEntrySize)); err != nil { // #nosec G115
+				return "", fmt.Errorf("failed to read metadata: %w", err)
+			}
+			sum := sha1.Sum(data) // #nosec G401
+			hashes = append(hashes, append(append([]byte{}, entry[0:4]...), sum[:]...))
+		}
+		offset = binary.BigEndian.Uint64(entry[8:16])
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i], hashes[j]) < 0 })
+
+	h := sha1.New() // #nosec G401
+	h.Write(rawSHA1)
+	for _, hash := range hashes {
+		h.Write(hash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // IsCHDFile checks if a file has a .chd extension.
 func IsCHDFile(path string) bool {
 	ext := getExtLower(path)
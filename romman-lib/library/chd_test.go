@@ -1,9 +1,15 @@
 package library
 
 import (
+	"crypto/sha1" // #nosec G505
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsCHDFile(t *testing.T) {
@@ -68,3 +74,70 @@ func TestParseCHD_NotFound(t *testing.T) {
 	_, err := ParseCHD("/nonexistent/file.chd")
 	assert.Error(t, err)
 }
+
+// writeTestCHD writes an uncompressed v5 CHD of one hunk, with one
+// checksummed metadata entry after its map and a correct overall SHA1.
+func writeTestCHD(t *testing.T, path string, dataSHA1 [20]byte) {
+	t.Helper()
+	meta := []byte("TRACK:1 TYPE:MODE1 SUBTYPE:NONE FRAMES:4")
+	metaOffset := uint64(chdV5Header + 4)
+
+	header := make([]byte, chdV5Header)
+	copy(header[0:8], chdMagic)
+	binary.BigEndian.PutUint32(header[8:12], chdV5Header)
+	binary.BigEndian.PutUint32(header[12:16], 5)
+	binary.BigEndian.PutUint64(header[32:40], 4096)
+	binary.BigEndian.PutUint64(header[40:48], chdV5Header)
+	binary.BigEndian.PutUint64(header[48:56], metaOffset)
+	binary.BigEndian.PutUint32(header[56:60], 4096)
+	binary.BigEndian.PutUint32(header[60:64], 2048)
+	copy(header[84:104], dataSHA1[:])
+
+	entry := make([]byte, chdMetaEntrySize)
+	copy(entry[0:4], "CHT2")
+	binary.BigEndian.PutUint32(entry[4:8], uint32(len(meta)))
+	entry[4] = chdMetaChecksum
+
+	metaSHA1 := sha1.Sum(meta) // #nosec G401
+	h := sha1.New()            // #nosec G401
+	h.Write(dataSHA1[:])
+	h.Write(entry[0:4])
+	h.Write(metaSHA1[:])
+	copy(header[64:84], h.Sum(nil))
+
+	var file []byte
+	file = append(file, header...)
+	file = append(file, make([]byte, 4)...) // Hunk map
+	file = append(file, entry...)
+	file = append(file, meta...)
+	require.NoError(t, os.WriteFile(path, file, 0644)) // #nosec G306
+}
+
+func TestVerifyCHD(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataSHA1 := sha1.Sum([]byte("disc data")) // #nosec G401
+
+	valid := filepath.Join(tmpDir, "valid.chd")
+	writeTestCHD(t, valid, dataSHA1)
+	info, err := VerifyCHD(valid)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(dataSHA1[:]), info.DataSHA1)
+	assert.Equal(t, uint32(1), info.TotalHunks)
+	assert.Equal(t, uint64(chdV5Header), info.MapOffset)
+
+	content, err := os.ReadFile(valid) // #nosec G304
+	require.NoError(t, err)
+
+	truncated := filepath.Join(tmpDir, "truncated.chd")
+	require.NoError(t, os.WriteFile(truncated, content[:len(content)-10], 0644)) // #nosec G306
+	_, err = VerifyCHD(truncated)
+	assert.ErrorContains(t, err, "truncated")
+
+	// chdman writes the header SHA1s last, so an unfinished file has none
+	unfinished := filepath.Join(tmpDir, "unfinished.chd")
+	zeroed := append([]byte{}, content...)
+	copy(zeroed[64:84], make([]byte, sha1Size))
+	require.NoError(t, os.WriteFile(unfinished, zeroed, 0644)) // #nosec G306
+	_, err = VerifyCHD(unfinished)
+	assert.ErrorContains(t, err, "does not match")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/ryanm101/romman-lib/tracing"
@@ -19,20 +20,39 @@ const (
 	ActionDelete ActionType = "delete"
 	ActionMove   ActionType = "move"
 	ActionIgnore ActionType = "ignore"
+	// ActionHardlink replaces a duplicate with a hardlink to its preferred
+	// copy, keeping the path while freeing its space.
+	ActionHardlink ActionType = "hardlink"
 )
 
 // CleanupAction represents a single file operation in a cleanup plan.
 type CleanupAction struct {
 	Action     ActionType `json:"action"`
 	SourcePath string     `json:"source_path"`
-	DestPath   string     `json:"dest_path,omitempty"` // For move actions
+	DestPath   string     `json:"dest_path,omitempty"`   // For move actions
+	LinkTarget string     `json:"link_target,omitempty"` // Preferred copy, for hardlink actions
 	Reason     string     `json:"reason"`
 	FileID     int64      `json:"file_id"`
 	DupType    string     `json:"duplicate_type"`
 	MatchType  string     `json:"match_type,omitempty"`
 	Flags      string     `json:"flags,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+
+	// Status and Error are written back by ExecutePlanWithOptions so an
+	// interrupted or partly failed cleanup can be resumed.
+	Status ActionStatus `json:"status,omitempty"`
+	Error  string       `json:"error,omitempty"`
 }
 
+// ActionStatus records whether a cleanup action has been executed.
+type ActionStatus string
+
+const (
+	ActionPending ActionStatus = "" // Not run yet
+	ActionDone    ActionStatus = "done"
+	ActionFailed  ActionStatus = "failed"
+)
+
 // CleanupPlan is a set of actions to clean up a library.
 type CleanupPlan struct {
 	LibraryName   string          `json:"library_name"`
@@ -50,7 +70,19 @@ type PlanSummary struct {
 	DeleteCount    int   `json:"delete_count"`
 	MoveCount      int   `json:"move_count"`
 	IgnoreCount    int   `json:"ignore_count"`
+	HardlinkCount  int   `json:"hardlink_count"`
 	SpaceReclaimed int64 `json:"space_reclaimed_bytes"`
+	HardlinkSaved  int64 `json:"hardlink_saved_bytes"` // Space freed by hardlinked duplicates
+}
+
+// PlanRollup totals a plan's actions that share an action, duplicate type
+// and reason.
+type PlanRollup struct {
+	Action  ActionType `json:"action"`
+	DupType string     `json:"duplicate_type,omitempty"`
+	Reason  string     `json:"reason"`
+	Count   int        `json:"count"`
+	Bytes   int64      `json:"bytes"`
 }
 
 // ExecutionResult is the result of executing a cleanup plan.
@@ -60,9 +92,29 @@ type ExecutionResult struct {
 	DryRun     bool          `json:"dry_run"`
 	Succeeded  int           `json:"succeeded"`
 	Failed     int           `json:"failed"`
+	Skipped    int           `json:"skipped,omitempty"` // Already done, when resuming
+	Pending    int           `json:"pending,omitempty"` // Not attempted, after stopping on an error
 	Errors     []ActionError `json:"errors,omitempty"`
 }
 
+// ExecuteOptions configures ExecutePlanWithOptions.
+type ExecuteOptions struct {
+	DryRun bool
+	// Resume skips actions already marked done, retrying failed and pending ones.
+	Resume bool
+	// StopOnError leaves the remaining actions pending after the first failure.
+	StopOnError bool
+	// PlanPath, if set, is rewritten with each action's status as execution
+	// proceeds, so the plan records what is left to do. Not used for dry runs.
+	PlanPath string
+	// ManifestPath, if set, is written with an undo manifest of the files
+	// moved to quarantine, for UndoCleanup. Not used for dry runs.
+	ManifestPath string
+}
+
+// planSaveInterval is how many actions run between plan write-backs.
+const planSaveInterval = 50
+
 // ActionError records a failed action.
 type ActionError struct {
 	Action CleanupAction `json:"action"`
@@ -73,6 +125,17 @@ type ActionError struct {
 type CleanupPlanner struct {
 	finder  *DuplicateFinder
 	manager *Manager
+
+	// Hardlink replaces exact duplicates with hardlinks to their preferred
+	// copy instead of quarantining them. Archives, and copies on another
+	// filesystem than the preferred one, cannot be linked: the former are
+	// still quarantined and the latter left alone.
+	Hardlink bool
+
+	// ChooseKeeper, if set, is asked for each duplicate group, the nth of
+	// total, which copy to keep. It returns the index of a file in dup.Files,
+	// or -1 to keep the one DuplicateFinder preferred. An error aborts the plan.
+	ChooseKeeper func(dup Duplicate, n, total int) (int, error)
 }
 
 // NewCleanupPlanner creates a new planner.
@@ -118,21 +181,42 @@ func (p *CleanupPlanner) GeneratePlan(ctx context.Context, libraryName string, q
 
 	// Track files we've already added to avoid duplicates
 	// A file may appear in multiple duplicate groups (exact, variant, package)
-	seenFiles := make(map[string]*CleanupAction)
+	seenFiles := make(map[string]int)
+
+	for n, dup := range duplicates {
+		if p.ChooseKeeper != nil {
+			keep, err := p.ChooseKeeper(dup, n+1, len(duplicates))
+			if err != nil {
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+			if keep >= 0 && keep < len(dup.Files) {
+				for i := range dup.Files {
+					dup.Files[i].IsPreferred = i == keep
+				}
+			}
+		}
 
-	for _, dup := range duplicates {
 		for _, file := range dup.Files {
 			// Check if we've already seen this file
-			if existing, ok := seenFiles[file.Path]; ok {
+			if idx, ok := seenFiles[file.Path]; ok {
 				// If we already have this file as ignore (keep), don't change it
 				// If we already have it as move but now it's preferred, upgrade to ignore
-				if file.IsPreferred && existing.Action == ActionMove {
+				existing := &plan.Actions[idx]
+				if file.IsPreferred && existing.Action == ActionMove && !hasTag(file.Tags, TagDelete) {
 					existing.Action = ActionIgnore
 					existing.Reason = "preferred copy"
 					existing.DestPath = ""
 					plan.Summary.MoveCount--
 					plan.Summary.IgnoreCount++
 				}
+				if file.IsPreferred && existing.Action == ActionHardlink {
+					existing.Action = ActionIgnore
+					existing.Reason = "preferred copy"
+					existing.LinkTarget = ""
+					plan.Summary.HardlinkCount--
+					plan.Summary.IgnoreCount++
+				}
 				continue
 			}
 
@@ -142,55 +226,185 @@ func (p *CleanupPlanner) GeneratePlan(ctx context.Context, libraryName string, q
 				DupType:    string(dup.Type),
 				MatchType:  file.MatchType,
 				Flags:      file.Flags,
+				Size:       file.Size,
 			}
 
-			if file.IsPreferred {
+			switch {
+			case hasTag(file.Tags, TagKeep):
+				// Never quarantine files the user tagged to keep
+				action.Action = ActionIgnore
+				action.Reason = "tagged keep"
+				plan.Summary.IgnoreCount++
+			case hasTag(file.Tags, TagDelete):
+				action.Action = ActionMove
+				action.DestPath = quarantinePath(lib.RootPath, quarantineDir, file.Path)
+				action.Reason = "tagged delete"
+				plan.Summary.MoveCount++
+			case file.IsPreferred:
 				// Keep preferred files
 				action.Action = ActionIgnore
 				action.Reason = "preferred copy"
 				plan.Summary.IgnoreCount++
-			} else {
+			case p.Hardlink && hardlinkTarget(dup, file) != nil:
+				planHardlink(&action, file, hardlinkTarget(dup, file))
+				if action.Action == ActionHardlink {
+					plan.Summary.HardlinkCount++
+				} else {
+					plan.Summary.IgnoreCount++
+				}
+			default:
 				// Move non-preferred to quarantine
 				action.Action = ActionMove
-				relPath, _ := filepath.Rel(lib.RootPath, file.Path)
-				action.DestPath = filepath.Join(quarantineDir, relPath)
+				action.DestPath = quarantinePath(lib.RootPath, quarantineDir, file.Path)
 				action.Reason = fmt.Sprintf("duplicate of preferred (%s)", dup.Type)
 				plan.Summary.MoveCount++
 			}
 
-			seenFiles[file.Path] = &action
+			seenFiles[file.Path] = len(plan.Actions)
 			plan.Actions = append(plan.Actions, action)
 		}
 	}
 
-	// Calculate space reclaimed from move actions
-	var totalSpace int64
+	// Files tagged for deletion are quarantined even if they have no duplicates
+	tagged, err := p.finder.findTaggedFiles(ctx, lib.ID, TagDelete)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	for _, file := range tagged {
+		if _, ok := seenFiles[file.Path]; ok || hasTag(file.Tags, TagKeep) {
+			continue
+		}
+		seenFiles[file.Path] = len(plan.Actions)
+		plan.Actions = append(plan.Actions, CleanupAction{
+			Action:     ActionMove,
+			FileID:     file.ScannedFileID,
+			SourcePath: file.Path,
+			DestPath:   quarantinePath(lib.RootPath, quarantineDir, file.Path),
+			Reason:     "tagged delete",
+			MatchType:  file.MatchType,
+			Flags:      file.Flags,
+			Size:       file.Size,
+		})
+		plan.Summary.MoveCount++
+	}
+
+	// Calculate space reclaimed from move actions and saved by hardlinks
+	var totalSpace, linkedSpace int64
 	for _, action := range plan.Actions {
-		if action.Action == ActionMove {
-			// Get file size from duplicates
-			for _, dup := range duplicates {
-				for _, file := range dup.Files {
-					if file.Path == action.SourcePath {
-						totalSpace += file.Size
-						break
-					}
-				}
-			}
+		switch action.Action {
+		case ActionMove:
+			totalSpace += action.Size
+		case ActionHardlink:
+			linkedSpace += action.Size
 		}
 	}
 
 	plan.Summary.TotalActions = len(plan.Actions)
 	plan.Summary.SpaceReclaimed = totalSpace
+	plan.Summary.HardlinkSaved = linkedSpace
 
 	tracing.AddSpanAttributes(span,
 		attribute.Int("result.total_actions", plan.Summary.TotalActions),
 		attribute.Int("result.move_count", plan.Summary.MoveCount),
 		attribute.Int64("result.space_reclaimed", plan.Summary.SpaceReclaimed),
+		attribute.Int("result.hardlink_count", plan.Summary.HardlinkCount),
 	)
 
 	return plan, nil
 }
 
+// RollupPlan groups a plan's actions by action, duplicate type and reason,
+// largest first. Plans saved before actions recorded their size total 0 bytes.
+func RollupPlan(plan *CleanupPlan) []PlanRollup {
+	type key struct {
+		action  ActionType
+		dupType string
+		reason  string
+	}
+	index := make(map[key]int)
+	var rollups []PlanRollup
+	for _, action := range plan.Actions {
+		k := key{action.Action, action.DupType, action.Reason}
+		i, ok := index[k]
+		if !ok {
+			i = len(rollups)
+			index[k] = i
+			rollups = append(rollups, PlanRollup{Action: k.action, DupType: k.dupType, Reason: k.reason})
+		}
+		rollups[i].Count++
+		rollups[i].Bytes += action.Size
+	}
+
+	sort.SliceStable(rollups, func(i, j int) bool {
+		if rollups[i].Bytes != rollups[j].Bytes {
+			return rollups[i].Bytes > rollups[j].Bytes
+		}
+		return rollups[i].Count > rollups[j].Count
+	})
+	return rollups
+}
+
+// hardlinkTarget returns the preferred copy an exact duplicate can be
+// hardlinked to, or nil if the pair cannot be linked. Only whole loose files
+// can share an inode, and a preferred copy tagged for deletion is moved away.
+func hardlinkTarget(dup Duplicate, file DuplicateFile) *DuplicateFile {
+	if dup.Type != DuplicateExact || file.ArchivePath != "" {
+		return nil
+	}
+	for i := range dup.Files {
+		target := &dup.Files[i]
+		if target.IsPreferred {
+			if target.ArchivePath != "" || target.Path == file.Path || hasTag(target.Tags, TagDelete) {
+				return nil
+			}
+			return target
+		}
+	}
+	return nil
+}
+
+// planHardlink makes action link file to its preferred copy, or leave it
+// alone if the two already share an inode or live on different filesystems.
+func planHardlink(action *CleanupAction, file DuplicateFile, target *DuplicateFile) {
+	action.Action = ActionIgnore
+	fileInfo, err := os.Stat(file.Path)
+	if err != nil {
+		action.Reason = "duplicate not found"
+		return
+	}
+	targetInfo, err := os.Stat(target.Path)
+	if err != nil {
+		action.Reason = "preferred copy not found"
+		return
+	}
+
+	switch {
+	case os.SameFile(fileInfo, targetInfo):
+		action.Reason = "already hardlinked to preferred copy"
+	case !sameDevice(fileInfo, targetInfo):
+		action.Reason = "preferred copy is on another filesystem"
+	default:
+		action.Action = ActionHardlink
+		action.LinkTarget = target.Path
+		action.Reason = "identical to preferred copy"
+	}
+}
+
+// sameDevice reports whether two files are on the same filesystem. Platforms
+// that cannot tell are assumed to be, leaving os.Link to refuse otherwise.
+func sameDevice(a, b os.FileInfo) bool {
+	devA, okA := fileDevice(a)
+	devB, okB := fileDevice(b)
+	return !okA || !okB || devA == devB
+}
+
+// quarantinePath returns where a library file is moved to in the quarantine directory.
+func quarantinePath(rootPath, quarantineDir, path string) string {
+	relPath, _ := filepath.Rel(rootPath, path)
+	return filepath.Join(quarantineDir, relPath)
+}
+
 // SavePlan saves a plan to a JSON file.
 func SavePlan(plan *CleanupPlan, path string) error {
 	data, err := json.MarshalIndent(plan, "", "  ")
@@ -198,8 +412,15 @@ func SavePlan(plan *CleanupPlan, path string) error {
 		return fmt.Errorf("failed to marshal plan: %w", err)
 	}
 
+	// Write a temporary file and rename it, so an interrupted write never
+	// leaves a truncated plan behind
+	tmp := path + ".tmp"
 	// #nosec G306
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
 		return fmt.Errorf("failed to write plan: %w", err)
 	}
 
@@ -223,44 +444,142 @@ func LoadPlan(path string) (*CleanupPlan, error) {
 
 // ExecutePlan executes a cleanup plan.
 func ExecutePlan(plan *CleanupPlan, dryRun bool) (*ExecutionResult, error) {
+	return ExecutePlanWithOptions(plan, ExecuteOptions{DryRun: dryRun})
+}
+
+// ExecutePlanWithOptions executes a cleanup plan, recording each action's
+// status in the plan. Failed actions are counted and, unless StopOnError is
+// set, execution continues with the next action.
+func ExecutePlanWithOptions(plan *CleanupPlan, opts ExecuteOptions) (*ExecutionResult, error) {
 	result := &ExecutionResult{
 		Plan:       plan,
 		ExecutedAt: time.Now(),
-		DryRun:     dryRun,
+		DryRun:     opts.DryRun,
+	}
+	writeBack := (opts.PlanPath != "" || opts.ManifestPath != "") && !opts.DryRun
+	save := func() error {
+		if opts.PlanPath != "" {
+			if err := SavePlan(plan, opts.PlanPath); err != nil {
+				return err
+			}
+		}
+		if opts.ManifestPath != "" {
+			return SaveUndoManifest(NewUndoManifest(plan, result.ExecutedAt), opts.ManifestPath)
+		}
+		return nil
 	}
 
-	for _, action := range plan.Actions {
-		if action.Action == ActionIgnore {
-			result.Succeeded++
+	for i := range plan.Actions {
+		action := &plan.Actions[i]
+		if opts.Resume && action.Status == ActionDone {
+			result.Skipped++
+			continue
+		}
+		if result.Failed > 0 && opts.StopOnError {
+			result.Pending++
 			continue
 		}
 
 		var err error
-		switch action.Action {
-		case ActionDelete:
-			if !dryRun {
-				err = os.Remove(action.SourcePath)
-			}
-		case ActionMove:
-			if !dryRun {
-				err = moveFile(action.SourcePath, action.DestPath)
-			}
+		if !opts.DryRun {
+			err = executeAction(*action, opts.Resume)
 		}
 
 		if err != nil {
 			result.Failed++
 			result.Errors = append(result.Errors, ActionError{
-				Action: action,
+				Action: *action,
 				Error:  err.Error(),
 			})
 		} else {
 			result.Succeeded++
 		}
+
+		if !opts.DryRun {
+			action.Status, action.Error = ActionDone, ""
+			if err != nil {
+				action.Status, action.Error = ActionFailed, err.Error()
+			}
+		}
+		if writeBack && (err != nil || (i+1)%planSaveInterval == 0) {
+			if err := save(); err != nil {
+				return result, err
+			}
+		}
 	}
 
+	if writeBack {
+		if err := save(); err != nil {
+			return result, err
+		}
+	}
 	return result, nil
 }
 
+// executeAction applies a single action. When resuming, an action whose
+// effect is already in place (the file is deleted, or moved to its
+// destination) ran before the plan was last saved and succeeds.
+func executeAction(action CleanupAction, resume bool) error {
+	switch action.Action {
+	case ActionDelete:
+		err := os.Remove(action.SourcePath)
+		if resume && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	case ActionMove:
+		if resume {
+			if _, err := os.Stat(action.SourcePath); os.IsNotExist(err) {
+				if _, err := os.Stat(action.DestPath); err == nil {
+					return nil
+				}
+			}
+		}
+		return moveFile(action.SourcePath, action.DestPath)
+	case ActionHardlink:
+		return hardlinkFile(action.SourcePath, action.LinkTarget)
+	}
+	return nil
+}
+
+// hardlinkFile replaces dup with a hardlink to target. The link is made
+// under a temporary name and renamed over dup, so dup is never missing; if
+// the files are on different filesystems or no longer the same size, dup is
+// left untouched. A dup already linked to target succeeds, which also makes
+// the action safe to resume.
+func hardlinkFile(dup, target string) error {
+	dupInfo, err := os.Stat(dup)
+	if err != nil {
+		return err
+	}
+	targetInfo, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("preferred copy: %w", err)
+	}
+	if os.SameFile(dupInfo, targetInfo) {
+		return nil
+	}
+	if !sameDevice(dupInfo, targetInfo) {
+		return fmt.Errorf("%s is on another filesystem than %s; left untouched", dup, target)
+	}
+	if dupInfo.Size() != targetInfo.Size() {
+		return fmt.Errorf("%s and %s are no longer the same size; left untouched", dup, target)
+	}
+
+	tmp := dup + ".romman-link"
+	_ = os.Remove(tmp)
+	if err := os.Link(target, tmp); err != nil {
+		return fmt.Errorf("failed to link: %w", err)
+	}
+	if err := os.Rename(tmp, dup); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace duplicate: %w", err)
+	}
+
+	syncDirs(dup, dup)
+	return nil
+}
+
 func moveFile(src, dst string) error {
 	// Ensure destination directory exists
 	// #nosec G301
@@ -270,10 +589,58 @@ func moveFile(src, dst string) error {
 
 	// Try rename first (fast, same filesystem)
 	if err := os.Rename(src, dst); err == nil {
+		syncDirs(src, dst)
 		return nil
 	}
 
 	// Fall back to copy + delete for cross-filesystem moves
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+
+	// Remove source
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove source: %w", err)
+	}
+
+	syncDirs(src, dst)
+	return nil
+}
+
+// linkFile hardlinks src at dst, creating dst's directory. Both must be on
+// the same filesystem. A dst already linked to src succeeds, so a rerun
+// skips what is done.
+func linkFile(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	// #nosec G301
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return nil
+	}
+	if dirInfo, err := os.Stat(filepath.Dir(dst)); err == nil && !sameDevice(srcInfo, dirInfo) {
+		return fmt.Errorf("%s is on another filesystem than %s; hardlinks need both on the same volume", filepath.Dir(dst), src)
+	}
+
+	if err := os.Link(src, dst); err != nil {
+		return fmt.Errorf("failed to link: %w", err)
+	}
+	syncDirs(dst, dst)
+	return nil
+}
+
+// copyFile copies src to dst, creating dst's directory, and syncs it to disk.
+// A partly written dst is removed on failure.
+func copyFile(src, dst string) (err error) {
+	// #nosec G301
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
 	srcFile, err := os.Open(src) // #nosec G304
 	if err != nil {
 		return fmt.Errorf("failed to open source: %w", err)
@@ -284,7 +651,14 @@ func moveFile(src, dst string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create destination: %w", err)
 	}
-	defer func() { _ = dstFile.Close() }()
+	defer func() {
+		if closeErr := dstFile.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close destination: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
 
 	if _, err := srcFile.WriteTo(dstFile); err != nil {
 		return fmt.Errorf("failed to copy: %w", err)
@@ -295,10 +669,20 @@ func moveFile(src, dst string) error {
 		return fmt.Errorf("failed to sync: %w", err)
 	}
 
-	// Remove source
-	if err := os.Remove(src); err != nil {
-		return fmt.Errorf("failed to remove source: %w", err)
-	}
-
+	syncDirs(src, dst)
 	return nil
 }
+
+// syncDirs flushes the directory entries of a move's source and destination
+// so the move survives a crash. Not every platform can sync a directory, so
+// failures are ignored.
+func syncDirs(src, dst string) {
+	for _, dir := range []string{filepath.Dir(dst), filepath.Dir(src)} {
+		d, err := os.Open(dir) // #nosec G304
+		if err != nil {
+			continue
+		}
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
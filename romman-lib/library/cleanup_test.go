@@ -1,6 +1,7 @@
 package library
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -110,4 +111,236 @@ func TestActionTypes(t *testing.T) {
 	assert.Equal(t, ActionType("delete"), ActionDelete)
 	assert.Equal(t, ActionType("move"), ActionMove)
 	assert.Equal(t, ActionType("ignore"), ActionIgnore)
+	assert.Equal(t, ActionType("hardlink"), ActionHardlink)
+}
+
+func TestExecutePlanWithOptions_ResumeAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	quarantine := filepath.Join(dir, "quarantine")
+	planPath := filepath.Join(dir, "plan.json")
+	for _, name := range []string{"a.rom", "b.rom", "c.rom"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)) // #nosec G306
+	}
+
+	// b.rom's destination is blocked by a file where its directory should be
+	require.NoError(t, os.MkdirAll(quarantine, 0755))                                         // #nosec G301
+	require.NoError(t, os.WriteFile(filepath.Join(quarantine, "blocked"), []byte("x"), 0644)) // #nosec G306
+
+	plan := &CleanupPlan{
+		Actions: []CleanupAction{
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "a.rom"), DestPath: filepath.Join(quarantine, "a.rom")},
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "b.rom"), DestPath: filepath.Join(quarantine, "blocked", "b.rom")},
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "c.rom"), DestPath: filepath.Join(quarantine, "c.rom")},
+		},
+	}
+
+	result, err := ExecutePlanWithOptions(plan, ExecuteOptions{StopOnError: true, PlanPath: planPath})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.Pending)
+	assert.FileExists(t, filepath.Join(dir, "c.rom"), "stop-on-error leaves later actions alone")
+
+	// The saved plan records what happened
+	saved, err := LoadPlan(planPath)
+	require.NoError(t, err)
+	assert.Equal(t, ActionDone, saved.Actions[0].Status)
+	assert.Equal(t, ActionFailed, saved.Actions[1].Status)
+	assert.NotEmpty(t, saved.Actions[1].Error)
+	assert.Equal(t, ActionPending, saved.Actions[2].Status)
+
+	// Fix the problem and resume: only the failed and pending actions run
+	require.NoError(t, os.Remove(filepath.Join(quarantine, "blocked")))
+	result, err = ExecutePlanWithOptions(saved, ExecuteOptions{Resume: true, PlanPath: planPath})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 0, result.Failed)
+	assert.FileExists(t, filepath.Join(quarantine, "blocked", "b.rom"))
+	assert.FileExists(t, filepath.Join(quarantine, "c.rom"))
+
+	saved, err = LoadPlan(planPath)
+	require.NoError(t, err)
+	for _, a := range saved.Actions {
+		assert.Equal(t, ActionDone, a.Status)
+		assert.Empty(t, a.Error)
+	}
+}
+
+func TestExecutePlanWithOptions_ResumeAlreadyApplied(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "q", "a.rom")
+	require.NoError(t, os.MkdirAll(filepath.Dir(dest), 0755)) // #nosec G301
+	require.NoError(t, os.WriteFile(dest, []byte("a"), 0644)) // #nosec G306
+
+	// Moved and deleted before the plan was saved, so still pending
+	plan := &CleanupPlan{
+		Actions: []CleanupAction{
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "a.rom"), DestPath: dest},
+			{Action: ActionDelete, SourcePath: filepath.Join(dir, "b.rom")},
+		},
+	}
+
+	result, err := ExecutePlanWithOptions(plan, ExecuteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Failed)
+
+	result, err = ExecutePlanWithOptions(plan, ExecuteOptions{Resume: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 0, result.Failed)
+}
+
+func TestCleanupPlan_Hardlink(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 2)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	// A copy of game00 in a deeper directory loses to the original; a copy of
+	// game01 is already a hardlink to it
+	original := filepath.Join(libPath, "game00.nes")
+	data, err := os.ReadFile(original) // #nosec G304
+	require.NoError(t, err)
+	copyDir := filepath.Join(libPath, "copies", "nes")
+	require.NoError(t, os.MkdirAll(copyDir, 0755)) // #nosec G301
+	copyPath := filepath.Join(copyDir, "game00.nes")
+	require.NoError(t, os.WriteFile(copyPath, data, 0644)) // #nosec G306
+	linkedPath := filepath.Join(copyDir, "game01.nes")
+	require.NoError(t, os.Link(filepath.Join(libPath, "game01.nes"), linkedPath))
+
+	_, err = NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	planner := NewCleanupPlanner(NewDuplicateFinder(conn), NewManager(conn))
+	planner.Hardlink = true
+	plan, err := planner.GeneratePlan(ctx, "test-lib", filepath.Join(t.TempDir(), "quarantine"))
+	require.NoError(t, err)
+
+	actions := make(map[string]CleanupAction)
+	for _, a := range plan.Actions {
+		actions[a.SourcePath] = a
+	}
+	assert.Equal(t, ActionHardlink, actions[copyPath].Action)
+	assert.Equal(t, original, actions[copyPath].LinkTarget)
+	assert.Equal(t, ActionIgnore, actions[original].Action)
+	assert.Equal(t, ActionIgnore, actions[linkedPath].Action)
+	assert.Equal(t, "already hardlinked to preferred copy", actions[linkedPath].Reason)
+	assert.Equal(t, 1, plan.Summary.HardlinkCount)
+	assert.Equal(t, 0, plan.Summary.MoveCount)
+	assert.Equal(t, int64(len(data)), plan.Summary.HardlinkSaved)
+	assert.Equal(t, plan.Summary.HardlinkCount+plan.Summary.IgnoreCount, plan.Summary.TotalActions)
+
+	result, err := ExecutePlan(plan, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Failed)
+
+	originalInfo, err := os.Stat(original)
+	require.NoError(t, err)
+	copyInfo, err := os.Stat(copyPath)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(originalInfo, copyInfo), "copy should now share the original's inode")
+}
+
+func TestExecutePlan_HardlinkLeavesChangedFilesAlone(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "a.rom")
+	dup := filepath.Join(dir, "b.rom")
+	require.NoError(t, os.WriteFile(target, []byte("same"), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(dup, []byte("changed"), 0644)) // #nosec G306
+
+	plan := &CleanupPlan{
+		Actions: []CleanupAction{
+			{Action: ActionHardlink, SourcePath: dup, LinkTarget: target},
+			{Action: ActionHardlink, SourcePath: filepath.Join(dir, "missing.rom"), LinkTarget: target},
+		},
+	}
+	result, err := ExecutePlan(plan, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Failed)
+
+	data, err := os.ReadFile(dup) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(data))
+	_, err = os.Stat(dup + ".romman-link")
+	assert.True(t, os.IsNotExist(err))
+
+	// Once linked, running the action again succeeds without changing anything
+	require.NoError(t, os.WriteFile(dup, []byte("same"), 0644)) // #nosec G306
+	plan.Actions = plan.Actions[:1]
+	for i := 0; i < 2; i++ {
+		result, err = ExecutePlan(plan, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Succeeded)
+	}
+	targetInfo, err := os.Stat(target)
+	require.NoError(t, err)
+	dupInfo, err := os.Stat(dup)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(targetInfo, dupInfo))
+}
+
+func TestCleanupPlan_ChooseKeeper(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 2)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	// The copy in a deeper directory would normally lose to the original
+	original := filepath.Join(libPath, "game00.nes")
+	data, err := os.ReadFile(original) // #nosec G304
+	require.NoError(t, err)
+	copyDir := filepath.Join(libPath, "regions", "europe")
+	require.NoError(t, os.MkdirAll(copyDir, 0755)) // #nosec G301
+	copyPath := filepath.Join(copyDir, "game00.nes")
+	require.NoError(t, os.WriteFile(copyPath, data, 0644)) // #nosec G306
+
+	_, err = NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	planner := NewCleanupPlanner(NewDuplicateFinder(conn), NewManager(conn))
+	var asked int
+	planner.ChooseKeeper = func(dup Duplicate, n, total int) (int, error) {
+		asked++
+		assert.Equal(t, asked, n)
+		for i, f := range dup.Files {
+			if f.Path == copyPath {
+				assert.False(t, f.IsPreferred, "finder should prefer the original")
+				return i, nil
+			}
+		}
+		return -1, nil
+	}
+	plan, err := planner.GeneratePlan(ctx, "test-lib", filepath.Join(t.TempDir(), "quarantine"))
+	require.NoError(t, err)
+	assert.Positive(t, asked)
+
+	actions := make(map[string]CleanupAction)
+	for _, a := range plan.Actions {
+		actions[a.SourcePath] = a
+	}
+	assert.Equal(t, ActionIgnore, actions[copyPath].Action)
+	assert.Equal(t, "preferred copy", actions[copyPath].Reason)
+	assert.Equal(t, ActionMove, actions[original].Action)
+
+	planner.ChooseKeeper = func(Duplicate, int, int) (int, error) { return 0, context.Canceled }
+	_, err = planner.GeneratePlan(ctx, "test-lib", filepath.Join(t.TempDir(), "quarantine"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRollupPlan(t *testing.T) {
+	plan := &CleanupPlan{
+		Actions: []CleanupAction{
+			{Action: ActionIgnore, DupType: "exact", Reason: "preferred copy", Size: 100},
+			{Action: ActionMove, DupType: "exact", Reason: "duplicate of preferred (exact)", Size: 100},
+			{Action: ActionMove, DupType: "exact", Reason: "duplicate of preferred (exact)", Size: 200},
+			{Action: ActionMove, DupType: "variant", Reason: "duplicate of preferred (variant)", Size: 50},
+			{Action: ActionMove, Reason: "tagged delete", Size: 50},
+		},
+	}
+
+	assert.Equal(t, []PlanRollup{
+		{Action: ActionMove, DupType: "exact", Reason: "duplicate of preferred (exact)", Count: 2, Bytes: 300},
+		{Action: ActionIgnore, DupType: "exact", Reason: "preferred copy", Count: 1, Bytes: 100},
+		{Action: ActionMove, DupType: "variant", Reason: "duplicate of preferred (variant)", Count: 1, Bytes: 50},
+		{Action: ActionMove, Reason: "tagged delete", Count: 1, Bytes: 50},
+	}, RollupPlan(plan))
 }
@@ -0,0 +1,132 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// UndoManifest records the files a cleanup moved to quarantine, so the
+// cleanup can be reversed. Deletes and hardlinks are not recorded; a deleted
+// file is gone and a hardlinked one is still at its path.
+type UndoManifest struct {
+	LibraryName   string     `json:"library_name"`
+	PlanCreatedAt time.Time  `json:"plan_created_at"`
+	ExecutedAt    time.Time  `json:"executed_at"`
+	Moves         []UndoMove `json:"moves"`
+}
+
+// UndoMove is a file moved from OriginalPath to QuarantinePath.
+type UndoMove struct {
+	OriginalPath   string `json:"original_path"`
+	QuarantinePath string `json:"quarantine_path"`
+}
+
+// UndoResult contains the result of undoing a cleanup.
+type UndoResult struct {
+	Manifest        *UndoManifest `json:"manifest"`
+	DryRun          bool          `json:"dry_run"`
+	Restored        int           `json:"restored"`
+	AlreadyRestored int           `json:"already_restored,omitempty"` // Back in place from an earlier undo
+	Conflicts       []UndoMove    `json:"conflicts,omitempty"`        // Original path taken again; left in quarantine
+	Errors          []UndoError   `json:"errors,omitempty"`
+}
+
+// UndoError records a move that could not be reversed.
+type UndoError struct {
+	Move  UndoMove `json:"move"`
+	Error string   `json:"error"`
+}
+
+// NewUndoManifest builds the undo manifest of a plan's completed moves.
+func NewUndoManifest(plan *CleanupPlan, executedAt time.Time) *UndoManifest {
+	manifest := &UndoManifest{
+		LibraryName:   plan.LibraryName,
+		PlanCreatedAt: plan.CreatedAt,
+		ExecutedAt:    executedAt,
+		Moves:         []UndoMove{},
+	}
+	for _, action := range plan.Actions {
+		if action.Action == ActionMove && action.Status == ActionDone {
+			manifest.Moves = append(manifest.Moves, UndoMove{
+				OriginalPath:   action.SourcePath,
+				QuarantinePath: action.DestPath,
+			})
+		}
+	}
+	return manifest
+}
+
+// SaveUndoManifest saves an undo manifest to a JSON file.
+func SaveUndoManifest(manifest *UndoManifest, path string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal undo manifest: %w", err)
+	}
+
+	// Written like SavePlan, so an interrupted write keeps the previous manifest
+	tmp := path + ".tmp"
+	// #nosec G306
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write undo manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write undo manifest: %w", err)
+	}
+
+	return nil
+}
+
+// LoadUndoManifest loads an undo manifest from a JSON file.
+func LoadUndoManifest(path string) (*UndoManifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read undo manifest: %w", err)
+	}
+
+	var manifest UndoManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse undo manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// UndoCleanup moves each file in the manifest from quarantine back to its
+// original path. Files whose original path is taken again are left in
+// quarantine and reported as conflicts, and files already back in place are
+// counted, so an undo can be rerun.
+func UndoCleanup(manifest *UndoManifest, dryRun bool) *UndoResult {
+	result := &UndoResult{Manifest: manifest, DryRun: dryRun}
+
+	// In reverse, so the last move into a path is the first undone
+	for i := len(manifest.Moves) - 1; i >= 0; i-- {
+		move := manifest.Moves[i]
+		_, origErr := os.Lstat(move.OriginalPath)
+		_, quarErr := os.Lstat(move.QuarantinePath)
+
+		switch {
+		case os.IsNotExist(quarErr) && origErr == nil:
+			result.AlreadyRestored++
+			continue
+		case quarErr != nil:
+			result.Errors = append(result.Errors, UndoError{Move: move, Error: fmt.Sprintf("not in quarantine: %v", quarErr)})
+			continue
+		case origErr == nil:
+			result.Conflicts = append(result.Conflicts, move)
+			continue
+		}
+
+		if !dryRun {
+			if err := moveFile(move.QuarantinePath, move.OriginalPath); err != nil {
+				result.Errors = append(result.Errors, UndoError{Move: move, Error: err.Error()})
+				continue
+			}
+		}
+		result.Restored++
+	}
+
+	return result
+}
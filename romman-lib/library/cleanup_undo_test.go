@@ -0,0 +1,76 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoCleanup(t *testing.T) {
+	dir := t.TempDir()
+	quarantine := filepath.Join(dir, "quarantine")
+	manifestPath := filepath.Join(dir, "plan.undo.json")
+	for _, name := range []string{"a.rom", "b.rom", "c.rom", "d.rom"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)) // #nosec G306
+	}
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	plan := &CleanupPlan{
+		LibraryName: "nes",
+		CreatedAt:   created,
+		Actions: []CleanupAction{
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "a.rom"), DestPath: filepath.Join(quarantine, "a.rom")},
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "b.rom"), DestPath: filepath.Join(quarantine, "b.rom")},
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "c.rom"), DestPath: filepath.Join(quarantine, "c.rom")},
+			{Action: ActionMove, SourcePath: filepath.Join(dir, "missing.rom"), DestPath: filepath.Join(quarantine, "missing.rom")},
+			{Action: ActionDelete, SourcePath: filepath.Join(dir, "d.rom")},
+		},
+	}
+
+	// Dry runs leave no manifest
+	_, err := ExecutePlanWithOptions(plan, ExecuteOptions{DryRun: true, ManifestPath: manifestPath})
+	require.NoError(t, err)
+	assert.NoFileExists(t, manifestPath)
+
+	_, err = ExecutePlanWithOptions(plan, ExecuteOptions{ManifestPath: manifestPath})
+	require.NoError(t, err)
+
+	// Only the moves that happened are recorded
+	manifest, err := LoadUndoManifest(manifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, "nes", manifest.LibraryName)
+	assert.True(t, created.Equal(manifest.PlanCreatedAt))
+	require.Len(t, manifest.Moves, 3)
+	assert.Equal(t, UndoMove{OriginalPath: filepath.Join(dir, "a.rom"), QuarantinePath: filepath.Join(quarantine, "a.rom")}, manifest.Moves[0])
+
+	// b.rom's path has been taken again, and c.rom is gone from quarantine
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.rom"), []byte("new"), 0644)) // #nosec G306
+	require.NoError(t, os.Remove(filepath.Join(quarantine, "c.rom")))
+
+	result := UndoCleanup(manifest, true)
+	assert.Equal(t, 1, result.Restored)
+	assert.FileExists(t, filepath.Join(quarantine, "a.rom"), "dry run leaves files in quarantine")
+
+	result = UndoCleanup(manifest, false)
+	assert.Equal(t, 1, result.Restored)
+	assert.Equal(t, []UndoMove{manifest.Moves[1]}, result.Conflicts)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, manifest.Moves[2], result.Errors[0].Move)
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.rom")) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, "a.rom", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "b.rom")) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data), "conflicting files are not overwritten")
+	assert.FileExists(t, filepath.Join(quarantine, "b.rom"))
+
+	// Rerunning skips what is back in place
+	result = UndoCleanup(manifest, false)
+	assert.Equal(t, 0, result.Restored)
+	assert.Equal(t, 1, result.AlreadyRestored)
+}
@@ -0,0 +1,89 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// SystemCompletion holds per-system collection completeness across all libraries.
+type SystemCompletion struct {
+	SystemID        int64   `json:"system_id"`
+	Name            string  `json:"name"`
+	DATName         string  `json:"dat_name"`
+	TotalReleases   int     `json:"total_releases"`
+	MatchedReleases int     `json:"matched_releases"`
+	Completion      float64 `json:"completion"` // Percentage, 0-100
+}
+
+// IsComplete reports whether every release in the system has been matched.
+func (c SystemCompletion) IsComplete() bool {
+	return c.TotalReleases > 0 && c.MatchedReleases >= c.TotalReleases
+}
+
+// GetSystemCompletion returns the number of releases and matched releases for each system,
+// counting a release as matched if any library holds a file matching one of its ROMs.
+func GetSystemCompletion(ctx context.Context, db *sql.DB) ([]SystemCompletion, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetSystemCompletion")
+	defer span.End()
+
+	results, err := queryCompletion(ctx, db, 0, 0)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return results, err
+}
+
+// GetLibraryCompletion returns the completion of a library's system counting
+// only the files of that library, as the stats report shows it.
+func GetLibraryCompletion(ctx context.Context, db *sql.DB, lib *Library) (SystemCompletion, error) {
+	results, err := queryCompletion(ctx, db, lib.SystemID, lib.ID)
+	if err != nil {
+		return SystemCompletion{}, err
+	}
+	if len(results) == 0 {
+		return SystemCompletion{}, NotFoundError("system", lib.SystemName)
+	}
+	return results[0], nil
+}
+
+// queryCompletion counts the releases and matched releases of one system, or
+// of every system when systemID is 0. With a libraryID only that library's
+// files count as matches.
+func queryCompletion(ctx context.Context, db *sql.DB, systemID, libraryID int64) ([]SystemCompletion, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			s.id,
+			s.name,
+			COALESCE(s.dat_name, ''),
+			(SELECT COUNT(*) FROM releases r WHERE r.system_id = s.id) as total,
+			(SELECT COUNT(DISTINCT re.release_id)
+				FROM matches m
+				JOIN scanned_files sf ON sf.id = m.scanned_file_id
+				JOIN rom_entries re ON re.id = m.rom_entry_id
+				JOIN releases r ON r.id = re.release_id
+				WHERE r.system_id = s.id AND (? = 0 OR sf.library_id = ?)) as matched
+		FROM systems s
+		WHERE ? = 0 OR s.id = ?
+		ORDER BY s.name
+	`, libraryID, libraryID, systemID, systemID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []SystemCompletion
+	for rows.Next() {
+		var c SystemCompletion
+		if err := rows.Scan(&c.SystemID, &c.Name, &c.DATName, &c.TotalReleases, &c.MatchedReleases); err != nil {
+			return nil, err
+		}
+		if c.TotalReleases > 0 {
+			c.Completion = float64(c.MatchedReleases) / float64(c.TotalReleases) * 100
+		}
+		results = append(results, c)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,53 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestGetSystemCompletion(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	conn := database.Conn()
+	stmts := []string{
+		`INSERT INTO systems (id, name, dat_name) VALUES (1, 'nes', 'Nintendo - NES'), (2, 'snes', 'Nintendo - SNES')`,
+		`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game A'), (2, 1, 'Game B'), (3, 2, 'Game C')`,
+		`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES (1, 1, 'a.nes', 'aa'), (2, 2, 'b.nes', 'bb'), (3, 3, 'c.sfc', 'cc')`,
+		`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'nes-lib', '/nes', 1), (2, 'snes-lib', '/snes', 2)`,
+		`INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1) VALUES (1, 1, '/nes/a.nes', 1, 0, 'aa'), (2, 2, '/snes/c.sfc', 1, 0, 'cc')`,
+		`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (2, 3, 'sha1')`,
+	}
+	for _, s := range stmts {
+		_, err := conn.Exec(s)
+		require.NoError(t, err)
+	}
+
+	results, err := GetSystemCompletion(context.Background(), conn)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "nes", results[0].Name)
+	assert.Equal(t, 2, results[0].TotalReleases)
+	assert.Equal(t, 1, results[0].MatchedReleases)
+	assert.InDelta(t, 50.0, results[0].Completion, 0.01)
+	assert.False(t, results[0].IsComplete())
+
+	assert.Equal(t, "snes", results[1].Name)
+	assert.InDelta(t, 100.0, results[1].Completion, 0.01)
+	assert.True(t, results[1].IsComplete())
+
+	// A library counts only its own files, against its system's releases
+	lib, err := NewManager(conn).Get(context.Background(), "nes-lib")
+	require.NoError(t, err)
+	c, err := GetLibraryCompletion(context.Background(), conn, lib)
+	require.NoError(t, err)
+	assert.Equal(t, results[0], c)
+}
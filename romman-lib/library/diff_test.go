@@ -0,0 +1,59 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Diff(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 3)
+	ctx := context.Background()
+	conn := database.Conn()
+	manager := NewManager(conn)
+
+	// The second drive has Game 00 and Game 03, which the first lacks
+	otherPath := filepath.Join(t.TempDir(), "roms")
+	require.NoError(t, os.MkdirAll(otherPath, 0755)) // #nosec G301
+
+	content, err := os.ReadFile(filepath.Join(libPath, "game00.nes")) // #nosec G304
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(otherPath, "game00.nes"), content, 0644)) // #nosec G306
+	writeCheckpointROM(t, database, otherPath, 3)
+	_, err = manager.Add(ctx, "other-lib", otherPath, "nes")
+	require.NoError(t, err)
+
+	scanner := NewScanner(conn)
+	for _, name := range []string{"test-lib", "other-lib"} {
+		_, err := scanner.Scan(ctx, name)
+		require.NoError(t, err)
+	}
+
+	diff, err := manager.Diff(ctx, "test-lib", "other-lib", DiffOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "nes", diff.System)
+	assert.Equal(t, []DiffRelease{{ID: 2, Name: "Game 01 (USA)"}, {ID: 3, Name: "Game 02 (USA)"}}, diff.OnlyInA)
+	assert.Equal(t, []DiffRelease{{ID: 4, Name: "Game 03 (USA)"}}, diff.OnlyInB)
+	assert.Equal(t, 1, diff.InBoth)
+
+	_, err = conn.Exec(`UPDATE releases SET is_preferred = 1 WHERE id IN (1, 4)`)
+	require.NoError(t, err)
+	diff, err = manager.Diff(ctx, "test-lib", "other-lib", DiffOptions{PreferredOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, diff.OnlyInA)
+	assert.Equal(t, []DiffRelease{{ID: 4, Name: "Game 03 (USA)"}}, diff.OnlyInB)
+	assert.Equal(t, 1, diff.InBoth)
+
+	// Libraries of different systems cannot be compared
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (2, 'snes')`)
+	require.NoError(t, err)
+	_, err = manager.Add(ctx, "snes-lib", t.TempDir(), "snes")
+	require.NoError(t, err)
+	_, err = manager.Diff(ctx, "test-lib", "snes-lib", DiffOptions{})
+	assert.True(t, errors.Is(err, ErrInvalidArg))
+}
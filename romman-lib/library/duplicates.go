@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/ryanm101/romman-lib/tracing"
 	"go.opentelemetry.io/otel/attribute"
@@ -35,19 +36,78 @@ type DuplicateFile struct {
 	Size          int64
 	SHA1          string
 	CRC32         string
-	MatchType     string // sha1, crc32, name, name_modified
+	MatchType     string // sha1, crc32, name, name_modified, name_fuzzy
 	Flags         string // bad-dump, cracked, etc.
-	IsPreferred   bool   // Based on match quality
+	Tags          string // User tags, comma-separated (keep, delete, ...)
+	ArchivePath   string // Entry within the archive at Path, empty for loose files
+	IsPreferred   bool   // Based on match quality and tags
+}
+
+// CopyScoring weighs the copies in a duplicate group to pick the one to keep.
+// User tags always take precedence over these weights.
+type CopyScoring struct {
+	MatchScores map[string]int // Points per match type (sha1, crc32, name, name_modified)
+	FlagPenalty int            // Points subtracted for problem flags such as bad-dump
+
+	// PreferArchive, when set, favours archive entries (true) or loose files
+	// (false) by ArchiveBonus points. Nil has no preference.
+	PreferArchive *bool
+	ArchiveBonus  int
+
+	// PreferredPathPrefix gives PathPrefixBonus points to copies under it.
+	PreferredPathPrefix string
+	PathPrefixBonus     int
+
+	// PathDepthWeight is subtracted per 10 characters of the directory path,
+	// favouring shallower (likely better organised) copies. 0 disables it.
+	PathDepthWeight int
+}
+
+// DefaultCopyScoring returns the built-in weights: sha256 = sha1 > md5 > crc32 >
+// name > name_modified > name_fuzzy, a small flag penalty and a preference for shorter paths.
+func DefaultCopyScoring() CopyScoring {
+	return CopyScoring{
+		MatchScores: map[string]int{
+			"sha256":        100,
+			"sha1":          100,
+			"md5":           90,
+			"crc32":         80,
+			"name":          50,
+			"name_modified": 20,
+			"name_fuzzy":    10,
+		},
+		FlagPenalty:     10,
+		ArchiveBonus:    15,
+		PathPrefixBonus: 50,
+		PathDepthWeight: 1,
+	}
+}
+
+// DuplicateProgress reports how far a duplicate search has got.
+type DuplicateProgress struct {
+	Phase           DuplicateType // Pass currently running
+	GroupsProcessed int           // Candidate groups checked in this pass
+	GroupsTotal     int           // Candidate groups in this pass
 }
 
 // DuplicateFinder finds duplicates in a library.
 type DuplicateFinder struct {
 	db *sql.DB
+
+	// Scoring picks the preferred copy in each group.
+	Scoring CopyScoring
+
+	// OnProgress, if set, is called after each candidate group is checked.
+	OnProgress func(DuplicateProgress)
 }
 
+// hashBatchSize bounds the number of hashes per query, keeping well under
+// SQLite's bound parameter limit.
+const hashBatchSize = 500
+
 // NewDuplicateFinder creates a new duplicate finder.
 func NewDuplicateFinder(db *sql.DB) *DuplicateFinder {
-	return &DuplicateFinder{db: db}
+	return &DuplicateFinder{db: db, Scoring: DefaultCopyScoring()}
 }
 
 // FindExactDuplicates finds files with identical SHA1 hashes.
@@ -56,7 +116,7 @@ func (d *DuplicateFinder) FindExactDuplicates(ctx context.Context, libraryID int
 	rows, err := d.db.QueryContext(ctx, `
 		SELECT sha1, COUNT(*) as cnt
 		FROM scanned_files
-		WHERE library_id = ? AND sha1 IS NOT NULL AND sha1 != ''
+		WHERE library_id = ? AND sha1 IS NOT NULL AND sha1 != '' AND virtual = 0
 		GROUP BY sha1
 		HAVING cnt > 1
 	`, libraryID)
@@ -75,19 +135,33 @@ func (d *DuplicateFinder) FindExactDuplicates(ctx context.Context, libraryID int
 		hashes = append(hashes, hash)
 	}
 
-	// Get file details for each duplicate hash
+	// Get file details for the duplicate hashes a batch at a time
 	var duplicates []Duplicate
-	for _, hash := range hashes {
-		files, err := d.getFilesForHash(ctx, libraryID, hash)
+	for start := 0; start < len(hashes); start += hashBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := start + hashBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		filesByHash, err := d.getFilesForHashes(ctx, libraryID, hashes[start:end])
 		if err != nil {
 			return nil, err
 		}
-		if len(files) > 1 {
-			duplicates = append(duplicates, Duplicate{
-				Type:  DuplicateExact,
-				Hash:  hash,
-				Files: files,
-			})
+
+		for i, hash := range hashes[start:end] {
+			files := filesByHash[hash]
+			if len(files) > 1 {
+				d.Scoring.markPreferred(files)
+				duplicates = append(duplicates, Duplicate{
+					Type:  DuplicateExact,
+					Hash:  hash,
+					Files: files,
+				})
+			}
+			d.reportProgress(DuplicateExact, start+i+1, len(hashes))
 		}
 	}
 
@@ -99,13 +173,13 @@ func (d *DuplicateFinder) FindVariantDuplicates(ctx context.Context, libraryID i
 	// Get all matched files with their release names (base title only)
 	rows, err := d.db.QueryContext(ctx, `
 		SELECT sf.id, sf.path, sf.size, sf.sha1, sf.crc32, 
-		       m.match_type, COALESCE(m.flags, ''),
-		       r.name
+		       m.match_type, COALESCE(m.flags, ''), `+fileTagsColumn+`,
+		       COALESCE(sf.archive_path, ''), r.name
 		FROM scanned_files sf
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
-		WHERE sf.library_id = ?
+		WHERE sf.library_id = ? AND sf.virtual = 0 AND COALESCE(m.flags, '') != '`+splitFlag+`'
 		ORDER BY r.name
 	`, libraryID)
 	if err != nil {
@@ -116,12 +190,16 @@ func (d *DuplicateFinder) FindVariantDuplicates(ctx context.Context, libraryID i
 	// Group by normalized base title
 	titleGroups := make(map[string][]DuplicateFile)
 	titleToRelease := make(map[string]string) // Store one release name per group
+	var titleOrder []string                   // Groups in release name order
 
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		var file DuplicateFile
 		var releaseName string
 		if err := rows.Scan(&file.ScannedFileID, &file.Path, &file.Size,
-			&file.SHA1, &file.CRC32, &file.MatchType, &file.Flags, &releaseName); err != nil {
+			&file.SHA1, &file.CRC32, &file.MatchType, &file.Flags, &file.Tags, &file.ArchivePath, &releaseName); err != nil {
 			return nil, err
 		}
 
@@ -130,15 +208,22 @@ func (d *DuplicateFinder) FindVariantDuplicates(ctx context.Context, libraryID i
 		titleGroups[normalized] = append(titleGroups[normalized], file)
 		if _, ok := titleToRelease[normalized]; !ok {
 			titleToRelease[normalized] = releaseName
+			titleOrder = append(titleOrder, normalized)
 		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	// Find groups with multiple files
 	var duplicates []Duplicate
-	for normalized, files := range titleGroups {
+	for i, normalized := range titleOrder {
+		files := titleGroups[normalized]
+		d.reportProgress(DuplicateVariant, i+1, len(titleOrder))
 		if len(files) > 1 {
 			// Mark preferred file (sha1 match with no problem flags is best)
-			markPreferred(files)
+			d.Scoring.markPreferred(files)
 			duplicates = append(duplicates, Duplicate{
 				Type:  DuplicateVariant,
 				Title: titleToRelease[normalized],
@@ -152,12 +237,13 @@ func (d *DuplicateFinder) FindVariantDuplicates(ctx context.Context, libraryID i
 
 // FindPackagingDuplicates finds multiple files matched to the same ROM entry.
 func (d *DuplicateFinder) FindPackagingDuplicates(ctx context.Context, libraryID int64) ([]Duplicate, error) {
-	// Find ROM entries that have multiple matched files
+	// Find ROM entries that have multiple matched files. Split ROM parts share
+	// one entry by design, so they are never duplicates of each other.
 	rows, err := d.db.QueryContext(ctx, `
 		SELECT m.rom_entry_id, COUNT(*) as cnt
 		FROM matches m
 		JOIN scanned_files sf ON sf.id = m.scanned_file_id
-		WHERE sf.library_id = ?
+		WHERE sf.library_id = ? AND sf.virtual = 0 AND COALESCE(m.flags, '') != '`+splitFlag+`'
 		GROUP BY m.rom_entry_id
 		HAVING cnt > 1
 	`, libraryID)
@@ -178,25 +264,37 @@ func (d *DuplicateFinder) FindPackagingDuplicates(ctx context.Context, libraryID
 
 	// Get file details for each entry
 	var duplicates []Duplicate
-	for _, romEntryID := range romEntryIDs {
+	for i, romEntryID := range romEntryIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		files, err := d.getFilesForROMEntry(ctx, libraryID, romEntryID)
 		if err != nil {
 			return nil, err
 		}
 		if len(files) > 1 {
-			markPreferred(files)
+			d.Scoring.markPreferred(files)
 			duplicates = append(duplicates, Duplicate{
 				Type:      DuplicatePackage,
 				ReleaseID: romEntryID,
 				Files:     files,
 			})
 		}
+		d.reportProgress(DuplicatePackage, i+1, len(romEntryIDs))
 	}
 
 	return duplicates, nil
 }
 
-// FindAllDuplicates finds all types of duplicates in a library.
+// reportProgress calls OnProgress if one is set.
+func (d *DuplicateFinder) reportProgress(phase DuplicateType, processed, total int) {
+	if d.OnProgress != nil {
+		d.OnProgress(DuplicateProgress{Phase: phase, GroupsProcessed: processed, GroupsTotal: total})
+	}
+}
+
+// FindAllDuplicates finds all types of duplicates in a library. It stops with
+// ctx.Err() when ctx is cancelled.
 func (d *DuplicateFinder) FindAllDuplicates(ctx context.Context, libraryID int64) ([]Duplicate, error) {
 	ctx, span := tracing.StartSpan(ctx, "library.FindDuplicates",
 		tracing.WithAttributes(attribute.Int64("library.id", libraryID)),
@@ -236,40 +334,54 @@ func (d *DuplicateFinder) FindAllDuplicates(ctx context.Context, libraryID int64
 	return all, nil
 }
 
-func (d *DuplicateFinder) getFilesForHash(ctx context.Context, libraryID int64, sha1 string) ([]DuplicateFile, error) {
+// getFilesForHashes returns the files for each of the given SHA1 hashes in one query.
+func (d *DuplicateFinder) getFilesForHashes(ctx context.Context, libraryID int64, hashes []string) (map[string][]DuplicateFile, error) {
+	placeholders := make([]string, len(hashes))
+	args := make([]interface{}, 0, len(hashes)+1)
+	args = append(args, libraryID)
+	for i, hash := range hashes {
+		placeholders[i] = "?"
+		args = append(args, hash)
+	}
+
+	// #nosec G202 - only placeholders are concatenated
 	rows, err := d.db.QueryContext(ctx, `
-		SELECT sf.id, sf.path, sf.size, sf.sha1, sf.crc32,
-		       COALESCE(m.match_type, ''), COALESCE(m.flags, '')
+		SELECT sf.id, sf.path, sf.size, sf.sha1, COALESCE(sf.crc32, ''),
+		       COALESCE(m.match_type, ''), COALESCE(m.flags, ''), `+fileTagsColumn+`,
+		       COALESCE(sf.archive_path, '')
 		FROM scanned_files sf
 		LEFT JOIN matches m ON m.scanned_file_id = sf.id
-		WHERE sf.library_id = ? AND sf.sha1 = ?
-	`, libraryID, sha1)
+		WHERE sf.library_id = ? AND sf.virtual = 0
+		  AND sf.sha1 IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY sf.id
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	var files []DuplicateFile
+	files := make(map[string][]DuplicateFile, len(hashes))
 	for rows.Next() {
 		var f DuplicateFile
 		if err := rows.Scan(&f.ScannedFileID, &f.Path, &f.Size, &f.SHA1, &f.CRC32,
-			&f.MatchType, &f.Flags); err != nil {
+			&f.MatchType, &f.Flags, &f.Tags, &f.ArchivePath); err != nil {
 			return nil, err
 		}
-		files = append(files, f)
+		files[f.SHA1] = append(files[f.SHA1], f)
 	}
 
-	markPreferred(files)
-	return files, nil
+	return files, rows.Err()
 }
 
 func (d *DuplicateFinder) getFilesForROMEntry(ctx context.Context, libraryID, romEntryID int64) ([]DuplicateFile, error) {
 	rows, err := d.db.QueryContext(ctx, `
 		SELECT sf.id, sf.path, sf.size, sf.sha1, sf.crc32,
-		       m.match_type, COALESCE(m.flags, '')
+		       m.match_type, COALESCE(m.flags, ''), `+fileTagsColumn+`,
+		       COALESCE(sf.archive_path, '')
 		FROM scanned_files sf
 		JOIN matches m ON m.scanned_file_id = sf.id
-		WHERE sf.library_id = ? AND m.rom_entry_id = ?
+		WHERE sf.library_id = ? AND m.rom_entry_id = ? AND sf.virtual = 0
+		  AND COALESCE(m.flags, '') != '`+splitFlag+`'
 	`, libraryID, romEntryID)
 	if err != nil {
 		return nil, err
@@ -280,7 +392,7 @@ func (d *DuplicateFinder) getFilesForROMEntry(ctx context.Context, libraryID, ro
 	for rows.Next() {
 		var f DuplicateFile
 		if err := rows.Scan(&f.ScannedFileID, &f.Path, &f.Size, &f.SHA1, &f.CRC32,
-			&f.MatchType, &f.Flags); err != nil {
+			&f.MatchType, &f.Flags, &f.Tags, &f.ArchivePath); err != nil {
 			return nil, err
 		}
 		files = append(files, f)
@@ -289,18 +401,62 @@ func (d *DuplicateFinder) getFilesForROMEntry(ctx context.Context, libraryID, ro
 	return files, nil
 }
 
-// markPreferred marks the best file in a duplicate group as preferred.
-// Priority: sha1 > crc32 > name > name_modified, then no flags > has flags
+// findTaggedFiles returns the scanned files in a library carrying the given tag.
+func (d *DuplicateFinder) findTaggedFiles(ctx context.Context, libraryID int64, tag string) ([]DuplicateFile, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT sf.id, sf.path, sf.size, COALESCE(sf.sha1, ''), COALESCE(sf.crc32, ''),
+		       COALESCE(m.match_type, ''), COALESCE(m.flags, ''), `+fileTagsColumn+`,
+		       COALESCE(sf.archive_path, '')
+		FROM scanned_files sf
+		JOIN file_tags t ON t.library_id = sf.library_id AND t.path = sf.path
+		     AND t.archive_path = COALESCE(sf.archive_path, '')
+		LEFT JOIN matches m ON m.scanned_file_id = sf.id
+		WHERE sf.library_id = ? AND t.tag = ? AND sf.virtual = 0
+		GROUP BY sf.id
+		ORDER BY sf.path
+	`, libraryID, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var files []DuplicateFile
+	for rows.Next() {
+		var f DuplicateFile
+		if err := rows.Scan(&f.ScannedFileID, &f.Path, &f.Size, &f.SHA1, &f.CRC32,
+			&f.MatchType, &f.Flags, &f.Tags, &f.ArchivePath); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, rows.Err()
+}
+
+// markPreferred marks the best file in a duplicate group as preferred
+// using the default scoring.
 func markPreferred(files []DuplicateFile) {
+	DefaultCopyScoring().markPreferred(files)
+}
+
+// scoreFile scores a file with the default scoring.
+func scoreFile(f DuplicateFile) int {
+	return DefaultCopyScoring().score(f)
+}
+
+// markPreferred marks the best file in a duplicate group as preferred.
+// User tags come first (keep > untagged > replace > delete), then the
+// match type, flag, archive, path prefix and path depth weights.
+func (c CopyScoring) markPreferred(files []DuplicateFile) {
 	if len(files) == 0 {
 		return
 	}
 
 	bestIdx := 0
-	bestScore := scoreFile(files[0])
+	bestScore := c.score(files[0])
 
 	for i := 1; i < len(files); i++ {
-		score := scoreFile(files[i])
+		score := c.score(files[i])
 		if score > bestScore {
 			bestScore = score
 			bestIdx = i
@@ -310,28 +466,42 @@ func markPreferred(files []DuplicateFile) {
 	files[bestIdx].IsPreferred = true
 }
 
-func scoreFile(f DuplicateFile) int {
+func (c CopyScoring) score(f DuplicateFile) int {
 	score := 0
 
-	// Match type scoring
-	switch f.MatchType {
-	case "sha1":
-		score += 100
-	case "crc32":
-		score += 80
-	case "name":
-		score += 50
-	case "name_modified":
-		score += 20
+	// User tags override match quality
+	switch {
+	case hasTag(f.Tags, TagKeep):
+		score += 1000
+	case hasTag(f.Tags, TagDelete):
+		score -= 1000
+	case hasTag(f.Tags, TagReplace):
+		score -= 500
+	}
+
+	score += c.MatchScores[f.MatchType]
+
+	// Penalty for problematic flags; a stripped header is not a problem
+	if f.Flags != "" && f.Flags != headeredFlag {
+		score -= c.FlagPenalty
 	}
 
-	// Penalty for problematic flags
-	if f.Flags != "" {
-		score -= 10
+	if c.PreferArchive != nil && (f.ArchivePath != "") == *c.PreferArchive {
+		score += c.ArchiveBonus
+	}
+
+	if c.PreferredPathPrefix != "" && pathHasPrefix(f.Path, c.PreferredPathPrefix) {
+		score += c.PathPrefixBonus
 	}
 
 	// Prefer shorter paths (likely better organized)
-	score -= len(filepath.Dir(f.Path)) / 10
+	score -= len(filepath.Dir(f.Path)) / 10 * c.PathDepthWeight
 
 	return score
 }
+
+// pathHasPrefix reports whether path is prefix or lies under it.
+func pathHasPrefix(path, prefix string) bool {
+	prefix = filepath.Clean(prefix)
+	return path == prefix || strings.HasPrefix(path, prefix+string(filepath.Separator))
+}
@@ -1,9 +1,16 @@
 package library
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
 )
 
 func TestDuplicateTypeConstants(t *testing.T) {
@@ -50,6 +57,19 @@ func TestMarkPreferred_PenalizesFlags(t *testing.T) {
 	assert.True(t, files[1].IsPreferred)  // no flags
 }
 
+func TestMarkPreferred_RespectsTags(t *testing.T) {
+	files := []DuplicateFile{
+		{ScannedFileID: 1, Path: "/a.rom", MatchType: "sha1", Tags: "delete"},
+		{ScannedFileID: 2, Path: "/b.rom", MatchType: "name", Flags: "bad-dump", Tags: "keep"},
+		{ScannedFileID: 3, Path: "/c.rom", MatchType: "sha1", Tags: "replace"},
+	}
+	markPreferred(files)
+
+	assert.False(t, files[0].IsPreferred)
+	assert.True(t, files[1].IsPreferred) // keep wins over a better match
+	assert.False(t, files[2].IsPreferred)
+}
+
 func TestScoreFile(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -72,3 +92,151 @@ func TestScoreFile(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyScoring_ArchivePreference(t *testing.T) {
+	newGroup := func() []DuplicateFile {
+		return []DuplicateFile{
+			{ScannedFileID: 1, Path: "/roms/game.nes", MatchType: "sha1"},
+			{ScannedFileID: 2, Path: "/roms/zipped/game.zip", ArchivePath: "game.nes", MatchType: "sha1"},
+		}
+	}
+	preferArchive, preferLoose := true, false
+
+	scoring := DefaultCopyScoring()
+	files := newGroup()
+	scoring.markPreferred(files)
+	assert.True(t, files[0].IsPreferred, "no preference keeps the shorter path")
+
+	scoring.PreferArchive = &preferArchive
+	files = newGroup()
+	scoring.markPreferred(files)
+	assert.True(t, files[1].IsPreferred, "prefer archive")
+
+	scoring.PreferArchive = &preferLoose
+	files = newGroup()
+	files[0].Path = "/roms/some/deeply/nested/folder/game.nes"
+	scoring.markPreferred(files)
+	assert.True(t, files[0].IsPreferred, "prefer loose outweighs path depth")
+
+	// Match quality still wins over packaging
+	files = newGroup()
+	files[0].MatchType = "crc32"
+	scoring.PreferArchive = &preferArchive
+	scoring.markPreferred(files)
+	assert.True(t, files[1].IsPreferred)
+	files = newGroup()
+	files[1].MatchType = "crc32"
+	scoring.markPreferred(files)
+	assert.True(t, files[0].IsPreferred)
+}
+
+func TestCopyScoring_Tunables(t *testing.T) {
+	scoring := DefaultCopyScoring()
+	scoring.PreferredPathPrefix = "/roms/keep/"
+
+	files := []DuplicateFile{
+		{ScannedFileID: 1, Path: "/roms/game.nes", MatchType: "sha1"},
+		{ScannedFileID: 2, Path: "/roms/keep/sub/game.nes", MatchType: "sha1"},
+		{ScannedFileID: 3, Path: "/roms/keeper/game.nes", MatchType: "sha1"},
+	}
+	scoring.markPreferred(files)
+	assert.True(t, files[1].IsPreferred)
+	assert.False(t, files[2].IsPreferred)
+
+	// A large flag penalty outweighs a better match type, and custom match scores apply
+	scoring = DefaultCopyScoring()
+	scoring.FlagPenalty = 50
+	scoring.MatchScores["name"] = 90
+	files = []DuplicateFile{
+		{ScannedFileID: 1, Path: "/a.rom", MatchType: "sha1", Flags: "bad-dump"},
+		{ScannedFileID: 2, Path: "/b.rom", MatchType: "name"},
+	}
+	scoring.markPreferred(files)
+	assert.True(t, files[1].IsPreferred)
+
+	// No path depth weight leaves equal copies to the first one
+	scoring = DefaultCopyScoring()
+	scoring.PathDepthWeight = 0
+	files = []DuplicateFile{
+		{ScannedFileID: 1, Path: "/a/very/long/directory/path/for/this/copy/a.rom", MatchType: "sha1"},
+		{ScannedFileID: 2, Path: "/b.rom", MatchType: "sha1"},
+	}
+	scoring.markPreferred(files)
+	assert.True(t, files[0].IsPreferred)
+}
+
+// setupExactDuplicates creates a library holding two copies of each of n files.
+func setupExactDuplicates(t *testing.T, n int) *db.DB {
+	t.Helper()
+
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+
+	_, err = database.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	_, err = database.Conn().Exec(`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'lib', '/roms', 1)`)
+	require.NoError(t, err)
+
+	var values []string
+	for i := 0; i < n; i++ {
+		hash := fmt.Sprintf("%040x", i)
+		values = append(values,
+			fmt.Sprintf("(1, '/roms/a/%d.nes', 1, 0, '%s')", i, hash),
+			fmt.Sprintf("(1, '/roms/b/%d.nes', 1, 0, '%s')", i, hash))
+	}
+	_, err = database.Conn().Exec(`INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES ` + strings.Join(values, ","))
+	require.NoError(t, err)
+
+	return database
+}
+
+func TestFindExactDuplicates_Batched(t *testing.T) {
+	// More hashes than fit in one batch
+	n := hashBatchSize + 20
+	database := setupExactDuplicates(t, n)
+
+	finder := NewDuplicateFinder(database.Conn())
+	var last DuplicateProgress
+	calls := 0
+	finder.OnProgress = func(p DuplicateProgress) {
+		calls++
+		last = p
+	}
+
+	dups, err := finder.FindExactDuplicates(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, dups, n)
+	for _, dup := range dups {
+		require.Len(t, dup.Files, 2)
+		preferred := 0
+		for _, f := range dup.Files {
+			assert.Equal(t, dup.Hash, f.SHA1)
+			if f.IsPreferred {
+				preferred++
+			}
+		}
+		assert.Equal(t, 1, preferred)
+	}
+
+	assert.Equal(t, n, calls)
+	assert.Equal(t, DuplicateProgress{Phase: DuplicateExact, GroupsProcessed: n, GroupsTotal: n}, last)
+}
+
+func TestFindAllDuplicates_Cancelled(t *testing.T) {
+	database := setupExactDuplicates(t, hashBatchSize*2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	finder := NewDuplicateFinder(database.Conn())
+	finder.OnProgress = func(p DuplicateProgress) {
+		// Cancel partway through, as Ctrl-C would
+		if p.GroupsProcessed == 10 {
+			cancel()
+		}
+	}
+
+	_, err := finder.FindAllDuplicates(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,85 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// esdeROMPath is the placeholder ES-DE expands to its ROM directory.
+const esdeROMPath = "%ROMPATH%"
+
+// ESDEOptions configures the ES-DE custom collection export.
+type ESDEOptions struct {
+	PreferredOnly bool   // Only include releases in the 1G1R preferred set
+	ROMPath       string // ES-DE's ROM directory; files under it are written relative to %ROMPATH%
+}
+
+// ESDECollectionFile returns the file name ES-DE reads a custom collection
+// from, custom-<name>.cfg.
+func ESDECollectionFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("%w: invalid collection name %q", ErrInvalidArg, name)
+	}
+	return "custom-" + name + ".cfg", nil
+}
+
+// ExportESDECollection generates an EmulationStation-DE custom collection
+// for a library: the path of each matched file, one per line. Archives are
+// listed once, as ES-DE launches them whole, and multi-disc sets with an
+// .m3u by their playlist.
+func (e *Exporter) ExportESDECollection(ctx context.Context, libraryName string, opts ESDEOptions) ([]byte, error) {
+	lib, err := e.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT r.name, sf.path
+		FROM scanned_files sf
+		JOIN matches m ON m.scanned_file_id = sf.id
+		JOIN rom_entries re ON re.id = m.rom_entry_id
+		JOIN releases r ON r.id = re.release_id
+		WHERE sf.library_id = ? AND sf.virtual = 0`
+	if opts.PreferredOnly {
+		query += " AND r.is_preferred = 1"
+	}
+	query += " ORDER BY r.name, sf.path"
+
+	rows, err := e.db.QueryContext(ctx, query, lib.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var b strings.Builder
+	seen := make(map[string]bool)
+	discSets := make(map[string]bool)
+	for rows.Next() {
+		var name, path string
+		if err := rows.Scan(&name, &path); err != nil {
+			return nil, err
+		}
+
+		_, path, skip := discSetEntry(name, path, discSets)
+		if skip || seen[path] {
+			continue
+		}
+		seen[path] = true
+		b.WriteString(formatESDEPath(path, opts.ROMPath) + "\n")
+	}
+
+	return []byte(b.String()), rows.Err()
+}
+
+// formatESDEPath writes a path under romPath relative to %ROMPATH%. ES-DE
+// uses forward slashes on every platform.
+func formatESDEPath(path, romPath string) string {
+	if romPath != "" {
+		if rel, err := filepath.Rel(romPath, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			path = filepath.Join(esdeROMPath, rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/ryanm101/romman-lib/tracing"
 	"go.opentelemetry.io/otel/attribute"
@@ -25,6 +26,7 @@ const (
 	ReportStats      ReportType = "stats"
 	ReportDuplicates ReportType = "duplicates"
 	ReportMismatch   ReportType = "mismatch"
+	ReportFlagged    ReportType = "flagged"
 )
 
 // ExportFormat defines output format.
@@ -32,6 +34,7 @@ type ExportFormat string
 
 const (
 	FormatCSV  ExportFormat = "csv"
+	FormatTSV  ExportFormat = "tsv" // Tab-separated, so titles with commas need no quoting
 	FormatJSON ExportFormat = "json"
 	FormatTXT  ExportFormat = "txt"
 )
@@ -53,6 +56,35 @@ type ExportResult struct {
 	Report  string         `json:"report"`
 	Count   int            `json:"count"`
 	Records []ExportRecord `json:"records"`
+
+	// Unverified lists 1G1R releases left out by VerifiedOnly because they
+	// only have CRC32 or name matches.
+	Unverified []ExportRecord `json:"unverified,omitempty"`
+}
+
+// ExportOptions configures report generation.
+type ExportOptions struct {
+	// VerifiedOnly restricts the 1G1R report to releases with a strong-hash
+	// (sha1 or md5) match and reports the rest separately as unverified.
+	VerifiedOnly bool
+
+	// Fallback fills 1G1R games whose preferred release is not owned with
+	// the best-scoring release of the same title that is, marked with status
+	// "1g1r-fallback".
+	Fallback bool
+
+	// PlayableOnly leaves MAME BIOS, device and mechanical releases out of
+	// the 1G1R report.
+	PlayableOnly bool
+
+	// ExcludeFlagged leaves out matches flagged as bad or overdumps, or as
+	// cracked, fixed, hacked, pirated, trained or translated (ExcludedFlags),
+	// from the matched and 1G1R reports.
+	ExcludeFlagged bool
+
+	// Preferences ranks fallback candidates. The zero value uses
+	// DefaultPreferenceConfig.
+	Preferences PreferenceConfig
 }
 
 // Exporter handles report generation.
@@ -68,6 +100,11 @@ func NewExporter(db *sql.DB, manager *Manager) *Exporter {
 
 // Export generates a report for the given library.
 func (e *Exporter) Export(ctx context.Context, libraryName string, report ReportType, format ExportFormat) ([]byte, error) {
+	return e.ExportWithOptions(ctx, libraryName, report, format, ExportOptions{})
+}
+
+// ExportWithOptions generates a report for the given library using the given options.
+func (e *Exporter) ExportWithOptions(ctx context.Context, libraryName string, report ReportType, format ExportFormat, opts ExportOptions) ([]byte, error) {
 	ctx, span := tracing.StartSpan(ctx, "library.Export",
 		tracing.WithAttributes(
 			attribute.String("library.name", libraryName),
@@ -90,7 +127,7 @@ func (e *Exporter) Export(ctx context.Context, libraryName string, report Report
 
 	switch report {
 	case ReportMatched:
-		result.Records, err = e.getMatched(ctx, lib.ID)
+		result.Records, err = e.getMatched(ctx, lib.ID, opts)
 	case ReportMissing:
 		result.Records, err = e.getMissing(ctx, lib.ID, lib.SystemID)
 	case ReportPreferred:
@@ -98,15 +135,20 @@ func (e *Exporter) Export(ctx context.Context, libraryName string, report Report
 	case ReportUnmatched:
 		result.Records, err = e.getUnmatched(ctx, lib.ID)
 	case Report1G1R:
-		result.Records, err = e.get1G1R(ctx, lib.ID, lib.SystemID)
+		result.Records, result.Unverified, err = e.get1G1R(ctx, lib.ID, lib.SystemID, opts)
+		if err == nil && opts.Fallback {
+			result.Records, result.Unverified, err = e.add1G1RFallbacks(ctx, lib.ID, lib.SystemID, result.Records, result.Unverified, opts)
+		}
 	case ReportStats:
 		return e.exportStats(ctx, lib, format)
 	case ReportDuplicates:
 		result.Records, err = e.getDuplicates(ctx, lib.ID)
 	case ReportMismatch:
 		result.Records, err = e.getMismatch(ctx, lib.ID)
+	case ReportFlagged:
+		result.Records, err = e.getFlagged(ctx, lib.ID)
 	default:
-		return nil, fmt.Errorf("unknown report type: %s", report)
+		return nil, fmt.Errorf("%w: unknown report type: %s", ErrInvalidArg, report)
 	}
 
 	if err != nil {
@@ -125,20 +167,28 @@ func (e *Exporter) Export(ctx context.Context, libraryName string, report Report
 		return json.MarshalIndent(result, "", "  ")
 	case FormatCSV:
 		return e.toCSV(result.Records, report)
+	case FormatTSV:
+		return e.toTSV(result.Records, report)
 	case FormatTXT:
 		return e.toTXT(result.Records), nil
 	default:
-		return nil, fmt.Errorf("unknown format: %s", format)
+		return nil, fmt.Errorf("%w: unknown format: %s", ErrInvalidArg, format)
 	}
 }
 
-func (e *Exporter) getMatched(ctx context.Context, libraryID int64) ([]ExportRecord, error) {
+func (e *Exporter) getMatched(ctx context.Context, libraryID int64, opts ExportOptions) ([]ExportRecord, error) {
 	ctx, span := tracing.StartSpan(ctx, "export.getMatched")
 	defer span.End()
 
+	unflagged := ""
+	if opts.ExcludeFlagged {
+		unflagged = "AND " + unflaggedMatchClause
+	}
+
 	span.AddEvent("query_start", trace.WithAttributes(
 		attribute.String("query_type", "matched"),
 	))
+	// #nosec G202 - only a constant clause is concatenated
 	rows, err := e.db.QueryContext(ctx, `
 		SELECT r.name, sf.path, sf.sha1, m.match_type, COALESCE(m.flags, '')
 		FROM scanned_files sf
@@ -146,7 +196,8 @@ func (e *Exporter) getMatched(ctx context.Context, libraryID int64) ([]ExportRec
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
 		WHERE sf.library_id = ?
-		ORDER BY r.name
+		  `+unflagged+`
+		ORDER BY r.name, sf.path
 	`, libraryID)
 	if err != nil {
 		tracing.RecordError(span, err)
@@ -168,21 +219,26 @@ func (e *Exporter) getMatched(ctx context.Context, libraryID int64) ([]ExportRec
 	return records, nil
 }
 
+// missingReleasesFilter selects releases r of a system (first argument) with
+// no matched ROM in a library (second argument).
+const missingReleasesFilter = `r.system_id = ?
+		AND r.id NOT IN (
+			SELECT DISTINCT re.release_id
+			FROM scanned_files sf
+			JOIN matches m ON m.scanned_file_id = sf.id
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			WHERE sf.library_id = ?
+		)`
+
 func (e *Exporter) getMissing(ctx context.Context, libraryID, systemID int64) ([]ExportRecord, error) {
 	ctx, span := tracing.StartSpan(ctx, "export.getMissing")
 	defer span.End()
 
+	// #nosec G202 - the filter is a constant
 	rows, err := e.db.QueryContext(ctx, `
 		SELECT r.name
 		FROM releases r
-		WHERE r.system_id = ?
-		AND r.id NOT IN (
-			SELECT DISTINCT re.release_id
-			FROM scanned_files sf
-			JOIN matches m ON m.scanned_file_id = sf.id
-			JOIN rom_entries re ON re.id = m.rom_entry_id
-			WHERE sf.library_id = ?
-		)
+		WHERE `+missingReleasesFilter+`
 		ORDER BY r.name
 	`, systemID, libraryID)
 	if err != nil {
@@ -266,6 +322,29 @@ func (e *Exporter) getUnmatched(ctx context.Context, libraryID int64) ([]ExportR
 		rec.Status = "unmatched"
 		records = append(records, rec)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Files the scan left out for their extension, with the reason
+	skippedRows, err := e.db.QueryContext(ctx, `
+		SELECT path, reason FROM skipped_files WHERE library_id = ? ORDER BY path
+	`, libraryID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer func() { _ = skippedRows.Close() }()
+	for skippedRows.Next() {
+		var rec ExportRecord
+		var reason string
+		if err := skippedRows.Scan(&rec.Path, &reason); err != nil {
+			return nil, err
+		}
+		rec.Name = rec.Path
+		rec.Status = "skipped: " + reason
+		records = append(records, rec)
+	}
 	span.AddEvent("processing_complete", trace.WithAttributes(
 		attribute.Int("rows_fetched", len(records)),
 	))
@@ -273,9 +352,20 @@ func (e *Exporter) getUnmatched(ctx context.Context, libraryID int64) ([]ExportR
 }
 
 // get1G1R returns matched preferred releases - one per game (1 Game, 1 ROM).
-func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]ExportRecord, error) {
+// With VerifiedOnly, releases without a strong-hash match are returned separately.
+func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64, opts ExportOptions) ([]ExportRecord, []ExportRecord, error) {
+	verifiedOnly := opts.VerifiedOnly
+	filter := ""
+	if opts.PlayableOnly {
+		filter = "AND " + playableReleaseClause
+	}
+	if opts.ExcludeFlagged {
+		filter += " AND " + unflaggedMatchClause
+	}
+
 	// Get preferred releases that are matched in this library
 	// We include parent_id to group clones
+	// #nosec G202 - only a constant clause is concatenated
 	rows, err := e.db.QueryContext(ctx, `
 		SELECT DISTINCT r.id, r.parent_id, r.name, sf.path, sf.sha1, m.match_type
 		FROM releases r
@@ -285,10 +375,11 @@ func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]Ex
 		WHERE r.system_id = ?
 		  AND r.is_preferred = 1
 		  AND sf.library_id = ?
-		ORDER BY r.name
+		  `+filter+`
+		ORDER BY r.name, sf.path
 	`, systemID, libraryID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
@@ -304,7 +395,7 @@ func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]Ex
 	for rows.Next() {
 		var rec extendedRecord
 		if err := rows.Scan(&rec.id, &rec.parentID, &rec.Name, &rec.Path, &rec.Hash, &rec.MatchType); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		rec.Status = "1g1r"
 
@@ -316,6 +407,7 @@ func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]Ex
 	}
 
 	records := make([]ExportRecord, 0, 100) // Pre-allocate for performance
+	var unverified []ExportRecord
 
 	// Process groups to pick the best one
 	for _, group := range groups {
@@ -326,8 +418,25 @@ func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]Ex
 		// The 'is_preferred=1' filter already selected our preferred regions.
 		// This handles tie-breaking when multiple variants are both preferred and matched.
 
-		best := group[0]
-		for _, rec := range group {
+		// In verified-only mode, only strong-hash matches are candidates
+		candidates := group
+		if verifiedOnly {
+			candidates = nil
+			for _, rec := range group {
+				if isVerifiedMatch(rec.MatchType) {
+					candidates = append(candidates, rec)
+				}
+			}
+			if len(candidates) == 0 {
+				rec := group[0].ExportRecord
+				rec.Status = "unverified"
+				unverified = append(unverified, rec)
+				continue
+			}
+		}
+
+		best := candidates[0]
+		for _, rec := range candidates {
 			if !rec.parentID.Valid {
 				// This is the parent release - prefer it
 				best = rec
@@ -338,12 +447,128 @@ func (e *Exporter) get1G1R(ctx context.Context, libraryID, systemID int64) ([]Ex
 		records = append(records, best.ExportRecord)
 	}
 
-	return records, nil
+	// Groups come from a map, so restore name order for stable output
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	sort.Slice(unverified, func(i, j int) bool { return unverified[i].Name < unverified[j].Name })
+
+	return records, unverified, nil
+}
+
+// add1G1RFallbacks adds, for each title with no preferred release in the
+// library, the owned release that scores highest under the preference rules.
+// Titles are grouped by base title, as when preferred releases are selected.
+// A fallback replaces an unverified entry for the same title.
+func (e *Exporter) add1G1RFallbacks(ctx context.Context, libraryID, systemID int64, records, unverified []ExportRecord, opts ExportOptions) ([]ExportRecord, []ExportRecord, error) {
+	config := opts.Preferences
+	if len(config.RegionOrder) == 0 {
+		config = DefaultPreferenceConfig()
+	}
+	selector := NewPreferenceSelector(e.db, config)
+
+	covered := make(map[string]bool)
+	for _, rec := range records {
+		covered[baseTitle(selector, rec.Name)] = true
+	}
+
+	filter := ""
+	if opts.PlayableOnly {
+		filter = "AND " + playableReleaseClause
+	}
+	if opts.ExcludeFlagged {
+		filter += " AND " + unflaggedMatchClause
+	}
+
+	// #nosec G202 - only a constant clause is concatenated
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT r.id, r.name, sf.path, sf.sha1, m.match_type
+		FROM releases r
+		JOIN rom_entries re ON re.release_id = r.id
+		JOIN matches m ON m.rom_entry_id = re.id
+		JOIN scanned_files sf ON sf.id = m.scanned_file_id
+		WHERE r.system_id = ?
+		  AND COALESCE(r.is_preferred, 0) = 0
+		  AND sf.library_id = ?
+		  `+filter+`
+		ORDER BY r.name, sf.path
+	`, systemID, libraryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	type fallback struct {
+		ExportRecord
+		score int
+	}
+	best := make(map[string]fallback)
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		var rec ExportRecord
+		if err := rows.Scan(&id, &rec.Name, &rec.Path, &rec.Hash, &rec.MatchType); err != nil {
+			return nil, nil, err
+		}
+		// One file per release; rows are ordered by path
+		if seen[id] || (opts.VerifiedOnly && !isVerifiedMatch(rec.MatchType)) {
+			continue
+		}
+		seen[id] = true
+
+		c := ReleaseCandidate{Name: rec.Name}
+		selector.parseReleaseName(&c)
+		if covered[c.BaseTitle] {
+			continue
+		}
+
+		// Rows are in name order, so equal scores keep the first name
+		score := selector.scoreCandidate(&c).Total()
+		if cur, ok := best[c.BaseTitle]; !ok || score > cur.score {
+			rec.Status = "1g1r-fallback"
+			best[c.BaseTitle] = fallback{ExportRecord: rec, score: score}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, fb := range best {
+		records = append(records, fb.ExportRecord)
+	}
+	kept := unverified[:0]
+	for _, rec := range unverified {
+		if _, ok := best[baseTitle(selector, rec.Name)]; !ok {
+			kept = append(kept, rec)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records, kept, nil
+}
+
+// baseTitle returns the title a release is grouped under for preference selection.
+func baseTitle(selector *PreferenceSelector, name string) string {
+	c := ReleaseCandidate{Name: name}
+	selector.parseReleaseName(&c)
+	return c.BaseTitle
 }
 
 func (e *Exporter) toCSV(records []ExportRecord, report ReportType) ([]byte, error) {
+	return e.toDelimited(records, report, ',')
+}
+
+// toTSV converts records to tab-separated values with the columns of toCSV.
+func (e *Exporter) toTSV(records []ExportRecord, report ReportType) ([]byte, error) {
+	return e.toDelimited(records, report, '\t')
+}
+
+// toDelimited writes records as CSV with the given field delimiter.
+func (e *Exporter) toDelimited(records []ExportRecord, report ReportType, comma rune) ([]byte, error) {
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
+	writer.Comma = comma
 
 	// Header based on report type
 	var header []string
@@ -358,6 +583,8 @@ func (e *Exporter) toCSV(records []ExportRecord, report ReportType) ([]byte, err
 		header = []string{"path", "hash", "matches"}
 	case ReportMismatch:
 		header = []string{"name", "path", "expected_hash", "actual_hash"}
+	case ReportFlagged:
+		header = []string{"name", "path", "match_type", "flags"}
 	}
 	if err := writer.Write(header); err != nil {
 		return nil, err
@@ -377,6 +604,8 @@ func (e *Exporter) toCSV(records []ExportRecord, report ReportType) ([]byte, err
 			row = []string{rec.Path, rec.Hash, rec.Status}
 		case ReportMismatch:
 			row = []string{rec.Name, rec.Path, rec.Hash, rec.Status}
+		case ReportFlagged:
+			row = []string{rec.Name, rec.Path, rec.MatchType, rec.Flags}
 		}
 		if err := writer.Write(row); err != nil {
 			return nil, err
@@ -415,28 +644,15 @@ func (e *Exporter) exportStats(ctx context.Context, lib *Library, format ExportF
 		System:  lib.SystemName,
 	}
 
-	// Total releases for system
-	err := e.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM releases WHERE system_id = ?", lib.SystemID).Scan(&stats.TotalReleases)
-	if err != nil {
-		return nil, err
-	}
-
-	// Matched releases (distinct)
-	err = e.db.QueryRowContext(ctx, `
-		SELECT COUNT(DISTINCT re.release_id)
-		FROM scanned_files sf
-		JOIN matches m ON m.scanned_file_id = sf.id
-		JOIN rom_entries re ON re.id = m.rom_entry_id
-		WHERE sf.library_id = ?
-	`, lib.ID).Scan(&stats.MatchedFiles)
+	// Releases and matched releases, counted as systems list does
+	completion, err := GetLibraryCompletion(ctx, e.db, lib)
 	if err != nil {
 		return nil, err
 	}
-
-	stats.MissingReleases = stats.TotalReleases - stats.MatchedFiles
-	if stats.TotalReleases > 0 {
-		stats.PercentComplete = float64(stats.MatchedFiles) / float64(stats.TotalReleases) * 100
-	}
+	stats.TotalReleases = completion.TotalReleases
+	stats.MatchedFiles = completion.MatchedReleases
+	stats.MissingReleases = completion.TotalReleases - completion.MatchedReleases
+	stats.PercentComplete = completion.Completion
 
 	// Region breakdown (parse from release names)
 	stats.RegionBreakdown = make(map[string]int)
@@ -465,14 +681,18 @@ func (e *Exporter) exportStats(ctx context.Context, lib *Library, format ExportF
 	switch format {
 	case FormatJSON:
 		return json.MarshalIndent(stats, "", "  ")
-	case FormatCSV:
+	case FormatCSV, FormatTSV:
+		sep := ","
+		if format == FormatTSV {
+			sep = "\t"
+		}
 		var buf bytes.Buffer
-		fmt.Fprintf(&buf, "Library,%s\n", stats.Library)
-		fmt.Fprintf(&buf, "System,%s\n", stats.System)
-		fmt.Fprintf(&buf, "Total Releases,%d\n", stats.TotalReleases)
-		fmt.Fprintf(&buf, "Matched,%d\n", stats.MatchedFiles)
-		fmt.Fprintf(&buf, "Missing,%d\n", stats.MissingReleases)
-		fmt.Fprintf(&buf, "Percent Complete,%.2f%%\n", stats.PercentComplete)
+		fmt.Fprintf(&buf, "Library%s%s\n", sep, stats.Library)
+		fmt.Fprintf(&buf, "System%s%s\n", sep, stats.System)
+		fmt.Fprintf(&buf, "Total Releases%s%d\n", sep, stats.TotalReleases)
+		fmt.Fprintf(&buf, "Matched%s%d\n", sep, stats.MatchedFiles)
+		fmt.Fprintf(&buf, "Missing%s%d\n", sep, stats.MissingReleases)
+		fmt.Fprintf(&buf, "Percent Complete%s%.2f%%\n", sep, stats.PercentComplete)
 		return buf.Bytes(), nil
 	case FormatTXT:
 		var buf bytes.Buffer
@@ -480,7 +700,7 @@ func (e *Exporter) exportStats(ctx context.Context, lib *Library, format ExportF
 		fmt.Fprintf(&buf, "Complete: %.1f%% (%d/%d)\n", stats.PercentComplete, stats.MatchedFiles, stats.TotalReleases)
 		return buf.Bytes(), nil
 	default:
-		return nil, fmt.Errorf("unknown format: %s", format)
+		return nil, fmt.Errorf("%w: unknown format: %s", ErrInvalidArg, format)
 	}
 }
 
@@ -553,7 +773,7 @@ func (e *Exporter) getMismatch(ctx context.Context, libraryID int64) ([]ExportRe
 		  AND sf.sha1 IS NOT NULL 
 		  AND re.sha1 IS NOT NULL
 		  AND sf.sha1 != re.sha1
-		ORDER BY r.name
+		ORDER BY r.name, sf.path
 	`, libraryID)
 	if err != nil {
 		return nil, err
@@ -573,3 +793,47 @@ func (e *Exporter) getMismatch(ctx context.Context, libraryID int64) ([]ExportRe
 	}
 	return records, nil
 }
+
+// FlaggedFilesQuery selects the matched files of a library, its ID the only
+// argument, whose match carries flags (bad dumps, hacks, headers, etc.): the
+// columns name, path, sha1, match_type and flags. Archive entries have the
+// path "archive:entry", as GetUnmatchedFiles lists them. The flagged report
+// and the web details view both list it.
+const FlaggedFilesQuery = `
+	SELECT r.name AS name,
+		CASE WHEN COALESCE(sf.archive_path, '') = '' THEN sf.path
+			ELSE sf.path || ':' || sf.archive_path END AS path,
+		COALESCE(sf.sha1, '') AS sha1, m.match_type AS match_type, m.flags AS flags
+	FROM scanned_files sf
+	JOIN matches m ON m.scanned_file_id = sf.id
+	JOIN rom_entries re ON re.id = m.rom_entry_id
+	JOIN releases r ON r.id = re.release_id
+	WHERE sf.library_id = ? AND m.flags IS NOT NULL AND m.flags != ''`
+
+// getFlagged returns matched files carrying problem flags (bad dumps, hacks, etc).
+// This is the review list for replacing bad dumps with verified copies.
+func (e *Exporter) getFlagged(ctx context.Context, libraryID int64) ([]ExportRecord, error) {
+	ctx, span := tracing.StartSpan(ctx, "export.getFlagged")
+	defer span.End()
+
+	rows, err := e.db.QueryContext(ctx, FlaggedFilesQuery+" ORDER BY name, path", libraryID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var records []ExportRecord
+	for rows.Next() {
+		var rec ExportRecord
+		if err := rows.Scan(&rec.Name, &rec.Path, &rec.Hash, &rec.MatchType, &rec.Flags); err != nil {
+			return nil, err
+		}
+		rec.Status = "flagged"
+		records = append(records, rec)
+	}
+	span.AddEvent("processing_complete", trace.WithAttributes(
+		attribute.Int("rows_fetched", len(records)),
+	))
+	return records, nil
+}
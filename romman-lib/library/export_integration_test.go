@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -88,6 +89,168 @@ func TestExport1G1RIntegration(t *testing.T) {
 	}
 }
 
+// TestExportDeterministicIntegration tests that repeated exports are byte-identical.
+func TestExportDeterministicIntegration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := initTestSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	setup1G1RTestData(t, db)
+
+	// More groups make map-ordered output likely to differ between runs
+	for i := 0; i < 20; i++ {
+		releaseID := 100 + i
+		if _, err := db.Exec(`INSERT INTO releases (id, system_id, name, is_preferred) VALUES (?, 1, ?, 1)`,
+			releaseID, fmt.Sprintf("Game %02d (Europe)", i)); err != nil {
+			t.Fatalf("Failed to create release: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES (?, ?, ?, ?)`,
+			releaseID, releaseID, fmt.Sprintf("game%02d.bin", i), fmt.Sprintf("sha%02d", i)); err != nil {
+			t.Fatalf("Failed to create ROM entry: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO scanned_files (id, library_id, path, sha1) VALUES (?, 1, ?, ?)`,
+			releaseID, fmt.Sprintf("/test/path/game%02d.bin", i), fmt.Sprintf("sha%02d", i)); err != nil {
+			t.Fatalf("Failed to create scanned file: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (?, ?, 'sha1')`,
+			releaseID, releaseID); err != nil {
+			t.Fatalf("Failed to create match: %v", err)
+		}
+	}
+
+	exporter := NewExporter(db, NewManager(db))
+	for _, report := range []ReportType{ReportMatched, ReportMissing, ReportPreferred, Report1G1R, ReportStats} {
+		first, err := exporter.Export(context.Background(), "testlib", report, FormatJSON)
+		if err != nil {
+			t.Fatalf("Failed to export %s: %v", report, err)
+		}
+		for run := 0; run < 5; run++ {
+			again, err := exporter.Export(context.Background(), "testlib", report, FormatJSON)
+			if err != nil {
+				t.Fatalf("Failed to export %s: %v", report, err)
+			}
+			if string(again) != string(first) {
+				t.Fatalf("Export %s differs between runs:\n%s\n---\n%s", report, first, again)
+			}
+		}
+	}
+
+	data, err := exporter.Export(context.Background(), "testlib", Report1G1R, FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to export 1G1R: %v", err)
+	}
+	var result ExportResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	for i := 1; i < len(result.Records); i++ {
+		if result.Records[i-1].Name > result.Records[i].Name {
+			t.Errorf("1G1R records not sorted: %q before %q", result.Records[i-1].Name, result.Records[i].Name)
+		}
+	}
+}
+
+// TestExport1G1RVerifiedOnlyIntegration tests that CRC32-only matches are reported separately.
+func TestExport1G1RVerifiedOnlyIntegration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := initTestSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	setup1G1RTestData(t, db)
+	if _, err := db.Exec(`UPDATE matches SET match_type = 'crc32' WHERE id = 2`); err != nil {
+		t.Fatalf("Failed to update match: %v", err)
+	}
+
+	exporter := NewExporter(db, NewManager(db))
+	data, err := exporter.ExportWithOptions(context.Background(), "testlib", Report1G1R, FormatJSON, ExportOptions{VerifiedOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to export 1G1R: %v", err)
+	}
+
+	var result ExportResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	if len(result.Records) != 1 || result.Records[0].Name != "Game A (Europe)" {
+		t.Errorf("Expected only Game A (Europe) as verified, got %+v", result.Records)
+	}
+	if len(result.Unverified) != 1 || result.Unverified[0].Name != "Game B (Europe)" {
+		t.Errorf("Expected Game B (Europe) as unverified, got %+v", result.Unverified)
+	}
+	if len(result.Unverified) == 1 && result.Unverified[0].Status != "unverified" {
+		t.Errorf("Expected status 'unverified', got '%s'", result.Unverified[0].Status)
+	}
+
+	// Without the option the CRC32 match still counts
+	data, err = exporter.Export(context.Background(), "testlib", Report1G1R, FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to export 1G1R: %v", err)
+	}
+	result = ExportResult{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(result.Records) != 2 || len(result.Unverified) != 0 {
+		t.Errorf("Expected 2 records and no unverified, got %d and %d", len(result.Records), len(result.Unverified))
+	}
+}
+
+// TestLibraryStatusVerifiedOnlyIntegration tests that CRC32-only releases are present but unverified.
+func TestLibraryStatusVerifiedOnlyIntegration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := initTestSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+
+	setup1G1RTestData(t, db)
+	if _, err := db.Exec(`UPDATE matches SET match_type = 'crc32' WHERE id = 2`); err != nil {
+		t.Fatalf("Failed to update match: %v", err)
+	}
+
+	scanner := NewScanner(db)
+	statusOf := func(opts StatusOptions) map[string]string {
+		statuses, err := scanner.GetLibraryStatusWithOptions(context.Background(), "testlib", opts)
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		byName := make(map[string]string)
+		for _, s := range statuses {
+			byName[s.ReleaseName] = s.Status
+		}
+		return byName
+	}
+
+	all := statusOf(StatusOptions{})
+	if all["Game A (Europe)"] != "present" || all["Game B (Europe)"] != "present" || all["Game A (USA)"] != "missing" {
+		t.Errorf("Unexpected default statuses: %v", all)
+	}
+
+	verified := statusOf(StatusOptions{VerifiedOnly: true})
+	if verified["Game A (Europe)"] != "present" {
+		t.Errorf("Expected Game A (Europe) present, got %s", verified["Game A (Europe)"])
+	}
+	if verified["Game B (Europe)"] != "unverified" {
+		t.Errorf("Expected Game B (Europe) unverified, got %s", verified["Game B (Europe)"])
+	}
+}
+
 // TestExportCSVFormat tests CSV output formatting.
 func TestExportCSVFormat(t *testing.T) {
 	db, err := sql.Open("sqlite3", ":memory:")
@@ -177,3 +340,81 @@ func setup1G1RTestData(t *testing.T, db *sql.DB) {
 		t.Fatalf("Failed to create matches: %v", err)
 	}
 }
+
+// TestExport1G1RFallbackIntegration tests that a missing preferred release is
+// replaced by the best owned alternative only in fallback mode.
+func TestExport1G1RFallbackIntegration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := initTestSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	setup1G1RTestData(t, db)
+
+	// Game C (Europe) is preferred but not owned; both alternates are
+	stmts := []string{
+		`INSERT INTO releases (id, system_id, name, is_preferred) VALUES
+			(4, 1, 'Game C (Europe)', 1),
+			(5, 1, 'Game C (Japan) (En)', 0),
+			(6, 1, 'Game C (USA)', 0)`,
+		`INSERT INTO rom_entries (id, release_id, name, sha1, crc32) VALUES
+			(4, 4, 'gamec.bin', 'c-eu', '00000004'),
+			(5, 5, 'gamec.bin', 'c-jp', '00000005'),
+			(6, 6, 'gamec.bin', 'c-us', '00000006')`,
+		`INSERT INTO scanned_files (id, library_id, path, sha1, crc32) VALUES
+			(3, 1, '/test/path/gamec-jp.bin', 'c-jp', '00000005'),
+			(4, 1, '/test/path/gamec-us.bin', 'c-us', '00000006')`,
+		`INSERT INTO matches (id, scanned_file_id, rom_entry_id, match_type) VALUES
+			(3, 3, 5, 'sha1'),
+			(4, 4, 6, 'sha1')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	exporter := NewExporter(db, NewManager(db))
+	export := func(opts ExportOptions) ExportResult {
+		t.Helper()
+		data, err := exporter.ExportWithOptions(context.Background(), "testlib", Report1G1R, FormatJSON, opts)
+		if err != nil {
+			t.Fatalf("Failed to export 1G1R: %v", err)
+		}
+		var result ExportResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("Failed to parse JSON: %v", err)
+		}
+		return result
+	}
+
+	plain := export(ExportOptions{})
+	for _, rec := range plain.Records {
+		if strings.HasPrefix(rec.Name, "Game C") {
+			t.Errorf("Expected no Game C without fallback, got %s", rec.Name)
+		}
+	}
+
+	result := export(ExportOptions{Fallback: true})
+	var got []string
+	for _, rec := range result.Records {
+		got = append(got, rec.Name+"|"+rec.Status)
+	}
+	want := []string{"Game A (Europe)|1g1r", "Game B (Europe)|1g1r", "Game C (USA)|1g1r-fallback"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if result.Records[2].Path != "/test/path/gamec-us.bin" {
+		t.Errorf("Expected fallback path of the USA file, got %s", result.Records[2].Path)
+	}
+
+	// Region order decides between alternates
+	result = export(ExportOptions{Fallback: true, Preferences: PreferenceConfig{RegionOrder: []string{"Japan", "USA"}}})
+	if len(result.Records) != 3 || result.Records[2].Name != "Game C (Japan) (En)" {
+		t.Errorf("Expected Japan fallback with Japan first, got %+v", result.Records)
+	}
+}
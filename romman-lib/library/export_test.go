@@ -135,6 +135,62 @@ func TestExporter_ExportMissing(t *testing.T) {
 	assert.Equal(t, 1, result.Count) // The test game should be missing
 }
 
+func TestExporter_ExportFlagged(t *testing.T) {
+	conn := setupExportTestDB(t)
+	setupExportTestData(t, conn)
+
+	_, err := conn.Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32)
+		VALUES (1, '/tmp/testlib/good.bin', 1024, 1, 'abc123', 'def456'),
+		       (1, '/tmp/testlib/Test Game (USA) [b1].bin', 1024, 1, 'bad000', 'bad000')
+	`)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(`
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type, flags)
+		VALUES (1, 1, 'sha1', NULL), (2, 1, 'name_modified', 'bad-dump')
+	`)
+	require.NoError(t, err)
+
+	exporter := NewExporter(conn, NewManager(conn))
+
+	data, err := exporter.Export(context.Background(), "testlib", ReportFlagged, FormatJSON)
+	require.NoError(t, err)
+
+	var result ExportResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Equal(t, 1, result.Count)
+	assert.Equal(t, "Test Game (USA)", result.Records[0].Name)
+	assert.Equal(t, "/tmp/testlib/Test Game (USA) [b1].bin", result.Records[0].Path)
+	assert.Equal(t, "name_modified", result.Records[0].MatchType)
+	assert.Equal(t, "bad-dump", result.Records[0].Flags)
+
+	data, err = exporter.Export(context.Background(), "testlib", ReportFlagged, FormatCSV)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "name,path,match_type,flags")
+	assert.Contains(t, string(data), "name_modified,bad-dump")
+	assert.NotContains(t, string(data), "good.bin")
+
+	// Flagged entries of one archive are told apart by their entry names
+	_, err = conn.Exec(`
+		INSERT INTO scanned_files (library_id, path, archive_path, size, mtime, sha1)
+		VALUES (1, '/tmp/testlib/set.zip', 'a [h1].bin', 1024, 1, 'h1'),
+		       (1, '/tmp/testlib/set.zip', 'b [h2].bin', 1024, 1, 'h2')
+	`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type, flags)
+		VALUES (3, 1, 'name_modified', 'hack'), (4, 1, 'name_modified', 'hack')
+	`)
+	require.NoError(t, err)
+	data, err = exporter.Export(context.Background(), "testlib", ReportFlagged, FormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Equal(t, 3, result.Count)
+	assert.Equal(t, "/tmp/testlib/set.zip:a [h1].bin", result.Records[1].Path)
+	assert.Equal(t, "/tmp/testlib/set.zip:b [h2].bin", result.Records[2].Path)
+}
+
 func TestExporter_ExportUnmatched(t *testing.T) {
 	conn := setupExportTestDB(t)
 	setupExportTestData(t, conn)
@@ -176,6 +232,7 @@ func TestExporter_InvalidReportType(t *testing.T) {
 
 	_, err := exporter.Export(context.Background(), "testlib", ReportType("invalid"), FormatCSV)
 	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidArg)
 	assert.Contains(t, err.Error(), "unknown report type")
 }
 
@@ -187,7 +244,7 @@ func TestExporter_InvalidFormat(t *testing.T) {
 	exporter := NewExporter(conn, manager)
 
 	_, err := exporter.Export(context.Background(), "testlib", ReportMatched, ExportFormat("xml"))
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidArg)
 	assert.Contains(t, err.Error(), "unknown format")
 }
 
@@ -210,6 +267,34 @@ func TestExporter_CSVEscapesCommas(t *testing.T) {
 	assert.True(t, strings.Contains(csv, `"Game, The (USA)"`))
 }
 
+func TestExporter_TSVKeepsCommasInOneField(t *testing.T) {
+	conn := setupExportTestDB(t)
+	setupExportTestData(t, conn)
+
+	_, err := conn.Exec(`UPDATE releases SET name = 'Game, The (USA)' WHERE id = 1`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32)
+		VALUES (1, '/tmp/testlib/test.bin', 1024, 1234567890, 'abc123', 'def456')
+	`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1')`)
+	require.NoError(t, err)
+
+	exporter := NewExporter(conn, NewManager(conn))
+	data, err := exporter.Export(context.Background(), "testlib", ReportMatched, FormatTSV)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"name", "path", "hash", "match_type", "flags"}, strings.Split(lines[0], "\t"))
+	assert.Equal(t, []string{"Game, The (USA)", "/tmp/testlib/test.bin", "abc123", "sha1", ""}, strings.Split(lines[1], "\t"))
+
+	data, err = exporter.Export(context.Background(), "testlib", ReportStats, FormatTSV)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Library\ttestlib\n")
+}
+
 func TestExporter_ExportMissing_TXT(t *testing.T) {
 	conn := setupExportTestDB(t)
 	setupExportTestData(t, conn)
@@ -251,3 +336,86 @@ func TestExporter_TXT_OneNamePerLine(t *testing.T) {
 	assert.Contains(t, lines[0], "Game")
 	assert.Contains(t, lines[1], "Game")
 }
+
+func TestPlayableOnly(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 3)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	scanner := NewScanner(conn)
+	_, err := scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	// Game 01 is a BIOS set and Game 02 a mechanical machine
+	_, err = conn.Exec(`UPDATE releases SET is_preferred = 1`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE releases SET is_bios = 1 WHERE id = 2`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE releases SET is_mechanical = 1 WHERE id = 3`)
+	require.NoError(t, err)
+
+	statuses, err := scanner.GetLibraryStatusWithOptions(ctx, "test-lib", StatusOptions{})
+	require.NoError(t, err)
+	assert.Len(t, statuses, 3)
+
+	statuses, err = scanner.GetLibraryStatusWithOptions(ctx, "test-lib", StatusOptions{PlayableOnly: true})
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "Game 00 (USA)", statuses[0].ReleaseName)
+
+	exporter := NewExporter(conn, NewManager(conn))
+	data, err := exporter.ExportWithOptions(ctx, "test-lib", Report1G1R, FormatJSON, ExportOptions{PlayableOnly: true})
+	require.NoError(t, err)
+	var result ExportResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, "Game 00 (USA)", result.Records[0].Name)
+}
+
+func TestExcludeFlagged(t *testing.T) {
+	conn := setupExportTestDB(t)
+	setupExportTestData(t, conn)
+	ctx := context.Background()
+
+	_, err := conn.Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32)
+		VALUES (1, '/tmp/testlib/good.bin', 1024, 1, 'abc123', 'def456'),
+		       (1, '/tmp/testlib/Test Game (USA) [b1].bin', 1024, 1, 'bad000', 'bad000'),
+		       (1, '/tmp/testlib/Test Game (USA) [h1].bin', 1024, 1, 'hack00', 'hack00'),
+		       (1, '/tmp/testlib/Test Game (USA) [!].bin', 1024, 1, 'good00', 'good00')
+	`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type, flags)
+		VALUES (1, 1, 'sha1', NULL), (2, 1, 'name_modified', 'bad-dump'),
+		       (3, 1, 'name_modified', 'hack,fuzzy'), (4, 1, 'name', 'verified,headered')
+	`)
+	require.NoError(t, err)
+
+	exporter := NewExporter(conn, NewManager(conn))
+	matched := func(opts ExportOptions) []string {
+		t.Helper()
+		data, err := exporter.ExportWithOptions(ctx, "testlib", ReportMatched, FormatJSON, opts)
+		require.NoError(t, err)
+		var result ExportResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		var paths []string
+		for _, rec := range result.Records {
+			paths = append(paths, filepath.Base(rec.Path))
+		}
+		return paths
+	}
+
+	assert.Len(t, matched(ExportOptions{}), 4)
+	assert.Equal(t, []string{"Test Game (USA) [!].bin", "good.bin"}, matched(ExportOptions{ExcludeFlagged: true}))
+
+	// A ROM only flagged files match is left out of the have DAT
+	_, err = conn.Exec(`DELETE FROM matches WHERE flags IS NULL OR flags LIKE 'verified%'`)
+	require.NoError(t, err)
+	have, err := exporter.ExportDAT(ctx, "testlib", ExportOptions{})
+	require.NoError(t, err)
+	assert.Len(t, have.Games, 1)
+	have, err = exporter.ExportDAT(ctx, "testlib", ExportOptions{ExcludeFlagged: true})
+	require.NoError(t, err)
+	assert.Empty(t, have.Games)
+}
@@ -0,0 +1,147 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ryanm101/romman-lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ExportFixDAT builds a "fixdat": a Logiqx DAT listing only the ROMs the
+// library is missing, for feeding to download tools. Releases that are only
+// partly present list just their missing ROMs.
+func (e *Exporter) ExportFixDAT(ctx context.Context, libraryName string) (*LogiqxDAT, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.ExportFixDAT",
+		tracing.WithAttributes(attribute.String("library.name", libraryName)),
+	)
+	defer span.End()
+
+	lib, err := e.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := e.logiqxHeader(ctx, lib, "fixdat", fmt.Sprintf("Missing ROMs for library %s", lib.Name))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	dat := &LogiqxDAT{Header: header}
+
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT r.name, COALESCE(r.description, ''), re.name, COALESCE(re.size, 0),
+		       COALESCE(re.crc32, ''), COALESCE(re.md5, ''), COALESCE(re.sha1, '')
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		WHERE r.system_id = ?
+		AND re.id NOT IN (
+			SELECT m.rom_entry_id
+			FROM matches m
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			WHERE sf.library_id = ?
+		)
+		ORDER BY r.name, re.name
+	`, lib.SystemID, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var releaseName, description string
+		var rom LogiqxROM
+		if err := rows.Scan(&releaseName, &description, &rom.Name, &rom.Size, &rom.CRC32, &rom.MD5, &rom.SHA1); err != nil {
+			return nil, err
+		}
+
+		dat.addROM(releaseName, description, rom)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tracing.AddSpanAttributes(span,
+		attribute.Int("result.games", len(dat.Games)),
+		attribute.Int("result.roms", dat.ROMCount()),
+	)
+	return dat, nil
+}
+
+// ExportMissingDAT builds a Logiqx DAT of the releases the library is missing
+// entirely, as in the missing report, with every ROM's size and hashes so
+// download tools can fetch and verify them. Unlike ExportFixDAT it leaves out
+// partly present releases.
+func (e *Exporter) ExportMissingDAT(ctx context.Context, libraryName string) (*LogiqxDAT, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.ExportMissingDAT",
+		tracing.WithAttributes(attribute.String("library.name", libraryName)),
+	)
+	defer span.End()
+
+	lib, err := e.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := e.logiqxHeader(ctx, lib, "missing", fmt.Sprintf("Releases missing from library %s", lib.Name))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	dat := &LogiqxDAT{Header: header}
+
+	// #nosec G202 - the filter is a constant
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT r.name, COALESCE(r.description, ''), re.name, COALESCE(re.size, 0),
+		       COALESCE(re.crc32, ''), COALESCE(re.md5, ''), COALESCE(re.sha1, '')
+		FROM releases r
+		JOIN rom_entries re ON re.release_id = r.id
+		WHERE `+missingReleasesFilter+`
+		ORDER BY r.name, re.name
+	`, lib.SystemID, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var releaseName, description string
+		var rom LogiqxROM
+		if err := rows.Scan(&releaseName, &description, &rom.Name, &rom.Size, &rom.CRC32, &rom.MD5, &rom.SHA1); err != nil {
+			return nil, err
+		}
+		dat.addROM(releaseName, description, rom)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tracing.AddSpanAttributes(span,
+		attribute.Int("result.games", len(dat.Games)),
+		attribute.Int("result.roms", dat.ROMCount()),
+	)
+	return dat, nil
+}
+
+// logiqxHeader returns a DAT header named after the library's system DAT,
+// e.g. "Nintendo - NES (fixdat)", carrying the system's DAT version.
+func (e *Exporter) logiqxHeader(ctx context.Context, lib *Library, kind, description string) (LogiqxHeader, error) {
+	var datName, datVersion string
+	err := e.db.QueryRowContext(ctx, `
+		SELECT COALESCE(dat_name, name), COALESCE(dat_version, '') FROM systems WHERE id = ?
+	`, lib.SystemID).Scan(&datName, &datVersion)
+	if err != nil {
+		return LogiqxHeader{}, WrapDBError(err, "get system")
+	}
+
+	return LogiqxHeader{
+		Name:        fmt.Sprintf("%s (%s)", datName, kind),
+		Description: description,
+		Version:     datVersion,
+		Date:        time.Now().Format("2006-01-02"),
+		Author:      "romman",
+	}, nil
+}
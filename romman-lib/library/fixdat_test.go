@@ -0,0 +1,87 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/dat"
+)
+
+func TestExporter_ExportFixDAT(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 3)
+
+	// Game 00 is only partly present and Game 02 is missing entirely
+	_, err := database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size)
+		VALUES (1, 'Game 00 (USA) (Track 2).bin', 'abcdef0123456789abcdef0123456789abcdef01', '1234abcd', 42)
+	`)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(libPath, "game02.nes")))
+
+	_, err = NewScanner(database.Conn()).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	fixdat, err := exporter.ExportFixDAT(ctx, "test-lib")
+	require.NoError(t, err)
+
+	assert.Equal(t, "nes (fixdat)", fixdat.Header.Name)
+	require.Len(t, fixdat.Games, 2)
+	assert.Equal(t, "Game 00 (USA)", fixdat.Games[0].Name)
+	require.Len(t, fixdat.Games[0].ROMs, 1)
+	assert.Equal(t, "Game 00 (USA) (Track 2).bin", fixdat.Games[0].ROMs[0].Name)
+	assert.Equal(t, "Game 02 (USA)", fixdat.Games[1].Name)
+	assert.Equal(t, 2, fixdat.ROMCount())
+
+	// The output must be readable as a regular DAT
+	data, err := fixdat.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<!DOCTYPE datafile")
+
+	parsed, err := dat.Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "nes (fixdat)", parsed.Header.Name)
+	require.Len(t, parsed.Games, 2)
+	assert.Equal(t, "Game 02 (USA)", parsed.Games[1].Name)
+	assert.Equal(t, "Game 02 (USA)", parsed.Games[1].Description)
+	require.Len(t, parsed.Games[0].Roms, 1)
+	assert.Equal(t, int64(42), parsed.Games[0].Roms[0].Size)
+	assert.Equal(t, "1234abcd", parsed.Games[0].Roms[0].CRC32)
+}
+
+func TestExporter_ExportMissingDAT(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 3)
+
+	// Game 00 is only partly present and Game 02 is missing entirely
+	_, err := database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size)
+		VALUES (3, 'Game 02 (USA) (Track 2).bin', 'abcdef0123456789abcdef0123456789abcdef01', '1234abcd', 42),
+		       (1, 'Game 00 (USA) (Track 2).bin', '0123456789abcdef0123456789abcdef01234567', 'abcd1234', 42)
+	`)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(libPath, "game02.nes")))
+
+	_, err = NewScanner(database.Conn()).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	missing, err := exporter.ExportMissingDAT(ctx, "test-lib")
+	require.NoError(t, err)
+
+	assert.Equal(t, "nes (missing)", missing.Header.Name)
+	require.Len(t, missing.Games, 1)
+	assert.Equal(t, "Game 02 (USA)", missing.Games[0].Name)
+	require.Len(t, missing.Games[0].ROMs, 2)
+	assert.Equal(t, "Game 02 (USA) (Track 2).bin", missing.Games[0].ROMs[0].Name)
+	assert.Equal(t, "abcdef0123456789abcdef0123456789abcdef01", missing.Games[0].ROMs[0].SHA1)
+	assert.Equal(t, "Game 02 (USA).nes", missing.Games[0].ROMs[1].Name)
+	assert.NotEmpty(t, missing.Games[0].ROMs[1].SHA1)
+}
@@ -69,13 +69,13 @@ func (e *Exporter) ExportGamelist(ctx context.Context, libraryName string, opts
 
 func (e *Exporter) getMatchedGamelist(ctx context.Context, libraryID int64, opts GamelistOptions) ([]GamelistGame, error) {
 	rows, err := e.db.QueryContext(ctx, `
-		SELECT DISTINCT r.name, sf.path
+		SELECT DISTINCT r.name, sf.path, `+gamelistBoxartColumn+`
 		FROM scanned_files sf
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
-		WHERE sf.library_id = ?
-		ORDER BY r.name
+		WHERE sf.library_id = ? AND sf.virtual = 0
+		ORDER BY r.name, sf.path
 	`, libraryID)
 	if err != nil {
 		return nil, err
@@ -83,23 +83,23 @@ func (e *Exporter) getMatchedGamelist(ctx context.Context, libraryID int64, opts
 	defer func() { _ = rows.Close() }()
 
 	var games []GamelistGame
+	discSets := make(map[string]bool)
 	for rows.Next() {
-		var name, path string
-		if err := rows.Scan(&name, &path); err != nil {
+		var name, path, boxart string
+		if err := rows.Scan(&name, &path, &boxart); err != nil {
 			return nil, err
 		}
 
-		game := GamelistGame{
-			Name: name,
-			Path: formatGamelistPath(path, opts.PathPrefix),
+		// Multi-disc sets with an .m3u are listed once, pointing at the playlist
+		name, path, skip := discSetEntry(name, path, discSets)
+		if skip {
+			continue
 		}
 
-		// Add image path if directory specified
-		if opts.ImageDir != "" {
-			baseName := filepath.Base(path)
-			ext := filepath.Ext(baseName)
-			imageName := baseName[:len(baseName)-len(ext)] + "-image.png"
-			game.Image = filepath.Join(opts.ImageDir, imageName)
+		game := GamelistGame{
+			Name:  name,
+			Path:  formatGamelistPath(path, opts.PathPrefix),
+			Image: gamelistImage(path, boxart, opts),
 		}
 
 		games = append(games, game)
@@ -111,7 +111,7 @@ func (e *Exporter) getMatchedGamelist(ctx context.Context, libraryID int64, opts
 func (e *Exporter) getAllReleasesGamelist(ctx context.Context, systemID, libraryID int64, opts GamelistOptions) ([]GamelistGame, error) {
 	// Get all releases, left join to matches to include status
 	rows, err := e.db.QueryContext(ctx, `
-		SELECT r.name, COALESCE(sf.path, '') as path
+		SELECT r.name, COALESCE(MIN(sf.path), '') as path, `+gamelistBoxartColumn+`
 		FROM releases r
 		LEFT JOIN rom_entries re ON re.release_id = r.id
 		LEFT JOIN matches m ON m.rom_entry_id = re.id
@@ -126,24 +126,27 @@ func (e *Exporter) getAllReleasesGamelist(ctx context.Context, systemID, library
 	defer func() { _ = rows.Close() }()
 
 	var games []GamelistGame
+	discSets := make(map[string]bool)
 	for rows.Next() {
-		var name, path string
-		if err := rows.Scan(&name, &path); err != nil {
+		var name, path, boxart string
+		if err := rows.Scan(&name, &path, &boxart); err != nil {
 			return nil, err
 		}
 
+		if path != "" {
+			var skip bool
+			if name, path, skip = discSetEntry(name, path, discSets); skip {
+				continue
+			}
+		}
+
 		game := GamelistGame{
 			Name: name,
 		}
 
 		if path != "" {
 			game.Path = formatGamelistPath(path, opts.PathPrefix)
-			if opts.ImageDir != "" {
-				baseName := filepath.Base(path)
-				ext := filepath.Ext(baseName)
-				imageName := baseName[:len(baseName)-len(ext)] + "-image.png"
-				game.Image = filepath.Join(opts.ImageDir, imageName)
-			}
+			game.Image = gamelistImage(path, boxart, opts)
 		}
 
 		games = append(games, game)
@@ -152,6 +155,25 @@ func (e *Exporter) getAllReleasesGamelist(ctx context.Context, systemID, library
 	return games, nil
 }
 
+// gamelistBoxartColumn selects the local boxart scraped for release r.
+const gamelistBoxartColumn = `COALESCE((SELECT gm.local_path FROM game_media gm
+			WHERE gm.release_id = r.id AND gm.type = 'boxart' AND gm.local_path != '' LIMIT 1), '')`
+
+// gamelistImage returns a game's image when an image directory is set: the
+// boxart scraped for the release if there is one, such as an image from a
+// thumbnails pack, or else <rom name>-image.png in the directory.
+func gamelistImage(path, boxart string, opts GamelistOptions) string {
+	if opts.ImageDir == "" {
+		return ""
+	}
+	if boxart != "" {
+		return boxart
+	}
+	baseName := filepath.Base(path)
+	ext := filepath.Ext(baseName)
+	return filepath.Join(opts.ImageDir, baseName[:len(baseName)-len(ext)]+"-image.png")
+}
+
 func formatGamelistPath(path, prefix string) string {
 	if prefix != "" {
 		return prefix + filepath.Base(path)
@@ -69,6 +69,41 @@ func TestExportGamelist(t *testing.T) {
 	assert.Contains(t, string(result), "</gameList>")
 }
 
+func TestExportGamelist_ScrapedBoxart(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	for _, stmt := range []string{
+		"INSERT INTO systems (name) VALUES ('nes')",
+		"INSERT INTO releases (system_id, name) VALUES (1, 'Super Mario Bros (USA)')",
+		"INSERT INTO releases (system_id, name) VALUES (1, 'Tetris (USA)')",
+		"INSERT INTO libraries (name, root_path, system_id) VALUES ('nes', '/roms/nes', 1)",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/smb.nes', 1024, 0, 'abc')",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/tetris.nes', 1024, 0, 'def')",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (1, 'Super Mario Bros (USA).nes', 'abc', 1024)",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (2, 'Tetris (USA).nes', 'def', 1024)",
+		"INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (2, 2, 'sha1')",
+		"INSERT INTO game_media (release_id, type, url, local_path) VALUES (1, 'boxart', '', '/thumbs/Named_Boxarts/Super Mario Bros (USA).png')",
+	} {
+		_, err = database.Conn().Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	for _, matchedOnly := range []bool{true, false} {
+		result, err := exporter.ExportGamelist(context.Background(), "nes", GamelistOptions{MatchedOnly: matchedOnly, ImageDir: "./images"})
+		require.NoError(t, err)
+		assert.Contains(t, string(result), "<image>/thumbs/Named_Boxarts/Super Mario Bros (USA).png</image>")
+		assert.Contains(t, string(result), "<image>images/tetris-image.png</image>")
+	}
+
+	// Without an image directory no images are listed
+	result, err := exporter.ExportGamelist(context.Background(), "nes", GamelistOptions{MatchedOnly: true})
+	require.NoError(t, err)
+	assert.NotContains(t, string(result), "<image>")
+}
+
 func TestExportLaunchBox(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -166,3 +201,97 @@ func TestFormatPlatformName(t *testing.T) {
 		})
 	}
 }
+
+func TestExportPegasus(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	for _, stmt := range []string{
+		"INSERT INTO systems (name) VALUES ('nes')",
+		"INSERT INTO releases (system_id, name) VALUES (1, 'Super Mario Bros (USA)')",
+		"INSERT INTO releases (system_id, name) VALUES (1, 'Tetris (USA)')",
+		"INSERT INTO releases (system_id, name) VALUES (1, 'Zelda (USA)')",
+		"INSERT INTO libraries (name, root_path, system_id) VALUES ('nes', '/roms/nes', 1)",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/smb.nes', 1024, 0, 'abc')",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/tetris.nes', 1024, 0, 'def')",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (1, 'Super Mario Bros (USA).nes', 'abc', 1024)",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (2, 'Tetris (USA).nes', 'def', 1024)",
+		"INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (2, 2, 'sha1')",
+		`INSERT INTO game_metadata (release_id, developer, publisher, genre, release_date, description)
+			VALUES (1, 'Nintendo', 'Nintendo', 'Platform', '1985-09-13', 'Save the princess.
+
+Again.')`,
+	} {
+		_, err = database.Conn().Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	result, err := exporter.ExportPegasus(context.Background(), "nes", PegasusOptions{PathPrefix: "./"})
+	require.NoError(t, err)
+
+	// Missing releases are left out, and so are empty fields
+	assert.Equal(t, `collection: Nintendo Entertainment System
+shortname: nes
+
+game: Super Mario Bros (USA)
+file: ./smb.nes
+developer: Nintendo
+publisher: Nintendo
+genre: Platform
+release: 1985-09-13
+description: Save the princess.
+  .
+  Again.
+
+game: Tetris (USA)
+file: ./tetris.nes
+`, string(result))
+
+	// Without a prefix files keep their full paths
+	result, err = exporter.ExportPegasus(context.Background(), "nes", PegasusOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(result), "file: /roms/nes/smb.nes\n")
+}
+
+func TestExportESDECollection(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	for _, stmt := range []string{
+		"INSERT INTO systems (name) VALUES ('nes')",
+		"INSERT INTO releases (system_id, name, is_preferred) VALUES (1, 'Super Mario Bros (USA)', 1)",
+		"INSERT INTO releases (system_id, name, is_preferred) VALUES (1, 'Super Mario Bros (Japan)', 0)",
+		"INSERT INTO releases (system_id, name, is_preferred) VALUES (1, 'Tetris (USA)', 1)",
+		"INSERT INTO libraries (name, root_path, system_id) VALUES ('nes', '/roms/nes', 1)",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/smb.nes', 1024, 0, 'abc')",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1) VALUES (1, '/roms/nes/Japan/smb.nes', 1024, 0, 'def')",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1, archive_path) VALUES (1, '/other/tetris.zip', 1024, 0, 'ghi', 'a.nes')",
+		"INSERT INTO scanned_files (library_id, path, size, mtime, sha1, archive_path) VALUES (1, '/other/tetris.zip', 1024, 0, 'ghi', 'b.nes')",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (1, 'Super Mario Bros (USA).nes', 'abc', 1024)",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (2, 'Super Mario Bros (Japan).nes', 'def', 1024)",
+		"INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (3, 'Tetris (USA).nes', 'ghi', 1024)",
+		"INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (2, 2, 'sha1'), (3, 3, 'sha1'), (4, 3, 'sha1')",
+	} {
+		_, err = database.Conn().Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	result, err := exporter.ExportESDECollection(context.Background(), "nes", ESDEOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "/roms/nes/Japan/smb.nes\n/roms/nes/smb.nes\n/other/tetris.zip\n", string(result))
+
+	// Files under the ROM directory are written relative to %ROMPATH%
+	result, err = exporter.ExportESDECollection(context.Background(), "nes", ESDEOptions{PreferredOnly: true, ROMPath: "/roms"})
+	require.NoError(t, err)
+	assert.Equal(t, "%ROMPATH%/nes/smb.nes\n/other/tetris.zip\n", string(result))
+
+	name, err := ESDECollectionFile("nes favourites")
+	require.NoError(t, err)
+	assert.Equal(t, "custom-nes favourites.cfg", name)
+	_, err = ESDECollectionFile("../nes")
+	assert.ErrorIs(t, err, ErrInvalidArg)
+}
@@ -0,0 +1,307 @@
+package library
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HashListFormat identifies the layout of an imported hash list.
+type HashListFormat string
+
+const (
+	HashListSFV   HashListFormat = "sfv"   // "filename CRC32" lines, ';' comments
+	HashListCSV   HashListFormat = "csv"   // Columns named sha1/crc32/path/size, or recognised by shape
+	HashListLines HashListFormat = "lines" // "hash path" lines, as written by sha1sum
+)
+
+// HashListEntry is one file from an imported hash list.
+type HashListEntry struct {
+	Path  string // Optional; a virtual path is generated when empty
+	SHA1  string
+	CRC32 string
+	Size  int64
+}
+
+// HashImportResult contains the results of importing a hash list.
+type HashImportResult struct {
+	LibraryName    string `json:"libraryName"`
+	Imported       int    `json:"imported"`
+	Skipped        int    `json:"skipped"` // Lines without a usable hash
+	MatchesFound   int    `json:"matchesFound"`
+	UnmatchedFiles int    `json:"unmatchedFiles"`
+}
+
+// DetectHashListFormat picks a format from a hash list's file extension.
+func DetectHashListFormat(filename string) HashListFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".sfv":
+		return HashListSFV
+	case ".csv":
+		return HashListCSV
+	default:
+		return HashListLines
+	}
+}
+
+// ParseHashList reads a hash list. Lines without a SHA1 or CRC32 are
+// skipped and counted.
+func ParseHashList(r io.Reader, format HashListFormat) ([]HashListEntry, int, error) {
+	switch format {
+	case HashListSFV:
+		return parseSFV(r)
+	case HashListCSV:
+		return parseHashCSV(r)
+	case HashListLines:
+		return parseHashLines(r)
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown hash list format %q", ErrInvalidArg, format)
+	}
+}
+
+// classifyHash reports whether s is a SHA1 or CRC32 hex string.
+func classifyHash(s string) (sha1Hash, crc32Hash string) {
+	s = strings.ToLower(s)
+	if !isHex(s) {
+		return "", ""
+	}
+	switch len(s) {
+	case 40:
+		return s, ""
+	case 8:
+		return "", s
+	}
+	return "", ""
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func parseSFV(r io.Reader) ([]HashListEntry, int, error) {
+	var entries []HashListEntry
+	skipped := 0
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		idx := strings.LastIndexAny(line, " \t")
+		if idx < 0 {
+			skipped++
+			continue
+		}
+		_, crc := classifyHash(line[idx+1:])
+		if crc == "" {
+			skipped++
+			continue
+		}
+		entries = append(entries, HashListEntry{Path: strings.TrimSpace(line[:idx]), CRC32: crc})
+	}
+	return entries, skipped, sc.Err()
+}
+
+func parseHashLines(r io.Reader) ([]HashListEntry, int, error) {
+	var entries []HashListEntry
+	skipped := 0
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, path, _ := strings.Cut(line, " ")
+		sha1Hash, crc32Hash := classifyHash(hash)
+		if sha1Hash == "" && crc32Hash == "" {
+			skipped++
+			continue
+		}
+		// sha1sum marks binary-mode files with a leading '*'
+		path = strings.TrimPrefix(strings.TrimSpace(path), "*")
+		entries = append(entries, HashListEntry{Path: path, SHA1: sha1Hash, CRC32: crc32Hash})
+	}
+	return entries, skipped, sc.Err()
+}
+
+func parseHashCSV(r io.Reader) ([]HashListEntry, int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var entries []HashListEntry
+	var columns map[string]int
+	skipped := 0
+
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, skipped, err
+		}
+
+		if first {
+			if columns = csvHeaderColumns(record); columns != nil {
+				continue
+			}
+		}
+
+		var entry HashListEntry
+		if columns != nil {
+			entry = csvEntryFromColumns(record, columns)
+		} else {
+			entry = csvEntryByShape(record)
+		}
+		if entry.SHA1 == "" && entry.CRC32 == "" {
+			skipped++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, skipped, nil
+}
+
+// csvHeaderColumns maps known column names to indexes, or returns nil if record is not a header.
+func csvHeaderColumns(record []string) map[string]int {
+	aliases := map[string]string{
+		"sha1": "sha1", "crc32": "crc32", "crc": "crc32",
+		"path": "path", "file": "path", "filename": "path", "name": "path",
+		"size": "size",
+	}
+	columns := make(map[string]int)
+	for i, field := range record {
+		if col, ok := aliases[strings.ToLower(strings.TrimSpace(field))]; ok {
+			columns[col] = i
+		}
+	}
+	if _, ok := columns["sha1"]; ok {
+		return columns
+	}
+	if _, ok := columns["crc32"]; ok {
+		return columns
+	}
+	return nil
+}
+
+func csvEntryFromColumns(record []string, columns map[string]int) HashListEntry {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entry HashListEntry
+	entry.SHA1, _ = classifyHash(field("sha1"))
+	_, entry.CRC32 = classifyHash(field("crc32"))
+	entry.Path = field("path")
+	entry.Size, _ = strconv.ParseInt(field("size"), 10, 64)
+	return entry
+}
+
+// csvEntryByShape recognises SHA1, CRC32 and size fields by their format; the remaining field is the path.
+func csvEntryByShape(record []string) HashListEntry {
+	var entry HashListEntry
+	for _, field := range record {
+		field = strings.TrimSpace(field)
+		if sha1Hash, crc32Hash := classifyHash(field); sha1Hash != "" && entry.SHA1 == "" {
+			entry.SHA1 = sha1Hash
+		} else if crc32Hash != "" && entry.CRC32 == "" {
+			entry.CRC32 = crc32Hash
+		} else if size, err := strconv.ParseInt(field, 10, 64); err == nil && entry.Size == 0 {
+			entry.Size = size
+		} else if entry.Path == "" {
+			entry.Path = field
+		}
+	}
+	return entry
+}
+
+// virtualPath returns the stored path for an imported entry. Entries without
+// a path are keyed by their hash so they stay distinct.
+func (e HashListEntry) virtualPath() string {
+	if e.Path != "" {
+		return e.Path
+	}
+	if e.SHA1 != "" {
+		return "virtual:" + e.SHA1
+	}
+	return "virtual:" + e.CRC32
+}
+
+// ImportHashes replaces the library's virtual files with the given hash list
+// entries and matches them, so completion can be reported without hashing
+// files on disk. Virtual files are skipped by verify, cleanup and organize.
+func (s *Scanner) ImportHashes(ctx context.Context, libraryName string, entries []HashListEntry) (*HashImportResult, error) {
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scanned_files WHERE library_id = ? AND virtual = 1`, lib.ID); err != nil {
+		return nil, WrapDBError(err, "clear virtual files")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32, virtual)
+		VALUES (?, ?, ?, 0, ?, ?, 1)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		path := e.virtualPath()
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		if _, err := stmt.ExecContext(ctx, lib.ID, path, e.Size, e.SHA1, e.CRC32); err != nil {
+			return nil, WrapDBError(err, "import hash")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	matches, err := s.matchFiles(ctx, lib)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match imported hashes: %w", err)
+	}
+
+	return &HashImportResult{
+		LibraryName:    libraryName,
+		Imported:       len(seen),
+		MatchesFound:   matches.MatchesFound,
+		UnmatchedFiles: matches.UnmatchedFiles,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSHA1  = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+	testCRC32 = "0000abcd"
+)
+
+func TestDetectHashListFormat(t *testing.T) {
+	assert.Equal(t, HashListSFV, DetectHashListFormat("have.SFV"))
+	assert.Equal(t, HashListCSV, DetectHashListFormat("have.csv"))
+	assert.Equal(t, HashListLines, DetectHashListFormat("have.sha1"))
+	assert.Equal(t, HashListLines, DetectHashListFormat("have.txt"))
+}
+
+func TestParseHashList(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  HashListFormat
+		input   string
+		want    []HashListEntry
+		skipped int
+	}{
+		{
+			name:   "sfv",
+			format: HashListSFV,
+			input:  "; generated by tool\nGame One (USA).nes 0000ABCD\nbroken line\n",
+			want: []HashListEntry{
+				{Path: "Game One (USA).nes", CRC32: testCRC32},
+			},
+			skipped: 1,
+		},
+		{
+			name:   "sha1sum lines",
+			format: HashListLines,
+			input:  testSHA1 + " *Game One (USA).nes\n# comment\n" + testCRC32 + "\nnot-a-hash file.nes\n",
+			want: []HashListEntry{
+				{Path: "Game One (USA).nes", SHA1: testSHA1},
+				{CRC32: testCRC32},
+			},
+			skipped: 1,
+		},
+		{
+			name:   "csv with header",
+			format: HashListCSV,
+			input:  "Name,Size,CRC,SHA1\n\"Game, The (USA).nes\",1024," + testCRC32 + "," + strings.ToUpper(testSHA1) + "\nx,1,,\n",
+			want: []HashListEntry{
+				{Path: "Game, The (USA).nes", Size: 1024, CRC32: testCRC32, SHA1: testSHA1},
+			},
+			skipped: 1,
+		},
+		{
+			name:   "csv without header",
+			format: HashListCSV,
+			input:  testSHA1 + ",Game.nes," + testCRC32 + ",2048\n",
+			want: []HashListEntry{
+				{Path: "Game.nes", Size: 2048, CRC32: testCRC32, SHA1: testSHA1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, skipped, err := ParseHashList(strings.NewReader(tt.input), tt.format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, entries)
+			assert.Equal(t, tt.skipped, skipped)
+		})
+	}
+}
+
+func TestScanner_ImportHashes(t *testing.T) {
+	ctx := context.Background()
+	database, _ := setupCheckpointLibrary(t, 3)
+
+	// The hash list overlaps the files already scanned from disk
+	scanner := NewScanner(database.Conn())
+	_, err := scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	var list strings.Builder
+	for i := 0; i < 3; i++ {
+		sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(fmt.Sprintf("rom content %d", i)))
+		require.NoError(t, err)
+		if i == 1 {
+			fmt.Fprintf(&list, "%s\n", crc32Hash)
+		} else {
+			fmt.Fprintf(&list, "%s other/game%02d.nes\n", sha1Hash, i)
+		}
+	}
+	list.WriteString(testSHA1 + " unknown.nes\n")
+
+	entries, skipped, err := ParseHashList(strings.NewReader(list.String()), HashListLines)
+	require.NoError(t, err)
+	assert.Equal(t, 0, skipped)
+
+	result, err := scanner.ImportHashes(ctx, "test-lib", entries)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Imported)
+	assert.Equal(t, 6, result.MatchesFound)
+	assert.Equal(t, 1, result.UnmatchedFiles)
+	assert.Equal(t, 4, countRows(t, database, `SELECT COUNT(*) FROM scanned_files WHERE virtual = 1`))
+
+	// Re-importing replaces the virtual files rather than adding to them
+	_, err = scanner.ImportHashes(ctx, "test-lib", entries[:1])
+	require.NoError(t, err)
+	assert.Equal(t, 1, countRows(t, database, `SELECT COUNT(*) FROM scanned_files WHERE virtual = 1`))
+
+	// Rescanning keeps virtual files even though they don't exist on disk
+	_, err = scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, countRows(t, database, `SELECT COUNT(*) FROM scanned_files WHERE virtual = 1`))
+
+	// A virtual copy of a physical file is not a duplicate to clean up
+	lib, err := NewManager(database.Conn()).Get(ctx, "test-lib")
+	require.NoError(t, err)
+	dups, err := NewDuplicateFinder(database.Conn()).FindAllDuplicates(ctx, lib.ID)
+	require.NoError(t, err)
+	assert.Empty(t, dups)
+}
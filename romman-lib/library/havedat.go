@@ -0,0 +1,79 @@
+package library
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ryanm101/romman-lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ExportDAT builds a Logiqx DAT of the library's "have" set: a game for each
+// release with at least one matched ROM, listing the matched ROMs under their
+// DAT names with the size and hashes of the scanned files. External tools
+// can diff it against a master DAT. Where several files match one ROM, the
+// first scanned is used. With ExcludeFlagged, ROMs only matched by flagged
+// files are left out, so they show as missing.
+func (e *Exporter) ExportDAT(ctx context.Context, libraryName string, opts ExportOptions) (*LogiqxDAT, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.ExportDAT",
+		tracing.WithAttributes(attribute.String("library.name", libraryName)),
+	)
+	defer span.End()
+
+	lib, err := e.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := e.logiqxHeader(ctx, lib, "have", fmt.Sprintf("ROMs present in library %s", lib.Name))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	dat := &LogiqxDAT{Header: header}
+
+	unflagged := ""
+	if opts.ExcludeFlagged {
+		unflagged = "AND " + unflaggedMatchClause
+	}
+
+	// #nosec G202 - only a constant clause is concatenated
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT r.name, COALESCE(r.description, ''), re.name, sf.size,
+		       COALESCE(sf.crc32, ''), COALESCE(sf.md5, ''), COALESCE(sf.sha1, '')
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		JOIN scanned_files sf ON sf.id = (
+			SELECT MIN(m.scanned_file_id)
+			FROM matches m
+			JOIN scanned_files msf ON msf.id = m.scanned_file_id
+			WHERE m.rom_entry_id = re.id AND msf.library_id = ?
+			  `+unflagged+`
+		)
+		ORDER BY r.name, re.name
+	`, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var releaseName, description string
+		var rom LogiqxROM
+		if err := rows.Scan(&releaseName, &description, &rom.Name, &rom.Size, &rom.CRC32, &rom.MD5, &rom.SHA1); err != nil {
+			return nil, err
+		}
+
+		dat.addROM(releaseName, description, rom)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tracing.AddSpanAttributes(span,
+		attribute.Int("result.games", len(dat.Games)),
+		attribute.Int("result.roms", dat.ROMCount()),
+	)
+	return dat, nil
+}
@@ -0,0 +1,61 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/dat"
+)
+
+func TestExporter_ExportDAT(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 3)
+
+	// Game 00 is only partly present and Game 02 is missing entirely
+	_, err := database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size)
+		VALUES (1, 'Game 00 (USA) (Track 2).bin', 'abcdef0123456789abcdef0123456789abcdef01', '1234abcd', 42)
+	`)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(libPath, "game02.nes")))
+
+	_, err = NewScanner(database.Conn()).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	exporter := NewExporter(database.Conn(), NewManager(database.Conn()))
+	have, err := exporter.ExportDAT(ctx, "test-lib", ExportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "nes (have)", have.Header.Name)
+	require.Len(t, have.Games, 2)
+	assert.Equal(t, "Game 00 (USA)", have.Games[0].Name)
+	require.Len(t, have.Games[0].ROMs, 1)
+	assert.Equal(t, "Game 00 (USA).nes", have.Games[0].ROMs[0].Name)
+	assert.Equal(t, "Game 01 (USA)", have.Games[1].Name)
+
+	content, err := os.ReadFile(filepath.Join(libPath, "game01.nes"))
+	require.NoError(t, err)
+	sha1Hash, crc32Hash, md5Hash, err := computeHashes(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, LogiqxROM{
+		Name:  "Game 01 (USA).nes",
+		Size:  int64(len(content)),
+		CRC32: crc32Hash,
+		MD5:   md5Hash,
+		SHA1:  sha1Hash,
+	}, have.Games[1].ROMs[0])
+
+	data, err := have.Marshal()
+	require.NoError(t, err)
+	parsed, err := dat.Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "nes (have)", parsed.Header.Name)
+	require.Len(t, parsed.Games, 2)
+	assert.Equal(t, sha1Hash, parsed.Games[1].Roms[0].SHA1)
+}
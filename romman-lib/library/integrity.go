@@ -8,12 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 // IntegrityIssue represents a detected integrity problem.
 type IntegrityIssue struct {
 	Path      string
-	IssueType string // "changed", "missing", "incomplete"
+	IssueType string // "changed", "missing", "incomplete", "crc-mismatch", "chd-corrupt", "chd-mismatch"
 	Details   string
 }
 
@@ -25,6 +26,13 @@ type IntegrityResult struct {
 	Changed      int
 	Missing      int
 	Incomplete   int
+	CRCMismatch  int // Zip entries whose header CRC differs from their data
+	CHDCorrupt   int // CHDs that are truncated or fail their header checks
+	CHDMismatch  int // CHDs whose data SHA1 differs from their DAT entry's
+
+	// Renames holds the renames Repair made, or would make in a dry run.
+	// It is nil for Check.
+	Renames *RenameResult
 }
 
 // IntegrityChecker verifies library file integrity.
@@ -44,24 +52,58 @@ func (c *IntegrityChecker) Check(ctx context.Context, libraryName string) (*Inte
 	if err != nil {
 		return nil, err
 	}
+	result, _, err := c.check(ctx, lib)
+	return result, err
+}
 
+// Repair verifies a library like Check, then renames the files whose hash
+// still matches to their DAT names, as Renamer does. Changed files are only
+// reported: their stored match no longer describes them.
+func (c *IntegrityChecker) Repair(ctx context.Context, libraryName string, dryRun bool) (*IntegrityResult, error) {
+	lib, err := c.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+	result, verified, err := c.check(ctx, lib)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Renames, err = NewRenamer(c.db, c.manager).renameFiles(ctx, lib, dryRun, verified)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// check verifies a library's files, also returning the ids of those whose
+// hash still matches.
+func (c *IntegrityChecker) check(ctx context.Context, lib *Library) (*IntegrityResult, map[int64]bool, error) {
 	result := &IntegrityResult{}
+	verified := make(map[int64]bool)
 
 	// Get all scanned files (non-archive only for now)
 	rows, err := c.db.QueryContext(ctx, `
-		SELECT id, path, sha1, size FROM scanned_files
-		WHERE library_id = ? AND archive_path IS NULL
+		SELECT sf.id, sf.path, sf.sha1, sf.size,
+			COALESCE((
+				SELECT re.sha1 FROM matches m
+				JOIN rom_entries re ON re.id = m.rom_entry_id
+				WHERE m.scanned_file_id = sf.id AND re.sha1 != ''
+				LIMIT 1
+			), '')
+		FROM scanned_files sf
+		WHERE sf.library_id = ? AND sf.archive_path IS NULL AND sf.virtual = 0
 	`, lib.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
 	for rows.Next() {
 		var fileID int64
-		var path, storedHash string
+		var path, storedHash, datSHA1 string
 		var storedSize int64
-		if err := rows.Scan(&fileID, &path, &storedHash, &storedSize); err != nil {
+		if err := rows.Scan(&fileID, &path, &storedHash, &storedSize, &datSHA1); err != nil {
 			continue
 		}
 
@@ -87,6 +129,13 @@ func (c *IntegrityChecker) Check(ctx context.Context, libraryName string) (*Inte
 			continue
 		}
 
+		if IsCHDFile(path) {
+			if checkCHD(result, path, storedHash, datSHA1) {
+				verified[fileID] = true
+			}
+			continue
+		}
+
 		// Check size first (fast check)
 		if info.Size() != storedSize {
 			result.Issues = append(result.Issues, IntegrityIssue{
@@ -118,8 +167,21 @@ func (c *IntegrityChecker) Check(ctx context.Context, libraryName string) (*Inte
 			result.Changed++
 		} else {
 			result.OK++
+			verified[fileID] = true
 		}
 	}
+	_ = rows.Close()
+
+	// Zip and 7z entries whose declared CRC differs from the hashed data point to a
+	// corrupt or badly repacked archive
+	mismatches, err := c.checkZipCRCs(ctx, lib.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, issue := range mismatches {
+		result.Issues = append(result.Issues, issue)
+		result.CRCMismatch++
+	}
 
 	// Check for incomplete multi-file games
 	incompleteReleases, err := c.checkIncomplete(ctx, lib.ID)
@@ -134,7 +196,75 @@ func (c *IntegrityChecker) Check(ctx context.Context, libraryName string) (*Inte
 		}
 	}
 
-	return result, nil
+	return result, verified, nil
+}
+
+// checkCHD verifies a CHD by its header rather than by hashing the whole
+// file, recording any issue in result. The data SHA1 in the header is what
+// DATs list, and recompressing a CHD changes its size and file hash but not
+// its data SHA1. It reports whether the CHD verified.
+func checkCHD(result *IntegrityResult, path, storedHash, datSHA1 string) bool {
+	info, err := VerifyCHD(path)
+	if err != nil {
+		result.Issues = append(result.Issues, IntegrityIssue{
+			Path:      path,
+			IssueType: "chd-corrupt",
+			Details:   err.Error(),
+		})
+		result.CHDCorrupt++
+		return false
+	}
+
+	switch {
+	case datSHA1 != "" && !strings.EqualFold(info.DataSHA1, datSHA1):
+		result.Issues = append(result.Issues, IntegrityIssue{
+			Path:      path,
+			IssueType: "chd-mismatch",
+			Details:   fmt.Sprintf("data SHA1 %s, DAT expects %s", info.DataSHA1, strings.ToLower(datSHA1)),
+		})
+		result.CHDMismatch++
+		return false
+	case datSHA1 == "" && info.DataSHA1 != storedHash:
+		result.Issues = append(result.Issues, IntegrityIssue{
+			Path:      path,
+			IssueType: "changed",
+			Details:   "data SHA1 mismatch",
+		})
+		result.Changed++
+		return false
+	}
+	result.OK++
+	return true
+}
+
+// checkZipCRCs compares the CRC32 stored in each zip or 7z entry header with
+// the CRC32 computed from its data during the scan.
+func (c *IntegrityChecker) checkZipCRCs(ctx context.Context, libraryID int64) ([]IntegrityIssue, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT path, archive_path, COALESCE(crc32, ''), zip_crc32
+		FROM scanned_files
+		WHERE library_id = ? AND archive_path IS NOT NULL AND virtual = 0
+		  AND zip_crc32 != '' AND zip_crc32 != COALESCE(crc32, '')
+		ORDER BY path, archive_path
+	`, libraryID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		var path, archivePath, computed, declared string
+		if err := rows.Scan(&path, &archivePath, &computed, &declared); err != nil {
+			return nil, err
+		}
+		issues = append(issues, IntegrityIssue{
+			Path:      path + "#" + archivePath,
+			IssueType: "crc-mismatch",
+			Details:   fmt.Sprintf("zip header CRC32 %s, data CRC32 %s", declared, computed),
+		})
+	}
+	return issues, rows.Err()
 }
 
 type incompleteRelease struct {
@@ -1,11 +1,15 @@
 package library
 
 import (
+	"context"
+	"crypto/sha1" // #nosec G505
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHashFile(t *testing.T) {
@@ -50,3 +54,93 @@ func TestIntegrityResultDefaults(t *testing.T) {
 	assert.Equal(t, 0, result.Incomplete)
 	assert.Empty(t, result.Issues)
 }
+
+func TestIntegrityChecker_Repair(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 3)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	_, err := NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	// game02 is corrupted after the scan; the others only have drifted names
+	corrupt := filepath.Join(libPath, "game02.nes")
+	require.NoError(t, os.WriteFile(corrupt, []byte("rom content X"), 0644)) // #nosec G306
+
+	checker := NewIntegrityChecker(conn, NewManager(conn))
+	result, err := checker.Repair(ctx, "test-lib", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.OK)
+	assert.Equal(t, 1, result.Changed)
+	require.NotNil(t, result.Renames)
+	require.Len(t, result.Renames.Actions, 2)
+	for _, a := range result.Renames.Actions {
+		assert.Equal(t, "pending", a.Status)
+		assert.NotEqual(t, corrupt, a.OldPath, "corrupt files are only reported")
+	}
+	assert.FileExists(t, filepath.Join(libPath, "game00.nes"), "dry run leaves files alone")
+
+	result, err = checker.Repair(ctx, "test-lib", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Renames.Renamed)
+	assert.FileExists(t, filepath.Join(libPath, "Game 00 (USA).nes"))
+	assert.FileExists(t, filepath.Join(libPath, "Game 01 (USA).nes"))
+	assert.FileExists(t, corrupt)
+
+	// The renamed files still verify at their new paths
+	result, err = checker.Check(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.OK)
+	assert.Equal(t, 1, result.Changed)
+	assert.Nil(t, result.Renames)
+}
+
+func TestIntegrityChecker_CHD(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 0)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	dataSHA1 := sha1.Sum([]byte("disc data"))   // #nosec G401
+	otherSHA1 := sha1.Sum([]byte("other disc")) // #nosec G401
+	games := []struct {
+		file    string
+		datSHA1 [20]byte
+	}{
+		{"good.chd", dataSHA1},
+		{"wrong.chd", otherSHA1},
+		{"truncated.chd", dataSHA1},
+	}
+	for i, g := range games {
+		path := filepath.Join(libPath, g.file)
+		writeTestCHD(t, path, dataSHA1)
+		_, err := conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, i+1, g.file)
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1, size) VALUES (?, ?, ?, ?, 0)`,
+			i+1, i+1, g.file, hex.EncodeToString(g.datSHA1[:]))
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1) VALUES (?, 1, ?, 0, 0, ?)`,
+			i+1, path, hex.EncodeToString(dataSHA1[:]))
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (?, ?, 'sha1')`, i+1, i+1)
+		require.NoError(t, err)
+	}
+
+	truncated := filepath.Join(libPath, "truncated.chd")
+	content, err := os.ReadFile(truncated) // #nosec G304
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(truncated, content[:len(content)-10], 0644)) // #nosec G306
+
+	result, err := NewIntegrityChecker(conn, NewManager(conn)).Check(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.FilesChecked)
+	assert.Equal(t, 1, result.OK, "the CHD's size differs from the stored size, but its data still verifies")
+	assert.Equal(t, 1, result.CHDMismatch)
+	assert.Equal(t, 1, result.CHDCorrupt)
+	assert.Equal(t, 0, result.Changed)
+
+	types := make(map[string]string)
+	for _, issue := range result.Issues {
+		types[filepath.Base(issue.Path)] = issue.IssueType
+	}
+	assert.Equal(t, map[string]string{"wrong.chd": "chd-mismatch", "truncated.chd": "chd-corrupt"}, types)
+}
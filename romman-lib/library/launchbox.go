@@ -72,8 +72,8 @@ func (e *Exporter) getMatchedLaunchBox(ctx context.Context, libraryID int64, sys
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
-		WHERE sf.library_id = ?
-		ORDER BY r.name
+		WHERE sf.library_id = ? AND sf.virtual = 0
+		ORDER BY r.name, sf.path
 	`, libraryID)
 	if err != nil {
 		return nil, err
@@ -103,7 +103,7 @@ func (e *Exporter) getMatchedLaunchBox(ctx context.Context, libraryID int64, sys
 
 func (e *Exporter) getAllReleasesLaunchBox(ctx context.Context, systemID, libraryID int64, systemName string, opts LaunchBoxOptions) ([]LBGame, error) {
 	rows, err := e.db.QueryContext(ctx, `
-		SELECT r.name, COALESCE(sf.path, '') as path
+		SELECT r.name, COALESCE(MIN(sf.path), '') as path
 		FROM releases r
 		LEFT JOIN rom_entries re ON re.release_id = r.id
 		LEFT JOIN matches m ON m.rom_entry_id = re.id
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
+	"unicode/utf8"
 )
 
 // Library represents a ROM collection directory.
@@ -16,6 +19,9 @@ type Library struct {
 	SystemName string
 	CreatedAt  time.Time
 	LastScanAt *time.Time
+	// MultiSystem libraries hold several systems in top-level subdirectories;
+	// each file's system is detected from its subdirectory name, falling back to SystemID.
+	MultiSystem bool
 }
 
 // Manager handles library operations.
@@ -70,13 +76,13 @@ func (m *Manager) Get(ctx context.Context, name string) (*Library, error) {
 	var lastScanAt sql.NullTime
 
 	err := m.db.QueryRowContext(ctx, `
-		SELECT l.id, l.name, l.root_path, l.system_id, s.name, l.created_at, l.last_scan_at
+		SELECT l.id, l.name, l.root_path, l.system_id, s.name, l.created_at, l.last_scan_at, l.multi_system
 		FROM libraries l
 		JOIN systems s ON l.system_id = s.id
 		WHERE l.name = ?
-	`, name).Scan(&lib.ID, &lib.Name, &lib.RootPath, &lib.SystemID, &lib.SystemName, &lib.CreatedAt, &lastScanAt)
+	`, name).Scan(&lib.ID, &lib.Name, &lib.RootPath, &lib.SystemID, &lib.SystemName, &lib.CreatedAt, &lastScanAt, &lib.MultiSystem)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("library not found: %s", name)
+		return nil, fmt.Errorf("library %w: %s", ErrNotFound, name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get library: %w", err)
@@ -92,7 +98,7 @@ func (m *Manager) Get(ctx context.Context, name string) (*Library, error) {
 // List returns all libraries.
 func (m *Manager) List(ctx context.Context) ([]*Library, error) {
 	rows, err := m.db.QueryContext(ctx, `
-		SELECT l.id, l.name, l.root_path, l.system_id, s.name, l.created_at, l.last_scan_at
+		SELECT l.id, l.name, l.root_path, l.system_id, s.name, l.created_at, l.last_scan_at, l.multi_system
 		FROM libraries l
 		JOIN systems s ON l.system_id = s.id
 		ORDER BY l.name
@@ -106,7 +112,7 @@ func (m *Manager) List(ctx context.Context) ([]*Library, error) {
 	for rows.Next() {
 		lib := &Library{}
 		var lastScanAt sql.NullTime
-		if err := rows.Scan(&lib.ID, &lib.Name, &lib.RootPath, &lib.SystemID, &lib.SystemName, &lib.CreatedAt, &lastScanAt); err != nil {
+		if err := rows.Scan(&lib.ID, &lib.Name, &lib.RootPath, &lib.SystemID, &lib.SystemName, &lib.CreatedAt, &lastScanAt, &lib.MultiSystem); err != nil {
 			return nil, fmt.Errorf("failed to scan library: %w", err)
 		}
 		if lastScanAt.Valid {
@@ -118,19 +124,153 @@ func (m *Manager) List(ctx context.Context) ([]*Library, error) {
 	return libraries, nil
 }
 
-// Delete removes a library and all its scanned files.
+// Delete removes a library and all its scan data. Scanned files, matches,
+// tags and scan state go with it through ON DELETE CASCADE.
 func (m *Manager) Delete(ctx context.Context, name string) error {
-	result, err := m.db.ExecContext(ctx, "DELETE FROM libraries WHERE name = ?", name)
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM libraries WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("library %w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get library: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM libraries WHERE id = ?", id); err != nil {
 		return fmt.Errorf("failed to delete library: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// moveSampleSize is how many of a library's files Move looks for under the
+// new root before rewriting their paths.
+const moveSampleSize = 20
+
+// MoveResult describes a library moved to a new root path.
+type MoveResult struct {
+	OldRoot      string
+	NewRoot      string
+	FilesMoved   int64 // Scanned files whose path was rewritten
+	FilesSampled int   // Files looked for under the new root
+	FilesFound   int   // Sampled files that were there
+}
+
+// Move points a library at a new root path after its ROMs were relocated,
+// rewriting the stored paths of its scanned files, tags and skipped files
+// so the hash cache survives the move. Some of the library's files must
+// exist under newRoot, or the move is refused as the wrong directory.
+func (m *Manager) Move(ctx context.Context, name, newRoot string) (*MoveResult, error) {
+	info, err := os.Stat(newRoot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArg, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrInvalidArg, newRoot)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	result := &MoveResult{NewRoot: filepath.Clean(newRoot)}
+	err = tx.QueryRowContext(ctx, "SELECT id, root_path FROM libraries WHERE name = ?", name).Scan(&id, &result.OldRoot)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("library %w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library: %w", err)
+	}
+	oldPrefix := filepath.Clean(result.OldRoot) + string(filepath.Separator)
+	newPrefix := result.NewRoot + string(filepath.Separator)
+
+	// Paths are compared by prefix with substr, which counts characters
+	prefixLen := utf8.RuneCountInString(oldPrefix)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT path FROM scanned_files
+		WHERE library_id = ? AND virtual = 0 AND substr(path, 1, ?) = ?
+		ORDER BY RANDOM() LIMIT ?`, id, prefixLen, oldPrefix, moveSampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample files: %w", err)
+	}
+	var sample []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to sample files: %w", err)
+		}
+		sample = append(sample, path)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to sample files: %w", err)
+	}
+	for _, path := range sample {
+		result.FilesSampled++
+		if _, err := os.Stat(newPrefix + path[len(oldPrefix):]); err == nil {
+			result.FilesFound++
+		}
+	}
+	if result.FilesSampled > 0 && result.FilesFound == 0 {
+		return nil, fmt.Errorf("%w: none of %d files sampled from %s are under %s", ErrInvalidArg, result.FilesSampled, result.OldRoot, result.NewRoot)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE libraries SET root_path = ? WHERE id = ?", result.NewRoot, id); err != nil {
+		return nil, fmt.Errorf("failed to update library: %w", err)
+	}
+	for _, table := range []string{"scanned_files", "file_tags", "skipped_files"} {
+		res, err := tx.ExecContext(ctx, `UPDATE `+table+` SET path = ? || substr(path, ?)
+			WHERE library_id = ? AND substr(path, 1, ?) = ?`, // #nosec G202
+			newPrefix, prefixLen+1, id, prefixLen, oldPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", table, err)
+		}
+		if table == "scanned_files" {
+			if result.FilesMoved, err = res.RowsAffected(); err != nil {
+				return nil, fmt.Errorf("failed to check update result: %w", err)
+			}
+		}
+	}
+	// An interrupted scan resumes past the directory it last committed
+	if _, err := tx.ExecContext(ctx, `UPDATE scan_state SET last_dir = ? || substr(last_dir, ?)
+		WHERE library_id = ? AND substr(last_dir, 1, ?) = ?`,
+		newPrefix, prefixLen+1, id, prefixLen, oldPrefix); err != nil {
+		return nil, fmt.Errorf("failed to update scan state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return result, nil
+}
+
+// SetMultiSystem turns per-subdirectory system detection on or off for a library.
+func (m *Manager) SetMultiSystem(ctx context.Context, name string, enabled bool) error {
+	result, err := m.db.ExecContext(ctx, "UPDATE libraries SET multi_system = ? WHERE name = ?", enabled, name)
+	if err != nil {
+		return fmt.Errorf("failed to update library: %w", err)
+	}
+
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to check delete result: %w", err)
+		return fmt.Errorf("failed to check update result: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("library not found: %s", name)
+		return fmt.Errorf("library %w: %s", ErrNotFound, name)
 	}
 
 	return nil
@@ -66,3 +66,68 @@ func TestLibraryManager(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, libs, 0)
 }
+
+func TestLibraryManager_DeleteRemovesScanData(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 5)
+	ctx := context.Background()
+
+	_, err := NewScanner(database.Conn()).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	require.Equal(t, 5, countRows(t, database, "SELECT COUNT(*) FROM scanned_files"))
+	require.Equal(t, 5, countRows(t, database, "SELECT COUNT(*) FROM matches"))
+
+	manager := NewManager(database.Conn())
+	require.NoError(t, manager.Delete(ctx, "test-lib"))
+
+	// Foreign keys are on, so the delete cascades
+	assert.Equal(t, 0, countRows(t, database, "SELECT COUNT(*) FROM libraries"))
+	assert.Equal(t, 0, countRows(t, database, "SELECT COUNT(*) FROM scanned_files"))
+	assert.Equal(t, 0, countRows(t, database, "SELECT COUNT(*) FROM matches"))
+	assert.Equal(t, 0, countRows(t, database, "SELECT COUNT(*) FROM scan_state"))
+	// The DAT data is kept
+	assert.Equal(t, 5, countRows(t, database, "SELECT COUNT(*) FROM rom_entries"))
+
+	err = manager.Delete(ctx, "test-lib")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, "library not found")
+}
+
+func TestLibraryManager_MoveKeepsHashCache(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 3)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	_, err := NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	manager := NewManager(conn)
+	require.NoError(t, NewTagManager(conn).Add(ctx, "test-lib", filepath.Join(libPath, "game01.nes"), "", TagKeep))
+
+	newRoot := filepath.Join(t.TempDir(), "new drive", "röms")
+	require.NoError(t, os.MkdirAll(filepath.Dir(newRoot), 0755)) // #nosec G301
+
+	// Nothing is there yet
+	_, err = manager.Move(ctx, "test-lib", filepath.Dir(newRoot))
+	assert.ErrorIs(t, err, ErrInvalidArg)
+	_, err = manager.Move(ctx, "test-lib", newRoot)
+	assert.ErrorIs(t, err, ErrInvalidArg)
+
+	require.NoError(t, os.Rename(libPath, newRoot))
+	result, err := manager.Move(ctx, "test-lib", newRoot)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.FilesMoved)
+	assert.Equal(t, 3, result.FilesSampled)
+	assert.Equal(t, 3, result.FilesFound)
+
+	lib, err := manager.Get(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, newRoot, lib.RootPath)
+	assert.Equal(t, 3, countRows(t, database, "SELECT COUNT(*) FROM scanned_files WHERE path LIKE ?", newRoot+"%"))
+	assert.Equal(t, 1, countRows(t, database, "SELECT COUNT(*) FROM file_tags WHERE path = ?", filepath.Join(newRoot, "game01.nes")))
+
+	// The next scan hashes nothing and keeps the matches
+	scan, err := NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 0, scan.FilesHashed)
+	assert.Equal(t, 3, scan.FilesSkipped)
+	assert.Equal(t, 3, countRows(t, database, "SELECT COUNT(*) FROM matches"))
+}
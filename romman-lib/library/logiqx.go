@@ -0,0 +1,75 @@
+package library
+
+import (
+	"encoding/xml"
+)
+
+// logiqxDoctype is the DOCTYPE declaration expected by clrmamepro and friends.
+const logiqxDoctype = `<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">` + "\n"
+
+// LogiqxROM is a <rom> element in a Logiqx DAT.
+type LogiqxROM struct {
+	Name  string `xml:"name,attr"`
+	Size  int64  `xml:"size,attr"`
+	CRC32 string `xml:"crc,attr,omitempty"`
+	MD5   string `xml:"md5,attr,omitempty"`
+	SHA1  string `xml:"sha1,attr,omitempty"`
+}
+
+// LogiqxGame is a <game> element in a Logiqx DAT.
+type LogiqxGame struct {
+	Name        string      `xml:"name,attr"`
+	Description string      `xml:"description"`
+	ROMs        []LogiqxROM `xml:"rom"`
+}
+
+// LogiqxHeader is the <header> element of a Logiqx DAT.
+type LogiqxHeader struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	Version     string `xml:"version,omitempty"`
+	Date        string `xml:"date,omitempty"`
+	Author      string `xml:"author,omitempty"`
+}
+
+// LogiqxDAT is a Logiqx XML datafile, the standard DAT interchange format.
+type LogiqxDAT struct {
+	XMLName xml.Name     `xml:"datafile"`
+	Header  LogiqxHeader `xml:"header"`
+	Games   []LogiqxGame `xml:"game"`
+}
+
+// ROMCount returns the number of ROMs across all games.
+func (d *LogiqxDAT) ROMCount() int {
+	n := 0
+	for _, g := range d.Games {
+		n += len(g.ROMs)
+	}
+	return n
+}
+
+// addROM appends rom to the named game, starting a new game unless it is the
+// last one. Rows must therefore arrive grouped by game. An empty description
+// defaults to the game name.
+func (d *LogiqxDAT) addROM(gameName, description string, rom LogiqxROM) {
+	if n := len(d.Games); n == 0 || d.Games[n-1].Name != gameName {
+		if description == "" {
+			description = gameName
+		}
+		d.Games = append(d.Games, LogiqxGame{Name: gameName, Description: description})
+	}
+	game := &d.Games[len(d.Games)-1]
+	game.ROMs = append(game.ROMs, rom)
+}
+
+// Marshal encodes the DAT as indented XML with the Logiqx DOCTYPE.
+func (d *LogiqxDAT) Marshal() ([]byte, error) {
+	output, err := xml.MarshalIndent(d, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	data := append([]byte(xml.Header), logiqxDoctype...)
+	data = append(data, output...)
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,205 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// ManualMatch is a match assigned by ReassignMatch.
+type ManualMatch struct {
+	Path     string   `json:"path"`
+	Release  string   `json:"release"`
+	ROM      string   `json:"rom"`
+	Previous []string `json:"previous,omitempty"` // Releases the file was matched to before
+}
+
+// scannedFileRef identifies a scanned file of a library.
+type scannedFileRef struct {
+	id          int64
+	path        string
+	archivePath string
+	size        int64
+}
+
+// display returns the file as GetUnmatchedFiles lists it.
+func (f scannedFileRef) display() string {
+	if f.archivePath != "" {
+		return fmt.Sprintf("%s:%s", f.path, f.archivePath)
+	}
+	return f.path
+}
+
+// ReassignMatch replaces a scanned file's matches with a manual match to a
+// release of its system, for when the name or fuzzy matcher picked the wrong
+// one. The file is given as GetUnmatchedFiles lists it, absolute or relative
+// to the library root. Of a release with several ROMs, the one of the
+// file's size is chosen, then one with the file's extension, then the first.
+// Rescans keep manual matches until the file leaves the library.
+func (s *Scanner) ReassignMatch(ctx context.Context, libraryName, file, release string) (*ManualMatch, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.ReassignMatch")
+	defer span.End()
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	f, err := s.findScannedFile(ctx, lib, file)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	systemID, err := newSystemResolver(s.db, lib, nil).systemFor(f.path)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "resolve system")
+	}
+
+	romEntryID, romName, err := s.manualROMEntry(ctx, systemID, release, f)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	result := &ManualMatch{Path: f.display(), Release: release, ROM: romName}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "begin transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT r.name
+		FROM matches m
+		JOIN rom_entries re ON re.id = m.rom_entry_id
+		JOIN releases r ON r.id = re.release_id
+		WHERE m.scanned_file_id = ?
+		ORDER BY r.name
+	`, f.id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "query matches")
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			tracing.RecordError(span, err)
+			return nil, WrapDBError(err, "scan match")
+		}
+		result.Previous = append(result.Previous, name)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "query matches")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM matches WHERE scanned_file_id = ?`, f.id); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "clear matches")
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type, is_manual)
+		VALUES (?, ?, ?, 1)
+	`, f.id, romEntryID, string(MatchTypeManual)); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "insert manual match")
+	}
+	if err := tx.Commit(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "commit manual match")
+	}
+
+	return result, nil
+}
+
+// findScannedFile looks up a library's scanned file by its path, absolute
+// or relative to the library root, or by "archive:entry" for archive entries.
+func (s *Scanner) findScannedFile(ctx context.Context, lib *Library, file string) (scannedFileRef, error) {
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return filepath.Clean(path)
+		}
+		return filepath.Join(lib.RootPath, path)
+	}
+
+	lookups := [][2]string{{resolve(file), ""}}
+	if i := strings.LastIndex(file, ":"); i > 0 && i < len(file)-1 {
+		lookups = append(lookups, [2]string{resolve(file[:i]), file[i+1:]})
+	}
+
+	for _, l := range lookups {
+		f := scannedFileRef{path: l[0], archivePath: l[1]}
+		err := s.db.QueryRowContext(ctx, `
+			SELECT id, size FROM scanned_files
+			WHERE library_id = ? AND path = ? AND COALESCE(archive_path, '') = ?
+		`, lib.ID, f.path, f.archivePath).Scan(&f.id, &f.size)
+		if err == nil {
+			return f, nil
+		}
+		if err != sql.ErrNoRows {
+			return scannedFileRef{}, WrapDBError(err, "find scanned file")
+		}
+	}
+	return scannedFileRef{}, NotFoundError("scanned file", file)
+}
+
+// manualROMEntry picks the ROM entry of a release that a file best fits.
+func (s *Scanner) manualROMEntry(ctx context.Context, systemID int64, release string, f scannedFileRef) (int64, string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT re.id, re.name, COALESCE(re.size, 0)
+		FROM rom_entries re
+		WHERE re.release_id = (
+			SELECT id FROM releases WHERE system_id = ? AND name = ? ORDER BY id LIMIT 1
+		)
+		ORDER BY re.id
+	`, systemID, release)
+	if err != nil {
+		return 0, "", WrapDBError(err, "query rom entries")
+	}
+	defer func() { _ = rows.Close() }()
+
+	name := f.path
+	if f.archivePath != "" {
+		name = f.archivePath
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+
+	var bestID int64
+	var bestName string
+	bestRank := -1
+	for rows.Next() {
+		var id, size int64
+		var romName string
+		if err := rows.Scan(&id, &romName, &size); err != nil {
+			return 0, "", WrapDBError(err, "scan rom entry")
+		}
+		rank := 0
+		switch {
+		case size == f.size:
+			rank = 2
+		case ext != "" && strings.ToLower(filepath.Ext(romName)) == ext:
+			rank = 1
+		}
+		if rank > bestRank {
+			bestID, bestName, bestRank = id, romName, rank
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", WrapDBError(err, "query rom entries")
+	}
+	if bestRank < 0 {
+		return 0, "", NotFoundError("release", release)
+	}
+	return bestID, bestName, nil
+}
@@ -0,0 +1,74 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_ReassignMatch(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 2)
+	conn := database.Conn()
+
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "unknown.nes"), []byte("mystery"), 0644)) // #nosec G306
+	_, err := conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (10, 1, 'Mystery (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (release_id, name, size, sha1) VALUES
+		(10, 'Mystery (USA).txt', 1, 'a'), (10, 'Mystery (USA).nes', 999, 'b')`)
+	require.NoError(t, err)
+
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	matchedRelease := func(file string) string {
+		t.Helper()
+		var release string
+		require.NoError(t, conn.QueryRow(`
+			SELECT r.name || ' ' || m.match_type || ' ' || m.is_manual FROM matches m
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			JOIN releases r ON r.id = re.release_id
+			WHERE sf.path = ?`, filepath.Join(libPath, file)).Scan(&release))
+		return release
+	}
+
+	result, err := scanner.ReassignMatch(ctx, "test-lib", "game00.nes", "Game 01 (USA)")
+	require.NoError(t, err)
+	assert.Equal(t, &ManualMatch{
+		Path:     filepath.Join(libPath, "game00.nes"),
+		Release:  "Game 01 (USA)",
+		ROM:      "Game 01 (USA).nes",
+		Previous: []string{"Game 00 (USA)"},
+	}, result)
+
+	// The ROM with the file's extension is chosen when no size fits
+	result, err = scanner.ReassignMatch(ctx, "test-lib", filepath.Join(libPath, "unknown.nes"), "Mystery (USA)")
+	require.NoError(t, err)
+	assert.Equal(t, "Mystery (USA).nes", result.ROM)
+	assert.Empty(t, result.Previous)
+
+	// Rescans of every kind keep manual matches
+	for _, cfg := range []ScanConfig{
+		DefaultScanConfig(),
+		{ForceRehash: true},
+		{ForceRehash: true, ChangedOnly: true},
+	} {
+		_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, "Game 01 (USA) manual 1", matchedRelease("game00.nes"))
+		assert.Equal(t, "Mystery (USA) manual 1", matchedRelease("unknown.nes"))
+		assert.Equal(t, "Game 01 (USA) sha1 0", matchedRelease("game01.nes"))
+	}
+
+	_, err = scanner.ReassignMatch(ctx, "test-lib", "missing.nes", "Game 01 (USA)")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = scanner.ReassignMatch(ctx, "test-lib", "game00.nes", "Missing (USA)")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "Game 01 (USA) manual 1", matchedRelease("game00.nes"))
+}
@@ -0,0 +1,129 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// discPattern matches Redump/No-Intro disc markers such as "(Disc 2)" or "(Disc 2 of 3)".
+var discPattern = regexp.MustCompile(`(?i)\s*\((?:disc|disk|cd)\s*(\d+)(?:\s+of\s+\d+)?\)`)
+
+// ParseDisc extracts the disc number from a release or file name.
+// It returns the title with the disc marker removed, the disc number, and
+// whether a disc marker was found.
+func ParseDisc(name string) (string, int, bool) {
+	loc := discPattern.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return name, 0, false
+	}
+
+	disc, err := strconv.Atoi(name[loc[2]:loc[3]])
+	if err != nil {
+		return name, 0, false
+	}
+
+	title := strings.Join(strings.Fields(name[:loc[0]]+" "+name[loc[1]:]), " ")
+	return title, disc, true
+}
+
+// DiscSet is a multi-disc game with an .m3u playlist listing its discs in order.
+type DiscSet struct {
+	Title string   // Release title without the disc marker
+	Path  string   // Path of the .m3u playlist
+	Discs []string // Disc file paths, ordered by disc number
+}
+
+// discPlacement is one disc file at its organized location.
+type discPlacement struct {
+	releaseName string
+	path        string
+}
+
+// m3uPath returns the playlist path for a multi-disc title in dir.
+func m3uPath(dir, title string) string {
+	return filepath.Join(dir, sanitizeFilename(title)+".m3u")
+}
+
+// groupDiscSets groups disc files that share a title and directory.
+// Titles with fewer than two discs are not treated as multi-disc sets.
+func groupDiscSets(placements []discPlacement) []DiscSet {
+	type disc struct {
+		number int
+		path   string
+	}
+	groups := make(map[string][]disc)
+	titles := make(map[string]string)
+
+	for _, p := range placements {
+		title, number, ok := ParseDisc(p.releaseName)
+		if !ok {
+			continue
+		}
+		key := m3uPath(filepath.Dir(p.path), title)
+		groups[key] = append(groups[key], disc{number: number, path: p.path})
+		titles[key] = title
+	}
+
+	var sets []DiscSet
+	for key, discs := range groups {
+		if len(discs) < 2 {
+			continue
+		}
+		sort.SliceStable(discs, func(i, j int) bool { return discs[i].number < discs[j].number })
+
+		set := DiscSet{Title: titles[key], Path: key}
+		for _, d := range discs {
+			set.Discs = append(set.Discs, d.path)
+		}
+		sets = append(sets, set)
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Path < sets[j].Path })
+	return sets
+}
+
+// WriteM3U writes the set's playlist, listing discs relative to the playlist.
+func (s DiscSet) WriteM3U() error {
+	var b strings.Builder
+	dir := filepath.Dir(s.Path)
+	for _, disc := range s.Discs {
+		rel, err := filepath.Rel(dir, disc)
+		if err != nil {
+			rel = disc
+		}
+		b.WriteString(filepath.ToSlash(rel))
+		b.WriteString("\n")
+	}
+
+	// #nosec G306
+	if err := os.WriteFile(s.Path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	return nil
+}
+
+// discSetEntry resolves a disc file to its set's .m3u playlist if one exists
+// next to it, so exporters list a multi-disc game once. seen tracks playlists
+// already emitted; skip is true for later discs of an emitted set.
+func discSetEntry(name, path string, seen map[string]bool) (string, string, bool) {
+	title, _, ok := ParseDisc(name)
+	if !ok {
+		return name, path, false
+	}
+
+	playlist := m3uPath(filepath.Dir(path), title)
+	if _, err := os.Stat(playlist); err != nil {
+		return name, path, false
+	}
+
+	if seen[playlist] {
+		return "", "", true
+	}
+	seen[playlist] = true
+	return title, playlist, false
+}
@@ -0,0 +1,140 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestParseDisc(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		disc  int
+		ok    bool
+	}{
+		{"Final Fantasy VII (USA) (Disc 2)", "Final Fantasy VII (USA)", 2, true},
+		{"Final Fantasy VII (Europe) (Disc 3 of 3) (Rev 1)", "Final Fantasy VII (Europe) (Rev 1)", 3, true},
+		{"Policenauts (Japan) (CD1)", "Policenauts (Japan)", 1, true},
+		{"Super Mario Bros (USA)", "Super Mario Bros (USA)", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, disc, ok := ParseDisc(tt.name)
+			assert.Equal(t, tt.title, title)
+			assert.Equal(t, tt.disc, disc)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+// setupMultiDiscLibrary creates a psx library with a three-disc game and a single-disc game.
+func setupMultiDiscLibrary(t *testing.T) (*db.DB, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+
+	libPath := filepath.Join(tmpDir, "roms")
+	require.NoError(t, os.MkdirAll(libPath, 0755)) // #nosec G301
+
+	conn := database.Conn()
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'psx')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'psx', ?, 1)`, libPath)
+	require.NoError(t, err)
+
+	// Discs are inserted out of order to check playlist ordering
+	names := []string{
+		"Game (USA) (Disc 3)",
+		"Game (USA) (Disc 1)",
+		"Game (USA) (Disc 2)",
+		"Other (USA)",
+	}
+	for i, name := range names {
+		path := filepath.Join(libPath, fmt.Sprintf("file%d.chd", i))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644)) // #nosec G306
+
+		_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, i+1, name)
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1, size) VALUES (?, ?, ?, ?, 1)`,
+			i+1, i+1, name+".chd", fmt.Sprintf("%040x", i))
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1, crc32) VALUES (?, 1, ?, 1, 0, ?, '00000000')`,
+			i+1, path, fmt.Sprintf("%040x", i))
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (?, ?, 'sha1')`, i+1, i+1)
+		require.NoError(t, err)
+	}
+
+	return database, tmpDir
+}
+
+func TestOrganizer_MultiDiscWritesM3U(t *testing.T) {
+	database, tmpDir := setupMultiDiscLibrary(t)
+	conn := database.Conn()
+	outDir := filepath.Join(tmpDir, "organized")
+
+	organizer := NewOrganizer(conn, NewManager(conn))
+	opts := OrganizeOptions{OutputDir: outDir, Structure: "system", RenameToDAT: true, MultiDisc: true}
+	result, err := organizer.Plan(context.Background(), "psx", opts)
+	require.NoError(t, err)
+
+	require.Len(t, result.DiscSets, 1)
+	set := result.DiscSets[0]
+	assert.Equal(t, "Game (USA)", set.Title)
+	assert.Equal(t, filepath.Join(outDir, "psx", "Game (USA).m3u"), set.Path)
+
+	require.NoError(t, organizer.Execute(result, false))
+	assert.Equal(t, 0, result.Errors, result.ErrorMsgs)
+
+	data, err := os.ReadFile(set.Path) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, "Game (USA) (Disc 1).chd\nGame (USA) (Disc 2).chd\nGame (USA) (Disc 3).chd\n", string(data))
+
+	// Point scanned paths at the organized files, as a rescan would, then export
+	for i := 1; i <= 4; i++ {
+		var name string
+		require.NoError(t, conn.QueryRow(`SELECT name FROM releases WHERE id = ?`, i).Scan(&name))
+		_, err = conn.Exec(`UPDATE scanned_files SET path = ? WHERE id = ?`, filepath.Join(outDir, "psx", name+".chd"), i)
+		require.NoError(t, err)
+	}
+
+	gamelist, err := NewExporter(conn, NewManager(conn)).ExportGamelist(context.Background(), "psx", GamelistOptions{MatchedOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(gamelist), "Game (USA).m3u"))
+	assert.NotContains(t, string(gamelist), "(Disc 2)")
+	assert.Contains(t, string(gamelist), "Other (USA).chd")
+
+	playlistPath := filepath.Join(tmpDir, "psx.lpl")
+	require.NoError(t, NewRetroArchExporter(conn).ExportPlaylist(context.Background(), "psx", playlistPath, PlaylistOptions{}))
+	playlist, err := os.ReadFile(playlistPath) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(playlist), "Game (USA).m3u"))
+	assert.NotContains(t, string(playlist), "(Disc 1)")
+}
+
+func TestOrganizer_MultiDiscDryRunWritesNothing(t *testing.T) {
+	database, tmpDir := setupMultiDiscLibrary(t)
+	conn := database.Conn()
+
+	organizer := NewOrganizer(conn, NewManager(conn))
+	result, err := organizer.Plan(context.Background(), "psx", OrganizeOptions{OutputDir: filepath.Join(tmpDir, "out"), MultiDisc: true})
+	require.NoError(t, err)
+	require.Len(t, result.DiscSets, 1)
+
+	require.NoError(t, organizer.Execute(result, true))
+	_, err = os.Stat(result.DiscSets[0].Path)
+	assert.True(t, os.IsNotExist(err))
+}
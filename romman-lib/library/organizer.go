@@ -4,15 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 )
 
 // OrganizeAction represents a file organization action.
 type OrganizeAction struct {
 	SourcePath  string
 	DestPath    string
-	Action      string // "move", "copy", "rename"
+	Action      string // "move", "copy", "link", "rename"
 	ReleaseName string
 	Reason      string
 }
@@ -20,20 +20,26 @@ type OrganizeAction struct {
 // OrganizeOptions configures the organization behavior.
 type OrganizeOptions struct {
 	OutputDir     string // Destination directory
-	Structure     string // "flat", "system", "system-region"
+	Structure     string // "flat", "system", "system-region", "system-genre", "system-region-genre"
 	RenameToDAT   bool   // Rename files to match DAT names
 	DryRun        bool   // Preview without making changes
 	MatchedOnly   bool   // Only organize matched files
 	PreferredOnly bool   // Only organize preferred releases
+	MultiDisc     bool   // Group multi-disc sets and write an .m3u per game
+	Copy          bool   // Copy files to the output, leaving the library as it is
+	Hardlink      bool   // Hardlink files into the output, which must be on the library's filesystem
 }
 
 // OrganizeResult contains the result of an organization operation.
 type OrganizeResult struct {
 	Actions   []OrganizeAction
 	Moved     int
+	Copied    int
+	Linked    int
 	Skipped   int
 	Errors    int
 	ErrorMsgs []string
+	DiscSets  []DiscSet // Multi-disc playlists to write (MultiDisc only)
 }
 
 // Organizer handles ROM file organization.
@@ -58,13 +64,14 @@ func (o *Organizer) Plan(ctx context.Context, libraryName string, opts OrganizeO
 
 	// Get matched files with their release info
 	query := `
-		SELECT sf.path, r.name, s.name as system_name
+		SELECT sf.path, r.name, s.name as system_name, COALESCE(gm.genre, '')
 		FROM scanned_files sf
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
 		JOIN systems s ON s.id = r.system_id
-		WHERE sf.library_id = ?
+		LEFT JOIN game_metadata gm ON gm.release_id = r.id
+		WHERE sf.library_id = ? AND sf.virtual = 0
 	`
 	args := []interface{}{lib.ID}
 
@@ -80,11 +87,20 @@ func (o *Organizer) Plan(ctx context.Context, libraryName string, opts OrganizeO
 	}
 	defer func() { _ = rows.Close() }()
 
+	actionType := "move"
+	switch {
+	case opts.Hardlink:
+		actionType = "link"
+	case opts.Copy:
+		actionType = "copy"
+	}
+
 	seen := make(map[string]bool)
+	var placements []discPlacement
 
 	for rows.Next() {
-		var srcPath, releaseName, systemName string
-		if err := rows.Scan(&srcPath, &releaseName, &systemName); err != nil {
+		var srcPath, releaseName, systemName, genre string
+		if err := rows.Scan(&srcPath, &releaseName, &systemName, &genre); err != nil {
 			return nil, err
 		}
 
@@ -95,7 +111,8 @@ func (o *Organizer) Plan(ctx context.Context, libraryName string, opts OrganizeO
 		seen[srcPath] = true
 
 		// Determine destination path
-		destPath := o.buildDestPath(srcPath, releaseName, systemName, opts)
+		destPath := o.buildDestPath(srcPath, releaseName, systemName, genre, opts)
+		placements = append(placements, discPlacement{releaseName: releaseName, path: destPath})
 
 		// Skip if source and dest are the same
 		if srcPath == destPath {
@@ -106,7 +123,7 @@ func (o *Organizer) Plan(ctx context.Context, libraryName string, opts OrganizeO
 		action := OrganizeAction{
 			SourcePath:  srcPath,
 			DestPath:    destPath,
-			Action:      "move",
+			Action:      actionType,
 			ReleaseName: releaseName,
 			Reason:      "matched",
 		}
@@ -114,42 +131,65 @@ func (o *Organizer) Plan(ctx context.Context, libraryName string, opts OrganizeO
 		result.Actions = append(result.Actions, action)
 	}
 
+	if opts.MultiDisc {
+		result.DiscSets = groupDiscSets(placements)
+	}
+
 	return result, nil
 }
 
-// Execute performs the organization based on a plan.
+// Execute performs the organization based on a plan. Copy and link actions
+// leave the source in place; moves fall back to copy and delete across
+// filesystems, while links fail there.
 func (o *Organizer) Execute(result *OrganizeResult, dryRun bool) error {
 	for i := range result.Actions {
 		action := &result.Actions[i]
 
-		if dryRun {
-			result.Moved++
-			continue
+		if !dryRun {
+			var err error
+			switch action.Action {
+			case "copy":
+				err = copyFile(action.SourcePath, action.DestPath)
+			case "link":
+				err = linkFile(action.SourcePath, action.DestPath)
+			default:
+				err = moveFile(action.SourcePath, action.DestPath)
+			}
+			if err != nil {
+				result.Errors++
+				result.ErrorMsgs = append(result.ErrorMsgs, fmt.Sprintf("failed to %s %s: %v", action.Action, action.SourcePath, err))
+				continue
+			}
 		}
 
-		// Create destination directory
-		destDir := filepath.Dir(action.DestPath)
-		if err := os.MkdirAll(destDir, 0755); err != nil { //nolint:gosec // Standard dir permissions
-			result.Errors++
-			result.ErrorMsgs = append(result.ErrorMsgs, fmt.Sprintf("failed to create dir %s: %v", destDir, err))
-			continue
+		switch action.Action {
+		case "copy":
+			result.Copied++
+		case "link":
+			result.Linked++
+		default:
+			result.Moved++
 		}
+	}
+
+	if dryRun {
+		return nil
+	}
 
-		// Move the file
-		if err := os.Rename(action.SourcePath, action.DestPath); err != nil {
+	// Write playlists once the discs are in place
+	for _, set := range result.DiscSets {
+		if err := set.WriteM3U(); err != nil {
 			result.Errors++
-			result.ErrorMsgs = append(result.ErrorMsgs, fmt.Sprintf("failed to move %s: %v", action.SourcePath, err))
-			continue
+			result.ErrorMsgs = append(result.ErrorMsgs, err.Error())
 		}
-
-		result.Moved++
 	}
 
 	return nil
 }
 
-// buildDestPath constructs the destination path based on options.
-func (o *Organizer) buildDestPath(srcPath, releaseName, systemName string, opts OrganizeOptions) string {
+// buildDestPath constructs the destination path based on options. genre is
+// the release's scraped genre, empty when it has none.
+func (o *Organizer) buildDestPath(srcPath, releaseName, systemName, genre string, opts OrganizeOptions) string {
 	ext := filepath.Ext(srcPath) // Preserve original extension
 	baseName := filepath.Base(srcPath)
 
@@ -170,9 +210,24 @@ func (o *Organizer) buildDestPath(srcPath, releaseName, systemName string, opts
 	case "system-region":
 		region := extractRegion(releaseName)
 		destDir = filepath.Join(opts.OutputDir, systemName, region)
+	case "system-genre":
+		destDir = filepath.Join(opts.OutputDir, systemName, genreDir(genre))
+	case "system-region-genre":
+		region := extractRegion(releaseName)
+		destDir = filepath.Join(opts.OutputDir, systemName, region, genreDir(genre))
 	default: // "flat"
 		destDir = opts.OutputDir
 	}
 
 	return filepath.Join(destDir, fileName)
 }
+
+// genreDir turns a scraped genre into a directory name. Releases without
+// one, or whose genre has nothing usable, go under "Unknown".
+func genreDir(genre string) string {
+	name := strings.Trim(sanitizeFilename(genre), " .")
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
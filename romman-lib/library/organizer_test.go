@@ -2,6 +2,7 @@ package library
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -65,6 +66,7 @@ func TestOrganizer_BuildDestPath(t *testing.T) {
 		srcPath     string
 		releaseName string
 		systemName  string
+		genre       string
 		opts        OrganizeOptions
 		expected    string
 	}{
@@ -116,16 +118,73 @@ func TestOrganizer_BuildDestPath(t *testing.T) {
 			},
 			expected: "/output/nes/Super Mario Bros (USA).nes",
 		},
+		{
+			name:        "system-genre structure",
+			srcPath:     "/roms/game.nes",
+			releaseName: "Super Mario Bros (USA)",
+			systemName:  "nes",
+			genre:       "Platform",
+			opts: OrganizeOptions{
+				OutputDir: "/output",
+				Structure: "system-genre",
+			},
+			expected: "/output/nes/Platform/game.nes",
+		},
+		{
+			name:        "system-region-genre structure, unsafe genre",
+			srcPath:     "/roms/game.nes",
+			releaseName: "Super Mario Bros (USA)",
+			systemName:  "nes",
+			genre:       "Shoot'em Up / Vertical: Arcade?",
+			opts: OrganizeOptions{
+				OutputDir: "/output",
+				Structure: "system-region-genre",
+			},
+			expected: "/output/nes/USA/Shoot'em Up - Vertical - Arcade/game.nes",
+		},
+		{
+			name:        "system-genre structure, no genre",
+			srcPath:     "/roms/game.nes",
+			releaseName: "Super Mario Bros (USA)",
+			systemName:  "nes",
+			genre:       "..",
+			opts: OrganizeOptions{
+				OutputDir: "/output",
+				Structure: "system-genre",
+			},
+			expected: "/output/nes/Unknown/game.nes",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := organizer.buildDestPath(tt.srcPath, tt.releaseName, tt.systemName, tt.opts)
+			result := organizer.buildDestPath(tt.srcPath, tt.releaseName, tt.systemName, tt.genre, tt.opts)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+func TestOrganizer_PlanGenreStructure(t *testing.T) {
+	ctx := context.Background()
+	database, _ := setupCheckpointLibrary(t, 2)
+	_, err := NewScanner(database.Conn()).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	require.NoError(t, database.SetGameMetadata(ctx, db.GameMetadata{ReleaseID: 1, Genre: "Action/Adventure"}))
+
+	organizer := NewOrganizer(database.Conn(), NewManager(database.Conn()))
+	result, err := organizer.Plan(ctx, "test-lib", OrganizeOptions{OutputDir: "/output", Structure: "system-genre"})
+	require.NoError(t, err)
+
+	dests := make(map[string]string)
+	for _, a := range result.Actions {
+		dests[filepath.Base(a.SourcePath)] = a.DestPath
+	}
+	assert.Equal(t, map[string]string{
+		"game00.nes": filepath.Join("/output", "nes", "Action-Adventure", "game00.nes"),
+		"game01.nes": filepath.Join("/output", "nes", "Unknown", "game01.nes"),
+	}, dests)
+}
+
 func TestOrganizeOptions_Defaults(t *testing.T) {
 	opts := OrganizeOptions{
 		OutputDir: "/output",
@@ -154,3 +213,61 @@ func TestOrganizeResult(t *testing.T) {
 	assert.Equal(t, 1, result.Moved)
 	assert.Equal(t, 0, result.Errors)
 }
+
+func TestOrganizer_ExecuteCopyAndMove(t *testing.T) {
+	src := t.TempDir()
+	out := t.TempDir()
+	for _, name := range []string{"kept.nes", "moved.nes"} {
+		require.NoError(t, os.WriteFile(filepath.Join(src, name), []byte(name), 0o600))
+	}
+
+	result := &OrganizeResult{Actions: []OrganizeAction{
+		{SourcePath: filepath.Join(src, "kept.nes"), DestPath: filepath.Join(out, "nes", "Kept.nes"), Action: "copy"},
+		{SourcePath: filepath.Join(src, "moved.nes"), DestPath: filepath.Join(out, "nes", "Moved.nes"), Action: "move"},
+		{SourcePath: filepath.Join(src, "missing.nes"), DestPath: filepath.Join(out, "nes", "Missing.nes"), Action: "copy"},
+	}}
+	require.NoError(t, NewOrganizer(nil, nil).Execute(result, false))
+
+	assert.Equal(t, 1, result.Copied)
+	assert.Equal(t, 1, result.Moved)
+	assert.Equal(t, 1, result.Errors)
+
+	// The copy leaves the original in place
+	data, err := os.ReadFile(filepath.Join(out, "nes", "Kept.nes")) // #nosec G304
+	require.NoError(t, err)
+	assert.Equal(t, "kept.nes", string(data))
+	assert.FileExists(t, filepath.Join(src, "kept.nes"))
+
+	assert.FileExists(t, filepath.Join(out, "nes", "Moved.nes"))
+	assert.NoFileExists(t, filepath.Join(src, "moved.nes"))
+
+	// A missing source is reported without creating the destination
+	assert.NoFileExists(t, filepath.Join(out, "nes", "Missing.nes"))
+}
+
+func TestOrganizer_ExecuteHardlink(t *testing.T) {
+	src := t.TempDir()
+	out := filepath.Join(src, "curated")
+	srcPath := filepath.Join(src, "game.nes")
+	require.NoError(t, os.WriteFile(srcPath, []byte("rom"), 0o600))
+
+	destPath := filepath.Join(out, "nes", "Game (USA).nes")
+	result := &OrganizeResult{Actions: []OrganizeAction{
+		{SourcePath: srcPath, DestPath: destPath, Action: "link"},
+	}}
+	require.NoError(t, NewOrganizer(nil, nil).Execute(result, false))
+	assert.Equal(t, 1, result.Linked)
+	assert.Zero(t, result.Errors)
+
+	srcInfo, err := os.Stat(srcPath)
+	require.NoError(t, err)
+	destInfo, err := os.Stat(destPath)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(srcInfo, destInfo))
+
+	// Running the plan again finds the link in place
+	result.Linked = 0
+	require.NoError(t, NewOrganizer(nil, nil).Execute(result, false))
+	assert.Equal(t, 1, result.Linked)
+	assert.Zero(t, result.Errors)
+}
@@ -0,0 +1,133 @@
+package library
+
+import (
+	"context"
+	"strings"
+)
+
+// PegasusGame is a game entry of a Pegasus frontend metadata file.
+type PegasusGame struct {
+	Title       string
+	Files       []string
+	Developer   string
+	Publisher   string
+	Genre       string
+	ReleaseDate string
+	Description string
+}
+
+// PegasusOptions configures the Pegasus export.
+type PegasusOptions struct {
+	PathPrefix string // Prefix to prepend to file names (e.g., "./"); empty keeps full paths
+}
+
+// ExportPegasus generates a Pegasus metadata.pegasus.txt for a library: a
+// collection named after the system and a game entry for each matched
+// release, with the developer, publisher, genre, release date and
+// description scraped into game_metadata when there are any. Pegasus only
+// lists games it has files for, so missing releases are left out.
+func (e *Exporter) ExportPegasus(ctx context.Context, libraryName string, opts PegasusOptions) ([]byte, error) {
+	lib, err := e.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := e.getPegasusGames(ctx, lib.ID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	writePegasusField(&b, "collection", formatPlatformName(lib.SystemName))
+	writePegasusField(&b, "shortname", lib.SystemName)
+	for _, game := range games {
+		b.WriteString("\n")
+		writePegasusField(&b, "game", game.Title)
+		if len(game.Files) == 1 {
+			writePegasusField(&b, "file", game.Files[0])
+		} else {
+			b.WriteString("files:\n")
+			for _, file := range game.Files {
+				b.WriteString("  " + file + "\n")
+			}
+		}
+		writePegasusField(&b, "developer", game.Developer)
+		writePegasusField(&b, "publisher", game.Publisher)
+		writePegasusField(&b, "genre", game.Genre)
+		writePegasusField(&b, "release", game.ReleaseDate)
+		writePegasusField(&b, "description", game.Description)
+	}
+
+	return []byte(b.String()), nil
+}
+
+func (e *Exporter) getPegasusGames(ctx context.Context, libraryID int64, opts PegasusOptions) ([]PegasusGame, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT DISTINCT r.name, sf.path,
+			COALESCE(gm.developer, ''), COALESCE(gm.publisher, ''), COALESCE(gm.genre, ''),
+			COALESCE(gm.release_date, ''), COALESCE(gm.description, '')
+		FROM scanned_files sf
+		JOIN matches m ON m.scanned_file_id = sf.id
+		JOIN rom_entries re ON re.id = m.rom_entry_id
+		JOIN releases r ON r.id = re.release_id
+		LEFT JOIN game_metadata gm ON gm.release_id = r.id
+		WHERE sf.library_id = ? AND sf.virtual = 0
+		ORDER BY r.name, sf.path
+	`, libraryID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var games []PegasusGame
+	index := make(map[string]int)
+	discSets := make(map[string]bool)
+	for rows.Next() {
+		var game PegasusGame
+		var path string
+		if err := rows.Scan(&game.Title, &path, &game.Developer, &game.Publisher, &game.Genre,
+			&game.ReleaseDate, &game.Description); err != nil {
+			return nil, err
+		}
+
+		// Multi-disc sets with an .m3u are listed once, pointing at the playlist
+		title, path, skip := discSetEntry(game.Title, path, discSets)
+		if skip {
+			continue
+		}
+		game.Title = title
+		file := formatGamelistPath(path, opts.PathPrefix)
+
+		// A release matched by several files, such as a cue sheet and its
+		// tracks, is one game with several files
+		if i, ok := index[game.Title]; ok {
+			games[i].Files = append(games[i].Files, file)
+			continue
+		}
+		game.Files = []string{file}
+		index[game.Title] = len(games)
+		games = append(games, game)
+	}
+
+	return games, rows.Err()
+}
+
+// writePegasusField writes a "key: value" line, skipping empty values.
+// Further lines of a value are indented, with blank ones written as "."
+// as the format requires.
+func writePegasusField(b *strings.Builder, key, value string) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n"))
+	if value == "" {
+		return
+	}
+
+	lines := strings.Split(value, "\n")
+	b.WriteString(key + ": " + strings.TrimSpace(lines[0]) + "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = "."
+		}
+		b.WriteString("  " + line + "\n")
+	}
+}
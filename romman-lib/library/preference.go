@@ -15,25 +15,48 @@ import (
 // PreferenceConfig holds user preferences for release selection.
 type PreferenceConfig struct {
 	RegionOrder []string // Region priority, e.g. ["Europe", "World", "USA"]
+
+	// Score weights. A config with none of them set uses the defaults.
+	LanguageWeight  int // Points for an English release
+	StabilityWeight int // Points for a stable release; pre-releases get a fraction
+	RevisionWeight  int // Points per revision step
+	RegionWeight    int // Points per place from the bottom of RegionOrder
+
+	// GroupByClones groups releases by the DAT's parent/clone links instead
+	// of by base title. A parent and its clones form one group, in which the
+	// parent wins ties. Releases without links still group by base title.
+	GroupByClones bool
 }
 
-// DefaultPreferenceConfig returns the default preference configuration.
+// DefaultPreferenceConfig returns the default preference configuration:
+// English first, then stability, then revision and region.
 func DefaultPreferenceConfig() PreferenceConfig {
 	return PreferenceConfig{
-		RegionOrder: []string{"Europe", "World", "USA", "Japan"},
+		RegionOrder:     []string{"Europe", "World", "USA", "Japan"},
+		LanguageWeight:  1000,
+		StabilityWeight: 500,
+		RevisionWeight:  10,
+		RegionWeight:    50,
 	}
 }
 
+// hasWeights reports whether any score weight is set.
+func (c PreferenceConfig) hasWeights() bool {
+	return c.LanguageWeight != 0 || c.StabilityWeight != 0 || c.RevisionWeight != 0 || c.RegionWeight != 0
+}
+
 // ReleaseCandidate represents a release being considered for selection.
 type ReleaseCandidate struct {
 	ReleaseID    int64
 	Name         string
 	BaseTitle    string
+	ParentID     int64 // Parent release from the DAT's clone_of link, or 0
 	Regions      []string
 	Languages    []string
 	Revision     int
 	Stability    Stability
 	Score        int
+	Breakdown    ScoreBreakdown
 	IsPreferred  bool
 	IgnoreReason string
 }
@@ -49,6 +72,23 @@ const (
 	StabilityDemo
 )
 
+// String returns the stability's name, e.g. "beta".
+func (s Stability) String() string {
+	switch s {
+	case StabilityStable:
+		return "stable"
+	case StabilityBeta:
+		return "beta"
+	case StabilityProto:
+		return "proto"
+	case StabilitySample:
+		return "sample"
+	case StabilityDemo:
+		return "demo"
+	}
+	return fmt.Sprintf("Stability(%d)", int(s))
+}
+
 var (
 	// Regex patterns for parsing release names
 	revisionPattern = regexp.MustCompile(`\(Rev\s*([A-Z0-9]+)\)|\(v([0-9.]+)\)`)
@@ -66,8 +106,16 @@ type PreferenceSelector struct {
 	config PreferenceConfig
 }
 
-// NewPreferenceSelector creates a new preference selector.
+// NewPreferenceSelector creates a new preference selector. If config sets
+// no score weights, the default weights are used.
 func NewPreferenceSelector(db *sql.DB, config PreferenceConfig) *PreferenceSelector {
+	if !config.hasWeights() {
+		defaults := DefaultPreferenceConfig()
+		config.LanguageWeight = defaults.LanguageWeight
+		config.StabilityWeight = defaults.StabilityWeight
+		config.RevisionWeight = defaults.RevisionWeight
+		config.RegionWeight = defaults.RegionWeight
+	}
 	return &PreferenceSelector{
 		db:     db,
 		config: config,
@@ -88,10 +136,11 @@ func (p *PreferenceSelector) SelectPreferred(ctx context.Context, systemID int64
 		return fmt.Errorf("failed to get releases: %w", err)
 	}
 
-	// Group by base title
+	// Group by base title, or by parent/clone links
+	keys := p.groupKeys(releases)
 	groups := make(map[string][]*ReleaseCandidate)
 	for i := range releases {
-		groups[releases[i].BaseTitle] = append(groups[releases[i].BaseTitle], &releases[i])
+		groups[keys[i]] = append(groups[keys[i]], &releases[i])
 	}
 
 	// Select preferred for each group
@@ -124,7 +173,8 @@ func (p *PreferenceSelector) SelectPreferred(ctx context.Context, systemID int64
 
 func (p *PreferenceSelector) getReleases(ctx context.Context, systemID int64) ([]ReleaseCandidate, error) {
 	rows, err := p.db.QueryContext(ctx, `
-		SELECT id, name FROM releases WHERE system_id = ?
+		SELECT id, name, COALESCE(parent_id, 0) FROM releases WHERE system_id = ?
+		ORDER BY name, id
 	`, systemID)
 	if err != nil {
 		return nil, err
@@ -134,7 +184,7 @@ func (p *PreferenceSelector) getReleases(ctx context.Context, systemID int64) ([
 	var releases []ReleaseCandidate
 	for rows.Next() {
 		var r ReleaseCandidate
-		if err := rows.Scan(&r.ReleaseID, &r.Name); err != nil {
+		if err := rows.Scan(&r.ReleaseID, &r.Name, &r.ParentID); err != nil {
 			return nil, err
 		}
 		p.parseReleaseName(&r)
@@ -144,6 +194,41 @@ func (p *PreferenceSelector) getReleases(ctx context.Context, systemID int64) ([
 	return releases, nil
 }
 
+// groupKeys returns the preference group of each release: its base title, or
+// with GroupByClones the root of its parent/clone family when it has one.
+func (p *PreferenceSelector) groupKeys(releases []ReleaseCandidate) []string {
+	keys := make([]string, len(releases))
+	if !p.config.GroupByClones {
+		for i := range releases {
+			keys[i] = "title:" + releases[i].BaseTitle
+		}
+		return keys
+	}
+
+	parents := make(map[int64]int64)
+	hasClones := make(map[int64]bool)
+	for _, r := range releases {
+		if r.ParentID != 0 {
+			parents[r.ReleaseID] = r.ParentID
+			hasClones[r.ParentID] = true
+		}
+	}
+
+	for i, r := range releases {
+		if r.ParentID == 0 && !hasClones[r.ReleaseID] {
+			keys[i] = "title:" + r.BaseTitle
+			continue
+		}
+		// Follow the links to the root, guarding against cycles
+		root := r.ReleaseID
+		for steps := 0; parents[root] != 0 && steps < len(releases); steps++ {
+			root = parents[root]
+		}
+		keys[i] = fmt.Sprintf("clone:%d", root)
+	}
+	return keys
+}
+
 func (p *PreferenceSelector) parseReleaseName(r *ReleaseCandidate) {
 	name := r.Name
 
@@ -179,12 +264,7 @@ func (p *PreferenceSelector) parseReleaseName(r *ReleaseCandidate) {
 	}
 
 	// Parse regions
-	if matches := regionPattern.FindStringSubmatch(name); matches != nil {
-		regions := strings.Split(matches[1], ", ")
-		for _, region := range regions {
-			r.Regions = append(r.Regions, strings.TrimSpace(region))
-		}
-	}
+	r.Regions = append(r.Regions, parseRegions(name)...)
 
 	// Parse languages
 	if matches := languagePattern.FindStringSubmatch(name); matches != nil {
@@ -205,6 +285,20 @@ func (p *PreferenceSelector) parseReleaseName(r *ReleaseCandidate) {
 	}
 }
 
+// parseRegions returns the regions in a No-Intro style release name, such as
+// ["USA", "Europe"] for "Game (USA, Europe)", or nil when it names none.
+func parseRegions(name string) []string {
+	matches := regionPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return nil
+	}
+	var regions []string
+	for _, region := range strings.Split(matches[1], ", ") {
+		regions = append(regions, strings.TrimSpace(region))
+	}
+	return regions
+}
+
 func parseVersion(v string) int {
 	// Convert "1.2" to 120 for comparison
 	parts := strings.Split(v, ".")
@@ -226,12 +320,20 @@ func (p *PreferenceSelector) selectFromGroup(candidates []*ReleaseCandidate) {
 
 	// Score each candidate
 	for _, c := range candidates {
-		c.Score = p.scoreCandidate(c)
+		c.Breakdown = p.scoreCandidate(c)
+		c.Score = c.Breakdown.Total()
 	}
 
-	// Sort by score (highest first)
+	// Sort by score (highest first). In clone groups the parent wins ties,
+	// so a clone is only chosen when it is actually preferred.
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Score > candidates[j].Score
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if p.config.GroupByClones && (candidates[i].ParentID == 0) != (candidates[j].ParentID == 0) {
+			return candidates[i].ParentID == 0
+		}
+		return false
 	})
 
 	// Mark preferred (first one)
@@ -244,10 +346,23 @@ func (p *PreferenceSelector) selectFromGroup(candidates []*ReleaseCandidate) {
 	}
 }
 
-func (p *PreferenceSelector) scoreCandidate(c *ReleaseCandidate) int {
-	score := 0
+// ScoreBreakdown holds the components of a candidate's preference score.
+type ScoreBreakdown struct {
+	Language  int `json:"language"`
+	Stability int `json:"stability"`
+	Revision  int `json:"revision"`
+	Region    int `json:"region"`
+}
+
+// Total returns the combined score.
+func (b ScoreBreakdown) Total() int {
+	return b.Language + b.Stability + b.Revision + b.Region
+}
+
+func (p *PreferenceSelector) scoreCandidate(c *ReleaseCandidate) ScoreBreakdown {
+	var score ScoreBreakdown
 
-	// Language: must include English (+1000)
+	// Language: must include English
 	hasEnglish := false
 	for _, lang := range c.Languages {
 		if lang == "En" || lang == "English" {
@@ -256,31 +371,32 @@ func (p *PreferenceSelector) scoreCandidate(c *ReleaseCandidate) int {
 		}
 	}
 	if hasEnglish {
-		score += 1000
+		score.Language = p.config.LanguageWeight
 	}
 
 	// Stability: stable > beta > proto > sample > demo
+	weight := p.config.StabilityWeight
 	switch c.Stability {
 	case StabilityStable:
-		score += 500
+		score.Stability = weight
 	case StabilityBeta:
-		score += 100
+		score.Stability = weight / 5
 	case StabilityProto:
-		score += 50
+		score.Stability = weight / 10
 	case StabilitySample:
-		score += 25
+		score.Stability = weight / 20
 	case StabilityDemo:
-		score += 10
+		score.Stability = weight / 50
 	}
 
 	// Revision: higher is better
-	score += c.Revision * 10
+	score.Revision = c.Revision * p.config.RevisionWeight
 
 	// Region: use config order
 	for i, preferredRegion := range p.config.RegionOrder {
 		for _, region := range c.Regions {
 			if strings.Contains(region, preferredRegion) {
-				score += (len(p.config.RegionOrder) - i) * 50
+				score.Region = (len(p.config.RegionOrder) - i) * p.config.RegionWeight
 				goto regionDone
 			}
 		}
@@ -0,0 +1,101 @@
+package library
+
+import (
+	"context"
+	"fmt"
+)
+
+// CandidateExplanation is one release in a preference group, with the
+// recomputed score breakdown alongside the selection stored in the database.
+type CandidateExplanation struct {
+	ReleaseCandidate
+	StoredPreferred    bool   `json:"storedPreferred"`
+	StoredIgnoreReason string `json:"storedIgnoreReason,omitempty"`
+}
+
+// PreferenceExplanation describes how the preferred release of a group is chosen.
+type PreferenceExplanation struct {
+	Release    string                 `json:"release,omitempty"` // Release asked about; empty when explaining a title
+	BaseTitle  string                 `json:"baseTitle"`
+	Candidates []CandidateExplanation `json:"candidates"` // Highest score first; the first is the recomputed winner
+}
+
+// Explain recomputes the preference group containing releaseName and returns
+// each candidate's score breakdown, so users can see why a release was or
+// wasn't chosen.
+func (p *PreferenceSelector) Explain(ctx context.Context, systemID int64, releaseName string) (*PreferenceExplanation, error) {
+	releases, err := p.getReleases(ctx, systemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+
+	var target *ReleaseCandidate
+	for i := range releases {
+		if releases[i].Name == releaseName {
+			target = &releases[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, NotFoundError("release", releaseName)
+	}
+
+	explanation, err := p.explainGroup(ctx, releases, target)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Release = releaseName
+	return explanation, nil
+}
+
+// ExplainGroup recomputes the preference group for baseTitle, the release
+// name before its first parenthesis (e.g. "Game" for "Game (USA) (Rev A)"),
+// and returns every candidate with its parsed tags and score breakdown. With
+// GroupByClones, the group is that of the first release with the title.
+func (p *PreferenceSelector) ExplainGroup(ctx context.Context, systemID int64, baseTitle string) (*PreferenceExplanation, error) {
+	releases, err := p.getReleases(ctx, systemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+
+	for i := range releases {
+		if releases[i].BaseTitle == baseTitle {
+			return p.explainGroup(ctx, releases, &releases[i])
+		}
+	}
+	return nil, NotFoundError("title", baseTitle)
+}
+
+// explainGroup scores the releases in target's group and attaches their stored selection.
+func (p *PreferenceSelector) explainGroup(ctx context.Context, releases []ReleaseCandidate, target *ReleaseCandidate) (*PreferenceExplanation, error) {
+	keys := p.groupKeys(releases)
+	var targetKey string
+	for i := range releases {
+		if releases[i].ReleaseID == target.ReleaseID {
+			targetKey = keys[i]
+		}
+	}
+
+	var group []*ReleaseCandidate
+	for i := range releases {
+		if keys[i] == targetKey {
+			group = append(group, &releases[i])
+		}
+	}
+	p.selectFromGroup(group)
+
+	explanation := &PreferenceExplanation{BaseTitle: target.BaseTitle}
+	for _, c := range group {
+		ce := CandidateExplanation{ReleaseCandidate: *c}
+		err := p.db.QueryRowContext(ctx, `
+			SELECT COALESCE(is_preferred, 0), COALESCE(ignore_reason, '')
+			FROM releases WHERE id = ?
+		`, c.ReleaseID).Scan(&ce.StoredPreferred, &ce.StoredIgnoreReason)
+		if err != nil {
+			return nil, WrapDBError(err, "get stored preference")
+		}
+		explanation.Candidates = append(explanation.Candidates, ce)
+	}
+
+	return explanation, nil
+}
@@ -0,0 +1,134 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestPreferenceSelector_Explain(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	_, err = database.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	for i, name := range []string{
+		"Game (Japan)",
+		"Game (USA)",
+		"Game (USA) (Rev A)",
+		"Game (Europe) (Beta)",
+		"Other Game (USA)",
+	} {
+		_, err = database.Conn().Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, i+1, name)
+		require.NoError(t, err)
+	}
+
+	selector := NewPreferenceSelector(database.Conn(), DefaultPreferenceConfig())
+	require.NoError(t, selector.SelectPreferred(ctx, 1))
+
+	explanation, err := selector.Explain(ctx, 1, "Game (Japan)")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Game", explanation.BaseTitle)
+	require.Len(t, explanation.Candidates, 4)
+
+	winner := explanation.Candidates[0]
+	assert.Equal(t, "Game (USA) (Rev A)", winner.Name)
+	assert.True(t, winner.IsPreferred)
+	assert.True(t, winner.StoredPreferred)
+	assert.Equal(t, ScoreBreakdown{Language: 1000, Stability: 500, Revision: 10, Region: 100}, winner.Breakdown)
+	assert.Equal(t, winner.Breakdown.Total(), winner.Score)
+
+	japan := explanation.Candidates[3]
+	assert.Equal(t, "Game (Japan)", japan.Name)
+	assert.Equal(t, 0, japan.Breakdown.Language)
+	assert.False(t, japan.StoredPreferred)
+	assert.Equal(t, japan.IgnoreReason, japan.StoredIgnoreReason)
+
+	_, err = selector.Explain(ctx, 1, "Missing Game (USA)")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	group, err := selector.ExplainGroup(ctx, 1, "Game")
+	require.NoError(t, err)
+	assert.Empty(t, group.Release)
+	require.Len(t, group.Candidates, 4)
+	assert.Equal(t, explanation.Candidates[0].Name, group.Candidates[0].Name)
+
+	beta := group.Candidates[2]
+	assert.Equal(t, "Game (Europe) (Beta)", beta.Name)
+	assert.Equal(t, []string{"Europe"}, beta.Regions)
+	assert.Equal(t, []string{"En"}, beta.Languages)
+	assert.Equal(t, StabilityBeta, beta.Stability)
+	assert.Equal(t, "beta", beta.Stability.String())
+	assert.Equal(t, "less-stable,older-revision", beta.StoredIgnoreReason)
+
+	_, err = selector.ExplainGroup(ctx, 1, "Missing Game")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestPreferenceSelector_GroupByClones(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	_, err = database.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	for _, r := range []struct {
+		id      int64
+		name    string
+		cloneOf string
+		parent  interface{}
+	}{
+		// The DAT links titles that base-title grouping keeps apart
+		{1, "Rockman (Japan)", "", nil},
+		{2, "Mega Man (USA)", "Rockman (Japan)", 1},
+		// Equal scores: the parent is kept even though the clone sorts first
+		{3, "Zeta Game (Europe)", "", nil},
+		{4, "Alpha Game (Europe)", "Zeta Game (Europe)", 3},
+		// Unlinked releases still group by base title
+		{5, "Alpha Game (USA)", "", nil},
+		{6, "Alpha Game (Japan)", "", nil},
+	} {
+		_, err = database.Conn().Exec(`INSERT INTO releases (id, system_id, name, clone_of, parent_id) VALUES (?, 1, ?, ?, ?)`,
+			r.id, r.name, r.cloneOf, r.parent)
+		require.NoError(t, err)
+	}
+
+	preferred := func() []string {
+		rows, err := database.Conn().Query(`SELECT name FROM releases WHERE is_preferred = 1 ORDER BY name`)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	selector := NewPreferenceSelector(database.Conn(), DefaultPreferenceConfig())
+	require.NoError(t, selector.SelectPreferred(ctx, 1))
+	assert.Equal(t, []string{"Alpha Game (Europe)", "Mega Man (USA)", "Rockman (Japan)", "Zeta Game (Europe)"}, preferred())
+
+	cfg := DefaultPreferenceConfig()
+	cfg.GroupByClones = true
+	selector = NewPreferenceSelector(database.Conn(), cfg)
+	require.NoError(t, selector.SelectPreferred(ctx, 1))
+	assert.Equal(t, []string{"Alpha Game (USA)", "Mega Man (USA)", "Zeta Game (Europe)"}, preferred())
+
+	explanation, err := selector.Explain(ctx, 1, "Rockman (Japan)")
+	require.NoError(t, err)
+	require.Len(t, explanation.Candidates, 2)
+	assert.Equal(t, "Mega Man (USA)", explanation.Candidates[0].Name)
+	assert.Equal(t, int64(1), explanation.Candidates[0].ParentID)
+}
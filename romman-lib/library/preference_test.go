@@ -14,6 +14,30 @@ func TestDefaultPreferenceConfig(t *testing.T) {
 	assert.Contains(t, cfg.RegionOrder, "Japan")
 }
 
+func TestPreferenceSelector_ScoreWeights(t *testing.T) {
+	pick := func(cfg PreferenceConfig) string {
+		selector := NewPreferenceSelector(nil, cfg)
+		var group []*ReleaseCandidate
+		for _, name := range []string{"Game (Japan)", "Game (USA)"} {
+			c := &ReleaseCandidate{Name: name}
+			selector.parseReleaseName(c)
+			group = append(group, c)
+		}
+		selector.selectFromGroup(group)
+		return group[0].Name
+	}
+
+	// Unset weights keep the defaults, so English wins over region order
+	assert.Equal(t, "Game (USA)", pick(PreferenceConfig{RegionOrder: []string{"Japan", "USA"}}))
+
+	regionFirst := DefaultPreferenceConfig()
+	regionFirst.RegionOrder = []string{"Japan", "USA"}
+	assert.Equal(t, "Game (USA)", pick(regionFirst))
+
+	regionFirst.RegionWeight = 2000
+	assert.Equal(t, "Game (Japan)", pick(regionFirst))
+}
+
 func TestParseVersion(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -56,6 +56,25 @@ func (r *Renamer) Rename(ctx context.Context, libraryName string, dryRun bool) (
 		return nil, err
 	}
 
+	result, err := r.renameFiles(ctx, lib, dryRun, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// Record results
+	tracing.AddSpanAttributes(span,
+		attribute.Int("result.renamed", result.Renamed),
+		attribute.Int("result.skipped", result.Skipped),
+		attribute.Int("result.errors", result.Errors),
+	)
+
+	return result, nil
+}
+
+// renameFiles renames a library's matched files to their DAT names. If only
+// is non-nil, files whose id it does not contain are left out.
+func (r *Renamer) renameFiles(ctx context.Context, lib *Library, dryRun bool, only map[int64]bool) (*RenameResult, error) {
 	result := &RenameResult{DryRun: dryRun}
 
 	// Get all matched files with their expected names
@@ -65,7 +84,7 @@ func (r *Renamer) Rename(ctx context.Context, libraryName string, dryRun bool) (
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
-		WHERE sf.library_id = ? AND sf.archive_path IS NULL
+		WHERE sf.library_id = ? AND sf.archive_path IS NULL AND sf.virtual = 0
 		ORDER BY sf.path
 	`, lib.ID)
 	if err != nil {
@@ -79,6 +98,9 @@ func (r *Renamer) Rename(ctx context.Context, libraryName string, dryRun bool) (
 		if err := rows.Scan(&fileID, &currentPath, &romName, &releaseName); err != nil {
 			continue
 		}
+		if only != nil && !only[fileID] {
+			continue
+		}
 
 		// Determine new filename
 		dir := filepath.Dir(currentPath)
@@ -149,14 +171,7 @@ func (r *Renamer) Rename(ctx context.Context, libraryName string, dryRun bool) (
 		result.Actions = append(result.Actions, action)
 	}
 
-	// Record results
-	tracing.AddSpanAttributes(span,
-		attribute.Int("result.renamed", result.Renamed),
-		attribute.Int("result.skipped", result.Skipped),
-		attribute.Int("result.errors", result.Errors),
-	)
-
-	return result, nil
+	return result, rows.Err()
 }
 
 // sanitizeFilename removes or replaces invalid characters.
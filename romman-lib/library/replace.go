@@ -0,0 +1,304 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ReplaceOptions configures a replace operation.
+type ReplaceOptions struct {
+	// QuarantineBase is where replaced files are moved, under a per-system
+	// subdirectory as with cleanup plans.
+	QuarantineBase string
+	// Structure and OutputDir place the new file as organize would. An empty
+	// OutputDir places it beside the file it replaces.
+	Structure string
+	OutputDir string
+	// RenameToDAT names the new file after its release.
+	RenameToDAT bool
+	DryRun      bool
+}
+
+// ReplacedFile is a library file moved to quarantine by a replace.
+type ReplacedFile struct {
+	Path           string `json:"path"`
+	ArchivePath    string `json:"archivePath,omitempty"`
+	MatchType      string `json:"matchType"`
+	Flags          string `json:"flags,omitempty"`
+	QuarantinePath string `json:"quarantinePath"`
+}
+
+// ReplaceResult describes a replace operation.
+type ReplaceResult struct {
+	Release  string         `json:"release"`
+	NewFile  string         `json:"newFile"`
+	DestPath string         `json:"destPath"`
+	SHA1     string         `json:"sha1"`
+	Replaced []ReplacedFile `json:"replaced"`
+	DryRun   bool           `json:"dryRun"`
+}
+
+// replaceCandidate is a matched library file considered for replacement.
+type replaceCandidate struct {
+	id int64
+	ReplacedFile
+}
+
+// Replace upgrades a release held only by flagged or weakly matched files.
+// newFile must match a ROM entry of the library by SHA1; it is moved into
+// the library and matched, and the files it supersedes are quarantined.
+func (s *Scanner) Replace(ctx context.Context, libraryName, newFile string, opts ReplaceOptions) (*ReplaceResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.Replace",
+		tracing.WithAttributes(attribute.String("library.name", libraryName)),
+	)
+	defer span.End()
+
+	result, err := s.replace(ctx, libraryName, newFile, opts)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Scanner) replace(ctx context.Context, libraryName, newFile string, opts ReplaceOptions) (*ReplaceResult, error) {
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+	if opts.QuarantineBase == "" {
+		return nil, fmt.Errorf("%w: quarantine directory is required", ErrInvalidArg)
+	}
+
+	info, err := os.Stat(newFile)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrInvalidArg, newFile)
+	}
+
+	sha1Hash, crc32Hash, md5Hash, err := s.hashFile(newFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", newFile, err)
+	}
+
+	systemIDs, err := librarySystemIDs(ctx, s.db, lib)
+	if err != nil {
+		return nil, err
+	}
+	romEntryID, releaseName, systemName, err := s.findVerifiedEntry(ctx, systemIDs, sha1Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.replaceCandidates(ctx, lib.ID, romEntryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s is not held by a flagged or unverified file in %s", ErrInvalidArg, releaseName, lib.Name)
+	}
+
+	quarantineDir := filepath.Join(opts.QuarantineBase, systemName)
+	result := &ReplaceResult{
+		Release: releaseName,
+		NewFile: newFile,
+		SHA1:    sha1Hash,
+		DryRun:  opts.DryRun,
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		// A zip is only quarantined whole when the replaced ROM is its only entry
+		if c.ArchivePath != "" {
+			var entries int
+			if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM scanned_files WHERE library_id = ? AND path = ?`, lib.ID, c.Path).Scan(&entries); err != nil {
+				return nil, WrapDBError(err, "count archive entries")
+			}
+			if entries > 1 {
+				return nil, fmt.Errorf("%w: %s holds other ROMs and cannot be quarantined", ErrInvalidArg, c.Path)
+			}
+		}
+		c.QuarantinePath = quarantinePath(lib.RootPath, quarantineDir, c.Path)
+		result.Replaced = append(result.Replaced, c.ReplacedFile)
+	}
+
+	organizeOpts := OrganizeOptions{
+		OutputDir:   opts.OutputDir,
+		Structure:   opts.Structure,
+		RenameToDAT: opts.RenameToDAT,
+	}
+	if organizeOpts.OutputDir == "" {
+		organizeOpts.OutputDir = filepath.Dir(candidates[0].Path)
+		organizeOpts.Structure = ""
+	}
+	// The genre directories of organize's genre structures need the release's scraped genre
+	var genre string
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(gm.genre, '') FROM rom_entries re
+		JOIN game_metadata gm ON gm.release_id = re.release_id
+		WHERE re.id = ?`, romEntryID).Scan(&genre); err != nil && err != sql.ErrNoRows {
+		return nil, WrapDBError(err, "get genre")
+	}
+	result.DestPath = (&Organizer{}).buildDestPath(newFile, releaseName, systemName, genre, organizeOpts)
+
+	if rel, err := filepath.Rel(lib.RootPath, result.DestPath); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("%w: destination %s is outside the library", ErrInvalidArg, result.DestPath)
+	}
+	if _, err := os.Stat(result.DestPath); err == nil && !replacedPath(candidates, result.DestPath) {
+		return nil, fmt.Errorf("%w: destination %s already exists", ErrInvalidArg, result.DestPath)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	// Quarantine first, since the new file often takes the old file's name
+	for i, c := range candidates {
+		if err := moveFile(c.Path, c.QuarantinePath); err != nil {
+			restoreQuarantined(candidates[:i])
+			return nil, fmt.Errorf("failed to quarantine %s: %w", c.Path, err)
+		}
+	}
+	if err := moveFile(newFile, result.DestPath); err != nil {
+		restoreQuarantined(candidates)
+		return nil, fmt.Errorf("failed to move %s into place: %w", newFile, err)
+	}
+
+	if err := s.recordReplacement(ctx, lib, candidates, result.DestPath, romEntryID, sha1Hash, crc32Hash, md5Hash); err != nil {
+		// Undo the moves so the files agree with the unchanged database
+		_ = moveFile(result.DestPath, newFile)
+		restoreQuarantined(candidates)
+		return nil, err
+	}
+	return result, nil
+}
+
+// findVerifiedEntry looks up the ROM entry a SHA1 belongs to within the given systems.
+func (s *Scanner) findVerifiedEntry(ctx context.Context, systemIDs []int64, sha1Hash string) (int64, string, string, error) {
+	placeholders := make([]string, len(systemIDs))
+	args := []interface{}{strings.ToLower(sha1Hash)}
+	for i, id := range systemIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	var romEntryID int64
+	var releaseName, systemName string
+	// #nosec G202 - only placeholders are concatenated
+	err := s.db.QueryRowContext(ctx, `
+		SELECT re.id, r.name, sy.name
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		JOIN systems sy ON sy.id = r.system_id
+		WHERE re.sha1 = ? AND r.system_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY re.id
+		LIMIT 1
+	`, args...).Scan(&romEntryID, &releaseName, &systemName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", "", fmt.Errorf("%w: file does not match any known ROM by SHA1", ErrInvalidArg)
+	}
+	if err != nil {
+		return 0, "", "", WrapDBError(err, "find ROM entry")
+	}
+	return romEntryID, releaseName, systemName, nil
+}
+
+// replaceCandidates returns the library files matched to a ROM entry by a
+// weak match or with status flags. An entry that already has a clean
+// verified file returns none.
+func (s *Scanner) replaceCandidates(ctx context.Context, libraryID, romEntryID int64) ([]replaceCandidate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sf.id, sf.path, COALESCE(sf.archive_path, ''), m.match_type, COALESCE(m.flags, '')
+		FROM matches m
+		JOIN scanned_files sf ON sf.id = m.scanned_file_id
+		WHERE sf.library_id = ? AND m.rom_entry_id = ? AND sf.virtual = 0
+		ORDER BY sf.path, sf.archive_path
+	`, libraryID, romEntryID)
+	if err != nil {
+		return nil, WrapDBError(err, "find matched files")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var candidates []replaceCandidate
+	for rows.Next() {
+		var c replaceCandidate
+		if err := rows.Scan(&c.id, &c.Path, &c.ArchivePath, &c.MatchType, &c.Flags); err != nil {
+			return nil, err
+		}
+		if isVerifiedMatch(c.MatchType) && c.Flags == "" {
+			return nil, nil
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// recordReplacement swaps the quarantined files for the new one in the
+// database and matches it to the ROM entry it verified against, so status is
+// correct without a rescan. Nothing is changed if any step fails.
+func (s *Scanner) recordReplacement(ctx context.Context, lib *Library, replaced []replaceCandidate, destPath string, romEntryID int64, sha1Hash, crc32Hash, md5Hash string) error {
+	info, err := os.Stat(destPath)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return WrapDBError(err, "begin transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, c := range replaced {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM matches WHERE scanned_file_id = ?`, c.id); err != nil {
+			return WrapDBError(err, "clear matches")
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scanned_files WHERE id = ?`, c.id); err != nil {
+			return WrapDBError(err, "remove replaced file")
+		}
+	}
+
+	if err := upsertScannedFile(tx, lib.ID, destPath, "", info.Size(), info.ModTime().Unix(), sha1Hash, crc32Hash, md5Hash, "", "", headerlessHash{}); err != nil {
+		return WrapDBError(err, "store replacement")
+	}
+	var fileID int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT id FROM scanned_files WHERE library_id = ? AND path = ? AND archive_path IS NULL
+	`, lib.ID, destPath).Scan(&fileID); err != nil {
+		return WrapDBError(err, "load replacement")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM matches WHERE scanned_file_id = ?`, fileID); err != nil {
+		return WrapDBError(err, "clear matches")
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (?, ?, 'sha1')
+	`, fileID, romEntryID); err != nil {
+		return WrapDBError(err, "match replacement")
+	}
+	return WrapDBError(tx.Commit(), "commit replacement")
+}
+
+// replacedPath reports whether path is one of the files being quarantined.
+func replacedPath(candidates []replaceCandidate, path string) bool {
+	for _, c := range candidates {
+		if c.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreQuarantined moves quarantined files back after a failed replace.
+func restoreQuarantined(moved []replaceCandidate) {
+	for _, c := range moved {
+		_ = moveFile(c.QuarantinePath, c.Path)
+	}
+}
@@ -0,0 +1,149 @@
+package library
+
+import (
+	"context"
+	"crypto/sha1" // #nosec G505 - matches the DAT hash format
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestScanner_Replace(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	good := []byte("good dump")
+	sum := sha1.Sum(good) // #nosec G401
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES (1, 1, 'Game (USA).nes', ?)`, hex.EncodeToString(sum[:]))
+	require.NoError(t, err)
+
+	// The library only has a bad dump, matched by name
+	libPath := filepath.Join(tmpDir, "roms")
+	require.NoError(t, os.MkdirAll(libPath, 0755))                                                      // #nosec G301
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "Game (USA) [b].nes"), []byte("bad"), 0644)) // #nosec G306
+	newFile := filepath.Join(tmpDir, "download.nes")
+	require.NoError(t, os.WriteFile(newFile, good, 0644)) // #nosec G306
+
+	_, err = NewManager(conn).Add(ctx, "lib", libPath, "nes")
+	require.NoError(t, err)
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "lib")
+	require.NoError(t, err)
+
+	quarantine := filepath.Join(tmpDir, "quarantine")
+	opts := ReplaceOptions{QuarantineBase: quarantine, RenameToDAT: true, DryRun: true}
+
+	result, err := scanner.Replace(ctx, "lib", newFile, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "Game (USA)", result.Release)
+	assert.Equal(t, filepath.Join(libPath, "Game (USA).nes"), result.DestPath)
+	require.Len(t, result.Replaced, 1)
+	assert.Equal(t, "name_modified", result.Replaced[0].MatchType)
+	assert.Equal(t, filepath.Join(quarantine, "nes", "Game (USA) [b].nes"), result.Replaced[0].QuarantinePath)
+	assert.FileExists(t, newFile, "dry run must not move files")
+
+	opts.DryRun = false
+	_, err = scanner.Replace(ctx, "lib", newFile, opts)
+	require.NoError(t, err)
+	assert.NoFileExists(t, newFile)
+	assert.FileExists(t, filepath.Join(libPath, "Game (USA).nes"))
+	assert.FileExists(t, filepath.Join(quarantine, "nes", "Game (USA) [b].nes"))
+
+	// The database reflects the swap without a rescan
+	statuses, err := scanner.GetLibraryStatusWithOptions(ctx, "lib", StatusOptions{VerifiedOnly: true})
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "present", statuses[0].Status)
+	unmatched, err := scanner.GetUnmatchedFiles(ctx, "lib")
+	require.NoError(t, err)
+	assert.Empty(t, unmatched)
+
+	// A release that already has a clean verified file is left alone
+	another := filepath.Join(tmpDir, "again.nes")
+	require.NoError(t, os.WriteFile(another, good, 0644)) // #nosec G306
+	_, err = scanner.Replace(ctx, "lib", another, opts)
+	assert.ErrorIs(t, err, ErrInvalidArg)
+
+	// Files that match nothing are rejected
+	unknown := filepath.Join(tmpDir, "unknown.nes")
+	require.NoError(t, os.WriteFile(unknown, []byte("other"), 0644)) // #nosec G306
+	_, err = scanner.Replace(ctx, "lib", unknown, opts)
+	assert.ErrorContains(t, err, "does not match any known ROM")
+}
+
+func TestScanner_ReplaceRollsBack(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	database, err := db.Open(ctx, filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	good := []byte("good dump")
+	sum := sha1.Sum(good) // #nosec G401
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (1, 2, 'Game (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES (1, 1, 'Game (USA).sfc', ?)`, hex.EncodeToString(sum[:]))
+	require.NoError(t, err)
+
+	// A multi-system library whose fallback system is nes holds a bad snes dump
+	libPath := filepath.Join(tmpDir, "roms")
+	badPath := filepath.Join(libPath, "snes", "Game (USA) [b].sfc")
+	require.NoError(t, os.MkdirAll(filepath.Dir(badPath), 0755))   // #nosec G301
+	require.NoError(t, os.WriteFile(badPath, []byte("bad"), 0644)) // #nosec G306
+	newFile := filepath.Join(tmpDir, "download.sfc")
+	require.NoError(t, os.WriteFile(newFile, good, 0644)) // #nosec G306
+
+	manager := NewManager(conn)
+	_, err = manager.Add(ctx, "lib", libPath, "nes")
+	require.NoError(t, err)
+	require.NoError(t, manager.SetMultiSystem(ctx, "lib", true))
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "lib")
+	require.NoError(t, err)
+
+	quarantine := filepath.Join(tmpDir, "quarantine")
+	opts := ReplaceOptions{QuarantineBase: quarantine, RenameToDAT: true, DryRun: true}
+	result, err := scanner.Replace(ctx, "lib", newFile, opts)
+	require.NoError(t, err)
+	require.Len(t, result.Replaced, 1)
+	assert.Equal(t, filepath.Join(quarantine, "snes", "snes", "Game (USA) [b].sfc"), result.Replaced[0].QuarantinePath)
+
+	// A database failure puts every file back where it was
+	_, err = conn.Exec(`CREATE TRIGGER fail_match BEFORE INSERT ON matches BEGIN SELECT RAISE(ABORT, 'match failed'); END`)
+	require.NoError(t, err)
+	opts.DryRun = false
+	_, err = scanner.Replace(ctx, "lib", newFile, opts)
+	require.ErrorContains(t, err, "match failed")
+	assert.FileExists(t, newFile)
+	assert.FileExists(t, badPath)
+	assert.NoFileExists(t, result.DestPath)
+	assert.NoFileExists(t, result.Replaced[0].QuarantinePath)
+
+	var paths []string
+	rows, err := conn.Query(`SELECT path FROM scanned_files`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var path string
+		require.NoError(t, rows.Scan(&path))
+		paths = append(paths, path)
+	}
+	assert.Equal(t, []string{badPath}, paths)
+}
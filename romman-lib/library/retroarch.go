@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // RetroArchPlaylist represents the JSON structure of a .lpl file.
@@ -31,6 +33,13 @@ type RetroArchPlaylistItem struct {
 	DBName   string `json:"db_name"`
 }
 
+// PlaylistOptions configures which matched files a RetroArch playlist lists.
+type PlaylistOptions struct {
+	PreferredOnly  bool     // Only include releases in the 1G1R preferred set
+	Regions        []string // Only include releases from these regions, e.g. "USA", "Europe"
+	ExcludeFlagged bool     // Leave out matches carrying one of ExcludedFlags
+}
+
 // RetroArchExporter generates RetroArch-compatible playlists.
 type RetroArchExporter struct {
 	db      *sql.DB
@@ -45,15 +54,59 @@ func NewRetroArchExporter(db *sql.DB) *RetroArchExporter {
 	}
 }
 
-// ExportPlaylist generates a .lpl playlist for a library.
-func (e *RetroArchExporter) ExportPlaylist(ctx context.Context, libraryName, outputPath string) error {
+// ExportPlaylist generates a .lpl playlist for a library. With no region
+// filter every matched file is listed, including regionless homebrew; with
+// one, only releases whose name carries one of the regions are.
+func (e *RetroArchExporter) ExportPlaylist(ctx context.Context, libraryName, outputPath string, opts PlaylistOptions) error {
+	playlist, err := e.buildPlaylist(ctx, libraryName, opts)
+	if err != nil {
+		return err
+	}
+
+	// Ensure output directory exists
+	// #nosec G301
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Write playlist file
+	data, err := json.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal playlist: %w", err)
+	}
+
+	// #nosec G306
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+
+	return nil
+}
+
+// WritePlaylist streams a .lpl playlist for a library to w.
+func (e *RetroArchExporter) WritePlaylist(ctx context.Context, libraryName string, w io.Writer, opts PlaylistOptions) error {
+	playlist, err := e.buildPlaylist(ctx, libraryName, opts)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(playlist); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+	return nil
+}
+
+// buildPlaylist collects the playlist entries for a library's matched files.
+func (e *RetroArchExporter) buildPlaylist(ctx context.Context, libraryName string, opts PlaylistOptions) (*RetroArchPlaylist, error) {
 	lib, err := e.manager.Get(ctx, libraryName)
 	if err != nil {
-		return fmt.Errorf("library not found: %w", err)
+		return nil, fmt.Errorf("library not found: %w", err)
 	}
 
 	// Query matched files with release info
-	rows, err := e.db.QueryContext(ctx, `
+	query := `
 		SELECT 
 			sf.path,
 			sf.archive_path,
@@ -63,11 +116,18 @@ func (e *RetroArchExporter) ExportPlaylist(ctx context.Context, libraryName, out
 		JOIN matches m ON m.scanned_file_id = sf.id
 		JOIN rom_entries re ON m.rom_entry_id = re.id
 		JOIN releases r ON re.release_id = r.id
-		WHERE sf.library_id = ?
-		ORDER BY r.name
-	`, lib.ID)
+		WHERE sf.library_id = ? AND sf.virtual = 0`
+	if opts.PreferredOnly {
+		query += " AND r.is_preferred = 1"
+	}
+	if opts.ExcludeFlagged {
+		query += " AND " + unflaggedMatchClause
+	}
+	query += " ORDER BY r.name, sf.path, sf.archive_path"
+
+	rows, err := e.db.QueryContext(ctx, query, lib.ID)
 	if err != nil {
-		return fmt.Errorf("failed to query matched files: %w", err)
+		return nil, fmt.Errorf("failed to query matched files: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
@@ -84,22 +144,39 @@ func (e *RetroArchExporter) ExportPlaylist(ctx context.Context, libraryName, out
 		SortMode:           0,
 		Items:              []RetroArchPlaylistItem{},
 	}
+	discSets := make(map[string]bool)
 
 	for rows.Next() {
 		var filePath, archivePath, crc32, label string
 		var archivePathNull sql.NullString
 		if err := rows.Scan(&filePath, &archivePathNull, &crc32, &label); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if archivePathNull.Valid {
 			archivePath = archivePathNull.String
 		}
+		if len(opts.Regions) > 0 && !hasAnyRegion(label, opts.Regions) {
+			continue
+		}
 
-		// Format path for RetroArch (zip#entry format for archives)
+		// Format path for RetroArch (zip#entry format for archives). RetroArch
+		// cannot open archives inside archives, so nested entries are left out.
 		romPath := filePath
+		if isNestedEntry(archivePath) {
+			continue
+		}
 		if archivePath != "" {
 			romPath = filePath + "#" + archivePath
+		} else {
+			// Multi-disc sets with an .m3u are listed once, pointing at the playlist
+			var skip bool
+			if label, romPath, skip = discSetEntry(label, filePath, discSets); skip {
+				continue
+			}
+			if romPath != filePath {
+				crc32 = "DETECT"
+			}
 		}
 
 		item := RetroArchPlaylistItem{
@@ -107,30 +184,34 @@ func (e *RetroArchExporter) ExportPlaylist(ctx context.Context, libraryName, out
 			Label:    label,
 			CorePath: "DETECT",
 			CoreName: "DETECT",
-			CRC32:    crc32 + "|crc",
+			CRC32:    playlistCRC(crc32),
 			DBName:   dbName,
 		}
 		playlist.Items = append(playlist.Items, item)
 	}
 
-	// Ensure output directory exists
-	// #nosec G301
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+	return &playlist, rows.Err()
+}
 
-	// Write playlist file
-	data, err := json.MarshalIndent(playlist, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal playlist: %w", err)
+// hasAnyRegion reports whether a release name carries one of regions,
+// ignoring case. Names without a region never match.
+func hasAnyRegion(name string, regions []string) bool {
+	for _, region := range parseRegions(name) {
+		for _, want := range regions {
+			if strings.EqualFold(region, strings.TrimSpace(want)) {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	// #nosec G306
-	if err := os.WriteFile(outputPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write playlist: %w", err)
+// playlistCRC formats a CRC for a playlist item; playlists without a CRC use DETECT.
+func playlistCRC(crc32 string) string {
+	if crc32 == "DETECT" {
+		return crc32
 	}
-
-	return nil
+	return crc32 + "|crc"
 }
 
 // getRetroArchDBName maps system names to RetroArch database names.
@@ -1,8 +1,10 @@
 package library
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -35,7 +37,7 @@ func TestRetroArchExporter_LibraryNotFound(t *testing.T) {
 
 	exporter := NewRetroArchExporter(database.Conn())
 
-	err = exporter.ExportPlaylist(context.Background(), "nonexistent", "/tmp/test.lpl")
+	err = exporter.ExportPlaylist(context.Background(), "nonexistent", "/tmp/test.lpl", PlaylistOptions{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "library not found")
 }
@@ -85,7 +87,7 @@ func TestRetroArchExporter_ExportPlaylist(t *testing.T) {
 	exporter := NewRetroArchExporter(database.Conn())
 
 	outputPath := filepath.Join(tmpDir, "nes.lpl")
-	err = exporter.ExportPlaylist(context.Background(), "nes", outputPath)
+	err = exporter.ExportPlaylist(context.Background(), "nes", outputPath, PlaylistOptions{})
 	require.NoError(t, err)
 
 	// Verify the file was created
@@ -105,6 +107,61 @@ func TestRetroArchExporter_ExportPlaylist(t *testing.T) {
 	assert.Equal(t, "Super Mario Bros (USA)", playlist.Items[0].Label)
 }
 
+func TestRetroArchExporter_PlaylistOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	_, err = conn.Exec("INSERT INTO systems (name) VALUES ('nes')")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO libraries (name, root_path, system_id) VALUES ('nes', '/roms/nes', 1)")
+	require.NoError(t, err)
+
+	releases := []struct {
+		name      string
+		preferred bool
+	}{
+		{"Game A (USA, Europe)", true},
+		{"Game A (Japan)", false},
+		{"Game B (Europe)", false},
+		{"Homebrew Game", true},
+	}
+	for i, r := range releases {
+		id := i + 1
+		_, err = conn.Exec("INSERT INTO releases (system_id, name, is_preferred) VALUES (1, ?, ?)", r.name, r.preferred)
+		require.NoError(t, err)
+		_, err = conn.Exec("INSERT INTO rom_entries (release_id, name, sha1, size) VALUES (?, ?, ?, 1024)",
+			id, r.name+".nes", fmt.Sprintf("sha%d", id))
+		require.NoError(t, err)
+		_, err = conn.Exec("INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32) VALUES (1, ?, 1024, 0, ?, 'AABBCCDD')",
+			fmt.Sprintf("/roms/nes/game%d.nes", id), fmt.Sprintf("sha%d", id))
+		require.NoError(t, err)
+		_, err = conn.Exec("INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (?, ?, 'sha1')", id, id)
+		require.NoError(t, err)
+	}
+
+	exporter := NewRetroArchExporter(conn)
+	labels := func(opts PlaylistOptions) []string {
+		var buf bytes.Buffer
+		require.NoError(t, exporter.WritePlaylist(context.Background(), "nes", &buf, opts))
+		var playlist RetroArchPlaylist
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &playlist))
+		var out []string
+		for _, item := range playlist.Items {
+			out = append(out, item.Label)
+		}
+		return out
+	}
+
+	assert.Len(t, labels(PlaylistOptions{}), 4)
+	assert.Equal(t, []string{"Game A (USA, Europe)", "Homebrew Game"}, labels(PlaylistOptions{PreferredOnly: true}))
+	// Region matching ignores case; regionless homebrew is left out once filtering
+	assert.Equal(t, []string{"Game A (USA, Europe)", "Game B (Europe)"}, labels(PlaylistOptions{Regions: []string{"europe"}}))
+	assert.Equal(t, []string{"Game A (USA, Europe)"}, labels(PlaylistOptions{PreferredOnly: true, Regions: []string{"USA", "Japan"}}))
+}
+
 func TestGetRetroArchDBName(t *testing.T) {
 	tests := []struct {
 		system   string
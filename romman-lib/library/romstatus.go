@@ -108,10 +108,13 @@ func isRegionCode(s string) bool {
 type MatchType string
 
 const (
+	MatchTypeSHA256    MatchType = "sha256"
 	MatchTypeSHA1      MatchType = "sha1"
+	MatchTypeMD5       MatchType = "md5"
 	MatchTypeCRC32     MatchType = "crc32"
 	MatchTypeName      MatchType = "name"       // Exact name match, but hash differs
 	MatchTypeFuzzyName MatchType = "name_fuzzy" // Fuzzy name match
+	MatchTypeManual    MatchType = "manual"     // Assigned by hand, kept by rescans
 )
 
 // NormalizeTitleForMatching normalizes a title for fuzzy matching.
@@ -187,3 +190,66 @@ func (s ROMStatus) IsModified() bool {
 func (s ROMStatus) IsProblematic() bool {
 	return s.IsBadDump || s.IsOverdump
 }
+
+// ParseStatusFlags reads the comma-separated flags of a match back into a
+// ROMStatus, the inverse of GetStatusFlags. Flags GetStatusFlags does not
+// write, such as headered or fuzzy, are ignored.
+func ParseStatusFlags(flags string) ROMStatus {
+	var s ROMStatus
+	for _, flag := range strings.Split(flags, ",") {
+		switch strings.TrimSpace(flag) {
+		case "verified":
+			s.IsVerified = true
+		case "bad-dump":
+			s.IsBadDump = true
+		case "cracked":
+			s.IsCracked = true
+		case "fixed":
+			s.IsFixed = true
+		case "hack":
+			s.IsHack = true
+		case "overdump":
+			s.IsOverdump = true
+		case "pirate":
+			s.IsPirate = true
+		case "trainer":
+			s.IsTrainer = true
+		case "translated":
+			s.IsTranslated = true
+		case "alternate":
+			s.IsAlternate = true
+		}
+	}
+	return s
+}
+
+// ExcludedFlags lists the match flags of ROMs that are problematic or
+// modified, which exports with ExcludeFlagged leave out: bad-dump and
+// overdump, then cracked, fixed, hack, pirate, trainer and translated.
+var ExcludedFlags = excludedStatusFlags()
+
+// excludedStatusFlags returns the flags GetStatusFlags writes for which
+// ROMStatus.IsProblematic or IsModified holds.
+func excludedStatusFlags() []string {
+	all := ROMStatus{
+		IsVerified: true, IsBadDump: true, IsCracked: true, IsFixed: true, IsHack: true,
+		IsOverdump: true, IsPirate: true, IsTrainer: true, IsTranslated: true, IsAlternate: true,
+	}
+	var flags []string
+	for _, flag := range strings.Split(all.GetStatusFlags(), ",") {
+		if s := ParseStatusFlags(flag); s.IsProblematic() || s.IsModified() {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// unflaggedMatchClause is a SQL condition on matches m that leaves out
+// matches carrying one of ExcludedFlags.
+var unflaggedMatchClause = func() string {
+	conds := make([]string, len(ExcludedFlags))
+	for i, flag := range ExcludedFlags {
+		conds[i] = `(',' || COALESCE(m.flags, '') || ',') NOT LIKE '%,` + flag + `,%'`
+	}
+	return strings.Join(conds, " AND ")
+}()
@@ -84,6 +84,17 @@ func TestROMStatus_GetStatusFlags_Empty(t *testing.T) {
 	assert.Empty(t, status.GetStatusFlags())
 }
 
+func TestParseStatusFlags(t *testing.T) {
+	status := ROMStatus{IsVerified: true, IsHack: true, IsTranslated: true}
+	assert.Equal(t, status, ParseStatusFlags(status.GetStatusFlags()))
+	assert.Equal(t, ROMStatus{IsBadDump: true}, ParseStatusFlags("headered,bad-dump,fuzzy"))
+	assert.Equal(t, ROMStatus{}, ParseStatusFlags(""))
+}
+
+func TestExcludedFlags(t *testing.T) {
+	assert.Equal(t, []string{"bad-dump", "cracked", "fixed", "hack", "overdump", "pirate", "trainer", "translated"}, ExcludedFlags)
+}
+
 func TestROMStatus_IsModified(t *testing.T) {
 	modified := ROMStatus{IsCracked: true}
 	assert.True(t, modified.IsModified())
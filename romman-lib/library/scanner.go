@@ -5,8 +5,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log/slog"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -22,11 +23,17 @@ import (
 
 // ScanResult contains statistics from a library scan.
 type ScanResult struct {
-	FilesScanned   int
-	FilesHashed    int
-	FilesSkipped   int // Unchanged files (hash cached)
-	MatchesFound   int
-	UnmatchedFiles int
+	FilesScanned   int           `json:"filesScanned"`
+	FilesHashed    int           `json:"filesHashed"`
+	FilesSkipped   int           `json:"filesCached"`           // Unchanged files (hash cached)
+	FilesVerified  int           `json:"filesVerified"`         // Cached files rehashed by sample verification
+	FilesPruned    int           `json:"filesPruned"`           // Entries removed because their extension is ignored
+	FilesExcluded  int           `json:"filesExcluded"`         // Generic-extension files not belonging to the system
+	DirsResumed    int           `json:"dirsResumed,omitempty"` // Directories skipped as committed by an interrupted scan
+	MatchesFound   int           `json:"matchesFound"`
+	UnmatchedFiles int           `json:"unmatchedFiles"`
+	Errors         []ScanError   `json:"errors"`
+	Duration       time.Duration `json:"-"` // Wall time of the whole scan
 }
 
 // ScannedFile represents a file found during scanning.
@@ -38,15 +45,27 @@ type ScannedFile struct {
 	Mtime       int64
 	SHA1        string
 	CRC32       string
+	MD5         string
+	SHA256      string // Empty unless scanned with scan.sha256 enabled
 	ArchivePath string // Path within zip, empty for regular files
 }
 
-// ScanProgress represents current scanning progress.
+// ScanProgress represents current scanning progress. Once the files to scan
+// have been counted, a scan reports an event carrying only TotalFiles, then
+// one per file as it is hashed or found in the cache, with CurrentPath
+// naming the file on disk. Parallel scans report each archive entry;
+// sequential scans report an archive once all its entries are done. Events
+// are only produced when OnProgress or Progress is set.
 type ScanProgress struct {
-	FilesScanned int64
-	FilesHashed  int64
-	FilesSkipped int64
-	TotalFiles   int64 // 0 if unknown
+	TotalFiles   int64  `json:"totalFiles"` // 0 if unknown
+	FilesScanned int64  `json:"filesScanned"`
+	FilesHashed  int64  `json:"filesHashed"`
+	FilesSkipped int64  `json:"filesSkipped"`
+	CurrentPath  string `json:"currentPath,omitempty"`
+
+	// Files matched so far, counted as each batch is committed, so it lags
+	// FilesScanned by up to a batch. The final count is ScanResult.MatchesFound.
+	MatchesFound int64 `json:"matchesFound"`
 }
 
 // ScanConfig configures parallel scanning behavior.
@@ -55,8 +74,91 @@ type ScanConfig struct {
 	BatchSize  int                         // Number of files per transaction batch (default: 100)
 	Parallel   bool                        // Use parallel scanning (default: true)
 	OnProgress func(progress ScanProgress) // Callback for progress updates
+
+	// Progress, if set, also receives every progress event. Sends never
+	// block the scan: events are dropped while the receiver is busy, so a
+	// slow reader only sees fewer updates. The scanner never closes it.
+	Progress chan<- ScanProgress
+
+	// ChangedOnly skips the final full re-match and relies on the per-batch
+	// checkpoints, so only files hashed during this scan are (re)matched.
+	ChangedOnly bool
+
+	// SampleVerifyPercent rehashes a random percentage of cached files each
+	// scan, ignoring the size/mtime cache, and reports changed content as
+	// corrupt errors. Over many scans this covers the whole library. 0 = off.
+	SampleVerifyPercent float64
+
+	// SplitROMs lists, per system name, rules for ROMs stored as sibling part
+	// files that only match when concatenated.
+	SplitROMs map[string][]SplitROMRule
+
+	// OneFileSystem keeps the scan on the library root's filesystem, skipping
+	// directories on other mounts (like find -xdev). Has no effect on Windows.
+	OneFileSystem bool
+
+	// IgnoreExtensions lists extra extensions to skip alongside the built-in
+	// ones, e.g. ".bak". Entries already scanned are pruned on the next scan.
+	IgnoreExtensions []string
+
+	// LibraryFilters adjusts, per library name, which files are ignored on
+	// top of the built-in list and IgnoreExtensions.
+	LibraryFilters map[string]LibraryFilter
+
+	// SystemExtensions overrides, per system name, the ROM extensions learned
+	// from the DAT. Files with no extension or a generic one (.bin, .rom)
+	// are only scanned if their system uses it; "" stands for no extension.
+	SystemExtensions map[string][]string
+
+	// Resume continues an interrupted scan by skipping the directories it
+	// had fully walked and committed, instead of walking and statting them
+	// again. It has no effect after a scan that completed.
+	Resume bool
+
+	// SHA256 also hashes files with SHA256 and matches on it before SHA1,
+	// for DATs such as Redump's that carry it. Cached files without a
+	// SHA256 are rehashed once when it is turned on.
+	SHA256 bool
+
+	// Fuzzy adds a last matching tier for files no hash or exact name
+	// matched: the release whose normalized title is within FuzzyDistance
+	// edits of the file's is matched as name_fuzzy, flagged "fuzzy". It is
+	// slower and can pick the wrong release, so it is off by default.
+	Fuzzy bool
+
+	// FuzzyDistance is the largest edit distance Fuzzy accepts (default 2).
+	FuzzyDistance int
+
+	// ParallelHashMinSize is the size from which a file's hashes are each
+	// computed in their own goroutine rather than in a single pass, for disc
+	// images and other large files on storage faster than one core hashes.
+	// 0 uses the default of 64 MiB; a negative value always uses one pass.
+	ParallelHashMinSize int64
+
+	// CacheKey picks what decides that a file is unchanged since it was
+	// hashed: CacheKeyMtimeSize (the default when empty) or CacheKeySize.
+	CacheKey string
+
+	// ForceRehash ignores the cache and hashes every file again.
+	ForceRehash bool
+
+	// NoArchives hashes .zip and .7z files whole, like any other ROM,
+	// instead of expanding them and hashing their entries. They then only
+	// match DATs that list the archive's own hashes, or by name. Entries
+	// of the other mode are dropped, so switching rehashes every archive.
+	NoArchives bool
 }
 
+// Cache keys for ScanConfig.CacheKey.
+const (
+	// CacheKeyMtimeSize rehashes files whose size or modification time changed.
+	CacheKeyMtimeSize = "mtime+size"
+	// CacheKeySize only rehashes files whose size changed, for network shares
+	// that rewrite mtimes on copy. A file changed in place without changing
+	// size keeps its old hashes until a ForceRehash scan.
+	CacheKeySize = "size"
+)
+
 // DefaultScanConfig returns sensible defaults for scanning.
 func DefaultScanConfig() ScanConfig {
 	return ScanConfig{
@@ -79,16 +181,109 @@ var ignoredExtensions = map[string]bool{
 	".cfg": true, ".lpl": true, ".opt": true,
 }
 
+// LibraryFilter adjusts which files the scanner ignores in one library.
+type LibraryFilter struct {
+	// IgnorePatterns skips files matching any of these globs, e.g. "*.sav".
+	// A pattern without a "/" is matched against the file name, one with a
+	// "/" against the path relative to the library root, as in "saves/*.bin".
+	// Case is ignored.
+	IgnorePatterns []string
+
+	// IncludeExtensions scans files with these extensions even though the
+	// built-in list or IgnoreExtensions skips them, e.g. ".txt" for manuals.
+	// IgnorePatterns still apply.
+	IncludeExtensions []string
+}
+
+// validate reports a malformed glob pattern.
+func (f LibraryFilter) validate() error {
+	for _, pattern := range f.IgnorePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: ignore pattern %q: %v", ErrInvalidArg, pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesPattern reports whether file, in a library rooted at root, matches
+// one of the filter's ignore patterns.
+func (f LibraryFilter) matchesPattern(root, file string) bool {
+	if len(f.IgnorePatterns) == 0 {
+		return false
+	}
+	name := strings.ToLower(filepath.Base(file))
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = file
+	}
+	rel = strings.ToLower(filepath.ToSlash(rel))
+
+	for _, pattern := range f.IgnorePatterns {
+		pattern = strings.ToLower(pattern)
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		// Patterns are validated before scanning, so errors cannot occur
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // isIgnoredExtension returns true if the file extension should be skipped.
 func isIgnoredExtension(ext string) bool {
 	return ignoredExtensions[ext]
 }
 
+// isIgnored reports whether a file of lib should be skipped, by the
+// library's filter, the built-in list or the configured IgnoreExtensions.
+func (s *Scanner) isIgnored(lib *Library, path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if filter, ok := s.config.LibraryFilters[lib.Name]; ok {
+		if filter.matchesPattern(lib.RootPath, path) {
+			return true
+		}
+		for _, included := range filter.IncludeExtensions {
+			if normalizeExtension(included) == ext {
+				return false
+			}
+		}
+	}
+	if isIgnoredExtension(ext) {
+		return true
+	}
+	for _, ignored := range s.config.IgnoreExtensions {
+		ignored = strings.ToLower(ignored)
+		if !strings.HasPrefix(ignored, ".") {
+			ignored = "." + ignored
+		}
+		if ext == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIgnored reports whether the scanner skips a library's file because of
+// its extension or the library's ignore patterns, so callers such as file
+// watchers can ignore the same files.
+func (s *Scanner) IsIgnored(lib *Library, path string) bool {
+	return s.isIgnored(lib, path)
+}
+
 // Scanner handles library scanning operations.
 type Scanner struct {
 	db      *sql.DB
 	manager *Manager
 	config  ScanConfig
+
+	verifier *sampleVerifier // Per-scan sample verification, nil when off
+	errs     *scanErrors     // Per-scan files that could not be read
+	headers  []romHeader     // Headers stripped for headerless hashes, loaded per scan
+	dirs     *dirTracker     // Per-scan record of fully committed directories
+	resume   *resumeFilter   // Directories committed by an interrupted scan, nil unless resuming
 }
 
 // NewScanner creates a new library scanner with default config.
@@ -117,13 +312,24 @@ func NewScannerWithConfig(db *sql.DB, config ScanConfig) *Scanner {
 
 // Scan scans a library for ROM files and matches them against the database.
 func (s *Scanner) Scan(ctx context.Context, libraryName string) (*ScanResult, error) {
-	defer metrics.RecordScanDuration(libraryName, time.Now())
+	start := time.Now()
+	defer metrics.RecordScanDuration(libraryName, start)
 
 	ctx, span := tracing.StartSpan(ctx, "scan: "+libraryName,
 		tracing.WithAttributes(attribute.String("library.name", libraryName)),
 	)
 	defer span.End()
 
+	switch s.config.CacheKey {
+	case "", CacheKeyMtimeSize, CacheKeySize:
+	default:
+		return nil, fmt.Errorf("%w: unknown cache key %q, want %q or %q", ErrInvalidArg, s.config.CacheKey, CacheKeyMtimeSize, CacheKeySize)
+	}
+
+	if err := s.config.LibraryFilters[libraryName].validate(); err != nil {
+		return nil, err
+	}
+
 	lib, err := s.manager.Get(ctx, libraryName)
 	if err != nil {
 		tracing.RecordError(span, err)
@@ -132,10 +338,45 @@ func (s *Scanner) Scan(ctx context.Context, libraryName string) (*ScanResult, er
 
 	span.SetAttributes(attribute.String("system.name", lib.SystemName))
 
+	state, err := s.scanState(ctx, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to read scan state: %w", err)
+	}
+	s.resume = s.newResumeFilter(lib, state)
+	s.dirs = newDirTracker()
+	if err := s.beginScanState(lib.ID, s.resume != nil); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to record scan state: %w", err)
+	}
+
+	s.verifier = newSampleVerifier(s.config.SampleVerifyPercent)
+	s.errs = newScanErrors(lib.Name)
+	if s.headers, err = s.loadHeaders(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to load header rules: %w", err)
+	}
+
+	var result *ScanResult
 	if s.config.Parallel && s.config.Workers > 1 {
-		return s.scanParallel(ctx, lib)
+		result, err = s.scanParallel(ctx, lib)
+	} else {
+		result, err = s.scanSequential(ctx, lib)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.finishScanState(lib.ID); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to record scan state: %w", err)
 	}
-	return s.scanSequential(ctx, lib)
+
+	s.errs.apply(result)
+	s.verifier.apply(result)
+	result.Duration = time.Since(start)
+	metrics.RecordScanResult(lib.Name, result.FilesHashed, result.FilesSkipped, result.MatchesFound, result.UnmatchedFiles)
+	return result, nil
 }
 
 // fileJob represents a file to be hashed.
@@ -145,17 +386,23 @@ type fileJob struct {
 	size        int64
 	mtime       int64
 	isZipEntry  bool
+	is7zEntry   bool
 	isCHD       bool
 	zipPath     string
+	zipCRC32    string // CRC32 declared in the zip or 7z entry header
+	seq         int64  // Order the file was handed out in, for the dirTracker
 }
 
 // hashResult contains the result of hashing a file.
 type hashResult struct {
-	job       fileJob
-	sha1      string
-	crc32     string
-	wasHashed bool // true if newly hashed, false if cache hit
-	err       error
+	job        fileJob
+	sha1       string
+	crc32      string
+	md5        string
+	sha256     string
+	headerless headerlessHash
+	wasHashed  bool // true if newly hashed, false if cache hit
+	err        error
 }
 
 // scanParallel performs parallel file discovery and hashing.
@@ -163,23 +410,35 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 	ctx, span := tracing.StartSpan(ctx, "scanParallel: "+lib.Name)
 	defer span.End()
 
+	cp, err := s.newCheckpointer(lib)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	devices := s.newDeviceFilter(lib.RootPath)
+	extensions := s.newExtensionFilter(ctx, lib)
+	var skipped []SkippedFile
+
 	jobs := make(chan fileJob, s.config.Workers*10)
 	results := make(chan hashResult, s.config.Workers*10)
 
 	var filesScanned, filesHashed, filesSkipped, totalFiles int64
+	var dirsResumed int
 
-	if s.config.OnProgress != nil {
+	if s.wantsProgress() {
 		// Quick walk to count files for progress bar
 		_ = filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
-			if err == nil && !info.IsDir() {
-				ext := strings.ToLower(filepath.Ext(path))
-				if !isIgnoredExtension(ext) {
+			if err == nil && info.IsDir() && (devices.skip(path, info) || s.resume.skip(path)) {
+				return filepath.SkipDir
+			}
+			if err == nil && !info.IsDir() && !s.isIgnored(lib, path) {
+				if reason, _ := extensions.skipReason(path); reason == "" {
 					atomic.AddInt64(&totalFiles, 1)
 				}
 			}
 			return nil
 		})
-		s.config.OnProgress(ScanProgress{TotalFiles: atomic.LoadInt64(&totalFiles)})
+		s.reportProgress(ScanProgress{TotalFiles: atomic.LoadInt64(&totalFiles)})
 	}
 
 	// Start workers
@@ -208,7 +467,8 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 
 		for r := range results {
 			if r.err != nil {
-				slog.Warn("failed to hash file", "path", r.job.path, "error", r.err)
+				s.errs.add("failed to hash file", r.job.path, r.job.archivePath, r.err)
+				s.dirs.commit(r.job.seq)
 				continue
 			}
 
@@ -221,11 +481,24 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 				atomic.AddInt64(&filesSkipped, 1)
 				metrics.FilesProcessed.WithLabelValues(lib.Name, "skipped").Inc()
 			}
+			if s.wantsProgress() {
+				s.reportProgress(ScanProgress{
+					TotalFiles:   atomic.LoadInt64(&totalFiles),
+					FilesScanned: atomic.LoadInt64(&filesScanned),
+					FilesHashed:  atomic.LoadInt64(&filesHashed),
+					FilesSkipped: atomic.LoadInt64(&filesSkipped),
+					CurrentPath:  r.job.path,
+					MatchesFound: cp.matched,
+				})
+			}
 
 			batch = append(batch, r)
 			if len(batch) >= s.config.BatchSize {
-				if err := s.storeBatch(lib.ID, batch); err != nil {
+				if err := s.storeCheckpoint(cp, batch); err != nil {
 					collectorErr = err
+					// Keep draining so workers never block on a full channel
+					for range results {
+					}
 					return
 				}
 				batch = batch[:0]
@@ -234,7 +507,7 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 
 		// Process remaining items
 		if len(batch) > 0 {
-			if err := s.storeBatch(lib.ID, batch); err != nil {
+			if err := s.storeCheckpoint(cp, batch); err != nil {
 				collectorErr = err
 			}
 		}
@@ -246,36 +519,78 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 	}()
 
 	// Walk and discover files
-	err := filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if info.IsDir() {
+			if devices.skip(path, info) {
+				return filepath.SkipDir
+			}
+			if s.resume.skip(path) {
+				dirsResumed++
+				return filepath.SkipDir
+			}
+			s.dirs.visit(path, true)
 			return nil
 		}
+		s.dirs.visit(path, false)
 
+		if s.isIgnored(lib, path) {
+			return nil
+		}
+		reason, err := extensions.skipReason(path)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			skipped = append(skipped, SkippedFile{Path: path, Reason: reason})
+			return nil
+		}
 		ext := strings.ToLower(filepath.Ext(path))
-		if isIgnoredExtension(ext) {
+
+		if ext == ".zip" && !s.config.NoArchives {
+			if err := s.queueZipEntries(ctx, lib.ID, path, info, jobs, results); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.errs.add("failed to open zip", path, "", err)
+			}
 			return nil
 		}
 
-		if ext == ".zip" {
-			if err := s.queueZipEntries(path, info, jobs); err != nil {
-				slog.Warn("failed to open zip", "path", path, "error", err)
+		if ext == ".7z" && !s.config.NoArchives {
+			if err := s.queue7zEntries(ctx, lib.ID, path, info, jobs, results); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.errs.add("failed to open 7z", path, "", err)
 			}
 			return nil
 		}
 
 		isCHD := ext == ".chd"
-		jobs <- fileJob{path: path, size: info.Size(), mtime: info.ModTime().Unix(), isCHD: isCHD}
+		select {
+		case jobs <- fileJob{path: path, size: info.Size(), mtime: info.ModTime().Unix(), isCHD: isCHD, seq: s.dirs.next()}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		return nil
 	})
 
+	// Workers finish any queued jobs so their results are committed before returning
 	close(jobs)
 	wg.Wait()
 	close(results)
 	collectorWg.Wait()
 
+	if ctx.Err() != nil {
+		tracing.RecordError(span, ctx.Err())
+		return nil, fmt.Errorf("scan interrupted: %w", ctx.Err())
+	}
 	if err != nil {
 		tracing.RecordError(span, fmt.Errorf("failed to walk library: %w", err))
 		return nil, fmt.Errorf("failed to walk library: %w", err)
@@ -285,11 +600,15 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 		return nil, fmt.Errorf("failed to store results: %w", collectorErr)
 	}
 
-	if err := s.cleanupStaleFiles(lib); err != nil {
+	if err := s.recordSkippedFiles(lib.ID, skipped); err != nil {
+		return nil, fmt.Errorf("failed to record skipped files: %w", err)
+	}
+	pruned, err := s.cleanupStaleFiles(lib)
+	if err != nil {
 		return nil, fmt.Errorf("failed to cleanup stale files: %w", err)
 	}
 
-	matchResult, err := s.matchFiles(lib)
+	matchResult, err := s.finalMatch(ctx, lib)
 	if err != nil {
 		tracing.RecordError(span, fmt.Errorf("failed to match files: %w", err))
 		return nil, fmt.Errorf("failed to match files: %w", err)
@@ -313,13 +632,17 @@ func (s *Scanner) scanParallel(ctx context.Context, lib *Library) (*ScanResult,
 		FilesScanned:   int(filesScanned),
 		FilesHashed:    int(filesHashed),
 		FilesSkipped:   int(filesSkipped),
+		FilesPruned:    pruned,
+		FilesExcluded:  len(skipped),
+		DirsResumed:    dirsResumed,
 		MatchesFound:   matchResult.MatchesFound,
 		UnmatchedFiles: matchResult.UnmatchedFiles,
 	}, nil
 }
 
-// queueZipEntries reads a zip file and queues its entries for hashing.
-func (s *Scanner) queueZipEntries(zipPath string, zipInfo os.FileInfo, jobs chan<- fileJob) error {
+// queueZipEntries reads a zip file and queues its entries for hashing. Files
+// in archives nested inside it are hashed at once and sent to results.
+func (s *Scanner) queueZipEntries(ctx context.Context, libraryID int64, zipPath string, zipInfo os.FileInfo, jobs chan<- fileJob, results chan<- hashResult) error {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return err
@@ -327,22 +650,71 @@ func (s *Scanner) queueZipEntries(zipPath string, zipInfo os.FileInfo, jobs chan
 	defer func() { _ = r.Close() }()
 
 	mtime := zipInfo.ModTime().Unix()
+	send := func(job fileJob) error {
+		select {
+		case jobs <- job:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sendResult := func(r hashResult) error {
+		select {
+		case results <- r:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	for _, f := range r.File {
 		if f.FileInfo().IsDir() {
 			continue
 		}
-		jobs <- fileJob{
+		if isArchiveName(f.Name) {
+			member := archiveMember{name: f.Name, size: int64(f.UncompressedSize64), crc32: zipHeaderCRC32(f), open: f.Open} // #nosec G115
+			if err := s.queueNested(libraryID, zipPath, member, mtime, sendResult); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.errs.add("failed to scan nested archive", zipPath, f.Name, err)
+			}
+			continue
+		}
+		job := fileJob{
 			path:        zipPath,
 			archivePath: f.Name,
 			size:        int64(f.UncompressedSize64), // #nosec G115 - safe cast for ROM sizes
 			mtime:       mtime,
 			isZipEntry:  true,
 			zipPath:     zipPath,
+			zipCRC32:    zipHeaderCRC32(f),
+			seq:         s.dirs.next(),
+		}
+		if err := send(job); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// wantsProgress reports whether progress events have a listener.
+func (s *Scanner) wantsProgress() bool {
+	return s.config.OnProgress != nil || s.config.Progress != nil
+}
+
+// reportProgress passes p to OnProgress and, without blocking, to Progress.
+func (s *Scanner) reportProgress(p ScanProgress) {
+	if s.config.OnProgress != nil {
+		s.config.OnProgress(p)
+	}
+	if s.config.Progress != nil {
+		select {
+		case s.config.Progress <- p:
+		default:
+		}
+	}
+}
+
 // hashWorker is a worker that hashes files from the jobs channel.
 func (s *Scanner) hashWorker(libraryID int64, jobs <-chan fileJob, results chan<- hashResult) {
 	for job := range jobs {
@@ -352,17 +724,23 @@ func (s *Scanner) hashWorker(libraryID int64, jobs <-chan fileJob, results chan<
 			continue
 		}
 		if cached != nil {
-			results <- hashResult{job: job, sha1: cached.SHA1, crc32: cached.CRC32, wasHashed: false}
+			s.verifier.check(s, job, cached)
+			results <- hashResult{job: job, sha1: cached.SHA1, crc32: cached.CRC32, md5: cached.MD5, sha256: cached.SHA256, wasHashed: false}
 			continue
 		}
 
-		var sha1Hash, crc32Hash string
+		var sha1Hash, crc32Hash, md5Hash string
+		sha256Hasher := s.sha256Hasher()
 		if job.isZipEntry {
-			sha1Hash, crc32Hash, err = s.hashZipEntry(job.zipPath, job.archivePath)
+			sha1Hash, crc32Hash, md5Hash, err = s.hashZipEntry(job.zipPath, job.archivePath, hashWriters(sha256Hasher)...)
+		} else if job.is7zEntry {
+			sha1Hash, crc32Hash, md5Hash, err = s.hash7zEntry(job.path, job.archivePath, hashWriters(sha256Hasher)...)
 		} else if job.isCHD {
-			sha1Hash, crc32Hash, err = s.hashCHDFile(job.path)
+			// CHD headers only record SHA1s
+			sha256Hasher = nil
+			sha1Hash, crc32Hash, md5Hash, err = s.hashCHDFile(job.path)
 		} else {
-			sha1Hash, crc32Hash, err = s.hashFile(job.path)
+			sha1Hash, crc32Hash, md5Hash, err = s.hashFile(job.path, hashWriters(sha256Hasher)...)
 		}
 
 		if err != nil {
@@ -370,7 +748,14 @@ func (s *Scanner) hashWorker(libraryID int64, jobs <-chan fileJob, results chan<
 			continue
 		}
 
-		results <- hashResult{job: job, sha1: sha1Hash, crc32: crc32Hash, wasHashed: true}
+		headerless, err := s.hashJobHeaderless(job)
+		if err != nil {
+			results <- hashResult{job: job, err: err}
+			continue
+		}
+
+		results <- hashResult{job: job, sha1: sha1Hash, crc32: crc32Hash, md5: md5Hash, sha256: hashHex(sha256Hasher),
+			headerless: headerless, wasHashed: true}
 	}
 }
 
@@ -381,20 +766,31 @@ func (s *Scanner) scanSequential(ctx context.Context, lib *Library) (*ScanResult
 
 	span.AddEvent("discovery_started")
 
+	cp, err := s.newCheckpointer(lib)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	devices := s.newDeviceFilter(lib.RootPath)
+	extensions := s.newExtensionFilter(ctx, lib)
+	var skipped []SkippedFile
+
 	result := &ScanResult{}
 	var totalFiles int64
 
-	if s.config.OnProgress != nil {
+	if s.wantsProgress() {
 		_ = filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
-			if err == nil && !info.IsDir() {
-				ext := strings.ToLower(filepath.Ext(path))
-				if !isIgnoredExtension(ext) {
+			if err == nil && info.IsDir() && (devices.skip(path, info) || s.resume.skip(path)) {
+				return filepath.SkipDir
+			}
+			if err == nil && !info.IsDir() && !s.isIgnored(lib, path) {
+				if reason, _ := extensions.skipReason(path); reason == "" {
 					totalFiles++
 				}
 			}
 			return nil
 		})
-		s.config.OnProgress(ScanProgress{TotalFiles: totalFiles})
+		s.reportProgress(ScanProgress{TotalFiles: totalFiles})
 	}
 
 	span.AddEvent("discovery_complete", trace.WithAttributes(
@@ -402,40 +798,81 @@ func (s *Scanner) scanSequential(ctx context.Context, lib *Library) (*ScanResult
 	))
 	span.AddEvent("hashing_started")
 
-	err := filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(lib.RootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if info.IsDir() {
+			if devices.skip(path, info) {
+				return filepath.SkipDir
+			}
+			if s.resume.skip(path) {
+				result.DirsResumed++
+				return filepath.SkipDir
+			}
+			s.dirs.visit(path, true)
 			return nil
 		}
+		s.dirs.visit(path, false)
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if isIgnoredExtension(ext) {
+		if s.isIgnored(lib, path) {
 			return nil
 		}
+		reason, err := extensions.skipReason(path)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			skipped = append(skipped, SkippedFile{Path: path, Reason: reason})
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
 
-		if ext == ".zip" {
-			zipResult, err := s.scanZipFile(lib, path, info)
+		if (ext == ".zip" || ext == ".7z") && !s.config.NoArchives {
+			scanArchive, kind := s.scanZipFile, "zip"
+			if ext == ".7z" {
+				scanArchive, kind = s.scan7zFile, "7z"
+			}
+			archiveResult, err := scanArchive(lib, path, info, cp)
 			if err != nil {
-				slog.Warn("failed to scan zip", "path", path, "error", err)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.errs.add("failed to scan "+kind, path, "", err)
 				return nil
 			}
-			result.FilesScanned += zipResult.FilesScanned
-			result.FilesHashed += zipResult.FilesHashed
-			result.FilesSkipped += zipResult.FilesSkipped
-
-			metrics.FilesProcessed.WithLabelValues(lib.Name, "scanned").Add(float64(zipResult.FilesScanned))
-			metrics.FilesProcessed.WithLabelValues(lib.Name, "hashed").Add(float64(zipResult.FilesHashed))
-			metrics.FilesProcessed.WithLabelValues(lib.Name, "skipped").Add(float64(zipResult.FilesSkipped))
+			result.FilesScanned += archiveResult.FilesScanned
+			result.FilesHashed += archiveResult.FilesHashed
+			result.FilesSkipped += archiveResult.FilesSkipped
+
+			metrics.FilesProcessed.WithLabelValues(lib.Name, "scanned").Add(float64(archiveResult.FilesScanned))
+			metrics.FilesProcessed.WithLabelValues(lib.Name, "hashed").Add(float64(archiveResult.FilesHashed))
+			metrics.FilesProcessed.WithLabelValues(lib.Name, "skipped").Add(float64(archiveResult.FilesSkipped))
+			if s.wantsProgress() {
+				s.reportProgress(ScanProgress{
+					TotalFiles:   totalFiles,
+					FilesScanned: int64(result.FilesScanned),
+					FilesHashed:  int64(result.FilesHashed),
+					FilesSkipped: int64(result.FilesSkipped),
+					CurrentPath:  path,
+					MatchesFound: cp.matched,
+				})
+			}
 			return nil
 		}
 
 		scanned, hashed, err := s.scanFile(lib, path, info, "")
 		if err != nil {
-			slog.Warn("failed to scan file", "path", path, "error", err)
+			s.errs.add("failed to scan file", path, "", err)
 			return nil
 		}
+		if err := cp.add(hashResult{job: fileJob{path: path}, wasHashed: hashed}); err != nil {
+			return err
+		}
+
 		result.FilesScanned++
 		metrics.FilesProcessed.WithLabelValues(lib.Name, "scanned").Inc()
 		if hashed {
@@ -446,28 +883,46 @@ func (s *Scanner) scanSequential(ctx context.Context, lib *Library) (*ScanResult
 			metrics.FilesProcessed.WithLabelValues(lib.Name, "skipped").Inc()
 		}
 
-		if s.config.OnProgress != nil {
-			s.config.OnProgress(ScanProgress{
+		if s.wantsProgress() {
+			s.reportProgress(ScanProgress{
+				TotalFiles:   totalFiles,
 				FilesScanned: int64(result.FilesScanned),
 				FilesHashed:  int64(result.FilesHashed),
 				FilesSkipped: int64(result.FilesSkipped),
-				TotalFiles:   totalFiles,
+				CurrentPath:  path,
+				MatchesFound: cp.matched,
 			})
 		}
 
 		return nil
 	})
+
+	// Commit whatever was stored before the walk stopped, even when interrupted
+	if flushErr := cp.flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	if ctx.Err() != nil {
+		tracing.RecordError(span, ctx.Err())
+		return nil, fmt.Errorf("scan interrupted: %w", ctx.Err())
+	}
 	if err != nil {
 		tracing.RecordError(span, fmt.Errorf("failed to walk library: %w", err))
 		return nil, fmt.Errorf("failed to walk library: %w", err)
 	}
 
-	if err := s.cleanupStaleFiles(lib); err != nil {
+	if err := s.recordSkippedFiles(lib.ID, skipped); err != nil {
+		tracing.RecordError(span, fmt.Errorf("failed to record skipped files: %w", err))
+		return nil, fmt.Errorf("failed to record skipped files: %w", err)
+	}
+	result.FilesExcluded = len(skipped)
+	pruned, err := s.cleanupStaleFiles(lib)
+	if err != nil {
 		tracing.RecordError(span, fmt.Errorf("failed to cleanup stale files: %w", err))
 		return nil, fmt.Errorf("failed to cleanup stale files: %w", err)
 	}
+	result.FilesPruned = pruned
 
-	matchResult, err := s.matchFiles(lib)
+	matchResult, err := s.finalMatch(ctx, lib)
 	if err != nil {
 		tracing.RecordError(span, fmt.Errorf("failed to match files: %w", err))
 		return nil, fmt.Errorf("failed to match files: %w", err)
@@ -493,7 +948,7 @@ func (s *Scanner) scanSequential(ctx context.Context, lib *Library) (*ScanResult
 	return result, nil
 }
 
-func (s *Scanner) scanZipFile(lib *Library, zipPath string, zipInfo os.FileInfo) (*ScanResult, error) {
+func (s *Scanner) scanZipFile(lib *Library, zipPath string, zipInfo os.FileInfo, cp *checkpointer) (*ScanResult, error) {
 	result := &ScanResult{}
 
 	r, err := zip.OpenReader(zipPath)
@@ -510,26 +965,30 @@ func (s *Scanner) scanZipFile(lib *Library, zipPath string, zipInfo os.FileInfo)
 		mtime := zipInfo.ModTime().Unix()
 		size := int64(f.UncompressedSize64) // #nosec G115 - safe cast for ROM sizes
 
+		if isArchiveName(f.Name) {
+			member := archiveMember{name: f.Name, size: size, crc32: zipHeaderCRC32(f), open: f.Open}
+			if err := s.scanNested(lib, zipPath, member, mtime, cp, result); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		scanned, hashed, err := s.scanZipEntry(lib, zipPath, f, mtime, size)
 		if err != nil {
-			slog.Warn("failed to scan zip entry", "entry", f.Name, "error", err)
+			s.errs.add("failed to scan zip entry", zipPath, f.Name, err)
 			continue
 		}
 
+		if err := cp.add(hashResult{job: fileJob{path: zipPath, archivePath: f.Name}, wasHashed: hashed}); err != nil {
+			return nil, err
+		}
+
 		result.FilesScanned++
 		if hashed {
 			result.FilesHashed++
 		} else if scanned {
 			result.FilesSkipped++
 		}
-
-		if s.config.OnProgress != nil {
-			s.config.OnProgress(ScanProgress{
-				FilesScanned: int64(result.FilesScanned),
-				FilesHashed:  int64(result.FilesHashed),
-				FilesSkipped: int64(result.FilesSkipped),
-			})
-		}
 	}
 
 	return result, nil
@@ -544,6 +1003,8 @@ func (s *Scanner) scanFile(lib *Library, path string, info os.FileInfo, archiveP
 		return false, false, err
 	}
 	if cached != nil {
+		isCHD := strings.ToLower(filepath.Ext(path)) == ".chd"
+		s.verifier.check(s, fileJob{path: path, archivePath: archivePath, isCHD: isCHD}, cached)
 		return true, false, nil
 	}
 
@@ -553,12 +1014,19 @@ func (s *Scanner) scanFile(lib *Library, path string, info os.FileInfo, archiveP
 	}
 	defer func() { _ = f.Close() }()
 
-	sha1Hash, crc32Hash, err := computeHashes(f)
+	sha256Hasher := s.sha256Hasher()
+	sha1Hash, crc32Hash, md5Hash, err := s.hashOpenFile(f, size, hashWriters(sha256Hasher)...)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to hash file: %w", err)
+	}
+	headerless, err := s.hashHeaderless(path, func() (io.ReadCloser, error) {
+		return os.Open(path) // #nosec G304
+	})
 	if err != nil {
 		return false, false, fmt.Errorf("failed to hash file: %w", err)
 	}
 
-	if err := s.storeScannedFile(lib.ID, path, archivePath, size, mtime, sha1Hash, crc32Hash); err != nil {
+	if err := s.storeScannedFile(lib.ID, path, archivePath, size, mtime, sha1Hash, crc32Hash, md5Hash, hashHex(sha256Hasher), "", headerless); err != nil {
 		return false, false, fmt.Errorf("failed to store scanned file: %w", err)
 	}
 
@@ -573,21 +1041,21 @@ func (s *Scanner) scanZipEntry(lib *Library, zipPath string, f *zip.File, mtime,
 		return false, false, err
 	}
 	if cached != nil {
+		s.verifier.check(s, fileJob{path: zipPath, archivePath: archivePath}, cached)
 		return true, false, nil
 	}
 
-	rc, err := f.Open()
+	sha256Hasher := s.sha256Hasher()
+	sha1Hash, crc32Hash, md5Hash, err := hashZipFile(f, hashWriters(sha256Hasher)...)
 	if err != nil {
-		return false, false, fmt.Errorf("failed to open zip entry: %w", err)
+		return false, false, fmt.Errorf("failed to hash zip entry: %w", err)
 	}
-	defer func() { _ = rc.Close() }()
-
-	sha1Hash, crc32Hash, err := computeHashes(rc)
+	headerless, err := s.hashHeaderless(f.Name, f.Open)
 	if err != nil {
 		return false, false, fmt.Errorf("failed to hash zip entry: %w", err)
 	}
 
-	if err := s.storeScannedFile(lib.ID, zipPath, archivePath, size, mtime, sha1Hash, crc32Hash); err != nil {
+	if err := s.storeScannedFile(lib.ID, zipPath, archivePath, size, mtime, sha1Hash, crc32Hash, md5Hash, hashHex(sha256Hasher), zipHeaderCRC32(f), headerless); err != nil {
 		return false, false, fmt.Errorf("failed to store scanned file: %w", err)
 	}
 
@@ -595,16 +1063,26 @@ func (s *Scanner) scanZipEntry(lib *Library, zipPath string, f *zip.File, mtime,
 }
 
 func (s *Scanner) getCachedFile(libraryID int64, path, archivePath string, size, mtime int64) (*ScannedFile, error) {
+	if s.config.ForceRehash {
+		return nil, nil
+	}
+
 	sf := &ScannedFile{}
 	var archivePathNull sql.NullString
 
 	query := `
-		SELECT id, library_id, path, size, mtime, sha1, crc32, archive_path
+		SELECT id, library_id, path, size, mtime, sha1, crc32, md5, sha256, archive_path
 		FROM scanned_files
-		WHERE library_id = ? AND path = ? AND COALESCE(archive_path, '') = ? AND size = ? AND mtime = ?
+		WHERE library_id = ? AND path = ? AND COALESCE(archive_path, '') = ? AND size = ?
+		  AND virtual = 0
 	`
-	err := s.db.QueryRow(query, libraryID, path, archivePath, size, mtime).Scan(
-		&sf.ID, &sf.LibraryID, &sf.Path, &sf.Size, &sf.Mtime, &sf.SHA1, &sf.CRC32, &archivePathNull,
+	args := []interface{}{libraryID, path, archivePath, size}
+	if s.config.CacheKey != CacheKeySize {
+		query += " AND mtime = ?"
+		args = append(args, mtime)
+	}
+	err := s.db.QueryRow(query, args...).Scan(
+		&sf.ID, &sf.LibraryID, &sf.Path, &sf.Size, &sf.Mtime, &sf.SHA1, &sf.CRC32, &sf.MD5, &sf.SHA256, &archivePathNull,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -613,6 +1091,12 @@ func (s *Scanner) getCachedFile(libraryID int64, path, archivePath string, size,
 		return nil, err
 	}
 
+	// Files hashed before SHA256 was enabled are rehashed to gain one.
+	// CHDs only ever have a SHA1, so they stay cached.
+	if s.config.SHA256 && sf.SHA256 == "" && (archivePath != "" || !IsCHDFile(path)) {
+		return nil, nil
+	}
+
 	if archivePathNull.Valid {
 		sf.ArchivePath = archivePathNull.String
 	}
@@ -620,72 +1104,176 @@ func (s *Scanner) getCachedFile(libraryID int64, path, archivePath string, size,
 	return sf, nil
 }
 
-func (s *Scanner) storeScannedFile(libraryID int64, path, archivePath string, size, mtime int64, sha1Hash, crc32Hash string) error {
+func (s *Scanner) storeScannedFile(libraryID int64, path, archivePath string, size, mtime int64, sha1Hash, crc32Hash, md5Hash, sha256Hash, zipCRC32 string, headerless headerlessHash) error {
+	return upsertScannedFile(s.db, libraryID, path, archivePath, size, mtime, sha1Hash, crc32Hash, md5Hash, sha256Hash, zipCRC32, headerless)
+}
+
+// execer is the part of *sql.DB and *sql.Tx used to store scanned files.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// upsertScannedFile inserts or updates the scanned_files row for a file.
+func upsertScannedFile(ex execer, libraryID int64, path, archivePath string, size, mtime int64, sha1Hash, crc32Hash, md5Hash, sha256Hash, zipCRC32 string, headerless headerlessHash) error {
+	sha1Hash, crc32Hash, md5Hash = strings.ToLower(sha1Hash), strings.ToLower(crc32Hash), strings.ToLower(md5Hash)
+	sha256Hash = strings.ToLower(sha256Hash)
+
 	var archivePathVal interface{}
 	if archivePath != "" {
 		archivePathVal = archivePath
+	} else {
+		// A NULL archive_path never conflicts in the unique index, so a
+		// rehashed plain file is updated in place rather than upserted.
+		// Otherwise it would gain a second row that keeps the stale hashes.
+		res, err := ex.Exec(`
+			UPDATE scanned_files SET size = ?, mtime = ?, sha1 = ?, crc32 = ?, md5 = ?, sha256 = ?, zip_crc32 = ?,
+				headerless_sha1 = ?, headerless_crc32 = ?, scanned_at = CURRENT_TIMESTAMP
+			WHERE library_id = ? AND path = ? AND archive_path IS NULL
+		`, size, mtime, sha1Hash, crc32Hash, md5Hash, sha256Hash, zipCRC32, headerless.sha1, headerless.crc32, libraryID, path)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
 	}
 
-	_, err := s.db.Exec(`
-		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32, archive_path)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	_, err := ex.Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32, md5, sha256, archive_path, zip_crc32,
+			headerless_sha1, headerless_crc32)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(library_id, path, archive_path) DO UPDATE SET
 			size = excluded.size,
 			mtime = excluded.mtime,
 			sha1 = excluded.sha1,
 			crc32 = excluded.crc32,
+			md5 = excluded.md5,
+			sha256 = excluded.sha256,
+			zip_crc32 = excluded.zip_crc32,
+			headerless_sha1 = excluded.headerless_sha1,
+			headerless_crc32 = excluded.headerless_crc32,
 			scanned_at = CURRENT_TIMESTAMP
-	`, libraryID, path, size, mtime, sha1Hash, crc32Hash, archivePathVal)
+	`, libraryID, path, size, mtime, sha1Hash, crc32Hash, md5Hash, sha256Hash, archivePathVal, zipCRC32,
+		headerless.sha1, headerless.crc32)
 
 	return err
 }
 
 // cleanupStaleFiles removes scanned file entries that no longer exist or should be ignored.
-func (s *Scanner) cleanupStaleFiles(lib *Library) error {
+// It returns how many entries were pruned for an ignored extension.
+func (s *Scanner) cleanupStaleFiles(lib *Library) (int, error) {
+	pruned, err := s.pruneIgnored(lib)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.pruneArchiveMode(lib); err != nil {
+		return 0, err
+	}
+
 	rows, err := s.db.Query(`
-		SELECT id, path, archive_path FROM scanned_files WHERE library_id = ?
+		SELECT id, path FROM scanned_files
+		WHERE library_id = ? AND virtual = 0 AND (archive_path IS NULL OR archive_path = '')
 	`, lib.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	var toDelete []int64
 	for rows.Next() {
 		var id int64
 		var path string
-		var archivePath sql.NullString
-		if err := rows.Scan(&id, &path, &archivePath); err != nil {
+		if err := rows.Scan(&id, &path); err != nil {
 			_ = rows.Close()
-			return err
+			return 0, err
 		}
-
-		shouldDelete := false
-
-		if !archivePath.Valid || archivePath.String == "" {
-			ext := strings.ToLower(filepath.Ext(path))
-			if isIgnoredExtension(ext) {
-				shouldDelete = true
-			}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			toDelete = append(toDelete, id)
 		}
+	}
+	_ = rows.Close()
 
-		if !shouldDelete && (!archivePath.Valid || archivePath.String == "") {
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				shouldDelete = true
-			}
+	for _, id := range toDelete {
+		_, err := s.db.Exec("DELETE FROM scanned_files WHERE id = ?", id)
+		if err != nil {
+			return 0, err
 		}
+	}
+
+	return pruned, nil
+}
 
-		if shouldDelete {
+// pruneIgnored removes scanned file entries whose extension is now ignored,
+// whether or not the file still exists. Archive entries are judged by the
+// archive's own path, as the scan does.
+func (s *Scanner) pruneIgnored(lib *Library) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT id, path FROM scanned_files WHERE library_id = ? AND virtual = 0
+	`, lib.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var toDelete []int64
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if s.isIgnored(lib, path) {
 			toDelete = append(toDelete, id)
 		}
 	}
 	_ = rows.Close()
 
 	for _, id := range toDelete {
-		_, err := s.db.Exec("DELETE FROM scanned_files WHERE id = ?", id)
-		if err != nil {
-			return err
+		if _, err := s.db.Exec("DELETE FROM scanned_files WHERE id = ?", id); err != nil {
+			return 0, err
 		}
 	}
+	return len(toDelete), nil
+}
 
-	return nil
+// pruneArchiveMode removes the entries a scan in the other archive mode
+// stored: archive entries when NoArchives is set, whole archives otherwise.
+// Entries with a manual match are kept so switching modes never loses one.
+func (s *Scanner) pruneArchiveMode(lib *Library) error {
+	mode := `COALESCE(archive_path, '') = '' AND (LOWER(path) LIKE '%.zip' OR LOWER(path) LIKE '%.7z')`
+	if s.config.NoArchives {
+		mode = `COALESCE(archive_path, '') <> ''`
+	}
+	// #nosec G202
+	_, err := s.db.Exec(`
+		DELETE FROM scanned_files
+		WHERE library_id = ? AND virtual = 0 AND `+mode+`
+		  AND id NOT IN (SELECT scanned_file_id FROM matches WHERE is_manual = 1)`, lib.ID)
+	return err
+}
+
+// Prune removes a library's scanned file entries whose extension is ignored
+// by the current configuration, without scanning, and returns how many were removed.
+func (s *Scanner) Prune(ctx context.Context, libraryName string) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.Prune",
+		tracing.WithAttributes(attribute.String("library.name", libraryName)),
+	)
+	defer span.End()
+
+	if err := s.config.LibraryFilters[libraryName].validate(); err != nil {
+		return 0, err
+	}
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	pruned, err := s.pruneIgnored(lib)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, WrapDBError(err, "prune ignored files")
+	}
+	tracing.AddSpanAttributes(span, attribute.Int("result.files_pruned", pruned))
+	return pruned, nil
 }
@@ -0,0 +1,189 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// is7z reports whether path is a 7-Zip archive.
+func is7z(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".7z"
+}
+
+// queue7zEntries reads a 7z file and queues its entries for hashing. Files
+// in archives nested inside it are hashed at once and sent to results.
+func (s *Scanner) queue7zEntries(ctx context.Context, libraryID int64, archivePath string, archiveInfo os.FileInfo, jobs chan<- fileJob, results chan<- hashResult) error {
+	r, err := sevenzip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	mtime := archiveInfo.ModTime().Unix()
+	send := func(job fileJob) error {
+		select {
+		case jobs <- job:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sendResult := func(r hashResult) error {
+		select {
+		case results <- r:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if isArchiveName(f.Name) {
+			member := archiveMember{name: f.Name, size: int64(f.UncompressedSize), crc32: sevenZipHeaderCRC32(f), open: f.Open} // #nosec G115
+			if err := s.queueNested(libraryID, archivePath, member, mtime, sendResult); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.errs.add("failed to scan nested archive", archivePath, f.Name, err)
+			}
+			continue
+		}
+		job := fileJob{
+			path:        archivePath,
+			archivePath: f.Name,
+			size:        int64(f.UncompressedSize), // #nosec G115 - safe cast for ROM sizes
+			mtime:       mtime,
+			is7zEntry:   true,
+			zipCRC32:    sevenZipHeaderCRC32(f),
+			seq:         s.dirs.next(),
+		}
+		if err := send(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hash7zEntry computes hashes for a file inside a 7z archive.
+func (s *Scanner) hash7zEntry(archivePath, entryName string, extra ...io.Writer) (string, string, string, error) {
+	if isNestedEntry(entryName) {
+		return hashNestedEntry(archivePath, entryName, extra...)
+	}
+	r, err := sevenzip.OpenReader(archivePath)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer func() { _ = r.Close() }()
+
+	for _, f := range r.File {
+		if f.Name == entryName {
+			return hash7zFile(f, extra...)
+		}
+	}
+	return "", "", "", fmt.Errorf("entry %s not found in %s", entryName, archivePath)
+}
+
+// hash7zFile computes hashes for a 7z entry's decompressed data.
+func hash7zFile(f *sevenzip.File, extra ...io.Writer) (string, string, string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", "", "", err
+	}
+	defer func() { _ = rc.Close() }()
+	return computeHashes(rc, extra...)
+}
+
+// sevenZipHeaderCRC32 returns the CRC32 recorded for a 7z entry as hex, or
+// an empty string when the archive has none. Like a zip header CRC it is
+// kept so verify can report entries whose data no longer matches.
+func sevenZipHeaderCRC32(f *sevenzip.File) string {
+	if f.CRC32 == 0 && f.UncompressedSize > 0 {
+		return ""
+	}
+	return fmt.Sprintf("%08x", f.CRC32)
+}
+
+// scan7zFile scans the entries of a 7z file for the sequential scanner. One
+// reader is shared by all entries, so solid archives are decompressed once.
+func (s *Scanner) scan7zFile(lib *Library, archivePath string, archiveInfo os.FileInfo, cp *checkpointer) (*ScanResult, error) {
+	result := &ScanResult{}
+
+	r, err := sevenzip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open 7z: %w", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		mtime := archiveInfo.ModTime().Unix()
+		size := int64(f.UncompressedSize) // #nosec G115 - safe cast for ROM sizes
+
+		if isArchiveName(f.Name) {
+			member := archiveMember{name: f.Name, size: size, crc32: sevenZipHeaderCRC32(f), open: f.Open}
+			if err := s.scanNested(lib, archivePath, member, mtime, cp, result); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		scanned, hashed, err := s.scan7zEntry(lib, archivePath, f, mtime, size)
+		if err != nil {
+			s.errs.add("failed to scan 7z entry", archivePath, f.Name, err)
+			continue
+		}
+
+		if err := cp.add(hashResult{job: fileJob{path: archivePath, archivePath: f.Name}, wasHashed: hashed}); err != nil {
+			return nil, err
+		}
+
+		result.FilesScanned++
+		if hashed {
+			result.FilesHashed++
+		} else if scanned {
+			result.FilesSkipped++
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Scanner) scan7zEntry(lib *Library, archivePath string, f *sevenzip.File, mtime, size int64) (scanned, hashed bool, err error) {
+	entryName := f.Name
+
+	cached, err := s.getCachedFile(lib.ID, archivePath, entryName, size, mtime)
+	if err != nil {
+		return false, false, err
+	}
+	if cached != nil {
+		s.verifier.check(s, fileJob{path: archivePath, archivePath: entryName}, cached)
+		return true, false, nil
+	}
+
+	sha256Hasher := s.sha256Hasher()
+	sha1Hash, crc32Hash, md5Hash, err := hash7zFile(f, hashWriters(sha256Hasher)...)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to hash 7z entry: %w", err)
+	}
+	headerless, err := s.hashHeaderless(f.Name, f.Open)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to hash 7z entry: %w", err)
+	}
+
+	if err := s.storeScannedFile(lib.ID, archivePath, entryName, size, mtime, sha1Hash, crc32Hash, md5Hash, hashHex(sha256Hasher), sevenZipHeaderCRC32(f), headerless); err != nil {
+		return false, false, fmt.Errorf("failed to store scanned file: %w", err)
+	}
+
+	return true, true, nil
+}
@@ -0,0 +1,157 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sevenZipEntry is a file to store in a test 7z archive.
+type sevenZipEntry struct {
+	name string
+	data []byte
+}
+
+// writeStored7z writes a 7z archive holding each entry uncompressed (the
+// "copy" coder), which is enough to exercise the reader without a 7z tool.
+// Sizes and name lengths must stay below 0x80 to fit 7z's one-byte numbers.
+func writeStored7z(t *testing.T, path string, entries []sevenZipEntry) {
+	t.Helper()
+	num := func(v int) byte {
+		require.Less(t, v, 0x80)
+		return byte(v)
+	}
+	crc := func(b []byte) []byte {
+		return binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(b))
+	}
+
+	var packed, h bytes.Buffer
+	for _, e := range entries {
+		packed.Write(e.data)
+	}
+
+	h.Write([]byte{0x01, 0x04})                          // Header, MainStreamsInfo
+	h.Write([]byte{0x06, 0x00, num(len(entries)), 0x09}) // PackInfo: offset, streams, sizes
+	for _, e := range entries {
+		h.WriteByte(num(len(e.data)))
+	}
+	h.WriteByte(0x00)
+	h.Write([]byte{0x07, 0x0B, num(len(entries)), 0x00}) // UnpackInfo: one folder per entry
+	for range entries {
+		h.Write([]byte{0x01, 0x01, 0x00}) // One simple coder: copy
+	}
+	h.WriteByte(0x0C)
+	for _, e := range entries {
+		h.WriteByte(num(len(e.data)))
+	}
+	h.WriteByte(0x00)
+	h.Write([]byte{0x08, 0x0A, 0x01}) // SubStreamsInfo: all CRCs defined
+	for _, e := range entries {
+		h.Write(crc(e.data))
+	}
+	h.Write([]byte{0x00, 0x00})
+
+	var names bytes.Buffer
+	names.WriteByte(0x00)
+	for _, e := range entries {
+		for _, u := range utf16.Encode([]rune(e.name)) {
+			names.Write(binary.LittleEndian.AppendUint16(nil, u))
+		}
+		names.Write([]byte{0x00, 0x00})
+	}
+	h.Write([]byte{0x05, num(len(entries)), 0x11, num(names.Len())}) // FilesInfo: names
+	h.Write(names.Bytes())
+	h.Write([]byte{0x00, 0x00})
+
+	start := binary.LittleEndian.AppendUint64(nil, uint64(packed.Len()))
+	start = binary.LittleEndian.AppendUint64(start, uint64(h.Len()))
+	start = append(start, crc(h.Bytes())...)
+
+	var out bytes.Buffer
+	out.Write([]byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C, 0x00, 0x04})
+	out.Write(crc(start))
+	out.Write(start)
+	out.Write(packed.Bytes())
+	out.Write(h.Bytes())
+	require.NoError(t, os.WriteFile(path, out.Bytes(), 0644)) // #nosec G306
+}
+
+// setup7zLibrary moves the ROMs of a two-game checkpoint library into a 7z.
+func setup7zLibrary(t *testing.T) (string, func(ScanConfig) *Scanner) {
+	t.Helper()
+	database, libPath := setupCheckpointLibrary(t, 2)
+	for _, name := range []string{"game00.nes", "game01.nes"} {
+		require.NoError(t, os.Remove(filepath.Join(libPath, name)))
+	}
+
+	archive := filepath.Join(libPath, "roms.7z")
+	writeStored7z(t, archive, []sevenZipEntry{
+		{name: "game00.nes", data: []byte("rom content 0")},
+		{name: "sub/game01.nes", data: []byte("rom content 1")},
+	})
+
+	return archive, func(cfg ScanConfig) *Scanner {
+		return NewScannerWithConfig(database.Conn(), cfg)
+	}
+}
+
+func TestScan_7zEntries(t *testing.T) {
+	for name, cfg := range map[string]ScanConfig{
+		"sequential": {Workers: 1, BatchSize: 10, Parallel: false},
+		"parallel":   {Workers: 4, BatchSize: 10, Parallel: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			archive, newScanner := setup7zLibrary(t)
+			scanner := newScanner(cfg)
+
+			result, err := scanner.Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 2, result.FilesHashed)
+			assert.Equal(t, 2, result.MatchesFound)
+
+			rows, err := scanner.db.Query(`
+				SELECT path, archive_path, zip_crc32 = crc32 FROM scanned_files ORDER BY archive_path
+			`)
+			require.NoError(t, err)
+			defer func() { _ = rows.Close() }()
+			var entries []string
+			for rows.Next() {
+				var path, entry string
+				var headerMatches bool
+				require.NoError(t, rows.Scan(&path, &entry, &headerMatches))
+				assert.Equal(t, archive, path)
+				assert.True(t, headerMatches, "header CRC should be recorded for %s", entry)
+				entries = append(entries, entry)
+			}
+			assert.Equal(t, []string{"game00.nes", "sub/game01.nes"}, entries)
+
+			// Entries are cached by the archive's mtime like zip entries
+			result, err = scanner.Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.FilesHashed)
+			assert.Equal(t, 2, result.FilesSkipped)
+		})
+	}
+}
+
+func TestGetUnmatchedFiles_7zEntry(t *testing.T) {
+	archive, newScanner := setup7zLibrary(t)
+	scanner := newScanner(ScanConfig{Workers: 1, BatchSize: 10})
+	_, err := scanner.db.Exec(`DELETE FROM rom_entries WHERE name LIKE 'Game 01%'`)
+	require.NoError(t, err)
+
+	_, err = scanner.Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+
+	unmatched, err := scanner.GetUnmatchedFiles(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, []string{archive + ":sub/game01.nes"}, unmatched)
+}
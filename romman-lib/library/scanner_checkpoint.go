@@ -0,0 +1,205 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Scan state values stored in scan_state.status.
+const (
+	scanStatusRunning  = "running"
+	scanStatusComplete = "complete"
+)
+
+// ScanState records the progress of the most recent scan of a library.
+type ScanState struct {
+	LibraryID      int64
+	Status         string // "running" or "complete"
+	FilesCommitted int
+	LastDir        string // Last directory fully walked and committed by a running scan
+	StartedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Interrupted reports whether the recorded scan stopped before completing.
+func (st *ScanState) Interrupted() bool {
+	return st.Status == scanStatusRunning
+}
+
+// GetScanState returns the recorded scan state for a library, or nil if it has never been scanned.
+func (s *Scanner) GetScanState(ctx context.Context, libraryName string) (*ScanState, error) {
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanState(ctx, lib.ID)
+}
+
+// scanState returns the recorded scan state for a library ID, or nil.
+func (s *Scanner) scanState(ctx context.Context, libraryID int64) (*ScanState, error) {
+	st := &ScanState{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT library_id, status, files_committed, last_dir, started_at, updated_at
+		FROM scan_state WHERE library_id = ?
+	`, libraryID).Scan(&st.LibraryID, &st.Status, &st.FilesCommitted, &st.LastDir, &st.StartedAt, &st.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// beginScanState marks a scan of the library as running. A resumed scan
+// keeps the interrupted scan's last directory until it gets past it.
+func (s *Scanner) beginScanState(libraryID int64, resume bool) error {
+	_, err := s.db.Exec(`
+		INSERT INTO scan_state (library_id, status, files_committed)
+		VALUES (?, ?, 0)
+		ON CONFLICT(library_id) DO UPDATE SET
+			status = excluded.status,
+			files_committed = 0,
+			last_dir = CASE WHEN ? THEN last_dir ELSE '' END,
+			started_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+	`, libraryID, scanStatusRunning, resume)
+	return err
+}
+
+// updateScanState records how many files the running scan has committed
+// and, unless empty, the last directory it has fully committed.
+func (s *Scanner) updateScanState(libraryID int64, committed int, lastDir string) error {
+	_, err := s.db.Exec(`
+		UPDATE scan_state SET files_committed = ?, last_dir = COALESCE(NULLIF(?, ''), last_dir),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE library_id = ?
+	`, committed, lastDir, libraryID)
+	return err
+}
+
+// finishScanState marks the library's scan as complete.
+func (s *Scanner) finishScanState(libraryID int64) error {
+	_, err := s.db.Exec(`
+		UPDATE scan_state SET status = ?, last_dir = '', updated_at = CURRENT_TIMESTAMP
+		WHERE library_id = ?
+	`, scanStatusComplete, libraryID)
+	return err
+}
+
+// checkpointer matches newly hashed files as each batch is committed and records
+// progress in scan_state, so an interrupted scan keeps both its hashes and its matches.
+type checkpointer struct {
+	scanner   *Scanner
+	lib       *Library
+	resolver  *systemResolver
+	pending   []hashResult
+	committed int
+	matched   int64 // Committed files with a match, counted for progress events only
+}
+
+// newCheckpointer builds the release name index once for the whole scan.
+func (s *Scanner) newCheckpointer(lib *Library) (*checkpointer, error) {
+	resolver := newSystemResolver(s.db, lib, s)
+	if _, err := resolver.releaseNames(lib.SystemID); err != nil {
+		return nil, fmt.Errorf("failed to build release index: %w", err)
+	}
+	return &checkpointer{scanner: s, lib: lib, resolver: resolver}, nil
+}
+
+// add queues a stored file and commits a checkpoint once a full batch is pending.
+// Used by the sequential scanner, which stores files one at a time.
+func (c *checkpointer) add(r hashResult) error {
+	r.job.seq = c.scanner.dirs.next()
+	c.pending = append(c.pending, r)
+	if len(c.pending) < c.scanner.config.BatchSize {
+		return nil
+	}
+	return c.flush()
+}
+
+// flush commits a checkpoint for any pending files.
+func (c *checkpointer) flush() error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	err := c.commit(c.pending)
+	c.pending = c.pending[:0]
+	return err
+}
+
+// commit matches the newly hashed files of an already stored batch and updates scan_state.
+// Cached files keep their existing matches until the final match pass.
+func (c *checkpointer) commit(batch []hashResult) error {
+	seqs := make([]int64, 0, len(batch))
+	for _, r := range batch {
+		c.committed++
+		seqs = append(seqs, r.job.seq)
+		if !r.wasHashed {
+			if c.scanner.wantsProgress() {
+				var matched bool
+				err := c.scanner.db.QueryRow(`
+					SELECT EXISTS (SELECT 1 FROM matches m JOIN scanned_files sf ON sf.id = m.scanned_file_id
+					WHERE sf.library_id = ? AND sf.path = ? AND COALESCE(sf.archive_path, '') = ?)
+				`, c.lib.ID, r.job.path, r.job.archivePath).Scan(&matched)
+				if err != nil {
+					return fmt.Errorf("failed to check matches of %s: %w", r.job.path, err)
+				}
+				if matched {
+					c.matched++
+				}
+			}
+			continue
+		}
+
+		var f fileToMatch
+		var manual bool
+		err := c.scanner.db.QueryRow(`
+			SELECT id, sha1, crc32, md5, sha256, path, headerless_sha1, headerless_crc32,
+				EXISTS (SELECT 1 FROM matches m WHERE m.scanned_file_id = scanned_files.id AND m.is_manual = 1)
+			FROM scanned_files
+			WHERE library_id = ? AND path = ? AND COALESCE(archive_path, '') = ?
+		`, c.lib.ID, r.job.path, r.job.archivePath).Scan(&f.id, &f.sha1, &f.crc32, &f.md5, &f.sha256, &f.path,
+			&f.headerless.sha1, &f.headerless.crc32, &manual)
+		if err != nil {
+			return fmt.Errorf("failed to load scanned file %s: %w", r.job.path, err)
+		}
+		if manual {
+			// Matched by hand; rehashing keeps the match
+			c.matched++
+			continue
+		}
+
+		if _, err := c.scanner.db.Exec(`DELETE FROM matches WHERE scanned_file_id = ? AND is_manual = 0`, f.id); err != nil {
+			return fmt.Errorf("failed to clear matches: %w", err)
+		}
+		matched, err := c.resolver.matchFile(f)
+		if err != nil {
+			return err
+		}
+		if matched {
+			c.matched++
+		}
+	}
+
+	return c.scanner.updateScanState(c.lib.ID, c.committed, c.scanner.dirs.commit(seqs...))
+}
+
+// countMatches reports matched and unmatched file counts for a library without re-matching.
+func (s *Scanner) countMatches(libraryID int64) (*matchResult, error) {
+	result := &matchResult{}
+	err := s.db.QueryRow(`
+		SELECT
+			COUNT(DISTINCT CASE WHEN m.id IS NOT NULL THEN sf.id END),
+			COUNT(DISTINCT CASE WHEN m.id IS NULL THEN sf.id END)
+		FROM scanned_files sf
+		LEFT JOIN matches m ON m.scanned_file_id = sf.id
+		WHERE sf.library_id = ?
+	`, libraryID).Scan(&result.MatchesFound, &result.UnmatchedFiles)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
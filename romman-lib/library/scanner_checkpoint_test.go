@@ -0,0 +1,174 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+// setupCheckpointLibrary creates a library of n ROM files, each with a matching DAT entry.
+func setupCheckpointLibrary(t *testing.T, n int) (*db.DB, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+
+	_, err = database.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+
+	libPath := filepath.Join(tmpDir, "roms")
+	require.NoError(t, os.MkdirAll(libPath, 0755)) // #nosec G301
+
+	for i := 0; i < n; i++ {
+		writeCheckpointROM(t, database, libPath, i)
+	}
+
+	_, err = NewManager(database.Conn()).Add(context.Background(), "test-lib", libPath, "nes")
+	require.NoError(t, err)
+
+	return database, libPath
+}
+
+// writeCheckpointROM writes ROM file i and inserts its release and rom_entry.
+func writeCheckpointROM(t *testing.T, database *db.DB, libPath string, i int) {
+	t.Helper()
+	content := fmt.Sprintf("rom content %d", i)
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(content))
+	require.NoError(t, err)
+
+	_, err = database.Conn().Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, i+1, fmt.Sprintf("Game %02d (USA)", i))
+	require.NoError(t, err)
+	_, err = database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (?, ?, ?, ?, ?)
+	`, i+1, fmt.Sprintf("Game %02d (USA).nes", i), sha1Hash, crc32Hash, len(content))
+	require.NoError(t, err)
+
+	path := filepath.Join(libPath, fmt.Sprintf("game%02d.nes", i))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644)) // #nosec G306
+}
+
+func countRows(t *testing.T, database *db.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	require.NoError(t, database.Conn().QueryRow(query, args...).Scan(&n))
+	return n
+}
+
+func TestScanner_InterruptedScanKeepsCheckpoints(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Interrupt the scan part way through, mid-batch
+	cfg := ScanConfig{
+		Workers:   1,
+		BatchSize: 5,
+		Parallel:  false,
+		OnProgress: func(p ScanProgress) {
+			if p.FilesScanned >= 12 {
+				cancel()
+			}
+		},
+	}
+	scanner := NewScannerWithConfig(database.Conn(), cfg)
+
+	_, err := scanner.Scan(ctx, "test-lib")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Everything hashed before the interruption is stored and already matched
+	assert.Equal(t, 12, countRows(t, database, `SELECT COUNT(*) FROM scanned_files`))
+	assert.Equal(t, 12, countRows(t, database, `SELECT COUNT(*) FROM matches`))
+
+	state, err := scanner.GetScanState(context.Background(), "test-lib")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.True(t, state.Interrupted())
+	assert.Equal(t, 12, state.FilesCommitted)
+
+	// Resuming only hashes the files the interrupted scan never reached
+	scanner = NewScannerWithConfig(database.Conn(), ScanConfig{Workers: 1, BatchSize: 5, Parallel: false})
+	result, err := scanner.Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+
+	assert.Equal(t, 20, result.FilesScanned)
+	assert.Equal(t, 8, result.FilesHashed)
+	assert.Equal(t, 12, result.FilesSkipped)
+	assert.Equal(t, 20, result.MatchesFound)
+
+	state, err = scanner.GetScanState(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.False(t, state.Interrupted())
+	assert.Equal(t, 20, state.FilesCommitted)
+}
+
+func TestScanner_ParallelCheckpointsAndChangedOnly(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 10)
+
+	cfg := ScanConfig{Workers: 4, BatchSize: 3, Parallel: true}
+	result, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.MatchesFound)
+
+	// Add one file and rescan in changed-only mode; only the new file is hashed and matched
+	writeCheckpointROM(t, database, libPath, 10)
+	cfg.ChangedOnly = true
+	result, err = NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.FilesHashed)
+	assert.Equal(t, 10, result.FilesSkipped)
+	assert.Equal(t, 11, result.MatchesFound)
+	assert.Equal(t, 0, result.UnmatchedFiles)
+	assert.Equal(t, 11, countRows(t, database, `SELECT COUNT(*) FROM scanned_files`))
+	assert.Equal(t, 11, countRows(t, database, `SELECT COUNT(*) FROM matches`))
+}
+
+func TestScanner_ChangedOnlyRematchesOnlyChangedFiles(t *testing.T) {
+	for name, cfg := range map[string]ScanConfig{
+		"sequential": {Workers: 1, BatchSize: 2, Parallel: false},
+		"parallel":   {Workers: 4, BatchSize: 2, Parallel: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			database, libPath := setupCheckpointLibrary(t, 5)
+
+			_, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			before := countRows(t, database, `SELECT MAX(id) FROM matches`)
+
+			// Give game03 the content of game04 with a new mtime so it is rehashed
+			changed := filepath.Join(libPath, "game03.nes")
+			require.NoError(t, os.WriteFile(changed, []byte("rom content 4"), 0644)) // #nosec G306
+			later := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(changed, later, later))
+
+			cfg.ChangedOnly = true
+			result, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.FilesHashed)
+			assert.Equal(t, 5, result.MatchesFound)
+
+			// Unchanged files keep their match rows; only the changed file got a new one
+			assert.Equal(t, 5, countRows(t, database, `SELECT COUNT(*) FROM scanned_files`))
+			assert.Equal(t, 4, countRows(t, database, `SELECT COUNT(*) FROM matches WHERE id <= ?`, before))
+			assert.Equal(t, 1, countRows(t, database, `
+				SELECT COUNT(*) FROM matches m
+				JOIN scanned_files sf ON sf.id = m.scanned_file_id
+				JOIN rom_entries re ON re.id = m.rom_entry_id
+				WHERE m.id > ? AND sf.path = ? AND re.name = 'Game 04 (USA).nes'
+			`, before, changed))
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package library
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cueFlag marks .cue sheets matched because every track they reference
+// matched the same release, rather than by their own hash. The match takes
+// the weakest match type among the tracks.
+const cueFlag = "cue"
+
+// parseCueSheet returns the files referenced by a cue sheet's FILE lines, in
+// order, e.g. "Game (Track 1).bin" from `FILE "Game (Track 1).bin" BINARY`.
+func parseCueSheet(r io.Reader) ([]string, error) {
+	var files []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		if len(line) < 5 || !strings.EqualFold(line[:5], "FILE ") {
+			continue
+		}
+
+		rest := strings.TrimSpace(line[5:])
+		var name string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				continue
+			}
+			name = rest[1 : end+1]
+		} else if i := strings.LastIndexByte(rest, ' '); i > 0 {
+			// Unquoted names run up to the file type
+			name = strings.TrimSpace(rest[:i])
+		} else {
+			name = rest
+		}
+
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, sc.Err()
+}
+
+// cueFile is a plain scanned file with the releases its hash matches point to.
+type cueFile struct {
+	id          int64
+	path        string
+	hashMatched bool           // Matched by sha256, sha1, md5 or crc32 on its own
+	manual      bool           // Matched by hand, so never re-matched
+	matchType   string         // Strongest of its hash match types
+	releases    map[int64]bool // Releases of its hash matches
+}
+
+// hashMatchRank orders hash match types from weakest to strongest.
+var hashMatchRank = map[string]int{
+	string(MatchTypeCRC32):  1,
+	string(MatchTypeMD5):    2,
+	string(MatchTypeSHA1):   3,
+	string(MatchTypeSHA256): 4,
+}
+
+// matchCueSheets matches each .cue sheet that has no hash match of its own
+// to its release's .cue entry once every track it references has a hash
+// match in that release. A sheet with a missing or unmatched track stays
+// unmatched, so a disc only counts as present as a whole. Name matches of
+// such sheets are replaced; manual matches are kept. Returns the number of
+// sheets matched.
+func (s *Scanner) matchCueSheets(ctx context.Context, lib *Library) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sf.id, sf.path, COALESCE(m.match_type, ''), COALESCE(m.flags, ''), COALESCE(re.release_id, 0),
+			COALESCE(m.is_manual, 0)
+		FROM scanned_files sf
+		LEFT JOIN matches m ON m.scanned_file_id = sf.id
+		LEFT JOIN rom_entries re ON re.id = m.rom_entry_id
+		WHERE sf.library_id = ? AND sf.virtual = 0 AND COALESCE(sf.archive_path, '') = ''
+	`, lib.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	files := make(map[string]*cueFile)
+	folded := make(map[string]*cueFile) // Cue sheets often differ from the disk in case
+	var sheets []*cueFile
+	for rows.Next() {
+		var id, releaseID int64
+		var path, matchType, flags string
+		var manual bool
+		if err := rows.Scan(&id, &path, &matchType, &flags, &releaseID, &manual); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+
+		f, ok := files[path]
+		if !ok {
+			f = &cueFile{id: id, path: path, releases: make(map[int64]bool)}
+			files[path] = f
+			folded[strings.ToLower(path)] = f
+			if strings.EqualFold(filepath.Ext(path), ".cue") {
+				sheets = append(sheets, f)
+			}
+		}
+		if manual {
+			f.manual = true
+		}
+		if hashMatchRank[matchType] == 0 || flags == cueFlag {
+			continue
+		}
+		f.hashMatched = true
+		f.releases[releaseID] = true
+		if hashMatchRank[matchType] > hashMatchRank[f.matchType] {
+			f.matchType = matchType
+		}
+	}
+	_ = rows.Close()
+
+	matched := 0
+	for _, sheet := range sheets {
+		if err := ctx.Err(); err != nil {
+			return matched, err
+		}
+		if sheet.hashMatched || sheet.manual {
+			continue
+		}
+
+		// Rebuilt every time, so a sheet whose tracks changed loses its match
+		if _, err := s.db.Exec(`DELETE FROM matches WHERE scanned_file_id = ?`, sheet.id); err != nil {
+			return matched, fmt.Errorf("failed to clear matches: %w", err)
+		}
+
+		releaseID, matchType, err := s.cueRelease(sheet, files, folded)
+		if err != nil {
+			slog.Warn("failed to read cue sheet", "path", sheet.path, "error", err)
+			continue
+		}
+		if releaseID == 0 {
+			continue
+		}
+
+		var romEntryID int64
+		err = s.db.QueryRow(`
+			SELECT id FROM rom_entries
+			WHERE release_id = ? AND LOWER(name) LIKE '%.cue'
+			ORDER BY id LIMIT 1
+		`, releaseID).Scan(&romEntryID)
+		if err != nil {
+			// The DAT has no cue entry for the release; the sheet stays unmatched
+			continue
+		}
+
+		if _, err := s.insertMatch(sheet.id, romEntryID, matchType, cueFlag); err != nil {
+			return matched, err
+		}
+		matched++
+	}
+
+	return matched, nil
+}
+
+// cueRelease returns the release every track of a cue sheet has a hash match
+// in and the weakest of the tracks' match types, or 0 when a track is
+// missing, unmatched or the tracks disagree.
+func (s *Scanner) cueRelease(sheet *cueFile, files, folded map[string]*cueFile) (int64, string, error) {
+	f, err := os.Open(sheet.path) // #nosec G304
+	if err != nil {
+		return 0, "", err
+	}
+	names, err := parseCueSheet(f)
+	_ = f.Close()
+	if err != nil {
+		return 0, "", err
+	}
+	if len(names) == 0 {
+		return 0, "", nil
+	}
+
+	var common map[int64]bool
+	matchType := string(MatchTypeSHA256)
+	for _, name := range names {
+		path := filepath.Join(filepath.Dir(sheet.path), filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")))
+		track, ok := files[path]
+		if !ok {
+			track, ok = folded[strings.ToLower(path)]
+		}
+		if !ok || !track.hashMatched {
+			return 0, "", nil
+		}
+		if hashMatchRank[track.matchType] < hashMatchRank[matchType] {
+			matchType = track.matchType
+		}
+
+		if common == nil {
+			common = track.releases
+			continue
+		}
+		next := make(map[int64]bool)
+		for id := range common {
+			if track.releases[id] {
+				next[id] = true
+			}
+		}
+		common = next
+	}
+
+	ids := make([]int64, 0, len(common))
+	for id := range common {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return 0, "", nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids[0], matchType, nil
+}
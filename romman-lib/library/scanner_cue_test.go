@@ -0,0 +1,87 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCueSheet(t *testing.T) {
+	sheet := "\ufeffREM COMMENT test\r\n" +
+		`FILE "Game (Track 1).bin" BINARY` + "\r\n" +
+		"  TRACK 01 MODE2/2352\r\n" +
+		"    INDEX 01 00:00:00\r\n" +
+		`file Game-Track2.bin BINARY` + "\r\n" +
+		"  TRACK 02 AUDIO\r\n"
+
+	files, err := parseCueSheet(strings.NewReader(sheet))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Game (Track 1).bin", "Game-Track2.bin"}, files)
+}
+
+func TestScanner_MatchesCueSheetByTracks(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 0)
+
+	track1, track2 := "disc track one data", "disc track two audio"
+	_, err := database.Conn().Exec(`INSERT INTO releases (id, system_id, name) VALUES (100, 1, 'Disc Game (USA)')`)
+	require.NoError(t, err)
+	for _, rom := range []struct{ name, content string }{
+		{"Disc Game (USA).cue", "the DAT's own cue sheet"},
+		{"Disc Game (USA) (Track 1).bin", track1},
+		{"Disc Game (USA) (Track 2).bin", track2},
+	} {
+		sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(rom.content))
+		require.NoError(t, err)
+		_, err = database.Conn().Exec(`
+			INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (100, ?, ?, ?, ?)
+		`, rom.name, sha1Hash, crc32Hash, len(rom.content))
+		require.NoError(t, err)
+	}
+
+	// The local sheet differs from the DAT's and refers to a track in another case
+	cue := `FILE "disc game (track 1).bin" BINARY` + "\n  TRACK 01 MODE2/2352\n" +
+		`FILE "Disc Game (Track 2).bin" BINARY` + "\n  TRACK 02 AUDIO\n"
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "Disc Game.cue"), []byte(cue), 0644))              // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "Disc Game (Track 1).bin"), []byte(track1), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "Disc Game (Track 2).bin"), []byte(track2), 0644)) // #nosec G306
+
+	cueMatches := `SELECT COUNT(*) FROM matches WHERE flags = 'cue'`
+	releaseStatus := func(scanner *Scanner) string {
+		statuses, err := scanner.GetLibraryStatus(context.Background(), "test-lib")
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		return statuses[0].Status
+	}
+
+	for _, changedOnly := range []bool{false, true} {
+		scanner := NewScannerWithConfig(database.Conn(), ScanConfig{ChangedOnly: changedOnly})
+		result, err := scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, result.MatchesFound)
+		assert.Equal(t, 0, result.UnmatchedFiles)
+		assert.Equal(t, 1, countRows(t, database, cueMatches))
+		assert.Equal(t, 1, countRows(t, database, `
+			SELECT COUNT(*) FROM matches m
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			WHERE re.name = 'Disc Game (USA).cue' AND m.match_type = 'sha1'
+		`))
+		assert.Equal(t, "present", releaseStatus(scanner))
+	}
+
+	// With a track gone the sheet no longer matches and the disc is partial
+	require.NoError(t, os.Remove(filepath.Join(libPath, "Disc Game (Track 2).bin")))
+	scanner := NewScannerWithConfig(database.Conn(), ScanConfig{})
+	result, err := scanner.Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.MatchesFound)
+	assert.Equal(t, 1, result.UnmatchedFiles)
+	assert.Equal(t, 0, countRows(t, database, cueMatches))
+	assert.Equal(t, "partial", releaseStatus(scanner))
+}
@@ -0,0 +1,42 @@
+package library
+
+import (
+	"log/slog"
+	"os"
+)
+
+// deviceFilter keeps a walk on the library root's filesystem, like find -xdev.
+type deviceFilter struct {
+	root uint64
+}
+
+// newDeviceFilter returns a filter for root's filesystem, or nil when
+// OneFileSystem is off or the platform cannot report device ids.
+func (s *Scanner) newDeviceFilter(root string) *deviceFilter {
+	if !s.config.OneFileSystem {
+		return nil
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil
+	}
+	dev, ok := fileDevice(info)
+	if !ok {
+		slog.Warn("one-filesystem scanning is not supported on this platform; scanning all mounts")
+		return nil
+	}
+	return &deviceFilter{root: dev}
+}
+
+// skip reports whether a directory lives on a different filesystem than the root.
+func (f *deviceFilter) skip(path string, info os.FileInfo) bool {
+	if f == nil {
+		return false
+	}
+	dev, ok := fileDevice(info)
+	if !ok || dev == f.root {
+		return false
+	}
+	slog.Debug("skipping directory on another filesystem", "path", path)
+	return true
+}
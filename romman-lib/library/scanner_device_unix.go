@@ -0,0 +1,17 @@
+//go:build !windows
+
+package library
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileDevice returns the id of the device holding a file.
+func fileDevice(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true // #nosec G115 - device ids are never negative
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+
+package library
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceFilter(t *testing.T) {
+	root := t.TempDir()
+	rootInfo, err := os.Stat(root)
+	require.NoError(t, err)
+
+	off := NewScannerWithConfig(nil, ScanConfig{}).newDeviceFilter(root)
+	assert.Nil(t, off)
+	assert.False(t, off.skip(root, rootInfo))
+
+	filter := NewScannerWithConfig(nil, ScanConfig{OneFileSystem: true}).newDeviceFilter(root)
+	require.NotNil(t, filter)
+	assert.False(t, filter.skip(root, rootInfo))
+
+	// Pseudo filesystems are always mounted separately from the temp dir
+	for _, mount := range []string{"/proc", "/dev"} {
+		info, err := os.Stat(mount)
+		if err != nil {
+			continue
+		}
+		if dev, _ := fileDevice(info); dev != filter.root {
+			assert.True(t, filter.skip(mount, info))
+			return
+		}
+	}
+	t.Skip("no mount on another device available")
+}
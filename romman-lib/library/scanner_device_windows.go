@@ -0,0 +1,10 @@
+//go:build windows
+
+package library
+
+import "os"
+
+// fileDevice is not supported on Windows, so OneFileSystem has no effect there.
+func fileDevice(_ os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package library
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/ryanm101/romman-lib/metrics"
+)
+
+// scanErrors collects the files a scan could not read, from whichever
+// goroutine hit the error, for ScanResult.Errors.
+type scanErrors struct {
+	library string
+
+	mu     sync.Mutex
+	errors []ScanError
+}
+
+func newScanErrors(library string) *scanErrors {
+	return &scanErrors{library: library}
+}
+
+// add logs and records a file or archive entry that could not be read.
+// Outside a scan there is nothing to record it in, so it is only logged.
+func (e *scanErrors) add(msg, path, archivePath string, err error) {
+	slog.Warn(msg, "path", path, "entry", archivePath, "error", err)
+	if e == nil {
+		return
+	}
+	metrics.FilesProcessed.WithLabelValues(e.library, "error").Inc()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, ScanError{
+		Path:        path,
+		ArchivePath: archivePath,
+		Kind:        ScanErrorRead,
+		Message:     msg + ": " + err.Error(),
+	})
+}
+
+// apply copies the recorded errors into a scan result.
+func (e *scanErrors) apply(result *ScanResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result.Errors = append(result.Errors, e.errors...)
+}
@@ -0,0 +1,29 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_RecordsReadErrors(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		database, libPath := setupCheckpointLibrary(t, 1)
+		brokenPath := filepath.Join(libPath, "broken.zip")
+		require.NoError(t, os.WriteFile(brokenPath, []byte("not a zip"), 0644)) // #nosec G306
+
+		scanner := NewScannerWithConfig(database.Conn(), ScanConfig{Parallel: parallel, Workers: 2, BatchSize: 10})
+		result, err := scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, result.FilesScanned, "the readable file is still scanned")
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, brokenPath, result.Errors[0].Path)
+		assert.Equal(t, ScanErrorRead, result.Errors[0].Kind)
+		assert.Contains(t, result.Errors[0].Message, "zip")
+	}
+}
@@ -0,0 +1,245 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// genericExtensions are extensions that say nothing about a file's system.
+// Files with these extensions, or none, are only scanned for systems known
+// to use them; any other extension is always scanned.
+var genericExtensions = map[string]bool{
+	"":     true,
+	".bin": true,
+	".rom": true,
+}
+
+// SkippedFile is a file the last scan left out, with the reason.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// extensionFilter decides whether files with generic extensions belong to
+// their system, using the system's known ROM extensions.
+type extensionFilter struct {
+	ctx       context.Context
+	db        *sql.DB
+	resolver  *systemResolver
+	overrides map[string][]string       // System name -> configured extensions
+	known     map[int64]map[string]bool // System ID -> known extensions
+	names     map[int64]string
+}
+
+func (s *Scanner) newExtensionFilter(ctx context.Context, lib *Library) *extensionFilter {
+	return &extensionFilter{
+		ctx:       ctx,
+		db:        s.db,
+		resolver:  newSystemResolver(s.db, lib, nil),
+		overrides: s.config.SystemExtensions,
+		known:     make(map[int64]map[string]bool),
+		names:     make(map[int64]string),
+	}
+}
+
+// skipReason returns why path should not be scanned, or "" to scan it.
+// Systems without known extensions (no DAT and no configuration) accept everything.
+func (f *extensionFilter) skipReason(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if !genericExtensions[ext] {
+		return "", nil
+	}
+
+	systemID, err := f.resolver.systemFor(path)
+	if err != nil {
+		return "", err
+	}
+	known, err := f.knownExtensions(systemID)
+	if err != nil {
+		return "", err
+	}
+	if len(known) == 0 || known[ext] {
+		return "", nil
+	}
+
+	label := ext
+	if label == "" {
+		label = "no extension"
+	}
+	return fmt.Sprintf("%s is not a ROM extension for %s (expected %s)",
+		label, f.names[systemID], strings.Join(sortedExtensions(known), ", ")), nil
+}
+
+// knownExtensions returns the extensions a system's ROMs use: the configured
+// list if there is one, otherwise the extensions of its DAT ROM names.
+func (f *extensionFilter) knownExtensions(systemID int64) (map[string]bool, error) {
+	if known, ok := f.known[systemID]; ok {
+		return known, nil
+	}
+
+	var name string
+	if err := f.db.QueryRowContext(f.ctx, "SELECT name FROM systems WHERE id = ?", systemID).Scan(&name); err != nil {
+		return nil, err
+	}
+	f.names[systemID] = name
+
+	known := make(map[string]bool)
+	if exts, ok := f.overrides[name]; ok {
+		for _, ext := range exts {
+			known[normalizeExtension(ext)] = true
+		}
+	} else {
+		exts, err := datExtensions(f.ctx, f.db, systemID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ext := range exts {
+			known[ext] = true
+		}
+	}
+
+	f.known[systemID] = known
+	return known, nil
+}
+
+// datExtensions returns the distinct lower-case extensions of a system's DAT
+// ROM names, with "" for names without one.
+func datExtensions(ctx context.Context, db *sql.DB, systemID int64) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT re.name
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		WHERE r.system_id = ?
+	`, systemID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		seen[strings.ToLower(filepath.Ext(name))] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sortedExtensions(seen), nil
+}
+
+// normalizeExtension lower-cases ext and adds the leading dot. "" stays "",
+// meaning files without an extension.
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func sortedExtensions(set map[string]bool) []string {
+	exts := make([]string, 0, len(set))
+	for ext := range set {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// recordSkippedFiles replaces the library's skipped files with those of this
+// scan and drops any earlier scanned entries for them. A resumed scan keeps
+// those in the directories it did not walk again.
+func (s *Scanner) recordSkippedFiles(libraryID int64, skipped []SkippedFile) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := s.clearSkippedFiles(tx, libraryID); err != nil {
+		return err
+	}
+	for _, f := range skipped {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO skipped_files (library_id, path, reason) VALUES (?, ?, ?)`,
+			libraryID, f.Path, f.Reason); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM scanned_files WHERE library_id = ? AND path = ?`, libraryID, f.Path); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// clearSkippedFiles deletes the library's recorded skipped files, other than
+// those in directories a resumed scan skipped.
+func (s *Scanner) clearSkippedFiles(tx *sql.Tx, libraryID int64) error {
+	if s.resume == nil {
+		_, err := tx.Exec(`DELETE FROM skipped_files WHERE library_id = ?`, libraryID)
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT path FROM skipped_files WHERE library_id = ?`, libraryID)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if !s.resume.covers(path) {
+			stale = append(stale, path)
+		}
+	}
+	_ = rows.Close()
+
+	for _, path := range stale {
+		if _, err := tx.Exec(`DELETE FROM skipped_files WHERE library_id = ? AND path = ?`, libraryID, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSkippedFiles returns the files the last scan of a library left out
+// because their extension does not belong to the system.
+func (s *Scanner) GetSkippedFiles(ctx context.Context, libraryName string) ([]SkippedFile, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetSkippedFiles")
+	defer span.End()
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT path, reason FROM skipped_files WHERE library_id = ? ORDER BY path
+	`, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "get skipped files")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var files []SkippedFile
+	for rows.Next() {
+		var f SkippedFile
+		if err := rows.Scan(&f.Path, &f.Reason); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
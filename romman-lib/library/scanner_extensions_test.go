@@ -0,0 +1,114 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+// setupExtensionLibrary creates a library for a system whose DAT holds one
+// ROM named romName, and writes files (name -> content) into it.
+func setupExtensionLibrary(t *testing.T, system, romName string, files map[string]string) (*db.DB, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader("rom"))
+	require.NoError(t, err)
+	conn := database.Conn()
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, ?)`, system)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (1, ?, ?, ?, 3)`, romName, sha1Hash, crc32Hash)
+	require.NoError(t, err)
+
+	libPath := filepath.Join(tmpDir, "roms")
+	require.NoError(t, os.MkdirAll(libPath, 0755)) // #nosec G301
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(libPath, name), []byte(content), 0644)) // #nosec G306
+	}
+
+	_, err = NewManager(conn).Add(context.Background(), "test-lib", libPath, system)
+	require.NoError(t, err)
+	return database, libPath
+}
+
+func TestScanner_KnownExtensionsA78(t *testing.T) {
+	database, libPath := setupExtensionLibrary(t, "atari7800", "Game (USA).a78", map[string]string{
+		"game.a78":  "rom",
+		"stray.bin": "junk",
+		"README":    "notes",
+	})
+
+	result, err := NewScanner(database.Conn()).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.FilesScanned)
+	assert.Equal(t, 2, result.FilesExcluded)
+	assert.Equal(t, 1, result.MatchesFound)
+
+	skipped, err := NewScanner(database.Conn()).GetSkippedFiles(context.Background(), "test-lib")
+	require.NoError(t, err)
+	require.Len(t, skipped, 2)
+	assert.Equal(t, filepath.Join(libPath, "README"), skipped[0].Path)
+	assert.Equal(t, "no extension is not a ROM extension for atari7800 (expected .a78)", skipped[0].Reason)
+	assert.Equal(t, filepath.Join(libPath, "stray.bin"), skipped[1].Path)
+	assert.Contains(t, skipped[1].Reason, ".bin is not a ROM extension")
+
+	// The unmatched report explains the skipped files
+	data, err := NewExporter(database.Conn(), NewManager(database.Conn())).Export(context.Background(), "test-lib", ReportUnmatched, FormatCSV)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "stray.bin,,skipped: .bin is not a ROM extension")
+
+	// Configuring extension-less ROMs for the system includes README on the next scan
+	cfg := DefaultScanConfig()
+	cfg.SystemExtensions = map[string][]string{"atari7800": {"A78", ""}}
+	result, err = NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.FilesScanned)
+	assert.Equal(t, 1, result.FilesExcluded)
+
+	skipped, err = NewScanner(database.Conn()).GetSkippedFiles(context.Background(), "test-lib")
+	require.NoError(t, err)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, filepath.Join(libPath, "stray.bin"), skipped[0].Path)
+}
+
+func TestScanner_KnownExtensionsBin(t *testing.T) {
+	database, _ := setupExtensionLibrary(t, "megadrive", "Game (USA).bin", map[string]string{
+		"game.bin":  "rom",
+		"other.rom": "junk",
+	})
+
+	// A stray file scanned before the DAT knew better is dropped
+	_, err := database.Conn().Exec(`DELETE FROM rom_entries`)
+	require.NoError(t, err)
+	result, err := NewScanner(database.Conn()).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.FilesScanned, "systems without known extensions scan everything")
+
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader("rom"))
+	require.NoError(t, err)
+	_, err = database.Conn().Exec(`INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (1, 'Game (USA).bin', ?, ?, 3)`, sha1Hash, crc32Hash)
+	require.NoError(t, err)
+
+	result, err = NewScanner(database.Conn()).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.FilesScanned)
+	assert.Equal(t, 1, result.FilesExcluded)
+	assert.Equal(t, 1, result.MatchesFound)
+
+	var count int
+	require.NoError(t, database.Conn().QueryRow(`SELECT COUNT(*) FROM scanned_files`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,117 @@
+package library
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	// fuzzyPrefixLen is how many leading characters of its normalized title
+	// a release must share with a file to be compared with it. A typo in
+	// the first characters is missed, which keeps the candidates few.
+	fuzzyPrefixLen = 3
+
+	// minFuzzyLength is the shortest normalized title fuzzy matching is
+	// tried for; a couple of edits turn one short title into another.
+	minFuzzyLength = 8
+
+	// defaultFuzzyDistance is the edit distance used when ScanConfig
+	// leaves FuzzyDistance unset.
+	defaultFuzzyDistance = 2
+
+	// fuzzyFlag marks matches found by the fuzzy name tier.
+	fuzzyFlag = "fuzzy"
+)
+
+// trailingArticleRegex finds a DAT-style trailing article, as in
+// "Legend of Zelda, The (USA)".
+var trailingArticleRegex = regexp.MustCompile(`(?i)^([^(\[,]+), (the|a|an)\b(.*)$`)
+
+// fuzzyNameIndex lists a system's normalized ROM titles by prefix. Each
+// candidate's ReleaseName holds the normalized title.
+type fuzzyNameIndex map[string][]FuzzyMatch
+
+// fuzzyTitle normalizes a ROM or file name for fuzzy matching, moving a
+// trailing article to the front first so "Legend of Zelda, The" and
+// "The Legend of Zelda" agree.
+func fuzzyTitle(name string) string {
+	return NormalizeTitleForMatching(trailingArticleRegex.ReplaceAllString(name, "$2 $1$3"))
+}
+
+// fuzzyPrefix returns the bucket of a normalized title in a fuzzyNameIndex.
+func fuzzyPrefix(title string) string {
+	if len(title) > fuzzyPrefixLen {
+		return title[:fuzzyPrefixLen]
+	}
+	return title
+}
+
+// buildFuzzyNameIndex indexes the titles of a release name index, keeping
+// the first ROM entry of each title.
+func buildFuzzyNameIndex(releaseNames map[string][]releaseNameEntry) fuzzyNameIndex {
+	seen := make(map[string]bool)
+	index := make(fuzzyNameIndex)
+	for _, entries := range releaseNames {
+		for _, entry := range entries {
+			title := fuzzyTitle(entry.romName)
+			if title == "" || seen[title] {
+				continue
+			}
+			seen[title] = true
+			prefix := fuzzyPrefix(title)
+			index[prefix] = append(index[prefix], FuzzyMatch{
+				ReleaseName: title,
+				ReleaseID:   entry.releaseID,
+				RomEntryID:  entry.romEntryID,
+			})
+		}
+	}
+
+	// Sorted, so ties between equally close titles always go the same way
+	for _, candidates := range index {
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].ReleaseName < candidates[j].ReleaseName
+		})
+	}
+	return index
+}
+
+// fuzzyDistance returns the maximum edit distance for fuzzy name matches.
+func (s *Scanner) fuzzyDistance() int {
+	if s.config.FuzzyDistance > 0 {
+		return s.config.FuzzyDistance
+	}
+	return defaultFuzzyDistance
+}
+
+// matchFuzzyName matches a file to the release whose normalized title is
+// closest to its own, within the configured edit distance. It is the last
+// tier, tried only when ScanConfig.Fuzzy is set and every other one failed.
+func (s *Scanner) matchFuzzyName(f fileToMatch, index fuzzyNameIndex) (bool, error) {
+	filename := filepath.Base(f.path)
+	title := fuzzyTitle(filename)
+	if len(title) < minFuzzyLength {
+		return false, nil
+	}
+
+	// Titles whose lengths differ by more than the threshold can't be close enough
+	threshold := s.fuzzyDistance()
+	var candidates []FuzzyMatch
+	for _, c := range index[fuzzyPrefix(title)] {
+		if diff := len(c.ReleaseName) - len(title); diff <= threshold && diff >= -threshold {
+			candidates = append(candidates, c)
+		}
+	}
+
+	best := (&FuzzyMatcher{Threshold: threshold}).FindBestMatch(title, candidates)
+	if best == nil {
+		return false, nil
+	}
+
+	flags := fuzzyFlag
+	if status := ParseFilenameStatus(filename).GetStatusFlags(); status != "" {
+		flags = status + "," + fuzzyFlag
+	}
+	return s.insertMatch(f.id, best.RomEntryID, string(MatchTypeFuzzyName), flags)
+}
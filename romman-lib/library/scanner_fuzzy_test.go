@@ -0,0 +1,97 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestFuzzyTitle(t *testing.T) {
+	assert.Equal(t, "thelegendofzelda", fuzzyTitle("Legend of Zelda, The (USA).nes"))
+	assert.Equal(t, "thelegendofzelda", fuzzyTitle("The Legend of Zelda.nes"))
+	assert.Equal(t, "thelegendofzeldaalinktothepast", fuzzyTitle("Legend of Zelda, The - A Link to the Past (USA).sfc"))
+	assert.Equal(t, "anamericantail", fuzzyTitle("American Tail, An (USA).nes"))
+	assert.Equal(t, "supermariobros", fuzzyTitle("Super Mario Bros. (World).nes"))
+}
+
+func TestScanner_FuzzyNameMatching(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	root := t.TempDir()
+	for _, name := range []string{
+		"The Legend of Zelda.nes",  // Article moved
+		"Metroid Fusoin (USA).nes", // Transposed letters
+		"Castlevania III [h].nes",  // One edit away, and modified
+		"Megaman Two.nes",          // Three edits away
+		"Tetris.nes",               // Too short to fuzzy match
+		"Completely Different.nes", // No close title
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(name), 0o600))
+	}
+
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES
+		(1, 1, 'Legend of Zelda, The (USA)'), (2, 1, 'Metroid Fusion (USA)'),
+		(3, 1, 'Castlevania II (USA)'), (4, 1, 'Mega Man 2 (USA)'), (5, 1, 'Tetrix (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES
+		(1, 1, 'Legend of Zelda, The (USA).nes', 'a'), (2, 2, 'Metroid Fusion (USA).nes', 'b'),
+		(3, 3, 'Castlevania II (USA).nes', 'c'), (4, 4, 'Mega Man 2 (USA).nes', 'd'),
+		(5, 5, 'Tetrix (USA).nes', 'e')`)
+	require.NoError(t, err)
+
+	_, err = NewManager(conn).Add(ctx, "nes", root, "nes")
+	require.NoError(t, err)
+
+	matches := func() map[string]string {
+		t.Helper()
+		rows, err := conn.Query(`
+			SELECT sf.path, r.name, m.match_type, COALESCE(m.flags, '') FROM matches m
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			JOIN releases r ON r.id = re.release_id`)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		got := make(map[string]string)
+		for rows.Next() {
+			var path, release, matchType, flags string
+			require.NoError(t, rows.Scan(&path, &release, &matchType, &flags))
+			got[filepath.Base(path)] = release + " " + matchType + " " + flags
+		}
+		return got
+	}
+
+	// Off by default
+	_, err = NewScanner(conn).Scan(ctx, "nes")
+	require.NoError(t, err)
+	assert.Empty(t, matches())
+
+	cfg := DefaultScanConfig()
+	cfg.Fuzzy = true
+	result, err := NewScannerWithConfig(conn, cfg).Scan(ctx, "nes")
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.MatchesFound)
+	assert.Equal(t, map[string]string{
+		"The Legend of Zelda.nes":  "Legend of Zelda, The (USA) name_fuzzy fuzzy",
+		"Metroid Fusoin (USA).nes": "Metroid Fusion (USA) name_fuzzy fuzzy",
+		"Castlevania III [h].nes":  "Castlevania II (USA) name_fuzzy hack,fuzzy",
+	}, matches())
+
+	// A larger distance reaches further
+	cfg.FuzzyDistance = 3
+	_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "nes")
+	require.NoError(t, err)
+	assert.Equal(t, "Mega Man 2 (USA) name_fuzzy fuzzy", matches()["Megaman Two.nes"])
+	assert.NotContains(t, matches(), "Tetris.nes")
+}
@@ -2,104 +2,236 @@ package library
 
 import (
 	"archive/zip"
+	"crypto/md5"  // #nosec G501
 	"crypto/sha1" // #nosec G505
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"hash/crc32"
 	"io"
 	"os"
+	"runtime"
+	"sync"
 )
 
-// computeHashes computes SHA1 and CRC32 hashes from a reader.
-func computeHashes(r io.Reader) (sha1Hex, crc32Hex string, err error) {
+// computeHashes computes SHA1, CRC32 and MD5 hashes from a reader. Any extra
+// writers, such as an optional SHA256 hasher, are fed the same data.
+func computeHashes(r io.Reader, extra ...io.Writer) (sha1Hex, crc32Hex, md5Hex string, err error) {
 	sha1Hasher := sha1.New() // #nosec G401
 	crc32Hasher := crc32.NewIEEE()
-	multiWriter := io.MultiWriter(sha1Hasher, crc32Hasher)
+	md5Hasher := md5.New() // #nosec G401
+	multiWriter := io.MultiWriter(append([]io.Writer{sha1Hasher, crc32Hasher, md5Hasher}, extra...)...)
 
 	if _, err := io.Copy(multiWriter, r); err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 
 	sha1Hex = hex.EncodeToString(sha1Hasher.Sum(nil))
 	crc32Hex = fmt.Sprintf("%08x", crc32Hasher.Sum32())
+	md5Hex = hex.EncodeToString(md5Hasher.Sum(nil))
 
-	return sha1Hex, crc32Hex, nil
+	return sha1Hex, crc32Hex, md5Hex, nil
+}
+
+// parallelHashChunk is how much computeHashesParallel hands each hasher at
+// a time. A hasher reads a whole chunk before hashing it, so the others can
+// take the chunk while it works.
+const parallelHashChunk = 1 << 20
+
+// defaultParallelHashMinSize is the smallest file hashed in parallel when
+// ScanConfig leaves ParallelHashMinSize unset. Below it the goroutine and
+// pipe overhead costs more than the parallelism wins.
+const defaultParallelHashMinSize = 64 << 20
+
+// computeHashesParallel computes the same hashes as computeHashes, running
+// each hasher in its own goroutine fed through an io.Pipe, so the hashes
+// use a core each rather than sharing one. It only pays off for large files
+// on storage that reads faster than a single core hashes.
+func computeHashesParallel(r io.Reader, extra ...io.Writer) (sha1Hex, crc32Hex, md5Hex string, err error) {
+	sha1Hasher := sha1.New() // #nosec G401
+	crc32Hasher := crc32.NewIEEE()
+	md5Hasher := md5.New() // #nosec G401
+	hashers := append([]io.Writer{sha1Hasher, crc32Hasher, md5Hasher}, extra...)
+
+	var wg sync.WaitGroup
+	pipes := make([]*io.PipeWriter, len(hashers))
+	fanOut := make([]io.Writer, len(hashers))
+	for i, h := range hashers {
+		pr, pw := io.Pipe()
+		pipes[i], fanOut[i] = pw, pw
+		wg.Add(1)
+		go func(h io.Writer) {
+			defer wg.Done()
+			buf := make([]byte, parallelHashChunk)
+			_, err := io.CopyBuffer(h, pr, buf)
+			_ = pr.CloseWithError(err)
+		}(h)
+	}
+
+	// Hide any WriterTo, so reads are chunk sized rather than io.Copy's 32 KiB
+	_, err = io.CopyBuffer(io.MultiWriter(fanOut...), struct{ io.Reader }{r}, make([]byte, parallelHashChunk))
+	for _, pw := range pipes {
+		_ = pw.CloseWithError(err)
+	}
+	wg.Wait()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	sha1Hex = hex.EncodeToString(sha1Hasher.Sum(nil))
+	crc32Hex = fmt.Sprintf("%08x", crc32Hasher.Sum32())
+	md5Hex = hex.EncodeToString(md5Hasher.Sum(nil))
+
+	return sha1Hex, crc32Hex, md5Hex, nil
+}
+
+// hashOpenFile hashes an open regular file of the given size, in parallel
+// when it is at least ScanConfig.ParallelHashMinSize and there is more than
+// one core to spread the hashes over.
+func (s *Scanner) hashOpenFile(f *os.File, size int64, extra ...io.Writer) (string, string, string, error) {
+	minSize := s.config.ParallelHashMinSize
+	if minSize == 0 {
+		minSize = defaultParallelHashMinSize
+	}
+	if minSize > 0 && size >= minSize && runtime.GOMAXPROCS(0) > 1 {
+		return computeHashesParallel(f, extra...)
+	}
+	return computeHashes(f, extra...)
+}
+
+// sha256Hasher returns a SHA256 hasher when scan.sha256 is enabled, or nil.
+func (s *Scanner) sha256Hasher() hash.Hash {
+	if !s.config.SHA256 {
+		return nil
+	}
+	return sha256.New()
+}
+
+// hashWriters returns h as extra writers for computeHashes, or none if h is nil.
+func hashWriters(h hash.Hash) []io.Writer {
+	if h == nil {
+		return nil
+	}
+	return []io.Writer{h}
+}
+
+// hashHex returns the hex digest of h, or "" if h is nil.
+func hashHex(h hash.Hash) string {
+	if h == nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // hashFile computes hashes for a regular file.
-func (s *Scanner) hashFile(path string) (string, string, error) {
+func (s *Scanner) hashFile(path string, extra ...io.Writer) (string, string, string, error) {
 	f, err := os.Open(path) // #nosec G304
 	if err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 	defer func() { _ = f.Close() }()
-	return computeHashes(f)
+	info, err := f.Stat()
+	if err != nil {
+		return "", "", "", err
+	}
+	return s.hashOpenFile(f, info.Size(), extra...)
 }
 
 // hashCHDFile extracts hashes from a CHD file header without decompression.
 // nolint:unparam
-func (s *Scanner) hashCHDFile(path string) (string, string, error) {
+func (s *Scanner) hashCHDFile(path string) (string, string, string, error) {
 	info, err := ParseCHD(path)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse CHD: %w", err)
+		return "", "", "", fmt.Errorf("failed to parse CHD: %w", err)
 	}
 
 	// Use DataSHA1 (raw data hash) for matching, as this is what DATs use.
-	// CHD files don't have a traditional CRC32; we leave it empty.
-	return info.DataSHA1, "", nil
+	// CHD files don't have a traditional CRC32 or MD5; we leave them empty.
+	return info.DataSHA1, "", "", nil
 }
 
 // hashZipEntry computes hashes for a file inside a zip archive.
-func (s *Scanner) hashZipEntry(zipPath, entryName string) (string, string, error) {
+func (s *Scanner) hashZipEntry(zipPath, entryName string, extra ...io.Writer) (string, string, string, error) {
+	if isNestedEntry(entryName) {
+		return hashNestedEntry(zipPath, entryName, extra...)
+	}
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 	defer func() { _ = r.Close() }()
 
 	for _, f := range r.File {
 		if f.Name == entryName {
-			rc, err := f.Open()
-			if err != nil {
-				return "", "", err
-			}
-			sha1, crc32, err := computeHashes(rc)
-			_ = rc.Close()
-			return sha1, crc32, err
+			return hashZipFile(f, extra...)
 		}
 	}
-	return "", "", fmt.Errorf("entry %s not found in %s", entryName, zipPath)
+	return "", "", "", fmt.Errorf("entry %s not found in %s", entryName, zipPath)
 }
 
-// storeBatch writes a batch of hash results to the database in a single transaction.
-func (s *Scanner) storeBatch(libraryID int64, batch []hashResult) error {
-	tx, err := s.db.Begin()
+// hashArchiveEntry computes hashes for an entry of a zip or 7z archive.
+func (s *Scanner) hashArchiveEntry(archivePath, entryName string, extra ...io.Writer) (string, string, string, error) {
+	if is7z(archivePath) {
+		return s.hash7zEntry(archivePath, entryName, extra...)
+	}
+	return s.hashZipEntry(archivePath, entryName, extra...)
+}
+
+// hashZipFile computes hashes for a zip entry's decompressed data. A CRC that
+// disagrees with the entry header is not an error here: the header CRC is
+// stored separately so verify can report the mismatch.
+func hashZipFile(f *zip.File, extra ...io.Writer) (string, string, string, error) {
+	rc, err := f.Open()
 	if err != nil {
+		return "", "", "", err
+	}
+	defer func() { _ = rc.Close() }()
+	return computeHashes(checksumTolerantReader{rc}, extra...)
+}
+
+// checksumTolerantReader turns zip.ErrChecksum, which is only returned once
+// all data has been read, into a normal EOF.
+type checksumTolerantReader struct {
+	r io.Reader
+}
+
+func (c checksumTolerantReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if errors.Is(err, zip.ErrChecksum) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// zipHeaderCRC32 returns the CRC32 declared in a zip entry's header as hex.
+func zipHeaderCRC32(f *zip.File) string {
+	return fmt.Sprintf("%08x", f.CRC32)
+}
+
+// storeCheckpoint stores a batch of hash results and commits a checkpoint for it.
+func (s *Scanner) storeCheckpoint(cp *checkpointer, batch []hashResult) error {
+	if err := s.storeBatch(cp.lib.ID, batch); err != nil {
 		return err
 	}
+	return cp.commit(batch)
+}
 
-	stmt, err := tx.Prepare(`
-		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32, archive_path)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(library_id, path, archive_path) DO UPDATE SET
-			size = excluded.size,
-			mtime = excluded.mtime,
-			sha1 = excluded.sha1,
-			crc32 = excluded.crc32,
-			scanned_at = CURRENT_TIMESTAMP
-	`)
+// storeBatch writes a batch of hash results to the database in a single transaction.
+func (s *Scanner) storeBatch(libraryID int64, batch []hashResult) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		_ = tx.Rollback()
 		return err
 	}
-	defer func() { _ = stmt.Close() }()
 
 	for _, r := range batch {
-		var archivePathVal interface{}
-		if r.job.archivePath != "" {
-			archivePathVal = r.job.archivePath
+		// Cached results are already stored
+		if !r.wasHashed {
+			continue
 		}
-		_, err := stmt.Exec(libraryID, r.job.path, r.job.size, r.job.mtime, r.sha1, r.crc32, archivePathVal)
+		err := upsertScannedFile(tx, libraryID, r.job.path, r.job.archivePath, r.job.size, r.job.mtime,
+			r.sha1, r.crc32, r.md5, r.sha256, r.job.zipCRC32, r.headerless)
 		if err != nil {
 			_ = tx.Rollback()
 			return err
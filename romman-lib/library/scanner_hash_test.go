@@ -0,0 +1,71 @@
+package library
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeHashesParallel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) // #nosec G404
+	for _, size := range []int{0, 1, parallelHashChunk - 1, parallelHashChunk, 3*parallelHashChunk + 17} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := make([]byte, size)
+			_, _ = rng.Read(data)
+
+			wantSHA256 := sha256.New()
+			wantSHA1, wantCRC32, wantMD5, err := computeHashes(bytes.NewReader(data), wantSHA256)
+			require.NoError(t, err)
+
+			gotSHA256 := sha256.New()
+			gotSHA1, gotCRC32, gotMD5, err := computeHashesParallel(bytes.NewReader(data), gotSHA256)
+			require.NoError(t, err)
+
+			assert.Equal(t, wantSHA1, gotSHA1)
+			assert.Equal(t, wantCRC32, gotCRC32)
+			assert.Equal(t, wantMD5, gotMD5)
+			assert.Equal(t, hashHex(wantSHA256), hashHex(gotSHA256))
+		})
+	}
+}
+
+func TestComputeHashesParallel_ReadError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 2*parallelHashChunk)), &failingReader{err: readErr})
+
+	_, _, _, err := computeHashesParallel(r)
+	assert.ErrorIs(t, err, readErr)
+}
+
+// failingReader fails every read with err.
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+// BenchmarkComputeHashes compares single-pass and parallel hashing, to pick
+// defaultParallelHashMinSize. Parallel hashing only wins with spare cores.
+func BenchmarkComputeHashes(b *testing.B) {
+	for _, size := range []int{1 << 20, 16 << 20, 64 << 20, 256 << 20} {
+		data := make([]byte, size)
+		for name, hash := range map[string]func(io.Reader, ...io.Writer) (string, string, string, error){
+			"single":   computeHashes,
+			"parallel": computeHashesParallel,
+		} {
+			b.Run(fmt.Sprintf("%s/%dMiB", name, size>>20), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					if _, _, _, err := hash(bytes.NewReader(data)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
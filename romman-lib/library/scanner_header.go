@@ -0,0 +1,209 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// headeredFlag marks matches made by hashing a ROM without its header.
+const headeredFlag = "headered"
+
+// romHeader describes a copier or emulator header that No-Intro DATs leave
+// out of their hashes.
+type romHeader struct {
+	ext    string // File extension of headered dumps
+	size   int    // Header length in bytes
+	magic  []byte // Bytes identifying the header
+	offset int    // Position of magic within the header
+}
+
+// skipperHeaders maps the clrmamepro header-skip rule files that DATs name
+// in <clrmamepro header="..."> to the header they strip, keyed in lower case.
+var skipperHeaders = map[string]romHeader{
+	"no-intro_nes.xml":   {ext: ".nes", size: 16, magic: []byte("NES\x1a")},
+	"no-intro_fds.xml":   {ext: ".fds", size: 16, magic: []byte("FDS\x1a")},
+	"no-intro_a7800.xml": {ext: ".a78", size: 128, magic: []byte("ATARI7800"), offset: 1},
+	"no-intro_lnx.xml":   {ext: ".lnx", size: 64, magic: []byte("LYNX")},
+}
+
+// systemHeaderRules gives the rule assumed for systems whose DAT names none,
+// such as DATs imported before header rules were recorded.
+var systemHeaderRules = map[string]string{
+	"nes":       "No-Intro_NES.xml",
+	"fds":       "No-Intro_FDS.xml",
+	"atari7800": "No-Intro_A7800.xml",
+	"atarilynx": "No-Intro_LNX.xml",
+}
+
+// loadHeaders returns the headers to strip this scan, from the rule each
+// system's DAT declares.
+func (s *Scanner) loadHeaders() ([]romHeader, error) {
+	rows, err := s.db.Query(`SELECT name, dat_header_rule FROM systems`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var headers []romHeader
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name, rule string
+		if err := rows.Scan(&name, &rule); err != nil {
+			return nil, err
+		}
+		if rule == "" {
+			rule = systemHeaderRules[name]
+		}
+		rule = strings.ToLower(rule)
+		if rule == "" || seen[rule] {
+			continue
+		}
+		seen[rule] = true
+
+		h, ok := skipperHeaders[rule]
+		if !ok {
+			slog.Debug("unknown header skip rule", "system", name, "rule", rule)
+			continue
+		}
+		headers = append(headers, h)
+	}
+	return headers, rows.Err()
+}
+
+// headerlessHash holds the hashes of a ROM minus its header. Both are empty
+// for files without a known header.
+type headerlessHash struct {
+	sha1  string
+	crc32 string
+}
+
+// headerForName returns the header rule for a file name's extension.
+func (s *Scanner) headerForName(name string) (romHeader, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, h := range s.headers {
+		if h.ext == ext {
+			return h, true
+		}
+	}
+	return romHeader{}, false
+}
+
+// hashHeaderless hashes the data after the header when name has a headered
+// extension and the data starts with that header. Other files are not
+// opened at all.
+func (s *Scanner) hashHeaderless(name string, open func() (io.ReadCloser, error)) (headerlessHash, error) {
+	h, ok := s.headerForName(name)
+	if !ok {
+		return headerlessHash{}, nil
+	}
+
+	rc, err := open()
+	if err != nil {
+		return headerlessHash{}, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	head := make([]byte, h.size)
+	if _, err := io.ReadFull(rc, head); err != nil {
+		// Too short to carry a header
+		return headerlessHash{}, nil
+	}
+	if !bytes.Equal(head[h.offset:h.offset+len(h.magic)], h.magic) {
+		return headerlessHash{}, nil
+	}
+
+	sha1Hash, crc32Hash, _, err := computeHashes(checksumTolerantReader{rc})
+	if err != nil {
+		return headerlessHash{}, err
+	}
+	return headerlessHash{sha1: sha1Hash, crc32: crc32Hash}, nil
+}
+
+// hashJobHeaderless computes the headerless hashes of a queued file, opening
+// archive entries through their archive.
+func (s *Scanner) hashJobHeaderless(job fileJob) (headerlessHash, error) {
+	if job.isCHD {
+		return headerlessHash{}, nil
+	}
+	if job.archivePath == "" {
+		return s.hashHeaderless(job.path, func() (io.ReadCloser, error) {
+			return os.Open(job.path) // #nosec G304
+		})
+	}
+	return s.hashHeaderless(job.archivePath, func() (io.ReadCloser, error) {
+		return openArchiveEntry(job.path, job.archivePath)
+	})
+}
+
+// openArchiveEntry opens an entry of a zip or 7z archive, descending into
+// nested archives for a composite entry name. Closing the returned reader
+// also closes the archive.
+func openArchiveEntry(archivePath, entryName string) (io.ReadCloser, error) {
+	names := splitNested(entryName)
+	rc, err := openTopEntry(archivePath, names[0])
+	if err != nil || len(names) == 1 {
+		return rc, err
+	}
+	return openNestedEntry(rc, names[0], names[1:])
+}
+
+// openTopEntry opens an entry stored directly in a zip or 7z archive.
+func openTopEntry(archivePath, entryName string) (io.ReadCloser, error) {
+	if is7z(archivePath) {
+		r, err := sevenzip.OpenReader(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range r.File {
+			if f.Name == entryName {
+				return openedEntry(f.Open, r)
+			}
+		}
+		_ = r.Close()
+		return nil, fmt.Errorf("entry %s not found in %s", entryName, archivePath)
+	}
+
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if f.Name == entryName {
+			return openedEntry(f.Open, r)
+		}
+	}
+	_ = r.Close()
+	return nil, fmt.Errorf("entry %s not found in %s", entryName, archivePath)
+}
+
+// openedEntry opens an archive entry and ties the archive's lifetime to it.
+func openedEntry(open func() (io.ReadCloser, error), archive io.Closer) (io.ReadCloser, error) {
+	rc, err := open()
+	if err != nil {
+		_ = archive.Close()
+		return nil, err
+	}
+	return entryReader{ReadCloser: rc, archive: archive}, nil
+}
+
+// entryReader is an archive entry that closes its archive when closed.
+type entryReader struct {
+	io.ReadCloser
+	archive io.Closer
+}
+
+func (e entryReader) Close() error {
+	err := e.ReadCloser.Close()
+	if cerr := e.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
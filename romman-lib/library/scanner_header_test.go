@@ -0,0 +1,98 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashHeaderless(t *testing.T) {
+	rom := []byte("rom content 0")
+	headered := append([]byte("NES\x1a\x02\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), rom...)
+	open := func(data []byte) func() (io.ReadCloser, error) {
+		return func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	}
+
+	wantSHA1, wantCRC32, _, err := computeHashes(bytes.NewReader(rom))
+	require.NoError(t, err)
+
+	s := &Scanner{headers: []romHeader{skipperHeaders["no-intro_nes.xml"]}}
+	got, err := s.hashHeaderless("Game.NES", open(headered))
+	require.NoError(t, err)
+	assert.Equal(t, headerlessHash{sha1: wantSHA1, crc32: wantCRC32}, got)
+
+	// No magic, a short file or an unheadered system leave the hashes empty
+	for name, data := range map[string][]byte{"game.nes": append(make([]byte, 16), rom...), "tiny.nes": []byte("NES\x1a"), "game.sfc": headered} {
+		got, err := s.hashHeaderless(name, open(data))
+		require.NoError(t, err)
+		assert.Equal(t, headerlessHash{}, got, name)
+	}
+}
+
+func TestScan_MatchesHeaderedROM(t *testing.T) {
+	for name, cfg := range map[string]ScanConfig{
+		"sequential": {Workers: 1, BatchSize: 10, Parallel: false},
+		"parallel":   {Workers: 4, BatchSize: 10, Parallel: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			database, libPath := setupCheckpointLibrary(t, 1)
+
+			// The DAT hashes "rom content 0"; the dump on disk carries an iNES header
+			header := "NES\x1a\x02\x01" + strings.Repeat("\x00", 10)
+			require.NoError(t, os.WriteFile(filepath.Join(libPath, "game00.nes"), []byte(header+"rom content 0"), 0644)) // #nosec G306
+
+			result, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.MatchesFound)
+
+			var matchType, flags string
+			err = database.Conn().QueryRow(`SELECT match_type, flags FROM matches`).Scan(&matchType, &flags)
+			require.NoError(t, err)
+			assert.Equal(t, "sha1", matchType)
+			assert.Equal(t, headeredFlag, flags)
+		})
+	}
+}
+
+func TestLoadHeaders(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 0)
+	_, err := database.Conn().Exec(`
+		INSERT INTO systems (name, dat_header_rule) VALUES
+			('famicom', 'No-Intro_NES.xml'),
+			('atarilynx', 'no-intro_lnx.xml'),
+			('atari7800', 'Custom_A7800.xml'),
+			('snes', '')
+	`)
+	require.NoError(t, err)
+
+	// famicom and nes share a rule; atari7800's unknown rule replaces the
+	// built-in one rather than falling back to it
+	headers, err := NewScanner(database.Conn()).loadHeaders()
+	require.NoError(t, err)
+	var exts []string
+	for _, h := range headers {
+		exts = append(exts, h.ext)
+	}
+	assert.ElementsMatch(t, []string{".nes", ".lnx"}, exts)
+}
+
+func TestScan_DATHeaderRuleOverridesDefault(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 1)
+	header := "NES\x1a\x02\x01" + strings.Repeat("\x00", 10)
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "game00.nes"), []byte(header+"rom content 0"), 0644)) // #nosec G306
+
+	// A DAT naming a rule we cannot apply turns header stripping off
+	_, err := database.Conn().Exec(`UPDATE systems SET dat_header_rule = 'Custom_NES.xml' WHERE name = 'nes'`)
+	require.NoError(t, err)
+
+	_, err = NewScannerWithConfig(database.Conn(), ScanConfig{Workers: 1, BatchSize: 10}).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 0, countRows(t, database, `SELECT COUNT(*) FROM matches WHERE flags = ?`, headeredFlag))
+}
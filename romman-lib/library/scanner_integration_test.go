@@ -129,6 +129,7 @@ func initTestSchema(db *sql.DB) error {
 			crc32 TEXT,
 			md5 TEXT,
 			size INTEGER,
+			part TEXT NOT NULL DEFAULT '',
 			FOREIGN KEY (release_id) REFERENCES releases(id)
 		);
 		CREATE TABLE IF NOT EXISTS libraries (
@@ -138,6 +139,7 @@ func initTestSchema(db *sql.DB) error {
 			system_id INTEGER NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			last_scan_at TIMESTAMP,
+			multi_system INTEGER NOT NULL DEFAULT 0,
 			FOREIGN KEY (system_id) REFERENCES systems(id)
 		);
 		CREATE TABLE IF NOT EXISTS scanned_files (
@@ -149,6 +151,11 @@ func initTestSchema(db *sql.DB) error {
 			mtime INTEGER,
 			sha1 TEXT,
 			crc32 TEXT,
+			md5 TEXT NOT NULL DEFAULT '',
+			headerless_sha1 TEXT NOT NULL DEFAULT '',
+			headerless_crc32 TEXT NOT NULL DEFAULT '',
+			virtual INTEGER NOT NULL DEFAULT 0,
+			zip_crc32 TEXT NOT NULL DEFAULT '',
 			scanned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (library_id) REFERENCES libraries(id)
 		);
@@ -161,6 +168,13 @@ func initTestSchema(db *sql.DB) error {
 			FOREIGN KEY (scanned_file_id) REFERENCES scanned_files(id),
 			FOREIGN KEY (rom_entry_id) REFERENCES rom_entries(id)
 		);
+		CREATE TABLE IF NOT EXISTS skipped_files (
+			id INTEGER PRIMARY KEY,
+			library_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			UNIQUE(library_id, path)
+		);
 	`
 	_, err := db.Exec(schema)
 	return err
@@ -1,9 +1,11 @@
 package library
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 // matchResult holds the result of matching files.
@@ -14,10 +16,14 @@ type matchResult struct {
 
 // fileToMatch represents a file to be matched.
 type fileToMatch struct {
-	id    int64
-	sha1  string
-	crc32 string
-	path  string
+	id     int64
+	sha1   string
+	crc32  string
+	md5    string
+	sha256 string
+	path   string
+
+	headerless headerlessHash
 }
 
 // releaseNameEntry represents a ROM name from the database.
@@ -28,24 +34,37 @@ type releaseNameEntry struct {
 	normalized string
 }
 
-// matchFiles matches all scanned files against known ROM entries.
-func (s *Scanner) matchFiles(lib *Library) (*matchResult, error) {
-	result := &matchResult{}
+// finalMatch runs the end-of-scan match pass. In ChangedOnly mode the per-batch
+// checkpoints have already matched every newly hashed file, so only counts are gathered.
+// Split ROM parts and cue sheets are handled afterwards, since they only match as a set.
+func (s *Scanner) finalMatch(ctx context.Context, lib *Library) (*matchResult, error) {
+	if !s.config.ChangedOnly {
+		if _, err := s.matchFiles(ctx, lib); err != nil {
+			return nil, err
+		}
+	}
 
-	// Clear existing matches for this library
-	_, err := s.db.Exec(`
-		DELETE FROM matches
-		WHERE scanned_file_id IN (
-			SELECT id FROM scanned_files WHERE library_id = ?
-		)
-	`, lib.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to clear matches: %w", err)
+	if _, err := s.matchSplitROMs(ctx, lib); err != nil {
+		return nil, fmt.Errorf("failed to match split ROMs: %w", err)
 	}
+	if _, err := s.matchCueSheets(ctx, lib); err != nil {
+		return nil, fmt.Errorf("failed to match cue sheets: %w", err)
+	}
+	return s.countMatches(lib.ID)
+}
+
+// matchFiles matches all scanned files against known ROM entries.
+// Existing matches are replaced one batch at a time, so an interrupted run
+// only loses the matches of the batch in flight. Files matched by hand are
+// left alone.
+func (s *Scanner) matchFiles(ctx context.Context, lib *Library) (*matchResult, error) {
+	result := &matchResult{}
 
 	// Get all scanned files - collect them first to avoid holding rows open during writes
 	rows, err := s.db.Query(`
-		SELECT id, sha1, crc32, path FROM scanned_files WHERE library_id = ?
+		SELECT id, sha1, crc32, md5, sha256, path, headerless_sha1, headerless_crc32
+		FROM scanned_files WHERE library_id = ?
+		AND id NOT IN (SELECT scanned_file_id FROM matches WHERE is_manual = 1)
 	`, lib.ID)
 	if err != nil {
 		return nil, err
@@ -54,7 +73,7 @@ func (s *Scanner) matchFiles(lib *Library) (*matchResult, error) {
 	var files []fileToMatch
 	for rows.Next() {
 		var f fileToMatch
-		if err := rows.Scan(&f.id, &f.sha1, &f.crc32, &f.path); err != nil {
+		if err := rows.Scan(&f.id, &f.sha1, &f.crc32, &f.md5, &f.sha256, &f.path, &f.headerless.sha1, &f.headerless.crc32); err != nil {
 			_ = rows.Close()
 			return nil, err
 		}
@@ -62,29 +81,65 @@ func (s *Scanner) matchFiles(lib *Library) (*matchResult, error) {
 	}
 	_ = rows.Close()
 
-	// Build a map of normalized release names for fuzzy matching
-	releaseNames, err := s.buildReleaseNameIndex(lib.SystemID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build release index: %w", err)
+	// Release name indexes for fuzzy matching are built per system on first use
+	resolver := newSystemResolver(s.db, lib, s)
+
+	batchSize := s.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
 	}
 
-	// Now match each file
-	for _, f := range files {
-		matched, err := s.matchSingleFile(lib.SystemID, f, releaseNames)
-		if err != nil {
+	for start := 0; start < len(files); start += batchSize {
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
 
-		if matched {
-			result.MatchesFound++
-		} else {
-			result.UnmatchedFiles++
+		end := start + batchSize
+		if end > len(files) {
+			end = len(files)
+		}
+		batch := files[start:end]
+
+		if err := s.clearMatches(batch); err != nil {
+			return nil, fmt.Errorf("failed to clear matches: %w", err)
+		}
+
+		for _, f := range batch {
+			matched, err := resolver.matchFile(f)
+			if err != nil {
+				return nil, err
+			}
+
+			if matched {
+				result.MatchesFound++
+			} else {
+				result.UnmatchedFiles++
+			}
 		}
 	}
 
 	return result, nil
 }
 
+// clearMatches deletes existing matches for the given files, except those
+// assigned by hand.
+func (s *Scanner) clearMatches(files []fileToMatch) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(files))
+	args := make([]interface{}, len(files))
+	for i, f := range files {
+		placeholders[i] = "?"
+		args[i] = f.id
+	}
+
+	// #nosec G202 - only placeholders are concatenated
+	_, err := s.db.Exec(`DELETE FROM matches WHERE is_manual = 0 AND scanned_file_id IN (`+strings.Join(placeholders, ",")+`)`, args...)
+	return err
+}
+
 // buildReleaseNameIndex builds an index of normalized ROM names for matching.
 func (s *Scanner) buildReleaseNameIndex(systemID int64) (map[string][]releaseNameEntry, error) {
 	rows, err := s.db.Query(`
@@ -113,13 +168,35 @@ func (s *Scanner) buildReleaseNameIndex(systemID int64) (map[string][]releaseNam
 
 // matchSingleFile attempts to match a single file against ROM entries.
 func (s *Scanner) matchSingleFile(systemID int64, f fileToMatch, releaseNames map[string][]releaseNameEntry) (bool, error) {
-	// Try SHA1 match first (exact match)
+	// Try SHA256 first: it is only set when scan.sha256 is enabled, and only
+	// DATs such as Redump's carry it. An empty hash never matches, since
+	// imported hash lists may carry only one hash.
 	var romEntryID int64
-	err := s.db.QueryRow(`
-		SELECT re.id FROM rom_entries re
-		JOIN releases r ON re.release_id = r.id
-		WHERE r.system_id = ? AND LOWER(re.sha1) = LOWER(?)
-	`, systemID, f.sha1).Scan(&romEntryID)
+	err := sql.ErrNoRows
+	if f.sha256 != "" {
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.sha256 = ?
+		`, systemID, strings.ToLower(f.sha256)).Scan(&romEntryID)
+	}
+
+	if err == nil {
+		return s.insertMatch(f.id, romEntryID, "sha256", "")
+	}
+
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	// Then SHA1 (exact match)
+	if f.sha1 != "" {
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.sha1 = ?
+		`, systemID, strings.ToLower(f.sha1)).Scan(&romEntryID)
+	}
 
 	if err == nil {
 		// SHA1 match found - verified good dump
@@ -131,11 +208,13 @@ func (s *Scanner) matchSingleFile(systemID int64, f fileToMatch, releaseNames ma
 	}
 
 	// Try CRC32 fallback
-	err = s.db.QueryRow(`
-		SELECT re.id FROM rom_entries re
-		JOIN releases r ON re.release_id = r.id
-		WHERE r.system_id = ? AND LOWER(re.crc32) = LOWER(?)
-	`, systemID, f.crc32).Scan(&romEntryID)
+	if f.crc32 != "" {
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.crc32 = ?
+		`, systemID, strings.ToLower(f.crc32)).Scan(&romEntryID)
+	}
 
 	if err == nil {
 		// CRC32 match found
@@ -146,6 +225,50 @@ func (s *Scanner) matchSingleFile(systemID int64, f fileToMatch, releaseNames ma
 		return false, err
 	}
 
+	// Try MD5, for DAT entries that carry no SHA1 or whose CRC32 is absent
+	if f.md5 != "" {
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.md5 = ?
+		`, systemID, strings.ToLower(f.md5)).Scan(&romEntryID)
+	}
+
+	if err == nil {
+		return s.insertMatch(f.id, romEntryID, "md5", "")
+	}
+
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	// Headered dumps match DATs that hash the ROM without its header
+	if f.headerless.sha1 != "" {
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.sha1 = ?
+		`, systemID, f.headerless.sha1).Scan(&romEntryID)
+		if err == nil {
+			return s.insertMatch(f.id, romEntryID, "sha1", headeredFlag)
+		}
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+
+		err = s.db.QueryRow(`
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.crc32 = ?
+		`, systemID, f.headerless.crc32).Scan(&romEntryID)
+		if err == nil {
+			return s.insertMatch(f.id, romEntryID, "crc32", headeredFlag)
+		}
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+	}
+
 	// Try name-based matching
 	filename := filepath.Base(f.path)
 	status := ParseFilenameStatus(filename)
@@ -0,0 +1,229 @@
+package library
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+// setupMatchBenchDB creates a system with n releases, each with a single ROM entry.
+func setupMatchBenchDB(tb testing.TB, n int) *db.DB {
+	tb.Helper()
+
+	database, err := db.Open(context.Background(), filepath.Join(tb.TempDir(), "bench.db"))
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = database.Close() })
+
+	conn := database.Conn()
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(tb, err)
+
+	tx, err := conn.Begin()
+	require.NoError(tb, err)
+	for i := 0; i < n; i++ {
+		_, err = tx.Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, i+1, fmt.Sprintf("Game %d (USA)", i))
+		require.NoError(tb, err)
+		_, err = tx.Exec(`
+			INSERT INTO rom_entries (release_id, name, sha1, crc32, size)
+			VALUES (?, ?, ?, ?, 1024)
+		`, i+1, fmt.Sprintf("Game %d (USA).nes", i), fmt.Sprintf("%040x", i), fmt.Sprintf("%08x", i))
+		require.NoError(tb, err)
+	}
+	require.NoError(tb, tx.Commit())
+
+	return database
+}
+
+func TestMatchSingleFile_UppercaseHashes(t *testing.T) {
+	database := setupMatchBenchDB(t, 10)
+	conn := database.Conn()
+
+	_, err := conn.Exec(`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'lib', '/roms', 1)`)
+	require.NoError(t, err)
+	res, err := conn.Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32)
+		VALUES (1, '/roms/unknown.nes', 1024, 0, 'ffff', 'ffff')
+	`)
+	require.NoError(t, err)
+	fileID, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	scanner := NewScanner(conn)
+
+	// Callers may pass uppercase hashes; stored values are always lowercase
+	f := fileToMatch{id: fileID, sha1: "FFFF", crc32: fmt.Sprintf("%08X", 7), path: "/roms/unknown.nes"}
+	matched, err := scanner.matchSingleFile(1, f, nil)
+	require.NoError(t, err)
+	assert.True(t, matched)
+
+	var matchType string
+	err = conn.QueryRow(`SELECT match_type FROM matches WHERE scanned_file_id = ?`, fileID).Scan(&matchType)
+	require.NoError(t, err)
+	assert.Equal(t, "crc32", matchType)
+}
+
+func TestScan_MatchesMD5OnlyEntries(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 1)
+	conn := database.Conn()
+
+	// Some Redump and TOSEC entries only carry an MD5
+	_, _, md5Hash, err := computeHashes(strings.NewReader("rom content 0"))
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE rom_entries SET sha1 = NULL, crc32 = NULL, md5 = ?`, md5Hash)
+	require.NoError(t, err)
+
+	result, err := NewScanner(conn).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MatchesFound)
+
+	var storedMD5, matchType string
+	err = conn.QueryRow(`
+		SELECT sf.md5, m.match_type FROM scanned_files sf
+		JOIN matches m ON m.scanned_file_id = sf.id
+	`).Scan(&storedMD5, &matchType)
+	require.NoError(t, err)
+	assert.Equal(t, md5Hash, storedMD5)
+	assert.Equal(t, "md5", matchType)
+}
+
+func BenchmarkMatchHashLookup(b *testing.B) {
+	database := setupMatchBenchDB(b, 50000)
+	conn := database.Conn()
+
+	queries := map[string]string{
+		"lower": `
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND LOWER(re.sha1) = LOWER(?)`,
+		"indexed": `
+			SELECT re.id FROM rom_entries re
+			JOIN releases r ON re.release_id = r.id
+			WHERE r.system_id = ? AND re.sha1 = ?`,
+	}
+
+	for _, name := range []string{"lower", "indexed"} {
+		query := queries[name]
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var id int64
+				sha1 := fmt.Sprintf("%040x", i%50000)
+				if err := conn.QueryRow(query, int64(1), sha1).Scan(&id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanner_MatchesSHA256WhenEnabled(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 0)
+	conn := database.Conn()
+
+	content := "redump disc track"
+	sum := sha256.Sum256([]byte(content))
+	sha256Hash := hex.EncodeToString(sum[:])
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(content))
+	require.NoError(t, err)
+
+	// Two entries share the SHA1; only the SHA256 tells them apart
+	for i, entrySHA256 := range []string{strings.Repeat("0", 64), sha256Hash} {
+		_, err := conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (?, 1, ?)`, 100+i, fmt.Sprintf("Disc %d (USA)", i))
+		require.NoError(t, err)
+		_, err = conn.Exec(`
+			INSERT INTO rom_entries (release_id, name, sha1, crc32, sha256, size) VALUES (?, ?, ?, ?, ?, ?)
+		`, 100+i, fmt.Sprintf("Disc %d (USA).bin", i), sha1Hash, crc32Hash, entrySHA256, len(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "disc.bin"), []byte(content), 0644)) // #nosec G306
+
+	matchType := `SELECT m.match_type FROM matches m`
+	var got string
+
+	_, err = NewScannerWithConfig(conn, ScanConfig{}).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	require.NoError(t, conn.QueryRow(matchType).Scan(&got))
+	assert.Equal(t, "sha1", got)
+	assert.Equal(t, 1, countRows(t, database, `SELECT COUNT(*) FROM scanned_files WHERE sha256 = ''`))
+
+	// Enabling SHA256 rehashes the cached file and matches the right release
+	for _, parallel := range []bool{true, false} {
+		scanner := NewScannerWithConfig(conn, ScanConfig{SHA256: true, Parallel: parallel, Workers: 2, BatchSize: 10})
+		_, err = scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+		require.NoError(t, conn.QueryRow(matchType).Scan(&got))
+		assert.Equal(t, "sha256", got)
+		assert.Equal(t, 1, countRows(t, database, `
+			SELECT COUNT(*) FROM matches m JOIN rom_entries re ON re.id = m.rom_entry_id WHERE re.release_id = 101
+		`))
+		assert.Equal(t, 1, countRows(t, database, fmt.Sprintf(`SELECT COUNT(*) FROM scanned_files WHERE sha256 = '%s'`, sha256Hash)))
+	}
+}
+
+func TestScanner_RescanKeepsManualMatches(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 2)
+	conn := database.Conn()
+
+	scanner := NewScanner(conn)
+	_, err := scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	_, err = scanner.ReassignMatch(ctx, "test-lib", "game00.nes", "Game 01 (USA)")
+	require.NoError(t, err)
+
+	matches := func() map[string]string {
+		t.Helper()
+		rows, err := conn.Query(`
+			SELECT sf.path, r.name, m.match_type FROM matches m
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			JOIN releases r ON r.id = re.release_id`)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		got := make(map[string]string)
+		for rows.Next() {
+			var path, release, matchType string
+			require.NoError(t, rows.Scan(&path, &release, &matchType))
+			got[filepath.Base(path)] = release + " " + matchType
+		}
+		return got
+	}
+
+	// Clearing a batch leaves the manual match
+	var files []fileToMatch
+	for _, name := range []string{"game00.nes", "game01.nes"} {
+		f := fileToMatch{}
+		require.NoError(t, conn.QueryRow(`SELECT id FROM scanned_files WHERE path = ?`, filepath.Join(libPath, name)).Scan(&f.id))
+		files = append(files, f)
+	}
+	require.NoError(t, scanner.clearMatches(files))
+	assert.Equal(t, map[string]string{"game00.nes": "Game 01 (USA) manual"}, matches())
+
+	// A DAT moving game01's hash to another release refreshes its auto match
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (10, 1, 'Game 01 (Europe)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size)
+		SELECT 10, 'Game 01 (Europe).nes', sha1, crc32, size FROM rom_entries WHERE release_id = 2`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE rom_entries SET sha1 = 'ffff', crc32 = 'ffff' WHERE release_id = 2`)
+	require.NoError(t, err)
+
+	cfg := DefaultScanConfig()
+	cfg.ChangedOnly = false
+	_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"game00.nes": "Game 01 (USA) manual",
+		"game01.nes": "Game 01 (Europe) sha1",
+	}, matches())
+}
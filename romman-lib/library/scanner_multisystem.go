@@ -0,0 +1,271 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/dat"
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// systemResolver picks the system each file of a library is matched against.
+// Single-system libraries always use the library's system; multi-system
+// libraries detect it from the file's top-level subdirectory.
+type systemResolver struct {
+	db      *sql.DB
+	lib     *Library
+	dirs    map[string]int64 // top-level directory -> system ID
+	indexes map[int64]map[string][]releaseNameEntry
+	fuzzy   map[int64]fuzzyNameIndex
+	scanner *Scanner
+}
+
+// newSystemResolver creates a resolver for lib. The scanner may be nil when
+// only system IDs are needed.
+func newSystemResolver(db *sql.DB, lib *Library, scanner *Scanner) *systemResolver {
+	return &systemResolver{
+		db:      db,
+		lib:     lib,
+		dirs:    make(map[string]int64),
+		indexes: make(map[int64]map[string][]releaseNameEntry),
+		fuzzy:   make(map[int64]fuzzyNameIndex),
+		scanner: scanner,
+	}
+}
+
+// systemFor returns the system ID for a scanned file path. Files at the
+// library root, in unrecognised directories or in directories whose system
+// has no imported DAT fall back to the library's system.
+func (r *systemResolver) systemFor(path string) (int64, error) {
+	if !r.lib.MultiSystem {
+		return r.lib.SystemID, nil
+	}
+
+	dir := topLevelDir(r.lib.RootPath, path)
+	if dir == "" {
+		return r.lib.SystemID, nil
+	}
+	if id, ok := r.dirs[dir]; ok {
+		return id, nil
+	}
+
+	id := r.lib.SystemID
+	if name, ok := dat.DetectSystemFromDirName(dir); ok {
+		var found int64
+		err := r.db.QueryRow("SELECT id FROM systems WHERE name = ?", name).Scan(&found)
+		switch {
+		case err == nil:
+			id = found
+		case err != sql.ErrNoRows:
+			return 0, err
+		}
+	}
+
+	r.dirs[dir] = id
+	return id, nil
+}
+
+// releaseNames returns the release name index for a system, building it on first use.
+func (r *systemResolver) releaseNames(systemID int64) (map[string][]releaseNameEntry, error) {
+	if index, ok := r.indexes[systemID]; ok {
+		return index, nil
+	}
+	index, err := r.scanner.buildReleaseNameIndex(systemID)
+	if err != nil {
+		return nil, err
+	}
+	r.indexes[systemID] = index
+	return index, nil
+}
+
+// matchFile matches f against the entries of its resolved system, falling
+// back to fuzzy name matching when it is enabled.
+func (r *systemResolver) matchFile(f fileToMatch) (bool, error) {
+	systemID, err := r.systemFor(f.path)
+	if err != nil {
+		return false, err
+	}
+	index, err := r.releaseNames(systemID)
+	if err != nil {
+		return false, err
+	}
+	matched, err := r.scanner.matchSingleFile(systemID, f, index)
+	if err != nil || matched || !r.scanner.config.Fuzzy {
+		return matched, err
+	}
+
+	fuzzy, ok := r.fuzzy[systemID]
+	if !ok {
+		fuzzy = buildFuzzyNameIndex(index)
+		r.fuzzy[systemID] = fuzzy
+	}
+	return r.scanner.matchFuzzyName(f, fuzzy)
+}
+
+// topLevelDir returns the first directory component of path below root, or
+// "" for files directly in root. Relative paths, such as those of imported
+// hash lists, are taken as already relative to the library.
+func topLevelDir(root, path string) string {
+	rel := path
+	if filepath.IsAbs(path) {
+		var err error
+		rel, err = filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+	}
+
+	rel = filepath.ToSlash(rel)
+	i := strings.Index(rel, "/")
+	if i <= 0 {
+		return ""
+	}
+	return rel[:i]
+}
+
+// librarySystemIDs returns the systems whose releases make up a library's
+// status: the library's own system plus, for multi-system libraries, every
+// system resolved from its scanned files.
+func librarySystemIDs(ctx context.Context, db *sql.DB, lib *Library) ([]int64, error) {
+	if !lib.MultiSystem {
+		return []int64{lib.SystemID}, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT path FROM scanned_files WHERE library_id = ?`, lib.ID)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resolver := newSystemResolver(db, lib, nil)
+	seen := map[int64]bool{lib.SystemID: true}
+	ids := []int64{lib.SystemID}
+	for _, path := range paths {
+		id, err := resolver.systemFor(path)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SystemStatus summarises a library's files and releases for one system.
+type SystemStatus struct {
+	System       string `json:"system"`
+	TotalFiles   int    `json:"totalFiles"`
+	MatchedFiles int    `json:"matchedFiles"`
+	Releases     int    `json:"releases"`
+	Present      int    `json:"present"`
+	Partial      int    `json:"partial"`
+	Missing      int    `json:"missing"`
+	Unverified   int    `json:"unverified,omitempty"`
+}
+
+// GetSystemBreakdown reports file and release counts per system, sorted by
+// system name. Single-system libraries return one entry.
+func (s *Scanner) GetSystemBreakdown(ctx context.Context, libraryName string, opts StatusOptions) ([]SystemStatus, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetSystemBreakdown")
+	defer span.End()
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := s.GetLibraryStatusWithOptions(ctx, libraryName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bySystem := make(map[string]*SystemStatus)
+	get := func(name string) *SystemStatus {
+		st, ok := bySystem[name]
+		if !ok {
+			st = &SystemStatus{System: name}
+			bySystem[name] = st
+		}
+		return st
+	}
+
+	for _, rs := range statuses {
+		st := get(rs.SystemName)
+		st.Releases++
+		switch rs.Status {
+		case "present":
+			st.Present++
+		case "partial":
+			st.Partial++
+		case "missing":
+			st.Missing++
+		case "unverified":
+			st.Unverified++
+		}
+	}
+
+	// Files are attributed to the system they resolve to, matched or not
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sf.path, EXISTS (SELECT 1 FROM matches m WHERE m.scanned_file_id = sf.id)
+		FROM scanned_files sf
+		WHERE sf.library_id = ?
+	`, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "get system breakdown")
+	}
+	defer func() { _ = rows.Close() }()
+
+	resolver := newSystemResolver(s.db, lib, nil)
+	names := make(map[int64]string)
+	for rows.Next() {
+		var path string
+		var matched bool
+		if err := rows.Scan(&path, &matched); err != nil {
+			return nil, err
+		}
+		id, err := resolver.systemFor(path)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := names[id]
+		if !ok {
+			if err := s.db.QueryRowContext(ctx, "SELECT name FROM systems WHERE id = ?", id).Scan(&name); err != nil {
+				return nil, WrapDBError(err, "get system name")
+			}
+			names[id] = name
+		}
+		st := get(name)
+		st.TotalFiles++
+		if matched {
+			st.MatchedFiles++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	breakdown := make([]SystemStatus, 0, len(bySystem))
+	for _, st := range bySystem {
+		breakdown = append(breakdown, *st)
+	}
+	sort.Slice(breakdown, func(i, j int) bool { return breakdown[i].System < breakdown[j].System })
+	return breakdown, nil
+}
@@ -0,0 +1,103 @@
+package library
+
+import (
+	"context"
+	"crypto/sha1" // #nosec G505 - matches the DAT hash format
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestMultiSystemLibrary(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"nes/Mario.nes":  "nes rom",
+		"snes/Zelda.sfc": "snes rom",
+		"snes/Other.sfc": "not a rom",
+		"loose.nes":      "loose rom",
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	sha := func(s string) string {
+		sum := sha1.Sum([]byte(s)) // #nosec G401
+		return hex.EncodeToString(sum[:])
+	}
+
+	stmts := []string{
+		`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes')`,
+		`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Mario'), (2, 2, 'Zelda'), (3, 2, 'Metroid'), (4, 1, 'Loose')`,
+	}
+	for _, s := range stmts {
+		_, err := conn.Exec(s)
+		require.NoError(t, err)
+	}
+	_, err = conn.Exec(`INSERT INTO rom_entries (release_id, name, sha1) VALUES (1, 'Mario.nes', ?), (2, 'Zelda.sfc', ?), (3, 'Metroid.sfc', ?), (4, 'Loose.nes', ?)`,
+		sha("nes rom"), sha("snes rom"), sha("other"), sha("loose rom"))
+	require.NoError(t, err)
+
+	manager := NewManager(conn)
+	_, err = manager.Add(ctx, "mixed", root, "nes")
+	require.NoError(t, err)
+
+	// Without the flag everything is matched against nes only
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "mixed")
+	require.NoError(t, err)
+	statuses, err := scanner.GetLibraryStatus(ctx, "mixed")
+	require.NoError(t, err)
+	assert.Len(t, statuses, 2)
+
+	require.NoError(t, manager.SetMultiSystem(ctx, "mixed", true))
+	lib, err := manager.Get(ctx, "mixed")
+	require.NoError(t, err)
+	assert.True(t, lib.MultiSystem)
+
+	result, err := scanner.Scan(ctx, "mixed")
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.MatchesFound)
+
+	statuses, err = scanner.GetLibraryStatus(ctx, "mixed")
+	require.NoError(t, err)
+	require.Len(t, statuses, 4)
+	got := make(map[string]string)
+	for _, s := range statuses {
+		got[s.ReleaseName] = s.SystemName + ":" + s.Status
+	}
+	assert.Equal(t, map[string]string{
+		"Loose":   "nes:present",
+		"Mario":   "nes:present",
+		"Metroid": "snes:missing",
+		"Zelda":   "snes:present",
+	}, got)
+
+	breakdown, err := scanner.GetSystemBreakdown(ctx, "mixed", StatusOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []SystemStatus{
+		{System: "nes", TotalFiles: 2, MatchedFiles: 2, Releases: 2, Present: 2},
+		{System: "snes", TotalFiles: 2, MatchedFiles: 1, Releases: 2, Present: 1, Missing: 1},
+	}, breakdown)
+
+	assert.ErrorContains(t, manager.SetMultiSystem(ctx, "nope", true), "library not found")
+}
+
+func TestTopLevelDir(t *testing.T) {
+	assert.Equal(t, "snes", topLevelDir("/roms", "/roms/snes/a/b.sfc"))
+	assert.Equal(t, "", topLevelDir("/roms", "/roms/a.sfc"))
+	assert.Equal(t, "", topLevelDir("/roms", "/other/snes/a.sfc"))
+	assert.Equal(t, "gb", topLevelDir("/roms", "gb/a.gb"))
+}
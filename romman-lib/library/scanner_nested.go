@@ -0,0 +1,296 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// nestedSep separates the archives in a nested entry's archive_path, as in
+// "inner.zip!game.nes" for game.nes inside inner.zip inside the scanned file.
+const nestedSep = "!"
+
+// maxNestedDepth is how many archives deep a scan looks inside an archive.
+// Deeper archives are hashed as plain files rather than opened, which also
+// bounds the damage a crafted archive can do.
+const maxNestedDepth = 2
+
+// maxNestedSize is the largest nested archive read into memory to be opened.
+const maxNestedSize = 512 << 20
+
+// archiveMember is a file inside an archive, possibly several archives deep.
+type archiveMember struct {
+	name  string // Path within the outermost archive, joined with nestedSep
+	size  int64
+	crc32 string // CRC32 declared in the entry header
+	open  func() (io.ReadCloser, error)
+}
+
+// isArchiveName reports whether an archive entry is itself a zip or 7z archive.
+func isArchiveName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".zip" || ext == ".7z"
+}
+
+// isNestedEntry reports whether an archive_path names a file inside a nested archive.
+func isNestedEntry(entryName string) bool {
+	return len(splitNested(entryName)) > 1
+}
+
+// splitNested splits a nested entry's archive_path into the entry names at
+// each level. Only a separator following an archive name splits, so entry
+// names that contain one are kept whole.
+func splitNested(entryName string) []string {
+	var parts []string
+	var current string
+	for _, part := range strings.Split(entryName, nestedSep) {
+		if current != "" && isArchiveName(current) {
+			parts = append(parts, current)
+			current = part
+			continue
+		}
+		if current != "" {
+			current += nestedSep
+		}
+		current += part
+	}
+	return append(parts, current)
+}
+
+// walkNested calls fn for every file inside the nested archive m, which is
+// depth archives deep, descending into the archives within it. An archive
+// past maxNestedDepth or maxNestedSize is passed to fn as a plain file.
+func walkNested(m archiveMember, depth int, fn func(archiveMember) error) error {
+	if depth > maxNestedDepth || m.size > maxNestedSize {
+		slog.Warn("nested archive limit reached; hashing it as a file", "entry", m.name,
+			"depth", depth, "size", m.size)
+		return fn(m)
+	}
+
+	data, err := readNested(m.open)
+	if err != nil {
+		return fmt.Errorf("failed to read nested archive %s: %w", m.name, err)
+	}
+	members, err := listArchive(m.name, data)
+	if err != nil {
+		return fmt.Errorf("failed to open nested archive %s: %w", m.name, err)
+	}
+
+	for _, member := range members {
+		member.name = m.name + nestedSep + member.name
+		if isArchiveName(member.name) {
+			if err := walkNested(member, depth+1, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readNested reads an archive entry into memory so it can be opened as an
+// archive itself.
+func readNested(open func() (io.ReadCloser, error)) (*bytes.Reader, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(checksumTolerantReader{rc}, maxNestedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxNestedSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxNestedSize)
+	}
+	return bytes.NewReader(data), nil
+}
+
+// listArchive lists the files of a zip or 7z archive held in memory, picking
+// the format by name.
+func listArchive(name string, data *bytes.Reader) ([]archiveMember, error) {
+	var members []archiveMember
+	if is7z(name) {
+		r, err := sevenzip.NewReader(data, data.Size())
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			members = append(members, archiveMember{
+				name:  f.Name,
+				size:  int64(f.UncompressedSize), // #nosec G115 - safe cast for ROM sizes
+				crc32: sevenZipHeaderCRC32(f),
+				open:  f.Open,
+			})
+		}
+		return members, nil
+	}
+
+	r, err := zip.NewReader(data, data.Size())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		members = append(members, archiveMember{
+			name:  f.Name,
+			size:  int64(f.UncompressedSize64), // #nosec G115 - safe cast for ROM sizes
+			crc32: zipHeaderCRC32(f),
+			open:  f.Open,
+		})
+	}
+	return members, nil
+}
+
+// openNestedEntry opens the file that names leads to, starting inside the
+// archive entry outer, which is consumed.
+func openNestedEntry(outer io.ReadCloser, outerName string, names []string) (io.ReadCloser, error) {
+	data, err := readNested(func() (io.ReadCloser, error) { return outer, nil })
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nested archive %s: %w", outerName, err)
+	}
+	members, err := listArchive(outerName, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open nested archive %s: %w", outerName, err)
+	}
+
+	for _, m := range members {
+		if m.name != names[0] {
+			continue
+		}
+		rc, err := m.open()
+		if err != nil || len(names) == 1 {
+			return rc, err
+		}
+		return openNestedEntry(rc, m.name, names[1:])
+	}
+	return nil, fmt.Errorf("entry %s not found in %s", names[0], outerName)
+}
+
+// hashNestedEntry computes hashes for a file inside a nested archive.
+func hashNestedEntry(archivePath, entryName string, extra ...io.Writer) (string, string, string, error) {
+	rc, err := openArchiveEntry(archivePath, entryName)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer func() { _ = rc.Close() }()
+	return computeHashes(checksumTolerantReader{rc}, extra...)
+}
+
+// queueNested hashes the files inside the nested archive m of the archive at
+// path and sends their results to the collector, marking them as entries of
+// a zip or 7z archive like its own entries. They are hashed here while the
+// nested archive is in memory, since a worker would read it again per file.
+func (s *Scanner) queueNested(libraryID int64, path string, m archiveMember, mtime int64, send func(hashResult) error) error {
+	return walkNested(m, 1, func(member archiveMember) error {
+		job := nestedJob(path, member, mtime)
+		job.seq = s.dirs.next()
+		return send(s.hashArchiveMember(libraryID, job, member))
+	})
+}
+
+// nestedJob describes a file inside a nested archive of the archive at path.
+func nestedJob(path string, m archiveMember, mtime int64) fileJob {
+	return fileJob{
+		path:        path,
+		archivePath: m.name,
+		size:        m.size,
+		mtime:       mtime,
+		isZipEntry:  !is7z(path),
+		is7zEntry:   is7z(path),
+		zipPath:     path,
+		zipCRC32:    m.crc32,
+	}
+}
+
+// scanNested scans the files inside the nested archive m of the archive at
+// path for the sequential scanner, adding them to result.
+func (s *Scanner) scanNested(lib *Library, path string, m archiveMember, mtime int64, cp *checkpointer, result *ScanResult) error {
+	var cpErr error
+	err := walkNested(m, 1, func(member archiveMember) error {
+		scanned, hashed, err := s.scanArchiveMember(lib, path, member, mtime)
+		if err != nil {
+			s.errs.add("failed to scan nested archive entry", path, member.name, err)
+			return nil
+		}
+		if err := cp.add(hashResult{job: fileJob{path: path, archivePath: member.name}, wasHashed: hashed}); err != nil {
+			cpErr = err
+			return err
+		}
+
+		result.FilesScanned++
+		if hashed {
+			result.FilesHashed++
+		} else if scanned {
+			result.FilesSkipped++
+		}
+		return nil
+	})
+	if cpErr != nil {
+		return cpErr
+	}
+	if err != nil {
+		s.errs.add("failed to scan nested archive", path, m.name, err)
+	}
+	return nil
+}
+
+// scanArchiveMember hashes and stores one file inside a nested archive.
+func (s *Scanner) scanArchiveMember(lib *Library, path string, m archiveMember, mtime int64) (scanned, hashed bool, err error) {
+	r := s.hashArchiveMember(lib.ID, nestedJob(path, m, mtime), m)
+	if r.err != nil {
+		return false, false, r.err
+	}
+	if !r.wasHashed {
+		return true, false, nil
+	}
+	if err := s.storeScannedFile(lib.ID, path, m.name, m.size, mtime, r.sha1, r.crc32, r.md5, r.sha256, m.crc32, r.headerless); err != nil {
+		return false, false, fmt.Errorf("failed to store scanned file: %w", err)
+	}
+	return true, true, nil
+}
+
+// hashArchiveMember hashes one file inside a nested archive through the
+// open of its in-memory archive, or takes its hashes from the cache.
+func (s *Scanner) hashArchiveMember(libraryID int64, job fileJob, m archiveMember) hashResult {
+	cached, err := s.getCachedFile(libraryID, job.path, job.archivePath, job.size, job.mtime)
+	if err != nil {
+		return hashResult{job: job, err: err}
+	}
+	if cached != nil {
+		s.verifier.check(s, job, cached)
+		return hashResult{job: job, sha1: cached.SHA1, crc32: cached.CRC32, md5: cached.MD5, sha256: cached.SHA256}
+	}
+
+	rc, err := m.open()
+	if err != nil {
+		return hashResult{job: job, err: fmt.Errorf("failed to open nested entry: %w", err)}
+	}
+	sha256Hasher := s.sha256Hasher()
+	sha1Hash, crc32Hash, md5Hash, err := computeHashes(checksumTolerantReader{rc}, hashWriters(sha256Hasher)...)
+	_ = rc.Close()
+	if err != nil {
+		return hashResult{job: job, err: fmt.Errorf("failed to hash nested entry: %w", err)}
+	}
+	headerless, err := s.hashHeaderless(m.name, m.open)
+	if err != nil {
+		return hashResult{job: job, err: fmt.Errorf("failed to hash nested entry: %w", err)}
+	}
+	return hashResult{job: job, sha1: sha1Hash, crc32: crc32Hash, md5: md5Hash, sha256: hashHex(sha256Hasher),
+		headerless: headerless, wasHashed: true}
+}
@@ -0,0 +1,143 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zipBytes returns a zip archive holding the given entries, in order.
+func zipBytes(t *testing.T, entries ...sevenZipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		fw, err := w.Create(e.name)
+		require.NoError(t, err)
+		_, err = fw.Write(e.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// setupNestedLibrary builds a library holding one per-system zip with
+// archives nested inside it, and returns the zip's path.
+func setupNestedLibrary(t *testing.T) (*Scanner, string, func(ScanConfig) *Scanner) {
+	t.Helper()
+	database, libPath := setupCheckpointLibrary(t, 4)
+	rom := func(i int) sevenZipEntry {
+		name := fmt.Sprintf("game%02d.nes", i)
+		require.NoError(t, os.Remove(filepath.Join(libPath, name)))
+		return sevenZipEntry{name: name, data: []byte(fmt.Sprintf("rom content %d", i))}
+	}
+
+	inner7z := filepath.Join(t.TempDir(), "inner.7z")
+	writeStored7z(t, inner7z, []sevenZipEntry{rom(3)})
+	data7z, err := os.ReadFile(inner7z) // #nosec G304
+	require.NoError(t, err)
+
+	// game01 sits three archives deep, past the depth limit
+	bottom := zipBytes(t, rom(1))
+	mid := zipBytes(t, sevenZipEntry{name: "bottom.zip", data: bottom})
+	deep := zipBytes(t, sevenZipEntry{name: "mid.zip", data: mid})
+
+	outer := filepath.Join(libPath, "nes.zip")
+	require.NoError(t, os.WriteFile(outer, zipBytes(t, // #nosec G306
+		sevenZipEntry{name: "inner.zip", data: zipBytes(t, rom(0))},
+		sevenZipEntry{name: "deep.zip", data: deep},
+		rom(2),
+		sevenZipEntry{name: "inner.7z", data: data7z},
+	), 0644))
+
+	newScanner := func(cfg ScanConfig) *Scanner {
+		return NewScannerWithConfig(database.Conn(), cfg)
+	}
+	return newScanner(ScanConfig{Workers: 1, BatchSize: 10}), outer, newScanner
+}
+
+func TestScan_NestedArchives(t *testing.T) {
+	for name, cfg := range map[string]ScanConfig{
+		"sequential": {Workers: 1, BatchSize: 10, Parallel: false},
+		"parallel":   {Workers: 4, BatchSize: 10, Parallel: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, outer, newScanner := setupNestedLibrary(t)
+			scanner := newScanner(cfg)
+
+			result, err := scanner.Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 4, result.FilesHashed)
+			assert.Equal(t, 3, result.MatchesFound)
+
+			rows, err := scanner.db.Query(`
+				SELECT path, archive_path, zip_crc32 = crc32 FROM scanned_files ORDER BY archive_path
+			`)
+			require.NoError(t, err)
+			defer func() { _ = rows.Close() }()
+			var entries []string
+			for rows.Next() {
+				var path, entry string
+				var headerMatches bool
+				require.NoError(t, rows.Scan(&path, &entry, &headerMatches))
+				assert.Equal(t, outer, path)
+				assert.True(t, headerMatches, "header CRC should be recorded for %s", entry)
+				entries = append(entries, entry)
+			}
+			assert.Equal(t, []string{
+				"deep.zip!mid.zip!bottom.zip",
+				"game02.nes",
+				"inner.7z!game03.nes",
+				"inner.zip!game00.nes",
+			}, entries)
+
+			unmatched, err := scanner.GetUnmatchedFiles(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, []string{outer + ":deep.zip!mid.zip!bottom.zip"}, unmatched)
+
+			// Nested entries are cached by the outer archive's mtime
+			result, err = scanner.Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.FilesHashed)
+			assert.Equal(t, 4, result.FilesSkipped)
+		})
+	}
+}
+
+func TestHashArchiveEntry_Nested(t *testing.T) {
+	scanner, outer, _ := setupNestedLibrary(t)
+
+	for entry, content := range map[string]string{
+		"inner.zip!game00.nes": "rom content 0",
+		"inner.7z!game03.nes":  "rom content 3",
+		"game02.nes":           "rom content 2",
+	} {
+		want, _, _, err := computeHashes(strings.NewReader(content))
+		require.NoError(t, err)
+		got, _, _, err := scanner.hashArchiveEntry(outer, entry)
+		require.NoError(t, err, entry)
+		assert.Equal(t, want, got, entry)
+	}
+
+	_, _, _, err := scanner.hashArchiveEntry(outer, "inner.zip!missing.nes")
+	assert.Error(t, err)
+}
+
+func TestSplitNested(t *testing.T) {
+	assert.Equal(t, []string{"game.nes"}, splitNested("game.nes"))
+	assert.Equal(t, []string{"inner.zip", "game.nes"}, splitNested("inner.zip!game.nes"))
+	assert.Equal(t, []string{"a.zip", "b.7z", "game.nes"}, splitNested("a.zip!b.7z!game.nes"))
+	// A separator that does not follow an archive name is part of the name
+	assert.Equal(t, []string{"Wow!.nes"}, splitNested("Wow!.nes"))
+	assert.Equal(t, []string{"inner.zip", "Wow!.nes"}, splitNested("inner.zip!Wow!.nes"))
+	assert.False(t, isNestedEntry("Wow!.nes"))
+	assert.True(t, isNestedEntry("inner.zip!Wow!.nes"))
+}
@@ -0,0 +1,108 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_PruneIgnoredExtensions(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 2)
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "game00.bak"), []byte("backup"), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "game01.ips"), []byte("patch"), 0644))  // #nosec G306
+
+	result, err := NewScanner(database.Conn()).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.FilesScanned)
+	assert.Equal(t, 0, result.FilesPruned)
+
+	countFiles := func() int {
+		var n int
+		require.NoError(t, database.Conn().QueryRow(`SELECT COUNT(*) FROM scanned_files`).Scan(&n))
+		return n
+	}
+	require.Equal(t, 4, countFiles())
+
+	// The ignore list grows: Prune drops entries without scanning, existing or not
+	require.NoError(t, os.Remove(filepath.Join(libPath, "game01.ips")))
+	cfg := ScanConfig{IgnoreExtensions: []string{".BAK", "ips"}}
+	pruned, err := NewScannerWithConfig(database.Conn(), cfg).Prune(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+	assert.Equal(t, 2, countFiles())
+
+	// A scan prunes too and no longer picks the ignored files up
+	_, err = database.Conn().Exec(`
+		INSERT INTO scanned_files (library_id, path, size, mtime, sha1, crc32)
+		SELECT id, ?, 6, 0, 'x', 'x' FROM libraries WHERE name = 'test-lib'
+	`, filepath.Join(libPath, "game00.bak"))
+	require.NoError(t, err)
+	result, err = NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.FilesPruned)
+	assert.Equal(t, 2, result.FilesScanned)
+	assert.Equal(t, 2, countFiles())
+}
+
+func TestScanner_LibraryFilters(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 1)
+	ctx := context.Background()
+	require.NoError(t, os.MkdirAll(filepath.Join(libPath, "Saves"), 0755))                                     // #nosec G301
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "Saves", "game00.nes"), []byte("save data"), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "manual.txt"), []byte("manual"), 0644))             // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "game00.BAK"), []byte("backup"), 0644))             // #nosec G306
+
+	scannedPaths := func() []string {
+		rows, err := database.Conn().Query(`SELECT path FROM scanned_files ORDER BY path`)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var paths []string
+		for rows.Next() {
+			var path string
+			require.NoError(t, rows.Scan(&path))
+			rel, err := filepath.Rel(libPath, path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return paths
+	}
+
+	// Without a filter for the library, the built-in defaults apply
+	cfg := ScanConfig{LibraryFilters: map[string]LibraryFilter{
+		"other-lib": {IncludeExtensions: []string{".txt"}},
+	}}
+	_, err := NewScannerWithConfig(database.Conn(), cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Saves/game00.nes", "game00.BAK", "game00.nes"}, scannedPaths())
+
+	// The library's filter skips saves by path and backups by name, and
+	// scans manuals the defaults ignore
+	cfg.LibraryFilters["test-lib"] = LibraryFilter{
+		IgnorePatterns:    []string{"saves/*", "*.bak"},
+		IncludeExtensions: []string{"TXT"},
+	}
+	scanner := NewScannerWithConfig(database.Conn(), cfg)
+	result, err := scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.FilesPruned)
+	assert.Equal(t, []string{"game00.nes", "manual.txt"}, scannedPaths())
+
+	lib, err := NewManager(database.Conn()).Get(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.True(t, scanner.IsIgnored(lib, filepath.Join(libPath, "Saves", "game01.nes")))
+	assert.False(t, scanner.IsIgnored(lib, filepath.Join(libPath, "game01.nes")))
+
+	cfg.LibraryFilters["test-lib"] = LibraryFilter{IgnorePatterns: []string{"[saves"}}
+	_, err = NewScannerWithConfig(database.Conn(), cfg).Scan(ctx, "test-lib")
+	assert.ErrorIs(t, err, ErrInvalidArg)
+}
+
+func TestScanner_PruneUnknownLibrary(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 0)
+	_, err := NewScanner(database.Conn()).Prune(context.Background(), "missing")
+	assert.Error(t, err)
+}
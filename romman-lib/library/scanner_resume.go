@@ -0,0 +1,145 @@
+package library
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// dirTracker follows a scan's walk to find the last directory whose files
+// have all been committed. A directory is left once the walk moves past it,
+// but in parallel scans its files may still be hashing, so it only counts as
+// committed once every file handed out before it was left has been.
+type dirTracker struct {
+	mu        sync.Mutex
+	open      []string       // Directories being walked, outermost first
+	left      []leftDir      // Directories walked past, waiting for their files
+	sent      int64          // Files handed out so far
+	done      map[int64]bool // Committed files above the watermark
+	watermark int64          // Every file up to this sequence number is committed
+}
+
+// leftDir is a directory the walk has left, with the number of files handed
+// out by then.
+type leftDir struct {
+	path string
+	seq  int64
+}
+
+func newDirTracker() *dirTracker {
+	return &dirTracker{done: make(map[int64]bool)}
+}
+
+// visit records that the walk reached path, leaving every open directory
+// that does not contain it.
+func (t *dirTracker) visit(path string, isDir bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.open) > 0 && !isWithin(path, t.open[len(t.open)-1]) {
+		t.left = append(t.left, leftDir{path: t.open[len(t.open)-1], seq: t.sent})
+		t.open = t.open[:len(t.open)-1]
+	}
+	if isDir {
+		t.open = append(t.open, path)
+	}
+}
+
+// next returns the sequence number of the next file handed out.
+func (t *dirTracker) next() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent++
+	return t.sent
+}
+
+// commit marks files as committed, or as given up on after an error, and
+// returns the last directory all of whose files are now committed, or ""
+// if that has not changed.
+func (t *dirTracker) commit(seqs ...int64) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, seq := range seqs {
+		if seq > t.watermark {
+			t.done[seq] = true
+		}
+	}
+	for t.done[t.watermark+1] {
+		delete(t.done, t.watermark+1)
+		t.watermark++
+	}
+
+	var dir string
+	for len(t.left) > 0 && t.left[0].seq <= t.watermark {
+		dir = t.left[0].path
+		t.left = t.left[1:]
+	}
+	return dir
+}
+
+// isWithin reports whether path is dir or lies below it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resumeFilter skips the directories an interrupted scan had fully walked
+// and committed, for ScanConfig.Resume. filepath.Walk visits entries in
+// lexical order, so those are the last recorded directory and every one
+// walked before it, other than its ancestors.
+type resumeFilter struct {
+	root    string
+	lastDir []string // Path components below root
+}
+
+// newResumeFilter returns a filter for the library's interrupted scan, or
+// nil when not resuming or there is nothing to skip.
+func (s *Scanner) newResumeFilter(lib *Library, state *ScanState) *resumeFilter {
+	if !s.config.Resume || state == nil || !state.Interrupted() || state.LastDir == "" {
+		return nil
+	}
+	parts, ok := relativeParts(lib.RootPath, state.LastDir)
+	if !ok {
+		return nil
+	}
+	slog.Debug("resuming interrupted scan", "after", state.LastDir)
+	return &resumeFilter{root: lib.RootPath, lastDir: parts}
+}
+
+// skip reports whether the interrupted scan committed the whole of dir.
+func (f *resumeFilter) skip(dir string) bool {
+	if f == nil {
+		return false
+	}
+	parts, ok := relativeParts(f.root, dir)
+	if !ok {
+		return false
+	}
+	for i, part := range parts {
+		if i == len(f.lastDir) {
+			return true // Below the last directory
+		}
+		if part != f.lastDir[i] {
+			return part < f.lastDir[i]
+		}
+	}
+	// dir is the last directory itself or one of its ancestors
+	return len(parts) == len(f.lastDir)
+}
+
+// covers reports whether a file lies in a directory skipped by the filter.
+func (f *resumeFilter) covers(path string) bool {
+	return f.skip(filepath.Dir(path))
+}
+
+// relativeParts splits path into its components below root. It fails for
+// root itself and for paths outside it.
+func relativeParts(root, path string) ([]string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || !isWithin(path, root) {
+		return nil, false
+	}
+	return strings.Split(filepath.ToSlash(rel), "/"), true
+}
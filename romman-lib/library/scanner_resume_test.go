@@ -0,0 +1,108 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirTracker_WaitsForEarlierFiles(t *testing.T) {
+	tr := newDirTracker()
+	tr.visit("/roms", true)
+	tr.visit("/roms/a", true)
+	a1, a2 := tr.next(), tr.next()
+	tr.visit("/roms/b", true) // Leaves /roms/a
+	b1 := tr.next()
+	tr.visit("/roms/c", true) // Leaves /roms/b
+
+	// /roms/a's files finish out of order; nothing counts until both are in
+	assert.Equal(t, "", tr.commit(b1))
+	assert.Equal(t, "", tr.commit(a2))
+	assert.Equal(t, "/roms/b", tr.commit(a1))
+	assert.Equal(t, "", tr.commit())
+}
+
+func TestResumeFilter_Skip(t *testing.T) {
+	root := filepath.FromSlash("/roms")
+	f := &resumeFilter{root: root, lastDir: []string{"b", "m"}}
+	for dir, want := range map[string]bool{
+		"/roms":       false, // Root
+		"/roms/a":     true,  // Walked before the last directory
+		"/roms/a/z":   true,
+		"/roms/b":     false, // Ancestor, still being walked
+		"/roms/b/c":   true,
+		"/roms/b/m":   true, // The last directory itself
+		"/roms/b/m/x": true,
+		"/roms/b/n":   false, // Not reached yet
+		"/roms/c":     false,
+		"/other/a":    false,
+	} {
+		assert.Equal(t, want, f.skip(filepath.FromSlash(dir)), dir)
+	}
+	assert.True(t, f.covers(filepath.FromSlash("/roms/a/game.nes")))
+	assert.False(t, f.covers(filepath.FromSlash("/roms/game.nes")))
+
+	var none *resumeFilter
+	assert.False(t, none.skip(filepath.FromSlash("/roms/a")))
+}
+
+func TestScanner_ResumeSkipsCommittedDirectories(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 0)
+	for i := 0; i < 12; i++ {
+		dir := filepath.Join(libPath, fmt.Sprintf("d%d", i/3))
+		require.NoError(t, os.MkdirAll(dir, 0755)) // #nosec G301
+		writeCheckpointROM(t, database, dir, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanner := NewScannerWithConfig(database.Conn(), ScanConfig{
+		BatchSize: 1,
+		OnProgress: func(p ScanProgress) {
+			if p.FilesScanned >= 7 {
+				cancel()
+			}
+		},
+	})
+	_, err := scanner.Scan(ctx, "test-lib")
+	require.ErrorIs(t, err, context.Canceled)
+
+	state, err := scanner.GetScanState(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.True(t, state.Interrupted())
+	assert.Equal(t, filepath.Join(libPath, "d1"), state.LastDir)
+
+	// A file added to a committed directory is not seen by the resumed walk
+	writeCheckpointROM(t, database, filepath.Join(libPath, "d0"), 12)
+
+	for _, parallel := range []bool{false, true} {
+		_, err = database.Conn().Exec(`UPDATE scan_state SET status = 'running', last_dir = ?`, filepath.Join(libPath, "d1"))
+		require.NoError(t, err)
+
+		scanner = NewScannerWithConfig(database.Conn(), ScanConfig{Resume: true, Parallel: parallel, Workers: 2, BatchSize: 2})
+		result, err := scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.DirsResumed)
+		assert.Equal(t, 6, result.FilesScanned)
+		assert.Equal(t, 12, countRows(t, database, `SELECT COUNT(*) FROM scanned_files`))
+		assert.Equal(t, 12, countRows(t, database, `SELECT COUNT(*) FROM matches`))
+
+		state, err = scanner.GetScanState(context.Background(), "test-lib")
+		require.NoError(t, err)
+		assert.False(t, state.Interrupted())
+		assert.Empty(t, state.LastDir)
+	}
+
+	// Resuming after a completed scan walks everything
+	result, err := NewScannerWithConfig(database.Conn(), ScanConfig{Resume: true}).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.DirsResumed)
+	assert.Equal(t, 13, result.FilesScanned)
+	assert.Equal(t, 1, result.FilesHashed)
+}
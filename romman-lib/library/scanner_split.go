@@ -0,0 +1,229 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// splitFlag marks matches made by combining split ROM parts.
+const splitFlag = "split"
+
+// SplitROMRule describes ROMs stored as sibling part files that must be
+// concatenated before hashing to match a single DAT entry, for example
+// "game-lo.bin" + "game-hi.bin".
+type SplitROMRule struct {
+	Parts []string // Filename suffixes before the extension, in concatenation order
+}
+
+// splitGroup is one set of sibling part files for a rule.
+type splitGroup struct {
+	paths []string
+	ids   []int64
+}
+
+// matchSplitROMs combines unmatched sibling part files according to the
+// rules of their system, resolved per directory in multi-system libraries,
+// hashes the concatenation and matches each part to the combined ROM entry.
+// Returns the number of part files matched.
+func (s *Scanner) matchSplitROMs(ctx context.Context, lib *Library) (int, error) {
+	if len(s.config.SplitROMs) == 0 {
+		return 0, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sf.id, sf.path
+		FROM scanned_files sf
+		LEFT JOIN matches m ON m.scanned_file_id = sf.id
+		WHERE sf.library_id = ? AND m.id IS NULL AND sf.virtual = 0
+		  AND COALESCE(sf.archive_path, '') = ''
+	`, lib.ID)
+	if err != nil {
+		return 0, err
+	}
+	type partFile struct {
+		id   int64
+		path string
+	}
+	var files []partFile
+	for rows.Next() {
+		var f partFile
+		if err := rows.Scan(&f.id, &f.path); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		files = append(files, f)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Siblings share a directory and so a system; group the files by it
+	resolver := newSystemResolver(s.db, lib, s)
+	unmatched := make(map[int64]map[string]int64)
+	for _, f := range files {
+		systemID, err := resolver.systemFor(f.path)
+		if err != nil {
+			return 0, err
+		}
+		if unmatched[systemID] == nil {
+			unmatched[systemID] = make(map[string]int64)
+		}
+		unmatched[systemID][f.path] = f.id
+	}
+
+	matched := 0
+	for systemID, paths := range unmatched {
+		var systemName string
+		if err := s.db.QueryRowContext(ctx, "SELECT name FROM systems WHERE id = ?", systemID).Scan(&systemName); err != nil {
+			return matched, err
+		}
+		n, err := s.matchSplitGroups(ctx, systemID, findSplitGroups(paths, s.config.SplitROMs[systemName]))
+		matched += n
+		if err != nil {
+			return matched, err
+		}
+	}
+
+	return matched, nil
+}
+
+// matchSplitGroups hashes each group of part files and matches its parts to
+// the ROM entry of the system the concatenation hashes to.
+func (s *Scanner) matchSplitGroups(ctx context.Context, systemID int64, groups []splitGroup) (int, error) {
+	matched := 0
+	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return matched, err
+		}
+
+		sha1Hash, crc32Hash, md5Hash, err := hashConcatenated(group.paths)
+		if err != nil {
+			return matched, fmt.Errorf("failed to hash split ROM %s: %w", group.paths[0], err)
+		}
+
+		romEntryID, matchType, err := s.findROMEntryByHash(systemID, sha1Hash, crc32Hash, md5Hash)
+		if err != nil {
+			return matched, err
+		}
+		if romEntryID == 0 {
+			continue
+		}
+
+		for _, id := range group.ids {
+			if _, err := s.insertMatch(id, romEntryID, matchType, splitFlag); err != nil {
+				return matched, err
+			}
+			matched++
+		}
+	}
+
+	return matched, nil
+}
+
+// findSplitGroups finds complete sets of sibling part files among the given paths.
+func findSplitGroups(files map[string]int64, rules []SplitROMRule) []splitGroup {
+	var groups []splitGroup
+	seen := make(map[string]bool)
+
+	for path := range files {
+		ext := filepath.Ext(path)
+		stem := strings.TrimSuffix(path, ext)
+
+		for ri, rule := range rules {
+			if len(rule.Parts) < 2 {
+				continue
+			}
+			for _, part := range rule.Parts {
+				if part == "" || !strings.HasSuffix(stem, part) {
+					continue
+				}
+
+				base := strings.TrimSuffix(stem, part)
+				key := fmt.Sprintf("%d:%s%s", ri, base, ext)
+				if seen[key] {
+					break
+				}
+				seen[key] = true
+
+				group := splitGroup{}
+				for _, p := range rule.Parts {
+					sibling := base + p + ext
+					id, ok := files[sibling]
+					if !ok {
+						group = splitGroup{}
+						break
+					}
+					group.paths = append(group.paths, sibling)
+					group.ids = append(group.ids, id)
+				}
+				if len(group.paths) == len(rule.Parts) {
+					groups = append(groups, group)
+				}
+				break
+			}
+		}
+	}
+
+	return groups
+}
+
+// hashConcatenated hashes the contents of files in order as a single stream.
+func hashConcatenated(paths []string) (string, string, string, error) {
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path) // #nosec G304
+		if err != nil {
+			return "", "", "", err
+		}
+		defer func() { _ = f.Close() }()
+		readers = append(readers, f)
+	}
+	return computeHashes(io.MultiReader(readers...))
+}
+
+// findROMEntryByHash looks up a ROM entry by SHA1, then CRC32, then MD5. Returns 0 if none matches.
+func (s *Scanner) findROMEntryByHash(systemID int64, sha1Hash, crc32Hash, md5Hash string) (int64, string, error) {
+	var romEntryID int64
+	err := s.db.QueryRow(`
+		SELECT re.id FROM rom_entries re
+		JOIN releases r ON re.release_id = r.id
+		WHERE r.system_id = ? AND re.sha1 = ?
+	`, systemID, sha1Hash).Scan(&romEntryID)
+	if err == nil {
+		return romEntryID, "sha1", nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", err
+	}
+
+	err = s.db.QueryRow(`
+		SELECT re.id FROM rom_entries re
+		JOIN releases r ON re.release_id = r.id
+		WHERE r.system_id = ? AND re.crc32 = ?
+	`, systemID, crc32Hash).Scan(&romEntryID)
+	if err == nil {
+		return romEntryID, "crc32", nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", err
+	}
+
+	err = s.db.QueryRow(`
+		SELECT re.id FROM rom_entries re
+		JOIN releases r ON re.release_id = r.id
+		WHERE r.system_id = ? AND re.md5 = ?
+	`, systemID, md5Hash).Scan(&romEntryID)
+	if err == nil {
+		return romEntryID, "md5", nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", err
+	}
+	return 0, "", nil
+}
@@ -0,0 +1,111 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_MatchesSplitROMs(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 1)
+
+	lo, hi := "split rom low half ", "split rom high half"
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(lo + hi))
+	require.NoError(t, err)
+	_, err = database.Conn().Exec(`INSERT INTO releases (id, system_id, name) VALUES (100, 1, 'Split Game (USA)')`)
+	require.NoError(t, err)
+	_, err = database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (100, 'Split Game (USA).nes', ?, ?, ?)
+	`, sha1Hash, crc32Hash, len(lo+hi))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "split-lo.nes"), []byte(lo), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "split-hi.nes"), []byte(hi), 0644)) // #nosec G306
+	// An incomplete set is left alone
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "lonely-lo.nes"), []byte("unpaired half"), 0644)) // #nosec G306
+
+	splitMatches := `SELECT COUNT(*) FROM matches WHERE flags = 'split'`
+
+	// Without a rule the parts stay unmatched
+	result, err := NewScannerWithConfig(database.Conn(), ScanConfig{}).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MatchesFound)
+	assert.Equal(t, 0, countRows(t, database, splitMatches))
+
+	for _, changedOnly := range []bool{false, true} {
+		cfg := ScanConfig{
+			ChangedOnly: changedOnly,
+			SplitROMs:   map[string][]SplitROMRule{"nes": {{Parts: []string{"-lo", "-hi"}}}},
+		}
+		result, err = NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, result.MatchesFound)
+		assert.Equal(t, 1, result.UnmatchedFiles)
+		assert.Equal(t, 2, countRows(t, database, splitMatches))
+		assert.Equal(t, 2, countRows(t, database, `
+			SELECT COUNT(*) FROM matches m
+			JOIN rom_entries re ON re.id = m.rom_entry_id
+			WHERE re.release_id = 100 AND m.match_type = 'sha1'
+		`))
+	}
+
+	// Parts of one split ROM are not duplicates of each other
+	lib, err := NewManager(database.Conn()).Get(context.Background(), "test-lib")
+	require.NoError(t, err)
+	dups, err := NewDuplicateFinder(database.Conn()).FindAllDuplicates(context.Background(), lib.ID)
+	require.NoError(t, err)
+	assert.Empty(t, dups)
+}
+
+func TestFindSplitGroups(t *testing.T) {
+	files := map[string]int64{
+		"/r/a-lo.bin": 1,
+		"/r/a-hi.bin": 2,
+		"/r/b-lo.bin": 3,
+		"/r/c.bin":    4,
+	}
+	groups := findSplitGroups(files, []SplitROMRule{{Parts: []string{"-lo", "-hi"}}})
+
+	require.Len(t, groups, 1)
+	assert.Equal(t, []string{"/r/a-lo.bin", "/r/a-hi.bin"}, groups[0].paths)
+	assert.Equal(t, []int64{1, 2}, groups[0].ids)
+}
+
+func TestScanner_SplitROMsInMultiSystemLibrary(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 1)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	lo, hi := "snes low half ", "snes high half"
+	sha1Hash, crc32Hash, _, err := computeHashes(strings.NewReader(lo + hi))
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (2, 'snes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (100, 2, 'Split Game (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (release_id, name, sha1, crc32, size) VALUES (100, 'Split Game (USA).sfc', ?, ?, ?)`,
+		sha1Hash, crc32Hash, len(lo+hi))
+	require.NoError(t, err)
+
+	snesDir := filepath.Join(libPath, "snes")
+	require.NoError(t, os.MkdirAll(snesDir, 0755))                                             // #nosec G301
+	require.NoError(t, os.WriteFile(filepath.Join(snesDir, "split-lo.sfc"), []byte(lo), 0644)) // #nosec G306
+	require.NoError(t, os.WriteFile(filepath.Join(snesDir, "split-hi.sfc"), []byte(hi), 0644)) // #nosec G306
+	require.NoError(t, NewManager(conn).SetMultiSystem(ctx, "test-lib", true))
+
+	// The rule of the subdirectory's system applies, not the library's
+	cfg := ScanConfig{SplitROMs: map[string][]SplitROMRule{"snes": {{Parts: []string{"-lo", "-hi"}}}}}
+	_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, countRows(t, database, `
+		SELECT COUNT(*) FROM matches m
+		JOIN rom_entries re ON re.id = m.rom_entry_id
+		WHERE re.release_id = 100 AND m.flags = 'split'
+	`))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ryanm101/romman-lib/tracing"
@@ -11,11 +12,41 @@ import (
 
 // ReleaseStatus represents the status of a release in a library.
 type ReleaseStatus struct {
-	ReleaseName string
-	ReleaseID   int64
-	Status      string // "present", "missing", "partial"
-	TotalROMs   int
-	MatchedROMs int
+	ReleaseName  string
+	ReleaseID    int64
+	SystemName   string
+	Status       string // "present", "missing", "partial", "unverified"
+	TotalROMs    int
+	MatchedROMs  int
+	VerifiedROMs int // ROMs matched by a strong hash (sha1 or md5)
+}
+
+// StatusOptions configures release status calculation.
+type StatusOptions struct {
+	// VerifiedOnly counts a release as present only when every ROM has a
+	// strong-hash match; releases complete only through CRC32 or name
+	// matches are reported as "unverified".
+	VerifiedOnly bool
+
+	// PlayableOnly leaves out MAME BIOS, device and mechanical releases,
+	// which are rarely collected as games.
+	PlayableOnly bool
+}
+
+// playableReleaseClause is a SQL condition on releases r that leaves out
+// MAME BIOS, device and mechanical entries.
+const playableReleaseClause = `COALESCE(r.is_bios, 0) = 0 AND COALESCE(r.is_device, 0) = 0 AND COALESCE(r.is_mechanical, 0) = 0`
+
+// verifiedMatchTypes lists match types strong enough to count as verified, for SQL IN clauses.
+const verifiedMatchTypes = `'sha256', 'sha1', 'md5'`
+
+// isVerifiedMatch reports whether a match type is a strong-hash match.
+func isVerifiedMatch(matchType string) bool {
+	switch MatchType(matchType) {
+	case MatchTypeSHA256, MatchTypeSHA1, MatchTypeMD5:
+		return true
+	}
+	return false
 }
 
 // determineReleaseStatus returns status based on matched vs total ROMs.
@@ -31,6 +62,11 @@ func determineReleaseStatus(matched, total int) string {
 
 // GetLibraryStatus returns the status of all releases for a library's system.
 func (s *Scanner) GetLibraryStatus(ctx context.Context, libraryName string) ([]*ReleaseStatus, error) {
+	return s.GetLibraryStatusWithOptions(ctx, libraryName, StatusOptions{})
+}
+
+// GetLibraryStatusWithOptions returns release statuses using the given options.
+func (s *Scanner) GetLibraryStatusWithOptions(ctx context.Context, libraryName string, opts StatusOptions) ([]*ReleaseStatus, error) {
 	ctx, span := tracing.StartSpan(ctx, "library.GetLibraryStatus")
 	defer span.End()
 
@@ -39,20 +75,40 @@ func (s *Scanner) GetLibraryStatus(ctx context.Context, libraryName string) ([]*
 		return nil, err
 	}
 
+	systemIDs, err := librarySystemIDs(ctx, s.db, lib)
+	if err != nil {
+		return nil, err
+	}
+	placeholders := make([]string, len(systemIDs))
+	args := []interface{}{lib.ID}
+	for i, id := range systemIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	where := "r.system_id IN (" + strings.Join(placeholders, ",") + ")"
+	if opts.PlayableOnly {
+		where += " AND " + playableReleaseClause
+	}
+
+	// #nosec G202 - only placeholders and constant clauses are concatenated
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT 
 			r.id,
 			r.name,
-			COUNT(re.id) as total_roms,
-			COUNT(m.id) as matched_roms
+			s.name,
+			COUNT(DISTINCT re.id) as total_roms,
+			COUNT(DISTINCT CASE WHEN m.id IS NOT NULL THEN re.id END) as matched_roms,
+			COUNT(DISTINCT CASE WHEN m.match_type IN (`+verifiedMatchTypes+`) THEN re.id END) as verified_roms
 		FROM releases r
+		JOIN systems s ON s.id = r.system_id
 		JOIN rom_entries re ON re.release_id = r.id
 		LEFT JOIN matches m ON m.rom_entry_id = re.id 
 			AND m.scanned_file_id IN (SELECT id FROM scanned_files WHERE library_id = ?)
-		WHERE r.system_id = ?
+		WHERE `+where+`
 		GROUP BY r.id
-		ORDER BY r.name
-	`, lib.ID, lib.SystemID)
+		ORDER BY r.name, r.id
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -61,18 +117,73 @@ func (s *Scanner) GetLibraryStatus(ctx context.Context, libraryName string) ([]*
 	var statuses []*ReleaseStatus
 	for rows.Next() {
 		status := &ReleaseStatus{}
-		if err := rows.Scan(&status.ReleaseID, &status.ReleaseName, &status.TotalROMs, &status.MatchedROMs); err != nil {
+		if err := rows.Scan(&status.ReleaseID, &status.ReleaseName, &status.SystemName, &status.TotalROMs,
+			&status.MatchedROMs, &status.VerifiedROMs); err != nil {
 			return nil, err
 		}
 
 		status.Status = determineReleaseStatus(status.MatchedROMs, status.TotalROMs)
+		if opts.VerifiedOnly && status.Status == "present" && status.VerifiedROMs < status.TotalROMs {
+			status.Status = "unverified"
+		}
 		statuses = append(statuses, status)
 	}
 
 	return statuses, nil
 }
 
-// GetUnmatchedFiles returns files that don't match any known ROM.
+// MissingROMEntry is a DAT ROM of a release that has no match in a library,
+// with the expected size and hashes to identify the missing file.
+type MissingROMEntry struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	CRC32 string `json:"crc32,omitempty"`
+	MD5   string `json:"md5,omitempty"`
+	SHA1  string `json:"sha1,omitempty"`
+}
+
+// GetMissingROMEntries returns the ROM entries of a release that no file in
+// the library matches, such as the absent tracks or discs of a partial release.
+func (s *Scanner) GetMissingROMEntries(ctx context.Context, libraryName string, releaseID int64) ([]MissingROMEntry, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetMissingROMEntries")
+	defer span.End()
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT re.id, re.name, COALESCE(re.size, 0), COALESCE(re.crc32, ''), COALESCE(re.md5, ''), COALESCE(re.sha1, '')
+		FROM rom_entries re
+		WHERE re.release_id = ?
+		AND NOT EXISTS (
+			SELECT 1 FROM matches m
+			JOIN scanned_files sf ON sf.id = m.scanned_file_id
+			WHERE m.rom_entry_id = re.id AND sf.library_id = ?
+		)
+		ORDER BY re.name, re.id
+	`, releaseID, lib.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []MissingROMEntry
+	for rows.Next() {
+		var e MissingROMEntry
+		if err := rows.Scan(&e.ID, &e.Name, &e.Size, &e.CRC32, &e.MD5, &e.SHA1); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetUnmatchedFiles returns files that don't match any known ROM. Archive
+// entries are listed as "archive:entry", where a nested entry's name holds
+// each archive it is in, as in "nes.zip:inner.zip!game.nes".
 func (s *Scanner) GetUnmatchedFiles(ctx context.Context, libraryName string) ([]string, error) {
 	ctx, span := tracing.StartSpan(ctx, "library.GetUnmatchedFiles")
 	defer span.End()
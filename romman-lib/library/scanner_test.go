@@ -3,9 +3,13 @@ package library
 import (
 	"archive/zip"
 	"context"
+	"crypto/sha1" // #nosec G505
+	"encoding/hex"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,6 +105,42 @@ func TestScanner_HashCaching(t *testing.T) {
 	assert.Equal(t, 1, result2.FilesSkipped)
 }
 
+func TestScanner_CacheKey(t *testing.T) {
+	ctx := context.Background()
+	database, libPath := setupCheckpointLibrary(t, 2)
+	conn := database.Conn()
+
+	result, err := NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.FilesHashed)
+
+	// A copy to a network share rewrote the mtimes
+	later := time.Now().Add(time.Hour)
+	for _, name := range []string{"game00.nes", "game01.nes"} {
+		require.NoError(t, os.Chtimes(filepath.Join(libPath, name), later, later))
+	}
+
+	cfg := DefaultScanConfig()
+	cfg.CacheKey = CacheKeySize
+	result, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.FilesHashed, "size alone keeps the cache")
+	assert.Equal(t, 2, result.FilesSkipped)
+
+	cfg.ForceRehash = true
+	result, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.FilesHashed, "forced rehash ignores the cache")
+	assert.Equal(t, 2, result.MatchesFound)
+
+	result, err = NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.FilesHashed, "the rehash stored the new mtimes")
+
+	_, err = NewScannerWithConfig(conn, ScanConfig{CacheKey: "inode"}).Scan(ctx, "test-lib")
+	assert.ErrorIs(t, err, ErrInvalidArg)
+}
+
 func TestScanner_ZipSupport(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -142,6 +182,169 @@ func TestScanner_ZipSupport(t *testing.T) {
 	assert.Equal(t, 1, result.FilesScanned)
 }
 
+func TestScanner_NoArchives(t *testing.T) {
+	for _, parallel := range []bool{true, false} {
+		ctx := context.Background()
+		database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+		require.NoError(t, err)
+		defer func() { _ = database.Close() }()
+		conn := database.Conn()
+
+		libPath := t.TempDir()
+		zipPath := filepath.Join(libPath, "game.zip")
+		createTestZip(t, zipPath, "game.nes", []byte("zip rom content"))
+		data, err := os.ReadFile(zipPath) // #nosec G304
+		require.NoError(t, err)
+		sum := sha1.Sum(data) // #nosec G401
+
+		// The DAT lists the zip itself, as for a one-game-per-zip set
+		_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Test Game')`)
+		require.NoError(t, err)
+		_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1, size) VALUES (1, 1, 'game.zip', ?, ?)`,
+			hex.EncodeToString(sum[:]), len(data))
+		require.NoError(t, err)
+		_, err = NewManager(conn).Add(ctx, "test-lib", libPath, "nes")
+		require.NoError(t, err)
+
+		entries := func() []string {
+			t.Helper()
+			rows, err := conn.Query(`
+				SELECT sf.path || '#' || COALESCE(sf.archive_path, '') || '#' || COALESCE(m.match_type, '')
+				FROM scanned_files sf LEFT JOIN matches m ON m.scanned_file_id = sf.id
+				ORDER BY sf.id`)
+			require.NoError(t, err)
+			defer func() { _ = rows.Close() }()
+			var got []string
+			for rows.Next() {
+				var entry string
+				require.NoError(t, rows.Scan(&entry))
+				got = append(got, entry)
+			}
+			return got
+		}
+
+		cfg := DefaultScanConfig()
+		cfg.Parallel = parallel
+		cfg.NoArchives = true
+		_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, []string{zipPath + "##sha1"}, entries(), "parallel=%v", parallel)
+
+		// Expanding again replaces the whole zip with its entry, which the
+		// DAT only lists by name
+		cfg.NoArchives = false
+		_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, []string{zipPath + "#game.nes#name"}, entries(), "parallel=%v", parallel)
+	}
+}
+
+func TestScanner_NoArchivesKeepsManualMatches(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	libPath := t.TempDir()
+	zipPath := filepath.Join(libPath, "game.zip")
+	createTestZip(t, zipPath, "game.nes", []byte("zip rom content"))
+
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Test Game')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, sha1, size) VALUES (1, 1, 'Test Game.nes', 'x', 15)`)
+	require.NoError(t, err)
+	_, err = NewManager(conn).Add(ctx, "test-lib", libPath, "nes")
+	require.NoError(t, err)
+
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+	_, err = scanner.ReassignMatch(ctx, "test-lib", zipPath+":game.nes", "Test Game")
+	require.NoError(t, err)
+
+	// Switching to whole archives keeps the manually matched entry
+	cfg := DefaultScanConfig()
+	cfg.NoArchives = true
+	_, err = NewScannerWithConfig(conn, cfg).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	var manual int
+	require.NoError(t, conn.QueryRow(`
+		SELECT COUNT(*) FROM matches m
+		JOIN scanned_files sf ON sf.id = m.scanned_file_id
+		WHERE sf.archive_path = 'game.nes' AND m.is_manual = 1`).Scan(&manual))
+	assert.Equal(t, 1, manual)
+}
+
+func TestScanner_ZipHeaderCRCMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.Open(context.Background(), filepath.Join(tmpDir, "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	_, err = database.Conn().Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes')`)
+	require.NoError(t, err)
+
+	libPath := filepath.Join(tmpDir, "roms")
+	require.NoError(t, os.MkdirAll(libPath, 0755)) // #nosec G301
+
+	content := []byte("zip rom content")
+	createTestZip(t, filepath.Join(libPath, "good.zip"), "good.nes", content)
+
+	// A stored entry whose header declares the wrong CRC, as left by a bad repack
+	f, err := os.Create(filepath.Join(libPath, "bad.zip")) // #nosec G304
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	fw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               "bad.nes",
+		Method:             zip.Store,
+		CRC32:              0xdeadbeef,
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: uint64(len(content)),
+	})
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	manager := NewManager(database.Conn())
+	_, err = manager.Add(context.Background(), "test-lib", libPath, "nes")
+	require.NoError(t, err)
+
+	for _, parallel := range []bool{true, false} {
+		_, err = database.Conn().Exec(`DELETE FROM scanned_files`)
+		require.NoError(t, err)
+
+		config := DefaultScanConfig()
+		config.Parallel = parallel
+		config.Workers = 2
+		result, err := NewScannerWithConfig(database.Conn(), config).Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.FilesScanned)
+
+		// The data is still hashed; only the declared CRC differs
+		var crc, zipCRC string
+		err = database.Conn().QueryRow(`SELECT crc32, zip_crc32 FROM scanned_files WHERE archive_path = 'bad.nes'`).Scan(&crc, &zipCRC)
+		require.NoError(t, err)
+		assert.Equal(t, "deadbeef", zipCRC)
+		assert.NotEqual(t, zipCRC, crc)
+
+		check, err := NewIntegrityChecker(database.Conn(), manager).Check(context.Background(), "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, 1, check.CRCMismatch, "parallel=%v", parallel)
+		require.Len(t, check.Issues, 1)
+		assert.Equal(t, "crc-mismatch", check.Issues[0].IssueType)
+		assert.Equal(t, filepath.Join(libPath, "bad.zip")+"#bad.nes", check.Issues[0].Path)
+	}
+}
+
 func createTestZip(t *testing.T, zipPath, filename string, content []byte) {
 	t.Helper()
 
@@ -158,3 +361,102 @@ func createTestZip(t *testing.T, zipPath, filename string, content []byte) {
 	_, err = fw.Write(content)
 	require.NoError(t, err)
 }
+
+func TestScanner_ProgressChannel(t *testing.T) {
+	for _, parallel := range []bool{true, false} {
+		database, libPath := setupCheckpointLibrary(t, 5)
+
+		progress := make(chan ScanProgress, 100)
+		var callbacks int
+		scanner := NewScannerWithConfig(database.Conn(), ScanConfig{
+			Parallel:   parallel,
+			BatchSize:  2,
+			Progress:   progress,
+			OnProgress: func(ScanProgress) { callbacks++ },
+		})
+		_, err := scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+		close(progress)
+
+		var events []ScanProgress
+		for p := range progress {
+			events = append(events, p)
+		}
+		// The count comes first, then one event per file
+		require.Len(t, events, 6)
+		assert.Equal(t, callbacks, len(events))
+		assert.Equal(t, ScanProgress{TotalFiles: 5}, events[0])
+		last := events[len(events)-1]
+		assert.Equal(t, int64(5), last.TotalFiles)
+		assert.Equal(t, int64(5), last.FilesScanned)
+		assert.Equal(t, int64(5), last.FilesHashed)
+		assert.Equal(t, libPath, filepath.Dir(last.CurrentPath))
+		// Matches are counted as batches are committed, so the last file's is not yet
+		assert.Equal(t, int64(4), last.MatchesFound)
+
+		// Cached files count their existing matches
+		var rescanned ScanProgress
+		scanner = NewScannerWithConfig(database.Conn(), ScanConfig{
+			Parallel:   parallel,
+			BatchSize:  2,
+			OnProgress: func(p ScanProgress) { rescanned = p },
+		})
+		_, err = scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), rescanned.FilesSkipped)
+		assert.Equal(t, int64(4), rescanned.MatchesFound)
+
+		// A reader that never keeps up does not stall the scan
+		scanner = NewScannerWithConfig(database.Conn(), ScanConfig{Parallel: parallel, Progress: make(chan ScanProgress)})
+		result, err := scanner.Scan(context.Background(), "test-lib")
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.FilesSkipped)
+	}
+}
+
+func TestScanResult_DurationAndJSON(t *testing.T) {
+	database, _ := setupCheckpointLibrary(t, 2)
+
+	result, err := NewScanner(database.Conn()).Scan(context.Background(), "test-lib")
+	require.NoError(t, err)
+	assert.Positive(t, result.Duration)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"filesScanned":2,"filesHashed":2,"filesCached":0,"filesVerified":0,"filesPruned":0,"filesExcluded":0,
+		"matchesFound":2,"unmatchedFiles":0,"errors":null}`, string(data))
+}
+
+func TestScanner_GetMissingROMEntries(t *testing.T) {
+	ctx := context.Background()
+	database, _ := setupCheckpointLibrary(t, 2)
+
+	// Game 00 gets a second disc the library doesn't have
+	_, err := database.Conn().Exec(`
+		INSERT INTO rom_entries (release_id, name, sha1, crc32, md5, size)
+		VALUES (1, 'Game 00 (USA) (Disc 2).bin', 'abcdef0123456789abcdef0123456789abcdef01', '1234abcd', '0123456789abcdef0123456789abcdef', 42)
+	`)
+	require.NoError(t, err)
+
+	scanner := NewScanner(database.Conn())
+	_, err = scanner.Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	statuses, err := scanner.GetLibraryStatus(ctx, "test-lib")
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "partial", statuses[0].Status)
+
+	missing, err := scanner.GetMissingROMEntries(ctx, "test-lib", statuses[0].ReleaseID)
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "Game 00 (USA) (Disc 2).bin", missing[0].Name)
+	assert.Equal(t, int64(42), missing[0].Size)
+	assert.Equal(t, "1234abcd", missing[0].CRC32)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", missing[0].MD5)
+	assert.Equal(t, "abcdef0123456789abcdef0123456789abcdef01", missing[0].SHA1)
+
+	missing, err = scanner.GetMissingROMEntries(ctx, "test-lib", statuses[1].ReleaseID)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
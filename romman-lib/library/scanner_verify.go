@@ -0,0 +1,98 @@
+package library
+
+import (
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Scan error kinds recorded in ScanResult.Errors.
+const (
+	ScanErrorCorrupt = "corrupt" // Content changed without a size/mtime change (bitrot)
+	ScanErrorRead    = "read"    // File, archive or archive entry could not be read, so it was skipped
+)
+
+// ScanError describes a problem found with a single file during a scan.
+type ScanError struct {
+	Path        string `json:"path"`
+	ArchivePath string `json:"archivePath,omitempty"`
+	Kind        string `json:"kind"`
+	Message     string `json:"message"`
+}
+
+// sampleVerifier rehashes a random sample of cached files during a scan to
+// detect content that changed without its size or mtime changing.
+type sampleVerifier struct {
+	percent float64
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	verified int
+	errors   []ScanError
+}
+
+// newSampleVerifier returns a verifier for the given sample percentage, or nil when sampling is off.
+func newSampleVerifier(percent float64) *sampleVerifier {
+	if percent <= 0 {
+		return nil
+	}
+	return &sampleVerifier{
+		percent: percent,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404 - sampling, not security
+	}
+}
+
+// sample reports whether the next cached file should be rehashed.
+func (v *sampleVerifier) sample() bool {
+	if v == nil {
+		return false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.rng.Float64()*100 < v.percent
+}
+
+// check rehashes a sampled cached file and records a corrupt error if its SHA1 changed.
+// CHD files are skipped because their stored hash comes from the header, not the content.
+func (v *sampleVerifier) check(s *Scanner, job fileJob, cached *ScannedFile) {
+	if job.isCHD || !v.sample() {
+		return
+	}
+
+	var sha1Hash string
+	var err error
+	if job.archivePath != "" {
+		sha1Hash, _, _, err = s.hashArchiveEntry(job.path, job.archivePath)
+	} else {
+		sha1Hash, _, _, err = s.hashFile(job.path)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.verified++
+
+	if err != nil {
+		slog.Warn("failed to verify sampled file", "path", job.path, "error", err)
+		return
+	}
+	if sha1Hash != cached.SHA1 {
+		v.errors = append(v.errors, ScanError{
+			Path:        job.path,
+			ArchivePath: job.archivePath,
+			Kind:        ScanErrorCorrupt,
+			Message:     "content hash changed: expected " + cached.SHA1 + ", got " + sha1Hash,
+		})
+	}
+}
+
+// apply copies the verification results into a scan result.
+func (v *sampleVerifier) apply(result *ScanResult) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	result.FilesVerified = v.verified
+	result.Errors = append(result.Errors, v.errors...)
+}
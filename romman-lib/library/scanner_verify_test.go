@@ -0,0 +1,51 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner_SampleVerifyCatchesChangedFile(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		name := "sequential"
+		if parallel {
+			name = "parallel"
+		}
+		t.Run(name, func(t *testing.T) {
+			database, libPath := setupCheckpointLibrary(t, 5)
+			cfg := ScanConfig{Workers: 2, BatchSize: 2, Parallel: parallel}
+
+			_, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+
+			// Flip the content without changing size or mtime, as bitrot would
+			path := filepath.Join(libPath, "game02.nes")
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, []byte("rom_content 2"), 0644)) // #nosec G306
+			require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+
+			// Sampling off: the size/mtime cache hides the change
+			result, err := NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+			assert.Empty(t, result.Errors)
+			assert.Equal(t, 0, result.FilesVerified)
+
+			// Sampling every file catches it
+			cfg.SampleVerifyPercent = 100
+			result, err = NewScannerWithConfig(database.Conn(), cfg).Scan(context.Background(), "test-lib")
+			require.NoError(t, err)
+
+			assert.Equal(t, 5, result.FilesSkipped)
+			assert.Equal(t, 5, result.FilesVerified)
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, path, result.Errors[0].Path)
+			assert.Equal(t, ScanErrorCorrupt, result.Errors[0].Kind)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// searchBatchSize bounds the release IDs looked up per location query.
+const searchBatchSize = 500
+
+// SearchLocation is a library file matching a release found by Search.
+type SearchLocation struct {
+	Library     string `json:"library"`
+	Path        string `json:"path"`
+	ArchivePath string `json:"archivePath,omitempty"`
+}
+
+// SearchHit is a release found by Search, with the library files that
+// match it, if any.
+type SearchHit struct {
+	ReleaseID int64            `json:"releaseId"`
+	Release   string           `json:"release"`
+	System    string           `json:"system"`
+	Matched   bool             `json:"matched"`
+	Locations []SearchLocation `json:"locations,omitempty"`
+}
+
+// Search finds releases of every system whose normalized name contains the
+// normalized query, so "chrono trigger" finds "Chrono Trigger (USA)". Hits
+// are ordered by system and release name.
+func Search(ctx context.Context, db *sql.DB, query string) ([]SearchHit, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.Search")
+	defer span.End()
+
+	needle := normalizeSearchTitle(query)
+	if needle == "" {
+		return nil, fmt.Errorf("%w: search query %q has no letters or digits", ErrInvalidArg, query)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.id, r.name, s.name
+		FROM releases r
+		JOIN systems s ON s.id = r.system_id
+		ORDER BY s.name, r.name, r.id
+	`)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "search releases")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.ReleaseID, &hit.Release, &hit.System); err != nil {
+			return nil, err
+		}
+		if strings.Contains(normalizeSearchTitle(hit.Release), needle) {
+			hits = append(hits, hit)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	byID := make(map[int64]*SearchHit, len(hits))
+	ids := make([]interface{}, 0, len(hits))
+	for i := range hits {
+		byID[hits[i].ReleaseID] = &hits[i]
+		ids = append(ids, hits[i].ReleaseID)
+	}
+	for start := 0; start < len(ids); start += searchBatchSize {
+		end := start + searchBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := addSearchLocations(ctx, db, ids[start:end], byID); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	return hits, nil
+}
+
+// addSearchLocations records the library files matching each of a batch of
+// releases on its hit.
+func addSearchLocations(ctx context.Context, db *sql.DB, ids []interface{}, byID map[int64]*SearchHit) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	// #nosec G202 - only placeholders are concatenated
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT re.release_id, l.name, sf.path, COALESCE(sf.archive_path, '')
+		FROM rom_entries re
+		JOIN matches m ON m.rom_entry_id = re.id
+		JOIN scanned_files sf ON sf.id = m.scanned_file_id
+		JOIN libraries l ON l.id = sf.library_id
+		WHERE re.release_id IN (`+placeholders+`)
+		ORDER BY l.name, sf.path, sf.archive_path
+	`, ids...)
+	if err != nil {
+		return WrapDBError(err, "search locations")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var releaseID int64
+		var loc SearchLocation
+		if err := rows.Scan(&releaseID, &loc.Library, &loc.Path, &loc.ArchivePath); err != nil {
+			return err
+		}
+		hit := byID[releaseID]
+		hit.Matched = true
+		hit.Locations = append(hit.Locations, loc)
+	}
+	return rows.Err()
+}
+
+// normalizeSearchTitle normalizes a release name or query for Search.
+// Neither has a file extension, so a dot is appended for
+// NormalizeTitleForMatching to strip instead of cutting a title such as
+// "Dr. Mario" short.
+func normalizeSearchTitle(title string) string {
+	return NormalizeTitleForMatching(title + ".")
+}
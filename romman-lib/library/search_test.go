@@ -0,0 +1,48 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 2)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	_, err := NewScanner(conn).Scan(ctx, "test-lib")
+	require.NoError(t, err)
+
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (2, 'snes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (system_id, name) VALUES (2, 'Game 01 - Special (Japan)'), (2, 'Dr. Mario (Japan)')`)
+	require.NoError(t, err)
+
+	hits, err := Search(ctx, conn, "game-01")
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+
+	assert.Equal(t, "nes", hits[0].System)
+	assert.Equal(t, "Game 01 (USA)", hits[0].Release)
+	assert.True(t, hits[0].Matched)
+	assert.Equal(t, []SearchLocation{{Library: "test-lib", Path: filepath.Join(libPath, "game01.nes")}}, hits[0].Locations)
+
+	assert.Equal(t, "snes", hits[1].System)
+	assert.Equal(t, "Game 01 - Special (Japan)", hits[1].Release)
+	assert.False(t, hits[1].Matched)
+	assert.Empty(t, hits[1].Locations)
+
+	// A dot in the title is not taken for an extension
+	hits, err = Search(ctx, conn, "dr. mario")
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "Dr. Mario (Japan)", hits[0].Release)
+
+	_, err = Search(ctx, conn, "(USA)")
+	assert.True(t, errors.Is(err, ErrInvalidArg))
+}
@@ -0,0 +1,95 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// SpaceUsage holds the disk usage of scanned files for a library or system.
+// Archive entries count at their uncompressed size.
+type SpaceUsage struct {
+	System       string `json:"system"`
+	Library      string `json:"library,omitempty"` // Empty for system totals
+	TotalFiles   int    `json:"totalFiles"`
+	TotalBytes   int64  `json:"totalBytes"`
+	MatchedFiles int    `json:"matchedFiles"`
+	MatchedBytes int64  `json:"matchedBytes"`
+}
+
+// SpaceReport contains disk usage per library, per system and overall.
+type SpaceReport struct {
+	Libraries    []SpaceUsage `json:"libraries"`
+	Systems      []SpaceUsage `json:"systems"`
+	TotalBytes   int64        `json:"totalBytes"`
+	MatchedBytes int64        `json:"matchedBytes"`
+}
+
+// GetSpaceUsage sums scanned file sizes per library and system, split into
+// matched and total. Virtual files imported from hash lists are not on disk
+// and are excluded.
+func GetSpaceUsage(ctx context.Context, db *sql.DB) (*SpaceReport, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetSpaceUsage")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			s.name,
+			l.name,
+			COUNT(sf.id),
+			COALESCE(SUM(sf.size), 0),
+			COUNT(mf.scanned_file_id),
+			COALESCE(SUM(CASE WHEN mf.scanned_file_id IS NOT NULL THEN sf.size END), 0)
+		FROM libraries l
+		JOIN systems s ON s.id = l.system_id
+		LEFT JOIN scanned_files sf ON sf.library_id = l.id AND sf.virtual = 0
+		LEFT JOIN (SELECT DISTINCT scanned_file_id FROM matches) mf ON mf.scanned_file_id = sf.id
+		GROUP BY l.id
+		ORDER BY s.name, l.name
+	`)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "get space usage")
+	}
+	defer func() { _ = rows.Close() }()
+
+	report := &SpaceReport{}
+	for rows.Next() {
+		var u SpaceUsage
+		if err := rows.Scan(&u.System, &u.Library, &u.TotalFiles, &u.TotalBytes, &u.MatchedFiles, &u.MatchedBytes); err != nil {
+			return nil, err
+		}
+		report.Libraries = append(report.Libraries, u)
+
+		// Libraries are ordered by system, so totals accumulate into the last entry
+		if n := len(report.Systems); n == 0 || report.Systems[n-1].System != u.System {
+			report.Systems = append(report.Systems, SpaceUsage{System: u.System})
+		}
+		sys := &report.Systems[len(report.Systems)-1]
+		sys.TotalFiles += u.TotalFiles
+		sys.TotalBytes += u.TotalBytes
+		sys.MatchedFiles += u.MatchedFiles
+		sys.MatchedBytes += u.MatchedBytes
+
+		report.TotalBytes += u.TotalBytes
+		report.MatchedBytes += u.MatchedBytes
+	}
+
+	return report, rows.Err()
+}
+
+// FormatBytes renders a byte count in binary units, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,62 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestGetSpaceUsage(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	conn := database.Conn()
+	stmts := []string{
+		`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes'), (3, 'gb')`,
+		`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game A'), (2, 1, 'Game B')`,
+		`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES (1, 1, 'a.nes', 'aa'), (2, 2, 'b.nes', 'aa')`,
+		`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'nes-a', '/a', 1), (2, 'nes-b', '/b', 1), (3, 'snes', '/s', 2)`,
+		// File 1 matches two entries but must only be counted once; file 4 is virtual
+		`INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1, virtual) VALUES
+			(1, 1, '/a/a.nes', 100, 0, 'aa', 0),
+			(2, 1, '/a/junk.txt', 10, 0, 'zz', 0),
+			(3, 2, '/b/a.zip', 1000, 0, 'aa', 0),
+			(4, 2, 'virtual:aa', 5000, 0, 'aa', 1),
+			(5, 3, '/s/x.sfc', 7, 0, 'xx', 0)`,
+		`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (1, 2, 'sha1'), (3, 1, 'sha1'), (4, 1, 'sha1')`,
+	}
+	for _, s := range stmts {
+		_, err := conn.Exec(s)
+		require.NoError(t, err)
+	}
+
+	report, err := GetSpaceUsage(context.Background(), conn)
+	require.NoError(t, err)
+
+	require.Len(t, report.Libraries, 3)
+	assert.Equal(t, SpaceUsage{System: "nes", Library: "nes-a", TotalFiles: 2, TotalBytes: 110, MatchedFiles: 1, MatchedBytes: 100}, report.Libraries[0])
+	assert.Equal(t, SpaceUsage{System: "nes", Library: "nes-b", TotalFiles: 1, TotalBytes: 1000, MatchedFiles: 1, MatchedBytes: 1000}, report.Libraries[1])
+	assert.Equal(t, SpaceUsage{System: "snes", Library: "snes", TotalFiles: 1, TotalBytes: 7}, report.Libraries[2])
+
+	// Systems without libraries are left out
+	require.Len(t, report.Systems, 2)
+	assert.Equal(t, SpaceUsage{System: "nes", TotalFiles: 3, TotalBytes: 1110, MatchedFiles: 2, MatchedBytes: 1100}, report.Systems[0])
+	assert.Equal(t, "snes", report.Systems[1].System)
+
+	assert.Equal(t, int64(1117), report.TotalBytes)
+	assert.Equal(t, int64(1100), report.MatchedBytes)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "0 B", FormatBytes(0))
+	assert.Equal(t, "1023 B", FormatBytes(1023))
+	assert.Equal(t, "1.0 KiB", FormatBytes(1024))
+	assert.Equal(t, "1.5 MiB", FormatBytes(1536*1024))
+	assert.Equal(t, "2.0 GiB", FormatBytes(2<<30))
+}
@@ -0,0 +1,64 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// CollectionStats summarizes the whole collection across every system and
+// library. File counts and sizes exclude virtual files imported from hash
+// lists, as in GetSpaceUsage.
+type CollectionStats struct {
+	Systems         int     `json:"systems"`
+	Libraries       int     `json:"libraries"`
+	Releases        int     `json:"releases"`
+	ROMEntries      int     `json:"romEntries"`
+	ScannedFiles    int     `json:"scannedFiles"`
+	MatchedFiles    int     `json:"matchedFiles"`
+	MatchedReleases int     `json:"matchedReleases"`
+	Completion      float64 `json:"completion"` // Percentage of releases matched, 0-100
+	MatchedBytes    int64   `json:"matchedBytes"`
+}
+
+// GetCollectionStats totals GetSystemCompletion and GetSpaceUsage over the
+// whole collection.
+func GetCollectionStats(ctx context.Context, db *sql.DB) (*CollectionStats, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetCollectionStats")
+	defer span.End()
+
+	stats := &CollectionStats{}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM rom_entries").Scan(&stats.ROMEntries); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "count rom entries")
+	}
+
+	completion, err := GetSystemCompletion(ctx, db)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "get system completion")
+	}
+	stats.Systems = len(completion)
+	for _, c := range completion {
+		stats.Releases += c.TotalReleases
+		stats.MatchedReleases += c.MatchedReleases
+	}
+	if stats.Releases > 0 {
+		stats.Completion = float64(stats.MatchedReleases) / float64(stats.Releases) * 100
+	}
+
+	space, err := GetSpaceUsage(ctx, db)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	stats.Libraries = len(space.Libraries)
+	for _, u := range space.Libraries {
+		stats.ScannedFiles += u.TotalFiles
+		stats.MatchedFiles += u.MatchedFiles
+	}
+	stats.MatchedBytes = space.MatchedBytes
+
+	return stats, nil
+}
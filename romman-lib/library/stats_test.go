@@ -0,0 +1,56 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestGetCollectionStats(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	stats, err := GetCollectionStats(context.Background(), database.Conn())
+	require.NoError(t, err)
+	assert.Equal(t, CollectionStats{}, *stats, "an empty collection")
+
+	conn := database.Conn()
+	stmts := []string{
+		`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes')`,
+		`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game A'), (2, 1, 'Game B'), (3, 2, 'Game C'), (4, 2, 'Game D')`,
+		`INSERT INTO rom_entries (id, release_id, name, sha1) VALUES
+			(1, 1, 'a.nes', 'aa'), (2, 2, 'b.nes', 'bb'), (3, 3, 'c.sfc', 'cc'), (4, 3, 'c2.sfc', 'c2'), (5, 4, 'd.sfc', 'dd')`,
+		`INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'nes', '/n', 1), (2, 'snes', '/s', 2)`,
+		// File 4 is virtual and only counts towards completion
+		`INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1, virtual) VALUES
+			(1, 1, '/n/a.nes', 100, 0, 'aa', 0),
+			(2, 1, '/n/junk.txt', 10, 0, 'zz', 0),
+			(3, 2, '/s/c.sfc', 1000, 0, 'cc', 0),
+			(4, 2, 'virtual:dd', 5000, 0, 'dd', 1)`,
+		`INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1'), (3, 3, 'sha1'), (4, 5, 'sha1')`,
+	}
+	for _, s := range stmts {
+		_, err := conn.Exec(s)
+		require.NoError(t, err)
+	}
+
+	stats, err = GetCollectionStats(context.Background(), conn)
+	require.NoError(t, err)
+	assert.Equal(t, CollectionStats{
+		Systems:         2,
+		Libraries:       2,
+		Releases:        4,
+		ROMEntries:      5,
+		ScannedFiles:    3,
+		MatchedFiles:    2,
+		MatchedReleases: 3,
+		Completion:      75,
+		MatchedBytes:    1100,
+	}, *stats)
+}
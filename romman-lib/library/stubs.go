@@ -0,0 +1,57 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// StubSystem is a system with libraries but nothing to match them against,
+// typically created by 'library discover --force' before a DAT was imported.
+type StubSystem struct {
+	Name      string   `json:"name"`
+	Libraries []string `json:"libraries"`
+	Releases  int      `json:"releases"`
+	HasDAT    bool     `json:"hasDat"`
+}
+
+// GetStubSystems returns the systems that have a library but no DAT source
+// or no releases, sorted by name. Matching always fails for these systems.
+func GetStubSystems(ctx context.Context, db *sql.DB) ([]StubSystem, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.GetStubSystems")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			s.name,
+			GROUP_CONCAT(l.name, char(10)),
+			(SELECT COUNT(*) FROM releases r WHERE r.system_id = s.id) as releases,
+			EXISTS (SELECT 1 FROM dat_sources ds WHERE ds.system_id = s.id) as has_dat
+		FROM systems s
+		JOIN libraries l ON l.system_id = s.id
+		GROUP BY s.id
+		HAVING releases = 0 OR has_dat = 0
+		ORDER BY s.name
+	`)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "get stub systems")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []StubSystem
+	for rows.Next() {
+		var st StubSystem
+		var libs string
+		if err := rows.Scan(&st.Name, &libs, &st.Releases, &st.HasDAT); err != nil {
+			return nil, err
+		}
+		st.Libraries = strings.Split(libs, "\n")
+		sort.Strings(st.Libraries)
+		results = append(results, st)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,39 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestGetStubSystems(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	conn := database.Conn()
+	stmts := []string{
+		// nes: real DAT; snes: stub from discover --force; gb: source but no releases; gba: no library
+		`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes'), (3, 'gb'), (4, 'gba')`,
+		`INSERT INTO dat_sources (system_id, source_type) VALUES (1, 'no-intro'), (3, 'no-intro')`,
+		`INSERT INTO releases (id, system_id, name) VALUES (1, 1, 'Game A')`,
+		`INSERT INTO libraries (name, root_path, system_id) VALUES
+			('nes-lib', '/nes', 1), ('snes-b', '/snes2', 2), ('snes-a', '/snes', 2), ('gb-lib', '/gb', 3)`,
+	}
+	for _, s := range stmts {
+		_, err := conn.Exec(s)
+		require.NoError(t, err)
+	}
+
+	stubs, err := GetStubSystems(context.Background(), conn)
+	require.NoError(t, err)
+	assert.Equal(t, []StubSystem{
+		{Name: "gb", Libraries: []string{"gb-lib"}, Releases: 0, HasDAT: true},
+		{Name: "snes", Libraries: []string{"snes-a", "snes-b"}, Releases: 0, HasDAT: false},
+	}, stubs)
+}
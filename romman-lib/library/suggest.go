@@ -0,0 +1,227 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/ryanm101/romman-lib/tracing"
+)
+
+// DefaultSuggestionLimit is how many candidates SuggestMatches proposes for
+// each file when no limit is given.
+const DefaultSuggestionLimit = 3
+
+// Reasons a candidate release was suggested.
+const (
+	SuggestByName = "name" // Closest normalized title
+	SuggestBySize = "size" // Closest ROM size
+)
+
+// MatchSuggestion is a release an unmatched file might be a dump of.
+type MatchSuggestion struct {
+	Release   string `json:"release"`
+	ROM       string `json:"rom"`
+	Size      int64  `json:"size"`
+	SizeDelta int64  `json:"sizeDelta"` // File size minus ROM size; 16 or 512 hints at a header
+	Distance  int    `json:"distance"`  // Edit distance between normalized titles
+	Reason    string `json:"reason"`
+}
+
+// UnmatchedSuggestion lists the candidate releases for an unmatched file.
+type UnmatchedSuggestion struct {
+	Path       string            `json:"path"`
+	Size       int64             `json:"size"`
+	Candidates []MatchSuggestion `json:"candidates"`
+}
+
+// suggestEntry is a ROM entry of the library's system a file is compared with.
+type suggestEntry struct {
+	release string
+	rom     string
+	size    int64
+	title   string
+}
+
+// SuggestMatches proposes up to limit likely releases for each unmatched
+// file of a library: those whose normalized titles are closest to the
+// file's, then the ROM closest to the file in size, which finds mis-hashed
+// and headered dumps with unhelpful names. A limit of 0 means
+// DefaultSuggestionLimit.
+func (s *Scanner) SuggestMatches(ctx context.Context, libraryName string, limit int) ([]UnmatchedSuggestion, error) {
+	ctx, span := tracing.StartSpan(ctx, "library.SuggestMatches")
+	defer span.End()
+
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: suggestion limit must not be negative", ErrInvalidArg)
+	}
+	if limit == 0 {
+		limit = DefaultSuggestionLimit
+	}
+
+	lib, err := s.manager.Get(ctx, libraryName)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	entries, err := s.loadSuggestEntries(ctx, lib.SystemID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sf.path, sf.archive_path, sf.size
+		FROM scanned_files sf
+		LEFT JOIN matches m ON m.scanned_file_id = sf.id
+		WHERE sf.library_id = ? AND m.id IS NULL
+		ORDER BY sf.path, sf.archive_path
+	`, lib.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "query unmatched files")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var suggestions []UnmatchedSuggestion
+	for rows.Next() {
+		var path string
+		var archivePath sql.NullString
+		var size int64
+		if err := rows.Scan(&path, &archivePath, &size); err != nil {
+			tracing.RecordError(span, err)
+			return nil, WrapDBError(err, "scan unmatched file")
+		}
+
+		display, name := path, filepath.Base(path)
+		if archivePath.Valid && archivePath.String != "" {
+			display = fmt.Sprintf("%s:%s", path, archivePath.String)
+			name = filepath.Base(archivePath.String)
+		}
+		suggestions = append(suggestions, UnmatchedSuggestion{
+			Path:       display,
+			Size:       size,
+			Candidates: suggestCandidates(entries, name, size, limit),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, WrapDBError(err, "query unmatched files")
+	}
+
+	return suggestions, nil
+}
+
+// loadSuggestEntries returns a system's ROM entries ordered by size.
+func (s *Scanner) loadSuggestEntries(ctx context.Context, systemID int64) ([]suggestEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.name, re.name, re.size
+		FROM rom_entries re
+		JOIN releases r ON r.id = re.release_id
+		WHERE r.system_id = ?
+		ORDER BY re.size, r.name, re.name
+	`, systemID)
+	if err != nil {
+		return nil, WrapDBError(err, "query rom entries")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []suggestEntry
+	for rows.Next() {
+		var e suggestEntry
+		var size sql.NullInt64
+		if err := rows.Scan(&e.release, &e.rom, &size); err != nil {
+			return nil, WrapDBError(err, "scan rom entry")
+		}
+		e.size = size.Int64
+		e.title = fuzzyTitle(e.release)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, WrapDBError(err, "query rom entries")
+	}
+	return entries, nil
+}
+
+// suggestCandidates picks the limit-1 releases closest to a file by title
+// and the ROM closest to it by size, or limit releases by title when the
+// closest in size is already among them. entries must be ordered by size.
+func suggestCandidates(entries []suggestEntry, name string, size int64, limit int) []MatchSuggestion {
+	if len(entries) == 0 {
+		return nil
+	}
+	title := fuzzyTitle(name)
+
+	// One candidate per release: its ROM closest to the file in size
+	byRelease := make(map[string]MatchSuggestion)
+	for _, e := range entries {
+		candidate := MatchSuggestion{
+			Release:   e.release,
+			ROM:       e.rom,
+			Size:      e.size,
+			SizeDelta: size - e.size,
+			Distance:  LevenshteinDistance(title, e.title),
+			Reason:    SuggestByName,
+		}
+		if prev, ok := byRelease[e.release]; !ok || absInt64(candidate.SizeDelta) < absInt64(prev.SizeDelta) {
+			byRelease[e.release] = candidate
+		}
+	}
+	byName := make([]MatchSuggestion, 0, len(byRelease))
+	for _, c := range byRelease {
+		byName = append(byName, c)
+	}
+	sort.Slice(byName, func(i, j int) bool {
+		a, b := byName[i], byName[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if absInt64(a.SizeDelta) != absInt64(b.SizeDelta) {
+			return absInt64(a.SizeDelta) < absInt64(b.SizeDelta)
+		}
+		return a.Release < b.Release
+	})
+
+	// The ROM closest in size; the smaller one wins a tie
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].size >= size })
+	if i == len(entries) || (i > 0 && size-entries[i-1].size <= entries[i].size-size) {
+		i--
+	}
+	closest := entries[i]
+
+	var candidates []MatchSuggestion
+	for _, c := range byName {
+		if len(candidates) == limit-1 {
+			break
+		}
+		candidates = append(candidates, c)
+	}
+	for _, c := range candidates {
+		if c.Release == closest.release {
+			// Already suggested by title, so take one more title instead
+			if len(byName) > len(candidates) {
+				candidates = append(candidates, byName[len(candidates)])
+			}
+			return candidates
+		}
+	}
+	return append(candidates, MatchSuggestion{
+		Release:   closest.release,
+		ROM:       closest.rom,
+		Size:      closest.size,
+		SizeDelta: size - closest.size,
+		Distance:  LevenshteinDistance(title, closest.title),
+		Reason:    SuggestBySize,
+	})
+}
+
+// absInt64 returns the absolute value of n.
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+func TestScanner_SuggestMatches(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+	conn := database.Conn()
+
+	root := t.TempDir()
+	files := map[string]int{
+		"Metroid Fusoin.nes": 40,   // Misspelled, and the size of Zelda
+		"dump001.nes":        1040, // Headered Castlevania: 16 bytes over
+	}
+	for name, size := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), make([]byte, size), 0o600))
+	}
+
+	_, err = conn.Exec(`INSERT INTO systems (id, name) VALUES (1, 'nes'), (2, 'snes')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO releases (id, system_id, name) VALUES
+		(1, 1, 'Legend of Zelda, The (USA)'), (2, 1, 'Metroid (USA)'),
+		(3, 1, 'Castlevania (USA)'), (4, 1, 'Mega Man (USA)'), (5, 2, 'Metroid Fusion (USA)')`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO rom_entries (id, release_id, name, size, sha1) VALUES
+		(1, 1, 'Legend of Zelda, The (USA).nes', 40, 'a'), (2, 2, 'Metroid (USA).nes', 24, 'b'),
+		(3, 3, 'Castlevania (USA).nes', 1024, 'c'), (4, 4, 'Mega Man (USA).nes', 60, 'd'),
+		(5, 5, 'Metroid Fusion (USA).sfc', 32, 'e')`)
+	require.NoError(t, err)
+
+	_, err = NewManager(conn).Add(ctx, "nes", root, "nes")
+	require.NoError(t, err)
+	scanner := NewScanner(conn)
+	_, err = scanner.Scan(ctx, "nes")
+	require.NoError(t, err)
+
+	suggestions, err := scanner.SuggestMatches(ctx, "nes", 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+
+	// Candidates come from the library's system only
+	fusion := suggestions[0]
+	assert.Equal(t, filepath.Join(root, "Metroid Fusoin.nes"), fusion.Path)
+	require.Len(t, fusion.Candidates, 3)
+	assert.Equal(t, "Metroid (USA)", fusion.Candidates[0].Release)
+	assert.Equal(t, SuggestByName, fusion.Candidates[0].Reason)
+	assert.Equal(t, 6, fusion.Candidates[0].Distance)
+	assert.Equal(t, MatchSuggestion{
+		Release: "Legend of Zelda, The (USA)",
+		ROM:     "Legend of Zelda, The (USA).nes",
+		Size:    40,
+		Distance: LevenshteinDistance(fuzzyTitle("Metroid Fusoin.nes"),
+			fuzzyTitle("Legend of Zelda, The (USA)")),
+		Reason: SuggestBySize,
+	}, fusion.Candidates[2])
+
+	dump := suggestions[1]
+	assert.Equal(t, int64(1040), dump.Size)
+	require.Len(t, dump.Candidates, 3)
+	last := dump.Candidates[2]
+	assert.Equal(t, "Castlevania (USA)", last.Release)
+	assert.Equal(t, SuggestBySize, last.Reason)
+	assert.Equal(t, int64(16), last.SizeDelta)
+
+	// With a limit of one only the closest size is suggested
+	suggestions, err = scanner.SuggestMatches(ctx, "nes", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []MatchSuggestion{{
+		Release:   "Castlevania (USA)",
+		ROM:       "Castlevania (USA).nes",
+		Size:      1024,
+		SizeDelta: 16,
+		Distance:  LevenshteinDistance(fuzzyTitle("dump001.nes"), fuzzyTitle("Castlevania (USA)")),
+		Reason:    SuggestBySize,
+	}}, suggestions[1].Candidates)
+
+	_, err = scanner.SuggestMatches(ctx, "nes", -1)
+	assert.ErrorIs(t, err, ErrInvalidArg)
+	_, err = scanner.SuggestMatches(ctx, "missing", 0)
+	assert.Error(t, err)
+}
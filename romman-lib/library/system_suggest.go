@@ -0,0 +1,84 @@
+package library
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minSuggestScore is the similarity below which no system is suggested.
+const minSuggestScore = 0.6
+
+// SystemSuggestion pairs an unrecognised directory with the closest known system.
+type SystemSuggestion struct {
+	Directory  string  `json:"directory"`
+	MappingKey string  `json:"mappingKey"`           // Key to use under directory_mappings
+	System     string  `json:"system,omitempty"`     // Empty when nothing is close enough
+	ClosestKey string  `json:"closestKey,omitempty"` // Known directory name the suggestion came from
+	Score      float64 `json:"score"`                // 0.0 to 1.0, higher is better
+}
+
+// SuggestSystem finds the directory mapping closest to dirName, for writing
+// new systems.yaml entries. mappings is a directory_mappings table such as
+// dat.LoadSystemMappings().DirectoryMappings.
+func SuggestSystem(dirName string, mappings map[string]string) SystemSuggestion {
+	key := normalizeDirName(dirName)
+	suggestion := SystemSuggestion{Directory: dirName, MappingKey: key}
+
+	// Sorted keys make ties resolve the same way on every run
+	keys := make([]string, 0, len(mappings))
+	for k := range mappings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		score := dirSimilarity(key, normalizeDirName(k))
+		if score > suggestion.Score {
+			suggestion.Score = score
+			suggestion.ClosestKey = k
+			suggestion.System = mappings[k]
+		}
+	}
+
+	if suggestion.Score < minSuggestScore {
+		suggestion.System = ""
+		suggestion.ClosestKey = ""
+	}
+	return suggestion
+}
+
+// normalizeDirName lowercases a directory name and drops separators, the
+// same form DetectSystemFromDirName looks up first.
+func normalizeDirName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// dirSimilarity scores two normalized names by edit distance. A known name
+// contained in the directory, as in "nesroms", scores by how much of the
+// directory it covers.
+func dirSimilarity(dir, known string) float64 {
+	if dir == "" || known == "" {
+		return 0
+	}
+
+	longest := len(dir)
+	if len(known) > longest {
+		longest = len(known)
+	}
+	score := 1 - float64(LevenshteinDistance(dir, known))/float64(longest)
+
+	// Very short names like "gb" appear inside too many unrelated words
+	if len(known) >= 3 && strings.Contains(dir, known) {
+		if contained := 0.5 + 0.5*float64(len(known))/float64(len(dir)); contained > score {
+			score = contained
+		}
+	}
+	return score
+}
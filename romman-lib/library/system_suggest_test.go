@@ -0,0 +1,51 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestSystem(t *testing.T) {
+	mappings := map[string]string{
+		"nes":          "nes",
+		"snes":         "snes",
+		"gameboy":      "gb",
+		"gb":           "gb",
+		"megadrive":    "md",
+		"mastersystem": "sms",
+	}
+
+	tests := []struct {
+		dir    string
+		key    string
+		system string
+	}{
+		{"Game Boy (Misc)", "gameboymisc", "gb"},
+		{"Mega_Driv", "megadriv", "md"},
+		{"SNES-roms", "snesroms", "snes"},
+		{"Master System", "mastersystem", "sms"},
+		{"Documents", "documents", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			s := SuggestSystem(tt.dir, mappings)
+			assert.Equal(t, tt.dir, s.Directory)
+			assert.Equal(t, tt.key, s.MappingKey)
+			assert.Equal(t, tt.system, s.System)
+			if tt.system == "" {
+				assert.Empty(t, s.ClosestKey)
+				assert.Less(t, s.Score, minSuggestScore)
+			} else {
+				assert.GreaterOrEqual(t, s.Score, minSuggestScore)
+			}
+		})
+	}
+}
+
+func TestSuggestSystem_ShortKeysNeedCloseMatch(t *testing.T) {
+	// "gb" is inside "rugby" but the directory is nothing like a Game Boy folder
+	s := SuggestSystem("rugby", map[string]string{"gb": "gb"})
+	assert.Empty(t, s.System)
+}
@@ -0,0 +1,160 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Well-known file tags. Other tags are stored as free-form labels.
+const (
+	TagKeep    = "keep"    // Never quarantine this file
+	TagDelete  = "delete"  // Quarantine this file on the next cleanup
+	TagReplace = "replace" // Keep for now, but prefer any other copy
+)
+
+// FileTag is a user tag on a library file. Tags are keyed by path and archive
+// path rather than scanned file ID, so they survive rescans and match rebuilds.
+type FileTag struct {
+	LibraryID   int64     `json:"libraryId"`
+	Path        string    `json:"path"`
+	ArchivePath string    `json:"archivePath,omitempty"`
+	Tag         string    `json:"tag"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// TagManager manages user tags on library files.
+type TagManager struct {
+	db      *sql.DB
+	manager *Manager
+}
+
+// NewTagManager creates a new tag manager.
+func NewTagManager(db *sql.DB) *TagManager {
+	return &TagManager{db: db, manager: NewManager(db)}
+}
+
+// normalizeTag lowercases and validates a tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || strings.ContainsAny(tag, ", ") {
+		return "", fmt.Errorf("%w: tag must be a single non-empty word", ErrInvalidArg)
+	}
+	return tag, nil
+}
+
+// Add tags a file in a library. Tagging a file twice with the same tag is a no-op.
+func (t *TagManager) Add(ctx context.Context, libraryName, path, archivePath, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	lib, err := t.manager.Get(ctx, libraryName)
+	if err != nil {
+		return err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	_, err = t.db.ExecContext(ctx, `
+		INSERT INTO file_tags (library_id, path, archive_path, tag)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(library_id, path, archive_path, tag) DO NOTHING
+	`, lib.ID, absPath, archivePath, tag)
+	if err != nil {
+		return WrapDBError(err, "add tag")
+	}
+	return nil
+}
+
+// Remove removes a tag from a file in a library.
+func (t *TagManager) Remove(ctx context.Context, libraryName, path, archivePath, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	lib, err := t.manager.Get(ctx, libraryName)
+	if err != nil {
+		return err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	result, err := t.db.ExecContext(ctx, `
+		DELETE FROM file_tags
+		WHERE library_id = ? AND path = ? AND archive_path = ? AND tag = ?
+	`, lib.ID, absPath, archivePath, tag)
+	if err != nil {
+		return WrapDBError(err, "remove tag")
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return NotFoundError("tag", tag)
+	}
+	return nil
+}
+
+// List returns the tags in a library, optionally filtered to a single tag.
+func (t *TagManager) List(ctx context.Context, libraryName, tag string) ([]FileTag, error) {
+	lib, err := t.manager.Get(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT library_id, path, archive_path, tag, created_at
+		FROM file_tags WHERE library_id = ?`
+	args := []interface{}{lib.ID}
+	if tag != "" {
+		if tag, err = normalizeTag(tag); err != nil {
+			return nil, err
+		}
+		query += ` AND tag = ?`
+		args = append(args, tag)
+	}
+	query += ` ORDER BY path, archive_path, tag`
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, WrapDBError(err, "list tags")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tags []FileTag
+	for rows.Next() {
+		var ft FileTag
+		if err := rows.Scan(&ft.LibraryID, &ft.Path, &ft.ArchivePath, &ft.Tag, &ft.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, ft)
+	}
+	return tags, rows.Err()
+}
+
+// fileTagsColumn selects a scanned file's tags as a comma-separated list.
+// The enclosing query must alias scanned_files as sf.
+const fileTagsColumn = `COALESCE((
+	SELECT GROUP_CONCAT(ft.tag) FROM file_tags ft
+	WHERE ft.library_id = sf.library_id AND ft.path = sf.path
+	  AND ft.archive_path = COALESCE(sf.archive_path, '')
+), '')`
+
+// hasTag reports whether a comma-separated tag list contains tag.
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagManager_AddListRemove(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 2)
+	ctx := context.Background()
+	tags := NewTagManager(database.Conn())
+
+	path := filepath.Join(libPath, "game00.nes")
+	require.NoError(t, tags.Add(ctx, "test-lib", path, "", "Keep"))
+	require.NoError(t, tags.Add(ctx, "test-lib", path, "", "keep")) // idempotent
+	require.NoError(t, tags.Add(ctx, "test-lib", path, "", "needs-patch"))
+
+	all, err := tags.List(ctx, "test-lib", "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "keep", all[0].Tag)
+	assert.Equal(t, "needs-patch", all[1].Tag)
+
+	keep, err := tags.List(ctx, "test-lib", TagKeep)
+	require.NoError(t, err)
+	require.Len(t, keep, 1)
+	assert.Equal(t, path, keep[0].Path)
+
+	require.NoError(t, tags.Remove(ctx, "test-lib", path, "", "needs-patch"))
+	err = tags.Remove(ctx, "test-lib", path, "", "needs-patch")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, tags.Add(ctx, "test-lib", path, "", " "), ErrInvalidArg)
+	assert.Error(t, tags.Add(ctx, "no-such-lib", path, "", TagKeep))
+}
+
+func TestCleanupPlan_RespectsTagsAcrossRescans(t *testing.T) {
+	database, libPath := setupCheckpointLibrary(t, 3)
+	ctx := context.Background()
+	conn := database.Conn()
+
+	// An exact duplicate of game00 in a deeper directory, which would normally lose
+	original := filepath.Join(libPath, "game00.nes")
+	copyDir := filepath.Join(libPath, "keepers", "nes")
+	require.NoError(t, os.MkdirAll(copyDir, 0755)) // #nosec G301
+	data, err := os.ReadFile(original)             // #nosec G304
+	require.NoError(t, err)
+	copyPath := filepath.Join(copyDir, "game00.nes")
+	require.NoError(t, os.WriteFile(copyPath, data, 0644)) // #nosec G306
+
+	tags := NewTagManager(conn)
+	require.NoError(t, tags.Add(ctx, "test-lib", copyPath, "", TagKeep))
+	deletePath := filepath.Join(libPath, "game02.nes")
+	require.NoError(t, tags.Add(ctx, "test-lib", deletePath, "", TagDelete))
+
+	// Scan twice: the full rescan rebuilds every match, tags must still apply
+	for i := 0; i < 2; i++ {
+		_, err = NewScanner(conn).Scan(ctx, "test-lib")
+		require.NoError(t, err)
+	}
+
+	planner := NewCleanupPlanner(NewDuplicateFinder(conn), NewManager(conn))
+	plan, err := planner.GeneratePlan(ctx, "test-lib", filepath.Join(t.TempDir(), "quarantine"))
+	require.NoError(t, err)
+
+	actions := make(map[string]CleanupAction)
+	for _, a := range plan.Actions {
+		actions[a.SourcePath] = a
+	}
+
+	assert.Equal(t, ActionIgnore, actions[copyPath].Action)
+	assert.Equal(t, "tagged keep", actions[copyPath].Reason)
+	assert.Equal(t, ActionMove, actions[original].Action)
+	assert.Equal(t, ActionMove, actions[deletePath].Action)
+	assert.Equal(t, "tagged delete", actions[deletePath].Reason)
+	assert.NotContains(t, actions, filepath.Join(libPath, "game01.nes"))
+	assert.Equal(t, plan.Summary.MoveCount+plan.Summary.IgnoreCount, plan.Summary.TotalActions)
+}
@@ -0,0 +1,397 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const screenScraperAPI = "https://api.screenscraper.fr/api2"
+
+// ScreenScraperConfig holds ScreenScraper.fr API credentials. The developer
+// credentials are required; a member account raises the rate limits.
+type ScreenScraperConfig struct {
+	DevID       string
+	DevPassword string
+	SoftName    string // Software name sent with each request (default "romman")
+	Username    string
+	Password    string
+}
+
+// ScreenScraperProvider implements the Provider and HashProvider interfaces
+// for ScreenScraper.fr. Requests are throttled to the thread and per-minute
+// limits the API reports for the account.
+type ScreenScraperProvider struct {
+	cfg     ScreenScraperConfig
+	baseURL string
+	client  *http.Client
+
+	mu          sync.Mutex
+	threads     chan struct{}
+	maxThreads  int
+	minInterval time.Duration
+	next        time.Time
+}
+
+// NewScreenScraperProvider creates a new ScreenScraper provider.
+func NewScreenScraperProvider(cfg ScreenScraperConfig) (*ScreenScraperProvider, error) {
+	if cfg.DevID == "" || cfg.DevPassword == "" {
+		return nil, fmt.Errorf("ScreenScraper developer ID and password are required")
+	}
+	if cfg.SoftName == "" {
+		cfg.SoftName = "romman"
+	}
+	return &ScreenScraperProvider{
+		cfg:     cfg,
+		baseURL: screenScraperAPI,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		// One thread at a request a second until the API reports the account's limits
+		threads:     make(chan struct{}, 1),
+		maxThreads:  1,
+		minInterval: time.Second,
+	}, nil
+}
+
+func (p *ScreenScraperProvider) Name() string {
+	return "screenscraper"
+}
+
+func (p *ScreenScraperProvider) Search(query string) ([]GameMetadata, error) {
+	var resp ssResponse
+	found, err := p.get("jeuRecherche.php", url.Values{"recherche": {query}}, &resp)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	results := make([]GameMetadata, 0, len(resp.Response.Games))
+	for _, g := range resp.Response.Games {
+		// No results come back as a single game without an ID
+		if g.ID == "" || g.ID == "0" {
+			continue
+		}
+		results = append(results, p.convertGame(g))
+	}
+	return results, nil
+}
+
+func (p *ScreenScraperProvider) GetDetails(id string) (*GameMetadata, error) {
+	// Parse ID (format: "screenscraper:12345")
+	gameID, ok := strings.CutPrefix(id, "screenscraper:")
+	if !ok || gameID == "" {
+		return nil, fmt.Errorf("invalid ScreenScraper ID: %s", id)
+	}
+
+	var resp ssResponse
+	found, err := p.get("jeuInfos.php", url.Values{"gameid": {gameID}}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !found || resp.Response.Game == nil {
+		return nil, fmt.Errorf("game %s not found", id)
+	}
+	md := p.convertGame(*resp.Response.Game)
+	return &md, nil
+}
+
+// LookupHash identifies a game by a ROM's hashes, name and size.
+func (p *ScreenScraperProvider) LookupHash(rom RomHashes) (*GameMetadata, error) {
+	params := url.Values{
+		"romtype":   {"rom"},
+		"romnom":    {rom.Name},
+		"romtaille": {strconv.FormatInt(rom.Size, 10)},
+	}
+	if rom.CRC32 != "" {
+		params.Set("crc", strings.ToUpper(rom.CRC32))
+	}
+	if rom.MD5 != "" {
+		params.Set("md5", rom.MD5)
+	}
+	if rom.SHA1 != "" {
+		params.Set("sha1", rom.SHA1)
+	}
+	if systemID, ok := screenScraperSystems[rom.System]; ok {
+		params.Set("systemeid", strconv.Itoa(systemID))
+	}
+
+	var resp ssResponse
+	found, err := p.get("jeuInfos.php", params, &resp)
+	if err != nil || !found || resp.Response.Game == nil {
+		return nil, err
+	}
+	md := p.convertGame(*resp.Response.Game)
+	return &md, nil
+}
+
+// FetchMedia downloads a media URL returned by this provider, adding back the
+// credentials that were left out of it.
+func (p *ScreenScraperProvider) FetchMedia(mediaURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(mediaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media URL: %w", err)
+	}
+	q := u.Query()
+	for k, v := range p.credentials() {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	release := p.acquire()
+	resp, err := p.client.Get(u.String())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		release()
+		return nil, screenScraperError(resp.StatusCode, resp.Status)
+	}
+	return &releasingBody{ReadCloser: resp.Body, release: release}, nil
+}
+
+// get calls an API endpoint and decodes its JSON response into out. found is
+// false when the API reports no matching game.
+func (p *ScreenScraperProvider) get(endpoint string, params url.Values, out *ssResponse) (found bool, err error) {
+	for k, v := range p.credentials() {
+		params[k] = v
+	}
+	params.Set("output", "json")
+
+	defer p.acquire()()
+
+	resp, err := p.client.Get(p.baseURL + "/" + endpoint + "?" + params.Encode())
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, screenScraperError(resp.StatusCode, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode ScreenScraper response: %w", err)
+	}
+	p.updateLimits(out.Response.User)
+	return true, nil
+}
+
+// credentials returns the query parameters that authenticate a request.
+func (p *ScreenScraperProvider) credentials() url.Values {
+	creds := url.Values{
+		"devid":       {p.cfg.DevID},
+		"devpassword": {p.cfg.DevPassword},
+		"softname":    {p.cfg.SoftName},
+	}
+	if p.cfg.Username != "" {
+		creds.Set("ssid", p.cfg.Username)
+		creds.Set("sspassword", p.cfg.Password)
+	}
+	return creds
+}
+
+// acquire waits for a free thread and the next request slot. The returned
+// function frees the thread.
+func (p *ScreenScraperProvider) acquire() func() {
+	p.mu.Lock()
+	threads := p.threads
+	p.mu.Unlock()
+	threads <- struct{}{}
+
+	p.mu.Lock()
+	wait := time.Until(p.next)
+	start := time.Now()
+	if wait > 0 {
+		start = p.next
+	}
+	p.next = start.Add(p.minInterval)
+	p.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return func() { <-threads }
+}
+
+// updateLimits applies the thread and request limits the API reported for
+// the account.
+func (p *ScreenScraperProvider) updateLimits(user *ssUser) {
+	if user == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if perMin, err := strconv.Atoi(user.MaxRequestsPerMin); err == nil && perMin > 0 {
+		p.minInterval = time.Minute / time.Duration(perMin)
+	}
+	if maxThreads, err := strconv.Atoi(user.MaxThreads); err == nil && maxThreads > 0 && maxThreads != p.maxThreads {
+		// Requests in flight release their slot in the old channel
+		p.threads = make(chan struct{}, maxThreads)
+		p.maxThreads = maxThreads
+	}
+}
+
+// screenScraperError explains the API's error status codes.
+func screenScraperError(code int, status string) error {
+	switch code {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("ScreenScraper API is closed to non-members, try again later")
+	case http.StatusForbidden:
+		return fmt.Errorf("ScreenScraper rejected the developer credentials")
+	case 423:
+		return fmt.Errorf("ScreenScraper API is fully closed, try again later")
+	case 426:
+		return fmt.Errorf("ScreenScraper has blocked this software")
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("ScreenScraper thread limit reached")
+	case 430:
+		return fmt.Errorf("ScreenScraper daily request quota exceeded")
+	case 431:
+		return fmt.Errorf("ScreenScraper daily quota of unrecognised ROMs exceeded")
+	}
+	return fmt.Errorf("unexpected status: %s", status)
+}
+
+func (p *ScreenScraperProvider) convertGame(g ssGame) GameMetadata {
+	md := GameMetadata{
+		ID:          "screenscraper:" + g.ID,
+		Description: pickText(g.Synopsis, "langue", "en"),
+		ReleaseDate: pickText(g.Dates, "region", "wor", "us", "eu", "jp"),
+		Developer:   g.Developer.Text,
+		Publisher:   g.Publisher.Text,
+	}
+	if len(g.Genres) > 0 {
+		md.Genre = pickText(g.Genres[0].Names, "langue", "en")
+	}
+	if note, err := strconv.ParseFloat(g.Rating.Text, 64); err == nil {
+		md.Rating = note * 5 // ScreenScraper rates out of 20
+	}
+
+	for _, region := range []string{"wor", "us", "eu", "ss", "jp", ""} {
+		for _, m := range g.Media {
+			if m.Type == "box-2D" && (region == "" || m.Region == region) {
+				md.BoxartURL = stripCredentials(m.URL)
+				return md
+			}
+		}
+	}
+	return md
+}
+
+// stripCredentials removes the account credentials ScreenScraper embeds in
+// media URLs so they are not stored; FetchMedia adds them back.
+func stripCredentials(mediaURL string) string {
+	u, err := url.Parse(mediaURL)
+	if err != nil {
+		return mediaURL
+	}
+	q := u.Query()
+	for _, k := range []string{"devid", "devpassword", "softname", "ssid", "sspassword"} {
+		q.Del(k)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// pickText returns the text of the first entry whose key field matches the
+// earliest preference, or the first entry when none does.
+func pickText(entries []ssText, key string, prefs ...string) string {
+	for _, pref := range prefs {
+		for _, e := range entries {
+			if e.key(key) == pref {
+				return e.Text
+			}
+		}
+	}
+	if len(entries) > 0 {
+		return entries[0].Text
+	}
+	return ""
+}
+
+// releasingBody frees a request thread when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+type ssResponse struct {
+	Response struct {
+		User  *ssUser  `json:"ssuser"`
+		Game  *ssGame  `json:"jeu"`
+		Games []ssGame `json:"jeux"`
+	} `json:"response"`
+}
+
+type ssUser struct {
+	MaxThreads        string `json:"maxthreads"`
+	MaxRequestsPerMin string `json:"maxrequestspermin"`
+}
+
+type ssGame struct {
+	ID        string    `json:"id"`
+	Synopsis  []ssText  `json:"synopsis"`
+	Dates     []ssText  `json:"dates"`
+	Developer ssText    `json:"developpeur"`
+	Publisher ssText    `json:"editeur"`
+	Rating    ssText    `json:"note"`
+	Genres    []ssGenre `json:"genres"`
+	Media     []ssMedia `json:"medias"`
+}
+
+type ssGenre struct {
+	Names []ssText `json:"noms"`
+}
+
+type ssText struct {
+	Language string `json:"langue"`
+	Region   string `json:"region"`
+	Text     string `json:"text"`
+}
+
+func (t ssText) key(field string) string {
+	if field == "langue" {
+		return t.Language
+	}
+	return t.Region
+}
+
+type ssMedia struct {
+	Type   string `json:"type"`
+	Region string `json:"region"`
+	URL    string `json:"url"`
+}
+
+// screenScraperSystems maps system names to ScreenScraper system IDs, which
+// narrow hash lookups.
+var screenScraperSystems = map[string]int{
+	"Sega - Mega Drive - Genesis":                    1,
+	"Sega - Master System - Mark III":                2,
+	"Nintendo - Nintendo Entertainment System":       3,
+	"Nintendo - Super Nintendo Entertainment System": 4,
+	"Nintendo - Game Boy":                            9,
+	"Nintendo - Game Boy Color":                      10,
+	"Nintendo - Game Boy Advance":                    12,
+	"Nintendo - Nintendo 64":                         14,
+	"Nintendo - Nintendo DS":                         15,
+	"Sega - Game Gear":                               21,
+	"Atari - 2600":                                   26,
+	"Sony - PlayStation":                             57,
+	"Sony - PlayStation Portable":                    61,
+}
@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ssGameJSON = `{"response": {
+	"ssuser": {"maxthreads": "2", "maxrequestspermin": "120"},
+	"jeu": {
+		"id": "3",
+		"synopsis": [{"langue": "fr", "text": "Un plombier"}, {"langue": "en", "text": "A plumber"}],
+		"dates": [{"region": "jp", "text": "1985-09-13"}, {"region": "us", "text": "1985-10-18"}],
+		"developpeur": {"text": "Nintendo EAD"},
+		"editeur": {"text": "Nintendo"},
+		"note": {"text": "18"},
+		"genres": [{"id": "7", "noms": [{"langue": "de", "text": "Plattform"}, {"langue": "en", "text": "Platform"}]}],
+		"medias": [
+			{"type": "box-2D", "region": "jp", "url": "MEDIA/mediaJeu.php?devid=d&devpassword=p&softname=romman&jeuid=3&media=box-2D(jp)"},
+			{"type": "box-2D", "region": "us", "url": "MEDIA/mediaJeu.php?devid=d&devpassword=p&softname=romman&jeuid=3&media=box-2D(us)"}
+		]
+	}
+}}`
+
+func newTestScreenScraper(t *testing.T, handler http.HandlerFunc) *ScreenScraperProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p, err := NewScreenScraperProvider(ScreenScraperConfig{DevID: "d", DevPassword: "p", Username: "u", Password: "pw"})
+	require.NoError(t, err)
+	p.baseURL = server.URL
+	p.minInterval = 0
+	return p
+}
+
+func TestNewScreenScraperProvider_RequiresDevCredentials(t *testing.T) {
+	_, err := NewScreenScraperProvider(ScreenScraperConfig{Username: "u", Password: "pw"})
+	assert.Error(t, err)
+}
+
+func TestScreenScraper_LookupHash(t *testing.T) {
+	var query map[string]string
+	p := newTestScreenScraper(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/jeuInfos.php", r.URL.Path)
+		query = map[string]string{}
+		for k, v := range r.URL.Query() {
+			query[k] = v[0]
+		}
+		_, _ = io.WriteString(w, ssGameJSON)
+	})
+
+	md, err := p.LookupHash(RomHashes{
+		Name:   "Super Mario Bros. (World).nes",
+		Size:   40976,
+		CRC32:  "3337ec46",
+		SHA1:   "ea343f4e445a9050d4b4fbac2c77d0693b1d0922",
+		System: "Nintendo - Nintendo Entertainment System",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, md)
+
+	assert.Equal(t, "3337EC46", query["crc"])
+	assert.Equal(t, "ea343f4e445a9050d4b4fbac2c77d0693b1d0922", query["sha1"])
+	assert.Equal(t, "40976", query["romtaille"])
+	assert.Equal(t, "3", query["systemeid"])
+	assert.Equal(t, "d", query["devid"])
+	assert.Equal(t, "u", query["ssid"])
+	assert.Equal(t, "json", query["output"])
+
+	assert.Equal(t, "screenscraper:3", md.ID)
+	assert.Equal(t, "A plumber", md.Description)
+	assert.Equal(t, "1985-10-18", md.ReleaseDate)
+	assert.Equal(t, "Nintendo EAD", md.Developer)
+	assert.Equal(t, "Nintendo", md.Publisher)
+	assert.Equal(t, "Platform", md.Genre)
+	assert.Equal(t, 90.0, md.Rating)
+	// Credentials are kept out of the stored URL
+	assert.Equal(t, "MEDIA/mediaJeu.php?jeuid=3&media=box-2D%28us%29", md.BoxartURL)
+
+	// The account's limits replace the defaults
+	assert.Equal(t, 2, p.maxThreads)
+	assert.Equal(t, 500*time.Millisecond, p.minInterval)
+}
+
+func TestScreenScraper_LookupHashNotFound(t *testing.T) {
+	p := newTestScreenScraper(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Erreur : Rom/Iso/Dossier non trouvée !", http.StatusNotFound)
+	})
+
+	md, err := p.LookupHash(RomHashes{Name: "Homebrew.nes", CRC32: "deadbeef"})
+	assert.NoError(t, err)
+	assert.Nil(t, md)
+}
+
+func TestScreenScraper_QuotaExceeded(t *testing.T) {
+	p := newTestScreenScraper(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(430)
+	})
+
+	_, err := p.Search("Tetris")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quota")
+}
+
+func TestScreenScraper_SearchSkipsEmptyResult(t *testing.T) {
+	p := newTestScreenScraper(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/jeuRecherche.php", r.URL.Path)
+		assert.Equal(t, "Nothing", r.URL.Query().Get("recherche"))
+		_, _ = io.WriteString(w, `{"response": {"jeux": [{}]}}`)
+	})
+
+	results, err := p.Search("Nothing")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestScreenScraper_FetchMediaAddsCredentials(t *testing.T) {
+	p := newTestScreenScraper(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "d", r.URL.Query().Get("devid"))
+		assert.Equal(t, "pw", r.URL.Query().Get("sspassword"))
+		assert.Equal(t, "box-2D(us)", r.URL.Query().Get("media"))
+		_, _ = io.WriteString(w, "image")
+	})
+
+	rc, err := p.FetchMedia(p.baseURL + "/mediaJeu.php?jeuid=3&media=box-2D%28us%29")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "image", string(data))
+
+	// The request's thread is free again
+	assert.Empty(t, p.threads)
+}
@@ -23,25 +23,47 @@ func NewService(d *db.DB, p Provider, mediaRoot string) *Service {
 	return &Service{db: d, provider: p, mediaRoot: mediaRoot}
 }
 
-// ScrapeGame searches for a game, fetches metadata, and downloads media.
+// ScrapeGame fetches metadata and downloads media for a release. Providers
+// that support it look the release up by its matched files' hashes first,
+// then by its name within its system; otherwise the game is searched for by
+// name.
 func (s *Service) ScrapeGame(ctx context.Context, releaseID int64, gameName string) error {
-	// 1. Search
-	results, err := s.provider.Search(gameName)
+	// 1. Look up by hash
+	details, err := s.lookupByHash(ctx, releaseID)
 	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
+		return fmt.Errorf("hash lookup failed: %w", err)
 	}
-	if len(results) == 0 {
-		return fmt.Errorf("no results found for %q", gameName)
+
+	if rp, ok := s.provider.(ReleaseProvider); ok && details == nil {
+		sysName, err := s.db.GetSystemNameForRelease(ctx, releaseID)
+		if err != nil {
+			return fmt.Errorf("failed to get system name: %w", err)
+		}
+		details, err = rp.LookupRelease(gameName, sysName)
+		if err != nil {
+			return fmt.Errorf("release lookup failed: %w", err)
+		}
 	}
 
-	// Pick first match for now
-	// TODO: Add fuzzy matching or user selection logic
-	best := results[0]
+	if details == nil {
+		// 2. Search
+		results, err := s.provider.Search(gameName)
+		if err != nil {
+			return fmt.Errorf("search failed: %w", err)
+		}
+		if len(results) == 0 {
+			return fmt.Errorf("no results found for %q", gameName)
+		}
 
-	// 2. Get Details
-	details, err := s.provider.GetDetails(best.ID)
-	if err != nil {
-		return fmt.Errorf("failed to get details: %w", err)
+		// Pick first match for now
+		// TODO: Add fuzzy matching or user selection logic
+		best := results[0]
+
+		// Get Details
+		details, err = s.provider.GetDetails(best.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get details: %w", err)
+		}
 	}
 
 	// 3. Save Metadata
@@ -52,6 +74,7 @@ func (s *Service) ScrapeGame(ctx context.Context, releaseID int64, gameName stri
 		ReleaseDate: details.ReleaseDate,
 		Developer:   details.Developer,
 		Publisher:   details.Publisher,
+		Genre:       details.Genre,
 		Rating:      details.Rating,
 	})
 	if err != nil {
@@ -79,23 +102,85 @@ func (s *Service) ScrapeGame(ctx context.Context, releaseID int64, gameName stri
 		}
 	}
 
+	// 5. Record local media
+	for mediaType, localPath := range details.Media {
+		if err := s.db.AddGameMedia(ctx, releaseID, mediaType, "", localPath); err != nil {
+			return fmt.Errorf("failed to save media record: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (s *Service) downloadFile(url, dest string) error {
-	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil { //nolint:gosec // Standard dir permissions
-		return err
+// lookupByHash asks a HashProvider for the game each of the release's
+// matched files belongs to, returning the first hit, or nil when the provider
+// cannot look up hashes or knows none of the files.
+func (s *Service) lookupByHash(ctx context.Context, releaseID int64) (*GameMetadata, error) {
+	hp, ok := s.provider.(HashProvider)
+	if !ok {
+		return nil, nil
 	}
 
-	resp, err := http.Get(url) //nolint:gosec // URL from trusted IGDB API
+	rows, err := s.db.Conn().QueryContext(ctx, `
+		SELECT DISTINCT re.name, sf.size, COALESCE(sf.crc32, ''), sf.md5, COALESCE(sf.sha1, ''), sys.name
+		FROM rom_entries re
+		JOIN matches m ON m.rom_entry_id = re.id
+		JOIN scanned_files sf ON sf.id = m.scanned_file_id
+		JOIN releases r ON r.id = re.release_id
+		JOIN systems sys ON sys.id = r.system_id
+		WHERE re.release_id = ?
+		ORDER BY re.name
+	`, releaseID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to query matched files: %w", err)
+	}
+	var roms []RomHashes
+	for rows.Next() {
+		var rom RomHashes
+		if err := rows.Scan(&rom.Name, &rom.Size, &rom.CRC32, &rom.MD5, &rom.SHA1, &rom.System); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan matched file: %w", err)
+		}
+		roms = append(roms, rom)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, rom := range roms {
+		md, err := hp.LookupHash(rom)
+		if err != nil || md != nil {
+			return md, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *Service) downloadFile(url, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil { //nolint:gosec // Standard dir permissions
 		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http error: %s", resp.Status)
+	var body io.ReadCloser
+	if fetcher, ok := s.provider.(MediaFetcher); ok {
+		rc, err := fetcher.FetchMedia(url)
+		if err != nil {
+			return err
+		}
+		body = rc
+	} else {
+		resp, err := http.Get(url) //nolint:gosec // URL from trusted IGDB API
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return fmt.Errorf("http error: %s", resp.Status)
+		}
+		body = resp.Body
 	}
+	defer func() { _ = body.Close() }()
 
 	f, err := os.Create(dest) //nolint:gosec // Path validated upstream
 	if err != nil {
@@ -103,6 +188,6 @@ func (s *Service) downloadFile(url, dest string) error {
 	}
 	defer func() { _ = f.Close() }()
 
-	_, err = io.Copy(f, resp.Body)
+	_, err = io.Copy(f, body)
 	return err
 }
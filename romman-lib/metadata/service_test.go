@@ -74,3 +74,55 @@ func TestScrapeGame(t *testing.T) {
 	assert.Equal(t, "Nintendo", md.Developer)
 	assert.Equal(t, 95.5, md.Rating)
 }
+
+type MockHashProvider struct {
+	MockProvider
+}
+
+func (m *MockHashProvider) LookupHash(rom RomHashes) (*GameMetadata, error) {
+	args := m.Called(rom)
+	md, _ := args.Get(0).(*GameMetadata)
+	return md, args.Error(1)
+}
+
+func TestScrapeGame_HashLookup(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"INSERT INTO systems (id, name) VALUES (1, 'nes')",
+		"INSERT INTO releases (id, system_id, name) VALUES (100, 1, 'Super Mario Bros (World)')",
+		"INSERT INTO releases (id, system_id, name) VALUES (101, 1, 'Homebrew')",
+		"INSERT INTO rom_entries (id, release_id, name, size, sha1, crc32) VALUES (1, 100, 'Super Mario Bros (World).nes', 40976, 'abc', '3337ec46')",
+		"INSERT INTO libraries (id, name, root_path, system_id) VALUES (1, 'nes', '/roms', 1)",
+		"INSERT INTO scanned_files (id, library_id, path, size, mtime, sha1, crc32, md5) VALUES (1, 1, '/roms/smb.nes', 40976, 0, 'abc', '3337ec46', 'def')",
+		"INSERT INTO matches (scanned_file_id, rom_entry_id, match_type) VALUES (1, 1, 'sha1')",
+	} {
+		_, err = database.Conn().Exec(stmt)
+		assert.NoError(t, err)
+	}
+
+	provider := new(MockHashProvider)
+	provider.On("LookupHash", RomHashes{
+		Name: "Super Mario Bros (World).nes", Size: 40976, CRC32: "3337ec46", MD5: "def", SHA1: "abc", System: "nes",
+	}).Return(&GameMetadata{ID: "screenscraper:3", Developer: "Nintendo"}, nil)
+	provider.On("Search", "Homebrew").Return([]GameMetadata{{ID: "screenscraper:9"}}, nil)
+	provider.On("GetDetails", "screenscraper:9").Return(&GameMetadata{ID: "screenscraper:9"}, nil)
+
+	service := NewService(database, provider, t.TempDir())
+
+	// A matched file is looked up by hash without searching
+	assert.NoError(t, service.ScrapeGame(ctx, 100, "Super Mario Bros (World)"))
+	md, err := database.GetGameMetadata(ctx, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "screenscraper:3", md.ProviderID)
+	provider.AssertNotCalled(t, "Search", "Super Mario Bros (World)")
+
+	// A release without matched files falls back to the name search
+	assert.NoError(t, service.ScrapeGame(ctx, 101, "Homebrew"))
+	md, err = database.GetGameMetadata(ctx, 101)
+	assert.NoError(t, err)
+	assert.Equal(t, "screenscraper:9", md.ProviderID)
+}
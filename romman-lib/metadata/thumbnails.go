@@ -0,0 +1,247 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ryanm101/romman-lib/library"
+)
+
+// thumbnailKinds maps the folders of a RetroArch thumbnails pack to
+// game_media types.
+var thumbnailKinds = []struct {
+	dir       string
+	mediaType string
+}{
+	{"Named_Boxarts", "boxart"},
+	{"Named_Titles", "title"},
+	{"Named_Snaps", "screenshot"},
+}
+
+// thumbnailReplacer applies RetroArch's substitutions for characters that
+// cannot appear in thumbnail filenames.
+var thumbnailReplacer = strings.NewReplacer(
+	"&", "_", "*", "_", "/", "_", ":", "_", "`", "_",
+	"<", "_", ">", "_", "?", "_", "\\", "_", "|", "_", "\"", "_",
+)
+
+// ThumbnailsProvider implements the Provider and ReleaseProvider interfaces
+// for a local RetroArch thumbnails pack, a directory holding a folder per
+// system (e.g. "Nintendo - Nintendo Entertainment System") with Named_Boxarts,
+// Named_Titles and Named_Snaps PNGs named after the release. It reads no
+// metadata, only the paths of the images, which are recorded as they are.
+type ThumbnailsProvider struct {
+	root string
+
+	mu      sync.Mutex
+	indexes map[string]*thumbnailIndex // By system folder
+}
+
+// thumbnailIndex lists one system folder's images by release name and by
+// normalized title, for each media type.
+type thumbnailIndex struct {
+	exact      map[string]map[string]string // media type -> file name without .png -> path
+	normalized map[string]map[string]string // media type -> normalized title -> path
+}
+
+// NewThumbnailsProvider creates a provider reading the thumbnails pack at root.
+func NewThumbnailsProvider(root string) (*ThumbnailsProvider, error) {
+	if root == "" {
+		return nil, fmt.Errorf("thumbnails directory is required")
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open thumbnails directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &ThumbnailsProvider{root: root, indexes: make(map[string]*thumbnailIndex)}, nil
+}
+
+func (p *ThumbnailsProvider) Name() string {
+	return "thumbnails"
+}
+
+// Search finds releases with images in any system folder.
+func (p *ThumbnailsProvider) Search(query string) ([]GameMetadata, error) {
+	systems, err := p.systemDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []GameMetadata
+	for _, system := range systems {
+		md, err := p.lookup(system, query)
+		if err != nil {
+			return nil, err
+		}
+		if md != nil {
+			results = append(results, *md)
+		}
+	}
+	return results, nil
+}
+
+func (p *ThumbnailsProvider) GetDetails(id string) (*GameMetadata, error) {
+	// Parse ID (format: "thumbnails:<system folder>/<release name>")
+	rest, ok := strings.CutPrefix(id, "thumbnails:")
+	system, name, found := strings.Cut(rest, "/")
+	if !ok || !found {
+		return nil, fmt.Errorf("invalid thumbnails ID: %s", id)
+	}
+
+	md, err := p.lookup(system, name)
+	if err != nil {
+		return nil, err
+	}
+	if md == nil {
+		return nil, fmt.Errorf("no thumbnails found for %s", id)
+	}
+	return md, nil
+}
+
+// LookupRelease finds the images for a release in the system's folder.
+func (p *ThumbnailsProvider) LookupRelease(name, system string) (*GameMetadata, error) {
+	dir, err := p.systemDir(system)
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	return p.lookup(dir, name)
+}
+
+// lookup finds a release's images in a system folder, by its exact name
+// first and then by normalized title.
+func (p *ThumbnailsProvider) lookup(system, name string) (*GameMetadata, error) {
+	idx, err := p.index(system)
+	if err != nil {
+		return nil, err
+	}
+
+	exact := thumbnailReplacer.Replace(name)
+	key := normalizeThumbnailTitle(name)
+	md := GameMetadata{Media: make(map[string]string)}
+	for _, kind := range thumbnailKinds {
+		path, ok := idx.exact[kind.mediaType][exact]
+		if !ok {
+			path, ok = idx.normalized[kind.mediaType][key]
+		}
+		if !ok {
+			continue
+		}
+		md.Media[kind.mediaType] = path
+		if md.ID == "" {
+			md.ID = "thumbnails:" + system + "/" + strings.TrimSuffix(filepath.Base(path), ".png")
+		}
+	}
+	if len(md.Media) == 0 {
+		return nil, nil
+	}
+	return &md, nil
+}
+
+// systemDirs lists the system folders of the pack, or "." when root is itself
+// a system folder.
+func (p *ThumbnailsProvider) systemDirs() ([]string, error) {
+	if isThumbnailSystemDir(p.root) {
+		return []string{"."}, nil
+	}
+	entries, err := os.ReadDir(p.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnails directory: %w", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && isThumbnailSystemDir(filepath.Join(p.root, e.Name())) {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	return dirs, nil
+}
+
+// systemDir returns the folder holding a system's images, matching its name
+// regardless of case, or "" when the pack has none.
+func (p *ThumbnailsProvider) systemDir(system string) (string, error) {
+	dirs, err := p.systemDirs()
+	if err != nil {
+		return "", err
+	}
+	if len(dirs) == 1 && dirs[0] == "." {
+		return ".", nil
+	}
+	for _, dir := range dirs {
+		if strings.EqualFold(dir, system) {
+			return dir, nil
+		}
+	}
+	return "", nil
+}
+
+// index returns the image index of a system folder, building it on first use.
+func (p *ThumbnailsProvider) index(system string) (*thumbnailIndex, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if idx, ok := p.indexes[system]; ok {
+		return idx, nil
+	}
+
+	idx := &thumbnailIndex{
+		exact:      make(map[string]map[string]string),
+		normalized: make(map[string]map[string]string),
+	}
+	for _, kind := range thumbnailKinds {
+		idx.exact[kind.mediaType] = make(map[string]string)
+		idx.normalized[kind.mediaType] = make(map[string]string)
+
+		dir := filepath.Join(p.root, system, kind.dir)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+
+		// Sorted, so the first of several regional images with the same
+		// title wins the normalized match
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, file := range names {
+			path := filepath.Join(dir, file)
+			idx.exact[kind.mediaType][strings.TrimSuffix(file, filepath.Ext(file))] = path
+			key := library.NormalizeTitleForMatching(file)
+			if _, ok := idx.normalized[kind.mediaType][key]; !ok && key != "" {
+				idx.normalized[kind.mediaType][key] = path
+			}
+		}
+	}
+
+	p.indexes[system] = idx
+	return idx, nil
+}
+
+// isThumbnailSystemDir reports whether dir holds any of the Named_* folders.
+func isThumbnailSystemDir(dir string) bool {
+	for _, kind := range thumbnailKinds {
+		if info, err := os.Stat(filepath.Join(dir, kind.dir)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeThumbnailTitle normalizes a release name like the image file names.
+// NormalizeTitleForMatching drops a file extension, so the name is given one
+// to keep titles such as "Dr. Mario" whole.
+func normalizeThumbnailTitle(name string) string {
+	return library.NormalizeTitleForMatching(name + ".png")
+}
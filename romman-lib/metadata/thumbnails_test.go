@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanm101/romman-lib/db"
+)
+
+// writeThumbnails creates a thumbnails pack with the given files, relative
+// to its root.
+func writeThumbnails(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("png"), 0644)) // #nosec G306
+	}
+	return root
+}
+
+func TestThumbnailsProvider_LookupRelease(t *testing.T) {
+	const nes = "Nintendo - Nintendo Entertainment System"
+	root := writeThumbnails(t,
+		nes+"/Named_Boxarts/Dr. Mario (Japan, USA).png",
+		nes+"/Named_Titles/Dr. Mario (Japan, USA).png",
+		nes+"/Named_Boxarts/Legend of Zelda, The (USA).png",
+		nes+"/Named_Snaps/Legend of Zelda, The (Europe).png",
+		nes+"/Named_Boxarts/Mega Man _ Rockman (USA).png",
+	)
+	p, err := NewThumbnailsProvider(root)
+	require.NoError(t, err)
+	boxarts := filepath.Join(root, nes, "Named_Boxarts")
+
+	// Exact name, with RetroArch's character substitutions
+	md, err := p.LookupRelease("Mega Man / Rockman (USA)", nes)
+	require.NoError(t, err)
+	require.NotNil(t, md)
+	assert.Equal(t, map[string]string{"boxart": filepath.Join(boxarts, "Mega Man _ Rockman (USA).png")}, md.Media)
+
+	// Titles with a dot are kept whole when normalized
+	md, err = p.LookupRelease("Dr. Mario (Japan, USA) (Rev 1)", nes)
+	require.NoError(t, err)
+	require.NotNil(t, md)
+	assert.Equal(t, filepath.Join(boxarts, "Dr. Mario (Japan, USA).png"), md.Media["boxart"])
+	assert.Equal(t, filepath.Join(root, nes, "Named_Titles", "Dr. Mario (Japan, USA).png"), md.Media["title"])
+
+	// Each kind falls back to a normalized match on its own
+	md, err = p.LookupRelease("Legend of Zelda, The (USA)", nes)
+	require.NoError(t, err)
+	require.NotNil(t, md)
+	assert.Equal(t, filepath.Join(boxarts, "Legend of Zelda, The (USA).png"), md.Media["boxart"])
+	assert.Equal(t, filepath.Join(root, nes, "Named_Snaps", "Legend of Zelda, The (Europe).png"), md.Media["screenshot"])
+
+	md, err = p.LookupRelease("Tetris (USA)", nes)
+	assert.NoError(t, err)
+	assert.Nil(t, md)
+
+	// Other systems have no folder in the pack
+	md, err = p.LookupRelease("Dr. Mario (Japan, USA)", "Nintendo - Game Boy")
+	assert.NoError(t, err)
+	assert.Nil(t, md)
+}
+
+func TestThumbnailsProvider_SystemFolderRoot(t *testing.T) {
+	root := writeThumbnails(t, "Named_Boxarts/Tetris (World).png")
+	p, err := NewThumbnailsProvider(root)
+	require.NoError(t, err)
+
+	results, err := p.Search("Tetris (World)")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	md, err := p.GetDetails(results[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Named_Boxarts", "Tetris (World).png"), md.Media["boxart"])
+}
+
+func TestScrapeGame_LocalThumbnails(t *testing.T) {
+	database, err := db.Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = database.Close() }()
+
+	ctx := context.Background()
+	_, err = database.Conn().Exec("INSERT INTO systems (name) VALUES ('Nintendo - Game Boy')")
+	require.NoError(t, err)
+	_, err = database.Conn().Exec("INSERT INTO releases (id, system_id, name) VALUES (100, 1, 'Tetris (World) (Rev 1)')")
+	require.NoError(t, err)
+
+	root := writeThumbnails(t,
+		"Nintendo - Game Boy/Named_Boxarts/Tetris (World) (Rev 1).png",
+		"Nintendo - Game Boy/Named_Snaps/Tetris (World) (Rev 1).png",
+	)
+	p, err := NewThumbnailsProvider(root)
+	require.NoError(t, err)
+
+	require.NoError(t, NewService(database, p, t.TempDir()).ScrapeGame(ctx, 100, "Tetris (World) (Rev 1)"))
+
+	media, err := database.GetGameMedia(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"boxart":     filepath.Join(root, "Nintendo - Game Boy", "Named_Boxarts", "Tetris (World) (Rev 1).png"),
+		"screenshot": filepath.Join(root, "Nintendo - Game Boy", "Named_Snaps", "Tetris (World) (Rev 1).png"),
+	}, media)
+}
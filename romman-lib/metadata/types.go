@@ -1,5 +1,7 @@
 package metadata
 
+import "io"
+
 // GameMetadata represents enriched information about a game.
 type GameMetadata struct {
 	ID          string  // Provider specific ID (e.g. "igdb:12345")
@@ -7,8 +9,13 @@ type GameMetadata struct {
 	ReleaseDate string  // ISO 8601 date string (approximate)
 	Developer   string  // Main developer
 	Publisher   string  // Main publisher
+	Genre       string  // Main genre
 	Rating      float64 // Rating out of 100
 	BoxartURL   string  // URL to boxart image
+
+	// Local media files by game_media type (e.g. "boxart", "title"),
+	// recorded as they are rather than downloaded
+	Media map[string]string
 }
 
 // Provider defines the interface for fetching game metadata.
@@ -20,3 +27,37 @@ type Provider interface {
 	// GetDetails fetches detailed metadata for a specific ID.
 	GetDetails(id string) (*GameMetadata, error)
 }
+
+// RomHashes identifies a matched ROM file by what the scanner stored for it.
+type RomHashes struct {
+	Name   string // ROM name from the DAT
+	Size   int64
+	CRC32  string
+	MD5    string
+	SHA1   string
+	System string // romman system name
+}
+
+// HashProvider is a Provider that can also identify a game by ROM hash,
+// which is tried before searching by name.
+type HashProvider interface {
+	Provider
+	// LookupHash returns the game a ROM belongs to, or nil when the provider
+	// does not know the ROM.
+	LookupHash(rom RomHashes) (*GameMetadata, error)
+}
+
+// ReleaseProvider is a Provider that can find a release by its name within
+// a system, which is tried before searching by name alone.
+type ReleaseProvider interface {
+	Provider
+	// LookupRelease returns the game for a release, or nil when the provider
+	// does not know it.
+	LookupRelease(name, system string) (*GameMetadata, error)
+}
+
+// MediaFetcher is implemented by providers whose media URLs need the
+// provider's credentials or rate limiting to download.
+type MediaFetcher interface {
+	FetchMedia(url string) (io.ReadCloser, error)
+}
@@ -41,7 +41,21 @@ var (
 	FilesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "romman_files_processed_total",
 		Help: "Total number of files processed during scans.",
-	}, []string{"library", "status"}) // status: scanned, hashed, matched, skipped
+	}, []string{"library", "status"}) // status: scanned, hashed, skipped
+
+	// Results of each library's most recent scan
+	ScanMatchesFound = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "romman_scan_matches_found",
+		Help: "Matches found by the last scan of a library.",
+	}, []string{"library"})
+	ScanUnmatchedFiles = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "romman_scan_unmatched_files",
+		Help: "Files left unmatched by the last scan of a library.",
+	}, []string{"library"})
+	ScanCacheHitRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "romman_scan_cache_hit_ratio",
+		Help: "Share of files the last scan of a library took from the hash cache rather than hashing, 0-1.",
+	}, []string{"library"})
 )
 
 // UpdateDBMetrics refreshes gauges that reflect the current state of the database.
@@ -77,3 +91,16 @@ func UpdateDBMetrics(db *sql.DB) error {
 func RecordScanDuration(library string, start time.Time) {
 	ScanDuration.WithLabelValues(library).Observe(time.Since(start).Seconds())
 }
+
+// RecordScanResult records the outcome of a completed library scan: files
+// hashed and taken from the cache, matches found and files left unmatched.
+func RecordScanResult(library string, hashed, cached, matches, unmatched int) {
+	ScanMatchesFound.WithLabelValues(library).Set(float64(matches))
+	ScanUnmatchedFiles.WithLabelValues(library).Set(float64(unmatched))
+
+	ratio := 0.0
+	if total := hashed + cached; total > 0 {
+		ratio = float64(cached) / float64(total)
+	}
+	ScanCacheHitRatio.WithLabelValues(library).Set(ratio)
+}
@@ -34,6 +34,21 @@ func TestFilesProcessed_Counter(t *testing.T) {
 	assert.GreaterOrEqual(t, skipped, float64(1))
 }
 
+func TestRecordScanResult(t *testing.T) {
+	RecordScanResult("result-lib", 25, 75, 90, 10)
+
+	assert.Equal(t, float64(90), testutil.ToFloat64(ScanMatchesFound.WithLabelValues("result-lib")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(ScanUnmatchedFiles.WithLabelValues("result-lib")))
+	assert.Equal(t, 0.75, testutil.ToFloat64(ScanCacheHitRatio.WithLabelValues("result-lib")))
+	// Match counts are a gauge only, so rescans don't inflate a counter
+	assert.Zero(t, testutil.ToFloat64(FilesProcessed.WithLabelValues("result-lib", "matched")))
+
+	// A later scan replaces the gauges; an empty library has no cache hits
+	RecordScanResult("result-lib", 0, 0, 0, 0)
+	assert.Zero(t, testutil.ToFloat64(ScanMatchesFound.WithLabelValues("result-lib")))
+	assert.Zero(t, testutil.ToFloat64(ScanCacheHitRatio.WithLabelValues("result-lib")))
+}
+
 func TestGauges_Exist(t *testing.T) {
 	// Verify all gauges are defined and accessible
 	SystemsTotal.Set(10)
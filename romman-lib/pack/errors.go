@@ -10,4 +10,7 @@ var (
 	ErrNoGames = errors.New("no games specified for pack")
 	// ErrFileNotFound is returned when a ROM file cannot be found.
 	ErrFileNotFound = errors.New("ROM file not found")
+	// ErrPathCollision is returned when two games would be written to the same
+	// path and the request uses CollisionError.
+	ErrPathCollision = errors.New("games share the same path in pack")
 )
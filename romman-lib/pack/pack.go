@@ -3,7 +3,10 @@ package pack
 
 import (
 	"archive/zip"
+	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 )
 
 // Format represents the target export format for a game pack.
@@ -33,11 +36,24 @@ type Game struct {
 	ArchivePath string // Path within archive, if ROM is in a zip
 }
 
+// CollisionPolicy decides what happens when two games would be written to
+// the same <system>/<filename> path in a pack.
+type CollisionPolicy string
+
+const (
+	// CollisionRename keeps every game, adding " (2)", " (3)"... before the
+	// extension of later duplicates. This is the default.
+	CollisionRename CollisionPolicy = "rename"
+	// CollisionError fails generation with ErrPathCollision listing the paths.
+	CollisionError CollisionPolicy = "error"
+)
+
 // Request defines what games to pack and in what format.
 type Request struct {
-	Games  []Game // Games to include
-	Format Format // Target format
-	Name   string // Optional pack name (used for zip filename)
+	Games       []Game          // Games to include
+	Format      Format          // Target format
+	Name        string          // Optional pack name (used for zip filename)
+	OnCollision CollisionPolicy // Handling of duplicate target paths, default CollisionRename
 }
 
 // Result contains metadata about a generated pack.
@@ -91,10 +107,15 @@ func (g *Generator) Generate(req Request, w io.Writer) (*Result, error) {
 		return nil, ErrUnsupportedFormat
 	}
 
+	games, err := resolveCollisions(req.Games, req.OnCollision)
+	if err != nil {
+		return nil, err
+	}
+
 	zw := zip.NewWriter(w)
 	defer func() { _ = zw.Close() }()
 
-	if err := exporter.Export(req.Games, zw); err != nil {
+	if err := exporter.Export(games, zw); err != nil {
 		return nil, err
 	}
 
@@ -112,6 +133,54 @@ func (g *Generator) Generate(req Request, w io.Writer) (*Result, error) {
 	}, nil
 }
 
+// resolveCollisions checks that no two games share a system and file name,
+// which every exporter uses as the target path. Names are compared case
+// insensitively, since packs are often extracted to FAT or NTFS cards.
+// Games are copied, so the caller's slice is left unchanged.
+func resolveCollisions(games []Game, policy CollisionPolicy) ([]Game, error) {
+	switch policy {
+	case "", CollisionRename, CollisionError:
+	default:
+		return nil, fmt.Errorf("unknown collision policy: %s", policy)
+	}
+
+	used := make(map[string]bool, len(games))
+	key := func(system, fileName string) string {
+		return strings.ToLower(system + "/" + fileName)
+	}
+
+	out := make([]Game, len(games))
+	var collisions []string
+	for i, game := range games {
+		out[i] = game
+		if !used[key(game.System, game.FileName)] {
+			used[key(game.System, game.FileName)] = true
+			continue
+		}
+
+		if policy == CollisionError {
+			collisions = append(collisions, game.System+"/"+game.FileName)
+			continue
+		}
+
+		ext := filepath.Ext(game.FileName)
+		stem := strings.TrimSuffix(game.FileName, ext)
+		for n := 2; ; n++ {
+			name := fmt.Sprintf("%s (%d)%s", stem, n, ext)
+			if !used[key(game.System, name)] {
+				used[key(game.System, name)] = true
+				out[i].FileName = name
+				break
+			}
+		}
+	}
+
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPathCollision, strings.Join(collisions, ", "))
+	}
+	return out, nil
+}
+
 // EstimateSize calculates the approximate size of a pack without generating it.
 func (g *Generator) EstimateSize(games []Game) int64 {
 	var total int64
@@ -192,3 +192,56 @@ func TestGenerator_UnsupportedFormat(t *testing.T) {
 	_, err := g.Generate(req, &buf)
 	assert.ErrorIs(t, err, ErrUnsupportedFormat)
 }
+
+func TestGenerator_Collisions(t *testing.T) {
+	tmpDir := t.TempDir()
+	romA := filepath.Join(tmpDir, "a.nes")
+	romB := filepath.Join(tmpDir, "b.nes")
+	// #nosec G306
+	require.NoError(t, os.WriteFile(romA, []byte("first"), 0644))
+	// #nosec G306
+	require.NoError(t, os.WriteFile(romB, []byte("second"), 0644))
+
+	// Two libraries holding the same game produce the same target path
+	games := []Game{
+		{Name: "Game", System: "nes", FilePath: romA, FileName: "Game.nes"},
+		{Name: "Game", System: "nes", FilePath: romB, FileName: "game.nes"},
+	}
+	g := NewGenerator()
+
+	t.Run("rename by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := g.Generate(Request{Format: FormatSimple, Games: games}, &buf)
+		require.NoError(t, err)
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 2)
+		assert.Equal(t, "nes/Game.nes", zr.File[0].Name)
+		assert.Equal(t, "nes/game (2).nes", zr.File[1].Name)
+
+		rc, err := zr.File[1].Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(content))
+
+		// The caller's games are not modified
+		assert.Equal(t, "game.nes", games[1].FileName)
+	})
+
+	t.Run("error lists collisions", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := g.Generate(Request{Format: FormatSimple, Games: games, OnCollision: CollisionError}, &buf)
+		assert.ErrorIs(t, err, ErrPathCollision)
+		assert.ErrorContains(t, err, "nes/game.nes")
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := g.Generate(Request{Format: FormatSimple, Games: games, OnCollision: "skip"}, &buf)
+		assert.ErrorContains(t, err, "unknown collision policy")
+	})
+}
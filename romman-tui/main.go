@@ -8,6 +8,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ryanm101/romman-lib/config"
 	"github.com/ryanm101/romman-lib/db"
 	"github.com/ryanm101/romman-lib/library"
 )
@@ -892,7 +893,7 @@ func loadDetail(libName string, filter detailFilter) tea.Cmd {
 				JOIN rom_entries re ON re.id = m.rom_entry_id
 				JOIN releases r ON r.id = re.release_id
 				JOIN libraries l ON l.id = sf.library_id
-				WHERE l.name = ? AND m.match_type IN ('sha1', 'crc32')
+				WHERE l.name = ? AND m.match_type IN ('sha256', 'sha1', 'md5', 'crc32')
 				ORDER BY r.name
 			`, libName)
 			if err == nil {
@@ -1118,7 +1119,7 @@ func scanLibrary(name string) tea.Cmd {
 		}
 		defer func() { _ = database.Close() }()
 
-		scanner := library.NewScanner(database.Conn())
+		scanner := library.NewScannerWithConfig(database.Conn(), scanConfig())
 		_, err = scanner.Scan(context.Background(), name)
 
 		return scanCompleteMsg{err: err}
@@ -1134,7 +1135,7 @@ func scanLibraries(names []string) tea.Cmd {
 		}
 		defer func() { _ = database.Close() }()
 
-		scanner := library.NewScanner(database.Conn())
+		scanner := library.NewScannerWithConfig(database.Conn(), scanConfig())
 		var lastErr error
 		for _, name := range names {
 			if _, err := scanner.Scan(context.Background(), name); err != nil {
@@ -1146,6 +1147,18 @@ func scanLibraries(names []string) tea.Cmd {
 	}
 }
 
+// scanConfig returns the default scan configuration with archive expansion
+// taken from the config's scan.expand_archives.
+func scanConfig() library.ScanConfig {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	scanCfg := library.DefaultScanConfig()
+	scanCfg.NoArchives = !cfg.GetExpandArchives()
+	return scanCfg
+}
+
 func getDBPath() string {
 	if path := os.Getenv("ROMMAN_DB"); path != "" {
 		return path
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ryanm101/romman-lib/apitypes"
+	"github.com/ryanm101/romman-lib/library"
+)
+
+// maxFinishedJobs is how many finished jobs are kept for status polling
+// before the oldest are forgotten.
+const maxFinishedJobs = 100
+
+// job is a background rename, organize or cleanup run.
+type job struct {
+	apitypes.Job
+	cancel context.CancelFunc
+}
+
+// jobManager runs jobs in goroutines. Jobs on the same library run one at a
+// time so two jobs never move the same files concurrently.
+type jobManager struct {
+	db     *sql.DB
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []string                 // Job IDs, oldest first
+	locks  map[string]chan struct{} // Per-library run slot
+	nextID int
+}
+
+func newJobManager(conn *sql.DB) *jobManager {
+	return &jobManager{
+		db:    conn,
+		jobs:  make(map[string]*job),
+		locks: make(map[string]chan struct{}),
+	}
+}
+
+// validateJob checks a request before it is queued, so bad requests fail
+// the POST instead of the job.
+func validateJob(req apitypes.JobRequest) error {
+	if req.Library == "" {
+		return errors.New("missing library")
+	}
+	switch req.Type {
+	case apitypes.JobRename:
+	case apitypes.JobOrganize:
+		if req.Options.OutputDir == "" {
+			return errors.New("organize requires options.outputDir")
+		}
+		switch req.Options.Structure {
+		case "", "flat", "system", "system-region", "system-genre", "system-region-genre":
+		default:
+			return fmt.Errorf("unknown structure: %s", req.Options.Structure)
+		}
+		if req.Options.Copy && req.Options.Hardlink {
+			return errors.New("organize takes options.copy or options.hardlink, not both")
+		}
+	case apitypes.JobCleanup:
+		if req.Options.QuarantineDir == "" {
+			return errors.New("cleanup requires options.quarantineDir")
+		}
+	default:
+		return fmt.Errorf("unknown job type: %s", req.Type)
+	}
+	return nil
+}
+
+// Start queues a job and returns its initial state.
+func (m *jobManager) Start(req apitypes.JobRequest) (apitypes.Job, error) {
+	if err := validateJob(req); err != nil {
+		return apitypes.Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	m.nextID++
+	j := &job{
+		Job: apitypes.Job{
+			ID:        fmt.Sprintf("job-%d", m.nextID),
+			Type:      req.Type,
+			Library:   req.Library,
+			Status:    apitypes.JobQueued,
+			Progress:  apitypes.JobProgress{Phase: "waiting"},
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+	m.jobs[j.ID] = j
+	m.order = append(m.order, j.ID)
+	m.evictLocked()
+	slot, ok := m.locks[req.Library]
+	if !ok {
+		slot = make(chan struct{}, 1)
+		m.locks[req.Library] = slot
+	}
+	snapshot := j.Job
+	m.mu.Unlock()
+
+	go m.run(ctx, j, req, slot)
+
+	return snapshot, nil
+}
+
+// Get returns a copy of a job's current state.
+func (m *jobManager) Get(id string) (apitypes.Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return apitypes.Job{}, false
+	}
+	return j.Job, true
+}
+
+// List returns all known jobs, oldest first.
+func (m *jobManager) List() []apitypes.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]apitypes.Job, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, m.jobs[id].Job)
+	}
+	return jobs
+}
+
+// Cancel cancels a queued or running job. A job cancelled while waiting or
+// planning moves nothing; moves already under way are finished, not undone.
+func (m *jobManager) Cancel(id string) (apitypes.Job, bool) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return apitypes.Job{}, false
+	}
+	j.cancel()
+	return m.Get(id)
+}
+
+// evictLocked forgets the oldest finished jobs beyond maxFinishedJobs.
+// Callers must hold m.mu.
+func (m *jobManager) evictLocked() {
+	finished := 0
+	for _, id := range m.order {
+		if isFinished(m.jobs[id].Status) {
+			finished++
+		}
+	}
+
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > maxFinishedJobs && isFinished(m.jobs[id].Status) {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+func isFinished(status string) bool {
+	return status == apitypes.JobDone || status == apitypes.JobFailed || status == apitypes.JobCancelled
+}
+
+// update applies fn to the job's state under the lock.
+func (m *jobManager) update(j *job, fn func(*apitypes.Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(&j.Job)
+}
+
+func (m *jobManager) run(ctx context.Context, j *job, req apitypes.JobRequest, slot chan struct{}) {
+	defer j.cancel()
+
+	// Wait for the library's run slot
+	select {
+	case slot <- struct{}{}:
+		defer func() { <-slot }()
+	case <-ctx.Done():
+		m.finish(j, nil, ctx.Err())
+		return
+	}
+
+	started := time.Now()
+	m.update(j, func(s *apitypes.Job) {
+		s.Status = apitypes.JobRunning
+		s.StartedAt = &started
+		s.Progress.Phase = "planning"
+	})
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch req.Type {
+	case apitypes.JobRename:
+		result, err = m.runRename(ctx, j, req)
+	case apitypes.JobOrganize:
+		result, err = m.runOrganize(ctx, j, req)
+	case apitypes.JobCleanup:
+		result, err = m.runCleanup(ctx, j, req)
+	}
+	m.finish(j, result, err)
+}
+
+func (m *jobManager) finish(j *job, result interface{}, err error) {
+	finished := time.Now()
+	m.update(j, func(s *apitypes.Job) {
+		s.FinishedAt = &finished
+		s.Progress.Phase = "finished"
+		s.Result = result
+		switch {
+		case errors.Is(err, context.Canceled):
+			s.Status = apitypes.JobCancelled
+			s.Error = err.Error()
+		case err != nil:
+			s.Status = apitypes.JobFailed
+			s.Error = err.Error()
+		default:
+			s.Status = apitypes.JobDone
+		}
+	})
+}
+
+func (m *jobManager) setProgress(j *job, phase string, done, total int) {
+	m.update(j, func(s *apitypes.Job) {
+		s.Progress = apitypes.JobProgress{Phase: phase, Done: done, Total: total}
+	})
+}
+
+func (m *jobManager) runRename(ctx context.Context, j *job, req apitypes.JobRequest) (interface{}, error) {
+	renamer := library.NewRenamer(m.db, library.NewManager(m.db))
+
+	m.setProgress(j, "executing", 0, 0)
+	result, err := renamer.Rename(ctx, req.Library, req.Options.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setProgress(j, "executing", len(result.Actions), len(result.Actions))
+	return result, nil
+}
+
+func (m *jobManager) runOrganize(ctx context.Context, j *job, req apitypes.JobRequest) (interface{}, error) {
+	organizer := library.NewOrganizer(m.db, library.NewManager(m.db))
+
+	opts := library.OrganizeOptions{
+		OutputDir:     req.Options.OutputDir,
+		Structure:     req.Options.Structure,
+		RenameToDAT:   req.Options.Rename,
+		DryRun:        req.Options.DryRun,
+		PreferredOnly: req.Options.PreferredOnly,
+		MultiDisc:     req.Options.MultiDisc,
+		Copy:          req.Options.Copy,
+		Hardlink:      req.Options.Hardlink,
+	}
+	if opts.Structure == "" {
+		opts.Structure = "flat"
+	}
+
+	result, err := organizer.Plan(ctx, req.Library, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.setProgress(j, "executing", 0, len(result.Actions))
+	if err := organizer.Execute(result, opts.DryRun); err != nil {
+		return result, err
+	}
+
+	m.setProgress(j, "executing", result.Moved+result.Copied+result.Linked+result.Errors, len(result.Actions))
+	return result, nil
+}
+
+func (m *jobManager) runCleanup(ctx context.Context, j *job, req apitypes.JobRequest) (interface{}, error) {
+	finder := library.NewDuplicateFinder(m.db)
+	planner := library.NewCleanupPlanner(finder, library.NewManager(m.db))
+	planner.Hardlink = req.Options.Hardlink
+
+	quarantine, err := filepath.Abs(req.Options.QuarantineDir)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := planner.GeneratePlan(ctx, req.Library, quarantine)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.setProgress(j, "executing", 0, len(plan.Actions))
+	result, err := library.ExecutePlanWithOptions(plan, library.ExecuteOptions{DryRun: req.Options.DryRun})
+	if err != nil {
+		return nil, err
+	}
+
+	m.setProgress(j, "executing", result.Succeeded+result.Failed+result.Skipped, len(plan.Actions))
+	return result, nil
+}
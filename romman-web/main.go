@@ -5,18 +5,25 @@ import (
 	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
+	"github.com/ryanm101/romman-lib/apitypes"
 	"github.com/ryanm101/romman-lib/config"
+	"github.com/ryanm101/romman-lib/dat"
 	"github.com/ryanm101/romman-lib/db"
 	"github.com/ryanm101/romman-lib/library"
 	"github.com/ryanm101/romman-lib/metrics"
@@ -37,13 +44,17 @@ func main() {
 	// Setup Tracing context early for database operations
 	ctx := context.Background()
 
-	database, err := db.Open(ctx, cfg.DBPath)
+	database, err := db.OpenWithConfig(ctx, cfg.DBPath, db.PoolConfig{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+	})
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 	defer func() { _ = database.Close() }()
 
-	server := NewServer(database.Conn())
+	server := NewServer(database.Conn(), cfg)
 
 	port := os.Getenv("ROMMAN_PORT")
 	if port == "" {
@@ -92,15 +103,19 @@ type Server struct {
 	db        *sql.DB
 	mux       *http.ServeMux
 	mediaRoot string
+	jobs      *jobManager
+	cfg       *config.Config
 }
 
 // NewServer creates a new web server.
-func NewServer(conn *sql.DB) *Server {
+func NewServer(conn *sql.DB, cfg *config.Config) *Server {
 	home, _ := os.UserHomeDir()
 	s := &Server{
 		db:        conn,
 		mux:       http.NewServeMux(),
 		mediaRoot: fmt.Sprintf("%s/.romman/media", home),
+		jobs:      newJobManager(conn),
+		cfg:       cfg,
 	}
 	s.setupRoutes()
 	return s
@@ -112,35 +127,84 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/systems", s.handleSystems)
+	s.mux.HandleFunc("/api/systems/stubs", s.handleStubSystems)
 	s.mux.HandleFunc("/api/libraries", s.handleLibraries)
 	s.mux.HandleFunc("/api/stats", s.handleStats)
+	s.mux.HandleFunc("/api/stats/space", s.handleSpace)
 	s.mux.HandleFunc("/api/scan", s.handleScan)
+	s.mux.HandleFunc("/api/scan/stream", s.handleScanStream)
 	s.mux.HandleFunc("/api/scan-all", s.handleScanAll)
 	s.mux.HandleFunc("/api/details", s.handleDetails)
+	s.mux.HandleFunc("/api/search", s.handleSearch)
 	s.mux.HandleFunc("/api/counts", s.handleCounts)
+	s.mux.HandleFunc("/api/export", s.handleExport)
 	s.mux.HandleFunc("/api/media/", s.handleMedia) // Note trailing slash for prefix matching
 	s.mux.HandleFunc("/api/packs/games", s.handlePackGames)
 	s.mux.HandleFunc("/api/packs/generate", s.handlePackGenerate)
+	s.mux.HandleFunc("/api/jobs", s.handleJobs)
+	s.mux.HandleFunc("/api/jobs/", s.handleJob)
 	s.mux.HandleFunc("/health", s.handleHealth)
 	s.mux.HandleFunc("/metrics", s.handleMetrics)
 	s.mux.HandleFunc("/", s.handleDashboard)
 }
 
+// deleteLibrary removes the library named by the name parameter along with
+// its scanned files and matches.
+func (s *Server) deleteLibrary(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Missing name parameter", http.StatusBadRequest)
+		return
+	}
+
+	manager := library.NewManager(s.db)
+	if _, err := manager.Get(r.Context(), name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := manager.Delete(r.Context(), name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(apitypes.StatusResponse{Status: "ok"})
+}
+
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
-	var systems, libraries, releases int
+	var data apitypes.StatsResponse
 
-	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM systems").Scan(&systems)
-	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM libraries").Scan(&libraries)
-	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM releases").Scan(&releases)
+	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM systems").Scan(&data.TotalSystems)
+	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM libraries").Scan(&data.TotalLibraries)
+	_ = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM releases").Scan(&data.TotalReleases)
 
-	data := map[string]int{
-		"totalSystems":   systems,
-		"totalLibraries": libraries,
-		"totalReleases":  releases,
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// handleSpace returns disk usage per system and library.
+func (s *Server) handleSpace(w http.ResponseWriter, r *http.Request) {
+	report, err := library.GetSpaceUsage(r.Context(), s.db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := apitypes.SpaceResponse{
+		Libraries:    make([]apitypes.SpaceUsage, 0, len(report.Libraries)),
+		Systems:      make([]apitypes.SpaceUsage, 0, len(report.Systems)),
+		TotalBytes:   report.TotalBytes,
+		MatchedBytes: report.MatchedBytes,
+	}
+	for _, u := range report.Libraries {
+		resp.Libraries = append(resp.Libraries, apitypes.SpaceUsage(u))
+	}
+	for _, u := range report.Systems {
+		resp.Systems = append(resp.Systems, apitypes.SpaceUsage(u))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(data)
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleSystems(w http.ResponseWriter, r *http.Request) {
@@ -157,25 +221,55 @@ func (s *Server) handleSystems(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = rows.Close() }()
 
-	var systems []map[string]interface{}
+	var systems []apitypes.SystemSummary
 	for rows.Next() {
-		var name string
-		var releases, preferred int
-		if err := rows.Scan(&name, &releases, &preferred); err != nil {
+		var sys apitypes.SystemSummary
+		if err := rows.Scan(&sys.Name, &sys.Releases, &sys.Preferred); err != nil {
 			continue
 		}
-		systems = append(systems, map[string]interface{}{
-			"name":      name,
-			"releases":  releases,
-			"preferred": preferred,
-		})
+		systems = append(systems, sys)
+	}
+
+	// BIOS readiness comes from the shared 'romman bios scan' results
+	bios := library.NewBIOSManager(s.db, dat.LoadSystemMappings().BIOS)
+	for i := range systems {
+		ready, required, err := bios.Ready(r.Context(), systems[i].Name)
+		if err == nil && required {
+			systems[i].BIOSReady = &ready
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(apitypes.SystemsResponse{Systems: systems})
+}
+
+func (s *Server) handleStubSystems(w http.ResponseWriter, r *http.Request) {
+	stubs, err := library.GetStubSystems(r.Context(), s.db)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	resp := apitypes.StubSystemsResponse{Systems: []apitypes.StubSystem{}}
+	for _, st := range stubs {
+		resp.Systems = append(resp.Systems, apitypes.StubSystem(st))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"systems": systems})
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleLibraries(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodDelete:
+		s.deleteLibrary(w, r)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	// Get library info
 	rows, err := s.db.QueryContext(r.Context(), `
 		SELECT l.id, l.name, s.name as system,
@@ -217,22 +311,22 @@ func (s *Server) handleLibraries(w http.ResponseWriter, r *http.Request) {
 		`, libList[i].id).Scan(&libList[i].matched)
 	}
 
-	libs := make([]map[string]interface{}, 0, len(libList))
+	libs := make([]apitypes.LibrarySummary, 0, len(libList))
 	for _, l := range libList {
 		pct := 0
 		if l.total > 0 {
 			pct = l.matched * 100 / l.total
 		}
-		libs = append(libs, map[string]interface{}{
-			"name":     l.name,
-			"system":   l.system,
-			"matched":  l.matched,
-			"total":    l.total,
-			"matchPct": pct,
+		libs = append(libs, apitypes.LibrarySummary{
+			Name:     l.name,
+			System:   l.system,
+			Matched:  l.matched,
+			Total:    l.total,
+			MatchPct: pct,
 		})
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"libraries": libs})
+	_ = json.NewEncoder(w).Encode(apitypes.LibrariesResponse{Libraries: libs})
 }
 
 func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
@@ -247,15 +341,105 @@ func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	scanner := library.NewScanner(s.db)
-	_, err := scanner.Scan(r.Context(), name)
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamScan(w, r, name)
+		return
+	}
+
+	scanner := library.NewScannerWithConfig(s.db, s.scanConfig())
+	result, err := scanner.Scan(r.Context(), name)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	_ = json.NewEncoder(w).Encode(apitypes.StatusResponse{Status: "ok", FileErrors: len(result.Errors)})
+}
+
+// handleScanStream scans a library and streams its progress as Server-Sent
+// Events whatever the Accept header, for clients that cannot set it.
+func (s *Server) handleScanStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("library")
+	if name == "" {
+		http.Error(w, "Missing library parameter", http.StatusBadRequest)
+		return
+	}
+	s.streamScan(w, r, name)
+}
+
+// streamScan scans a library and streams its progress as Server-Sent
+// Events: a "progress" event per apitypes.ScanProgress, then a final "done"
+// or "error" event carrying an apitypes.StatusResponse. A successful scan
+// sends one more progress event with the final counts before "done".
+// Progress events are dropped rather than slowing the scan when the client
+// falls behind.
+func (s *Server) streamScan(w http.ResponseWriter, r *http.Request, name string) {
+	rc := http.NewResponseController(w)
+	// A scan outlives the server's write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("Scan stream for %s cannot be flushed: %v", name, err)
+	}
+
+	progress := make(chan library.ScanProgress, 64)
+	scanCfg := s.scanConfig()
+	scanCfg.Progress = progress
+
+	var result *library.ScanResult
+	var scanErr error
+	go func() {
+		defer close(progress)
+		result, scanErr = library.NewScannerWithConfig(s.db, scanCfg).Scan(r.Context(), name)
+	}()
+
+	for p := range progress {
+		if err := writeEvent(w, "progress", apitypes.ScanProgress(p)); err != nil {
+			continue // The client went away; the scan stops with the request context
+		}
+		_ = rc.Flush()
+	}
+
+	if scanErr != nil {
+		_ = writeEvent(w, "error", apitypes.StatusResponse{Status: "error", Error: scanErr.Error()})
+	} else {
+		_ = writeEvent(w, "progress", apitypes.ScanProgress{
+			TotalFiles:   int64(result.FilesScanned),
+			FilesScanned: int64(result.FilesScanned),
+			FilesHashed:  int64(result.FilesHashed),
+			FilesSkipped: int64(result.FilesSkipped),
+			MatchesFound: int64(result.MatchesFound),
+		})
+		_ = writeEvent(w, "done", apitypes.StatusResponse{Status: "ok", FileErrors: len(result.Errors)})
+	}
+	_ = rc.Flush()
+}
+
+// scanConfig returns the default scan configuration with archive expansion
+// taken from the config's scan.expand_archives.
+func (s *Server) scanConfig() library.ScanConfig {
+	scanCfg := library.DefaultScanConfig()
+	scanCfg.NoArchives = !s.cfg.GetExpandArchives()
+	return scanCfg
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload.
+func writeEvent(w io.Writer, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
 }
 
 func (s *Server) handleScanAll(w http.ResponseWriter, r *http.Request) {
@@ -275,6 +459,7 @@ func (s *Server) handleScanAll(w http.ResponseWriter, r *http.Request) {
 		SELECT l.name FROM libraries l
 		JOIN systems s ON s.id = l.system_id
 		WHERE s.name = ?
+		ORDER BY l.name
 	`, system)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -295,7 +480,7 @@ func (s *Server) handleScanAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	scanner := library.NewScanner(s.db)
+	scanner := library.NewScannerWithConfig(s.db, s.scanConfig())
 	var scanned int
 	for _, name := range libNames {
 		if _, err := scanner.Scan(r.Context(), name); err == nil {
@@ -304,10 +489,10 @@ func (s *Server) handleScanAll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "ok",
-		"scanned": scanned,
-		"total":   len(libNames),
+	_ = json.NewEncoder(w).Encode(apitypes.ScanAllResponse{
+		Status:  "ok",
+		Scanned: scanned,
+		Total:   len(libNames),
 	})
 }
 
@@ -318,7 +503,7 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var matched, missing, flagged, unmatched, preferred int
+	var counts apitypes.CountsResponse
 
 	// Matched count
 	_ = s.db.QueryRowContext(r.Context(), `
@@ -328,8 +513,8 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 		JOIN rom_entries re ON re.id = m.rom_entry_id
 		JOIN releases r ON r.id = re.release_id
 		JOIN libraries l ON l.id = sf.library_id
-		WHERE l.name = ? AND m.match_type IN ('sha1', 'crc32')
-	`, libName).Scan(&matched)
+		WHERE l.name = ? AND m.match_type IN ('sha256', 'sha1', 'md5', 'crc32')
+	`, libName).Scan(&counts.Matched)
 
 	// Missing count
 	_ = s.db.QueryRowContext(r.Context(), `
@@ -344,7 +529,7 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 			JOIN rom_entries re ON re.id = m.rom_entry_id
 			WHERE sf.library_id = l.id
 		)
-	`, libName).Scan(&missing)
+	`, libName).Scan(&counts.Missing)
 
 	// Flagged count
 	_ = s.db.QueryRowContext(r.Context(), `
@@ -355,7 +540,7 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 		JOIN releases r ON r.id = re.release_id
 		JOIN libraries l ON l.id = sf.library_id
 		WHERE l.name = ? AND m.flags IS NOT NULL AND m.flags != ''
-	`, libName).Scan(&flagged)
+	`, libName).Scan(&counts.Flagged)
 
 	// Unmatched count
 	_ = s.db.QueryRowContext(r.Context(), `
@@ -364,7 +549,7 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 		JOIN libraries l ON l.id = sf.library_id
 		LEFT JOIN matches m ON m.scanned_file_id = sf.id
 		WHERE l.name = ? AND m.id IS NULL
-	`, libName).Scan(&unmatched)
+	`, libName).Scan(&counts.Unmatched)
 
 	// Preferred count
 	_ = s.db.QueryRowContext(r.Context(), `
@@ -372,32 +557,36 @@ func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
 		FROM releases r
 		JOIN libraries l ON l.system_id = r.system_id
 		WHERE l.name = ? AND r.is_preferred = 1
-	`, libName).Scan(&preferred)
+	`, libName).Scan(&counts.Preferred)
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]int{
-		"matched":   matched,
-		"missing":   missing,
-		"flagged":   flagged,
-		"unmatched": unmatched,
-		"preferred": preferred,
-	})
+	_ = json.NewEncoder(w).Encode(counts)
 }
 
 func (s *Server) handleDetails(w http.ResponseWriter, r *http.Request) {
-	libName := r.URL.Query().Get("library")
-	filter := r.URL.Query().Get("filter")
+	query := r.URL.Query()
+	libName := query.Get("library")
+	filter := query.Get("filter")
 	if libName == "" {
 		http.Error(w, "Missing library parameter", http.StatusBadRequest)
 		return
 	}
+	page, err := parseDetailsPage(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var items []map[string]string
+	// Each filter's query returns name and path columns first, which the
+	// search and paging in detailsRows apply to
+	var items []apitypes.DetailItem
+	var total int
 
 	switch filter {
 	case "matched":
-		rows, err := s.db.QueryContext(r.Context(), `
-			SELECT r.name, sf.path, m.match_type, COALESCE(m.flags, ''),
+		var rows *sql.Rows
+		rows, total, err = s.detailsRows(r.Context(), `
+			SELECT r.name AS name, sf.path AS path, m.match_type, COALESCE(m.flags, ''),
 				COALESCE(gm.local_path, ''), COALESCE(gmd.description, '')
 			FROM scanned_files sf
 			JOIN matches m ON m.scanned_file_id = sf.id
@@ -406,31 +595,29 @@ func (s *Server) handleDetails(w http.ResponseWriter, r *http.Request) {
 			JOIN libraries l ON l.id = sf.library_id
 			LEFT JOIN game_media gm ON gm.release_id = r.id AND gm.type = 'boxart'
 			LEFT JOIN game_metadata gmd ON gmd.release_id = r.id
-			WHERE l.name = ? AND m.match_type IN ('sha1', 'crc32')
-			ORDER BY r.name
-		`, libName)
+			WHERE l.name = ? AND m.match_type IN ('sha256', 'sha1', 'md5', 'crc32')
+		`, []any{libName}, page)
 		if err == nil {
 			defer func() { _ = rows.Close() }()
 			for rows.Next() {
-				var name, path, matchType, flags, mediaPath, desc string
-				_ = rows.Scan(&name, &path, &matchType, &flags, &mediaPath, &desc)
+				var mediaPath string
+				item := apitypes.DetailItem{Status: "matched"}
+				_ = rows.Scan(&item.Name, &item.Path, &item.MatchType, &item.Flags, &mediaPath, &item.Description)
 
-				item := map[string]string{
-					"name": name, "path": path, "matchType": matchType,
-					"flags": flags, "status": "matched",
-					"description": desc,
-				}
 				if mediaPath != "" {
-					if rel, err := filepath.Rel(s.mediaRoot, mediaPath); err == nil {
-						item["boxart"] = "/api/media/" + rel
+					// Only downloaded media is served; images recorded in place
+					// from a thumbnails pack live outside the media root
+					if rel, err := filepath.Rel(s.mediaRoot, mediaPath); err == nil && !strings.HasPrefix(rel, "..") {
+						item.Boxart = "/api/media/" + filepath.ToSlash(rel)
 					}
 				}
 				items = append(items, item)
 			}
 		}
 	case "missing":
-		rows, err := s.db.QueryContext(r.Context(), `
-			SELECT r.name
+		var rows *sql.Rows
+		rows, total, err = s.detailsRows(r.Context(), `
+			SELECT r.name AS name, '' AS path
 			FROM releases r
 			JOIN libraries l ON l.system_id = r.system_id
 			WHERE l.name = ?
@@ -441,68 +628,64 @@ func (s *Server) handleDetails(w http.ResponseWriter, r *http.Request) {
 				JOIN rom_entries re ON re.id = m.rom_entry_id
 				WHERE sf.library_id = l.id
 			)
-			ORDER BY r.name
-		`, libName)
+		`, []any{libName}, page)
 		if err == nil {
 			defer func() { _ = rows.Close() }()
 			for rows.Next() {
-				var name string
-				_ = rows.Scan(&name)
-				items = append(items, map[string]string{"name": name, "status": "missing"})
+				var name, path string
+				_ = rows.Scan(&name, &path)
+				items = append(items, apitypes.DetailItem{Name: name, Status: "missing"})
 			}
 		}
 	case "flagged":
-		rows, err := s.db.QueryContext(r.Context(), `
-			SELECT r.name, sf.path, m.match_type, m.flags
-			FROM scanned_files sf
-			JOIN matches m ON m.scanned_file_id = sf.id
-			JOIN rom_entries re ON re.id = m.rom_entry_id
-			JOIN releases r ON r.id = re.release_id
-			JOIN libraries l ON l.id = sf.library_id
-			WHERE l.name = ? AND m.flags IS NOT NULL AND m.flags != ''
-			ORDER BY r.name
-		`, libName)
+		// The same list as the flagged report, archive entries included
+		var libraryID int64
+		if lib, lerr := library.NewManager(s.db).Get(r.Context(), libName); lerr == nil {
+			libraryID = lib.ID
+		}
+		var rows *sql.Rows
+		rows, total, err = s.detailsRows(r.Context(), library.FlaggedFilesQuery, []any{libraryID}, page)
 		if err == nil {
 			defer func() { _ = rows.Close() }()
 			for rows.Next() {
-				var name, path, matchType, flags string
-				_ = rows.Scan(&name, &path, &matchType, &flags)
-				items = append(items, map[string]string{"name": name, "path": path, "matchType": matchType, "flags": flags, "status": "flagged"})
+				var name, path, sha1, matchType, flags string
+				_ = rows.Scan(&name, &path, &sha1, &matchType, &flags)
+				items = append(items, apitypes.DetailItem{Name: name, Path: path, MatchType: matchType, Flags: flags, Status: "flagged"})
 			}
 		}
 	case "unmatched":
-		rows, err := s.db.QueryContext(r.Context(), `
-			SELECT sf.path
+		var rows *sql.Rows
+		rows, total, err = s.detailsRows(r.Context(), `
+			SELECT sf.path AS name, sf.path AS path
 			FROM scanned_files sf
 			JOIN libraries l ON l.id = sf.library_id
 			LEFT JOIN matches m ON m.scanned_file_id = sf.id
 			WHERE l.name = ? AND m.id IS NULL
-			ORDER BY sf.path
-		`, libName)
+		`, []any{libName}, page)
 		if err == nil {
 			defer func() { _ = rows.Close() }()
 			for rows.Next() {
-				var path string
-				_ = rows.Scan(&path)
-				items = append(items, map[string]string{"name": path, "path": path, "status": "unmatched"})
+				var name, path string
+				_ = rows.Scan(&name, &path)
+				items = append(items, apitypes.DetailItem{Name: path, Path: path, Status: "unmatched"})
 			}
 		}
 	case "preferred":
-		rows, err := s.db.QueryContext(r.Context(), `
-			SELECT r.name, 
+		var rows *sql.Rows
+		rows, total, err = s.detailsRows(r.Context(), `
+			SELECT r.name AS name, 
 				COALESCE((SELECT sf.path FROM scanned_files sf 
 						  JOIN matches m ON m.scanned_file_id = sf.id 
 						  JOIN rom_entries re ON re.id = m.rom_entry_id 
-						  WHERE re.release_id = r.id AND sf.library_id = (SELECT id FROM libraries WHERE name = ?) LIMIT 1), ''),
+						  WHERE re.release_id = r.id AND sf.library_id = (SELECT id FROM libraries WHERE name = ?) ORDER BY sf.path LIMIT 1), '') AS path,
 				COALESCE((SELECT m.match_type FROM scanned_files sf 
 						  JOIN matches m ON m.scanned_file_id = sf.id 
 						  JOIN rom_entries re ON re.id = m.rom_entry_id 
-						  WHERE re.release_id = r.id AND sf.library_id = (SELECT id FROM libraries WHERE name = ?) LIMIT 1), '')
+						  WHERE re.release_id = r.id AND sf.library_id = (SELECT id FROM libraries WHERE name = ?) ORDER BY sf.path LIMIT 1), '')
 			FROM releases r
 			JOIN libraries l ON l.system_id = r.system_id
 			WHERE l.name = ? AND r.is_preferred = 1
-			ORDER BY r.name
-		`, libName, libName, libName)
+		`, []any{libName, libName, libName}, page)
 		if err == nil {
 			defer func() { _ = rows.Close() }()
 			for rows.Next() {
@@ -512,13 +695,245 @@ func (s *Server) handleDetails(w http.ResponseWriter, r *http.Request) {
 				if path != "" {
 					status = "matched"
 				}
-				items = append(items, map[string]string{"name": name, "path": path, "matchType": matchType, "status": status})
+				items = append(items, apitypes.DetailItem{Name: name, Path: path, MatchType: matchType, Status: status})
 			}
 		}
 	}
 
+	resp := apitypes.DetailsResponse{Items: items, Total: total}
+	if page.size > 0 {
+		resp.Page = page.number
+		resp.PageSize = page.size
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleSearch returns one page of the releases of any system whose name
+// matches q, as the CLI's search command finds them. Paging defaults to the
+// first page of defaultDetailsPageSize hits.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	page, err := parseDetailsPage(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.search == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+	if page.size == 0 {
+		page.size = defaultDetailsPageSize
+	}
+
+	hits, err := library.Search(r.Context(), s.db, page.search)
+	if err != nil {
+		if errors.Is(err, library.ErrInvalidArg) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := apitypes.SearchResponse{
+		Items:    []apitypes.SearchHit{},
+		Total:    len(hits),
+		Page:     page.number,
+		PageSize: page.size,
+	}
+	start := min((page.number-1)*page.size, len(hits))
+	end := min(start+page.size, len(hits))
+	for _, hit := range hits[start:end] {
+		item := apitypes.SearchHit{
+			ReleaseID: hit.ReleaseID,
+			Release:   hit.Release,
+			System:    hit.System,
+			Matched:   hit.Matched,
+		}
+		for _, loc := range hit.Locations {
+			item.Locations = append(item.Locations, apitypes.SearchLocation(loc))
+		}
+		resp.Items = append(resp.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Page sizes for /api/details and /api/search
+const (
+	defaultDetailsPageSize = 100
+	maxDetailsPageSize     = 1000
+)
+
+// detailsPage holds the search and paging parameters of /api/details.
+type detailsPage struct {
+	search string // Substring of the name or path, "" for all
+	number int    // 1-based page number
+	size   int    // Items per page, 0 for all items
+}
+
+// parseDetailsPage reads the q, page and pageSize parameters. Without page
+// or pageSize every item is returned, as before paging was added.
+func parseDetailsPage(query url.Values) (detailsPage, error) {
+	page := detailsPage{search: strings.TrimSpace(query.Get("q")), number: 1}
+	if query.Get("page") == "" && query.Get("pageSize") == "" {
+		return page, nil
+	}
+
+	page.size = defaultDetailsPageSize
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return page, fmt.Errorf("invalid page %q", v)
+		}
+		page.number = n
+	}
+	if v := query.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDetailsPageSize {
+			return page, fmt.Errorf("invalid pageSize %q (1-%d)", v, maxDetailsPageSize)
+		}
+		page.size = n
+	}
+	return page, nil
+}
+
+// detailsRows runs a details query, whose first columns are name and path,
+// keeping rows whose name or path contains the search text and returning the
+// requested page ordered by name and path, along with the number of rows
+// across all pages.
+func (s *Server) detailsRows(ctx context.Context, inner string, args []any, page detailsPage) (*sql.Rows, int, error) {
+	where := ""
+	if page.search != "" {
+		pattern := "%" + likeEscaper.Replace(page.search) + "%"
+		where = ` WHERE name LIKE ? ESCAPE '\' OR path LIKE ? ESCAPE '\'`
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+inner+")"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT * FROM (" + inner + ")" + where + " ORDER BY name, path"
+	if page.size > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, page.size, (page.number-1)*page.size)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	return rows, total, err
+}
+
+// likeEscaper escapes LIKE wildcards so search text matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// exportContentTypes maps report formats to response content types.
+var exportContentTypes = map[library.ExportFormat]string{
+	library.FormatCSV:  "text/csv; charset=utf-8",
+	library.FormatTSV:  "text/tab-separated-values; charset=utf-8",
+	library.FormatJSON: "application/json",
+	library.FormatTXT:  "text/plain; charset=utf-8",
+}
+
+// handleExport streams a report, playlist or gamelist for download.
+// Reports take report= and format= (csv, tsv, json, txt); report=retroarch,
+// gamelist and launchbox produce their native formats.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	libName := query.Get("library")
+	report := query.Get("report")
+	if libName == "" || report == "" {
+		http.Error(w, "Missing library or report parameter", http.StatusBadRequest)
+		return
+	}
+
+	manager := library.NewManager(s.db)
+	if _, err := manager.Get(r.Context(), libName); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, library.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	matchedOnly := query.Get("matched_only") == "true"
+	exporter := library.NewExporter(s.db, manager)
+
+	var data []byte
+	var err error
+	switch report {
+	case "retroarch":
+		// The playlist is streamed straight to the response
+		setDownloadHeaders(w, "application/json", libName+".lpl")
+		opts := library.PlaylistOptions{
+			PreferredOnly:  query.Get("preferred_only") == "true",
+			ExcludeFlagged: query.Get("exclude_flagged") == "true",
+		}
+		for _, region := range strings.Split(query.Get("regions"), ",") {
+			if region = strings.TrimSpace(region); region != "" {
+				opts.Regions = append(opts.Regions, region)
+			}
+		}
+		if err := library.NewRetroArchExporter(s.db).WritePlaylist(r.Context(), libName, w, opts); err != nil {
+			log.Printf("Export %s/%s failed: %v", libName, report, err)
+		}
+		return
+	case "gamelist":
+		data, err = exporter.ExportGamelist(r.Context(), libName, library.GamelistOptions{MatchedOnly: matchedOnly})
+		if err == nil {
+			setDownloadHeaders(w, "application/xml", "gamelist.xml")
+		}
+	case "launchbox":
+		data, err = exporter.ExportLaunchBox(r.Context(), libName, library.LaunchBoxOptions{MatchedOnly: matchedOnly})
+		if err == nil {
+			setDownloadHeaders(w, "application/xml", libName+".xml")
+		}
+	default:
+		format := library.ExportFormat(query.Get("format"))
+		if format == "" {
+			format = library.FormatCSV
+		}
+		contentType, ok := exportContentTypes[format]
+		if !ok {
+			http.Error(w, "Invalid format: "+string(format), http.StatusBadRequest)
+			return
+		}
+		opts := library.ExportOptions{
+			VerifiedOnly:   query.Get("verified_only") == "true",
+			Fallback:       query.Get("fallback") == "true",
+			PlayableOnly:   query.Get("playable_only") == "true",
+			ExcludeFlagged: query.Get("exclude_flagged") == "true",
+		}
+		data, err = exporter.ExportWithOptions(r.Context(), libName, library.ReportType(report), format, opts)
+		if err == nil {
+			setDownloadHeaders(w, contentType, fmt.Sprintf("%s-%s.%s", libName, report, format))
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, library.ErrInvalidArg) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, _ = w.Write(data)
+}
+
+// setDownloadHeaders marks a response as a file download with the given name.
+func setDownloadHeaders(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
 }
 
 func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
@@ -567,9 +982,9 @@ func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"status": status,
-		"db":     fmt.Sprintf("%v", err == nil),
+	_ = json.NewEncoder(w).Encode(apitypes.StatusResponse{
+		Status: status,
+		DB:     fmt.Sprintf("%v", err == nil),
 	})
 }
 
@@ -602,14 +1017,9 @@ func (s *Server) handlePackGames(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = rows.Close() }()
 
-	type gameInfo struct {
-		ID       int64  `json:"id"`
-		Name     string `json:"name"`
-		FilePath string `json:"filePath"`
-		Size     int64  `json:"size"`
-	}
-	systemGames := make(map[string][]gameInfo)
+	systemGames := make(map[string][]apitypes.PackGame)
 	systemNames := make(map[string]string)
+	var systemOrder []string // Query order, so the response is stable
 
 	for rows.Next() {
 		var systemID, systemName, gameName, filePath, entryName string
@@ -617,8 +1027,11 @@ func (s *Server) handlePackGames(w http.ResponseWriter, r *http.Request) {
 		if err := rows.Scan(&systemID, &systemName, &releaseID, &gameName, &filePath, &entryName, &size); err != nil {
 			continue
 		}
+		if _, ok := systemNames[systemID]; !ok {
+			systemOrder = append(systemOrder, systemID)
+		}
 		systemNames[systemID] = systemName
-		systemGames[systemID] = append(systemGames[systemID], gameInfo{
+		systemGames[systemID] = append(systemGames[systemID], apitypes.PackGame{
 			ID:       releaseID,
 			Name:     gameName,
 			FilePath: filePath,
@@ -627,29 +1040,17 @@ func (s *Server) handlePackGames(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build response
-	type systemInfo struct {
-		ID    string     `json:"id"`
-		Name  string     `json:"name"`
-		Games []gameInfo `json:"games"`
-	}
-	systems := make([]systemInfo, 0, len(systemGames))
-	for sysID, games := range systemGames {
-		systems = append(systems, systemInfo{
+	systems := make([]apitypes.PackSystem, 0, len(systemGames))
+	for _, sysID := range systemOrder {
+		systems = append(systems, apitypes.PackSystem{
 			ID:    sysID,
 			Name:  systemNames[sysID],
-			Games: games,
+			Games: systemGames[sysID],
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"systems": systems})
-}
-
-// PackGenerateRequest is the request body for pack generation.
-type PackGenerateRequest struct {
-	GameIDs []int64 `json:"gameIds"`
-	Format  string  `json:"format"`
-	Name    string  `json:"name"`
+	_ = json.NewEncoder(w).Encode(apitypes.PackGamesResponse{Systems: systems})
 }
 
 // handlePackGenerate streams a zip file containing the requested games.
@@ -659,7 +1060,7 @@ func (s *Server) handlePackGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req PackGenerateRequest
+	var req apitypes.PackGenerateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
@@ -722,13 +1123,77 @@ func (s *Server) handlePackGenerate(w http.ResponseWriter, r *http.Request) {
 	// Stream zip directly to response
 	generator := pack.NewGenerator()
 	_, err := generator.Generate(pack.Request{
-		Name:   packName,
-		Format: format,
-		Games:  games,
+		Name:        packName,
+		Format:      format,
+		Games:       games,
+		OnCollision: pack.CollisionPolicy(req.OnCollision),
 	}, w)
 
+	if errors.Is(err, pack.ErrPathCollision) {
+		// Collisions are found before anything is written
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		// Can't send error to client since we've already started writing
 		log.Printf("Error generating pack: %v", err)
 	}
 }
+
+// handleJobs starts a background job (POST) or lists jobs (GET).
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(apitypes.JobsResponse{Jobs: s.jobs.List()})
+	case http.MethodPost:
+		var req apitypes.JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		job, err := s.jobs.Start(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(job)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJob returns a job's status (GET) or cancels it (DELETE).
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	if id == "" {
+		http.Error(w, "Missing job id", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		job apitypes.Job
+		ok  bool
+	)
+	switch r.Method {
+	case http.MethodGet:
+		job, ok = s.jobs.Get(id)
+	case http.MethodDelete:
+		job, ok = s.jobs.Cancel(id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}